@@ -6,8 +6,14 @@ import (
 	"core/internal/controller"
 	"core/internal/database"
 	"core/internal/database/repository"
+	"core/internal/geo"
+	"core/internal/mail"
+	"core/internal/money"
+	"core/internal/oidc"
+	"core/internal/ratelimit"
 	"core/internal/security"
 	"core/internal/service"
+	"core/internal/storage"
 	"encoding/json"
 	"errors"
 	"github.com/gin-contrib/cors"
@@ -15,6 +21,7 @@ import (
 	"github.com/go-playground/validator/v10"
 	"log"
 	"net/http"
+	"time"
 )
 
 type Entity interface {
@@ -26,6 +33,7 @@ func main() {
 
 	// Загружаем HTML шаблоны
 	r.LoadHTMLGlob("templates/*")
+	r.MaxMultipartMemory = 8 << 20
 
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"*"},
@@ -40,6 +48,12 @@ func main() {
 		log.Fatal("Error loading .env file")
 	}
 
+	// ClientIP берется из X-Forwarded-For только за доверенными прокси, иначе ограничения обходятся подменой заголовка
+	err = r.SetTrustedProxies(internal.TrustedProxies)
+	if err != nil {
+		panic(err)
+	}
+
 	db, err := database.InitialiseDB(&database.DbConfig{
 		User:     internal.PostgresUser,
 		Password: internal.PostgresPassword,
@@ -52,6 +66,16 @@ func main() {
 		panic(err)
 	}
 
+	// Денежные колонки переводятся в целые копейки до обновления схемы
+	err = database.MigrateMoneyColumns(db)
+	if err != nil {
+		panic(err)
+	}
+	// Уже зарегистрированные пользователи считаются подтвердившими email
+	err = database.MigrateEmailVerification(db)
+	if err != nil {
+		panic(err)
+	}
 	err = db.AutoMigrate(&database.ClientDB{})
 	if err != nil {
 		panic(err)
@@ -76,6 +100,106 @@ func main() {
 	if err != nil {
 		panic(err)
 	}
+	err = db.AutoMigrate(&database.ReviewReport{})
+	if err != nil {
+		panic(err)
+	}
+	err = db.AutoMigrate(&database.FeeRule{})
+	if err != nil {
+		panic(err)
+	}
+	err = db.AutoMigrate(&database.PlatformAccount{})
+	if err != nil {
+		panic(err)
+	}
+	err = db.AutoMigrate(&database.FinancialDocument{})
+	if err != nil {
+		panic(err)
+	}
+	err = db.AutoMigrate(&database.DocumentSequence{})
+	if err != nil {
+		panic(err)
+	}
+	err = db.AutoMigrate(&database.ExportJob{})
+	if err != nil {
+		panic(err)
+	}
+	err = db.AutoMigrate(&database.PromoCode{})
+	if err != nil {
+		panic(err)
+	}
+	err = db.AutoMigrate(&database.PromoRedemption{})
+	if err != nil {
+		panic(err)
+	}
+	err = db.AutoMigrate(&database.OrderLineItem{})
+	if err != nil {
+		panic(err)
+	}
+	err = db.AutoMigrate(&database.ReferralCode{})
+	if err != nil {
+		panic(err)
+	}
+	err = db.AutoMigrate(&database.Referral{})
+	if err != nil {
+		panic(err)
+	}
+	err = db.AutoMigrate(&database.RecurringOrder{})
+	if err != nil {
+		panic(err)
+	}
+	err = db.AutoMigrate(&database.RecurringOccurrence{})
+	if err != nil {
+		panic(err)
+	}
+	err = db.AutoMigrate(&database.CartItem{})
+	if err != nil {
+		panic(err)
+	}
+	err = db.AutoMigrate(&database.Checkout{})
+	if err != nil {
+		panic(err)
+	}
+	err = db.AutoMigrate(&database.UserDB{})
+	if err != nil {
+		panic(err)
+	}
+	err = db.AutoMigrate(&database.CompanyMembership{})
+	if err != nil {
+		panic(err)
+	}
+	err = db.AutoMigrate(&database.ExternalIdentity{})
+	if err != nil {
+		panic(err)
+	}
+	err = db.AutoMigrate(&database.OIDCState{})
+	if err != nil {
+		panic(err)
+	}
+	err = db.AutoMigrate(&database.AuthToken{})
+	if err != nil {
+		panic(err)
+	}
+	err = db.AutoMigrate(&database.TwoFactor{})
+	if err != nil {
+		panic(err)
+	}
+	err = db.AutoMigrate(&database.RecoveryCode{})
+	if err != nil {
+		panic(err)
+	}
+	err = db.AutoMigrate(&database.TrustedDevice{})
+	if err != nil {
+		panic(err)
+	}
+	err = db.AutoMigrate(&database.RateLimitBucket{})
+	if err != nil {
+		panic(err)
+	}
+	err = db.AutoMigrate(&database.RateLimitFailure{})
+	if err != nil {
+		panic(err)
+	}
 	err = db.AutoMigrate(&database.Notification{})
 	if err != nil {
 		panic(err)
@@ -88,14 +212,80 @@ func main() {
 	if err != nil {
 		panic(err)
 	}
+	err = db.AutoMigrate(&database.WorkingHours{})
+	if err != nil {
+		panic(err)
+	}
+	err = db.AutoMigrate(&database.BlackoutDate{})
+	if err != nil {
+		panic(err)
+	}
+	err = db.AutoMigrate(&database.Worker{})
+	if err != nil {
+		panic(err)
+	}
+	err = db.AutoMigrate(&database.CompletionReport{})
+	if err != nil {
+		panic(err)
+	}
+	err = db.AutoMigrate(&database.WorkerLinkAccess{})
+	if err != nil {
+		panic(err)
+	}
+	err = db.AutoMigrate(&database.AdminDB{})
+	if err != nil {
+		panic(err)
+	}
+	err = db.AutoMigrate(&database.Category{})
+	if err != nil {
+		panic(err)
+	}
+	err = db.AutoMigrate(&database.Favorite{})
+	if err != nil {
+		panic(err)
+	}
+	err = db.AutoMigrate(&database.SavedSearch{})
+	if err != nil {
+		panic(err)
+	}
+	err = database.SetupCardSearch(db)
+	if err != nil {
+		panic(err)
+	}
+	err = database.SetupListIndexes(db)
+	if err != nil {
+		panic(err)
+	}
+	// Переводим строковые категории карточек на справочник
+	err = database.MigrateCardCategories(db)
+	if err != nil {
+		panic(err)
+	}
+	// Клиенты и компании, зарегистрированные до появления пользователей, получают пользователей
+	err = database.MigrateUsers(db)
+	if err != nil {
+		panic(err)
+	}
+
+	// Курсы валют из настроек. Внешний источник подключается реализацией money.RateProvider
+	exchangeRates, err := money.ParseRates(internal.ExchangeRates)
+	if err != nil {
+		panic(err)
+	}
+	rateProvider, err := money.NewStaticProvider(internal.BaseCurrency, exchangeRates)
+	if err != nil {
+		panic(err)
+	}
+
+	// Хранилище файлов (фотоотчеты и документы)
+	fileStorage, err := storage.NewLocalStorage(internal.StorageDir)
+	if err != nil {
+		panic(err)
+	}
 
 	// Existing repositories and services
 	clientRepository := repository.NewClientRepository(db)
-	clientService := service.NewClientService(clientRepository)
-	clientController := controller.NewClientController(clientService)
 	companyRepository := repository.NewCompanyRepository(db)
-	companyService := service.NewCompanyService(companyRepository)
-	companyController := controller.NewCompanyController(companyService)
 
 	// New repositories
 	cardRepository := repository.NewCardRepository(db)
@@ -104,21 +294,152 @@ func main() {
 	escrowRepository := repository.NewEscrowRepository(db)
 	workerLinkRepository := repository.NewWorkerLinkRepository(db)
 	reviewRepository := repository.NewReviewRepository(db)
+	reviewReportRepository := repository.NewReviewReportRepository(db)
+	analyticsRepository := repository.NewAnalyticsRepository(db)
 	notificationRepository := repository.NewNotificationRepository(db)
+	scheduleRepository := repository.NewScheduleRepository(db)
+	workerRepository := repository.NewWorkerRepository(db)
+	completionReportRepository := repository.NewCompletionReportRepository(db)
+	adminRepository := repository.NewAdminRepository(db)
+	categoryRepository := repository.NewCategoryRepository(db)
+	feeRuleRepository := repository.NewFeeRuleRepository(db)
+	platformAccountRepository := repository.NewPlatformAccountRepository(db)
+	favoriteRepository := repository.NewFavoriteRepository(db)
+	savedSearchRepository := repository.NewSavedSearchRepository(db)
+	documentRepository := repository.NewDocumentRepository(db)
+	exportRepository := repository.NewExportRepository(db)
+	exportJobRepository := repository.NewExportJobRepository(db)
+	promoCodeRepository := repository.NewPromoCodeRepository(db)
+	referralRepository := repository.NewReferralRepository(db)
+	recurringOrderRepository := repository.NewRecurringOrderRepository(db)
+	cartRepository := repository.NewCartRepository(db)
+	authTokenRepository := repository.NewAuthTokenRepository(db)
+	userRepository := repository.NewUserRepository(db)
+	twoFactorRepository := repository.NewTwoFactorRepository(db)
+	oidcRepository := repository.NewOIDCRepository(db)
+
+	// Письма пишутся в журнал, пока не настроен SMTP сервер
+	mailer := mail.NewLogSender()
+	if internal.MailDriver == "smtp" {
+		mailer = mail.NewSMTPSender(internal.SMTPHost, internal.SMTPPort, internal.SMTPUsername, internal.SMTPPassword, internal.MailFrom)
+	}
+
+	// Ограничения частоты хранятся в памяти процесса, при нескольких репликах - в Postgres
+	var rateLimitStore ratelimit.Store = ratelimit.NewMemoryStore()
+	if internal.RateLimitBackend == "postgres" {
+		rateLimitStore = repository.NewRateLimitRepository(db)
+	}
+	limiter := ratelimit.NewLimiter(rateLimitStore, ratelimit.LockoutPolicy{
+		Threshold: internal.LoginLockoutThreshold,
+		Base:      internal.LoginLockoutBase,
+		Max:       internal.LoginLockoutMax,
+		Window:    time.Hour,
+	})
+	limiter.Start(time.Hour)
+
+	// Провайдеры входа через OpenID Connect из OIDC_PROVIDERS
+	var oidcProviders []*oidc.Provider
+	for _, provider := range internal.OIDCProviders {
+		oidcProviders = append(oidcProviders, oidc.NewProvider(oidc.Config{
+			Name:           provider.Name,
+			Title:          provider.Title,
+			Issuer:         provider.Issuer,
+			ClientID:       provider.ClientID,
+			ClientSecret:   provider.ClientSecret,
+			Scopes:         provider.Scopes,
+			AllowedDomains: provider.AllowedDomains,
+		}, internal.OIDCRedirectURL))
+	}
+
+	// Геокодер без внешних сервисов, настоящий провайдер подключается через интерфейс geo.Geocoder
+	geocoder := geo.NewStubGeocoder()
 
 	// New services
-	cardService := service.NewCardService(cardRepository)
-	orderService := service.NewOrderService(orderRepository, cardRepository, balanceRepository, escrowRepository, workerLinkRepository)
-	balanceService := service.NewBalanceService(balanceRepository)
-	reviewService := service.NewReviewService(reviewRepository, orderRepository)
+	feeService := service.NewFeeService(feeRuleRepository, platformAccountRepository, companyRepository, categoryRepository, rateProvider)
+	documentService := service.NewDocumentService(documentRepository, orderRepository, balanceRepository, clientRepository, companyRepository, fileStorage)
+	promoService := service.NewPromoService(promoCodeRepository, referralRepository, cardRepository, categoryRepository, companyRepository, orderRepository, balanceRepository, platformAccountRepository, rateProvider)
+	orderService := service.NewOrderService(orderRepository, cardRepository, balanceRepository, escrowRepository, workerLinkRepository, scheduleRepository, completionReportRepository, fileStorage, feeService, platformAccountRepository, documentService, rateProvider, promoService)
+	balanceService := service.NewBalanceService(balanceRepository, documentService, rateProvider)
 	notificationService := service.NewNotificationService(notificationRepository, orderRepository)
+	accountService := service.NewAccountService(userRepository, clientRepository, companyRepository, authTokenRepository, notificationService, mailer)
+	twoFactorService := service.NewTwoFactorService(twoFactorRepository, authTokenRepository, userRepository, clientRepository, companyRepository, accountService, limiter)
+	identityService := service.NewIdentityService(userRepository, clientRepository, companyRepository, accountService, limiter)
+	oidcService := service.NewOIDCService(oidcProviders, oidcRepository, userRepository, clientRepository, accountService)
+	clientService := service.NewClientService(clientRepository, userRepository, accountService)
+	companyService := service.NewCompanyService(companyRepository, userRepository, accountService)
+	reviewService := service.NewReviewService(reviewRepository, reviewReportRepository, orderRepository, companyRepository, notificationService)
+	favoriteService := service.NewFavoriteService(favoriteRepository, savedSearchRepository, cardRepository, companyRepository, categoryRepository, notificationService)
+	cardService := service.NewCardService(cardRepository, companyRepository, categoryRepository, favoriteRepository, geocoder, favoriteService)
+	scheduleService := service.NewScheduleService(scheduleRepository)
+	workerService := service.NewWorkerService(workerRepository, orderRepository, completionReportRepository, limiter)
+	workerLinkService := service.NewWorkerLinkService(workerLinkRepository, orderRepository)
+	serviceAreaService := service.NewServiceAreaService(companyRepository, cardRepository, geocoder)
+	adminService := service.NewAdminService(adminRepository, limiter)
+	categoryService := service.NewCategoryService(categoryRepository)
+	analyticsService := service.NewAnalyticsService(analyticsRepository, companyRepository)
+	exportService := service.NewExportService(exportRepository, exportJobRepository, fileStorage)
+	recurringOrderService := service.NewRecurringOrderService(recurringOrderRepository, cardRepository, scheduleRepository, orderRepository, orderService, notificationService)
+	cartService := service.NewCartService(cartRepository, cardRepository, scheduleRepository, balanceRepository, orderService, rateProvider)
+	companyProfileService := service.NewCompanyProfileService(companyRepository, cardRepository, reviewRepository, favoriteRepository)
+
+	err = adminService.EnsureAdmin(internal.AdminEmail, internal.AdminPassword)
+	if err != nil {
+		panic(err)
+	}
+	// Фоновые выгрузки не переживают перезапуск, их нужно запустить заново
+	err = exportService.FailInterruptedJobs()
+	if err != nil {
+		panic(err)
+	}
+	// Байесовские оценки зависят от средней оценки платформы, пересчитываем их при запуске
+	err = reviewRepository.RecalculateAllRatings()
+	if err != nil {
+		panic(err)
+	}
+	// Повторяющиеся заказы создаются фоновой проверкой расписаний
+	recurringOrderService.Start(internal.RecurringCheckInterval)
 
 	// New controllers
+	clientController := controller.NewClientController(clientService, identityService, twoFactorService)
+	companyController := controller.NewCompanyController(companyService, identityService, twoFactorService)
+	accountController := controller.NewAccountController(accountService)
+	twoFactorController := controller.NewTwoFactorController(twoFactorService, identityService)
+	identityController := controller.NewIdentityController(identityService)
+	oidcController := controller.NewOIDCController(oidcService, identityService, twoFactorService)
 	cardController := controller.NewCardController(cardService)
 	orderController := controller.NewOrderController(orderService)
-	balanceController := controller.NewBalanceController(balanceService)
+	balanceController := controller.NewBalanceController(balanceService, twoFactorService)
 	reviewController := controller.NewReviewController(reviewService)
 	notificationController := controller.NewNotificationController(notificationService)
+	scheduleController := controller.NewScheduleController(scheduleService)
+	workerController := controller.NewWorkerController(workerService)
+	fileController := controller.NewFileController(fileStorage)
+	workerLinkController := controller.NewWorkerLinkController(workerLinkService)
+	serviceAreaController := controller.NewServiceAreaController(serviceAreaService)
+	adminController := controller.NewAdminController(adminService)
+	categoryController := controller.NewCategoryController(categoryService)
+	favoriteController := controller.NewFavoriteController(favoriteService)
+	companyProfileController := controller.NewCompanyProfileController(companyProfileService)
+	analyticsController := controller.NewAnalyticsController(analyticsService)
+	feeController := controller.NewFeeController(feeService)
+	documentController := controller.NewDocumentController(documentService)
+	exportController := controller.NewExportController(exportService)
+	promoController := controller.NewPromoController(promoService)
+	recurringOrderController := controller.NewRecurringOrderController(recurringOrderService)
+	cartController := controller.NewCartController(cartService)
+
+	// Ограничения частоты для входа, регистрации, публичных ссылок и денежных операций
+	loginLimit := ratelimit.Limit{Requests: 10, Per: time.Minute}
+	registerLimit := ratelimit.Limit{Requests: 5, Per: time.Hour}
+	authLimit := ratelimit.Limit{Requests: 10, Per: time.Minute}
+	workerLimit := ratelimit.Limit{Requests: 30, Per: time.Minute}
+	moneyLimit := ratelimit.Limit{Requests: 20, Per: time.Minute}
+	moneyIPLimit := ratelimit.Limit{Requests: 60, Per: time.Minute}
+
+	// Роли сотрудников компании, см. database.CompanyMembership. Клиентов не ограничивают
+	operationsRoles := controller.RequireCompanyRole(database.RoleOwner, database.RoleManager)
+	financeRoles := controller.RequireCompanyRole(database.RoleOwner, database.RoleAccountant)
+	ownerOnly := controller.RequireCompanyRole(database.RoleOwner)
 
 	// Публичные маршруты (без авторизации)
 	r.GET("/cards", cardController.GetAllCards)
@@ -126,29 +447,37 @@ func main() {
 	r.GET("/cards/:id", cardController.GetCardByID)
 	r.GET("/cards/search", cardController.SearchCards)
 	r.GET("/cards/price-range", cardController.GetCardsByPriceRange)
+	r.GET("/search/cards", cardController.FindCards)
+	r.GET("/categories", categoryController.GetTree)
 	r.GET("/orders", orderController.GetAllOrders)
 	r.GET("/reviews/company/:company_id", reviewController.GetCompanyReviews)
 	r.GET("/reviews/order/:order_id", reviewController.GetOrderReview)
+	r.GET("/companies/:company_id", companyProfileController.GetPublicProfile)
 	r.GET("/companies/:company_id/rating", reviewController.GetCompanyRating)
+	r.GET("/companies/:company_id/slots", scheduleController.GetAvailableSlots)
 
 	// Специальная страница для работников (без авторизации)
-	r.GET("/worker/complete/:token", orderController.CompleteOrderByWorker)
+	r.GET("/worker/complete/:token", controller.RateLimitByIP(limiter, "worker_complete", workerLimit), orderController.ShowWorkerCompleteForm)
+	r.POST("/worker/complete/:token", controller.RateLimitByIP(limiter, "worker_complete", workerLimit), orderController.CompleteOrderByWorker)
+
+	// Файлы из хранилища по подписанным ссылкам
+	r.GET("/files/*path", fileController.ServeFile)
 
 	v1 := r.Group("v1")
 	{
 		// Новые простые эндпоинты для логина
 		loginGroup := v1.Group("login")
 		{
-			loginGroup.POST("/client", func(c *gin.Context) {
+			loginGroup.POST("/client", controller.RateLimitByIP(limiter, "login", loginLimit), func(c *gin.Context) {
 				clientController.Login(c)
 			})
-			loginGroup.POST("/company", func(c *gin.Context) {
+			loginGroup.POST("/company", controller.RateLimitByIP(limiter, "login", loginLimit), func(c *gin.Context) {
 				companyController.Login(c)
 			})
 		}
 
 		// Универсальный эндпоинт логина (поддерживает и простой, и сложный формат)
-		v1.POST("/login", func(c *gin.Context) {
+		v1.POST("/login", controller.RateLimitByIP(limiter, "login", loginLimit), func(c *gin.Context) {
 			// Читаем RAW тело запроса
 			rawData, err := c.GetRawData()
 			if err != nil {
@@ -156,43 +485,11 @@ func main() {
 				return
 			}
 
-			// Сначала пробуем простой формат: {"email": "...", "password": "..."}
+			// Сначала пробуем простой формат: {"email": "...", "password": "..."}.
+			// Токен выдается в роли по умолчанию, сменить ее можно через /v1/account/switch
 			var simpleRequest api.LoginRequest
 			if err := json.Unmarshal(rawData, &simpleRequest); err == nil && simpleRequest.Email != "" && simpleRequest.Password != "" {
-				resultClient, _, _ := clientRepository.ExistsByEmail(simpleRequest.Email)
-				if resultClient {
-					// Логин как клиент
-					dbUser, err := clientService.LoginSimple(&simpleRequest)
-					if err != nil {
-						api.GetErrorJSON(c, http.StatusUnauthorized, "Invalid credentials")
-						return
-					}
-					jwtToken := security.CreateToken(false, dbUser.ID, internal.LifeTimeJWT)
-					c.JSON(http.StatusOK, api.ResponseSuccessAccess{
-						StatusResponse: internal.StatusResponse{Status: "success"},
-						ResponseUser: api.ResponseUser{
-							ID:    dbUser.ID,
-							Token: jwtToken,
-							Type:  dbUser.Type,
-						},
-					})
-				} else {
-					// Логин как компания
-					dbUser, err := companyService.LoginSimple(&simpleRequest)
-					if err != nil {
-						api.GetErrorJSON(c, http.StatusUnauthorized, "Invalid credentials")
-						return
-					}
-					jwtToken := security.CreateToken(true, dbUser.ID, internal.LifeTimeJWT)
-					c.JSON(http.StatusOK, api.ResponseSuccessAccess{
-						StatusResponse: internal.StatusResponse{Status: "success"},
-						ResponseUser: api.ResponseUser{
-							ID:    dbUser.ID,
-							Token: jwtToken,
-							Type:  dbUser.Type,
-						},
-					})
-				}
+				controller.LoginUser(c, identityService, twoFactorService, simpleRequest.Email, simpleRequest.Password, "")
 				return
 			}
 
@@ -204,23 +501,20 @@ func main() {
 			}
 
 			// Проверяем обязательные поля для старого формата
-			if request.GeneralLogin.GeneralLoginAttributes.Email == "" {
+			credentials := request.GeneralLogin.GeneralLoginAttributes
+			if credentials.Email == "" {
 				api.GetErrorJSON(c, http.StatusBadRequest, "Email is required")
 				return
 			}
-			if request.GeneralLogin.GeneralLoginAttributes.PasswordHash == "" {
+			if credentials.PasswordHash == "" {
 				api.GetErrorJSON(c, http.StatusBadRequest, "Password is required")
 				return
 			}
 
-			resultClient, _, _ := clientRepository.ExistsByEmail(request.GeneralLogin.GeneralLoginAttributes.Email)
-			if resultClient {
-				clientController.LoginOld(c, &request)
-			} else {
-				companyController.LoginOld(c, &request)
-			}
+			controller.LoginUser(c, identityService, twoFactorService, credentials.Email, credentials.PasswordHash, "")
 		})
-		accountGroup := v1.Group("account")
+		// Сотрудники компании проверяются при каждом запросе: исключение и смена роли действуют сразу
+		accountGroup := v1.Group("account", controller.CheckCompanyMembership(identityService))
 		{
 			accountGroup.POST("/", func(c *gin.Context) {
 				request := &api.TokenAccess{}
@@ -245,7 +539,33 @@ func main() {
 					return
 				}
 			})
-			cardGroup := accountGroup.Group("card")
+
+			accountGroup.POST("/service-area", operationsRoles, func(c *gin.Context) {
+				request := &api.TokenSetCompanyArea{}
+				if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+					api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+					return
+				}
+				ok, mapClaims := security.CheckToken(request.TokenAccess.User.Login.Token)
+				if mapClaims == nil {
+					api.GetErrorJSON(c, http.StatusBadRequest, "The token is invalid")
+					return
+				}
+				if ok {
+					isCompany := mapClaims["isCompany"].(bool)
+					if isCompany {
+						serviceAreaController.SetCompanyArea(c, request)
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "You're not a company")
+						return
+					}
+				} else {
+					api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+					return
+				}
+			})
+
+			cardGroup := accountGroup.Group("card", operationsRoles)
 			{
 				cardGroup.POST("/create", func(c *gin.Context) {
 					request := &api.TokenCreateCard{}
@@ -345,12 +665,37 @@ func main() {
 						return
 					}
 				})
+
+				cardGroup.POST("/area", func(c *gin.Context) {
+					request := &api.TokenSetCardArea{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, mapClaims := security.CheckToken(request.TokenAccess.User.Login.Token)
+					if mapClaims == nil {
+						api.GetErrorJSON(c, http.StatusBadRequest, "The token is invalid")
+						return
+					}
+					if ok {
+						isCompany := mapClaims["isCompany"].(bool)
+						if isCompany {
+							serviceAreaController.SetCardArea(c, request)
+						} else {
+							api.GetErrorJSON(c, http.StatusForbidden, "You're not a company")
+							return
+						}
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
 			}
 
 			// Группа для заказов
 			orderGroup := accountGroup.Group("order")
 			{
-				orderGroup.POST("/create", func(c *gin.Context) {
+				orderGroup.POST("/create", controller.RateLimitByIP(limiter, "order_create", moneyIPLimit), controller.RateLimitByAccount(limiter, "order_create", moneyLimit), func(c *gin.Context) {
 					request := &api.TokenCreateOrder{}
 					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
 						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
@@ -375,7 +720,7 @@ func main() {
 					}
 				})
 
-				orderGroup.POST("/pay", func(c *gin.Context) {
+				orderGroup.POST("/pay", controller.RateLimitByIP(limiter, "order_pay", moneyIPLimit), controller.RateLimitByAccount(limiter, "order_pay", moneyLimit), func(c *gin.Context) {
 					request := &api.TokenOrderAction{}
 					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
 						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
@@ -400,7 +745,7 @@ func main() {
 					}
 				})
 
-				orderGroup.POST("/start", func(c *gin.Context) {
+				orderGroup.POST("/start", operationsRoles, func(c *gin.Context) {
 					request := &api.TokenOrderAction{}
 					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
 						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
@@ -425,7 +770,7 @@ func main() {
 					}
 				})
 
-				orderGroup.POST("/finish", func(c *gin.Context) {
+				orderGroup.POST("/finish", operationsRoles, func(c *gin.Context) {
 					request := &api.TokenOrderAction{}
 					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
 						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
@@ -450,7 +795,7 @@ func main() {
 					}
 				})
 
-				orderGroup.POST("/cancel", func(c *gin.Context) {
+				orderGroup.POST("/cancel", operationsRoles, func(c *gin.Context) {
 					request := &api.TokenOrderAction{}
 					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
 						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
@@ -493,6 +838,40 @@ func main() {
 					}
 				})
 
+				orderGroup.POST("/report", operationsRoles, func(c *gin.Context) {
+					request := &api.TokenOrderAction{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, mapClaims := security.CheckToken(request.TokenAccess.User.Login.Token)
+					if mapClaims == nil {
+						api.GetErrorJSON(c, http.StatusBadRequest, "The token is invalid")
+						return
+					}
+					if ok {
+						orderController.GetCompletionReport(c, request)
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+
+				orderGroup.POST("/invoice", func(c *gin.Context) {
+					request := &api.TokenOrderAction{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, _ := security.CheckToken(request.TokenAccess.User.Login.Token)
+					if ok {
+						documentController.GetOrderInvoice(c, request)
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+
 				orderGroup.GET("/:id", orderController.GetOrderByID)
 			}
 
@@ -523,7 +902,7 @@ func main() {
 					}
 				})
 
-				balanceGroup.POST("/deposit", func(c *gin.Context) {
+				balanceGroup.POST("/deposit", financeRoles, controller.RateLimitByIP(limiter, "deposit", moneyIPLimit), controller.RateLimitByAccount(limiter, "deposit", moneyLimit), func(c *gin.Context) {
 					request := &api.TokenDepositBalance{}
 					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
 						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
@@ -548,7 +927,7 @@ func main() {
 					}
 				})
 
-				balanceGroup.POST("/withdraw", func(c *gin.Context) {
+				balanceGroup.POST("/withdraw", financeRoles, controller.RateLimitByIP(limiter, "withdraw", moneyIPLimit), controller.RateLimitByAccount(limiter, "withdraw", moneyLimit), func(c *gin.Context) {
 					request := &api.TokenWithdrawBalance{}
 					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
 						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
@@ -573,7 +952,7 @@ func main() {
 					}
 				})
 
-				balanceGroup.POST("/transactions", func(c *gin.Context) {
+				balanceGroup.POST("/transactions", financeRoles, func(c *gin.Context) {
 					request := &api.TokenAccessDouble{}
 					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
 						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
@@ -596,6 +975,36 @@ func main() {
 						return
 					}
 				})
+
+				balanceGroup.POST("/currency", financeRoles, func(c *gin.Context) {
+					request := &api.TokenSetCurrency{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, _ := security.CheckToken(request.TokenAccess.User.Login.Token)
+					if ok {
+						balanceController.SetCurrency(c, request)
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+
+				balanceGroup.POST("/document", financeRoles, func(c *gin.Context) {
+					request := &api.TokenTransactionDocument{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, _ := security.CheckToken(request.TokenAccess.User.Login.Token)
+					if ok {
+						documentController.GetTransactionDocument(c, request)
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
 			}
 
 			// Группа для отзывов
@@ -625,6 +1034,71 @@ func main() {
 						return
 					}
 				})
+
+				reviewGroup.POST("/update", func(c *gin.Context) {
+					request := &api.TokenUpdateReview{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, mapClaims := security.CheckToken(request.TokenAccess.User.Login.Token)
+					if mapClaims == nil {
+						api.GetErrorJSON(c, http.StatusBadRequest, "The token is invalid")
+						return
+					}
+					if ok {
+						isCompany := mapClaims["isCompany"].(bool)
+						if !isCompany {
+							reviewController.UpdateReview(c, request)
+						} else {
+							api.GetErrorJSON(c, http.StatusForbidden, "Only clients can edit reviews")
+							return
+						}
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+
+				reviewGroup.POST("/reply", operationsRoles, func(c *gin.Context) {
+					request := &api.TokenReviewReply{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, mapClaims := security.CheckToken(request.TokenAccess.User.Login.Token)
+					if mapClaims == nil {
+						api.GetErrorJSON(c, http.StatusBadRequest, "The token is invalid")
+						return
+					}
+					if ok {
+						isCompany := mapClaims["isCompany"].(bool)
+						if isCompany {
+							reviewController.ReplyToReview(c, request)
+						} else {
+							api.GetErrorJSON(c, http.StatusForbidden, "Only companies can reply to reviews")
+							return
+						}
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+
+				reviewGroup.POST("/report", operationsRoles, func(c *gin.Context) {
+					request := &api.TokenReviewReport{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, _ := security.CheckToken(request.TokenAccess.User.Login.Token)
+					if ok {
+						reviewController.ReportReview(c, request)
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
 			}
 
 			// Группа для уведомлений
@@ -679,7 +1153,7 @@ func main() {
 			// Группа для управления профилем
 			profileGroup := accountGroup.Group("profile")
 			{
-				profileGroup.POST("/update", func(c *gin.Context) {
+				profileGroup.POST("/update", ownerOnly, func(c *gin.Context) {
 					// Сначала парсим базовую часть для получения токена
 					var baseRequest api.TokenUpdateClientProfileDouble
 					if err := c.ShouldBind(&baseRequest); err != nil {
@@ -736,31 +1210,1939 @@ func main() {
 						return
 					}
 				})
+
+				statsGroup.POST("/analytics", func(c *gin.Context) {
+					request := &api.TokenCompanyAnalytics{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, mapClaims := security.CheckToken(request.TokenAccess.User.Login.Token)
+					if mapClaims == nil {
+						api.GetErrorJSON(c, http.StatusBadRequest, "The token is invalid")
+						return
+					}
+					if ok {
+						isCompany := mapClaims["isCompany"].(bool)
+						if isCompany {
+							analyticsController.GetCompanyAnalytics(c, request)
+						} else {
+							api.GetErrorJSON(c, http.StatusForbidden, "Only companies can access stats")
+							return
+						}
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
 			}
 
-			// Дополнительные маршруты для заказов
-			orderGroup.POST("/update-status", func(c *gin.Context) {
-				request := &api.TokenOrderAction{}
+			// Группа для выгрузок транзакций и заказов
+			exportGroup := accountGroup.Group("export", financeRoles)
+			{
+				exportGroup.POST("/create", func(c *gin.Context) {
+					request := &api.TokenExport{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, _ := security.CheckToken(request.TokenAccess.User.Login.Token)
+					if ok {
+						exportController.CreateExport(c, request)
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+
+				exportGroup.POST("/status", func(c *gin.Context) {
+					request := &api.TokenExportJob{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, _ := security.CheckToken(request.TokenAccess.User.Login.Token)
+					if ok {
+						exportController.GetExportJob(c, request)
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+
+				exportGroup.POST("/list", func(c *gin.Context) {
+					request := &api.TokenAccess{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, _ := security.CheckToken(request.User.Login.Token)
+					if ok {
+						exportController.GetExportJobs(c, request)
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+			}
+
+			// Группа безопасности учетной записи: смена пароля и email, двухфакторная аутентификация
+			securityGroup := accountGroup.Group("security")
+			{
+				securityGroup.POST("/change-password", func(c *gin.Context) {
+					request := &api.TokenChangePassword{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, _ := security.CheckToken(request.TokenAccess.User.Login.Token)
+					if ok {
+						accountController.ChangePassword(c, request)
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+
+				securityGroup.POST("/change-email", func(c *gin.Context) {
+					request := &api.TokenChangeEmail{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, _ := security.CheckToken(request.TokenAccess.User.Login.Token)
+					if ok {
+						accountController.ChangeEmail(c, request)
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+
+				securityGroup.POST("/two-factor/status", func(c *gin.Context) {
+					request := &api.TokenAccess{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, _ := security.CheckToken(request.User.Login.Token)
+					if ok {
+						twoFactorController.GetStatus(c, request)
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+
+				securityGroup.POST("/two-factor/setup", func(c *gin.Context) {
+					request := &api.TokenTwoFactorSetup{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, _ := security.CheckToken(request.TokenAccess.User.Login.Token)
+					if ok {
+						twoFactorController.BeginSetup(c, request)
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+
+				securityGroup.POST("/two-factor/confirm", func(c *gin.Context) {
+					request := &api.TokenTwoFactorCode{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, _ := security.CheckToken(request.TokenAccess.User.Login.Token)
+					if ok {
+						twoFactorController.ConfirmSetup(c, request)
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+
+				securityGroup.POST("/two-factor/disable", func(c *gin.Context) {
+					request := &api.TokenTwoFactorDisable{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, _ := security.CheckToken(request.TokenAccess.User.Login.Token)
+					if ok {
+						twoFactorController.Disable(c, request)
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+
+				securityGroup.POST("/two-factor/recovery-codes", func(c *gin.Context) {
+					request := &api.TokenTwoFactorCode{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, _ := security.CheckToken(request.TokenAccess.User.Login.Token)
+					if ok {
+						twoFactorController.RegenerateRecoveryCodes(c, request)
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+
+				securityGroup.POST("/trusted-devices", func(c *gin.Context) {
+					request := &api.TokenAccess{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, _ := security.CheckToken(request.User.Login.Token)
+					if ok {
+						twoFactorController.ListTrustedDevices(c, request)
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+
+				securityGroup.POST("/trusted-devices/revoke", func(c *gin.Context) {
+					request := &api.TokenTrustedDevice{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, _ := security.CheckToken(request.TokenAccess.User.Login.Token)
+					if ok {
+						twoFactorController.RevokeTrustedDevice(c, request)
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+			}
+
+			// Учетные записи пользователя: клиентский профиль и компании, в которых он состоит
+			accountGroup.POST("/accounts", func(c *gin.Context) {
+				request := &api.TokenAccess{}
+				if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+					api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+					return
+				}
+				ok, _ := security.CheckToken(request.User.Login.Token)
+				if ok {
+					identityController.ListAccounts(c, request)
+				} else {
+					api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+					return
+				}
+			})
+			accountGroup.POST("/switch", func(c *gin.Context) {
+				request := &api.TokenSwitchAccount{}
 				if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
 					api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
 					return
 				}
 				ok, _ := security.CheckToken(request.TokenAccess.User.Login.Token)
 				if ok {
-					orderController.UpdateOrderStatus(c, request)
+					identityController.SwitchAccount(c, request)
 				} else {
 					api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
 					return
 				}
 			})
-		}
-		registerGroup := v1.Group("register")
-		{
-			registerGroup.POST("/client", func(c *gin.Context) {
-				clientController.Signup(c)
+			accountGroup.POST("/client/create", func(c *gin.Context) {
+				request := &api.TokenCreateClientProfile{}
+				if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+					api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+					return
+				}
+				ok, _ := security.CheckToken(request.TokenAccess.User.Login.Token)
+				if ok {
+					identityController.CreateClientProfile(c, request)
+				} else {
+					api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+					return
+				}
 			})
-			registerGroup.POST("/company", func(c *gin.Context) {
-				companyController.Signup(c)
+			accountGroup.POST("/company/create", func(c *gin.Context) {
+				request := &api.TokenCreateCompany{}
+				if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+					api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+					return
+				}
+				ok, _ := security.CheckToken(request.TokenAccess.User.Login.Token)
+				if ok {
+					identityController.CreateCompany(c, request)
+				} else {
+					api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+					return
+				}
+			})
+			// Группа сотрудников компании. Состав и роли меняет только владелец
+			membersGroup := accountGroup.Group("members")
+			{
+				membersGroup.POST("/list", func(c *gin.Context) {
+					request := &api.TokenAccess{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, mapClaims := security.CheckToken(request.User.Login.Token)
+					if mapClaims == nil {
+						api.GetErrorJSON(c, http.StatusBadRequest, "The token is invalid")
+						return
+					}
+					if ok {
+						isCompany := mapClaims["isCompany"].(bool)
+						if isCompany {
+							identityController.ListMembers(c, request)
+						} else {
+							api.GetErrorJSON(c, http.StatusForbidden, "You're not a company")
+							return
+						}
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+
+				membersGroup.POST("/add", ownerOnly, func(c *gin.Context) {
+					request := &api.TokenAddCompanyMember{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, mapClaims := security.CheckToken(request.TokenAccess.User.Login.Token)
+					if mapClaims == nil {
+						api.GetErrorJSON(c, http.StatusBadRequest, "The token is invalid")
+						return
+					}
+					if ok {
+						isCompany := mapClaims["isCompany"].(bool)
+						if isCompany {
+							identityController.AddMember(c, request)
+						} else {
+							api.GetErrorJSON(c, http.StatusForbidden, "You're not a company")
+							return
+						}
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+
+				membersGroup.POST("/update-role", ownerOnly, func(c *gin.Context) {
+					request := &api.TokenUpdateCompanyMember{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, mapClaims := security.CheckToken(request.TokenAccess.User.Login.Token)
+					if mapClaims == nil {
+						api.GetErrorJSON(c, http.StatusBadRequest, "The token is invalid")
+						return
+					}
+					if ok {
+						isCompany := mapClaims["isCompany"].(bool)
+						if isCompany {
+							identityController.UpdateMemberRole(c, request)
+						} else {
+							api.GetErrorJSON(c, http.StatusForbidden, "You're not a company")
+							return
+						}
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+
+				membersGroup.POST("/remove", ownerOnly, func(c *gin.Context) {
+					request := &api.TokenRemoveCompanyMember{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, mapClaims := security.CheckToken(request.TokenAccess.User.Login.Token)
+					if mapClaims == nil {
+						api.GetErrorJSON(c, http.StatusBadRequest, "The token is invalid")
+						return
+					}
+					if ok {
+						isCompany := mapClaims["isCompany"].(bool)
+						if isCompany {
+							identityController.RemoveMember(c, request)
+						} else {
+							api.GetErrorJSON(c, http.StatusForbidden, "You're not a company")
+							return
+						}
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+			}
+
+			// Группа входа через внешних провайдеров: привязанные учетные записи, привязка и отвязка
+			oidcGroup := accountGroup.Group("oidc")
+			{
+				oidcGroup.POST("/identities", func(c *gin.Context) {
+					request := &api.TokenAccess{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, _ := security.CheckToken(request.User.Login.Token)
+					if ok {
+						oidcController.ListIdentities(c, request)
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+
+				oidcGroup.POST("/link/start", func(c *gin.Context) {
+					request := &api.TokenOIDCLinkStart{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, _ := security.CheckToken(request.TokenAccess.User.Login.Token)
+					if ok {
+						oidcController.StartLink(c, request)
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+
+				oidcGroup.POST("/link/complete", func(c *gin.Context) {
+					request := &api.TokenOIDCLinkComplete{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, _ := security.CheckToken(request.TokenAccess.User.Login.Token)
+					if ok {
+						oidcController.CompleteLink(c, request)
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+
+				oidcGroup.POST("/unlink", func(c *gin.Context) {
+					request := &api.TokenOIDCUnlink{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, _ := security.CheckToken(request.TokenAccess.User.Login.Token)
+					if ok {
+						oidcController.Unlink(c, request)
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+			}
+
+			// Группа реквизитов для вывода средств. Смена реквизитов подтверждается кодом 2FA
+			payoutGroup := accountGroup.Group("payout-details")
+			{
+				payoutGroup.POST("/get", financeRoles, func(c *gin.Context) {
+					request := &api.TokenAccess{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, mapClaims := security.CheckToken(request.User.Login.Token)
+					if mapClaims == nil {
+						api.GetErrorJSON(c, http.StatusBadRequest, "The token is invalid")
+						return
+					}
+					if ok {
+						isCompany := mapClaims["isCompany"].(bool)
+						if isCompany {
+							companyController.GetPayoutDetails(c, request)
+						} else {
+							api.GetErrorJSON(c, http.StatusForbidden, "Only companies have payout details")
+							return
+						}
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+
+				payoutGroup.POST("/update", ownerOnly, controller.RateLimitByIP(limiter, "payout_update", moneyIPLimit), controller.RateLimitByAccount(limiter, "payout_update", moneyLimit), func(c *gin.Context) {
+					request := &api.TokenUpdatePayoutDetails{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, mapClaims := security.CheckToken(request.TokenAccess.User.Login.Token)
+					if mapClaims == nil {
+						api.GetErrorJSON(c, http.StatusBadRequest, "The token is invalid")
+						return
+					}
+					if ok {
+						isCompany := mapClaims["isCompany"].(bool)
+						if isCompany {
+							companyController.UpdatePayoutDetails(c, request)
+						} else {
+							api.GetErrorJSON(c, http.StatusForbidden, "Only companies have payout details")
+							return
+						}
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+			}
+
+			// Группа для корзины: клиент собирает услуги разных компаний и оформляет их одной оплатой
+			cartGroup := accountGroup.Group("cart")
+			{
+				cartGroup.POST("/get", func(c *gin.Context) {
+					request := &api.TokenAccess{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, mapClaims := security.CheckToken(request.User.Login.Token)
+					if mapClaims == nil {
+						api.GetErrorJSON(c, http.StatusBadRequest, "The token is invalid")
+						return
+					}
+					if ok {
+						isCompany := mapClaims["isCompany"].(bool)
+						if !isCompany {
+							cartController.GetCart(c, request)
+						} else {
+							api.GetErrorJSON(c, http.StatusForbidden, "Only clients can use the cart")
+							return
+						}
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+
+				cartGroup.POST("/add", func(c *gin.Context) {
+					request := &api.TokenCartItem{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, mapClaims := security.CheckToken(request.TokenAccess.User.Login.Token)
+					if mapClaims == nil {
+						api.GetErrorJSON(c, http.StatusBadRequest, "The token is invalid")
+						return
+					}
+					if ok {
+						isCompany := mapClaims["isCompany"].(bool)
+						if !isCompany {
+							cartController.AddItem(c, request)
+						} else {
+							api.GetErrorJSON(c, http.StatusForbidden, "Only clients can use the cart")
+							return
+						}
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+
+				cartGroup.POST("/update", func(c *gin.Context) {
+					request := &api.TokenCartItem{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, mapClaims := security.CheckToken(request.TokenAccess.User.Login.Token)
+					if mapClaims == nil {
+						api.GetErrorJSON(c, http.StatusBadRequest, "The token is invalid")
+						return
+					}
+					if ok {
+						isCompany := mapClaims["isCompany"].(bool)
+						if !isCompany {
+							cartController.UpdateItem(c, request)
+						} else {
+							api.GetErrorJSON(c, http.StatusForbidden, "Only clients can use the cart")
+							return
+						}
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+
+				cartGroup.POST("/remove", func(c *gin.Context) {
+					request := &api.TokenCartItem{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, mapClaims := security.CheckToken(request.TokenAccess.User.Login.Token)
+					if mapClaims == nil {
+						api.GetErrorJSON(c, http.StatusBadRequest, "The token is invalid")
+						return
+					}
+					if ok {
+						isCompany := mapClaims["isCompany"].(bool)
+						if !isCompany {
+							cartController.RemoveItem(c, request)
+						} else {
+							api.GetErrorJSON(c, http.StatusForbidden, "Only clients can use the cart")
+							return
+						}
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+
+				cartGroup.POST("/clear", func(c *gin.Context) {
+					request := &api.TokenAccess{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, mapClaims := security.CheckToken(request.User.Login.Token)
+					if mapClaims == nil {
+						api.GetErrorJSON(c, http.StatusBadRequest, "The token is invalid")
+						return
+					}
+					if ok {
+						isCompany := mapClaims["isCompany"].(bool)
+						if !isCompany {
+							cartController.ClearCart(c, request)
+						} else {
+							api.GetErrorJSON(c, http.StatusForbidden, "Only clients can use the cart")
+							return
+						}
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+
+				cartGroup.POST("/checkout", controller.RateLimitByIP(limiter, "checkout", moneyIPLimit), controller.RateLimitByAccount(limiter, "checkout", moneyLimit), func(c *gin.Context) {
+					request := &api.TokenCartCheckout{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, mapClaims := security.CheckToken(request.TokenAccess.User.Login.Token)
+					if mapClaims == nil {
+						api.GetErrorJSON(c, http.StatusBadRequest, "The token is invalid")
+						return
+					}
+					if ok {
+						isCompany := mapClaims["isCompany"].(bool)
+						if !isCompany {
+							cartController.Checkout(c, request)
+						} else {
+							api.GetErrorJSON(c, http.StatusForbidden, "Only clients can use the cart")
+							return
+						}
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+			}
+
+			// Группа для повторяющихся заказов: клиенты управляют расписаниями, компании видят ближайшие занятия
+			recurringGroup := accountGroup.Group("recurring")
+			{
+				recurringGroup.POST("/create", func(c *gin.Context) {
+					request := &api.TokenRecurringOrder{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, mapClaims := security.CheckToken(request.TokenAccess.User.Login.Token)
+					if mapClaims == nil {
+						api.GetErrorJSON(c, http.StatusBadRequest, "The token is invalid")
+						return
+					}
+					if ok {
+						isCompany := mapClaims["isCompany"].(bool)
+						if !isCompany {
+							recurringOrderController.CreateRecurringOrder(c, request)
+						} else {
+							api.GetErrorJSON(c, http.StatusForbidden, "Only clients can create recurring orders")
+							return
+						}
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+
+				recurringGroup.POST("/list", func(c *gin.Context) {
+					request := &api.TokenAccess{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, _ := security.CheckToken(request.User.Login.Token)
+					if ok {
+						recurringOrderController.GetRecurringOrders(c, request)
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+
+				recurringGroup.POST("/upcoming", func(c *gin.Context) {
+					request := &api.TokenRecurringUpcoming{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, _ := security.CheckToken(request.TokenAccess.User.Login.Token)
+					if ok {
+						recurringOrderController.GetUpcoming(c, request)
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+
+				recurringGroup.POST("/pause", func(c *gin.Context) {
+					request := &api.TokenRecurringAction{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, mapClaims := security.CheckToken(request.TokenAccess.User.Login.Token)
+					if mapClaims == nil {
+						api.GetErrorJSON(c, http.StatusBadRequest, "The token is invalid")
+						return
+					}
+					if ok {
+						isCompany := mapClaims["isCompany"].(bool)
+						if !isCompany {
+							recurringOrderController.PauseRecurringOrder(c, request)
+						} else {
+							api.GetErrorJSON(c, http.StatusForbidden, "Only clients can manage recurring orders")
+							return
+						}
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+
+				recurringGroup.POST("/resume", func(c *gin.Context) {
+					request := &api.TokenRecurringAction{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, mapClaims := security.CheckToken(request.TokenAccess.User.Login.Token)
+					if mapClaims == nil {
+						api.GetErrorJSON(c, http.StatusBadRequest, "The token is invalid")
+						return
+					}
+					if ok {
+						isCompany := mapClaims["isCompany"].(bool)
+						if !isCompany {
+							recurringOrderController.ResumeRecurringOrder(c, request)
+						} else {
+							api.GetErrorJSON(c, http.StatusForbidden, "Only clients can manage recurring orders")
+							return
+						}
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+
+				recurringGroup.POST("/cancel", func(c *gin.Context) {
+					request := &api.TokenRecurringAction{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, mapClaims := security.CheckToken(request.TokenAccess.User.Login.Token)
+					if mapClaims == nil {
+						api.GetErrorJSON(c, http.StatusBadRequest, "The token is invalid")
+						return
+					}
+					if ok {
+						isCompany := mapClaims["isCompany"].(bool)
+						if !isCompany {
+							recurringOrderController.CancelRecurringOrder(c, request)
+						} else {
+							api.GetErrorJSON(c, http.StatusForbidden, "Only clients can manage recurring orders")
+							return
+						}
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+
+				recurringGroup.POST("/skip", func(c *gin.Context) {
+					request := &api.TokenRecurringAction{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, mapClaims := security.CheckToken(request.TokenAccess.User.Login.Token)
+					if mapClaims == nil {
+						api.GetErrorJSON(c, http.StatusBadRequest, "The token is invalid")
+						return
+					}
+					if ok {
+						isCompany := mapClaims["isCompany"].(bool)
+						if !isCompany {
+							recurringOrderController.SkipOccurrence(c, request)
+						} else {
+							api.GetErrorJSON(c, http.StatusForbidden, "Only clients can manage recurring orders")
+							return
+						}
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+
+				recurringGroup.POST("/autopay", func(c *gin.Context) {
+					request := &api.TokenRecurringAction{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, mapClaims := security.CheckToken(request.TokenAccess.User.Login.Token)
+					if mapClaims == nil {
+						api.GetErrorJSON(c, http.StatusBadRequest, "The token is invalid")
+						return
+					}
+					if ok {
+						isCompany := mapClaims["isCompany"].(bool)
+						if !isCompany {
+							recurringOrderController.SetAutoPay(c, request)
+						} else {
+							api.GetErrorJSON(c, http.StatusForbidden, "Only clients can manage recurring orders")
+							return
+						}
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+			}
+
+			// Группа для промокодов: компании управляют своими, клиенты проверяют скидку
+			promoGroup := accountGroup.Group("promo", operationsRoles)
+			{
+				promoGroup.POST("/list", func(c *gin.Context) {
+					request := &api.TokenAccess{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, mapClaims := security.CheckToken(request.User.Login.Token)
+					if mapClaims == nil {
+						api.GetErrorJSON(c, http.StatusBadRequest, "The token is invalid")
+						return
+					}
+					if ok {
+						isCompany := mapClaims["isCompany"].(bool)
+						if isCompany {
+							promoController.ListCompanyPromoCodes(c, request)
+						} else {
+							api.GetErrorJSON(c, http.StatusForbidden, "Only companies can manage promo codes")
+							return
+						}
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+
+				promoGroup.POST("/create", func(c *gin.Context) {
+					request := &api.TokenPromoCode{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, mapClaims := security.CheckToken(request.TokenAccess.User.Login.Token)
+					if mapClaims == nil {
+						api.GetErrorJSON(c, http.StatusBadRequest, "The token is invalid")
+						return
+					}
+					if ok {
+						isCompany := mapClaims["isCompany"].(bool)
+						if isCompany {
+							promoController.CreateCompanyPromoCode(c, request)
+						} else {
+							api.GetErrorJSON(c, http.StatusForbidden, "Only companies can manage promo codes")
+							return
+						}
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+
+				promoGroup.POST("/update", func(c *gin.Context) {
+					request := &api.TokenPromoCode{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, mapClaims := security.CheckToken(request.TokenAccess.User.Login.Token)
+					if mapClaims == nil {
+						api.GetErrorJSON(c, http.StatusBadRequest, "The token is invalid")
+						return
+					}
+					if ok {
+						isCompany := mapClaims["isCompany"].(bool)
+						if isCompany {
+							promoController.UpdateCompanyPromoCode(c, request)
+						} else {
+							api.GetErrorJSON(c, http.StatusForbidden, "Only companies can manage promo codes")
+							return
+						}
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+
+				promoGroup.POST("/check", controller.RateLimitByIP(limiter, "promo_check", moneyIPLimit), controller.RateLimitByAccount(limiter, "promo_check", moneyLimit), func(c *gin.Context) {
+					request := &api.TokenCheckPromoCode{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, mapClaims := security.CheckToken(request.TokenAccess.User.Login.Token)
+					if mapClaims == nil {
+						api.GetErrorJSON(c, http.StatusBadRequest, "The token is invalid")
+						return
+					}
+					if ok {
+						isCompany := mapClaims["isCompany"].(bool)
+						if !isCompany {
+							promoController.CheckPromoCode(c, request)
+						} else {
+							api.GetErrorJSON(c, http.StatusForbidden, "Only clients can use promo codes")
+							return
+						}
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+			}
+
+			// Группа для реферальной программы клиентов
+			referralGroup := accountGroup.Group("referral")
+			{
+				referralGroup.POST("/code", func(c *gin.Context) {
+					request := &api.TokenAccess{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, mapClaims := security.CheckToken(request.User.Login.Token)
+					if mapClaims == nil {
+						api.GetErrorJSON(c, http.StatusBadRequest, "The token is invalid")
+						return
+					}
+					if ok {
+						isCompany := mapClaims["isCompany"].(bool)
+						if !isCompany {
+							promoController.GetReferral(c, request)
+						} else {
+							api.GetErrorJSON(c, http.StatusForbidden, "Only clients can invite other clients")
+							return
+						}
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+
+				referralGroup.POST("/apply", controller.RateLimitByIP(limiter, "referral_apply", moneyIPLimit), controller.RateLimitByAccount(limiter, "referral_apply", moneyLimit), func(c *gin.Context) {
+					request := &api.TokenApplyReferral{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, mapClaims := security.CheckToken(request.TokenAccess.User.Login.Token)
+					if mapClaims == nil {
+						api.GetErrorJSON(c, http.StatusBadRequest, "The token is invalid")
+						return
+					}
+					if ok {
+						isCompany := mapClaims["isCompany"].(bool)
+						if !isCompany {
+							promoController.ApplyReferral(c, request)
+						} else {
+							api.GetErrorJSON(c, http.StatusForbidden, "Only clients can use referral codes")
+							return
+						}
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+			}
+
+			// Группа для расписания компании
+			scheduleGroup := accountGroup.Group("schedule", operationsRoles)
+			{
+				scheduleGroup.POST("/", func(c *gin.Context) {
+					request := &api.TokenAccess{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, mapClaims := security.CheckToken(request.User.Login.Token)
+					if mapClaims == nil {
+						api.GetErrorJSON(c, http.StatusBadRequest, "The token is invalid")
+						return
+					}
+					if ok {
+						isCompany := mapClaims["isCompany"].(bool)
+						if isCompany {
+							scheduleController.GetSchedule(c, request)
+						} else {
+							api.GetErrorJSON(c, http.StatusForbidden, "Only companies can manage schedule")
+							return
+						}
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+
+				scheduleGroup.POST("/hours", func(c *gin.Context) {
+					request := &api.TokenSetWorkingHours{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, mapClaims := security.CheckToken(request.TokenAccess.User.Login.Token)
+					if mapClaims == nil {
+						api.GetErrorJSON(c, http.StatusBadRequest, "The token is invalid")
+						return
+					}
+					if ok {
+						isCompany := mapClaims["isCompany"].(bool)
+						if isCompany {
+							scheduleController.SetWorkingHours(c, request)
+						} else {
+							api.GetErrorJSON(c, http.StatusForbidden, "Only companies can manage schedule")
+							return
+						}
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+
+				scheduleGroup.POST("/blackout/add", func(c *gin.Context) {
+					request := &api.TokenAddBlackoutDate{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, mapClaims := security.CheckToken(request.TokenAccess.User.Login.Token)
+					if mapClaims == nil {
+						api.GetErrorJSON(c, http.StatusBadRequest, "The token is invalid")
+						return
+					}
+					if ok {
+						isCompany := mapClaims["isCompany"].(bool)
+						if isCompany {
+							scheduleController.AddBlackoutDate(c, request)
+						} else {
+							api.GetErrorJSON(c, http.StatusForbidden, "Only companies can manage schedule")
+							return
+						}
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+
+				scheduleGroup.POST("/blackout/delete", func(c *gin.Context) {
+					request := &api.TokenDeleteBlackoutDate{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, mapClaims := security.CheckToken(request.TokenAccess.User.Login.Token)
+					if mapClaims == nil {
+						api.GetErrorJSON(c, http.StatusBadRequest, "The token is invalid")
+						return
+					}
+					if ok {
+						isCompany := mapClaims["isCompany"].(bool)
+						if isCompany {
+							scheduleController.DeleteBlackoutDate(c, request)
+						} else {
+							api.GetErrorJSON(c, http.StatusForbidden, "Only companies can manage schedule")
+							return
+						}
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+
+				scheduleGroup.POST("/calendar.ics", func(c *gin.Context) {
+					request := &api.TokenAccess{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, mapClaims := security.CheckToken(request.User.Login.Token)
+					if mapClaims == nil {
+						api.GetErrorJSON(c, http.StatusBadRequest, "The token is invalid")
+						return
+					}
+					if ok {
+						isCompany := mapClaims["isCompany"].(bool)
+						if isCompany {
+							scheduleController.ExportCalendar(c, request)
+						} else {
+							api.GetErrorJSON(c, http.StatusForbidden, "Only companies can export calendar")
+							return
+						}
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+			}
+
+			// Избранное клиента
+			favoriteGroup := accountGroup.Group("favorite")
+			{
+				favoriteGroup.POST("/add", func(c *gin.Context) {
+					request := &api.TokenFavorite{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, mapClaims := security.CheckToken(request.TokenAccess.User.Login.Token)
+					if mapClaims == nil {
+						api.GetErrorJSON(c, http.StatusBadRequest, "The token is invalid")
+						return
+					}
+					if ok {
+						isCompany := mapClaims["isCompany"].(bool)
+						if !isCompany {
+							favoriteController.AddFavorite(c, request)
+						} else {
+							api.GetErrorJSON(c, http.StatusForbidden, "Only clients can use favorites")
+							return
+						}
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+
+				favoriteGroup.POST("/remove", func(c *gin.Context) {
+					request := &api.TokenFavorite{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, mapClaims := security.CheckToken(request.TokenAccess.User.Login.Token)
+					if mapClaims == nil {
+						api.GetErrorJSON(c, http.StatusBadRequest, "The token is invalid")
+						return
+					}
+					if ok {
+						isCompany := mapClaims["isCompany"].(bool)
+						if !isCompany {
+							favoriteController.RemoveFavorite(c, request)
+						} else {
+							api.GetErrorJSON(c, http.StatusForbidden, "Only clients can use favorites")
+							return
+						}
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+
+				favoriteGroup.POST("/list", func(c *gin.Context) {
+					request := &api.TokenAccess{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, mapClaims := security.CheckToken(request.User.Login.Token)
+					if mapClaims == nil {
+						api.GetErrorJSON(c, http.StatusBadRequest, "The token is invalid")
+						return
+					}
+					if ok {
+						isCompany := mapClaims["isCompany"].(bool)
+						if !isCompany {
+							favoriteController.ListFavorites(c, request)
+						} else {
+							api.GetErrorJSON(c, http.StatusForbidden, "Only clients can use favorites")
+							return
+						}
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+			}
+
+			// Сохраненные поиски клиента
+			savedSearchGroup := accountGroup.Group("saved-search")
+			{
+				savedSearchGroup.POST("/create", func(c *gin.Context) {
+					request := &api.TokenSavedSearch{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, mapClaims := security.CheckToken(request.TokenAccess.User.Login.Token)
+					if mapClaims == nil {
+						api.GetErrorJSON(c, http.StatusBadRequest, "The token is invalid")
+						return
+					}
+					if ok {
+						isCompany := mapClaims["isCompany"].(bool)
+						if !isCompany {
+							favoriteController.CreateSavedSearch(c, request)
+						} else {
+							api.GetErrorJSON(c, http.StatusForbidden, "Only clients can save searches")
+							return
+						}
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+
+				savedSearchGroup.POST("/update", func(c *gin.Context) {
+					request := &api.TokenSavedSearch{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, mapClaims := security.CheckToken(request.TokenAccess.User.Login.Token)
+					if mapClaims == nil {
+						api.GetErrorJSON(c, http.StatusBadRequest, "The token is invalid")
+						return
+					}
+					if ok {
+						isCompany := mapClaims["isCompany"].(bool)
+						if !isCompany {
+							favoriteController.UpdateSavedSearch(c, request)
+						} else {
+							api.GetErrorJSON(c, http.StatusForbidden, "Only clients can save searches")
+							return
+						}
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+
+				savedSearchGroup.POST("/delete", func(c *gin.Context) {
+					request := &api.TokenSavedSearch{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, mapClaims := security.CheckToken(request.TokenAccess.User.Login.Token)
+					if mapClaims == nil {
+						api.GetErrorJSON(c, http.StatusBadRequest, "The token is invalid")
+						return
+					}
+					if ok {
+						isCompany := mapClaims["isCompany"].(bool)
+						if !isCompany {
+							favoriteController.DeleteSavedSearch(c, request)
+						} else {
+							api.GetErrorJSON(c, http.StatusForbidden, "Only clients can save searches")
+							return
+						}
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+
+				savedSearchGroup.POST("/list", func(c *gin.Context) {
+					request := &api.TokenAccess{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, mapClaims := security.CheckToken(request.User.Login.Token)
+					if mapClaims == nil {
+						api.GetErrorJSON(c, http.StatusBadRequest, "The token is invalid")
+						return
+					}
+					if ok {
+						isCompany := mapClaims["isCompany"].(bool)
+						if !isCompany {
+							favoriteController.ListSavedSearches(c, request)
+						} else {
+							api.GetErrorJSON(c, http.StatusForbidden, "Only clients can save searches")
+							return
+						}
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+			}
+
+			// Группа для работников компании
+			companyWorkerGroup := accountGroup.Group("worker", operationsRoles)
+			{
+				companyWorkerGroup.POST("/create", func(c *gin.Context) {
+					request := &api.TokenCreateWorker{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, mapClaims := security.CheckToken(request.TokenAccess.User.Login.Token)
+					if mapClaims == nil {
+						api.GetErrorJSON(c, http.StatusBadRequest, "The token is invalid")
+						return
+					}
+					if ok {
+						isCompany := mapClaims["isCompany"].(bool)
+						if isCompany {
+							workerController.CreateWorker(c, request)
+						} else {
+							api.GetErrorJSON(c, http.StatusForbidden, "Only companies can manage workers")
+							return
+						}
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+
+				companyWorkerGroup.POST("/list", func(c *gin.Context) {
+					request := &api.TokenAccess{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, mapClaims := security.CheckToken(request.User.Login.Token)
+					if mapClaims == nil {
+						api.GetErrorJSON(c, http.StatusBadRequest, "The token is invalid")
+						return
+					}
+					if ok {
+						isCompany := mapClaims["isCompany"].(bool)
+						if isCompany {
+							workerController.ListWorkers(c, request)
+						} else {
+							api.GetErrorJSON(c, http.StatusForbidden, "Only companies can manage workers")
+							return
+						}
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+
+				companyWorkerGroup.POST("/deactivate", func(c *gin.Context) {
+					request := &api.TokenWorkerAction{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, mapClaims := security.CheckToken(request.TokenAccess.User.Login.Token)
+					if mapClaims == nil {
+						api.GetErrorJSON(c, http.StatusBadRequest, "The token is invalid")
+						return
+					}
+					if ok {
+						isCompany := mapClaims["isCompany"].(bool)
+						if isCompany {
+							workerController.DeactivateWorker(c, request)
+						} else {
+							api.GetErrorJSON(c, http.StatusForbidden, "Only companies can manage workers")
+							return
+						}
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+			}
+
+			// Дополнительные маршруты для заказов
+			orderGroup.POST("/update-status", operationsRoles, func(c *gin.Context) {
+				request := &api.TokenOrderAction{}
+				if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+					api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+					return
+				}
+				ok, _ := security.CheckToken(request.TokenAccess.User.Login.Token)
+				if ok {
+					orderController.UpdateOrderStatus(c, request)
+				} else {
+					api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+					return
+				}
+			})
+
+			orderGroup.POST("/assign", operationsRoles, func(c *gin.Context) {
+				request := &api.TokenAssignOrder{}
+				if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+					api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+					return
+				}
+				ok, mapClaims := security.CheckToken(request.TokenAccess.User.Login.Token)
+				if mapClaims == nil {
+					api.GetErrorJSON(c, http.StatusBadRequest, "The token is invalid")
+					return
+				}
+				if ok {
+					isCompany := mapClaims["isCompany"].(bool)
+					if isCompany {
+						workerController.AssignOrder(c, request)
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "Only companies can assign orders")
+						return
+					}
+				} else {
+					api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+					return
+				}
+			})
+
+			orderGroup.POST("/worker-link/reissue", operationsRoles, func(c *gin.Context) {
+				request := &api.TokenWorkerLinkAction{}
+				if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+					api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+					return
+				}
+				ok, mapClaims := security.CheckToken(request.TokenAccess.User.Login.Token)
+				if mapClaims == nil {
+					api.GetErrorJSON(c, http.StatusBadRequest, "The token is invalid")
+					return
+				}
+				if ok {
+					isCompany := mapClaims["isCompany"].(bool)
+					if isCompany {
+						workerLinkController.ReissueLink(c, request)
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "Only companies can manage worker links")
+						return
+					}
+				} else {
+					api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+					return
+				}
+			})
+
+			orderGroup.POST("/worker-link/revoke", operationsRoles, func(c *gin.Context) {
+				request := &api.TokenWorkerLinkAction{}
+				if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+					api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+					return
+				}
+				ok, mapClaims := security.CheckToken(request.TokenAccess.User.Login.Token)
+				if mapClaims == nil {
+					api.GetErrorJSON(c, http.StatusBadRequest, "The token is invalid")
+					return
+				}
+				if ok {
+					isCompany := mapClaims["isCompany"].(bool)
+					if isCompany {
+						workerLinkController.RevokeLink(c, request)
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "Only companies can manage worker links")
+						return
+					}
+				} else {
+					api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+					return
+				}
+			})
+
+			orderGroup.POST("/worker-link/list", operationsRoles, func(c *gin.Context) {
+				request := &api.TokenWorkerLinkAction{}
+				if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+					api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+					return
+				}
+				ok, mapClaims := security.CheckToken(request.TokenAccess.User.Login.Token)
+				if mapClaims == nil {
+					api.GetErrorJSON(c, http.StatusBadRequest, "The token is invalid")
+					return
+				}
+				if ok {
+					isCompany := mapClaims["isCompany"].(bool)
+					if isCompany {
+						workerLinkController.ListLinks(c, request)
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "Only companies can manage worker links")
+						return
+					}
+				} else {
+					api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+					return
+				}
+			})
+		}
+
+		// Эндпоинты для работников компании (токен работника)
+		workerGroup := v1.Group("worker")
+		{
+			workerGroup.POST("/login", controller.RateLimitByIP(limiter, "login", loginLimit), func(c *gin.Context) {
+				workerController.Login(c)
+			})
+
+			workerGroup.POST("/tasks", func(c *gin.Context) {
+				request := &api.TokenAccess{}
+				if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+					api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+					return
+				}
+				ok, _ := security.CheckWorkerToken(request.User.Login.Token)
+				if ok {
+					workerController.GetTasks(c, request)
+				} else {
+					api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+					return
+				}
+			})
+
+			workerGroup.POST("/check-in", func(c *gin.Context) {
+				request := &api.TokenOrderAction{}
+				if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+					api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+					return
+				}
+				ok, _ := security.CheckWorkerToken(request.TokenAccess.User.Login.Token)
+				if ok {
+					workerController.CheckIn(c, request)
+				} else {
+					api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+					return
+				}
+			})
+
+			workerGroup.POST("/check-out", func(c *gin.Context) {
+				request := &api.TokenOrderAction{}
+				if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+					api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+					return
+				}
+				ok, _ := security.CheckWorkerToken(request.TokenAccess.User.Login.Token)
+				if ok {
+					workerController.CheckOut(c, request)
+				} else {
+					api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+					return
+				}
+			})
+
+			workerGroup.POST("/complete", func(c *gin.Context) {
+				request := &api.TokenWorkerComplete{}
+				if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+					api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+					return
+				}
+				ok, _ := security.CheckWorkerToken(request.TokenAccess.User.Login.Token)
+				if ok {
+					workerController.CompleteOrder(c, request)
+				} else {
+					api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+					return
+				}
+			})
+		}
+		// Эндпоинты администраторов платформы (токен администратора)
+		adminGroup := v1.Group("admin")
+		{
+			adminGroup.POST("/login", controller.RateLimitByIP(limiter, "login", loginLimit), func(c *gin.Context) {
+				adminController.Login(c)
+			})
+
+			// Справочник категорий
+			adminCategoryGroup := adminGroup.Group("category")
+			{
+				adminCategoryGroup.POST("/list", func(c *gin.Context) {
+					request := &api.TokenAccess{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, _ := security.CheckAdminToken(request.User.Login.Token)
+					if ok {
+						categoryController.AdminGetTree(c, request)
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+
+				adminCategoryGroup.POST("/create", func(c *gin.Context) {
+					request := &api.TokenAdminCategory{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, _ := security.CheckAdminToken(request.TokenAccess.User.Login.Token)
+					if ok {
+						categoryController.CreateCategory(c, request)
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+
+				adminCategoryGroup.POST("/update", func(c *gin.Context) {
+					request := &api.TokenAdminCategory{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, _ := security.CheckAdminToken(request.TokenAccess.User.Login.Token)
+					if ok {
+						categoryController.UpdateCategory(c, request)
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+
+				adminCategoryGroup.POST("/delete", func(c *gin.Context) {
+					request := &api.TokenAdminCategory{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, _ := security.CheckAdminToken(request.TokenAccess.User.Login.Token)
+					if ok {
+						categoryController.DeleteCategory(c, request)
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+			}
+
+			// Модерация отзывов
+			adminReviewGroup := adminGroup.Group("review")
+			{
+				adminReviewGroup.POST("/queue", func(c *gin.Context) {
+					request := &api.TokenModerationQueue{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, _ := security.CheckAdminToken(request.TokenAccess.User.Login.Token)
+					if ok {
+						reviewController.GetModerationQueue(c, request)
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+
+				adminReviewGroup.POST("/hide", func(c *gin.Context) {
+					request := &api.TokenModerateReview{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, _ := security.CheckAdminToken(request.TokenAccess.User.Login.Token)
+					if ok {
+						reviewController.HideReview(c, request)
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+
+				adminReviewGroup.POST("/restore", func(c *gin.Context) {
+					request := &api.TokenModerateReview{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, _ := security.CheckAdminToken(request.TokenAccess.User.Login.Token)
+					if ok {
+						reviewController.RestoreReview(c, request)
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+
+				adminReviewGroup.POST("/dismiss", func(c *gin.Context) {
+					request := &api.TokenModerateReview{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, _ := security.CheckAdminToken(request.TokenAccess.User.Login.Token)
+					if ok {
+						reviewController.DismissReport(c, request)
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+			}
+
+			// Проверка документов компаний
+			adminCompanyGroup := adminGroup.Group("company")
+			{
+				adminCompanyGroup.POST("/verification/queue", func(c *gin.Context) {
+					request := &api.TokenCompanyVerificationQueue{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, _ := security.CheckAdminToken(request.TokenAccess.User.Login.Token)
+					if ok {
+						companyController.AdminVerificationQueue(c, request)
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+
+				adminCompanyGroup.POST("/verify", func(c *gin.Context) {
+					request := &api.TokenVerifyCompany{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, _ := security.CheckAdminToken(request.TokenAccess.User.Login.Token)
+					if ok {
+						companyController.AdminVerify(c, request)
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+
+				adminCompanyGroup.POST("/unverify", func(c *gin.Context) {
+					request := &api.TokenVerifyCompany{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, _ := security.CheckAdminToken(request.TokenAccess.User.Login.Token)
+					if ok {
+						companyController.AdminUnverify(c, request)
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+			}
+
+			// Комиссии платформы
+			adminFeeGroup := adminGroup.Group("fee")
+			{
+				adminFeeGroup.POST("/list", func(c *gin.Context) {
+					request := &api.TokenAccess{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, _ := security.CheckAdminToken(request.User.Login.Token)
+					if ok {
+						feeController.ListRules(c, request)
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+
+				adminFeeGroup.POST("/create", func(c *gin.Context) {
+					request := &api.TokenAdminFeeRule{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, _ := security.CheckAdminToken(request.TokenAccess.User.Login.Token)
+					if ok {
+						feeController.CreateRule(c, request)
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+
+				adminFeeGroup.POST("/update", func(c *gin.Context) {
+					request := &api.TokenAdminFeeRule{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, _ := security.CheckAdminToken(request.TokenAccess.User.Login.Token)
+					if ok {
+						feeController.UpdateRule(c, request)
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+
+				adminFeeGroup.POST("/delete", func(c *gin.Context) {
+					request := &api.TokenAdminFeeRule{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, _ := security.CheckAdminToken(request.TokenAccess.User.Login.Token)
+					if ok {
+						feeController.DeleteRule(c, request)
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+
+				adminFeeGroup.POST("/tier", func(c *gin.Context) {
+					request := &api.TokenAdminCompanyTier{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, _ := security.CheckAdminToken(request.TokenAccess.User.Login.Token)
+					if ok {
+						feeController.SetCompanyTier(c, request)
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+			}
+
+			// Промокоды
+			adminPromoGroup := adminGroup.Group("promo")
+			{
+				adminPromoGroup.POST("/list", func(c *gin.Context) {
+					request := &api.TokenAccess{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, _ := security.CheckAdminToken(request.User.Login.Token)
+					if ok {
+						promoController.AdminListPromoCodes(c, request)
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+
+				adminPromoGroup.POST("/create", func(c *gin.Context) {
+					request := &api.TokenPromoCode{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, _ := security.CheckAdminToken(request.TokenAccess.User.Login.Token)
+					if ok {
+						promoController.AdminCreatePromoCode(c, request)
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+
+				adminPromoGroup.POST("/update", func(c *gin.Context) {
+					request := &api.TokenPromoCode{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, _ := security.CheckAdminToken(request.TokenAccess.User.Login.Token)
+					if ok {
+						promoController.AdminUpdatePromoCode(c, request)
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+
+				adminPromoGroup.POST("/delete", func(c *gin.Context) {
+					request := &api.TokenPromoCode{}
+					if err := c.ShouldBind(request); err != nil && errors.As(err, &validator.ValidationErrors{}) {
+						api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid")
+						return
+					}
+					ok, _ := security.CheckAdminToken(request.TokenAccess.User.Login.Token)
+					if ok {
+						promoController.AdminDeletePromoCode(c, request)
+					} else {
+						api.GetErrorJSON(c, http.StatusForbidden, "The token had expired")
+						return
+					}
+				})
+			}
+		}
+		registerGroup := v1.Group("register")
+		{
+			registerGroup.POST("/client", controller.RateLimitByIP(limiter, "register", registerLimit), func(c *gin.Context) {
+				clientController.Signup(c)
+			})
+			registerGroup.POST("/company", controller.RateLimitByIP(limiter, "register", registerLimit), func(c *gin.Context) {
+				companyController.Signup(c)
+			})
+		}
+
+		// Подтверждение email, восстановление пароля и второй шаг входа (без авторизации)
+		authGroup := v1.Group("auth", controller.RateLimitByIP(limiter, "auth", authLimit))
+		{
+			authGroup.POST("/verify-email", func(c *gin.Context) {
+				accountController.VerifyEmail(c)
+			})
+			authGroup.POST("/resend-verification", func(c *gin.Context) {
+				accountController.ResendVerification(c)
+			})
+			authGroup.POST("/forgot-password", func(c *gin.Context) {
+				accountController.ForgotPassword(c)
+			})
+			authGroup.POST("/reset-password", func(c *gin.Context) {
+				accountController.ResetPassword(c)
+			})
+			authGroup.POST("/confirm-email", func(c *gin.Context) {
+				accountController.ConfirmEmailChange(c)
+			})
+			// Второй шаг входа при включенной двухфакторной аутентификации
+			authGroup.POST("/two-factor", func(c *gin.Context) {
+				twoFactorController.CompleteLogin(c)
+			})
+			// Вход через внешних OIDC-провайдеров
+			authGroup.GET("/oidc/providers", func(c *gin.Context) {
+				oidcController.Providers(c)
+			})
+			authGroup.POST("/oidc/start", func(c *gin.Context) {
+				oidcController.StartLogin(c)
+			})
+			authGroup.POST("/oidc/callback", func(c *gin.Context) {
+				oidcController.CompleteLogin(c)
 			})
 		}
 	}
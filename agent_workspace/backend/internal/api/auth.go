@@ -1,6 +1,11 @@
 package api
 
-import "core/internal"
+import (
+	"core/internal"
+	"core/internal/money"
+	"core/internal/pagination"
+	"time"
+)
 
 // Простые структуры для регистрации
 type ClientRegisterRequest struct {
@@ -27,6 +32,96 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
+// EmailRequest запрос письма по адресу: повторное подтверждение email или сброс пароля
+type EmailRequest struct {
+	Email string `json:"email" binding:"required"`
+}
+
+// AuthTokenRequest токен из ссылки в письме
+type AuthTokenRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+type TokenChangePassword struct {
+	TokenAccess     TokenAccess `json:"token_access"`
+	CurrentPassword string      `json:"current_password"`
+	NewPassword     string      `json:"new_password"`
+}
+
+type TokenChangeEmail struct {
+	TokenAccess     TokenAccess `json:"token_access"`
+	CurrentPassword string      `json:"current_password"`
+	NewEmail        string      `json:"new_email"`
+}
+
+// TwoFactorLoginRequest второй шаг входа: challenge из ответа на пароль и код из приложения
+// или резервный код. При trust_device в ответе приходит device_token для следующих входов
+type TwoFactorLoginRequest struct {
+	Challenge   string `json:"challenge" binding:"required"`
+	Code        string `json:"code" binding:"required"`
+	TrustDevice bool   `json:"trust_device"`
+	DeviceName  string `json:"device_name"`
+	AccountType string `json:"account_type"` // Роль, в которой выдается токен, см. TokenSwitchAccount
+	CompanyID   uint   `json:"company_id"`
+}
+
+type TokenTwoFactorSetup struct {
+	TokenAccess TokenAccess `json:"token_access"`
+	Password    string      `json:"password"`
+}
+
+// TokenTwoFactorCode код из приложения или резервный код для действия с настройками 2FA
+type TokenTwoFactorCode struct {
+	TokenAccess TokenAccess `json:"token_access"`
+	Code        string      `json:"code"`
+}
+
+type TokenTwoFactorDisable struct {
+	TokenAccess TokenAccess `json:"token_access"`
+	Password    string      `json:"password"`
+	Code        string      `json:"code"`
+}
+
+type TokenTrustedDevice struct {
+	TokenAccess TokenAccess `json:"token_access"`
+	DeviceID    uint        `json:"device_id"`
+}
+
+type TwoFactorStatus struct {
+	Enabled           bool       `json:"enabled"`
+	Pending           bool       `json:"pending"` // Секрет выдан, но подключение не подтверждено кодом
+	EnabledAt         *time.Time `json:"enabled_at"`
+	RecoveryCodesLeft int64      `json:"recovery_codes_left"`
+}
+
+// TwoFactorSetup секрет для приложения-аутентификатора. URI кодируется в QR-код
+type TwoFactorSetup struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// PayoutDetails реквизиты компании для вывода средств
+type PayoutDetails struct {
+	Recipient string     `json:"recipient"`
+	INN       string     `json:"inn"`
+	BankName  string     `json:"bank_name"`
+	BIK       string     `json:"bik"`
+	Account   string     `json:"account"`
+	UpdatedAt *time.Time `json:"updated_at"`
+}
+
+// TokenUpdatePayoutDetails смена реквизитов. Если включена 2FA, нужен код two_factor_code
+type TokenUpdatePayoutDetails struct {
+	TokenAccess   TokenAccess   `json:"token_access"`
+	Details       PayoutDetails `json:"details"`
+	TwoFactorCode string        `json:"two_factor_code"`
+}
+
 // Старые структуры для обратной совместимости (можно удалить после переноса)
 type RegisterInfoPost struct {
 	FullName     string `json:"full_name"`
@@ -83,11 +178,12 @@ type TokenAccessDouble struct {
 type TokenCreateCard struct {
 	TokenAccess TokenAccess `json:"token_access"`
 	Card        struct {
-		Title       string  `json:"title"`
-		Description string  `json:"description"`
-		Category    string  `json:"category"`
-		Location    string  `json:"location"`
-		Price       float64 `json:"price"`
+		Title       string      `json:"title"`
+		Description string      `json:"description"`
+		Category    string      `json:"category"`
+		CategoryID  *uint       `json:"category_id"`
+		Location    string      `json:"location"`
+		Price       money.Minor `json:"price"`
 	} `json:"card"`
 }
 
@@ -135,14 +231,18 @@ type CompanyInfoPost struct {
 }
 
 type AccountInfo struct {
-	ID       uint    `json:"id"`
-	FullName string  `json:"full_name"`
-	Email    string  `json:"email"`
-	Phone    string  `json:"phone"`
-	Photo    string  `json:"photo"`
-	Token    string  `json:"token"`
-	Type     string  `json:"type"`
-	Balance  float64 `json:"balance"`
+	ID       uint        `json:"id"`
+	FullName string      `json:"full_name"`
+	Email    string      `json:"email"`
+	Phone    string      `json:"phone"`
+	Photo    string      `json:"photo"`
+	Token    string      `json:"token"`
+	Type     string      `json:"type"`
+	Balance  money.Minor `json:"balance"`
+
+	// Мультивалютность
+	Currency         string `json:"currency"`
+	BalanceFormatted string `json:"balance_formatted"` // По правилам локали из Accept-Language
 }
 
 type CompanyInfo struct {
@@ -155,7 +255,6 @@ type CompanyInfo struct {
 	IDCompany     string   `json:"id_company"`
 	Address       string   `json:"address"`
 	TypeService   string   `json:"type_service"`
-	PasswordHash  string   `json:"password_hash"`
 	Photo         string   `json:"photo"`
 	Documents     []string `json:"documents"`
 	Type          string   `json:"type"`
@@ -166,10 +265,22 @@ type ResponseSuccessAccess struct {
 	ResponseUser   ResponseUser            `json:"user"`
 }
 
+// ResponseUser учетная запись, от имени которой выдан токен. ID и Type - клиент или компания,
+// UserID, Role и CompanyID - пользователь и его активная роль
 type ResponseUser struct {
-	ID    uint   `json:"id"`
-	Token string `json:"token"`
-	Type  string `json:"type"`
+	ID        uint   `json:"id"`
+	Token     string `json:"token"`
+	Type      string `json:"type"`
+	UserID    uint   `json:"user_id,omitempty"`
+	Role      string `json:"role,omitempty"`
+	CompanyID uint   `json:"company_id,omitempty"`
+}
+
+// ResponseTwoFactorLogin ответ второго шага входа. DeviceToken передается при следующих
+// входах в заголовке X-Device-Token, чтобы не вводить код на доверенном устройстве
+type ResponseTwoFactorLogin struct {
+	ResponseSuccessAccess
+	DeviceToken string `json:"device_token,omitempty"`
 }
 
 type ResponseAccount struct {
@@ -211,28 +322,85 @@ type TokenUpdateClientProfileDouble struct {
 
 type TokenTopUpBalance struct {
 	TokenAccess TokenAccess `json:"token_access"`
-	Amount      float64     `json:"amount"`
+	Amount      money.Minor `json:"amount"`
+}
+
+type TokenSetCurrency struct {
+	TokenAccess TokenAccess `json:"token_access"`
+	Currency    string      `json:"currency"` // Код ISO 4217: RUB, USD, EUR, KZT, BYN, CNY
 }
 
 type TokenBalanceHistory struct {
 	TokenAccess TokenAccess `json:"token_access"`
 	Limit       int         `json:"limit"`
 	Offset      int         `json:"offset"`
+	Cursor      *string     `json:"cursor"` // Пустая строка - первая страница по курсору
 }
 
 type BalanceHistoryItem struct {
-	ID          uint    `json:"id"`
-	Amount      float64 `json:"amount"`
-	Type        string  `json:"type"`
-	Status      string  `json:"status"`
-	Description string  `json:"description"`
-	CreatedAt   string  `json:"created_at"`
+	ID          uint        `json:"id"`
+	Amount      money.Minor `json:"amount"`
+	Currency    string      `json:"currency"`
+	Type        string      `json:"type"`
+	Status      string      `json:"status"`
+	Description string      `json:"description"`
+	CreatedAt   string      `json:"created_at"`
+	OrderID     *uint       `json:"order_id"`
+}
+
+type TokenTransactionDocument struct {
+	TokenAccess   TokenAccess `json:"token_access"`
+	TransactionID uint        `json:"transaction_id"`
+}
+
+// DocumentInfo выпущенный документ со ссылкой на PDF, действующей ограниченное время
+type DocumentInfo struct {
+	ID            uint        `json:"id"`
+	Number        string      `json:"number"`
+	Type          string      `json:"type"` // invoice, receipt, payout_statement
+	OrderID       *uint       `json:"order_id,omitempty"`
+	TransactionID *uint       `json:"transaction_id,omitempty"`
+	Amount        money.Minor `json:"amount"`
+	Currency      string      `json:"currency"`
+	IssuedAt      string      `json:"issued_at"`
+	URL           string      `json:"url"`
+}
+
+type TokenExport struct {
+	TokenAccess TokenAccess `json:"token_access"`
+	Kind        string      `json:"kind"`   // balance, escrow, orders
+	Format      string      `json:"format"` // csv (по умолчанию), xlsx, ndjson
+	From        string      `json:"from"`   // YYYY-MM-DD
+	To          string      `json:"to"`     // YYYY-MM-DD включительно
+	Status      string      `json:"status"`
+}
+
+type TokenExportJob struct {
+	TokenAccess TokenAccess `json:"token_access"`
+	JobID       uint        `json:"job_id"`
+}
+
+// ExportJobInfo фоновая выгрузка. Ссылка на файл появляется, когда выгрузка готова
+type ExportJobInfo struct {
+	ID           uint    `json:"id"`
+	Kind         string  `json:"kind"`
+	Format       string  `json:"format"`
+	From         *string `json:"from"`
+	To           *string `json:"to"`
+	StatusFilter string  `json:"status_filter,omitempty"`
+	Status       string  `json:"status"` // pending, running, completed, failed
+	Rows         int64   `json:"rows"`
+	Error        string  `json:"error,omitempty"`
+	CreatedAt    string  `json:"created_at"`
+	CompletedAt  *string `json:"completed_at"`
+	URL          string  `json:"url,omitempty"`
 }
 
 type ResponseBalanceHistory struct {
 	StatusResponse internal.StatusResponse `json:"status_response"`
 	Transactions   []BalanceHistoryItem    `json:"transactions"`
 	Total          int                     `json:"total"`
+	Pagination     pagination.Page         `json:"pagination"`
 }
 
 // ================================
@@ -244,29 +412,80 @@ type TokenOrdersList struct {
 	Status      string      `json:"status"` // all, pending, in_progress, completed, cancelled
 	Limit       int         `json:"limit"`
 	Offset      int         `json:"offset"`
+	Cursor      *string     `json:"cursor"` // Пустая строка - первая страница по курсору
 }
 
 type OrderInfo struct {
-	ID            uint    `json:"id"`
-	ClientName    string  `json:"client_name"`
-	CompanyName   string  `json:"company_name"`
-	ServiceName   string  `json:"service_name"`
-	Description   string  `json:"description"`
-	Amount        float64 `json:"amount"`
-	Status        string  `json:"status"`
-	PaymentStatus string  `json:"payment_status"`
-	CreatedAt     string  `json:"created_at"`
-	CompletedAt   *string `json:"completed_at"`
-	WorkerURL     string  `json:"worker_url"`
-	CanCancel     bool    `json:"can_cancel"`
-	CanPay        bool    `json:"can_pay"`
-	CanRate       bool    `json:"can_rate"`
+	ID            uint        `json:"id"`
+	ClientName    string      `json:"client_name"`
+	CompanyName   string      `json:"company_name"`
+	ServiceName   string      `json:"service_name"`
+	Description   string      `json:"description"`
+	Amount        money.Minor `json:"amount"`
+	Status        string      `json:"status"`
+	PaymentStatus string      `json:"payment_status"`
+	CreatedAt     string      `json:"created_at"`
+	CompletedAt   *string     `json:"completed_at"`
+	ScheduledAt   *string     `json:"scheduled_at"`
+	ScheduledEnd  *string     `json:"scheduled_end"`
+	WorkerURL     string      `json:"worker_url"`
+	WorkerID      *uint       `json:"worker_id"`
+	WorkerName    string      `json:"worker_name,omitempty"`
+	CanCancel     bool        `json:"can_cancel"`
+	CanPay        bool        `json:"can_pay"`
+	CanRate       bool        `json:"can_rate"`
+
+	CompletionReport *CompletionReportInfo `json:"completion_report,omitempty"`
+	Fee              *FeeBreakdown         `json:"fee,omitempty"` // Только для компании
+
+	// Мультивалютность: валюта заказа и сумма списания с клиента по курсу на момент оплаты
+	Currency string       `json:"currency"`
+	Payment  *PaymentInfo `json:"payment,omitempty"`
+
+	// Скидки: сумма до скидки, размер скидки и строки заказа
+	Subtotal  money.Minor     `json:"subtotal"`
+	Discount  money.Minor     `json:"discount"`
+	LineItems []OrderLineItem `json:"line_items,omitempty"`
+
+	// Расписание повторяющегося заказа, по которому создан заказ
+	RecurringID *uint `json:"recurring_id,omitempty"`
+
+	// Оформление корзины, в котором заказ оплачен вместе с другими
+	CheckoutID *uint `json:"checkout_id,omitempty"`
+}
+
+// OrderLineItem строка заказа. Скидки имеют отрицательную сумму
+type OrderLineItem struct {
+	Type        string      `json:"type"` // service, discount
+	Description string      `json:"description"`
+	Amount      money.Minor `json:"amount"`
+	FundedBy    string      `json:"funded_by,omitempty"` // Для скидок: platform, company
+	CardID      *uint       `json:"card_id,omitempty"`
+	Quantity    int         `json:"quantity,omitempty"`
+}
+
+// PaymentInfo сумма оплаты заказа в валюте счета клиента и зафиксированный курс
+type PaymentInfo struct {
+	Currency     string      `json:"currency"`
+	Amount       money.Minor `json:"amount"`
+	ExchangeRate float64     `json:"exchange_rate"` // Единиц валюты оплаты за единицу валюты заказа
+	LockedAt     string      `json:"locked_at"`
+}
+
+// FeeBreakdown комиссия платформы по заказу и сумма к зачислению компании
+type FeeBreakdown struct {
+	RuleID  *uint       `json:"rule_id"`
+	Percent float64     `json:"percent"`
+	Fixed   money.Minor `json:"fixed"`
+	Fee     money.Minor `json:"fee"`
+	Payout  money.Minor `json:"payout"`
 }
 
 type ResponseOrdersList struct {
 	StatusResponse internal.StatusResponse `json:"status_response"`
 	Orders         []OrderInfo             `json:"orders"`
 	Total          int                     `json:"total"`
+	Pagination     pagination.Page         `json:"pagination"`
 }
 
 type ResponseOrderAction struct {
@@ -284,6 +503,16 @@ type WorkerCompleteOrder struct {
 	Message string `json:"message"`
 }
 
+type CompletionReportInfo struct {
+	Notes            string   `json:"notes"`
+	TimeSpentMinutes int      `json:"time_spent_minutes"`
+	Latitude         *float64 `json:"latitude"`
+	Longitude        *float64 `json:"longitude"`
+	Photos           []string `json:"photos"` // Подписанные ссылки на фотографии
+	WorkerID         *uint    `json:"worker_id"`
+	SubmittedAt      string   `json:"submitted_at"`
+}
+
 type ResponseWorkerComplete struct {
 	StatusResponse internal.StatusResponse `json:"status_response"`
 	Order          OrderInfo               `json:"order"`
@@ -318,16 +547,17 @@ type TokenCompanyStats struct {
 }
 
 type CompanyStats struct {
-	TotalServices    int     `json:"total_services"`
-	TotalOrders      int     `json:"total_orders"`
-	ActiveOrders     int     `json:"active_orders"`
-	CompletedOrders  int     `json:"completed_orders"`
-	TotalEarnings    float64 `json:"total_earnings"`
-	TotalRevenue     float64 `json:"total_revenue"`
-	AverageRating    float64 `json:"average_rating"`
-	TotalReviews     int     `json:"total_reviews"`
-	ReviewCount      int     `json:"review_count"`
-	BalanceAvailable float64 `json:"balance_available"`
+	TotalServices    int         `json:"total_services"`
+	TotalOrders      int         `json:"total_orders"`
+	ActiveOrders     int         `json:"active_orders"`
+	CompletedOrders  int         `json:"completed_orders"`
+	TotalEarnings    money.Minor `json:"total_earnings"`
+	TotalRevenue     money.Minor `json:"total_revenue"`
+	AverageRating    float64     `json:"average_rating"`
+	TotalReviews     int         `json:"total_reviews"`
+	ReviewCount      int         `json:"review_count"`
+	BalanceAvailable money.Minor `json:"balance_available"`
+	Currency         string      `json:"currency"`
 }
 
 type ResponseCompanyStats struct {
@@ -335,6 +565,46 @@ type ResponseCompanyStats struct {
 	Stats          CompanyStats            `json:"stats"`
 }
 
+type TokenCompanyAnalytics struct {
+	TokenAccess TokenAccess `json:"token_access"`
+	From        string      `json:"from"`     // YYYY-MM-DD, по умолчанию 30 дней назад
+	To          string      `json:"to"`       // YYYY-MM-DD включительно, по умолчанию сегодня
+	Interval    string      `json:"interval"` // day (по умолчанию), week, month
+}
+
+type AnalyticsMetrics struct {
+	Orders             int64       `json:"orders"`
+	Paid               int64       `json:"paid"`
+	Cancelled          int64       `json:"cancelled"`
+	Finished           int64       `json:"finished"`
+	Revenue            money.Minor `json:"revenue"`
+	Earnings           money.Minor `json:"earnings"`
+	ConversionRate     float64     `json:"conversion_rate"`      // Доля оплаченных среди созданных
+	CancellationRate   float64     `json:"cancellation_rate"`    // Доля отмененных среди созданных
+	AvgCompletionHours *float64    `json:"avg_completion_hours"` // От создания до выполнения работы
+}
+
+type AnalyticsPoint struct {
+	Period string `json:"period"` // Начало интервала, YYYY-MM-DD
+	AnalyticsMetrics
+}
+
+type CardAnalytics struct {
+	CardID uint   `json:"card_id"`
+	Title  string `json:"title"`
+	AnalyticsMetrics
+}
+
+type CompanyAnalytics struct {
+	From     string           `json:"from"`
+	To       string           `json:"to"`
+	Interval string           `json:"interval"`
+	Currency string           `json:"currency"`
+	Totals   AnalyticsMetrics `json:"totals"`
+	Series   []AnalyticsPoint `json:"series"`
+	Cards    []CardAnalytics  `json:"cards"`
+}
+
 // ================================
 // NOTIFICATION STRUCTURES
 // ================================
@@ -344,6 +614,7 @@ type TokenNotificationsList struct {
 	IsRead      *bool       `json:"is_read"` // nil = all, true = read, false = unread
 	Limit       int         `json:"limit"`
 	Offset      int         `json:"offset"`
+	Cursor      *string     `json:"cursor"` // Пустая строка - первая страница по курсору
 }
 
 type NotificationInfo struct {
@@ -362,6 +633,7 @@ type ResponseNotificationsList struct {
 	Notifications  []NotificationInfo      `json:"notifications"`
 	Total          int                     `json:"total"`
 	UnreadCount    int                     `json:"unread_count"`
+	Pagination     pagination.Page         `json:"pagination"`
 }
 
 type TokenMarkNotificationRead struct {
@@ -396,9 +668,122 @@ type ClientProfileInfo struct {
 
 // CardInfo структура для обновления карточки услуги
 type CardInfo struct {
-	Title       string  `json:"title"`
-	Description string  `json:"description"`
-	Category    string  `json:"category"`
-	Location    string  `json:"location"`
-	Price       float64 `json:"price"`
+	Title       string      `json:"title"`
+	Description string      `json:"description"`
+	Category    string      `json:"category"`
+	CategoryID  *uint       `json:"category_id"`
+	Location    string      `json:"location"`
+	Price       money.Minor `json:"price"`
+}
+
+// UserAccount учетная запись, от имени которой может действовать пользователь
+type UserAccount struct {
+	Type string `json:"type"` // "client" или "company"
+	ID   uint   `json:"id"`
+	Role string `json:"role"`
+	Name string `json:"name"`
+}
+
+// TokenSwitchAccount выбор активной роли. AccountType "client" или "company", CompanyID
+// выбирает компанию, если их несколько
+type TokenSwitchAccount struct {
+	TokenAccess TokenAccess `json:"token_access"`
+	AccountType string      `json:"account_type"`
+	CompanyID   uint        `json:"company_id"`
+}
+
+type TokenCreateClientProfile struct {
+	TokenAccess TokenAccess `json:"token_access"`
+	Phone       string      `json:"phone"`
+}
+
+type CompanyCreateInfo struct {
+	CompanyName string `json:"company_name"`
+	Email       string `json:"email"`
+	Phone       string `json:"phone"`
+	Website     string `json:"website"`
+	Description string `json:"description"`
+	Photo       string `json:"photo"`
+}
+
+type TokenCreateCompany struct {
+	TokenAccess TokenAccess       `json:"token_access"`
+	Company     CompanyCreateInfo `json:"company"`
+}
+
+type CompanyMember struct {
+	UserID   uint      `json:"user_id"`
+	Email    string    `json:"email"`
+	FullName string    `json:"full_name"`
+	Role     string    `json:"role"`
+	AddedAt  time.Time `json:"added_at"`
+}
+
+type TokenAddCompanyMember struct {
+	TokenAccess TokenAccess `json:"token_access"`
+	Email       string      `json:"email"`
+	Role        string      `json:"role"`
+}
+
+type TokenUpdateCompanyMember struct {
+	TokenAccess TokenAccess `json:"token_access"`
+	UserID      uint        `json:"user_id"`
+	Role        string      `json:"role"`
+}
+
+type TokenRemoveCompanyMember struct {
+	TokenAccess TokenAccess `json:"token_access"`
+	UserID      uint        `json:"user_id"`
+}
+
+// OIDCProvider провайдер входа для кнопки на странице входа
+type OIDCProvider struct {
+	Name  string `json:"name"`
+	Title string `json:"title"`
+}
+
+// OIDCStartRequest начало входа через провайдера
+type OIDCStartRequest struct {
+	Provider string `json:"provider" binding:"required"`
+}
+
+// OIDCAuthorization адрес, на который нужно перенаправить пользователя. State нужно
+// сохранить в браузере и сверить со state в ответе провайдера перед завершением входа
+type OIDCAuthorization struct {
+	AuthorizationURL string `json:"authorization_url"`
+	State            string `json:"state"`
+	ExpiresIn        int    `json:"expires_in"`
+}
+
+// OIDCCallbackRequest код и state, с которыми провайдер вернул пользователя на OIDC_REDIRECT_URL
+type OIDCCallbackRequest struct {
+	State       string `json:"state" binding:"required"`
+	Code        string `json:"code" binding:"required"`
+	AccountType string `json:"account_type"` // Роль, в которой выдается токен, см. TokenSwitchAccount
+	CompanyID   uint   `json:"company_id"`
+}
+
+// LinkedIdentity привязанная учетная запись внешнего провайдера
+type LinkedIdentity struct {
+	Provider   string     `json:"provider"`
+	Title      string     `json:"title"`
+	Email      string     `json:"email"`
+	LinkedAt   time.Time  `json:"linked_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+type TokenOIDCLinkStart struct {
+	TokenAccess TokenAccess `json:"token_access"`
+	Provider    string      `json:"provider"`
+}
+
+type TokenOIDCLinkComplete struct {
+	TokenAccess TokenAccess `json:"token_access"`
+	State       string      `json:"state"`
+	Code        string      `json:"code"`
+}
+
+type TokenOIDCUnlink struct {
+	TokenAccess TokenAccess `json:"token_access"`
+	Provider    string      `json:"provider"`
 }
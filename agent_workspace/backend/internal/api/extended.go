@@ -1,36 +1,46 @@
 package api
 
+import "core/internal/money"
+
 // Расширенные структуры для карточек услуг
 type TokenUpdateCard struct {
 	TokenAccess TokenAccess `json:"token_access"`
 	CardID      uint        `json:"card_id"`
 	Card        struct {
-		Title       string  `json:"title"`
-		Description string  `json:"description"`
-		Category    string  `json:"category"`
-		Location    string  `json:"location"`
-		Price       float64 `json:"price"`
+		Title       string      `json:"title"`
+		Description string      `json:"description"`
+		Category    string      `json:"category"`
+		CategoryID  *uint       `json:"category_id"`
+		Location    string      `json:"location"`
+		Price       money.Minor `json:"price"`
 	} `json:"card"`
 }
 
 type ExtendedCardResponse struct {
-	ID          uint    `json:"id"`
-	Title       string  `json:"title"`
-	Description string  `json:"description"`
-	Category    string  `json:"category"`
-	Location    string  `json:"location"`
-	Price       float64 `json:"price"`
-	IsActive    bool    `json:"is_active"`
-	CompanyID   uint    `json:"company_id"`
+	ID          uint        `json:"id"`
+	Title       string      `json:"title"`
+	Description string      `json:"description"`
+	Category    string      `json:"category"`
+	Location    string      `json:"location"`
+	Price       money.Minor `json:"price"`
+	Currency    string      `json:"currency"`
+	IsActive    bool        `json:"is_active"`
+	CompanyID   uint        `json:"company_id"`
 	Company     struct {
 		ID          uint    `json:"id"`
 		CompanyName string  `json:"company_name"`
 		Stars       float64 `json:"stars"`
 		ReviewCount int     `json:"review_count"`
 		Photo       string  `json:"photo"`
+		IsVerified  bool    `json:"is_verified"`
 	} `json:"company"`
-	CreatedAt string `json:"created_at"`
-	UpdatedAt string `json:"updated_at"`
+	Latitude      *float64 `json:"latitude"`
+	Longitude     *float64 `json:"longitude"`
+	DistanceKm    *float64 `json:"distance_km,omitempty"`
+	CategoryID    *uint    `json:"category_id"`
+	FavoriteCount int64    `json:"favorite_count"`
+	CreatedAt     string   `json:"created_at"`
+	UpdatedAt     string   `json:"updated_at"`
 }
 
 // Структуры для заказов
@@ -40,6 +50,8 @@ type TokenCreateOrder struct {
 		CompanyID   uint   `json:"company_id"`
 		CardID      uint   `json:"card_id"`
 		Description string `json:"description"`
+		ScheduledAt string `json:"scheduled_at"` // RFC3339, начало выбранного слота
+		PromoCode   string `json:"promo_code"`   // Необязательный промокод на скидку
 	} `json:"order"`
 }
 
@@ -50,18 +62,18 @@ type TokenOrderAction struct {
 }
 
 type OrderResponse struct {
-	ID                uint    `json:"id"`
-	ClientID          uint    `json:"client_id"`
-	CompanyID         uint    `json:"company_id"`
-	CardID            uint    `json:"card_id"`
-	Amount            float64 `json:"amount"`
-	Status            string  `json:"status"`
-	PaymentStatus     string  `json:"payment_status"`
-	Description       string  `json:"description"`
-	WorkerCompleteURL string  `json:"worker_complete_url,omitempty"`
-	CreatedAt         string  `json:"created_at"`
-	UpdatedAt         string  `json:"updated_at"`
-	CompletedAt       *string `json:"completed_at,omitempty"`
+	ID                uint        `json:"id"`
+	ClientID          uint        `json:"client_id"`
+	CompanyID         uint        `json:"company_id"`
+	CardID            uint        `json:"card_id"`
+	Amount            money.Minor `json:"amount"`
+	Status            string      `json:"status"`
+	PaymentStatus     string      `json:"payment_status"`
+	Description       string      `json:"description"`
+	WorkerCompleteURL string      `json:"worker_complete_url,omitempty"`
+	CreatedAt         string      `json:"created_at"`
+	UpdatedAt         string      `json:"updated_at"`
+	CompletedAt       *string     `json:"completed_at,omitempty"`
 	Client            struct {
 		ID       uint   `json:"id"`
 		FullName string `json:"full_name"`
@@ -85,38 +97,67 @@ type OrderResponse struct {
 // Структуры для баланса
 type TokenDepositBalance struct {
 	TokenAccess TokenAccess `json:"token_access"`
-	Amount      float64     `json:"amount"`
+	Amount      money.Minor `json:"amount"`
 }
 
 type TokenWithdrawBalance struct {
 	TokenAccess TokenAccess `json:"token_access"`
-	Amount      float64     `json:"amount"`
+	Amount      money.Minor `json:"amount"`
+
+	// Код из приложения или резервный код, если у компании включена 2FA
+	TwoFactorCode string `json:"two_factor_code"`
 }
 
 type BalanceResponse struct {
-	Balance float64 `json:"balance"`
+	Balance money.Minor `json:"balance"`
 }
 
 type BalanceTransactionResponse struct {
-	ID          uint    `json:"id"`
-	Amount      float64 `json:"amount"`
-	Type        string  `json:"type"`
-	Status      string  `json:"status"`
-	Description string  `json:"description"`
-	CreatedAt   string  `json:"created_at"`
+	ID          uint        `json:"id"`
+	Amount      money.Minor `json:"amount"`
+	Type        string      `json:"type"`
+	Status      string      `json:"status"`
+	Description string      `json:"description"`
+	CreatedAt   string      `json:"created_at"`
 }
 
 // Структуры для отзывов
 type TokenCreateReview struct {
 	TokenAccess TokenAccess `json:"token_access"`
 	Review      struct {
-		OrderID   uint   `json:"order_id"`
-		CompanyID uint   `json:"company_id"`
-		Rating    int    `json:"rating"`
-		Comment   string `json:"comment"`
+		OrderID    uint             `json:"order_id"`
+		CompanyID  uint             `json:"company_id"`
+		Rating     int              `json:"rating"`
+		Comment    string           `json:"comment"`
+		SubRatings ReviewSubRatings `json:"sub_ratings"`
 	} `json:"review"`
 }
 
+// ReviewSubRatings необязательные оценки по критериям от 1 до 5
+type ReviewSubRatings struct {
+	Quality       *int `json:"quality"`
+	Punctuality   *int `json:"punctuality"`
+	Communication *int `json:"communication"`
+	Value         *int `json:"value"`
+}
+
+// CompanySubRatings средние оценки компании по критериям, 0 - оценок нет
+type CompanySubRatings struct {
+	Quality       float64 `json:"quality"`
+	Punctuality   float64 `json:"punctuality"`
+	Communication float64 `json:"communication"`
+	Value         float64 `json:"value"`
+}
+
+type CompanyRatingSummary struct {
+	CompanyID    uint               `json:"company_id"`
+	Rating       float64            `json:"rating"`
+	ReviewCount  int                `json:"review_count"`
+	RankingScore float64            `json:"ranking_score"`
+	Histogram    RatingDistribution `json:"histogram"`
+	SubRatings   CompanySubRatings  `json:"sub_ratings"`
+}
+
 type ReviewResponse struct {
 	ID        uint   `json:"id"`
 	ClientID  uint   `json:"client_id"`
@@ -178,10 +219,596 @@ type TokenDeleteCardFixed struct {
 type TokenCreateCardExtended struct {
 	TokenAccess TokenAccess `json:"token_access"`
 	Card        struct {
-		Title       string  `json:"title"`
-		Description string  `json:"description"`
-		Category    string  `json:"category"`
-		Location    string  `json:"location"`
-		Price       float64 `json:"price"`
+		Title       string      `json:"title"`
+		Description string      `json:"description"`
+		Category    string      `json:"category"`
+		Location    string      `json:"location"`
+		Price       money.Minor `json:"price"`
 	} `json:"card"`
 }
+
+// Структуры для расписания компании
+type WorkingHoursInfo struct {
+	Weekday     int    `json:"weekday"` // 0 - воскресенье, 6 - суббота
+	Start       string `json:"start"`   // "09:00"
+	End         string `json:"end"`     // "18:00"
+	SlotMinutes int    `json:"slot_minutes"`
+	Capacity    int    `json:"capacity"`
+}
+
+type BlackoutDateInfo struct {
+	ID     uint   `json:"id"`
+	Date   string `json:"date"` // "2006-01-02"
+	Reason string `json:"reason"`
+}
+
+type TokenSetWorkingHours struct {
+	TokenAccess TokenAccess        `json:"token_access"`
+	Hours       []WorkingHoursInfo `json:"hours"`
+}
+
+type TokenAddBlackoutDate struct {
+	TokenAccess TokenAccess `json:"token_access"`
+	Date        string      `json:"date"`
+	Reason      string      `json:"reason"`
+}
+
+type TokenDeleteBlackoutDate struct {
+	TokenAccess TokenAccess `json:"token_access"`
+	BlackoutID  uint        `json:"blackout_id"`
+}
+
+type ScheduleResponse struct {
+	Hours     []WorkingHoursInfo `json:"hours"`
+	Blackouts []BlackoutDateInfo `json:"blackouts"`
+}
+
+type SlotInfo struct {
+	Start     string `json:"start"`
+	End       string `json:"end"`
+	Available int    `json:"available"`
+}
+
+// Структуры для работников компании
+type TokenCreateWorker struct {
+	TokenAccess TokenAccess `json:"token_access"`
+	Worker      struct {
+		FullName    string   `json:"full_name"`
+		Email       string   `json:"email"`
+		Phone       string   `json:"phone"`
+		Password    string   `json:"password"`
+		Permissions []string `json:"permissions"`
+	} `json:"worker"`
+}
+
+type TokenWorkerAction struct {
+	TokenAccess TokenAccess `json:"token_access"`
+	WorkerID    uint        `json:"worker_id"`
+}
+
+type TokenAssignOrder struct {
+	TokenAccess TokenAccess `json:"token_access"`
+	OrderID     uint        `json:"order_id"`
+	WorkerID    uint        `json:"worker_id"`
+}
+
+type TokenWorkerComplete struct {
+	TokenAccess      TokenAccess `json:"token_access"`
+	OrderID          uint        `json:"order_id"`
+	Notes            string      `json:"notes"`
+	TimeSpentMinutes int         `json:"time_spent_minutes"`
+	Latitude         *float64    `json:"latitude"`
+	Longitude        *float64    `json:"longitude"`
+}
+
+type WorkerInfo struct {
+	ID          uint     `json:"id"`
+	FullName    string   `json:"full_name"`
+	Email       string   `json:"email"`
+	Phone       string   `json:"phone"`
+	Permissions []string `json:"permissions"`
+	IsActive    bool     `json:"is_active"`
+}
+
+type WorkerTaskInfo struct {
+	OrderID      uint    `json:"order_id"`
+	ServiceName  string  `json:"service_name"`
+	Description  string  `json:"description"`
+	ClientName   string  `json:"client_name"`
+	ClientPhone  string  `json:"client_phone"`
+	Location     string  `json:"location"`
+	Status       string  `json:"status"`
+	ScheduledAt  *string `json:"scheduled_at"`
+	ScheduledEnd *string `json:"scheduled_end"`
+	CheckInAt    *string `json:"check_in_at"`
+	CheckOutAt   *string `json:"check_out_at"`
+}
+
+// Структуры для управления ссылками работников
+type TokenWorkerLinkAction struct {
+	TokenAccess TokenAccess `json:"token_access"`
+	OrderID     uint        `json:"order_id"`
+	TTLHours    int         `json:"ttl_hours"` // Только для перевыпуска, 0 - срок по умолчанию
+}
+
+type WorkerLinkInfo struct {
+	ID        uint    `json:"id"`
+	URL       string  `json:"url,omitempty"` // Только для действующей ссылки
+	Status    string  `json:"status"`        // active, used, revoked, expired
+	CreatedAt string  `json:"created_at"`
+	ExpiresAt string  `json:"expires_at"`
+	RevokedAt *string `json:"revoked_at"`
+}
+
+type WorkerLinkAccessInfo struct {
+	WorkerLinkID *uint  `json:"worker_link_id"`
+	Action       string `json:"action"`
+	Result       string `json:"result"`
+	IP           string `json:"ip"`
+	UserAgent    string `json:"user_agent"`
+	CreatedAt    string `json:"created_at"`
+}
+
+type WorkerLinksResponse struct {
+	Links    []WorkerLinkInfo       `json:"links"`
+	Accesses []WorkerLinkAccessInfo `json:"accesses"`
+}
+
+// Структуры для поиска карточек
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+type CardSearchFacets struct {
+	Categories  []FacetCount `json:"categories"`
+	PriceRanges []FacetCount `json:"price_ranges"`
+	Ratings     []FacetCount `json:"ratings"`
+	Verified    int64        `json:"verified"`
+}
+
+type CardSearchResponse struct {
+	Cards  []ExtendedCardResponse `json:"cards"`
+	Total  int64                  `json:"total"`
+	Page   int                    `json:"page"`
+	Limit  int                    `json:"limit"`
+	Facets CardSearchFacets       `json:"facets"`
+}
+
+// Структуры для зон обслуживания
+type ServiceAreaInfo struct {
+	Address   string       `json:"address"` // Геокодируется, если координаты не заданы
+	Latitude  *float64     `json:"latitude"`
+	Longitude *float64     `json:"longitude"`
+	RadiusKm  *float64     `json:"radius_km"`
+	Polygon   [][2]float64 `json:"polygon"` // Вершины [широта, долгота]
+}
+
+type TokenSetCompanyArea struct {
+	TokenAccess TokenAccess     `json:"token_access"`
+	Area        ServiceAreaInfo `json:"area"`
+}
+
+type TokenSetCardArea struct {
+	TokenAccess TokenAccess     `json:"token_access"`
+	CardID      uint            `json:"card_id"`
+	Area        ServiceAreaInfo `json:"area"`
+}
+
+// Структуры для категорий
+type CategoryNode struct {
+	ID        uint           `json:"id"`
+	ParentID  *uint          `json:"parent_id"`
+	Slug      string         `json:"slug"`
+	Name      string         `json:"name"` // Название на языке запроса
+	NameRu    string         `json:"name_ru"`
+	NameEn    string         `json:"name_en"`
+	Icon      string         `json:"icon"`
+	SortOrder int            `json:"sort_order"`
+	Aliases   []string       `json:"aliases"`
+	IsActive  bool           `json:"is_active"`
+	CardCount int64          `json:"card_count"` // Вместе с подкатегориями
+	Children  []CategoryNode `json:"children"`
+}
+
+type CategoryInfo struct {
+	ParentID  *uint    `json:"parent_id"`
+	Slug      string   `json:"slug"` // Генерируется из названия, если не задан
+	NameRu    string   `json:"name_ru"`
+	NameEn    string   `json:"name_en"`
+	Icon      string   `json:"icon"`
+	SortOrder int      `json:"sort_order"`
+	Aliases   []string `json:"aliases"`
+	IsActive  *bool    `json:"is_active"`
+}
+
+type TokenAdminCategory struct {
+	TokenAccess TokenAccess  `json:"token_access"`
+	CategoryID  uint         `json:"category_id"`
+	Category    CategoryInfo `json:"category"`
+}
+
+// Структуры для избранного и сохраненных поисков
+type TokenFavorite struct {
+	TokenAccess TokenAccess `json:"token_access"`
+	TargetType  string      `json:"target_type"` // card, company
+	TargetID    uint        `json:"target_id"`
+}
+
+type FavoriteCompanyInfo struct {
+	ID          uint    `json:"id"`
+	CompanyName string  `json:"company_name"`
+	Description string  `json:"description"`
+	Photo       string  `json:"photo"`
+	Stars       float64 `json:"stars"`
+	ReviewCount int     `json:"review_count"`
+	IsVerified  bool    `json:"is_verified"`
+}
+
+type FavoritesResponse struct {
+	Cards     []ExtendedCardResponse `json:"cards"`
+	Companies []FavoriteCompanyInfo  `json:"companies"`
+}
+
+type SavedSearchInfo struct {
+	ID             uint     `json:"id"`
+	Name           string   `json:"name"`
+	Query          string   `json:"q"`
+	Category       string   `json:"category"`
+	MinPrice       *float64 `json:"min_price"`
+	MaxPrice       *float64 `json:"max_price"`
+	Location       string   `json:"location"`
+	MinRating      *float64 `json:"min_rating"`
+	VerifiedOnly   bool     `json:"verified"`
+	Latitude       *float64 `json:"lat"`
+	Longitude      *float64 `json:"lon"`
+	RadiusKm       float64  `json:"radius_km"`
+	Sort           string   `json:"sort"`
+	NotifyNewCards *bool    `json:"notify_new_cards"` // По умолчанию включено
+	LastNotifiedAt *string  `json:"last_notified_at"`
+	CreatedAt      string   `json:"created_at"`
+}
+
+type TokenSavedSearch struct {
+	TokenAccess   TokenAccess     `json:"token_access"`
+	SavedSearchID uint            `json:"saved_search_id"`
+	Search        SavedSearchInfo `json:"search"`
+}
+
+// Структуры публичного профиля компании. Содержат только данные, которые можно
+// показывать любому посетителю
+type PublicReview struct {
+	ID         uint             `json:"id"`
+	OrderID    uint             `json:"order_id"`
+	CompanyID  uint             `json:"company_id"`
+	Rating     int              `json:"rating"`
+	Comment    string           `json:"comment"`
+	ClientName string           `json:"client_name"`
+	CreatedAt  string           `json:"created_at"`
+	EditedAt   *string          `json:"edited_at"`
+	Reply      string           `json:"reply"`
+	RepliedAt  *string          `json:"replied_at"`
+	SubRatings ReviewSubRatings `json:"sub_ratings"`
+}
+
+// PublicOrder активный заказ в публичном списке без данных клиента, сумм и ссылок работника
+type PublicOrder struct {
+	ID          uint    `json:"id"`
+	CompanyID   uint    `json:"company_id"`
+	CompanyName string  `json:"company_name"`
+	CardID      uint    `json:"card_id"`
+	ServiceName string  `json:"service_name"`
+	Status      string  `json:"status"`
+	CreatedAt   string  `json:"created_at"`
+	ScheduledAt *string `json:"scheduled_at"`
+}
+
+type RatingDistribution struct {
+	One   int64 `json:"1"`
+	Two   int64 `json:"2"`
+	Three int64 `json:"3"`
+	Four  int64 `json:"4"`
+	Five  int64 `json:"5"`
+}
+
+type CompanyPublicProfile struct {
+	ID                 uint                   `json:"id"`
+	CompanyName        string                 `json:"company_name"`
+	Description        string                 `json:"description"`
+	Website            string                 `json:"website"`
+	Photo              string                 `json:"photo"`
+	Address            string                 `json:"address"`
+	TypeService        string                 `json:"type_service"`
+	IsVerified         bool                   `json:"is_verified"`
+	Stars              float64                `json:"stars"`
+	ReviewCount        int                    `json:"review_count"`
+	RatingDistribution RatingDistribution     `json:"rating_distribution"`
+	CompletedOrders    int64                  `json:"completed_orders"`
+	Cards              []ExtendedCardResponse `json:"cards"`
+	RecentReviews      []PublicReview         `json:"recent_reviews"`
+	MemberSince        string                 `json:"member_since"`
+	RankingScore       float64                `json:"ranking_score"`
+	SubRatings         CompanySubRatings      `json:"sub_ratings"`
+}
+
+// Структуры для редактирования отзывов, ответов компаний и модерации
+type TokenUpdateReview struct {
+	TokenAccess TokenAccess `json:"token_access"`
+	ReviewID    uint        `json:"review_id"`
+	Review      struct {
+		Rating     int              `json:"rating"`
+		Comment    string           `json:"comment"`
+		SubRatings ReviewSubRatings `json:"sub_ratings"`
+	} `json:"review"`
+}
+
+type TokenReviewReply struct {
+	TokenAccess TokenAccess `json:"token_access"`
+	ReviewID    uint        `json:"review_id"`
+	Reply       string      `json:"reply"`
+}
+
+type TokenReviewReport struct {
+	TokenAccess TokenAccess `json:"token_access"`
+	ReviewID    uint        `json:"review_id"`
+	Reason      string      `json:"reason"`
+}
+
+type TokenModerationQueue struct {
+	TokenAccess TokenAccess `json:"token_access"`
+	Status      string      `json:"status"` // pending (по умолчанию), hidden, dismissed
+	Limit       int         `json:"limit"`
+	Offset      int         `json:"offset"`
+	Cursor      *string     `json:"cursor"`
+}
+
+type TokenModerateReview struct {
+	TokenAccess TokenAccess `json:"token_access"`
+	ReviewID    uint        `json:"review_id"`
+	ReportID    uint        `json:"report_id"`
+	Reason      string      `json:"reason"`
+}
+
+type ModerationReview struct {
+	PublicReview
+	ClientID     uint   `json:"client_id"`
+	IsHidden     bool   `json:"is_hidden"`
+	HiddenReason string `json:"hidden_reason"`
+}
+
+type ReviewReportInfo struct {
+	ID           uint             `json:"id"`
+	ReporterID   uint             `json:"reporter_id"`
+	ReporterType string           `json:"reporter_type"`
+	Reason       string           `json:"reason"`
+	Status       string           `json:"status"`
+	ResolvedAt   *string          `json:"resolved_at"`
+	CreatedAt    string           `json:"created_at"`
+	Review       ModerationReview `json:"review"`
+}
+
+// Структуры для проверки документов компаний администратором
+type TokenCompanyVerificationQueue struct {
+	TokenAccess TokenAccess `json:"token_access"`
+	Limit       int         `json:"limit"`
+	Offset      int         `json:"offset"`
+	Cursor      *string     `json:"cursor"`
+}
+
+type TokenVerifyCompany struct {
+	TokenAccess TokenAccess `json:"token_access"`
+	CompanyID   uint        `json:"company_id"`
+}
+
+type CompanyVerificationInfo struct {
+	ID          uint     `json:"id"`
+	CompanyName string   `json:"company_name"`
+	IDCompany   string   `json:"id_company"`
+	Email       string   `json:"email"`
+	Phone       string   `json:"phone"`
+	Address     string   `json:"address"`
+	TypeService string   `json:"type_service"`
+	Documents   []string `json:"documents"`
+	CreatedAt   string   `json:"created_at"`
+}
+
+// Структуры для управления комиссиями платформы
+type FeeRuleInfo struct {
+	ID          uint        `json:"id"`
+	Name        string      `json:"name"`
+	CategoryID  *uint       `json:"category_id"`
+	CompanyTier string      `json:"company_tier"`
+	Percent     float64     `json:"percent"`
+	Fixed       money.Minor `json:"fixed"`
+	Currency    string      `json:"currency"` // Валюта фиксированной части, по умолчанию BASE_CURRENCY
+	Priority    int         `json:"priority"`
+	StartsAt    *string     `json:"starts_at"` // RFC3339
+	EndsAt      *string     `json:"ends_at"`   // RFC3339
+	IsActive    *bool       `json:"is_active"` // По умолчанию включено
+}
+
+type TokenAdminFeeRule struct {
+	TokenAccess TokenAccess `json:"token_access"`
+	RuleID      uint        `json:"rule_id"`
+	Rule        FeeRuleInfo `json:"rule"`
+}
+
+type TokenAdminCompanyTier struct {
+	TokenAccess TokenAccess `json:"token_access"`
+	CompanyID   uint        `json:"company_id"`
+	Tier        string      `json:"tier"`
+}
+
+// Структуры для промокодов и реферальной программы
+type PromoCodeInfo struct {
+	ID           uint        `json:"id"`
+	Code         string      `json:"code"`
+	Description  string      `json:"description"`
+	Type         string      `json:"type"` // percent, fixed
+	Percent      float64     `json:"percent"`
+	Amount       money.Minor `json:"amount"`       // Для fixed
+	MaxDiscount  money.Minor `json:"max_discount"` // Для percent, 0 - без ограничения
+	Currency     string      `json:"currency"`     // Валюта amount, max_discount и min_order, по умолчанию BASE_CURRENCY
+	MinOrder     money.Minor `json:"min_order"`
+	UsageLimit   int         `json:"usage_limit"`    // 0 - без ограничения
+	PerUserLimit int         `json:"per_user_limit"` // 0 - без ограничения
+	UsedCount    int         `json:"used_count"`
+	CategoryID   *uint       `json:"category_id"`
+	CompanyID    *uint       `json:"company_id"`
+	FundedBy     string      `json:"funded_by"` // platform, company
+	StartsAt     *string     `json:"starts_at"` // RFC3339
+	EndsAt       *string     `json:"ends_at"`   // RFC3339
+	IsActive     *bool       `json:"is_active"` // По умолчанию включено
+}
+
+type TokenPromoCode struct {
+	TokenAccess TokenAccess   `json:"token_access"`
+	PromoCodeID uint          `json:"promo_code_id"`
+	PromoCode   PromoCodeInfo `json:"promo_code"`
+}
+
+type TokenCheckPromoCode struct {
+	TokenAccess TokenAccess `json:"token_access"`
+	Code        string      `json:"code"`
+	CardID      uint        `json:"card_id"`
+}
+
+// PromoQuote предварительный расчет скидки по промокоду для карточки услуги
+type PromoQuote struct {
+	Code     string      `json:"code"`
+	Subtotal money.Minor `json:"subtotal"`
+	Discount money.Minor `json:"discount"`
+	Total    money.Minor `json:"total"`
+	Currency string      `json:"currency"`
+}
+
+type TokenApplyReferral struct {
+	TokenAccess TokenAccess `json:"token_access"`
+	Code        string      `json:"code"`
+}
+
+type ReferralInfo struct {
+	Code     string      `json:"code"`
+	Invited  int         `json:"invited"`  // Всего приглашенных
+	Credited int         `json:"credited"` // Приглашенных, за которых начислен бонус
+	Reward   money.Minor `json:"reward"`
+	Currency string      `json:"currency"`
+	// Реферальный код, по которому пришел сам клиент
+	ReferredBy *string `json:"referred_by"`
+}
+
+// Структуры для повторяющихся заказов
+type RecurringOrderRequest struct {
+	CompanyID   uint   `json:"company_id"`
+	CardID      uint   `json:"card_id"`
+	Description string `json:"description"`
+	Frequency   string `json:"frequency"` // weekly, biweekly, monthly, custom
+	Rule        string `json:"rule"`      // RRULE для custom, например FREQ=WEEKLY;BYDAY=MO,TH
+	StartsAt    string `json:"starts_at"` // RFC3339, первое занятие
+	AutoPay     bool   `json:"auto_pay"`
+}
+
+type TokenRecurringOrder struct {
+	TokenAccess TokenAccess           `json:"token_access"`
+	Recurring   RecurringOrderRequest `json:"recurring"`
+}
+
+type TokenRecurringAction struct {
+	TokenAccess TokenAccess `json:"token_access"`
+	RecurringID uint        `json:"recurring_id"`
+	ScheduledAt string      `json:"scheduled_at"` // RFC3339, занятие для пропуска
+	AutoPay     *bool       `json:"auto_pay"`
+}
+
+type TokenRecurringUpcoming struct {
+	TokenAccess TokenAccess `json:"token_access"`
+	Days        int         `json:"days"` // По умолчанию 14, не больше 90
+}
+
+type RecurringOrderInfo struct {
+	ID          uint                      `json:"id"`
+	CompanyID   uint                      `json:"company_id"`
+	CompanyName string                    `json:"company_name"`
+	ClientName  string                    `json:"client_name"`
+	CardID      uint                      `json:"card_id"`
+	ServiceName string                    `json:"service_name"`
+	Description string                    `json:"description"`
+	Amount      money.Minor               `json:"amount"` // Текущая цена карточки
+	Currency    string                    `json:"currency"`
+	Frequency   string                    `json:"frequency"`
+	Rule        string                    `json:"rule"`
+	StartsAt    string                    `json:"starts_at"`
+	NextAt      *string                   `json:"next_at"`
+	AutoPay     bool                      `json:"auto_pay"`
+	Status      string                    `json:"status"`
+	LastError   string                    `json:"last_error,omitempty"`
+	Upcoming    []RecurringOccurrenceInfo `json:"upcoming"`
+}
+
+// RecurringOccurrenceInfo занятие повторяющегося заказа. Статус scheduled - заказ еще не создан
+type RecurringOccurrenceInfo struct {
+	RecurringID uint   `json:"recurring_id"`
+	ScheduledAt string `json:"scheduled_at"`
+	Status      string `json:"status"`
+	OrderID     *uint  `json:"order_id"`
+	ClientName  string `json:"client_name,omitempty"`
+	ServiceName string `json:"service_name"`
+}
+
+type TokenCartItem struct {
+	TokenAccess TokenAccess `json:"token_access"`
+	CardID      uint        `json:"card_id"`
+	Quantity    int         `json:"quantity"` // Для update 0 удаляет карточку из корзины
+}
+
+type TokenCartCheckout struct {
+	TokenAccess TokenAccess         `json:"token_access"`
+	Checkout    CartCheckoutRequest `json:"checkout"`
+}
+
+// CartCheckoutRequest слоты для компаний с расписанием и общий комментарий к заказам
+type CartCheckoutRequest struct {
+	Slots       []CartSlot `json:"slots"`
+	Description string     `json:"description"`
+}
+
+type CartSlot struct {
+	CompanyID   uint   `json:"company_id"`
+	ScheduledAt string `json:"scheduled_at"` // RFC3339, начало выбранного слота
+}
+
+// CartInfo корзина, сгруппированная по компаниям. Итог в валюте счета клиента по текущему курсу
+type CartInfo struct {
+	Companies []CartCompanyInfo `json:"companies"`
+	ItemCount int               `json:"item_count"`
+	Total     money.Minor       `json:"total"`
+	Currency  string            `json:"currency"`
+}
+
+// CartCompanyInfo услуги одной компании, при оформлении из них создается один заказ
+type CartCompanyInfo struct {
+	CompanyID    uint           `json:"company_id"`
+	CompanyName  string         `json:"company_name"`
+	Currency     string         `json:"currency"`
+	Subtotal     money.Minor    `json:"subtotal"`
+	RequiresSlot bool           `json:"requires_slot"` // Компания принимает заказы только на слот расписания
+	Items        []CartItemInfo `json:"items"`
+}
+
+type CartItemInfo struct {
+	CardID    uint        `json:"card_id"`
+	Title     string      `json:"title"`
+	Price     money.Minor `json:"price"`
+	Quantity  int         `json:"quantity"`
+	Amount    money.Minor `json:"amount"`
+	Available bool        `json:"available"` // false, если карточка снята с публикации
+}
+
+// CheckoutInfo результат оформления корзины: списание с баланса и созданные заказы
+type CheckoutInfo struct {
+	ID        uint        `json:"id"`
+	Amount    money.Minor `json:"amount"`
+	Currency  string      `json:"currency"`
+	Orders    []OrderInfo `json:"orders"`
+	CreatedAt string      `json:"created_at"`
+}
@@ -1,9 +1,13 @@
 package internal
 
 import (
+	"fmt"
 	"github.com/joho/godotenv"
 	"os"
 	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata"
 )
 
 var PostgresUser string
@@ -15,6 +19,108 @@ var KeyJWT string
 
 var LifeTimeJWT int
 
+// WorkerLinkBaseURL адрес страницы завершения заказа, к которому добавляется токен работника
+var WorkerLinkBaseURL string
+
+// WorkerLinkTTL срок действия ссылки работника по умолчанию, WorkerLinkMaxTTL - максимальный
+// срок, который компания может задать при перевыпуске ссылки
+var WorkerLinkTTL time.Duration
+var WorkerLinkMaxTTL time.Duration
+
+// StorageDir каталог для загружаемых файлов (фотоотчеты, документы)
+var StorageDir string
+
+// AdminEmail и AdminPassword задают первого администратора платформы.
+// Если переменные не заданы, администратор не создается
+var AdminEmail string
+var AdminPassword string
+
+// ReviewEditWindow срок, в течение которого клиент может изменить свой отзыв
+var ReviewEditWindow time.Duration
+
+// PlatformFeePercent и PlatformFeeFixed комиссия платформы, если не подошло ни одно правило.
+// Фиксированная часть задается в базовой валюте
+var PlatformFeePercent float64
+var PlatformFeeFixed float64
+
+// BaseCurrency базовая валюта платформы, ExchangeRates курсы других валют к ней
+// в виде "USD=92.5,EUR=100.1"
+var BaseCurrency string
+var ExchangeRates string
+
+// ReferralReward бонус в базовой валюте, который получают пригласивший и приглашенный
+// клиенты после первого завершенного заказа приглашенного
+var ReferralReward float64
+
+// RecurringLeadTime за сколько до занятия создается заказ повторяющегося расписания,
+// RecurringCheckInterval как часто проверяются расписания
+var RecurringLeadTime time.Duration
+var RecurringCheckInterval time.Duration
+
+// EmailVerificationRequired запрещает вход, пока email не подтвержден по ссылке из письма.
+// AccountLinkBaseURL адрес страниц подтверждения email и сброса пароля, к которым добавляется токен
+var EmailVerificationRequired bool
+var AccountLinkBaseURL string
+
+// EmailTokenTTL срок действия ссылок подтверждения email, PasswordResetTTL - ссылки сброса пароля
+var EmailTokenTTL time.Duration
+var PasswordResetTTL time.Duration
+
+// MailDriver способ отправки писем: log (в журнал сервера) или smtp
+var MailDriver string
+var MailFrom string
+var SMTPHost string
+var SMTPPort int
+var SMTPUsername string
+var SMTPPassword string
+
+// TwoFactorIssuer название сервиса в приложении-аутентификаторе. TwoFactorKey ключ шифрования
+// секретов TOTP в базе, по умолчанию выводится из KEY_JWT
+var TwoFactorIssuer string
+var TwoFactorKey string
+
+// TwoFactorChallengeTTL время на ввод кода после пароля, TrustedDeviceTTL - срок,
+// в течение которого доверенное устройство входит без кода
+var TwoFactorChallengeTTL time.Duration
+var TrustedDeviceTTL time.Duration
+
+// RateLimitBackend хранилище ограничений частоты запросов: memory (один экземпляр сервиса)
+// или postgres (общее для всех реплик). TrustedProxies адреса прокси, которым можно верить
+// в X-Forwarded-For при определении IP клиента
+var RateLimitBackend string
+var TrustedProxies []string
+
+// LoginLockoutThreshold число неудачных попыток входа подряд, после которого учетная запись
+// блокируется на LoginLockoutBase. Каждая следующая неудача удваивает срок до LoginLockoutMax
+var LoginLockoutThreshold int
+var LoginLockoutBase time.Duration
+var LoginLockoutMax time.Duration
+
+// OIDCProvider провайдер входа через OpenID Connect. Настраивается переменными
+// OIDC_<NAME>_ISSUER, _CLIENT_ID, _CLIENT_SECRET, _TITLE, _SCOPES и _ALLOWED_DOMAINS
+type OIDCProvider struct {
+	Name           string
+	Title          string
+	Issuer         string
+	ClientID       string
+	ClientSecret   string
+	Scopes         []string
+	AllowedDomains []string
+}
+
+// OIDCProviders провайдеры из списка OIDC_PROVIDERS. OIDCRedirectURL страница, на которую
+// провайдер возвращает код авторизации, OIDCStateTTL - время на вход у провайдера
+var OIDCProviders []OIDCProvider
+var OIDCRedirectURL string
+var OIDCStateTTL time.Duration
+
+// ExportInlineRows наибольшее число строк выгрузки, которая отдается сразу в ответе.
+// Более крупные выгрузки выполняются фоновыми задачами
+var ExportInlineRows int64
+
+// TimeZone часовой пояс, в котором компании задают рабочее время
+var TimeZone *time.Location
+
 func InitEnv() error {
 	err := godotenv.Load()
 	if err != nil {
@@ -31,9 +137,160 @@ func InitEnv() error {
 		return err
 	}
 	LifeTimeJWT = int(lifeTime)
+	WorkerLinkBaseURL = getEnvDefault("WORKER_LINK_BASE_URL", "https://auth.tomsk-center.ru/worker/complete")
+	linkTTLHours, err := strconv.ParseInt(getEnvDefault("WORKER_LINK_TTL_HOURS", "168"), 10, 64)
+	if err != nil {
+		return err
+	}
+	WorkerLinkTTL = time.Duration(linkTTLHours) * time.Hour
+	linkMaxTTLHours, err := strconv.ParseInt(getEnvDefault("WORKER_LINK_MAX_TTL_HOURS", "720"), 10, 64)
+	if err != nil {
+		return err
+	}
+	WorkerLinkMaxTTL = time.Duration(linkMaxTTLHours) * time.Hour
+	StorageDir = getEnvDefault("STORAGE_DIR", "uploads")
+	AdminEmail = os.Getenv("ADMIN_EMAIL")
+	AdminPassword = os.Getenv("ADMIN_PASSWORD")
+	editWindowHours, err := strconv.ParseInt(getEnvDefault("REVIEW_EDIT_WINDOW_HOURS", "72"), 10, 64)
+	if err != nil {
+		return err
+	}
+	ReviewEditWindow = time.Duration(editWindowHours) * time.Hour
+	PlatformFeePercent, err = strconv.ParseFloat(getEnvDefault("PLATFORM_FEE_PERCENT", "0"), 64)
+	if err != nil {
+		return err
+	}
+	PlatformFeeFixed, err = strconv.ParseFloat(getEnvDefault("PLATFORM_FEE_FIXED", "0"), 64)
+	if err != nil {
+		return err
+	}
+	ExportInlineRows, err = strconv.ParseInt(getEnvDefault("EXPORT_INLINE_ROWS", "5000"), 10, 64)
+	if err != nil {
+		return err
+	}
+	BaseCurrency = getEnvDefault("BASE_CURRENCY", "RUB")
+	ExchangeRates = os.Getenv("EXCHANGE_RATES")
+	ReferralReward, err = strconv.ParseFloat(getEnvDefault("REFERRAL_REWARD", "300"), 64)
+	if err != nil {
+		return err
+	}
+	recurringLeadHours, err := strconv.ParseInt(getEnvDefault("RECURRING_LEAD_HOURS", "24"), 10, 64)
+	if err != nil {
+		return err
+	}
+	RecurringLeadTime = time.Duration(recurringLeadHours) * time.Hour
+	recurringCheckMinutes, err := strconv.ParseInt(getEnvDefault("RECURRING_CHECK_MINUTES", "5"), 10, 64)
+	if err != nil {
+		return err
+	}
+	RecurringCheckInterval = time.Duration(recurringCheckMinutes) * time.Minute
+	EmailVerificationRequired, err = strconv.ParseBool(getEnvDefault("EMAIL_VERIFICATION_REQUIRED", "true"))
+	if err != nil {
+		return err
+	}
+	AccountLinkBaseURL = getEnvDefault("ACCOUNT_LINK_BASE_URL", "https://auth.tomsk-center.ru/account")
+	emailTokenHours, err := strconv.ParseInt(getEnvDefault("EMAIL_TOKEN_TTL_HOURS", "48"), 10, 64)
+	if err != nil {
+		return err
+	}
+	EmailTokenTTL = time.Duration(emailTokenHours) * time.Hour
+	passwordResetMinutes, err := strconv.ParseInt(getEnvDefault("PASSWORD_RESET_TTL_MINUTES", "60"), 10, 64)
+	if err != nil {
+		return err
+	}
+	PasswordResetTTL = time.Duration(passwordResetMinutes) * time.Minute
+	MailDriver = getEnvDefault("MAIL_DRIVER", "log")
+	MailFrom = getEnvDefault("MAIL_FROM", "no-reply@tomsk-center.ru")
+	SMTPHost = os.Getenv("SMTP_HOST")
+	smtpPort, err := strconv.ParseInt(getEnvDefault("SMTP_PORT", "587"), 10, 64)
+	if err != nil {
+		return err
+	}
+	SMTPPort = int(smtpPort)
+	SMTPUsername = os.Getenv("SMTP_USERNAME")
+	SMTPPassword = os.Getenv("SMTP_PASSWORD")
+	TwoFactorIssuer = getEnvDefault("TWO_FACTOR_ISSUER", "Tomsk Center")
+	TwoFactorKey = getEnvDefault("TWO_FACTOR_KEY", KeyJWT)
+	challengeMinutes, err := strconv.ParseInt(getEnvDefault("TWO_FACTOR_CHALLENGE_TTL_MINUTES", "5"), 10, 64)
+	if err != nil {
+		return err
+	}
+	TwoFactorChallengeTTL = time.Duration(challengeMinutes) * time.Minute
+	trustedDeviceDays, err := strconv.ParseInt(getEnvDefault("TRUSTED_DEVICE_TTL_DAYS", "30"), 10, 64)
+	if err != nil {
+		return err
+	}
+	TrustedDeviceTTL = time.Duration(trustedDeviceDays) * 24 * time.Hour
+	RateLimitBackend = getEnvDefault("RATE_LIMIT_BACKEND", "memory")
+	TrustedProxies = strings.Split(getEnvDefault("TRUSTED_PROXIES", "127.0.0.1,::1"), ",")
+	lockoutThreshold, err := strconv.ParseInt(getEnvDefault("LOGIN_LOCKOUT_THRESHOLD", "5"), 10, 64)
+	if err != nil {
+		return err
+	}
+	LoginLockoutThreshold = int(lockoutThreshold)
+	lockoutBaseSeconds, err := strconv.ParseInt(getEnvDefault("LOGIN_LOCKOUT_BASE_SECONDS", "60"), 10, 64)
+	if err != nil {
+		return err
+	}
+	LoginLockoutBase = time.Duration(lockoutBaseSeconds) * time.Second
+	lockoutMaxMinutes, err := strconv.ParseInt(getEnvDefault("LOGIN_LOCKOUT_MAX_MINUTES", "60"), 10, 64)
+	if err != nil {
+		return err
+	}
+	LoginLockoutMax = time.Duration(lockoutMaxMinutes) * time.Minute
+	OIDCProviders, err = loadOIDCProviders(os.Getenv("OIDC_PROVIDERS"))
+	if err != nil {
+		return err
+	}
+	OIDCRedirectURL = getEnvDefault("OIDC_REDIRECT_URL", AccountLinkBaseURL+"/oidc/callback")
+	oidcStateMinutes, err := strconv.ParseInt(getEnvDefault("OIDC_STATE_TTL_MINUTES", "10"), 10, 64)
+	if err != nil {
+		return err
+	}
+	OIDCStateTTL = time.Duration(oidcStateMinutes) * time.Minute
+	TimeZone, err = time.LoadLocation(getEnvDefault("TIME_ZONE", "Asia/Tomsk"))
+	if err != nil {
+		return err
+	}
 	return nil
 }
 
+// loadOIDCProviders читает настройки провайдеров из списка имен через запятую
+func loadOIDCProviders(names string) ([]OIDCProvider, error) {
+	var providers []OIDCProvider
+	for _, name := range strings.Split(names, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		provider := OIDCProvider{
+			Name:         name,
+			Title:        getEnvDefault(prefix+"TITLE", name),
+			Issuer:       os.Getenv(prefix + "ISSUER"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			Scopes:       strings.Fields(getEnvDefault(prefix+"SCOPES", "openid email profile")),
+		}
+		if domains := os.Getenv(prefix + "ALLOWED_DOMAINS"); domains != "" {
+			provider.AllowedDomains = strings.Split(domains, ",")
+		}
+		if provider.Issuer == "" || provider.ClientID == "" {
+			return nil, fmt.Errorf("%sISSUER and %sCLIENT_ID are required", prefix, prefix)
+		}
+		providers = append(providers, provider)
+	}
+	return providers, nil
+}
+
+// getEnvDefault возвращает значение переменной окружения или значение по умолчанию
+func getEnvDefault(key, defaultValue string) string {
+	if value, ok := os.LookupEnv(key); ok && value != "" {
+		return value
+	}
+	return defaultValue
+}
+
 type StatusResponse struct {
 	Status string `json:"status"`
 }
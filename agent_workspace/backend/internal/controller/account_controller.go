@@ -0,0 +1,153 @@
+package controller
+
+import (
+	"core/internal/api"
+	"core/internal/service"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type AccountController interface {
+	// Публичные эндпоинты: пользователь переходит по ссылке из письма без токена доступа
+	VerifyEmail(c *gin.Context)
+	ResendVerification(c *gin.Context)
+	ForgotPassword(c *gin.Context)
+	ResetPassword(c *gin.Context)
+	ConfirmEmailChange(c *gin.Context)
+
+	ChangePassword(c *gin.Context, request *api.TokenChangePassword)
+	ChangeEmail(c *gin.Context, request *api.TokenChangeEmail)
+}
+
+type accountController struct {
+	accountService service.AccountService
+}
+
+func (ctrl *accountController) VerifyEmail(c *gin.Context) {
+	request := &api.AuthTokenRequest{}
+	if err := c.ShouldBind(request); err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid: "+err.Error())
+		return
+	}
+
+	if err := ctrl.accountService.VerifyEmail(request.Token); err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Email verified",
+	})
+}
+
+func (ctrl *accountController) ResendVerification(c *gin.Context) {
+	request := &api.EmailRequest{}
+	if err := c.ShouldBind(request); err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid: "+err.Error())
+		return
+	}
+
+	if err := ctrl.accountService.ResendVerification(request.Email); err != nil {
+		api.GetErrorJSON(c, http.StatusInternalServerError, "Failed to send verification email")
+		return
+	}
+
+	// Ответ одинаковый для любого адреса, чтобы по нему нельзя было проверить регистрацию
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "If the email is registered and not verified, a confirmation link has been sent",
+	})
+}
+
+func (ctrl *accountController) ForgotPassword(c *gin.Context) {
+	request := &api.EmailRequest{}
+	if err := c.ShouldBind(request); err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid: "+err.Error())
+		return
+	}
+
+	if err := ctrl.accountService.ForgotPassword(request.Email); err != nil {
+		api.GetErrorJSON(c, http.StatusInternalServerError, "Failed to send password reset email")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "If the email is registered, a password reset link has been sent",
+	})
+}
+
+func (ctrl *accountController) ResetPassword(c *gin.Context) {
+	request := &api.ResetPasswordRequest{}
+	if err := c.ShouldBind(request); err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid: "+err.Error())
+		return
+	}
+
+	if err := ctrl.accountService.ResetPassword(request.Token, request.Password); err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Password has been reset",
+	})
+}
+
+func (ctrl *accountController) ConfirmEmailChange(c *gin.Context) {
+	request := &api.AuthTokenRequest{}
+	if err := c.ShouldBind(request); err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid: "+err.Error())
+		return
+	}
+
+	if err := ctrl.accountService.ConfirmEmailChange(request.Token); err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Email changed",
+	})
+}
+
+func (ctrl *accountController) ChangePassword(c *gin.Context, request *api.TokenChangePassword) {
+	userID, userType, ok := identityFromToken(c, request.TokenAccess.User.Login.Token)
+	if !ok {
+		return
+	}
+
+	if err := ctrl.accountService.ChangePassword(userID, userType, request.CurrentPassword, request.NewPassword); err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Password changed",
+	})
+}
+
+func (ctrl *accountController) ChangeEmail(c *gin.Context, request *api.TokenChangeEmail) {
+	userID, userType, ok := identityFromToken(c, request.TokenAccess.User.Login.Token)
+	if !ok {
+		return
+	}
+
+	if err := ctrl.accountService.ChangeEmail(userID, userType, request.CurrentPassword, request.NewEmail); err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Confirmation link was sent to the new email",
+	})
+}
+
+func NewAccountController(accountService service.AccountService) AccountController {
+	return &accountController{accountService: accountService}
+}
@@ -0,0 +1,53 @@
+package controller
+
+import (
+	"core/internal"
+	"core/internal/api"
+	"core/internal/security"
+	"core/internal/service"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type AdminController interface {
+	Login(c *gin.Context)
+}
+
+type adminController struct {
+	adminService service.AdminService
+}
+
+func (ctrl *adminController) Login(c *gin.Context) {
+	request := &api.LoginRequest{}
+	if err := c.ShouldBind(request); err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid: "+err.Error())
+		return
+	}
+
+	admin, err := ctrl.adminService.Login(request)
+	if err != nil {
+		if RespondLimited(c, err) {
+			return
+		}
+		api.GetErrorJSON(c, http.StatusUnauthorized, "Invalid credentials")
+		return
+	}
+
+	jwtToken := security.CreateAdminToken(admin.ID, internal.LifeTimeJWT)
+	if jwtToken == "" {
+		api.GetErrorJSON(c, http.StatusBadRequest, "the created jwt was faulty")
+		return
+	}
+	c.JSON(http.StatusOK, api.ResponseSuccessAccess{
+		StatusResponse: internal.StatusResponse{Status: "success"},
+		ResponseUser: api.ResponseUser{
+			ID:    admin.ID,
+			Token: jwtToken,
+			Type:  "admin",
+		},
+	})
+}
+
+func NewAdminController(adminService service.AdminService) AdminController {
+	return &adminController{adminService: adminService}
+}
@@ -0,0 +1,41 @@
+package controller
+
+import (
+	"core/internal/api"
+	"core/internal/service"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type AnalyticsController interface {
+	GetCompanyAnalytics(c *gin.Context, request *api.TokenCompanyAnalytics)
+}
+
+type analyticsController struct {
+	analyticsService service.AnalyticsService
+}
+
+func (ctrl *analyticsController) GetCompanyAnalytics(c *gin.Context, request *api.TokenCompanyAnalytics) {
+	userInfo, err := ExtractUserFromToken(request.TokenAccess.User.Login.Token)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	if !userInfo.IsCompany {
+		api.GetErrorJSON(c, http.StatusForbidden, "Only companies can access stats")
+		return
+	}
+
+	analytics, err := ctrl.analyticsService.GetCompanyAnalytics(userInfo.UserID, request.From, request.To, request.Interval)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"analytics": analytics})
+}
+
+func NewAnalyticsController(analyticsService service.AnalyticsService) AnalyticsController {
+	return &analyticsController{analyticsService: analyticsService}
+}
@@ -1,16 +1,35 @@
 package controller
 
 import (
+	"core/internal/database"
 	"core/internal/security"
 	"errors"
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// ErrSessionOutdated токен выдан до появления пользователей и не содержит пользователя
+var ErrSessionOutdated = errors.New("the session is outdated, please sign in again")
+
 // UserInfo представляет информацию о пользователе из токена
 type UserInfo struct {
 	UserID    uint
 	IsCompany bool
 	UserType  string // "client" или "company"
+
+	// Пользователь и его активная роль. У токенов, выданных до появления пользователей,
+	// IdentityID равен 0, а роль - client или owner
+	IdentityID uint
+	Role       string
+	CompanyID  uint
+}
+
+// Identity учетная запись для операций с паролем, email и 2FA: они относятся к пользователю,
+// а не к клиенту или компании
+func (u *UserInfo) Identity() (uint, string, error) {
+	if u.IdentityID == 0 {
+		return 0, "", ErrSessionOutdated
+	}
+	return u.IdentityID, "user", nil
 }
 
 // ExtractUserFromToken извлекает информацию о пользователе из токена
@@ -34,14 +53,30 @@ func ExtractUserFromToken(token string) (*UserInfo, error) {
 	}
 
 	userType := "client"
+	role := database.RoleClient
+	var companyID uint
 	if isCompany {
 		userType = "company"
+		role = database.RoleOwner
+		companyID = accessID
+	}
+
+	// Роль и пользователь есть только в токенах CreateSessionToken
+	var identityID uint
+	if userIDFloat, ok := claims["userID"].(float64); ok {
+		identityID = uint(userIDFloat)
+		if claimRole, ok := claims["role"].(string); ok && claimRole != "" {
+			role = claimRole
+		}
 	}
 
 	return &UserInfo{
-		UserID:    accessID,
-		IsCompany: isCompany,
-		UserType:  userType,
+		UserID:     accessID,
+		IsCompany:  isCompany,
+		UserType:   userType,
+		IdentityID: identityID,
+		Role:       role,
+		CompanyID:  companyID,
 	}, nil
 }
 
@@ -62,3 +97,41 @@ func IsCompanyFromClaims(claims jwt.MapClaims) (bool, error) {
 	}
 	return isCompany, nil
 }
+
+// WorkerInfo представляет информацию о работнике компании из токена
+type WorkerInfo struct {
+	WorkerID  uint
+	CompanyID uint
+}
+
+// ExtractWorkerFromToken извлекает информацию о работнике из токена работника
+func ExtractWorkerFromToken(token string) (*WorkerInfo, error) {
+	isValid, claims := security.CheckWorkerToken(token)
+	if !isValid || claims == nil {
+		return nil, errors.New("invalid or expired token")
+	}
+
+	workerIDFloat, ok := claims["accessID"].(float64)
+	if !ok {
+		return nil, errors.New("invalid accessID in token")
+	}
+
+	companyIDFloat, ok := claims["companyID"].(float64)
+	if !ok {
+		return nil, errors.New("invalid companyID in token")
+	}
+
+	return &WorkerInfo{
+		WorkerID:  uint(workerIDFloat),
+		CompanyID: uint(companyIDFloat),
+	}, nil
+}
+
+// ExtractAdminFromToken извлекает ID администратора из токена администратора
+func ExtractAdminFromToken(token string) (uint, error) {
+	isValid, claims := security.CheckAdminToken(token)
+	if !isValid || claims == nil {
+		return 0, errors.New("invalid or expired token")
+	}
+	return GetUserIDFromClaims(claims)
+}
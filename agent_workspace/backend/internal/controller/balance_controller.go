@@ -3,10 +3,11 @@ package controller
 import (
 	"core/internal"
 	"core/internal/api"
+	"core/internal/money"
+	"core/internal/pagination"
 	"core/internal/service"
 	"github.com/gin-gonic/gin"
 	"net/http"
-	"strconv"
 )
 
 type BalanceController interface {
@@ -18,10 +19,12 @@ type BalanceController interface {
 	GetCompanyTransactions(c *gin.Context, request *api.TokenAccessDouble)
 	TopUpBalance(c *gin.Context, request *api.TokenTopUpBalance)
 	GetBalanceHistory(c *gin.Context, request *api.TokenBalanceHistory)
+	SetCurrency(c *gin.Context, request *api.TokenSetCurrency)
 }
 
 type balanceController struct {
-	balanceService service.BalanceService
+	balanceService   service.BalanceService
+	twoFactorService service.TwoFactorService
 }
 
 func (ctrl *balanceController) GetClientBalance(c *gin.Context, request *api.TokenAccess) {
@@ -42,7 +45,7 @@ func (ctrl *balanceController) GetClientBalance(c *gin.Context, request *api.Tok
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"balance": balance})
+	c.JSON(http.StatusOK, balanceJSON(c, balance, gin.H{}))
 }
 
 func (ctrl *balanceController) GetCompanyBalance(c *gin.Context, request *api.TokenAccess) {
@@ -63,7 +66,7 @@ func (ctrl *balanceController) GetCompanyBalance(c *gin.Context, request *api.To
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"balance": balance})
+	c.JSON(http.StatusOK, balanceJSON(c, balance, gin.H{}))
 }
 
 func (ctrl *balanceController) DepositClientBalance(c *gin.Context, request *api.TokenDepositBalance) {
@@ -96,11 +99,10 @@ func (ctrl *balanceController) DepositClientBalance(c *gin.Context, request *api
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	c.JSON(http.StatusOK, balanceJSON(c, newBalance, gin.H{
 		"message": "Balance deposited successfully",
-		"balance": newBalance,
 		"amount":  request.Amount,
-	})
+	}))
 }
 
 func (ctrl *balanceController) WithdrawCompanyBalance(c *gin.Context, request *api.TokenWithdrawBalance) {
@@ -120,6 +122,11 @@ func (ctrl *balanceController) WithdrawCompanyBalance(c *gin.Context, request *a
 		return
 	}
 
+	// Вывод средств подтверждается кодом 2FA даже с доверенного устройства
+	if !requireStepUp(c, ctrl.twoFactorService, userInfo, request.TwoFactorCode) {
+		return
+	}
+
 	err = ctrl.balanceService.WithdrawCompanyBalance(userInfo.UserID, request.Amount)
 	if err != nil {
 		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
@@ -133,11 +140,10 @@ func (ctrl *balanceController) WithdrawCompanyBalance(c *gin.Context, request *a
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	c.JSON(http.StatusOK, balanceJSON(c, newBalance, gin.H{
 		"message": "Balance withdrawn successfully",
-		"balance": newBalance,
 		"amount":  request.Amount,
-	})
+	}))
 }
 
 func (ctrl *balanceController) GetClientTransactions(c *gin.Context, request *api.TokenAccessDouble) {
@@ -152,17 +158,13 @@ func (ctrl *balanceController) GetClientTransactions(c *gin.Context, request *ap
 		return
 	}
 
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
-
-	if page < 1 {
-		page = 1
-	}
-	if limit < 1 || limit > 100 {
-		limit = 10
+	pageRequest, page, err := parsePageQuery(c)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
 	}
 
-	transactions, err := ctrl.balanceService.GetClientTransactions(userInfo.UserID, page, limit)
+	transactions, next, err := ctrl.balanceService.GetClientTransactions(userInfo.UserID, pageRequest)
 	if err != nil {
 		api.GetErrorJSON(c, http.StatusInternalServerError, "Failed to get transactions")
 		return
@@ -171,7 +173,8 @@ func (ctrl *balanceController) GetClientTransactions(c *gin.Context, request *ap
 	c.JSON(http.StatusOK, gin.H{
 		"transactions": transactions,
 		"page":         page,
-		"limit":        limit,
+		"limit":        pageRequest.Limit,
+		"pagination":   next,
 	})
 }
 
@@ -187,23 +190,19 @@ func (ctrl *balanceController) GetCompanyTransactions(c *gin.Context, request *a
 		return
 	}
 
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
-
-	if page < 1 {
-		page = 1
-	}
-	if limit < 1 || limit > 100 {
-		limit = 10
+	pageRequest, page, err := parsePageQuery(c)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
 	}
 
-	transactions, err := ctrl.balanceService.GetCompanyTransactions(userInfo.UserID, page, limit)
+	transactions, next, err := ctrl.balanceService.GetCompanyTransactions(userInfo.UserID, pageRequest)
 	if err != nil {
 		api.GetErrorJSON(c, http.StatusInternalServerError, "Failed to get transactions")
 		return
 	}
 
-	var totalAmount float64
+	var totalAmount money.Minor
 	for _, t := range transactions {
 		totalAmount += t.Amount
 	}
@@ -211,7 +210,8 @@ func (ctrl *balanceController) GetCompanyTransactions(c *gin.Context, request *a
 	c.JSON(http.StatusOK, gin.H{
 		"transactions": transactions,
 		"page":         page,
-		"limit":        limit,
+		"limit":        pageRequest.Limit,
+		"pagination":   next,
 		"total":        totalAmount,
 	})
 }
@@ -229,7 +229,7 @@ func (ctrl *balanceController) TopUpBalance(c *gin.Context, request *api.TokenTo
 	}
 
 	// Пополняем баланс в зависимости от типа пользователя
-	var newBalance float64
+	var newBalance money.Money
 	if userInfo.IsCompany {
 		err = ctrl.balanceService.DepositCompanyBalance(userInfo.UserID, request.Amount)
 		if err != nil {
@@ -251,12 +251,11 @@ func (ctrl *balanceController) TopUpBalance(c *gin.Context, request *api.TokenTo
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	c.JSON(http.StatusOK, balanceJSON(c, newBalance, gin.H{
 		"status":  "success",
 		"message": "Balance topped up successfully",
-		"balance": newBalance,
 		"amount":  request.Amount,
-	})
+	}))
 }
 
 func (ctrl *balanceController) GetBalanceHistory(c *gin.Context, request *api.TokenBalanceHistory) {
@@ -266,17 +265,13 @@ func (ctrl *balanceController) GetBalanceHistory(c *gin.Context, request *api.To
 		return
 	}
 
-	// Устанавливаем значения по умолчанию
-	limit := request.Limit
-	offset := request.Offset
-	if limit <= 0 || limit > 100 {
-		limit = 10
-	}
-	if offset < 0 {
-		offset = 0
+	page, err := pagination.Parse(request.Cursor, request.Limit, request.Offset)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
 	}
 
-	transactions, total, err := ctrl.balanceService.GetTransactionHistory(userInfo.UserID, userInfo.UserType, limit, offset)
+	transactions, total, next, err := ctrl.balanceService.GetTransactionHistory(userInfo.UserID, userInfo.UserType, page)
 	if err != nil {
 		api.GetErrorJSON(c, http.StatusInternalServerError, "Failed to get transaction history")
 		return
@@ -286,9 +281,45 @@ func (ctrl *balanceController) GetBalanceHistory(c *gin.Context, request *api.To
 		StatusResponse: internal.StatusResponse{Status: "success"},
 		Transactions:   transactions,
 		Total:          total,
+		Pagination:     next,
 	})
 }
 
-func NewBalanceController(balanceService service.BalanceService) BalanceController {
-	return &balanceController{balanceService: balanceService}
+func (ctrl *balanceController) SetCurrency(c *gin.Context, request *api.TokenSetCurrency) {
+	userInfo, err := ExtractUserFromToken(request.TokenAccess.User.Login.Token)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	if err := ctrl.balanceService.SetCurrency(userInfo.UserID, userInfo.UserType, request.Currency); err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var balance money.Money
+	if userInfo.IsCompany {
+		balance, err = ctrl.balanceService.GetCompanyBalance(userInfo.UserID)
+	} else {
+		balance, err = ctrl.balanceService.GetClientBalance(userInfo.UserID)
+	}
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusInternalServerError, "Failed to get balance")
+		return
+	}
+
+	c.JSON(http.StatusOK, balanceJSON(c, balance, gin.H{"status": "success"}))
+}
+
+// balanceJSON дополняет ответ балансом, его валютой и записью суммы по правилам
+// локали из заголовка Accept-Language
+func balanceJSON(c *gin.Context, balance money.Money, response gin.H) gin.H {
+	response["balance"] = balance.Amount
+	response["currency"] = balance.Currency
+	response["balance_formatted"] = money.Format(balance, money.ParseLocale(c.GetHeader("Accept-Language")))
+	return response
+}
+
+func NewBalanceController(balanceService service.BalanceService, twoFactorService service.TwoFactorService) BalanceController {
+	return &balanceController{balanceService: balanceService, twoFactorService: twoFactorService}
 }
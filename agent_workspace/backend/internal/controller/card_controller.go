@@ -2,10 +2,12 @@ package controller
 
 import (
 	"core/internal/api"
+	"core/internal/database/repository"
 	"core/internal/service"
 	"github.com/gin-gonic/gin"
 	"net/http"
 	"strconv"
+	"strings"
 )
 
 type CardController interface {
@@ -14,6 +16,7 @@ type CardController interface {
 	GetCardByID(c *gin.Context)
 	SearchCards(c *gin.Context)
 	GetCardsByPriceRange(c *gin.Context)
+	FindCards(c *gin.Context)
 	CreateCard(c *gin.Context, request *api.TokenCreateCard)
 	UpdateCard(c *gin.Context, request *api.TokenUpdateCard)
 	DeleteCard(c *gin.Context, request *api.TokenDeleteCard)
@@ -25,52 +28,46 @@ type cardController struct {
 }
 
 func (ctrl *cardController) GetAllCards(c *gin.Context) {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
-
-	if page < 1 {
-		page = 1
-	}
-	if limit < 1 || limit > 100 {
-		limit = 10
+	pageRequest, page, err := parsePageQuery(c)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
 	}
 
-	cards, err := ctrl.cardService.GetAllCards(page, limit)
+	cards, next, err := ctrl.cardService.GetAllCards(pageRequest)
 	if err != nil {
 		api.GetErrorJSON(c, http.StatusInternalServerError, "Failed to get cards")
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"cards": cards,
-		"page":  page,
-		"limit": limit,
+		"cards":      cards,
+		"page":       page,
+		"limit":      pageRequest.Limit,
+		"pagination": next,
 	})
 }
 
 func (ctrl *cardController) GetCardsByCategory(c *gin.Context) {
 	category := c.Param("category")
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
-
-	if page < 1 {
-		page = 1
-	}
-	if limit < 1 || limit > 100 {
-		limit = 10
+	pageRequest, page, err := parsePageQuery(c)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
 	}
 
-	cards, err := ctrl.cardService.GetCardsByCategory(category, page, limit)
+	cards, next, err := ctrl.cardService.GetCardsByCategory(category, pageRequest)
 	if err != nil {
-		api.GetErrorJSON(c, http.StatusInternalServerError, "Failed to get cards")
+		api.GetErrorJSON(c, http.StatusNotFound, err.Error())
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"cards":    cards,
-		"category": category,
-		"page":     page,
-		"limit":    limit,
+		"cards":      cards,
+		"category":   category,
+		"page":       page,
+		"limit":      pageRequest.Limit,
+		"pagination": next,
 	})
 }
 
@@ -98,27 +95,24 @@ func (ctrl *cardController) SearchCards(c *gin.Context) {
 		return
 	}
 
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
-
-	if page < 1 {
-		page = 1
-	}
-	if limit < 1 || limit > 100 {
-		limit = 10
+	pageRequest, page, err := parsePageQuery(c)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
 	}
 
-	cards, err := ctrl.cardService.SearchCards(query, page, limit)
+	cards, next, err := ctrl.cardService.SearchCards(query, pageRequest)
 	if err != nil {
 		api.GetErrorJSON(c, http.StatusInternalServerError, "Failed to search cards")
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"cards": cards,
-		"query": query,
-		"page":  page,
-		"limit": limit,
+		"cards":      cards,
+		"query":      query,
+		"page":       page,
+		"limit":      pageRequest.Limit,
+		"pagination": next,
 	})
 }
 
@@ -172,6 +166,76 @@ func (ctrl *cardController) GetCardsByPriceRange(c *gin.Context) {
 	})
 }
 
+func (ctrl *cardController) FindCards(c *gin.Context) {
+	filter := repository.CardSearchFilter{
+		Query:        strings.TrimSpace(c.Query("q")),
+		Category:     c.Query("category"),
+		Location:     c.Query("location"),
+		VerifiedOnly: c.Query("verified") == "true" || c.Query("verified") == "1",
+		Sort:         c.Query("sort"),
+	}
+
+	var err error
+	if filter.MinPrice, err = parseOptionalFloat(c, "min_price"); err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, "Invalid min_price")
+		return
+	}
+	if filter.MaxPrice, err = parseOptionalFloat(c, "max_price"); err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, "Invalid max_price")
+		return
+	}
+	if filter.MinRating, err = parseOptionalFloat(c, "min_rating"); err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, "Invalid min_rating")
+		return
+	}
+	if filter.Latitude, err = parseOptionalFloat(c, "lat"); err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, "Invalid lat")
+		return
+	}
+	if filter.Longitude, err = parseOptionalFloat(c, "lon"); err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, "Invalid lon")
+		return
+	}
+	radius, err := parseOptionalFloat(c, "radius_km")
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, "Invalid radius_km")
+		return
+	}
+	if radius != nil {
+		filter.RadiusKm = *radius
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	result, err := ctrl.cardService.FindCards(filter, page, limit)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func parseOptionalFloat(c *gin.Context, name string) (*float64, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &parsed, nil
+}
+
 func (ctrl *cardController) CreateCard(c *gin.Context, request *api.TokenCreateCard) {
 	userInfo, err := ExtractUserFromToken(request.TokenAccess.User.Login.Token)
 	if err != nil {
@@ -189,6 +253,7 @@ func (ctrl *cardController) CreateCard(c *gin.Context, request *api.TokenCreateC
 		request.Card.Title,
 		request.Card.Description,
 		request.Card.Category,
+		request.Card.CategoryID,
 		request.Card.Location,
 		request.Card.Price,
 	)
@@ -218,6 +283,7 @@ func (ctrl *cardController) UpdateCard(c *gin.Context, request *api.TokenUpdateC
 		request.Card.Title,
 		request.Card.Description,
 		request.Card.Category,
+		request.Card.CategoryID,
 		request.Card.Location,
 		request.Card.Price,
 	)
@@ -262,26 +328,23 @@ func (ctrl *cardController) GetCompanyCards(c *gin.Context, request *api.TokenLi
 		return
 	}
 
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
-
-	if page < 1 {
-		page = 1
-	}
-	if limit < 1 || limit > 100 {
-		limit = 10
+	pageRequest, page, err := parsePageQuery(c)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
 	}
 
-	cards, err := ctrl.cardService.GetCardsByCompany(userInfo.UserID, page, limit)
+	cards, next, err := ctrl.cardService.GetCardsByCompany(userInfo.UserID, pageRequest)
 	if err != nil {
 		api.GetErrorJSON(c, http.StatusInternalServerError, "Failed to get cards")
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"cards": cards,
-		"page":  page,
-		"limit": limit,
+		"cards":      cards,
+		"page":       page,
+		"limit":      pageRequest.Limit,
+		"pagination": next,
 	})
 }
 
@@ -0,0 +1,132 @@
+package controller
+
+import (
+	"core/internal/api"
+	"core/internal/service"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type CartController interface {
+	GetCart(c *gin.Context, request *api.TokenAccess)
+	AddItem(c *gin.Context, request *api.TokenCartItem)
+	UpdateItem(c *gin.Context, request *api.TokenCartItem)
+	RemoveItem(c *gin.Context, request *api.TokenCartItem)
+	ClearCart(c *gin.Context, request *api.TokenAccess)
+	Checkout(c *gin.Context, request *api.TokenCartCheckout)
+}
+
+type cartController struct {
+	cartService service.CartService
+}
+
+func (ctrl *cartController) GetCart(c *gin.Context, request *api.TokenAccess) {
+	clientID, ok := ctrl.client(c, request)
+	if !ok {
+		return
+	}
+
+	cart, err := ctrl.cartService.GetCart(clientID)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusInternalServerError, "Failed to get cart")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"cart": cart})
+}
+
+func (ctrl *cartController) AddItem(c *gin.Context, request *api.TokenCartItem) {
+	clientID, ok := ctrl.client(c, &request.TokenAccess)
+	if !ok {
+		return
+	}
+
+	cart, err := ctrl.cartService.AddItem(clientID, request.CardID, request.Quantity)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"cart": cart})
+}
+
+func (ctrl *cartController) UpdateItem(c *gin.Context, request *api.TokenCartItem) {
+	clientID, ok := ctrl.client(c, &request.TokenAccess)
+	if !ok {
+		return
+	}
+
+	cart, err := ctrl.cartService.UpdateItem(clientID, request.CardID, request.Quantity)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"cart": cart})
+}
+
+func (ctrl *cartController) RemoveItem(c *gin.Context, request *api.TokenCartItem) {
+	clientID, ok := ctrl.client(c, &request.TokenAccess)
+	if !ok {
+		return
+	}
+
+	cart, err := ctrl.cartService.RemoveItem(clientID, request.CardID)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"cart": cart})
+}
+
+func (ctrl *cartController) ClearCart(c *gin.Context, request *api.TokenAccess) {
+	clientID, ok := ctrl.client(c, request)
+	if !ok {
+		return
+	}
+
+	if err := ctrl.cartService.Clear(clientID); err != nil {
+		api.GetErrorJSON(c, http.StatusInternalServerError, "Failed to clear cart")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Cart cleared",
+	})
+}
+
+func (ctrl *cartController) Checkout(c *gin.Context, request *api.TokenCartCheckout) {
+	clientID, ok := ctrl.client(c, &request.TokenAccess)
+	if !ok {
+		return
+	}
+
+	checkout, err := ctrl.cartService.Checkout(clientID, request.Checkout)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"checkout": checkout})
+}
+
+// client проверяет токен и возвращает ID клиента. Корзина есть только у клиентов
+func (ctrl *cartController) client(c *gin.Context, request *api.TokenAccess) (uint, bool) {
+	userInfo, err := ExtractUserFromToken(request.User.Login.Token)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return 0, false
+	}
+
+	if userInfo.IsCompany {
+		api.GetErrorJSON(c, http.StatusForbidden, "Only clients can use the cart")
+		return 0, false
+	}
+	return userInfo.UserID, true
+}
+
+func NewCartController(cartService service.CartService) CartController {
+	return &cartController{cartService: cartService}
+}
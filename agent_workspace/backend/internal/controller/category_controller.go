@@ -0,0 +1,97 @@
+package controller
+
+import (
+	"core/internal/api"
+	"core/internal/service"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type CategoryController interface {
+	GetTree(c *gin.Context)
+	AdminGetTree(c *gin.Context, request *api.TokenAccess)
+	CreateCategory(c *gin.Context, request *api.TokenAdminCategory)
+	UpdateCategory(c *gin.Context, request *api.TokenAdminCategory)
+	DeleteCategory(c *gin.Context, request *api.TokenAdminCategory)
+}
+
+type categoryController struct {
+	categoryService service.CategoryService
+}
+
+// GetTree отдает дерево активных категорий. Параметр locale=en выбирает английские названия
+func (ctrl *categoryController) GetTree(c *gin.Context) {
+	tree, err := ctrl.categoryService.GetTree(c.DefaultQuery("locale", "ru"), false)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusInternalServerError, "Failed to get categories")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"categories": tree})
+}
+
+func (ctrl *categoryController) AdminGetTree(c *gin.Context, request *api.TokenAccess) {
+	if _, err := ExtractAdminFromToken(request.User.Login.Token); err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	tree, err := ctrl.categoryService.GetTree(c.DefaultQuery("locale", "ru"), true)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusInternalServerError, "Failed to get categories")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"categories": tree})
+}
+
+func (ctrl *categoryController) CreateCategory(c *gin.Context, request *api.TokenAdminCategory) {
+	if _, err := ExtractAdminFromToken(request.TokenAccess.User.Login.Token); err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	category, err := ctrl.categoryService.CreateCategory(request.Category)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"category": category})
+}
+
+func (ctrl *categoryController) UpdateCategory(c *gin.Context, request *api.TokenAdminCategory) {
+	if _, err := ExtractAdminFromToken(request.TokenAccess.User.Login.Token); err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	category, err := ctrl.categoryService.UpdateCategory(request.CategoryID, request.Category)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"category": category})
+}
+
+func (ctrl *categoryController) DeleteCategory(c *gin.Context, request *api.TokenAdminCategory) {
+	if _, err := ExtractAdminFromToken(request.TokenAccess.User.Login.Token); err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	if err := ctrl.categoryService.DeleteCategory(request.CategoryID); err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Category deleted successfully",
+	})
+}
+
+func NewCategoryController(categoryService service.CategoryService) CategoryController {
+	return &categoryController{categoryService: categoryService}
+}
@@ -3,7 +3,7 @@ package controller
 import (
 	"core/internal"
 	"core/internal/api"
-	"core/internal/security"
+	"core/internal/money"
 	"core/internal/service"
 	"github.com/gin-gonic/gin"
 	"net/http"
@@ -18,7 +18,9 @@ type ClientController interface {
 }
 
 type clientController struct {
-	service service.ClientService
+	service          service.ClientService
+	identityService  service.IdentityService
+	twoFactorService service.TwoFactorService
 }
 
 func (controller clientController) Signup(c *gin.Context) {
@@ -34,20 +36,28 @@ func (controller clientController) Signup(c *gin.Context) {
 		return
 	}
 
-	client, err := controller.service.SignupSimple(request)
+	client, user, err := controller.service.SignupSimple(request)
 	if err != nil {
 		api.GetErrorJSON(c, http.StatusPreconditionFailed, err.Error())
 		return
 	}
-	tokenGenerated := security.CreateToken(false, client.ID, internal.LifeTimeJWT)
-	c.JSON(http.StatusOK, api.ResponseSuccessAccess{
-		StatusResponse: internal.StatusResponse{Status: "success"},
-		ResponseUser: api.ResponseUser{
-			ID:    client.ID,
-			Token: tokenGenerated,
-			Type:  client.Type,
-		},
-	})
+
+	// Пока email не подтвержден, токен не выдается: войти можно после перехода по ссылке из письма
+	if client.EmailVerifiedAt == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  "verification_required",
+			"message": "Confirmation link was sent to " + client.Email,
+			"id":      client.ID,
+			"type":    client.Type,
+		})
+		return
+	}
+	session, err := controller.identityService.StartSession(user.ID, "client", 0)
+	if err != nil {
+		respondSessionError(c, err)
+		return
+	}
+	respondSession(c, session)
 }
 
 func (controller clientController) Login(c *gin.Context) {
@@ -63,41 +73,12 @@ func (controller clientController) Login(c *gin.Context) {
 		return
 	}
 
-	dbUser, err := controller.service.LoginSimple(request)
-	if err != nil {
-		api.GetErrorJSON(c, http.StatusPreconditionFailed, err.Error())
-		return
-	}
-
-	jwtToken := security.CreateToken(false, dbUser.ID, internal.LifeTimeJWT)
-	if jwtToken == "" {
-		api.GetErrorJSON(c, http.StatusBadRequest, "the created jwt was faulty")
-		return
-	}
-	c.JSON(http.StatusOK, api.ResponseSuccessAccess{
-		StatusResponse: internal.StatusResponse{Status: "success"},
-		ResponseUser: api.ResponseUser{
-			ID:    dbUser.ID,
-			Token: jwtToken,
-			Type:  dbUser.Type,
-		},
-	})
+	LoginUser(c, controller.identityService, controller.twoFactorService, request.Email, request.Password, "client")
 }
 
 func (controller clientController) LoginOld(c *gin.Context, request *api.GeneralAuth) {
-	dbUser, jwtToken, err := controller.service.Login(request)
-	if err != nil {
-		api.GetErrorJSON(c, http.StatusBadRequest, "the created jwt was faulty")
-		return
-	}
-	c.JSON(http.StatusOK, api.ResponseSuccessAccess{
-		StatusResponse: internal.StatusResponse{Status: "success"},
-		ResponseUser: api.ResponseUser{
-			ID:    dbUser.ID,
-			Token: jwtToken,
-			Type:  dbUser.Type,
-		},
-	})
+	credentials := request.GeneralLogin.GeneralLoginAttributes
+	LoginUser(c, controller.identityService, controller.twoFactorService, credentials.Email, credentials.PasswordHash, "client")
 }
 
 func (controller clientController) GetAccount(c *gin.Context, request *api.TokenAccess) {
@@ -123,6 +104,9 @@ func (controller clientController) GetAccount(c *gin.Context, request *api.Token
 			Token:    response.ResponseUser.Token,
 			Type:     user.Type,
 			Balance:  user.Balance,
+
+			Currency:         user.Currency,
+			BalanceFormatted: money.Format(money.New(user.Balance, user.Currency), money.ParseLocale(c.GetHeader("Accept-Language"))),
 		}},
 	})
 }
@@ -155,12 +139,17 @@ func (controller clientController) UpdateProfile(c *gin.Context, request *api.To
 			Token:    request.TokenAccess.User.Login.Token,
 			Type:     updatedUser.Type,
 			Balance:  updatedUser.Balance,
+
+			Currency:         updatedUser.Currency,
+			BalanceFormatted: money.Format(money.New(updatedUser.Balance, updatedUser.Currency), money.ParseLocale(c.GetHeader("Accept-Language"))),
 		}},
 	})
 }
 
-func NewClientController(service service.ClientService) ClientController {
+func NewClientController(service service.ClientService, identityService service.IdentityService, twoFactorService service.TwoFactorService) ClientController {
 	return &clientController{
-		service: service,
+		service:          service,
+		identityService:  identityService,
+		twoFactorService: twoFactorService,
 	}
 }
@@ -3,7 +3,7 @@ package controller
 import (
 	"core/internal"
 	"core/internal/api"
-	"core/internal/security"
+	"core/internal/pagination"
 	"core/internal/service"
 	"github.com/gin-gonic/gin"
 	"net/http"
@@ -20,10 +20,18 @@ type CompanyController interface {
 	UpdateProfile(c *gin.Context, request *api.TokenUpdateClientProfileDouble)
 	GetStats(c *gin.Context, request *api.TokenCompanyStats)
 	UpdateCard(c *gin.Context, request *api.TokenUpdateCard)
+	GetPayoutDetails(c *gin.Context, request *api.TokenAccess)
+	UpdatePayoutDetails(c *gin.Context, request *api.TokenUpdatePayoutDetails)
+
+	AdminVerificationQueue(c *gin.Context, request *api.TokenCompanyVerificationQueue)
+	AdminVerify(c *gin.Context, request *api.TokenVerifyCompany)
+	AdminUnverify(c *gin.Context, request *api.TokenVerifyCompany)
 }
 
 type companyController struct {
-	service service.CompanyService
+	service          service.CompanyService
+	identityService  service.IdentityService
+	twoFactorService service.TwoFactorService
 }
 
 func (controller companyController) Signup(c *gin.Context) {
@@ -39,20 +47,28 @@ func (controller companyController) Signup(c *gin.Context) {
 		return
 	}
 
-	company, err := controller.service.SignupSimple(request)
+	company, user, err := controller.service.SignupSimple(request)
 	if err != nil {
 		api.GetErrorJSON(c, http.StatusPreconditionFailed, err.Error())
 		return
 	}
-	tokenGenerated := security.CreateToken(true, company.ID, internal.LifeTimeJWT)
-	c.JSON(http.StatusOK, api.ResponseSuccessAccess{
-		StatusResponse: internal.StatusResponse{Status: "success"},
-		ResponseUser: api.ResponseUser{
-			ID:    company.ID,
-			Token: tokenGenerated,
-			Type:  company.Type,
-		},
-	})
+
+	// Пока email не подтвержден, токен не выдается: войти можно после перехода по ссылке из письма
+	if company.EmailVerifiedAt == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  "verification_required",
+			"message": "Confirmation link was sent to " + company.Email,
+			"id":      company.ID,
+			"type":    company.Type,
+		})
+		return
+	}
+	session, err := controller.identityService.StartSession(user.ID, "company", 0)
+	if err != nil {
+		respondSessionError(c, err)
+		return
+	}
+	respondSession(c, session)
 }
 
 func (controller companyController) Login(c *gin.Context) {
@@ -68,41 +84,12 @@ func (controller companyController) Login(c *gin.Context) {
 		return
 	}
 
-	dbUser, err := controller.service.LoginSimple(request)
-	if err != nil {
-		api.GetErrorJSON(c, http.StatusPreconditionFailed, err.Error())
-		return
-	}
-
-	jwtToken := security.CreateToken(true, dbUser.ID, internal.LifeTimeJWT)
-	if jwtToken == "" {
-		api.GetErrorJSON(c, http.StatusBadRequest, "the created jwt was faulty")
-		return
-	}
-	c.JSON(http.StatusOK, api.ResponseSuccessAccess{
-		StatusResponse: internal.StatusResponse{Status: "success"},
-		ResponseUser: api.ResponseUser{
-			ID:    dbUser.ID,
-			Token: jwtToken,
-			Type:  dbUser.Type,
-		},
-	})
+	LoginUser(c, controller.identityService, controller.twoFactorService, request.Email, request.Password, "company")
 }
 
 func (controller companyController) LoginOld(c *gin.Context, request *api.GeneralAuth) {
-	dbUser, jwtToken, err := controller.service.Login(request)
-	if err != nil {
-		api.GetErrorJSON(c, http.StatusBadRequest, "the created jwt was faulty")
-		return
-	}
-	c.JSON(http.StatusOK, api.ResponseSuccessAccess{
-		StatusResponse: internal.StatusResponse{Status: "success"},
-		ResponseUser: api.ResponseUser{
-			ID:    dbUser.ID,
-			Token: jwtToken,
-			Type:  dbUser.Type,
-		},
-	})
+	credentials := request.GeneralLogin.GeneralLoginAttributes
+	LoginUser(c, controller.identityService, controller.twoFactorService, credentials.Email, credentials.PasswordHash, "company")
 }
 
 func (controller companyController) GetAccount(c *gin.Context, request *api.TokenAccess) {
@@ -129,7 +116,6 @@ func (controller companyController) GetAccount(c *gin.Context, request *api.Toke
 			IDCompany:     user.IDCompany,
 			Address:       user.Address,
 			TypeService:   user.TypeService,
-			PasswordHash:  user.PasswordHash,
 			Photo:         user.Photo,
 			Documents:     user.Documents,
 			Type:          user.Type,
@@ -259,8 +245,111 @@ func (controller companyController) UpdateCard(c *gin.Context, request *api.Toke
 	})
 }
 
-func NewCompanyController(service service.CompanyService) CompanyController {
+func (controller companyController) GetPayoutDetails(c *gin.Context, request *api.TokenAccess) {
+	userInfo, err := ExtractUserFromToken(request.User.Login.Token)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+	if !userInfo.IsCompany {
+		api.GetErrorJSON(c, http.StatusForbidden, "Only companies have payout details")
+		return
+	}
+
+	details, err := controller.service.GetPayoutDetails(userInfo.UserID)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusNotFound, "Company not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"details": details,
+	})
+}
+
+func (controller companyController) UpdatePayoutDetails(c *gin.Context, request *api.TokenUpdatePayoutDetails) {
+	userInfo, err := ExtractUserFromToken(request.TokenAccess.User.Login.Token)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+	if !userInfo.IsCompany {
+		api.GetErrorJSON(c, http.StatusForbidden, "Only companies have payout details")
+		return
+	}
+
+	// Смена реквизитов подтверждается кодом 2FA даже с доверенного устройства
+	if !requireStepUp(c, controller.twoFactorService, userInfo, request.TwoFactorCode) {
+		return
+	}
+
+	if err := controller.service.UpdatePayoutDetails(userInfo.UserID, request.Details); err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Payout details updated",
+	})
+}
+
+func (controller companyController) AdminVerificationQueue(c *gin.Context, request *api.TokenCompanyVerificationQueue) {
+	if _, err := ExtractAdminFromToken(request.TokenAccess.User.Login.Token); err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	page, err := pagination.Parse(request.Cursor, request.Limit, request.Offset)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	companies, next, err := controller.service.GetVerificationQueue(page)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusInternalServerError, "Failed to get companies")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"companies":  companies,
+		"pagination": next,
+	})
+}
+
+func (controller companyController) AdminVerify(c *gin.Context, request *api.TokenVerifyCompany) {
+	controller.setVerified(c, request, true)
+}
+
+func (controller companyController) AdminUnverify(c *gin.Context, request *api.TokenVerifyCompany) {
+	controller.setVerified(c, request, false)
+}
+
+func (controller companyController) setVerified(c *gin.Context, request *api.TokenVerifyCompany, verified bool) {
+	adminID, err := ExtractAdminFromToken(request.TokenAccess.User.Login.Token)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	if err := controller.service.SetVerified(adminID, request.CompanyID, verified); err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	message := "Company verified"
+	if !verified {
+		message = "Company verification revoked"
+	}
+	c.JSON(http.StatusOK, gin.H{"message": message})
+}
+
+func NewCompanyController(service service.CompanyService, identityService service.IdentityService, twoFactorService service.TwoFactorService) CompanyController {
 	return &companyController{
-		service: service,
+		service:          service,
+		identityService:  identityService,
+		twoFactorService: twoFactorService,
 	}
 }
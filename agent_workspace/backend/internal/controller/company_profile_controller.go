@@ -0,0 +1,37 @@
+package controller
+
+import (
+	"core/internal/api"
+	"core/internal/service"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+)
+
+type CompanyProfileController interface {
+	GetPublicProfile(c *gin.Context)
+}
+
+type companyProfileController struct {
+	profileService service.CompanyProfileService
+}
+
+func (ctrl *companyProfileController) GetPublicProfile(c *gin.Context) {
+	companyID, err := strconv.ParseUint(c.Param("company_id"), 10, 32)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, "Invalid company ID")
+		return
+	}
+
+	profile, err := ctrl.profileService.GetPublicProfile(uint(companyID))
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"company": profile})
+}
+
+func NewCompanyProfileController(profileService service.CompanyProfileService) CompanyProfileController {
+	return &companyProfileController{profileService: profileService}
+}
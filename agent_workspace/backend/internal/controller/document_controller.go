@@ -0,0 +1,57 @@
+package controller
+
+import (
+	"core/internal/api"
+	"core/internal/service"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type DocumentController interface {
+	GetOrderInvoice(c *gin.Context, request *api.TokenOrderAction)
+	GetTransactionDocument(c *gin.Context, request *api.TokenTransactionDocument)
+}
+
+type documentController struct {
+	documentService service.DocumentService
+}
+
+func (ctrl *documentController) GetOrderInvoice(c *gin.Context, request *api.TokenOrderAction) {
+	userInfo, err := ExtractUserFromToken(request.TokenAccess.User.Login.Token)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	document, err := ctrl.documentService.GetOrderInvoice(request.OrderID, userInfo.UserID, userInfo.UserType)
+	if err != nil {
+		status := http.StatusNotFound
+		if err.Error() == "access denied" {
+			status = http.StatusForbidden
+		}
+		api.GetErrorJSON(c, status, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"document": document})
+}
+
+func (ctrl *documentController) GetTransactionDocument(c *gin.Context, request *api.TokenTransactionDocument) {
+	userInfo, err := ExtractUserFromToken(request.TokenAccess.User.Login.Token)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	document, err := ctrl.documentService.GetTransactionDocument(request.TransactionID, userInfo.UserID, userInfo.UserType)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"document": document})
+}
+
+func NewDocumentController(documentService service.DocumentService) DocumentController {
+	return &documentController{documentService: documentService}
+}
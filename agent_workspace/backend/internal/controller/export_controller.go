@@ -0,0 +1,95 @@
+package controller
+
+import (
+	"core/internal/api"
+	"core/internal/service"
+	"github.com/gin-gonic/gin"
+	"io"
+	"log"
+	"net/http"
+)
+
+type ExportController interface {
+	CreateExport(c *gin.Context, request *api.TokenExport)
+	GetExportJob(c *gin.Context, request *api.TokenExportJob)
+	GetExportJobs(c *gin.Context, request *api.TokenAccess)
+}
+
+type exportController struct {
+	exportService service.ExportService
+}
+
+// CreateExport отдает небольшую выгрузку файлом в ответе, а для большой возвращает
+// фоновую задачу со статусом 202
+func (ctrl *exportController) CreateExport(c *gin.Context, request *api.TokenExport) {
+	userInfo, err := ExtractUserFromToken(request.TokenAccess.User.Login.Token)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	target := func(fileName, contentType string) io.Writer {
+		c.Header("Content-Type", contentType)
+		c.Header("Content-Disposition", `attachment; filename="`+fileName+`"`)
+		c.Status(http.StatusOK)
+		return c.Writer
+	}
+	job, err := ctrl.exportService.Export(userInfo.UserID, userInfo.UserType, service.ExportRequest{
+		Kind:   request.Kind,
+		Format: request.Format,
+		From:   request.From,
+		To:     request.To,
+		Status: request.Status,
+	}, target)
+	if err != nil {
+		if c.Writer.Written() {
+			// Часть файла уже отправлена, сообщить об ошибке можно только обрывом ответа
+			log.Printf("export for %s %d interrupted: %v", userInfo.UserType, userInfo.UserID, err)
+			c.Abort()
+			return
+		}
+		c.Writer.Header().Del("Content-Disposition")
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if job != nil {
+		c.JSON(http.StatusAccepted, gin.H{"job": job})
+	}
+}
+
+func (ctrl *exportController) GetExportJob(c *gin.Context, request *api.TokenExportJob) {
+	userInfo, err := ExtractUserFromToken(request.TokenAccess.User.Login.Token)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	job, err := ctrl.exportService.GetJob(request.JobID, userInfo.UserID, userInfo.UserType)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"job": job})
+}
+
+func (ctrl *exportController) GetExportJobs(c *gin.Context, request *api.TokenAccess) {
+	userInfo, err := ExtractUserFromToken(request.User.Login.Token)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	jobs, err := ctrl.exportService.GetJobs(userInfo.UserID, userInfo.UserType)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusInternalServerError, "Failed to get export jobs")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"jobs": jobs})
+}
+
+func NewExportController(exportService service.ExportService) ExportController {
+	return &exportController{exportService: exportService}
+}
@@ -0,0 +1,182 @@
+package controller
+
+import (
+	"core/internal/api"
+	"core/internal/service"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type FavoriteController interface {
+	AddFavorite(c *gin.Context, request *api.TokenFavorite)
+	RemoveFavorite(c *gin.Context, request *api.TokenFavorite)
+	ListFavorites(c *gin.Context, request *api.TokenAccess)
+	CreateSavedSearch(c *gin.Context, request *api.TokenSavedSearch)
+	UpdateSavedSearch(c *gin.Context, request *api.TokenSavedSearch)
+	DeleteSavedSearch(c *gin.Context, request *api.TokenSavedSearch)
+	ListSavedSearches(c *gin.Context, request *api.TokenAccess)
+}
+
+type favoriteController struct {
+	favoriteService service.FavoriteService
+}
+
+func (ctrl *favoriteController) AddFavorite(c *gin.Context, request *api.TokenFavorite) {
+	userInfo, err := ExtractUserFromToken(request.TokenAccess.User.Login.Token)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	if userInfo.IsCompany {
+		api.GetErrorJSON(c, http.StatusForbidden, "Only clients can use favorites")
+		return
+	}
+
+	err = ctrl.favoriteService.AddFavorite(userInfo.UserID, request.TargetType, request.TargetID)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Added to favorites",
+	})
+}
+
+func (ctrl *favoriteController) RemoveFavorite(c *gin.Context, request *api.TokenFavorite) {
+	userInfo, err := ExtractUserFromToken(request.TokenAccess.User.Login.Token)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	if userInfo.IsCompany {
+		api.GetErrorJSON(c, http.StatusForbidden, "Only clients can use favorites")
+		return
+	}
+
+	err = ctrl.favoriteService.RemoveFavorite(userInfo.UserID, request.TargetType, request.TargetID)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Removed from favorites",
+	})
+}
+
+func (ctrl *favoriteController) ListFavorites(c *gin.Context, request *api.TokenAccess) {
+	userInfo, err := ExtractUserFromToken(request.User.Login.Token)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	if userInfo.IsCompany {
+		api.GetErrorJSON(c, http.StatusForbidden, "Only clients can use favorites")
+		return
+	}
+
+	favorites, err := ctrl.favoriteService.GetFavorites(userInfo.UserID)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusInternalServerError, "Failed to get favorites")
+		return
+	}
+
+	c.JSON(http.StatusOK, favorites)
+}
+
+func (ctrl *favoriteController) CreateSavedSearch(c *gin.Context, request *api.TokenSavedSearch) {
+	userInfo, err := ExtractUserFromToken(request.TokenAccess.User.Login.Token)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	if userInfo.IsCompany {
+		api.GetErrorJSON(c, http.StatusForbidden, "Only clients can save searches")
+		return
+	}
+
+	search, err := ctrl.favoriteService.CreateSavedSearch(userInfo.UserID, request.Search)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"search": search})
+}
+
+func (ctrl *favoriteController) UpdateSavedSearch(c *gin.Context, request *api.TokenSavedSearch) {
+	userInfo, err := ExtractUserFromToken(request.TokenAccess.User.Login.Token)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	if userInfo.IsCompany {
+		api.GetErrorJSON(c, http.StatusForbidden, "Only clients can save searches")
+		return
+	}
+
+	search, err := ctrl.favoriteService.UpdateSavedSearch(userInfo.UserID, request.SavedSearchID, request.Search)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"search": search})
+}
+
+func (ctrl *favoriteController) DeleteSavedSearch(c *gin.Context, request *api.TokenSavedSearch) {
+	userInfo, err := ExtractUserFromToken(request.TokenAccess.User.Login.Token)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	if userInfo.IsCompany {
+		api.GetErrorJSON(c, http.StatusForbidden, "Only clients can save searches")
+		return
+	}
+
+	err = ctrl.favoriteService.DeleteSavedSearch(userInfo.UserID, request.SavedSearchID)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Saved search deleted successfully",
+	})
+}
+
+func (ctrl *favoriteController) ListSavedSearches(c *gin.Context, request *api.TokenAccess) {
+	userInfo, err := ExtractUserFromToken(request.User.Login.Token)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	if userInfo.IsCompany {
+		api.GetErrorJSON(c, http.StatusForbidden, "Only clients can save searches")
+		return
+	}
+
+	searches, err := ctrl.favoriteService.GetSavedSearches(userInfo.UserID)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusInternalServerError, "Failed to get saved searches")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"searches": searches})
+}
+
+func NewFavoriteController(favoriteService service.FavoriteService) FavoriteController {
+	return &favoriteController{favoriteService: favoriteService}
+}
@@ -0,0 +1,111 @@
+package controller
+
+import (
+	"core/internal/api"
+	"core/internal/service"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type FeeController interface {
+	ListRules(c *gin.Context, request *api.TokenAccess)
+	CreateRule(c *gin.Context, request *api.TokenAdminFeeRule)
+	UpdateRule(c *gin.Context, request *api.TokenAdminFeeRule)
+	DeleteRule(c *gin.Context, request *api.TokenAdminFeeRule)
+	SetCompanyTier(c *gin.Context, request *api.TokenAdminCompanyTier)
+}
+
+type feeController struct {
+	feeService service.FeeService
+}
+
+func (ctrl *feeController) ListRules(c *gin.Context, request *api.TokenAccess) {
+	if _, err := ExtractAdminFromToken(request.User.Login.Token); err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	rules, err := ctrl.feeService.GetRules()
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusInternalServerError, "Failed to get fee rules")
+		return
+	}
+	revenue, err := ctrl.feeService.GetPlatformRevenue()
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusInternalServerError, "Failed to get platform revenue")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"rules":            rules,
+		"platform_revenue": revenue,
+	})
+}
+
+func (ctrl *feeController) CreateRule(c *gin.Context, request *api.TokenAdminFeeRule) {
+	if _, err := ExtractAdminFromToken(request.TokenAccess.User.Login.Token); err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	rule, err := ctrl.feeService.CreateRule(request.Rule)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"rule": rule})
+}
+
+func (ctrl *feeController) UpdateRule(c *gin.Context, request *api.TokenAdminFeeRule) {
+	if _, err := ExtractAdminFromToken(request.TokenAccess.User.Login.Token); err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	rule, err := ctrl.feeService.UpdateRule(request.RuleID, request.Rule)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"rule": rule})
+}
+
+func (ctrl *feeController) DeleteRule(c *gin.Context, request *api.TokenAdminFeeRule) {
+	if _, err := ExtractAdminFromToken(request.TokenAccess.User.Login.Token); err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	if err := ctrl.feeService.DeleteRule(request.RuleID); err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Fee rule deleted successfully",
+	})
+}
+
+func (ctrl *feeController) SetCompanyTier(c *gin.Context, request *api.TokenAdminCompanyTier) {
+	if _, err := ExtractAdminFromToken(request.TokenAccess.User.Login.Token); err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	if err := ctrl.feeService.SetCompanyTier(request.CompanyID, request.Tier); err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Company tier updated",
+	})
+}
+
+func NewFeeController(feeService service.FeeService) FeeController {
+	return &feeController{feeService: feeService}
+}
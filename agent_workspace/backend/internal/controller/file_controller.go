@@ -0,0 +1,50 @@
+package controller
+
+import (
+	"core/internal/api"
+	"core/internal/security"
+	"core/internal/storage"
+	"github.com/gin-gonic/gin"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+type FileController interface {
+	ServeFile(c *gin.Context)
+}
+
+type fileController struct {
+	fileStorage storage.Storage
+}
+
+// ServeFile отдает файл из хранилища по подписанной ссылке
+func (ctrl *fileController) ServeFile(c *gin.Context) {
+	name := strings.TrimPrefix(c.Param("path"), "/")
+	if !security.VerifyPathSignature("/files/"+name, c.Query("expires"), c.Query("signature")) {
+		api.GetErrorJSON(c, http.StatusForbidden, "The link is invalid or expired")
+		return
+	}
+
+	file, err := ctrl.fileStorage.Open(name)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusNotFound, "File not found")
+		return
+	}
+	defer file.Close()
+
+	contentType := mime.TypeByExtension(filepath.Ext(name))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("Cache-Control", "private, max-age=3600")
+	c.Status(http.StatusOK)
+	io.Copy(c.Writer, file)
+}
+
+func NewFileController(fileStorage storage.Storage) FileController {
+	return &fileController{fileStorage: fileStorage}
+}
@@ -0,0 +1,301 @@
+package controller
+
+import (
+	"core/internal"
+	"core/internal/api"
+	"core/internal/service"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// Ключи контекста, которые заполняет CheckCompanyMembership: пользователь из токена в теле
+// запроса и его текущая роль в компании
+const (
+	bodyUserKey    = "bodyUser"
+	companyRoleKey = "companyRole"
+)
+
+type IdentityController interface {
+	ListAccounts(c *gin.Context, request *api.TokenAccess)
+	SwitchAccount(c *gin.Context, request *api.TokenSwitchAccount)
+	CreateClientProfile(c *gin.Context, request *api.TokenCreateClientProfile)
+	CreateCompany(c *gin.Context, request *api.TokenCreateCompany)
+
+	ListMembers(c *gin.Context, request *api.TokenAccess)
+	AddMember(c *gin.Context, request *api.TokenAddCompanyMember)
+	UpdateMemberRole(c *gin.Context, request *api.TokenUpdateCompanyMember)
+	RemoveMember(c *gin.Context, request *api.TokenRemoveCompanyMember)
+}
+
+type identityController struct {
+	identityService service.IdentityService
+}
+
+func (ctrl *identityController) ListAccounts(c *gin.Context, request *api.TokenAccess) {
+	userID, _, ok := identityFromToken(c, request.User.Login.Token)
+	if !ok {
+		return
+	}
+
+	accounts, err := ctrl.identityService.ListAccounts(userID)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusInternalServerError, "Failed to get accounts")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":   "success",
+		"accounts": accounts,
+	})
+}
+
+func (ctrl *identityController) SwitchAccount(c *gin.Context, request *api.TokenSwitchAccount) {
+	userID, _, ok := identityFromToken(c, request.TokenAccess.User.Login.Token)
+	if !ok {
+		return
+	}
+
+	user, err := ctrl.identityService.StartSession(userID, request.AccountType, request.CompanyID)
+	if err != nil {
+		respondSessionError(c, err)
+		return
+	}
+	respondSession(c, user)
+}
+
+func (ctrl *identityController) CreateClientProfile(c *gin.Context, request *api.TokenCreateClientProfile) {
+	userID, _, ok := identityFromToken(c, request.TokenAccess.User.Login.Token)
+	if !ok {
+		return
+	}
+
+	user, err := ctrl.identityService.CreateClientProfile(userID, request.Phone)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	respondSession(c, user)
+}
+
+func (ctrl *identityController) CreateCompany(c *gin.Context, request *api.TokenCreateCompany) {
+	userID, _, ok := identityFromToken(c, request.TokenAccess.User.Login.Token)
+	if !ok {
+		return
+	}
+
+	user, err := ctrl.identityService.CreateCompany(userID, request.Company)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	respondSession(c, user)
+}
+
+func (ctrl *identityController) ListMembers(c *gin.Context, request *api.TokenAccess) {
+	userInfo, ok := companyFromToken(c, request.User.Login.Token)
+	if !ok {
+		return
+	}
+
+	members, err := ctrl.identityService.ListMembers(userInfo.UserID)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusInternalServerError, "Failed to get company members")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"members": members,
+	})
+}
+
+func (ctrl *identityController) AddMember(c *gin.Context, request *api.TokenAddCompanyMember) {
+	userInfo, ok := companyFromToken(c, request.TokenAccess.User.Login.Token)
+	if !ok {
+		return
+	}
+
+	member, err := ctrl.identityService.AddMember(userInfo.UserID, request.Email, request.Role)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"member": member,
+	})
+}
+
+func (ctrl *identityController) UpdateMemberRole(c *gin.Context, request *api.TokenUpdateCompanyMember) {
+	userInfo, ok := companyFromToken(c, request.TokenAccess.User.Login.Token)
+	if !ok {
+		return
+	}
+
+	if err := ctrl.identityService.UpdateMemberRole(userInfo.UserID, request.UserID, request.Role); err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Member role updated",
+	})
+}
+
+func (ctrl *identityController) RemoveMember(c *gin.Context, request *api.TokenRemoveCompanyMember) {
+	userInfo, ok := companyFromToken(c, request.TokenAccess.User.Login.Token)
+	if !ok {
+		return
+	}
+
+	if err := ctrl.identityService.RemoveMember(userInfo.UserID, request.UserID); err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Member removed",
+	})
+}
+
+// LoginUser вход по email и паролю для /v1/login, /v1/login/client и /v1/login/company.
+// accountType - роль, в которой выдается токен, пустая - роль по умолчанию. При включенной
+// 2FA вместо токена отвечает challenge
+func LoginUser(c *gin.Context, identityService service.IdentityService, twoFactorService service.TwoFactorService, email, password, accountType string) {
+	user, err := identityService.Login(email, password)
+	if err != nil {
+		RespondLoginError(c, err)
+		return
+	}
+	startUserSession(c, identityService, twoFactorService, user.ID, accountType, 0)
+}
+
+// startUserSession выдает токен пользователю, прошедшему первый шаг входа, или challenge,
+// если у него включена 2FA
+func startUserSession(c *gin.Context, identityService service.IdentityService, twoFactorService service.TwoFactorService, userID uint, accountType string, companyID uint) {
+	if TwoFactorChallenge(c, twoFactorService, userID, "user") {
+		return
+	}
+
+	session, err := identityService.StartSession(userID, accountType, companyID)
+	if err != nil {
+		respondSessionError(c, err)
+		return
+	}
+	respondSession(c, session)
+}
+
+// CheckCompanyMembership проверяет, что пользователь из токена компании все еще в ней
+// состоит, и запоминает его текущую роль для RequireCompanyRole. Исключение сотрудника
+// и смена роли действуют сразу, не дожидаясь истечения токена. Запрос с токеном, который
+// не удалось разобрать, отклоняется, чтобы обработчик не прочитал другое поле тела
+func CheckCompanyMembership(identityService service.IdentityService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userInfo, err := userFromBody(peekBody(c))
+		if err != nil {
+			api.GetErrorJSON(c, http.StatusUnauthorized, "The token is invalid")
+			c.Abort()
+			return
+		}
+		if userInfo == nil {
+			c.Next()
+			return
+		}
+		c.Set(bodyUserKey, userInfo)
+		if !userInfo.IsCompany {
+			c.Next()
+			return
+		}
+
+		role := userInfo.Role
+		if userInfo.IdentityID != 0 {
+			role, err = identityService.MemberRole(userInfo.IdentityID, userInfo.UserID)
+			if err != nil {
+				api.GetErrorJSON(c, http.StatusForbidden, "You are no longer a member of this company")
+				c.Abort()
+				return
+			}
+		}
+		c.Set(companyRoleKey, role)
+		c.Next()
+	}
+}
+
+// RequireCompanyRole пропускает компанию, только если роль пользователя в ней из roles.
+// Клиентов не ограничивает. Работает после CheckCompanyMembership и отклоняет запросы,
+// пользователь которых ею не определен
+func RequireCompanyRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, ok := c.Get(bodyUserKey)
+		if !ok {
+			api.GetErrorJSON(c, http.StatusUnauthorized, "The token is invalid")
+			c.Abort()
+			return
+		}
+		if userInfo, _ := value.(*UserInfo); userInfo != nil && !userInfo.IsCompany {
+			c.Next()
+			return
+		}
+		role := c.GetString(companyRoleKey)
+		for _, allowed := range roles {
+			if role == allowed {
+				c.Next()
+				return
+			}
+		}
+		api.GetErrorJSON(c, http.StatusForbidden, "Your role in the company does not allow this action")
+		c.Abort()
+	}
+}
+
+func respondSession(c *gin.Context, user *api.ResponseUser) {
+	c.JSON(http.StatusOK, api.ResponseSuccessAccess{
+		StatusResponse: internal.StatusResponse{Status: "success"},
+		ResponseUser:   *user,
+	})
+}
+
+func respondSessionError(c *gin.Context, err error) {
+	if errors.Is(err, service.ErrAccountNotAvailable) {
+		api.GetErrorJSON(c, http.StatusForbidden, err.Error())
+		return
+	}
+	api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+}
+
+// identityFromToken возвращает пользователя из токена. Токены без пользователя выданы до его
+// появления, для операций с ролями нужно войти заново
+func identityFromToken(c *gin.Context, token string) (uint, string, bool) {
+	userInfo, err := ExtractUserFromToken(token)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return 0, "", false
+	}
+	userID, userType, err := userInfo.Identity()
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return 0, "", false
+	}
+	return userID, userType, true
+}
+
+func companyFromToken(c *gin.Context, token string) (*UserInfo, bool) {
+	userInfo, err := ExtractUserFromToken(token)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return nil, false
+	}
+	if !userInfo.IsCompany {
+		api.GetErrorJSON(c, http.StatusForbidden, "You're not a company")
+		return nil, false
+	}
+	return userInfo, true
+}
+
+func NewIdentityController(identityService service.IdentityService) IdentityController {
+	return &identityController{identityService: identityService}
+}
@@ -3,6 +3,7 @@ package controller
 import (
 	"core/internal"
 	"core/internal/api"
+	"core/internal/pagination"
 	"core/internal/service"
 	"github.com/gin-gonic/gin"
 	"net/http"
@@ -25,21 +26,21 @@ func (ctrl *notificationController) GetNotifications(c *gin.Context, request *ap
 		return
 	}
 
-	// Устанавливаем значения по умолчанию
+	// Уведомлений по умолчанию показываем больше, чем записей в других списках
 	limit := request.Limit
-	offset := request.Offset
-	if limit <= 0 || limit > 100 {
+	if limit <= 0 {
 		limit = 20
 	}
-	if offset < 0 {
-		offset = 0
+	page, err := pagination.Parse(request.Cursor, limit, request.Offset)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
 	}
 
-	notifications, total, err := ctrl.notificationService.GetUserNotifications(
+	notifications, total, next, err := ctrl.notificationService.GetUserNotifications(
 		userInfo.UserID,
 		userInfo.UserType,
-		limit,
-		offset,
+		page,
 	)
 	if err != nil {
 		api.GetErrorJSON(c, http.StatusInternalServerError, "Failed to get notifications")
@@ -50,6 +51,7 @@ func (ctrl *notificationController) GetNotifications(c *gin.Context, request *ap
 		StatusResponse: internal.StatusResponse{Status: "success"},
 		Notifications:  notifications,
 		Total:          total,
+		Pagination:     next,
 	})
 }
 
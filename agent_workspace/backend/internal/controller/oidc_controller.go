@@ -0,0 +1,165 @@
+package controller
+
+import (
+	"core/internal/api"
+	"core/internal/oidc"
+	"core/internal/service"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+)
+
+type OIDCController interface {
+	Providers(c *gin.Context)
+	StartLogin(c *gin.Context)
+	CompleteLogin(c *gin.Context)
+
+	ListIdentities(c *gin.Context, request *api.TokenAccess)
+	StartLink(c *gin.Context, request *api.TokenOIDCLinkStart)
+	CompleteLink(c *gin.Context, request *api.TokenOIDCLinkComplete)
+	Unlink(c *gin.Context, request *api.TokenOIDCUnlink)
+}
+
+type oidcController struct {
+	oidcService      service.OIDCService
+	identityService  service.IdentityService
+	twoFactorService service.TwoFactorService
+}
+
+func (ctrl *oidcController) Providers(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status":    "success",
+		"providers": ctrl.oidcService.Providers(),
+	})
+}
+
+func (ctrl *oidcController) StartLogin(c *gin.Context) {
+	request := &api.OIDCStartRequest{}
+	if err := c.ShouldBind(request); err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid: "+err.Error())
+		return
+	}
+
+	authorization, err := ctrl.oidcService.StartLogin(request.Provider)
+	if err != nil {
+		respondOIDCError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":        "success",
+		"authorization": authorization,
+	})
+}
+
+// CompleteLogin выдает токен так же, как вход по паролю: при включенной 2FA отвечает challenge
+func (ctrl *oidcController) CompleteLogin(c *gin.Context) {
+	request := &api.OIDCCallbackRequest{}
+	if err := c.ShouldBind(request); err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid: "+err.Error())
+		return
+	}
+
+	user, err := ctrl.oidcService.CompleteLogin(request.State, request.Code)
+	if err != nil {
+		respondOIDCError(c, err)
+		return
+	}
+	startUserSession(c, ctrl.identityService, ctrl.twoFactorService, user.ID, request.AccountType, request.CompanyID)
+}
+
+func (ctrl *oidcController) ListIdentities(c *gin.Context, request *api.TokenAccess) {
+	userID, _, ok := identityFromToken(c, request.User.Login.Token)
+	if !ok {
+		return
+	}
+
+	identities, err := ctrl.oidcService.ListIdentities(userID)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusInternalServerError, "Failed to get linked accounts")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":     "success",
+		"identities": identities,
+	})
+}
+
+func (ctrl *oidcController) StartLink(c *gin.Context, request *api.TokenOIDCLinkStart) {
+	userID, _, ok := identityFromToken(c, request.TokenAccess.User.Login.Token)
+	if !ok {
+		return
+	}
+
+	authorization, err := ctrl.oidcService.StartLink(userID, request.Provider)
+	if err != nil {
+		respondOIDCError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":        "success",
+		"authorization": authorization,
+	})
+}
+
+func (ctrl *oidcController) CompleteLink(c *gin.Context, request *api.TokenOIDCLinkComplete) {
+	userID, _, ok := identityFromToken(c, request.TokenAccess.User.Login.Token)
+	if !ok {
+		return
+	}
+
+	identity, err := ctrl.oidcService.CompleteLink(userID, request.State, request.Code)
+	if err != nil {
+		respondOIDCError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":   "success",
+		"identity": identity,
+	})
+}
+
+func (ctrl *oidcController) Unlink(c *gin.Context, request *api.TokenOIDCUnlink) {
+	userID, _, ok := identityFromToken(c, request.TokenAccess.User.Login.Token)
+	if !ok {
+		return
+	}
+
+	if err := ctrl.oidcService.Unlink(userID, request.Provider); err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Provider unlinked",
+	})
+}
+
+// respondOIDCError отвечает на ошибку входа или привязки. Подробности сбоев провайдера
+// пишутся в журнал, клиенту уходит общее сообщение
+func respondOIDCError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrUnknownProvider):
+		api.GetErrorJSON(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrInvalidOIDCState):
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrProviderEmailNotVerified),
+		errors.Is(err, service.ErrProviderDomainNotAllowed),
+		errors.Is(err, service.ErrIdentityLinked):
+		api.GetErrorJSON(c, http.StatusForbidden, err.Error())
+	case errors.Is(err, oidc.ErrInvalidIDToken):
+		log.Printf("oidc sign-in rejected: %v", err)
+		api.GetErrorJSON(c, http.StatusUnauthorized, oidc.ErrInvalidIDToken.Error())
+	default:
+		log.Printf("oidc sign-in failed: %v", err)
+		api.GetErrorJSON(c, http.StatusBadGateway, "Failed to sign in with the identity provider")
+	}
+}
+
+func NewOIDCController(oidcService service.OIDCService, identityService service.IdentityService, twoFactorService service.TwoFactorService) OIDCController {
+	return &oidcController{
+		oidcService:      oidcService,
+		identityService:  identityService,
+		twoFactorService: twoFactorService,
+	}
+}
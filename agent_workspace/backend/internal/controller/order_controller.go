@@ -3,10 +3,22 @@ package controller
 import (
 	"core/internal"
 	"core/internal/api"
+	"core/internal/pagination"
+	"core/internal/security"
 	"core/internal/service"
+	"errors"
 	"github.com/gin-gonic/gin"
+	"mime/multipart"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	workerCSRFCookie       = "worker_csrf"
+	maxCompletionPhotoSize = 10 << 20
+	maxCompletionFormSize  = service.MaxCompletionPhotos*maxCompletionPhotoSize + 1<<20
 )
 
 type OrderController interface {
@@ -16,7 +28,9 @@ type OrderController interface {
 	GetCompanyOrders(c *gin.Context, request *api.TokenAccess)
 	PayOrder(c *gin.Context, request *api.TokenOrderAction)
 	StartOrder(c *gin.Context, request *api.TokenOrderAction)
+	ShowWorkerCompleteForm(c *gin.Context)
 	CompleteOrderByWorker(c *gin.Context)
+	GetCompletionReport(c *gin.Context, request *api.TokenOrderAction)
 	FinishOrder(c *gin.Context, request *api.TokenOrderAction)
 	CancelOrder(c *gin.Context, request *api.TokenOrderAction)
 	GetAllOrders(c *gin.Context)
@@ -40,11 +54,23 @@ func (ctrl *orderController) CreateOrder(c *gin.Context, request *api.TokenCreat
 		return
 	}
 
+	var scheduledAt *time.Time
+	if request.Order.ScheduledAt != "" {
+		parsed, err := time.Parse(time.RFC3339, request.Order.ScheduledAt)
+		if err != nil {
+			api.GetErrorJSON(c, http.StatusBadRequest, "Invalid scheduled_at, expected RFC3339")
+			return
+		}
+		scheduledAt = &parsed
+	}
+
 	order, err := ctrl.orderService.CreateOrder(
 		userInfo.UserID,
 		request.Order.CompanyID,
 		request.Order.CardID,
 		request.Order.Description,
+		scheduledAt,
+		request.Order.PromoCode,
 	)
 	if err != nil {
 		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
@@ -83,26 +109,23 @@ func (ctrl *orderController) GetClientOrders(c *gin.Context, request *api.TokenA
 		return
 	}
 
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
-
-	if page < 1 {
-		page = 1
-	}
-	if limit < 1 || limit > 100 {
-		limit = 10
+	pageRequest, page, err := parsePageQuery(c)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
 	}
 
-	orders, err := ctrl.orderService.GetOrdersByClient(userInfo.UserID, page, limit)
+	orders, next, err := ctrl.orderService.GetOrdersByClient(userInfo.UserID, pageRequest)
 	if err != nil {
 		api.GetErrorJSON(c, http.StatusInternalServerError, "Failed to get orders")
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"orders": orders,
-		"page":   page,
-		"limit":  limit,
+		"orders":     orders,
+		"page":       page,
+		"limit":      pageRequest.Limit,
+		"pagination": next,
 	})
 }
 
@@ -118,26 +141,23 @@ func (ctrl *orderController) GetCompanyOrders(c *gin.Context, request *api.Token
 		return
 	}
 
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
-
-	if page < 1 {
-		page = 1
-	}
-	if limit < 1 || limit > 100 {
-		limit = 10
+	pageRequest, page, err := parsePageQuery(c)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
 	}
 
-	orders, err := ctrl.orderService.GetOrdersByCompany(userInfo.UserID, page, limit)
+	orders, next, err := ctrl.orderService.GetOrdersByCompany(userInfo.UserID, pageRequest)
 	if err != nil {
 		api.GetErrorJSON(c, http.StatusInternalServerError, "Failed to get orders")
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"orders": orders,
-		"page":   page,
-		"limit":  limit,
+		"orders":     orders,
+		"page":       page,
+		"limit":      pageRequest.Limit,
+		"pagination": next,
 	})
 }
 
@@ -183,6 +203,22 @@ func (ctrl *orderController) StartOrder(c *gin.Context, request *api.TokenOrderA
 	c.JSON(http.StatusOK, gin.H{"message": "Order started successfully"})
 }
 
+func (ctrl *orderController) ShowWorkerCompleteForm(c *gin.Context) {
+	token := c.Param("token")
+	if token == "" {
+		api.GetErrorJSON(c, http.StatusBadRequest, "Token is required")
+		return
+	}
+
+	order, err := ctrl.orderService.OpenWorkerLink(token, service.WorkerLinkActionView, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		renderWorkerLinkError(c, err)
+		return
+	}
+
+	ctrl.renderWorkerCompleteForm(c, http.StatusOK, token, order.Card.Title, order.Description, "")
+}
+
 func (ctrl *orderController) CompleteOrderByWorker(c *gin.Context) {
 	token := c.Param("token")
 	if token == "" {
@@ -190,17 +226,152 @@ func (ctrl *orderController) CompleteOrderByWorker(c *gin.Context) {
 		return
 	}
 
-	err := ctrl.orderService.CompleteOrderByWorker(token)
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxCompletionFormSize)
+	form, err := c.MultipartForm()
 	if err != nil {
-		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		api.GetErrorJSON(c, http.StatusBadRequest, "Form is invalid")
+		return
+	}
+
+	nonce, _ := c.Cookie(workerCSRFCookie)
+	if !security.CheckCSRFToken(nonce, token, c.PostForm("csrf_token")) {
+		api.GetErrorJSON(c, http.StatusForbidden, "CSRF token is invalid")
+		return
+	}
+
+	order, err := ctrl.orderService.OpenWorkerLink(token, service.WorkerLinkActionSubmit, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		renderWorkerLinkError(c, err)
 		return
 	}
 
+	input, files, err := parseCompletionForm(c, form)
+	defer closeFiles(files)
+	if err == nil {
+		input.IP = c.ClientIP()
+		input.UserAgent = c.Request.UserAgent()
+		err = ctrl.orderService.CompleteOrderByWorker(token, input)
+	}
+	if err != nil {
+		ctrl.renderWorkerCompleteForm(c, http.StatusBadRequest, token, order.Card.Title, order.Description, err.Error())
+		return
+	}
+
+	c.SetCookie(workerCSRFCookie, "", -1, "/worker/complete/"+token, "", c.Request.TLS != nil, true)
 	c.HTML(http.StatusOK, "worker_success.html", gin.H{
 		"message": "Заказ успешно отмечен как выполненный!",
 	})
 }
 
+// renderWorkerLinkError показывает работнику, почему ссылка недоступна
+func renderWorkerLinkError(c *gin.Context, err error) {
+	status := http.StatusGone
+	var title, message string
+	switch {
+	case errors.Is(err, service.ErrWorkerLinkExpired):
+		title = "Срок действия ссылки истек"
+		message = "Попросите компанию выдать новую ссылку для завершения заказа."
+	case errors.Is(err, service.ErrWorkerLinkRevoked):
+		title = "Ссылка отозвана"
+		message = "Компания отозвала эту ссылку. Если работа еще не завершена, попросите новую."
+	case errors.Is(err, service.ErrWorkerLinkUsed):
+		title = "Заказ уже завершен"
+		message = "По этой ссылке уже отправлен отчет о выполнении."
+	case errors.Is(err, service.ErrWorkerLinkNotFound):
+		status = http.StatusNotFound
+		title = "Ссылка не найдена"
+		message = "Проверьте, что ссылка скопирована полностью."
+	default:
+		api.GetErrorJSON(c, http.StatusInternalServerError, "Failed to open worker link")
+		return
+	}
+
+	c.HTML(status, "worker_link_invalid.html", gin.H{
+		"title":   title,
+		"message": message,
+	})
+}
+
+// renderWorkerCompleteForm выдает форму отчета с новым CSRF токеном
+func (ctrl *orderController) renderWorkerCompleteForm(c *gin.Context, status int, token, serviceName, description, formError string) {
+	nonce, csrfToken, err := security.NewCSRFToken(token)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusInternalServerError, "Failed to create form")
+		return
+	}
+
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(workerCSRFCookie, nonce, 3600, "/worker/complete/"+token, "", c.Request.TLS != nil, true)
+	c.HTML(status, "worker_complete.html", gin.H{
+		"service_name": serviceName,
+		"description":  description,
+		"csrf_token":   csrfToken,
+		"max_photos":   service.MaxCompletionPhotos,
+		"error":        formError,
+	})
+}
+
+func parseCompletionForm(c *gin.Context, form *multipart.Form) (*service.CompletionReportInput, []multipart.File, error) {
+	input := &service.CompletionReportInput{
+		Notes: strings.TrimSpace(c.PostForm("notes")),
+	}
+
+	if value := c.PostForm("time_spent_minutes"); value != "" {
+		minutes, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, nil, errors.New("time spent must be a number of minutes")
+		}
+		input.TimeSpentMinutes = minutes
+	}
+
+	if latitude, longitude := c.PostForm("latitude"), c.PostForm("longitude"); latitude != "" || longitude != "" {
+		lat, errLat := strconv.ParseFloat(latitude, 64)
+		lon, errLon := strconv.ParseFloat(longitude, 64)
+		if errLat != nil || errLon != nil {
+			return nil, nil, errors.New("invalid coordinates")
+		}
+		input.Latitude = &lat
+		input.Longitude = &lon
+	}
+
+	var files []multipart.File
+	for _, header := range form.File["photos"] {
+		if header.Size > maxCompletionPhotoSize {
+			return nil, files, errors.New("photo is too large, max 10 MB")
+		}
+		file, err := header.Open()
+		if err != nil {
+			return nil, files, errors.New("failed to read photo")
+		}
+		files = append(files, file)
+		input.Photos = append(input.Photos, service.CompletionPhoto{Content: file})
+	}
+
+	return input, files, nil
+}
+
+func closeFiles(files []multipart.File) {
+	for _, file := range files {
+		file.Close()
+	}
+}
+
+func (ctrl *orderController) GetCompletionReport(c *gin.Context, request *api.TokenOrderAction) {
+	userInfo, err := ExtractUserFromToken(request.TokenAccess.User.Login.Token)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	report, err := ctrl.orderService.GetCompletionReport(request.OrderID, userInfo.UserID, userInfo.UserType)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"report": report})
+}
+
 func (ctrl *orderController) FinishOrder(c *gin.Context, request *api.TokenOrderAction) {
 	userInfo, err := ExtractUserFromToken(request.TokenAccess.User.Login.Token)
 	if err != nil {
@@ -239,26 +410,23 @@ func (ctrl *orderController) CancelOrder(c *gin.Context, request *api.TokenOrder
 }
 
 func (ctrl *orderController) GetAllOrders(c *gin.Context) {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
-
-	if page < 1 {
-		page = 1
-	}
-	if limit < 1 || limit > 100 {
-		limit = 10
+	pageRequest, page, err := parsePageQuery(c)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
 	}
 
-	orders, err := ctrl.orderService.GetAllOrders(page, limit)
+	orders, next, err := ctrl.orderService.GetAllOrders(pageRequest)
 	if err != nil {
 		api.GetErrorJSON(c, http.StatusInternalServerError, "Failed to get orders")
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"orders": orders,
-		"page":   page,
-		"limit":  limit,
+		"orders":     orders,
+		"page":       page,
+		"limit":      pageRequest.Limit,
+		"pagination": next,
 	})
 }
 
@@ -269,22 +437,17 @@ func (ctrl *orderController) ListOrders(c *gin.Context, request *api.TokenOrders
 		return
 	}
 
-	// Устанавливаем значения по умолчанию
-	limit := request.Limit
-	offset := request.Offset
-	if limit <= 0 || limit > 100 {
-		limit = 10
-	}
-	if offset < 0 {
-		offset = 0
+	page, err := pagination.Parse(request.Cursor, request.Limit, request.Offset)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
 	}
 
-	orders, total, err := ctrl.orderService.GetOrdersWithFilter(
+	orders, total, next, err := ctrl.orderService.GetOrdersWithFilter(
 		userInfo.UserID,
 		userInfo.UserType,
 		request.Status,
-		limit,
-		offset,
+		page,
 	)
 	if err != nil {
 		api.GetErrorJSON(c, http.StatusInternalServerError, "Failed to get orders")
@@ -295,6 +458,7 @@ func (ctrl *orderController) ListOrders(c *gin.Context, request *api.TokenOrders
 		StatusResponse: internal.StatusResponse{Status: "success"},
 		Orders:         orders,
 		Total:          total,
+		Pagination:     next,
 	})
 }
 
@@ -0,0 +1,24 @@
+package controller
+
+import (
+	"core/internal/pagination"
+	"github.com/gin-gonic/gin"
+	"strconv"
+)
+
+// parsePageQuery читает параметры страницы из строки запроса. Параметр cursor
+// (пустой для первой страницы) включает выдачу по курсору, иначе используется
+// page из v1. Возвращает номер страницы для ответа, в режиме курсора - 0
+func parsePageQuery(c *gin.Context) (pagination.Request, int, error) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	if cursor, ok := c.GetQuery("cursor"); ok {
+		request, err := pagination.Keyset(cursor, limit)
+		return request, 0, err
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	return pagination.FromPage(page, limit), page, nil
+}
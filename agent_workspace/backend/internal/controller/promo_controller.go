@@ -0,0 +1,223 @@
+package controller
+
+import (
+	"core/internal/api"
+	"core/internal/service"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type PromoController interface {
+	// Управление промокодами администратором
+	AdminListPromoCodes(c *gin.Context, request *api.TokenAccess)
+	AdminCreatePromoCode(c *gin.Context, request *api.TokenPromoCode)
+	AdminUpdatePromoCode(c *gin.Context, request *api.TokenPromoCode)
+	AdminDeletePromoCode(c *gin.Context, request *api.TokenPromoCode)
+
+	// Промокоды компании за ее счет
+	ListCompanyPromoCodes(c *gin.Context, request *api.TokenAccess)
+	CreateCompanyPromoCode(c *gin.Context, request *api.TokenPromoCode)
+	UpdateCompanyPromoCode(c *gin.Context, request *api.TokenPromoCode)
+
+	CheckPromoCode(c *gin.Context, request *api.TokenCheckPromoCode)
+	GetReferral(c *gin.Context, request *api.TokenAccess)
+	ApplyReferral(c *gin.Context, request *api.TokenApplyReferral)
+}
+
+type promoController struct {
+	promoService service.PromoService
+}
+
+func (ctrl *promoController) AdminListPromoCodes(c *gin.Context, request *api.TokenAccess) {
+	if _, err := ExtractAdminFromToken(request.User.Login.Token); err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	promoCodes, err := ctrl.promoService.GetPromoCodes(nil)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusInternalServerError, "Failed to get promo codes")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"promo_codes": promoCodes})
+}
+
+func (ctrl *promoController) AdminCreatePromoCode(c *gin.Context, request *api.TokenPromoCode) {
+	if _, err := ExtractAdminFromToken(request.TokenAccess.User.Login.Token); err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	promoCode, err := ctrl.promoService.CreatePromoCode(request.PromoCode, nil)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"promo_code": promoCode})
+}
+
+func (ctrl *promoController) AdminUpdatePromoCode(c *gin.Context, request *api.TokenPromoCode) {
+	if _, err := ExtractAdminFromToken(request.TokenAccess.User.Login.Token); err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	promoCode, err := ctrl.promoService.UpdatePromoCode(request.PromoCodeID, request.PromoCode, nil)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"promo_code": promoCode})
+}
+
+func (ctrl *promoController) AdminDeletePromoCode(c *gin.Context, request *api.TokenPromoCode) {
+	if _, err := ExtractAdminFromToken(request.TokenAccess.User.Login.Token); err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	if err := ctrl.promoService.DeletePromoCode(request.PromoCodeID); err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Promo code deleted successfully",
+	})
+}
+
+func (ctrl *promoController) ListCompanyPromoCodes(c *gin.Context, request *api.TokenAccess) {
+	userInfo, err := ExtractUserFromToken(request.User.Login.Token)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	if !userInfo.IsCompany {
+		api.GetErrorJSON(c, http.StatusForbidden, "Only companies can manage promo codes")
+		return
+	}
+
+	promoCodes, err := ctrl.promoService.GetPromoCodes(&userInfo.UserID)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusInternalServerError, "Failed to get promo codes")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"promo_codes": promoCodes})
+}
+
+func (ctrl *promoController) CreateCompanyPromoCode(c *gin.Context, request *api.TokenPromoCode) {
+	userInfo, err := ExtractUserFromToken(request.TokenAccess.User.Login.Token)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	if !userInfo.IsCompany {
+		api.GetErrorJSON(c, http.StatusForbidden, "Only companies can manage promo codes")
+		return
+	}
+
+	promoCode, err := ctrl.promoService.CreatePromoCode(request.PromoCode, &userInfo.UserID)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"promo_code": promoCode})
+}
+
+func (ctrl *promoController) UpdateCompanyPromoCode(c *gin.Context, request *api.TokenPromoCode) {
+	userInfo, err := ExtractUserFromToken(request.TokenAccess.User.Login.Token)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	if !userInfo.IsCompany {
+		api.GetErrorJSON(c, http.StatusForbidden, "Only companies can manage promo codes")
+		return
+	}
+
+	promoCode, err := ctrl.promoService.UpdatePromoCode(request.PromoCodeID, request.PromoCode, &userInfo.UserID)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"promo_code": promoCode})
+}
+
+func (ctrl *promoController) CheckPromoCode(c *gin.Context, request *api.TokenCheckPromoCode) {
+	userInfo, err := ExtractUserFromToken(request.TokenAccess.User.Login.Token)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	if userInfo.IsCompany {
+		api.GetErrorJSON(c, http.StatusForbidden, "Only clients can use promo codes")
+		return
+	}
+
+	quote, err := ctrl.promoService.CheckPromoCode(userInfo.UserID, request.Code, request.CardID)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"quote": quote})
+}
+
+func (ctrl *promoController) GetReferral(c *gin.Context, request *api.TokenAccess) {
+	userInfo, err := ExtractUserFromToken(request.User.Login.Token)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	if userInfo.IsCompany {
+		api.GetErrorJSON(c, http.StatusForbidden, "Only clients can invite other clients")
+		return
+	}
+
+	referral, err := ctrl.promoService.GetReferralInfo(userInfo.UserID)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusInternalServerError, "Failed to get referral code")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"referral": referral})
+}
+
+func (ctrl *promoController) ApplyReferral(c *gin.Context, request *api.TokenApplyReferral) {
+	userInfo, err := ExtractUserFromToken(request.TokenAccess.User.Login.Token)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	if userInfo.IsCompany {
+		api.GetErrorJSON(c, http.StatusForbidden, "Only clients can use referral codes")
+		return
+	}
+
+	if err := ctrl.promoService.ApplyReferralCode(userInfo.UserID, request.Code); err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Referral code applied",
+	})
+}
+
+func NewPromoController(promoService service.PromoService) PromoController {
+	return &promoController{promoService: promoService}
+}
@@ -0,0 +1,113 @@
+package controller
+
+import (
+	"bytes"
+	"core/internal/api"
+	"core/internal/ratelimit"
+	"core/internal/service"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// RateLimitByIP ограничивает частоту запросов к маршруту route с одного IP-адреса
+func RateLimitByIP(limiter *ratelimit.Limiter, route string, limit ratelimit.Limit) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := limiter.Allow("ip:"+route+":"+c.ClientIP(), limit); err != nil {
+			RespondLimited(c, err)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RateLimitByAccount ограничивает частоту запросов к маршруту route от одной учетной записи.
+// Учетная запись берется из токена в теле запроса, тело затем восстанавливается для обработчика.
+// Запросы без токена пропускаются: их отклонит сам обработчик. Запросы с недействительным
+// токеном отклоняются сразу, иначе обработчик мог бы взять другое поле без ограничения
+func RateLimitByAccount(limiter *ratelimit.Limiter, route string, limit ratelimit.Limit) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userInfo, err := userFromBody(peekBody(c))
+		if err != nil {
+			api.GetErrorJSON(c, http.StatusUnauthorized, "The token is invalid")
+			c.Abort()
+			return
+		}
+		if userInfo != nil {
+			key := fmt.Sprintf("account:%s:%s:%d", route, userInfo.UserType, userInfo.UserID)
+			if err := limiter.Allow(key, limit); err != nil {
+				RespondLimited(c, err)
+				c.Abort()
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// RespondLimited отвечает 429 с заголовком Retry-After, если err - ограничение частоты
+// или блокировка. Возвращает false для остальных ошибок
+func RespondLimited(c *gin.Context, err error) bool {
+	var limitErr *ratelimit.LimitError
+	if !errors.As(err, &limitErr) {
+		return false
+	}
+	c.Header("Retry-After", strconv.Itoa(limitErr.RetrySeconds()))
+	api.GetErrorJSON(c, http.StatusTooManyRequests, "Too many attempts, try again later")
+	return true
+}
+
+// RespondLoginError единый ответ на неудачный вход. Неверный пароль и незарегистрированный
+// email неотличимы. Неподтвержденный email сообщается только после верного пароля
+func RespondLoginError(c *gin.Context, err error) {
+	if RespondLimited(c, err) {
+		return
+	}
+	if errors.Is(err, service.ErrEmailNotVerified) {
+		api.GetErrorJSON(c, http.StatusForbidden, "Email is not verified")
+		return
+	}
+	api.GetErrorJSON(c, http.StatusUnauthorized, "Invalid email or password")
+}
+
+// peekBody читает тело запроса и восстанавливает его для следующих обработчиков
+func peekBody(c *gin.Context) []byte {
+	body, err := c.GetRawData()
+	if err != nil {
+		return nil
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	return body
+}
+
+// errAmbiguousToken в теле запроса разные токены в простом и расширенном форматах
+var errAmbiguousToken = errors.New("the request contains different tokens")
+
+// userFromBody извлекает пользователя из токена в теле запроса. Поддерживает простой
+// ({"user": ...}) и расширенный ({"token_access": {"user": ...}}) форматы. Обработчики
+// читают только один из них, поэтому, если заданы оба, токены должны совпадать. Тело без
+// токена дает nil без ошибки, токен, который не удалось разобрать, - ошибку
+func userFromBody(body []byte) (*UserInfo, error) {
+	var request struct {
+		api.TokenAccess
+		TokenAccessExtended api.TokenAccess `json:"token_access"`
+	}
+	// Ошибки типов не прерывают разбор, как и при привязке запроса в обработчике
+	_ = json.Unmarshal(body, &request)
+
+	token := request.TokenAccessExtended.User.Login.Token
+	if simple := request.User.Login.Token; token == "" {
+		token = simple
+	} else if simple != "" && simple != token {
+		return nil, errAmbiguousToken
+	}
+	if token == "" {
+		return nil, nil
+	}
+	return ExtractUserFromToken(token)
+}
@@ -0,0 +1,138 @@
+package controller
+
+import (
+	"core/internal/api"
+	"core/internal/service"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type RecurringOrderController interface {
+	CreateRecurringOrder(c *gin.Context, request *api.TokenRecurringOrder)
+	GetRecurringOrders(c *gin.Context, request *api.TokenAccess)
+	GetUpcoming(c *gin.Context, request *api.TokenRecurringUpcoming)
+	PauseRecurringOrder(c *gin.Context, request *api.TokenRecurringAction)
+	ResumeRecurringOrder(c *gin.Context, request *api.TokenRecurringAction)
+	CancelRecurringOrder(c *gin.Context, request *api.TokenRecurringAction)
+	SkipOccurrence(c *gin.Context, request *api.TokenRecurringAction)
+	SetAutoPay(c *gin.Context, request *api.TokenRecurringAction)
+}
+
+type recurringOrderController struct {
+	recurringOrderService service.RecurringOrderService
+}
+
+func (ctrl *recurringOrderController) CreateRecurringOrder(c *gin.Context, request *api.TokenRecurringOrder) {
+	userInfo, err := ExtractUserFromToken(request.TokenAccess.User.Login.Token)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	if userInfo.IsCompany {
+		api.GetErrorJSON(c, http.StatusForbidden, "Only clients can create recurring orders")
+		return
+	}
+
+	recurring, err := ctrl.recurringOrderService.CreateRecurringOrder(userInfo.UserID, request.Recurring)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"recurring": recurring})
+}
+
+func (ctrl *recurringOrderController) GetRecurringOrders(c *gin.Context, request *api.TokenAccess) {
+	userInfo, err := ExtractUserFromToken(request.User.Login.Token)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	recurring, err := ctrl.recurringOrderService.GetRecurringOrders(userInfo.UserID, userInfo.UserType)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusInternalServerError, "Failed to get recurring orders")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recurring": recurring})
+}
+
+func (ctrl *recurringOrderController) GetUpcoming(c *gin.Context, request *api.TokenRecurringUpcoming) {
+	userInfo, err := ExtractUserFromToken(request.TokenAccess.User.Login.Token)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	occurrences, err := ctrl.recurringOrderService.GetUpcoming(userInfo.UserID, userInfo.UserType, request.Days)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"occurrences": occurrences})
+}
+
+func (ctrl *recurringOrderController) PauseRecurringOrder(c *gin.Context, request *api.TokenRecurringAction) {
+	ctrl.clientAction(c, request, "Recurring order paused", func(clientID uint) error {
+		return ctrl.recurringOrderService.Pause(request.RecurringID, clientID)
+	})
+}
+
+func (ctrl *recurringOrderController) ResumeRecurringOrder(c *gin.Context, request *api.TokenRecurringAction) {
+	ctrl.clientAction(c, request, "Recurring order resumed", func(clientID uint) error {
+		return ctrl.recurringOrderService.Resume(request.RecurringID, clientID)
+	})
+}
+
+func (ctrl *recurringOrderController) CancelRecurringOrder(c *gin.Context, request *api.TokenRecurringAction) {
+	ctrl.clientAction(c, request, "Recurring order cancelled", func(clientID uint) error {
+		return ctrl.recurringOrderService.Cancel(request.RecurringID, clientID)
+	})
+}
+
+func (ctrl *recurringOrderController) SkipOccurrence(c *gin.Context, request *api.TokenRecurringAction) {
+	ctrl.clientAction(c, request, "Occurrence skipped", func(clientID uint) error {
+		return ctrl.recurringOrderService.Skip(request.RecurringID, clientID, request.ScheduledAt)
+	})
+}
+
+func (ctrl *recurringOrderController) SetAutoPay(c *gin.Context, request *api.TokenRecurringAction) {
+	if request.AutoPay == nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, "auto_pay is required")
+		return
+	}
+	ctrl.clientAction(c, request, "Auto-pay updated", func(clientID uint) error {
+		return ctrl.recurringOrderService.SetAutoPay(request.RecurringID, clientID, *request.AutoPay)
+	})
+}
+
+// clientAction выполняет действие клиента над своим расписанием и отвечает сообщением message
+func (ctrl *recurringOrderController) clientAction(c *gin.Context, request *api.TokenRecurringAction, message string, action func(clientID uint) error) {
+	userInfo, err := ExtractUserFromToken(request.TokenAccess.User.Login.Token)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	if userInfo.IsCompany {
+		api.GetErrorJSON(c, http.StatusForbidden, "Only clients can manage recurring orders")
+		return
+	}
+
+	if err := action(userInfo.UserID); err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": message,
+	})
+}
+
+func NewRecurringOrderController(recurringOrderService service.RecurringOrderService) RecurringOrderController {
+	return &recurringOrderController{recurringOrderService: recurringOrderService}
+}
@@ -2,6 +2,7 @@ package controller
 
 import (
 	"core/internal/api"
+	"core/internal/pagination"
 	"core/internal/service"
 	"github.com/gin-gonic/gin"
 	"net/http"
@@ -13,6 +14,13 @@ type ReviewController interface {
 	GetCompanyReviews(c *gin.Context)
 	GetOrderReview(c *gin.Context)
 	GetCompanyRating(c *gin.Context)
+	UpdateReview(c *gin.Context, request *api.TokenUpdateReview)
+	ReplyToReview(c *gin.Context, request *api.TokenReviewReply)
+	ReportReview(c *gin.Context, request *api.TokenReviewReport)
+	GetModerationQueue(c *gin.Context, request *api.TokenModerationQueue)
+	HideReview(c *gin.Context, request *api.TokenModerateReview)
+	RestoreReview(c *gin.Context, request *api.TokenModerateReview)
+	DismissReport(c *gin.Context, request *api.TokenModerateReview)
 }
 
 type reviewController struct {
@@ -37,6 +45,7 @@ func (ctrl *reviewController) CreateReview(c *gin.Context, request *api.TokenCre
 		request.Review.OrderID,
 		request.Review.Rating,
 		request.Review.Comment,
+		request.Review.SubRatings,
 	)
 	if err != nil {
 		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
@@ -54,17 +63,13 @@ func (ctrl *reviewController) GetCompanyReviews(c *gin.Context) {
 		return
 	}
 
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
-
-	if page < 1 {
-		page = 1
-	}
-	if limit < 1 || limit > 100 {
-		limit = 10
+	pageRequest, page, err := parsePageQuery(c)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
 	}
 
-	reviews, err := ctrl.reviewService.GetReviewsByCompany(uint(companyID), page, limit)
+	reviews, next, err := ctrl.reviewService.GetReviewsByCompany(uint(companyID), pageRequest)
 	if err != nil {
 		api.GetErrorJSON(c, http.StatusInternalServerError, "Failed to get reviews")
 		return
@@ -74,7 +79,8 @@ func (ctrl *reviewController) GetCompanyReviews(c *gin.Context) {
 		"reviews":    reviews,
 		"company_id": companyID,
 		"page":       page,
-		"limit":      limit,
+		"limit":      pageRequest.Limit,
+		"pagination": next,
 	})
 }
 
@@ -103,19 +109,147 @@ func (ctrl *reviewController) GetCompanyRating(c *gin.Context) {
 		return
 	}
 
-	rating, count, err := ctrl.reviewService.GetCompanyRating(uint(companyID))
+	summary, err := ctrl.reviewService.GetCompanyRating(uint(companyID))
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
+func (ctrl *reviewController) UpdateReview(c *gin.Context, request *api.TokenUpdateReview) {
+	userInfo, err := ExtractUserFromToken(request.TokenAccess.User.Login.Token)
 	if err != nil {
-		api.GetErrorJSON(c, http.StatusInternalServerError, "Failed to get rating")
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	if userInfo.IsCompany {
+		api.GetErrorJSON(c, http.StatusForbidden, "Only clients can edit reviews")
+		return
+	}
+
+	review, err := ctrl.reviewService.UpdateReview(userInfo.UserID, request.ReviewID, request.Review.Rating, request.Review.Comment, request.Review.SubRatings)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"review": review})
+}
+
+func (ctrl *reviewController) ReplyToReview(c *gin.Context, request *api.TokenReviewReply) {
+	userInfo, err := ExtractUserFromToken(request.TokenAccess.User.Login.Token)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	if !userInfo.IsCompany {
+		api.GetErrorJSON(c, http.StatusForbidden, "Only companies can reply to reviews")
+		return
+	}
+
+	review, err := ctrl.reviewService.ReplyToReview(userInfo.UserID, request.ReviewID, request.Reply)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"review": review})
+}
+
+func (ctrl *reviewController) ReportReview(c *gin.Context, request *api.TokenReviewReport) {
+	userInfo, err := ExtractUserFromToken(request.TokenAccess.User.Login.Token)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	reporterType := "client"
+	if userInfo.IsCompany {
+		reporterType = "company"
+	}
+
+	err = ctrl.reviewService.ReportReview(userInfo.UserID, reporterType, request.ReviewID, request.Reason)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Review reported"})
+}
+
+func (ctrl *reviewController) GetModerationQueue(c *gin.Context, request *api.TokenModerationQueue) {
+	if _, err := ExtractAdminFromToken(request.TokenAccess.User.Login.Token); err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	page, err := pagination.Parse(request.Cursor, request.Limit, request.Offset)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	reports, next, err := ctrl.reviewService.GetModerationQueue(request.Status, page)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"company_id":   companyID,
-		"rating":       rating,
-		"review_count": count,
+		"reports":    reports,
+		"pagination": next,
 	})
 }
 
+func (ctrl *reviewController) HideReview(c *gin.Context, request *api.TokenModerateReview) {
+	adminID, err := ExtractAdminFromToken(request.TokenAccess.User.Login.Token)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	if err := ctrl.reviewService.HideReview(adminID, request.ReviewID, request.Reason); err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Review hidden"})
+}
+
+func (ctrl *reviewController) RestoreReview(c *gin.Context, request *api.TokenModerateReview) {
+	adminID, err := ExtractAdminFromToken(request.TokenAccess.User.Login.Token)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	if err := ctrl.reviewService.RestoreReview(adminID, request.ReviewID); err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Review restored"})
+}
+
+func (ctrl *reviewController) DismissReport(c *gin.Context, request *api.TokenModerateReview) {
+	adminID, err := ExtractAdminFromToken(request.TokenAccess.User.Login.Token)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	if err := ctrl.reviewService.DismissReport(adminID, request.ReportID); err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Report dismissed"})
+}
+
 func NewReviewController(reviewService service.ReviewService) ReviewController {
 	return &reviewController{reviewService: reviewService}
 }
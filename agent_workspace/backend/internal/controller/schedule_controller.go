@@ -0,0 +1,176 @@
+package controller
+
+import (
+	"core/internal"
+	"core/internal/api"
+	"core/internal/service"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type ScheduleController interface {
+	GetSchedule(c *gin.Context, request *api.TokenAccess)
+	SetWorkingHours(c *gin.Context, request *api.TokenSetWorkingHours)
+	AddBlackoutDate(c *gin.Context, request *api.TokenAddBlackoutDate)
+	DeleteBlackoutDate(c *gin.Context, request *api.TokenDeleteBlackoutDate)
+	ExportCalendar(c *gin.Context, request *api.TokenAccess)
+	GetAvailableSlots(c *gin.Context)
+}
+
+type scheduleController struct {
+	scheduleService service.ScheduleService
+}
+
+func (ctrl *scheduleController) GetSchedule(c *gin.Context, request *api.TokenAccess) {
+	userInfo, err := ExtractUserFromToken(request.User.Login.Token)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	if !userInfo.IsCompany {
+		api.GetErrorJSON(c, http.StatusForbidden, "Only companies can manage schedule")
+		return
+	}
+
+	schedule, err := ctrl.scheduleService.GetSchedule(userInfo.UserID)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusInternalServerError, "Failed to get schedule")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"schedule": schedule})
+}
+
+func (ctrl *scheduleController) SetWorkingHours(c *gin.Context, request *api.TokenSetWorkingHours) {
+	userInfo, err := ExtractUserFromToken(request.TokenAccess.User.Login.Token)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	if !userInfo.IsCompany {
+		api.GetErrorJSON(c, http.StatusForbidden, "Only companies can manage schedule")
+		return
+	}
+
+	err = ctrl.scheduleService.SetWorkingHours(userInfo.UserID, request.Hours)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Working hours updated successfully",
+	})
+}
+
+func (ctrl *scheduleController) AddBlackoutDate(c *gin.Context, request *api.TokenAddBlackoutDate) {
+	userInfo, err := ExtractUserFromToken(request.TokenAccess.User.Login.Token)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	if !userInfo.IsCompany {
+		api.GetErrorJSON(c, http.StatusForbidden, "Only companies can manage schedule")
+		return
+	}
+
+	blackout, err := ctrl.scheduleService.AddBlackoutDate(userInfo.UserID, request.Date, request.Reason)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"blackout": api.BlackoutDateInfo{
+		ID:     blackout.ID,
+		Date:   blackout.Date.Format("2006-01-02"),
+		Reason: blackout.Reason,
+	}})
+}
+
+func (ctrl *scheduleController) DeleteBlackoutDate(c *gin.Context, request *api.TokenDeleteBlackoutDate) {
+	userInfo, err := ExtractUserFromToken(request.TokenAccess.User.Login.Token)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	if !userInfo.IsCompany {
+		api.GetErrorJSON(c, http.StatusForbidden, "Only companies can manage schedule")
+		return
+	}
+
+	err = ctrl.scheduleService.RemoveBlackoutDate(userInfo.UserID, request.BlackoutID)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Blackout date deleted successfully",
+	})
+}
+
+func (ctrl *scheduleController) ExportCalendar(c *gin.Context, request *api.TokenAccess) {
+	userInfo, err := ExtractUserFromToken(request.User.Login.Token)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	if !userInfo.IsCompany {
+		api.GetErrorJSON(c, http.StatusForbidden, "Only companies can export calendar")
+		return
+	}
+
+	calendar, err := ctrl.scheduleService.ExportCalendar(userInfo.UserID)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusInternalServerError, "Failed to export calendar")
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"company-%d.ics\"", userInfo.UserID))
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", calendar)
+}
+
+func (ctrl *scheduleController) GetAvailableSlots(c *gin.Context) {
+	companyIDStr := c.Param("company_id")
+	companyID, err := strconv.ParseUint(companyIDStr, 10, 32)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, "Invalid company ID")
+		return
+	}
+
+	from := time.Now()
+	if fromStr := c.Query("from"); fromStr != "" {
+		from, err = time.ParseInLocation("2006-01-02", fromStr, internal.TimeZone)
+		if err != nil {
+			api.GetErrorJSON(c, http.StatusBadRequest, "Invalid from, expected YYYY-MM-DD")
+			return
+		}
+	}
+
+	days, _ := strconv.Atoi(c.DefaultQuery("days", "7"))
+
+	slots, err := ctrl.scheduleService.GetAvailableSlots(uint(companyID), from, days)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"company_id": companyID,
+		"slots":      slots,
+	})
+}
+
+func NewScheduleController(scheduleService service.ScheduleService) ScheduleController {
+	return &scheduleController{scheduleService: scheduleService}
+}
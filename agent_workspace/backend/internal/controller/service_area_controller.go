@@ -0,0 +1,63 @@
+package controller
+
+import (
+	"core/internal/api"
+	"core/internal/service"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type ServiceAreaController interface {
+	SetCompanyArea(c *gin.Context, request *api.TokenSetCompanyArea)
+	SetCardArea(c *gin.Context, request *api.TokenSetCardArea)
+}
+
+type serviceAreaController struct {
+	serviceAreaService service.ServiceAreaService
+}
+
+func (ctrl *serviceAreaController) SetCompanyArea(c *gin.Context, request *api.TokenSetCompanyArea) {
+	userInfo, err := ExtractUserFromToken(request.TokenAccess.User.Login.Token)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	if !userInfo.IsCompany {
+		api.GetErrorJSON(c, http.StatusForbidden, "Only companies can set service areas")
+		return
+	}
+
+	area, err := ctrl.serviceAreaService.SetCompanyArea(userInfo.UserID, request.Area)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"area": area})
+}
+
+func (ctrl *serviceAreaController) SetCardArea(c *gin.Context, request *api.TokenSetCardArea) {
+	userInfo, err := ExtractUserFromToken(request.TokenAccess.User.Login.Token)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	if !userInfo.IsCompany {
+		api.GetErrorJSON(c, http.StatusForbidden, "Only companies can set service areas")
+		return
+	}
+
+	area, err := ctrl.serviceAreaService.SetCardArea(userInfo.UserID, request.CardID, request.Area)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"area": area})
+}
+
+func NewServiceAreaController(serviceAreaService service.ServiceAreaService) ServiceAreaController {
+	return &serviceAreaController{serviceAreaService: serviceAreaService}
+}
@@ -0,0 +1,255 @@
+package controller
+
+import (
+	"core/internal"
+	"core/internal/api"
+	"core/internal/service"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+)
+
+// DeviceTokenHeader заголовок с токеном доверенного устройства, который передается при входе
+const DeviceTokenHeader = "X-Device-Token"
+
+type TwoFactorController interface {
+	// CompleteLogin второй шаг входа, публичный эндпоинт
+	CompleteLogin(c *gin.Context)
+
+	GetStatus(c *gin.Context, request *api.TokenAccess)
+	BeginSetup(c *gin.Context, request *api.TokenTwoFactorSetup)
+	ConfirmSetup(c *gin.Context, request *api.TokenTwoFactorCode)
+	Disable(c *gin.Context, request *api.TokenTwoFactorDisable)
+	RegenerateRecoveryCodes(c *gin.Context, request *api.TokenTwoFactorCode)
+	ListTrustedDevices(c *gin.Context, request *api.TokenAccess)
+	RevokeTrustedDevice(c *gin.Context, request *api.TokenTrustedDevice)
+}
+
+type twoFactorController struct {
+	twoFactorService service.TwoFactorService
+	identityService  service.IdentityService
+}
+
+func (ctrl *twoFactorController) CompleteLogin(c *gin.Context) {
+	request := &api.TwoFactorLoginRequest{}
+	if err := c.ShouldBind(request); err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid: "+err.Error())
+		return
+	}
+
+	login, err := ctrl.twoFactorService.CompleteLogin(request.Challenge, request.Code, request.TrustDevice, request.DeviceName)
+	if err != nil {
+		if RespondLimited(c, err) {
+			return
+		}
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	// Challenge, выданный до появления пользователей, относится к клиенту или компании
+	if login.UserType != "user" {
+		api.GetErrorJSON(c, http.StatusUnauthorized, ErrSessionOutdated.Error())
+		return
+	}
+	user, err := ctrl.identityService.StartSession(login.UserID, request.AccountType, request.CompanyID)
+	if err != nil {
+		respondSessionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, api.ResponseTwoFactorLogin{
+		ResponseSuccessAccess: api.ResponseSuccessAccess{
+			StatusResponse: internal.StatusResponse{Status: "success"},
+			ResponseUser:   *user,
+		},
+		DeviceToken: login.DeviceToken,
+	})
+}
+
+func (ctrl *twoFactorController) GetStatus(c *gin.Context, request *api.TokenAccess) {
+	userID, userType, ok := identityFromToken(c, request.User.Login.Token)
+	if !ok {
+		return
+	}
+
+	status, err := ctrl.twoFactorService.GetStatus(userID, userType)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusInternalServerError, "Failed to get two-factor status")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":     "success",
+		"two_factor": status,
+	})
+}
+
+func (ctrl *twoFactorController) BeginSetup(c *gin.Context, request *api.TokenTwoFactorSetup) {
+	userID, userType, ok := identityFromToken(c, request.TokenAccess.User.Login.Token)
+	if !ok {
+		return
+	}
+
+	setup, err := ctrl.twoFactorService.BeginSetup(userID, userType, request.Password)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"setup":  setup,
+	})
+}
+
+func (ctrl *twoFactorController) ConfirmSetup(c *gin.Context, request *api.TokenTwoFactorCode) {
+	userID, userType, ok := identityFromToken(c, request.TokenAccess.User.Login.Token)
+	if !ok {
+		return
+	}
+
+	codes, err := ctrl.twoFactorService.ConfirmSetup(userID, userType, request.Code)
+	if err != nil {
+		if RespondLimited(c, err) {
+			return
+		}
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":         "success",
+		"message":        "Two-factor authentication enabled. Save the recovery codes, they are shown only once",
+		"recovery_codes": codes,
+	})
+}
+
+func (ctrl *twoFactorController) Disable(c *gin.Context, request *api.TokenTwoFactorDisable) {
+	userID, userType, ok := identityFromToken(c, request.TokenAccess.User.Login.Token)
+	if !ok {
+		return
+	}
+
+	if err := ctrl.twoFactorService.Disable(userID, userType, request.Password, request.Code); err != nil {
+		if RespondLimited(c, err) {
+			return
+		}
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Two-factor authentication disabled",
+	})
+}
+
+func (ctrl *twoFactorController) RegenerateRecoveryCodes(c *gin.Context, request *api.TokenTwoFactorCode) {
+	userID, userType, ok := identityFromToken(c, request.TokenAccess.User.Login.Token)
+	if !ok {
+		return
+	}
+
+	codes, err := ctrl.twoFactorService.RegenerateRecoveryCodes(userID, userType, request.Code)
+	if err != nil {
+		if RespondLimited(c, err) {
+			return
+		}
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":         "success",
+		"recovery_codes": codes,
+	})
+}
+
+func (ctrl *twoFactorController) ListTrustedDevices(c *gin.Context, request *api.TokenAccess) {
+	userID, userType, ok := identityFromToken(c, request.User.Login.Token)
+	if !ok {
+		return
+	}
+
+	devices, err := ctrl.twoFactorService.ListTrustedDevices(userID, userType)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusInternalServerError, "Failed to get trusted devices")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"devices": devices,
+	})
+}
+
+func (ctrl *twoFactorController) RevokeTrustedDevice(c *gin.Context, request *api.TokenTrustedDevice) {
+	userID, userType, ok := identityFromToken(c, request.TokenAccess.User.Login.Token)
+	if !ok {
+		return
+	}
+
+	if err := ctrl.twoFactorService.RevokeTrustedDevice(userID, userType, request.DeviceID); err != nil {
+		api.GetErrorJSON(c, http.StatusNotFound, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Trusted device revoked",
+	})
+}
+
+// TwoFactorChallenge вызывается при входе после проверки пароля. Если нужен код, отвечает
+// challenge для /v1/auth/two-factor и возвращает true: токен доступа выдавать нельзя
+func TwoFactorChallenge(c *gin.Context, twoFactorService service.TwoFactorService, userID uint, userType string) bool {
+	challenge, err := twoFactorService.BeginLogin(userID, userType, c.GetHeader(DeviceTokenHeader))
+	if err != nil {
+		log.Printf("failed to start two-factor login for %s %d: %v", userType, userID, err)
+		api.GetErrorJSON(c, http.StatusInternalServerError, "Failed to start two-factor login")
+		return true
+	}
+	if challenge == "" {
+		return false
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":     "two_factor_required",
+		"challenge":  challenge,
+		"expires_in": int(internal.TwoFactorChallengeTTL.Seconds()),
+	})
+	return true
+}
+
+// requireStepUp проверяет код 2FA перед выводом средств или сменой реквизитов.
+// При ошибке отвечает клиенту и возвращает false
+func requireStepUp(c *gin.Context, twoFactorService service.TwoFactorService, userInfo *UserInfo, code string) bool {
+	userID, userType, err := userInfo.Identity()
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return false
+	}
+	err = twoFactorService.VerifyStepUp(userID, userType, code)
+	if err == nil {
+		return true
+	}
+	if RespondLimited(c, err) {
+		return false
+	}
+	switch {
+	case errors.Is(err, service.ErrTwoFactorRequired):
+		api.GetErrorJSON(c, http.StatusForbidden, "Two-factor code is required")
+	case errors.Is(err, service.ErrInvalidTwoFactorCode):
+		api.GetErrorJSON(c, http.StatusForbidden, "Invalid two-factor code")
+	default:
+		api.GetErrorJSON(c, http.StatusInternalServerError, "Failed to verify two-factor code")
+	}
+	return false
+}
+
+func NewTwoFactorController(twoFactorService service.TwoFactorService, identityService service.IdentityService) TwoFactorController {
+	return &twoFactorController{
+		twoFactorService: twoFactorService,
+		identityService:  identityService,
+	}
+}
@@ -0,0 +1,222 @@
+package controller
+
+import (
+	"core/internal"
+	"core/internal/api"
+	"core/internal/security"
+	"core/internal/service"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type WorkerController interface {
+	CreateWorker(c *gin.Context, request *api.TokenCreateWorker)
+	ListWorkers(c *gin.Context, request *api.TokenAccess)
+	DeactivateWorker(c *gin.Context, request *api.TokenWorkerAction)
+	AssignOrder(c *gin.Context, request *api.TokenAssignOrder)
+	Login(c *gin.Context)
+	GetTasks(c *gin.Context, request *api.TokenAccess)
+	CheckIn(c *gin.Context, request *api.TokenOrderAction)
+	CheckOut(c *gin.Context, request *api.TokenOrderAction)
+	CompleteOrder(c *gin.Context, request *api.TokenWorkerComplete)
+}
+
+type workerController struct {
+	workerService service.WorkerService
+}
+
+func (ctrl *workerController) CreateWorker(c *gin.Context, request *api.TokenCreateWorker) {
+	userInfo, err := ExtractUserFromToken(request.TokenAccess.User.Login.Token)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	if !userInfo.IsCompany {
+		api.GetErrorJSON(c, http.StatusForbidden, "Only companies can manage workers")
+		return
+	}
+
+	worker, err := ctrl.workerService.CreateWorker(userInfo.UserID, request)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"worker": worker})
+}
+
+func (ctrl *workerController) ListWorkers(c *gin.Context, request *api.TokenAccess) {
+	userInfo, err := ExtractUserFromToken(request.User.Login.Token)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	if !userInfo.IsCompany {
+		api.GetErrorJSON(c, http.StatusForbidden, "Only companies can manage workers")
+		return
+	}
+
+	workers, err := ctrl.workerService.GetCompanyWorkers(userInfo.UserID)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusInternalServerError, "Failed to get workers")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"workers": workers})
+}
+
+func (ctrl *workerController) DeactivateWorker(c *gin.Context, request *api.TokenWorkerAction) {
+	userInfo, err := ExtractUserFromToken(request.TokenAccess.User.Login.Token)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	if !userInfo.IsCompany {
+		api.GetErrorJSON(c, http.StatusForbidden, "Only companies can manage workers")
+		return
+	}
+
+	err = ctrl.workerService.DeactivateWorker(userInfo.UserID, request.WorkerID)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Worker deactivated successfully",
+	})
+}
+
+func (ctrl *workerController) AssignOrder(c *gin.Context, request *api.TokenAssignOrder) {
+	userInfo, err := ExtractUserFromToken(request.TokenAccess.User.Login.Token)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	if !userInfo.IsCompany {
+		api.GetErrorJSON(c, http.StatusForbidden, "Only companies can assign orders")
+		return
+	}
+
+	err = ctrl.workerService.AssignOrder(userInfo.UserID, request.OrderID, request.WorkerID)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Order assigned successfully",
+	})
+}
+
+func (ctrl *workerController) Login(c *gin.Context) {
+	request := &api.LoginRequest{}
+	if err := c.ShouldBind(request); err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, "JSON is invalid: "+err.Error())
+		return
+	}
+
+	worker, err := ctrl.workerService.Login(request)
+	if err != nil {
+		if RespondLimited(c, err) {
+			return
+		}
+		api.GetErrorJSON(c, http.StatusUnauthorized, "Invalid credentials")
+		return
+	}
+
+	jwtToken := security.CreateWorkerToken(worker.ID, worker.CompanyID, internal.LifeTimeJWT)
+	if jwtToken == "" {
+		api.GetErrorJSON(c, http.StatusBadRequest, "the created jwt was faulty")
+		return
+	}
+	c.JSON(http.StatusOK, api.ResponseSuccessAccess{
+		StatusResponse: internal.StatusResponse{Status: "success"},
+		ResponseUser: api.ResponseUser{
+			ID:    worker.ID,
+			Token: jwtToken,
+			Type:  "worker",
+		},
+	})
+}
+
+func (ctrl *workerController) GetTasks(c *gin.Context, request *api.TokenAccess) {
+	workerInfo, err := ExtractWorkerFromToken(request.User.Login.Token)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	tasks, err := ctrl.workerService.GetTasks(workerInfo.WorkerID)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusForbidden, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tasks": tasks})
+}
+
+func (ctrl *workerController) CheckIn(c *gin.Context, request *api.TokenOrderAction) {
+	workerInfo, err := ExtractWorkerFromToken(request.TokenAccess.User.Login.Token)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	err = ctrl.workerService.CheckIn(workerInfo.WorkerID, request.OrderID)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Checked in successfully"})
+}
+
+func (ctrl *workerController) CheckOut(c *gin.Context, request *api.TokenOrderAction) {
+	workerInfo, err := ExtractWorkerFromToken(request.TokenAccess.User.Login.Token)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	err = ctrl.workerService.CheckOut(workerInfo.WorkerID, request.OrderID)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Checked out successfully"})
+}
+
+func (ctrl *workerController) CompleteOrder(c *gin.Context, request *api.TokenWorkerComplete) {
+	workerInfo, err := ExtractWorkerFromToken(request.TokenAccess.User.Login.Token)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	err = ctrl.workerService.CompleteOrder(workerInfo.WorkerID, request.OrderID, &service.CompletionReportInput{
+		Notes:            request.Notes,
+		TimeSpentMinutes: request.TimeSpentMinutes,
+		Latitude:         request.Latitude,
+		Longitude:        request.Longitude,
+		IP:               c.ClientIP(),
+		UserAgent:        c.Request.UserAgent(),
+	})
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Order completed successfully"})
+}
+
+func NewWorkerController(workerService service.WorkerService) WorkerController {
+	return &workerController{workerService: workerService}
+}
@@ -0,0 +1,88 @@
+package controller
+
+import (
+	"core/internal/api"
+	"core/internal/service"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+type WorkerLinkController interface {
+	ReissueLink(c *gin.Context, request *api.TokenWorkerLinkAction)
+	RevokeLink(c *gin.Context, request *api.TokenWorkerLinkAction)
+	ListLinks(c *gin.Context, request *api.TokenWorkerLinkAction)
+}
+
+type workerLinkController struct {
+	workerLinkService service.WorkerLinkService
+}
+
+func (ctrl *workerLinkController) ReissueLink(c *gin.Context, request *api.TokenWorkerLinkAction) {
+	userInfo, err := ExtractUserFromToken(request.TokenAccess.User.Login.Token)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	if !userInfo.IsCompany {
+		api.GetErrorJSON(c, http.StatusForbidden, "Only companies can manage worker links")
+		return
+	}
+
+	link, err := ctrl.workerLinkService.Reissue(userInfo.UserID, request.OrderID, request.TTLHours)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"link": link})
+}
+
+func (ctrl *workerLinkController) RevokeLink(c *gin.Context, request *api.TokenWorkerLinkAction) {
+	userInfo, err := ExtractUserFromToken(request.TokenAccess.User.Login.Token)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	if !userInfo.IsCompany {
+		api.GetErrorJSON(c, http.StatusForbidden, "Only companies can manage worker links")
+		return
+	}
+
+	err = ctrl.workerLinkService.Revoke(userInfo.UserID, request.OrderID)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Worker link revoked successfully",
+	})
+}
+
+func (ctrl *workerLinkController) ListLinks(c *gin.Context, request *api.TokenWorkerLinkAction) {
+	userInfo, err := ExtractUserFromToken(request.TokenAccess.User.Login.Token)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	if !userInfo.IsCompany {
+		api.GetErrorJSON(c, http.StatusForbidden, "Only companies can manage worker links")
+		return
+	}
+
+	links, err := ctrl.workerLinkService.GetOrderLinks(userInfo.UserID, request.OrderID)
+	if err != nil {
+		api.GetErrorJSON(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, links)
+}
+
+func NewWorkerLinkController(workerLinkService service.WorkerLinkService) WorkerLinkController {
+	return &workerLinkController{workerLinkService: workerLinkService}
+}
@@ -0,0 +1,165 @@
+package database
+
+import (
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+	"strconv"
+	"strings"
+)
+
+// defaultCategory элемент начального справочника категорий
+type defaultCategory struct {
+	Slug     string
+	NameRu   string
+	NameEn   string
+	Icon     string
+	Aliases  []string
+	Children []defaultCategory
+}
+
+// defaultCategories начальный справочник. Создается только в пустой таблице,
+// дальше категориями управляют администраторы
+var defaultCategories = []defaultCategory{
+	{Slug: "cleaning", NameRu: "Уборка", NameEn: "Cleaning", Icon: "broom", Aliases: []string{"клининг", "уборка помещений"},
+		Children: []defaultCategory{
+			{Slug: "apartment-cleaning", NameRu: "Уборка квартир", NameEn: "Apartment cleaning", Icon: "home"},
+			{Slug: "office-cleaning", NameRu: "Уборка офисов", NameEn: "Office cleaning", Icon: "building"},
+			{Slug: "window-cleaning", NameRu: "Мойка окон", NameEn: "Window cleaning", Icon: "window"},
+		}},
+	{Slug: "repair", NameRu: "Ремонт", NameEn: "Repair", Icon: "hammer", Aliases: []string{"ремонт и отделка"},
+		Children: []defaultCategory{
+			{Slug: "plumbing", NameRu: "Сантехника", NameEn: "Plumbing", Icon: "pipe", Aliases: []string{"сантехник"}},
+			{Slug: "electrical", NameRu: "Электрика", NameEn: "Electrical", Icon: "bolt", Aliases: []string{"электрик"}},
+			{Slug: "appliance-repair", NameRu: "Ремонт техники", NameEn: "Appliance repair", Icon: "wrench"},
+		}},
+	{Slug: "moving", NameRu: "Переезды", NameEn: "Moving", Icon: "truck", Aliases: []string{"переезд", "грузоперевозки"}},
+	{Slug: "beauty", NameRu: "Красота", NameEn: "Beauty", Icon: "scissors", Aliases: []string{"красота и здоровье"}},
+	{Slug: "tutoring", NameRu: "Репетиторы", NameEn: "Tutoring", Icon: "book", Aliases: []string{"обучение", "репетиторство"}},
+	{Slug: "other", NameRu: "Другое", NameEn: "Other", Icon: "dots", Aliases: []string{"прочее"}},
+}
+
+// MigrateCardCategories заполняет справочник категорий и привязывает к нему карточки
+// со строковой категорией. Неизвестные строки становятся новыми корневыми категориями.
+// Повторный запуск безопасен
+func MigrateCardCategories(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var total int64
+		if err := tx.Model(&Category{}).Count(&total).Error; err != nil {
+			return err
+		}
+		if total == 0 {
+			if err := seedCategories(tx, nil, defaultCategories); err != nil {
+				return err
+			}
+		}
+
+		var names []string
+		err := tx.Model(&Card{}).Where("category_id IS NULL AND category <> ''").
+			Distinct("category").Pluck("category", &names).Error
+		if err != nil {
+			return err
+		}
+
+		for _, name := range names {
+			category, err := FindCategoryByName(tx, name)
+			if err != nil {
+				return err
+			}
+			if category == nil {
+				category = &Category{Slug: uniqueSlug(tx, Slugify(name)), NameRu: strings.TrimSpace(name), IsActive: true}
+				if err := tx.Create(category).Error; err != nil {
+					return err
+				}
+			}
+			err = tx.Model(&Card{}).Where("category_id IS NULL AND category = ?", name).
+				Update("category_id", category.ID).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func seedCategories(tx *gorm.DB, parentID *uint, categories []defaultCategory) error {
+	for i, item := range categories {
+		category := &Category{
+			ParentID:  parentID,
+			Slug:      item.Slug,
+			NameRu:    item.NameRu,
+			NameEn:    item.NameEn,
+			Icon:      item.Icon,
+			SortOrder: i,
+			Aliases:   pq.StringArray(item.Aliases),
+			IsActive:  true,
+		}
+		if err := tx.Create(category).Error; err != nil {
+			return err
+		}
+		if err := seedCategories(tx, &category.ID, item.Children); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FindCategoryByName ищет категорию по слагу, названию или одному из старых написаний
+// без учета регистра. Возвращает nil, если ничего не найдено
+func FindCategoryByName(tx *gorm.DB, name string) (*Category, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	var categories []Category
+	err := tx.Where("slug = ? OR LOWER(name_ru) = ? OR LOWER(name_en) = ? OR ? = ANY(SELECT LOWER(alias) FROM unnest(aliases) AS alias)",
+		name, name, name, name).
+		Order("id ASC").Limit(1).Find(&categories).Error
+	if err != nil || len(categories) == 0 {
+		return nil, err
+	}
+	return &categories[0], nil
+}
+
+// uniqueSlug добавляет к слагу номер, если такой уже занят
+func uniqueSlug(tx *gorm.DB, slug string) string {
+	candidate := slug
+	for i := 2; ; i++ {
+		var count int64
+		tx.Unscoped().Model(&Category{}).Where("slug = ?", candidate).Count(&count)
+		if count == 0 {
+			return candidate
+		}
+		candidate = slug + "-" + strconv.Itoa(i)
+	}
+}
+
+var cyrillicToLatin = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh",
+	'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o",
+	'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "h", 'ц': "ts",
+	'ч': "ch", 'ш': "sh", 'щ': "sch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu",
+	'я': "ya",
+}
+
+// Slugify переводит название в латинский слаг: "Уборка квартир" -> "uborka-kvartir"
+func Slugify(name string) string {
+	var builder strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(strings.TrimSpace(name)) {
+		switch {
+		case r >= 'a' && r <= 'z' || r >= '0' && r <= '9':
+			builder.WriteRune(r)
+			dash = false
+		case cyrillicToLatin[r] != "" || r == 'ъ' || r == 'ь':
+			builder.WriteString(cyrillicToLatin[r])
+			dash = false
+		default:
+			if !dash && builder.Len() > 0 {
+				builder.WriteByte('-')
+				dash = true
+			}
+		}
+	}
+	slug := strings.TrimSuffix(builder.String(), "-")
+	if slug == "" {
+		slug = "category"
+	}
+	return slug
+}
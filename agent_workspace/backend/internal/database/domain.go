@@ -1,6 +1,7 @@
 package database
 
 import (
+	"core/internal/money"
 	"github.com/lib/pq"
 	"gorm.io/gorm"
 	"time"
@@ -12,13 +13,19 @@ type ClientDB struct {
 	FullName     string
 	Email        string `gorm:"unique"`
 	Phone        string
-	PasswordHash string
+	PasswordHash string `json:"-"`
 	Photo        string
 	Type         string
-	Permissions  pq.StringArray `gorm:"type:text[]"`
-	Balance      float64        `gorm:"default:0"`
+	Permissions  pq.StringArray `gorm:"type:text[]" json:"-"`
+	Balance      money.Minor    `gorm:"default:0" json:"-"`
 	Orders       []Order        `gorm:"foreignKey:ClientID"`
 	Reviews      []Review       `gorm:"foreignKey:ClientID"`
+
+	// Валюта счета: в ней ведется баланс и списывается оплата заказов
+	Currency string `gorm:"default:'RUB'"`
+
+	// Время подтверждения email по ссылке из письма, nil - email не подтвержден
+	EmailVerifiedAt *time.Time
 }
 
 type CompanyDB struct {
@@ -32,33 +39,77 @@ type CompanyDB struct {
 	Phone         string         `json:"phone"`
 	Address       string         `json:"address"`
 	TypeService   string         `json:"type_service"`
-	PasswordHash  string         `json:"password_hash"`
+	PasswordHash  string         `json:"-"`
 	Photo         string         `json:"photo"`
 	Website       string         `json:"website"`
 	Description   string         `json:"description"`
-	Documents     pq.StringArray `gorm:"type:text[]" json:"documents"`
+	Documents     pq.StringArray `gorm:"type:text[]" json:"-"` // Видны только администраторам при проверке
 	Stars         float64        `gorm:"default:0" json:"stars"`
 	ReviewCount   int            `gorm:"default:0" json:"review_count"`
+	IsVerified    bool           `gorm:"default:false" json:"is_verified"` // Документы компании проверены администратором
+	VerifiedAt    *time.Time     `json:"verified_at"`
+	VerifiedByID  *uint          `json:"-"` // Администратор, проверивший документы
 	Type          string
-	Permissions   pq.StringArray `gorm:"type:text[]" json:"permissions"`
-	Balance       float64        `gorm:"default:0" json:"balance"`
+	Permissions   pq.StringArray `gorm:"type:text[]" json:"-"`
+	Balance       money.Minor    `gorm:"default:0" json:"-"`
 	Cards         []Card         `gorm:"foreignKey:CompanyID" json:"cards"`
 	Orders        []Order        `gorm:"foreignKey:CompanyID" json:"orders"`
 	Reviews       []Review       `gorm:"foreignKey:CompanyID" json:"reviews"`
+
+	// Зона обслуживания компании по умолчанию для всех ее карточек
+	Latitude        *float64 `json:"latitude"`
+	Longitude       *float64 `json:"longitude"`
+	ServiceRadiusKm *float64 `json:"service_radius_km"`
+	ServiceArea     *string  `json:"service_area"` // Многоугольник в текстовом формате polygon Postgres
+
+	// Средние оценки по критериям и рейтинг для сортировки в поиске
+	QualityRating       float64 `gorm:"default:0" json:"quality_rating"`
+	PunctualityRating   float64 `gorm:"default:0" json:"punctuality_rating"`
+	CommunicationRating float64 `gorm:"default:0" json:"communication_rating"`
+	ValueRating         float64 `gorm:"default:0" json:"value_rating"`
+	RankingScore        float64 `gorm:"default:0;index" json:"ranking_score"` // Байесовская оценка, см. пакет rating
+
+	// Уровень компании, от которого зависит комиссия платформы
+	Tier string `gorm:"default:'standard'" json:"tier"`
+
+	// Валюта счета компании. В ней же указываются цены карточек и начисляются выплаты
+	Currency string `gorm:"default:'RUB'" json:"currency"`
+
+	// Время подтверждения email по ссылке из письма, nil - email не подтвержден
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+
+	// Реквизиты для вывода средств. Не отдаются вместе с компанией, см. /v1/account/payout-details
+	PayoutRecipient string     `json:"-"`
+	PayoutINN       string     `json:"-"`
+	PayoutBankName  string     `json:"-"`
+	PayoutBIK       string     `json:"-"`
+	PayoutAccount   string     `json:"-"`
+	PayoutUpdatedAt *time.Time `json:"-"`
 }
 
 type Card struct {
 	gorm.Model
-	ID          uint      `gorm:"primaryKey;autoIncrement" json:"id"`
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	Price       float64   `json:"price"`
-	Location    string    `json:"location"`
-	Category    string    `json:"category"`
-	IsActive    bool      `gorm:"default:true"`
-	CompanyID   uint      `json:"company_id"`
-	Company     CompanyDB `gorm:"foreignKey:CompanyID" json:"company"`
-	Orders      []Order   `gorm:"foreignKey:CardID" json:"orders"`
+	ID          uint        `gorm:"primaryKey;autoIncrement" json:"id"`
+	Title       string      `json:"title"`
+	Description string      `json:"description"`
+	Price       money.Minor `json:"price"`
+	Location    string      `json:"location"`
+	Category    string      `json:"category"` // Название категории, сохранено для совместимости
+	CategoryID  *uint       `gorm:"index" json:"category_id"`
+	IsActive    bool        `gorm:"default:true"`
+	CompanyID   uint        `json:"company_id"`
+	Company     CompanyDB   `gorm:"foreignKey:CompanyID" json:"company"`
+	Orders      []Order     `gorm:"foreignKey:CardID" json:"orders"`
+
+	// Координаты и зона обслуживания карточки. Если не заданы, берутся из компании
+	Latitude        *float64 `json:"latitude"`
+	Longitude       *float64 `json:"longitude"`
+	ServiceRadiusKm *float64 `json:"service_radius_km"`
+	ServiceArea     *string  `json:"service_area"`
+	DistanceKm      *float64 `gorm:"->;-:migration" json:"distance_km,omitempty"` // Заполняется при поиске рядом с точкой
+
+	// Валюта цены, совпадает с валютой счета компании
+	Currency string `gorm:"default:'RUB'" json:"currency"`
 }
 
 type Order struct {
@@ -70,26 +121,64 @@ type Order struct {
 	Company            CompanyDB           `gorm:"foreignKey:CompanyID" json:"company"`
 	CardID             uint                `json:"card_id"`
 	Card               Card                `gorm:"foreignKey:CardID" json:"card"`
-	Amount             float64             `json:"amount"`
+	Amount             money.Minor         `json:"amount"`
 	Status             string              `gorm:"default:'created'" json:"status"`         // created, paid, in_progress, completed, finished, cancelled
 	PaymentStatus      string              `gorm:"default:'pending'" json:"payment_status"` // pending, paid, refunded
 	Description        string              `json:"description"`
-	WorkerCompleteURL  string              `json:"worker_complete_url"` // Одноразовая ссылка для работника
+	WorkerCompleteURL  string              `json:"-"` // Одноразовая ссылка для работника, отдается только компании
 	EscrowTransactions []EscrowTransaction `gorm:"foreignKey:OrderID" json:"escrow_transactions"`
 	Notifications      []Notification      `gorm:"foreignKey:OrderID" json:"notifications"`
 	CompletedAt        *time.Time          `json:"completed_at"`
+	ScheduledAt        *time.Time          `gorm:"index" json:"scheduled_at"` // Начало забронированного слота
+	ScheduledEnd       *time.Time          `json:"scheduled_end"`             // Конец забронированного слота
+	WorkerID           *uint               `gorm:"index" json:"worker_id"`    // Назначенный работник компании
+	Worker             *Worker             `gorm:"foreignKey:WorkerID" json:"worker"`
+	CheckInAt          *time.Time          `json:"check_in_at"`
+	CheckOutAt         *time.Time          `json:"check_out_at"`
+	CompletedByID      *uint               `json:"completed_by_id"` // Работник, отметивший выполнение
+	CompletionReport   *CompletionReport   `gorm:"foreignKey:OrderID" json:"completion_report"`
+
+	// Комиссия платформы, зафиксированная при создании заказа
+	FeeRuleID   *uint       `json:"fee_rule_id"`
+	FeePercent  float64     `json:"fee_percent"`
+	FeeFixed    money.Minor `json:"fee_fixed"`
+	FeeAmount   money.Minor `json:"fee_amount"`
+	FeeQuotedAt *time.Time  `json:"fee_quoted_at"` // nil у заказов, созданных до появления комиссий
+
+	// Валюта заказа совпадает с валютой карточки. Если счет клиента в другой валюте,
+	// при оплате фиксируются курс и списанная с клиента сумма: по ним же делается возврат
+	Currency        string      `gorm:"default:'RUB'" json:"currency"`
+	PaymentCurrency string      `json:"payment_currency"`
+	PaymentAmount   money.Minor `json:"payment_amount"`
+	ExchangeRate    float64     `json:"exchange_rate"`
+	RateLockedAt    *time.Time  `json:"rate_locked_at"`
+
+	// Скидка по промокоду. Amount - сумма к оплате клиентом: Subtotal за вычетом скидки.
+	// Часть скидки за счет платформы доплачивается платформой в эскроу при оплате
+	Subtotal         money.Minor     `json:"subtotal"`
+	DiscountAmount   money.Minor     `json:"discount_amount"`
+	PlatformDiscount money.Minor     `json:"platform_discount"`
+	PromoCodeID      *uint           `gorm:"index" json:"promo_code_id"`
+	LineItems        []OrderLineItem `gorm:"foreignKey:OrderID" json:"line_items"`
+
+	// Повторяющийся заказ, по расписанию которого создан заказ
+	RecurringOrderID *uint `gorm:"index" json:"recurring_order_id"`
+
+	// Оформление корзины, в котором заказ создан и оплачен вместе с другими
+	CheckoutID *uint `gorm:"index" json:"checkout_id"`
 }
 
 type EscrowTransaction struct {
 	gorm.Model
-	ID       uint    `gorm:"primaryKey;autoIncrement" json:"id"`
-	OrderID  uint    `json:"order_id"`
-	Order    Order   `gorm:"foreignKey:OrderID" json:"order"`
-	Amount   float64 `json:"amount"`
-	Type     string  `json:"type"`      // hold, release, refund
-	Status   string  `json:"status"`    // pending, completed, failed
-	FromUser string  `json:"from_user"` // client, company, system
-	ToUser   string  `json:"to_user"`   // client, company, escrow
+	ID       uint        `gorm:"primaryKey;autoIncrement" json:"id"`
+	OrderID  uint        `json:"order_id"`
+	Order    Order       `gorm:"foreignKey:OrderID" json:"order"`
+	Amount   money.Minor `json:"amount"`
+	Type     string      `json:"type"`      // hold, release, refund
+	Status   string      `json:"status"`    // pending, completed, failed
+	FromUser string      `json:"from_user"` // client, company, system
+	ToUser   string      `json:"to_user"`   // client, company, escrow
+	Currency string      `gorm:"default:'RUB'" json:"currency"`
 }
 
 type Review struct {
@@ -103,6 +192,20 @@ type Review struct {
 	Order     Order     `gorm:"foreignKey:OrderID" json:"order"`
 	Rating    int       `gorm:"check:rating >= 1 AND rating <= 5" json:"rating"`
 	Comment   string    `json:"comment"`
+
+	// Ответ компании (один на отзыв) и модерация
+	Reply        string     `json:"reply"`
+	RepliedAt    *time.Time `json:"replied_at"`
+	EditedAt     *time.Time `json:"edited_at"`
+	IsHidden     bool       `gorm:"default:false;index" json:"is_hidden"`
+	HiddenAt     *time.Time `json:"hidden_at"`
+	HiddenReason string     `json:"hidden_reason"`
+
+	// Необязательные оценки по критериям
+	QualityRating       *int `gorm:"check:quality_rating IS NULL OR quality_rating BETWEEN 1 AND 5" json:"quality_rating"`
+	PunctualityRating   *int `gorm:"check:punctuality_rating IS NULL OR punctuality_rating BETWEEN 1 AND 5" json:"punctuality_rating"`
+	CommunicationRating *int `gorm:"check:communication_rating IS NULL OR communication_rating BETWEEN 1 AND 5" json:"communication_rating"`
+	ValueRating         *int `gorm:"check:value_rating IS NULL OR value_rating BETWEEN 1 AND 5" json:"value_rating"`
 }
 
 type Notification struct {
@@ -121,22 +224,488 @@ type Notification struct {
 
 type BalanceTransaction struct {
 	gorm.Model
-	ID          uint    `gorm:"primaryKey;autoIncrement" json:"id"`
-	UserID      uint    `json:"user_id"`   // ID клиента или компании
-	UserType    string  `json:"user_type"` // client, company
-	Amount      float64 `json:"amount"`
-	Type        string  `json:"type"`     // deposit, withdrawal, payment, refund
-	Status      string  `json:"status"`   // pending, completed, failed
-	OrderID     *uint   `json:"order_id"` // Связь с заказом, если транзакция связана с заказом
-	Description string  `json:"description"`
+	ID          uint        `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID      uint        `gorm:"index:idx_balance_transactions_owner_order,priority:2" json:"user_id"`   // ID клиента или компании
+	UserType    string      `gorm:"index:idx_balance_transactions_owner_order,priority:1" json:"user_type"` // client, company
+	Amount      money.Minor `json:"amount"`
+	Type        string      `json:"type"`                                                                  // deposit, withdrawal, payment, refund
+	Status      string      `json:"status"`                                                                // pending, completed, failed
+	OrderID     *uint       `gorm:"index:idx_balance_transactions_owner_order,priority:3" json:"order_id"` // Связь с заказом, если транзакция связана с заказом
+	Description string      `json:"description"`
+	Currency    string      `gorm:"default:'RUB'" json:"currency"`
+
+	// Оформление корзины: одно списание оплачивает сразу несколько заказов
+	CheckoutID *uint `gorm:"index" json:"checkout_id"`
 }
 
 type WorkerLink struct {
+	gorm.Model
+	ID        uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	OrderID   uint       `json:"order_id"`
+	Order     Order      `gorm:"foreignKey:OrderID" json:"order"`
+	Token     string     `gorm:"unique" json:"token"`
+	IsUsed    bool       `gorm:"default:false" json:"is_used"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+}
+
+// WorkerLinkAccess запись журнала обращений к ссылке работника
+type WorkerLinkAccess struct {
+	gorm.Model
+	ID           uint   `gorm:"primaryKey;autoIncrement" json:"id"`
+	WorkerLinkID *uint  `gorm:"index" json:"worker_link_id"` // Пусто, если токен не найден
+	OrderID      *uint  `gorm:"index" json:"order_id"`
+	Action       string `json:"action"` // view, submit
+	Result       string `json:"result"` // ok, expired, revoked, used, not_found
+	IP           string `json:"ip"`
+	UserAgent    string `json:"user_agent"`
+}
+
+// WorkingHours рабочее время компании в один из дней недели
+type WorkingHours struct {
+	gorm.Model
+	ID          uint `gorm:"primaryKey;autoIncrement" json:"id"`
+	CompanyID   uint `gorm:"index" json:"company_id"`
+	Weekday     int  `json:"weekday"`      // 0 - воскресенье, 6 - суббота
+	StartMinute int  `json:"start_minute"` // Минуты от начала дня
+	EndMinute   int  `json:"end_minute"`
+	SlotMinutes int  `gorm:"default:60" json:"slot_minutes"`
+	Capacity    int  `gorm:"default:1" json:"capacity"` // Сколько заказов компания принимает в один слот
+}
+
+// BlackoutDate день, в который компания не принимает заказы
+type BlackoutDate struct {
 	gorm.Model
 	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
-	OrderID   uint      `json:"order_id"`
-	Order     Order     `gorm:"foreignKey:OrderID" json:"order"`
-	Token     string    `gorm:"unique" json:"token"`
-	IsUsed    bool      `gorm:"default:false" json:"is_used"`
-	ExpiresAt time.Time `json:"expires_at"`
+	CompanyID uint      `gorm:"index" json:"company_id"`
+	Date      time.Time `gorm:"type:date" json:"date"`
+	Reason    string    `json:"reason"`
+}
+
+// Worker сотрудник компании с ограниченным доступом к назначенным заказам
+type Worker struct {
+	gorm.Model
+	ID           uint           `gorm:"primaryKey;autoIncrement" json:"id"`
+	CompanyID    uint           `gorm:"index" json:"company_id"`
+	FullName     string         `json:"full_name"`
+	Email        string         `gorm:"unique" json:"email"`
+	Phone        string         `json:"phone"`
+	PasswordHash string         `json:"-"`
+	Permissions  pq.StringArray `gorm:"type:text[]" json:"permissions"` // view_tasks, check_in, complete_orders
+	IsActive     bool           `gorm:"default:true" json:"is_active"`
+}
+
+// CompletionReport отчет о выполнении заказа. Хранится как доказательство для споров
+type CompletionReport struct {
+	gorm.Model
+	ID               uint           `gorm:"primaryKey;autoIncrement" json:"id"`
+	OrderID          uint           `gorm:"uniqueIndex" json:"order_id"`
+	WorkerID         *uint          `json:"worker_id"`
+	Notes            string         `json:"notes"`
+	TimeSpentMinutes int            `json:"time_spent_minutes"`
+	Latitude         *float64       `json:"latitude"`
+	Longitude        *float64       `json:"longitude"`
+	Photos           pq.StringArray `gorm:"type:text[]" json:"photos"` // Имена файлов в хранилище
+	SubmittedIP      string         `json:"-"`
+	UserAgent        string         `json:"-"`
+}
+
+// AdminDB администратор платформы. Управляет справочниками и модерацией
+type AdminDB struct {
+	gorm.Model
+	ID           uint   `gorm:"primaryKey;autoIncrement" json:"id"`
+	FullName     string `json:"full_name"`
+	Email        string `gorm:"unique" json:"email"`
+	PasswordHash string `json:"-"`
+	IsActive     bool   `gorm:"default:true" json:"is_active"`
+}
+
+// Category категория услуг. Категории образуют дерево через ParentID
+type Category struct {
+	gorm.Model
+	ID        uint           `gorm:"primaryKey;autoIncrement" json:"id"`
+	ParentID  *uint          `gorm:"index" json:"parent_id"`
+	Slug      string         `gorm:"uniqueIndex" json:"slug"`
+	NameRu    string         `json:"name_ru"`
+	NameEn    string         `json:"name_en"`
+	Icon      string         `json:"icon"`
+	SortOrder int            `gorm:"default:0" json:"sort_order"`
+	Aliases   pq.StringArray `gorm:"type:text[]" json:"aliases"` // Старые написания для сопоставления строк
+	IsActive  bool           `gorm:"default:true" json:"is_active"`
+}
+
+// Favorite карточка или компания в избранном клиента
+type Favorite struct {
+	gorm.Model
+	ID         uint   `gorm:"primaryKey;autoIncrement" json:"id"`
+	ClientID   uint   `gorm:"uniqueIndex:idx_favorite_target" json:"client_id"`
+	TargetType string `gorm:"uniqueIndex:idx_favorite_target;index:idx_favorite_by_target" json:"target_type"` // card, company
+	TargetID   uint   `gorm:"uniqueIndex:idx_favorite_target;index:idx_favorite_by_target" json:"target_id"`
+}
+
+// SavedSearch сохраненный поиск клиента. Фильтры повторяют параметры /search/cards
+type SavedSearch struct {
+	gorm.Model
+	ID             uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	ClientID       uint       `gorm:"index" json:"client_id"`
+	Name           string     `json:"name"`
+	Query          string     `json:"query"`
+	Category       string     `json:"category"` // Слаг категории
+	MinPrice       *float64   `json:"min_price"`
+	MaxPrice       *float64   `json:"max_price"`
+	Location       string     `json:"location"`
+	MinRating      *float64   `json:"min_rating"`
+	VerifiedOnly   bool       `json:"verified_only"`
+	Latitude       *float64   `json:"latitude"`
+	Longitude      *float64   `json:"longitude"`
+	RadiusKm       float64    `json:"radius_km"`
+	Sort           string     `json:"sort"`
+	NotifyNewCards bool       `gorm:"default:true" json:"notify_new_cards"`
+	LastNotifiedAt *time.Time `json:"last_notified_at"`
+}
+
+// ReviewReport жалоба на отзыв. Попадает в очередь модерации администраторов
+type ReviewReport struct {
+	gorm.Model
+	ID           uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	ReviewID     uint       `gorm:"index" json:"review_id"`
+	Review       Review     `gorm:"foreignKey:ReviewID" json:"-"`
+	ReporterID   uint       `json:"reporter_id"`
+	ReporterType string     `json:"reporter_type"` // client, company
+	Reason       string     `json:"reason"`
+	Status       string     `gorm:"default:'pending';index" json:"status"` // pending, hidden, dismissed
+	ResolvedByID *uint      `json:"resolved_by_id"`
+	ResolvedAt   *time.Time `json:"resolved_at"`
+}
+
+// FeeRule правило комиссии платформы: процент от суммы заказа плюс фиксированная часть.
+// Из подходящих правил выбирается правило с наибольшим приоритетом, при равенстве -
+// более конкретное. Промо-период без комиссии задается правилом с нулевыми значениями
+// и сроком действия
+type FeeRule struct {
+	gorm.Model
+	ID          uint        `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name        string      `json:"name"`
+	CategoryID  *uint       `gorm:"index" json:"category_id"` // nil - любая категория, иначе категория и ее подкатегории
+	CompanyTier string      `json:"company_tier"`             // Пусто - любой уровень компании
+	Percent     float64     `json:"percent"`
+	Fixed       money.Minor `json:"fixed"` // В валюте Currency, для заказов в другой валюте пересчитывается
+	Priority    int         `gorm:"default:0" json:"priority"`
+	StartsAt    *time.Time  `json:"starts_at"`
+	EndsAt      *time.Time  `json:"ends_at"`
+	IsActive    bool        `json:"is_active"`
+	Currency    string      `gorm:"default:'RUB'" json:"currency"`
+}
+
+// PlatformAccount счет платформы. Комиссии зачисляются на счет revenue в валюте заказа
+type PlatformAccount struct {
+	gorm.Model
+	ID       uint        `gorm:"primaryKey;autoIncrement" json:"id"`
+	Code     string      `gorm:"uniqueIndex:idx_platform_accounts_code_currency" json:"code"`
+	Currency string      `gorm:"uniqueIndex:idx_platform_accounts_code_currency;default:'RUB'" json:"currency"`
+	Balance  money.Minor `gorm:"default:0" json:"balance"`
+}
+
+// FinancialDocument бухгалтерский документ в PDF: счет по оплаченному заказу, квитанция
+// о пополнении баланса или выписка по выплате компании. Документ выпускается один раз
+// на заказ или транзакцию, номера вида INV-2026-000001 идут подряд в пределах типа и года.
+// Типы: invoice, receipt, payout_statement
+type FinancialDocument struct {
+	gorm.Model
+	ID            uint        `gorm:"primaryKey;autoIncrement" json:"id"`
+	Number        string      `gorm:"uniqueIndex" json:"number"`
+	Type          string      `gorm:"uniqueIndex:idx_financial_documents_order;uniqueIndex:idx_financial_documents_transaction" json:"type"`
+	OrderID       *uint       `gorm:"uniqueIndex:idx_financial_documents_order" json:"order_id"`
+	TransactionID *uint       `gorm:"uniqueIndex:idx_financial_documents_transaction" json:"transaction_id"`
+	ClientID      *uint       `gorm:"index" json:"client_id"`  // Клиент, которому доступен документ
+	CompanyID     *uint       `gorm:"index" json:"company_id"` // Компания, которой доступен документ
+	Amount        money.Minor `json:"amount"`
+	Currency      string      `gorm:"default:'RUB'" json:"currency"`
+	FileName      string      `json:"-"` // Путь в файловом хранилище
+	IssuedAt      time.Time   `json:"issued_at"`
+}
+
+// DocumentSequence счетчик номеров документов по типу и году
+type DocumentSequence struct {
+	Type  string `gorm:"primaryKey"`
+	Year  int    `gorm:"primaryKey;autoIncrement:false"`
+	Value int64  `gorm:"default:0"`
+}
+
+// ExportJob фоновая выгрузка данных пользователя в файл. Небольшие выгрузки отдаются
+// сразу и задач не создают
+type ExportJob struct {
+	gorm.Model
+	ID           uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID       uint       `gorm:"index:idx_export_jobs_owner" json:"user_id"`
+	UserType     string     `gorm:"index:idx_export_jobs_owner" json:"user_type"` // client, company
+	Kind         string     `json:"kind"`                                         // balance, escrow, orders
+	Format       string     `json:"format"`                                       // csv, xlsx, ndjson
+	DateFrom     *time.Time `json:"date_from"`
+	DateTo       *time.Time `json:"date_to"` // Не включается в выгрузку
+	StatusFilter string     `json:"status_filter"`
+	Status       string     `gorm:"default:'pending'" json:"status"` // pending, running, completed, failed
+	Rows         int64      `json:"rows"`
+	FileName     string     `json:"-"`
+	Error        string     `json:"error"`
+	CompletedAt  *time.Time `json:"completed_at"`
+}
+
+// OrderLineItem строка заказа: услуга по цене карточки и скидки с отрицательной суммой.
+// Типы: service, discount
+type OrderLineItem struct {
+	gorm.Model
+	ID          uint        `gorm:"primaryKey;autoIncrement" json:"id"`
+	OrderID     uint        `gorm:"index" json:"order_id"`
+	Type        string      `json:"type"`
+	Description string      `json:"description"`
+	Amount      money.Minor `json:"amount"`
+	Currency    string      `json:"currency"`
+	FundedBy    string      `json:"funded_by"` // Для скидок: platform, company
+	PromoCodeID *uint       `json:"promo_code_id"`
+
+	// Карточка и количество для строк услуг. В заказе из корзины строк несколько
+	CardID   *uint `json:"card_id"`
+	Quantity int   `gorm:"default:1" json:"quantity"`
+}
+
+// PromoCode промокод на скидку в процентах (percent) или фиксированной суммой (fixed).
+// Код можно ограничить категорией, компанией, сроком действия и числом применений.
+// Скидку оплачивает платформа (platform) или компания (company)
+type PromoCode struct {
+	gorm.Model
+	ID           uint        `gorm:"primaryKey;autoIncrement" json:"id"`
+	Code         string      `gorm:"uniqueIndex" json:"code"` // Хранится в верхнем регистре
+	Description  string      `json:"description"`
+	Type         string      `json:"type"`
+	Percent      float64     `json:"percent"`
+	Amount       money.Minor `json:"amount"`       // Для fixed, в валюте Currency
+	MaxDiscount  money.Minor `json:"max_discount"` // Для percent, 0 - без ограничения
+	Currency     string      `gorm:"default:'RUB'" json:"currency"`
+	MinOrder     money.Minor `json:"min_order"`      // Наименьшая цена услуги, 0 - любая
+	UsageLimit   int         `json:"usage_limit"`    // Всего применений, 0 - без ограничения
+	PerUserLimit int         `json:"per_user_limit"` // Применений одним клиентом, 0 - без ограничения
+	UsedCount    int         `gorm:"default:0" json:"used_count"`
+	CategoryID   *uint       `gorm:"index" json:"category_id"` // Категория вместе с подкатегориями
+	CompanyID    *uint       `gorm:"index" json:"company_id"`
+	FundedBy     string      `gorm:"default:'platform'" json:"funded_by"`
+	StartsAt     *time.Time  `json:"starts_at"`
+	EndsAt       *time.Time  `json:"ends_at"`
+	IsActive     bool        `json:"is_active"`
+}
+
+// PromoRedemption применение промокода к заказу. Удаляется при отмене заказа,
+// освобождая применение
+type PromoRedemption struct {
+	gorm.Model
+	ID          uint        `gorm:"primaryKey;autoIncrement" json:"id"`
+	PromoCodeID uint        `gorm:"index:idx_promo_redemptions_client" json:"promo_code_id"`
+	ClientID    uint        `gorm:"index:idx_promo_redemptions_client" json:"client_id"`
+	OrderID     uint        `gorm:"uniqueIndex" json:"order_id"`
+	Amount      money.Minor `json:"amount"`
+	Currency    string      `json:"currency"`
+}
+
+// ReferralCode личный реферальный код клиента
+type ReferralCode struct {
+	gorm.Model
+	ID       uint   `gorm:"primaryKey;autoIncrement" json:"id"`
+	ClientID uint   `gorm:"uniqueIndex" json:"client_id"`
+	Code     string `gorm:"uniqueIndex" json:"code"`
+}
+
+// Referral приглашение клиента другим клиентом. После первого завершенного заказа
+// приглашенного оба получают бонус на баланс. Статусы: pending, credited
+type Referral struct {
+	gorm.Model
+	ID         uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	ReferrerID uint       `gorm:"index" json:"referrer_id"`
+	ReferredID uint       `gorm:"uniqueIndex" json:"referred_id"`
+	Status     string     `gorm:"default:'pending'" json:"status"`
+	OrderID    *uint      `json:"order_id"` // Заказ, после которого начислен бонус
+	CreditedAt *time.Time `json:"credited_at"`
+}
+
+// RecurringOrder расписание повторяющегося заказа клиента по карточке услуги. Заказы
+// создаются заранее, за RECURRING_LEAD_HOURS до занятия, и при AutoPay оплачиваются с баланса.
+// Статусы: active, paused, cancelled, finished
+type RecurringOrder struct {
+	gorm.Model
+	ID          uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	ClientID    uint       `gorm:"index" json:"client_id"`
+	CompanyID   uint       `gorm:"index" json:"company_id"`
+	CardID      uint       `json:"card_id"`
+	Description string     `json:"description"`
+	Frequency   string     `json:"frequency"`            // weekly, biweekly, monthly, custom
+	Rule        string     `json:"rule"`                 // RRULE, например FREQ=WEEKLY;INTERVAL=2
+	StartsAt    time.Time  `json:"starts_at"`            // Первое занятие, задает время остальных
+	NextAt      *time.Time `gorm:"index" json:"next_at"` // Ближайшее занятие без заказа, nil - занятий больше нет
+	AutoPay     bool       `json:"auto_pay"`
+	Status      string     `gorm:"default:'active'" json:"status"`
+	LastError   string     `json:"last_error"`
+
+	Client  ClientDB  `gorm:"foreignKey:ClientID" json:"client"`
+	Company CompanyDB `gorm:"foreignKey:CompanyID" json:"company"`
+	Card    Card      `gorm:"foreignKey:CardID" json:"card"`
+}
+
+// RecurringOccurrence занятие повторяющегося заказа, для которого что-то произошло:
+// создан заказ или клиент его пропустил. Статусы: skipped, created, paid, payment_failed,
+// payment_retrying (оплата повторяется прямо сейчас), failed
+type RecurringOccurrence struct {
+	gorm.Model
+	ID               uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	RecurringOrderID uint      `gorm:"uniqueIndex:idx_recurring_occurrences_slot" json:"recurring_order_id"`
+	ScheduledAt      time.Time `gorm:"uniqueIndex:idx_recurring_occurrences_slot" json:"scheduled_at"`
+	Status           string    `json:"status"`
+	OrderID          *uint     `json:"order_id"`
+	Error            string    `json:"error"`
+}
+
+// CartItem карточка в корзине клиента. Корзина может содержать услуги разных компаний
+type CartItem struct {
+	gorm.Model
+	ID       uint `gorm:"primaryKey;autoIncrement" json:"id"`
+	ClientID uint `gorm:"uniqueIndex:idx_cart_items_client_card" json:"client_id"`
+	CardID   uint `gorm:"uniqueIndex:idx_cart_items_client_card" json:"card_id"`
+	Quantity int  `json:"quantity"`
+	Card     Card `gorm:"foreignKey:CardID" json:"card"`
+}
+
+// Checkout оформление корзины: по заказу на каждую компанию, оплаченные одним списанием
+// с баланса клиента. Amount указан в валюте счета клиента
+type Checkout struct {
+	gorm.Model
+	ID         uint        `gorm:"primaryKey;autoIncrement" json:"id"`
+	ClientID   uint        `gorm:"index" json:"client_id"`
+	Amount     money.Minor `json:"amount"`
+	Currency   string      `json:"currency"`
+	OrderCount int         `json:"order_count"`
+	Orders     []Order     `gorm:"foreignKey:CheckoutID" json:"orders"`
+}
+
+// AuthToken одноразовый токен из письма: подтверждение email (verify_email), сброс пароля
+// (reset_password) или смена email (change_email). Хранится только хеш токена. Email - адрес,
+// который подтверждает токен. С целью two_factor_login выдается после пароля при входе
+// с двухфакторной аутентификацией и обменивается на токен доступа вместе с кодом
+type AuthToken struct {
+	gorm.Model
+	ID        uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID    uint       `gorm:"index:idx_auth_tokens_user" json:"user_id"`
+	UserType  string     `gorm:"index:idx_auth_tokens_user" json:"user_type"`
+	Purpose   string     `gorm:"index:idx_auth_tokens_user" json:"purpose"`
+	TokenHash string     `gorm:"uniqueIndex" json:"-"`
+	Email     string     `json:"email"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+}
+
+// TwoFactor настройка двухфакторной аутентификации (TOTP) клиента или компании. Секрет
+// зашифрован, см. security.EncryptSecret. Пока EnabledAt пустой, подключение не подтверждено
+// кодом и при входе не требуется. LastUsedStep - шаг последнего принятого кода, код
+// нельзя использовать дважды
+type TwoFactor struct {
+	gorm.Model
+	ID              uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID          uint       `gorm:"uniqueIndex:idx_two_factors_user" json:"user_id"`
+	UserType        string     `gorm:"uniqueIndex:idx_two_factors_user" json:"user_type"`
+	SecretEncrypted string     `json:"-"`
+	EnabledAt       *time.Time `json:"enabled_at"`
+	LastUsedStep    int64      `gorm:"default:0" json:"-"`
+}
+
+// RecoveryCode резервный код для входа без приложения-аутентификатора. Каждый код
+// одноразовый, хранится только хеш
+type RecoveryCode struct {
+	gorm.Model
+	ID       uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID   uint       `gorm:"index:idx_recovery_codes_user" json:"user_id"`
+	UserType string     `gorm:"index:idx_recovery_codes_user" json:"user_type"`
+	CodeHash string     `gorm:"uniqueIndex" json:"-"`
+	UsedAt   *time.Time `json:"used_at"`
+}
+
+// TrustedDevice устройство, на котором пользователь отметил "доверять" при вводе кода.
+// С него вход выполняется без кода до ExpiresAt. Подтверждение вывода средств и смены
+// реквизитов код требует всегда
+type TrustedDevice struct {
+	gorm.Model
+	ID         uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID     uint      `gorm:"index:idx_trusted_devices_user" json:"user_id"`
+	UserType   string    `gorm:"index:idx_trusted_devices_user" json:"user_type"`
+	TokenHash  string    `gorm:"uniqueIndex" json:"-"`
+	Name       string    `json:"name"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+}
+
+// RateLimitBucket корзина ограничения частоты запросов для хранилища в Postgres.
+// Key состоит из маршрута и IP-адреса или учетной записи, см. пакет ratelimit
+type RateLimitBucket struct {
+	Key        string    `gorm:"primaryKey"`
+	Tokens     float64   `gorm:"not null"`
+	RefilledAt time.Time `gorm:"index"`
+}
+
+// RateLimitFailure счетчик неудачных попыток подряд (вход, код 2FA) и время окончания блокировки
+type RateLimitFailure struct {
+	Key           string    `gorm:"primaryKey"`
+	Failures      int       `gorm:"not null"`
+	LastFailureAt time.Time `gorm:"index"`
+	LockedUntil   *time.Time
+}
+
+// UserDB человек, который входит в систему. Он действует от имени своего клиентского
+// профиля (ClientID) или компании, в которой состоит (CompanyMembership). Пароль, email
+// и 2FA относятся к пользователю, а баланс, заказы и карточки - к клиенту или компании.
+// Пароли и подтверждение email в ClientDB и CompanyDB остались от учетных записей до
+// появления пользователей и при входе не используются
+type UserDB struct {
+	gorm.Model
+	ID              uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	Email           string     `gorm:"unique" json:"email"`
+	FullName        string     `json:"full_name"`
+	PasswordHash    string     `json:"-"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	ClientID        *uint      `gorm:"uniqueIndex" json:"client_id"` // Клиентский профиль, nil - пользователь не клиент
+}
+
+// CompanyMembership роль пользователя в компании: owner управляет всем, включая состав
+// сотрудников и реквизиты, manager - карточками, заказами и расписанием, accountant -
+// балансом, документами и выгрузками
+type CompanyMembership struct {
+	gorm.Model
+	ID        uint   `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID    uint   `gorm:"uniqueIndex:idx_company_memberships_user_company" json:"user_id"`
+	CompanyID uint   `gorm:"uniqueIndex:idx_company_memberships_user_company;index" json:"company_id"`
+	Role      string `json:"role"`
+}
+
+// ExternalIdentity учетная запись пользователя у внешнего OIDC-провайдера. Subject -
+// постоянный идентификатор у провайдера: вход находит пользователя по нему, а не по email.
+// У пользователя не больше одной учетной записи каждого провайдера
+type ExternalIdentity struct {
+	gorm.Model
+	ID         uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID     uint       `gorm:"uniqueIndex:idx_external_identities_user_provider" json:"user_id"`
+	Provider   string     `gorm:"uniqueIndex:idx_external_identities_user_provider;uniqueIndex:idx_external_identities_provider_subject" json:"provider"`
+	Subject    string     `gorm:"uniqueIndex:idx_external_identities_provider_subject" json:"-"`
+	Email      string     `json:"email"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+// OIDCState вход или привязка через OIDC-провайдера, начатые, но еще не завершенные.
+// Хранится хеш state из адреса входа, nonce и code_verifier (PKCE) для проверки ответа.
+// UserID задан при привязке: завершить ее может только тот же пользователь
+type OIDCState struct {
+	gorm.Model
+	ID           uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	StateHash    string     `gorm:"uniqueIndex" json:"-"`
+	Provider     string     `json:"provider"`
+	Purpose      string     `json:"purpose"` // "login" или "link"
+	UserID       uint       `json:"user_id"`
+	Nonce        string     `json:"-"`
+	CodeVerifier string     `json:"-"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+	UsedAt       *time.Time `json:"used_at"`
 }
@@ -0,0 +1,22 @@
+package database
+
+import (
+	"fmt"
+	"gorm.io/gorm"
+)
+
+// listTables таблицы, списки которых отдаются постранично по курсору (created_at, id)
+var listTables = []string{"cards", "orders", "notifications", "balance_transactions", "reviews"}
+
+// SetupListIndexes создает составные индексы для постраничной выдачи по курсору
+// и для отчетов компаний. Повторный запуск безопасен
+func SetupListIndexes(db *gorm.DB) error {
+	for _, table := range listTables {
+		statement := fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_%s_created_at_id ON %s (created_at DESC, id DESC)", table, table)
+		if err := db.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	// Отчеты компаний выбирают заказы компании за период
+	return db.Exec("CREATE INDEX IF NOT EXISTS idx_orders_company_created_at ON orders (company_id, created_at)").Error
+}
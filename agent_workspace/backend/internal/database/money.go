@@ -0,0 +1,53 @@
+package database
+
+import (
+	"fmt"
+	"gorm.io/gorm"
+)
+
+// moneyColumns денежные колонки, которые до появления валют хранились в рублях
+// числами с плавающей точкой
+var moneyColumns = []struct {
+	Table  string
+	Column string
+}{
+	{"client_dbs", "balance"},
+	{"company_dbs", "balance"},
+	{"cards", "price"},
+	{"orders", "amount"},
+	{"orders", "fee_fixed"},
+	{"orders", "fee_amount"},
+	{"escrow_transactions", "amount"},
+	{"balance_transactions", "amount"},
+	{"fee_rules", "fixed"},
+	{"platform_accounts", "balance"},
+	{"financial_documents", "amount"},
+}
+
+// MigrateMoneyColumns переводит денежные колонки в целые копейки. Вызывается до
+// AutoMigrate, повторный запуск безопасен: переводятся только колонки double precision.
+// Валюта существующих записей заполняется значением по умолчанию RUB при AutoMigrate
+func MigrateMoneyColumns(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, money := range moneyColumns {
+			var dataType string
+			err := tx.Raw(`SELECT data_type FROM information_schema.columns
+				WHERE table_schema = current_schema() AND table_name = ? AND column_name = ?`,
+				money.Table, money.Column).Scan(&dataType).Error
+			if err != nil {
+				return err
+			}
+			if dataType != "double precision" {
+				continue
+			}
+
+			statement := fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s TYPE bigint USING ROUND(%s * 100)::bigint",
+				money.Table, money.Column, money.Column)
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		// Счета платформы теперь уникальны по коду и валюте
+		return tx.Exec("DROP INDEX IF EXISTS idx_platform_accounts_code").Error
+	})
+}
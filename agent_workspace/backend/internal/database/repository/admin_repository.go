@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"core/internal/database"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+)
+
+type AdminRepository interface {
+	Create(admin *database.AdminDB) error
+	GetByID(id uint) (*database.AdminDB, error)
+	GetByEmail(email string) (*database.AdminDB, error)
+	ExistsByEmail(email string) (bool, error)
+}
+
+type adminRepository struct {
+	db *gorm.DB
+}
+
+func (r *adminRepository) Create(admin *database.AdminDB) error {
+	return r.db.Create(admin).Error
+}
+
+func (r *adminRepository) GetByID(id uint) (*database.AdminDB, error) {
+	var admin database.AdminDB
+	err := r.db.First(&admin, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("admin with ID %d not found", id)
+		}
+		return nil, err
+	}
+	return &admin, nil
+}
+
+func (r *adminRepository) GetByEmail(email string) (*database.AdminDB, error) {
+	var admin database.AdminDB
+	err := r.db.Where("email = ?", email).First(&admin).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("admin not found")
+		}
+		return nil, err
+	}
+	return &admin, nil
+}
+
+func (r *adminRepository) ExistsByEmail(email string) (bool, error) {
+	var count int64
+	err := r.db.Model(&database.AdminDB{}).Where("email = ?", email).Count(&count).Error
+	return count > 0, err
+}
+
+func NewAdminRepository(db *gorm.DB) AdminRepository {
+	return &adminRepository{db: db}
+}
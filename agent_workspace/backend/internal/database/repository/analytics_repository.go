@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"core/internal/money"
+	"gorm.io/gorm"
+	"time"
+)
+
+// AnalyticsRange период отчета: заказы, созданные в [From, To), группируются
+// по интервалу (day, week, month) в часовом поясе TimeZone. Учитываются заказы
+// в валюте Currency, чтобы суммы не смешивали валюты
+type AnalyticsRange struct {
+	CompanyID uint
+	Currency  string
+	From      time.Time
+	To        time.Time
+	Interval  string
+	TimeZone  string
+}
+
+// AnalyticsMetrics агрегаты по заказам. Время выполнения хранится суммой, чтобы
+// агрегаты можно было складывать
+type AnalyticsMetrics struct {
+	Orders            int64
+	Paid              int64
+	Cancelled         int64
+	Finished          int64
+	Revenue           money.Minor
+	Earnings          money.Minor
+	CompletionSeconds float64
+	CompletionCount   int64
+}
+
+type AnalyticsBucket struct {
+	Bucket time.Time
+	AnalyticsMetrics
+}
+
+type CardAnalyticsRow struct {
+	CardID uint
+	Title  string
+	AnalyticsMetrics
+}
+
+type AnalyticsRepository interface {
+	GetOrderSeries(filter AnalyticsRange) ([]AnalyticsBucket, error)
+	GetCardBreakdown(filter AnalyticsRange) ([]CardAnalyticsRow, error)
+}
+
+type analyticsRepository struct {
+	db *gorm.DB
+}
+
+// analyticsMetricsSQL общие агрегаты для рядов и разбивки по карточкам. Оплаченными
+// считаются и возвращенные заказы: оплата по ним была. Начисления компании берутся
+// из транзакций баланса: оплата за заказ минус комиссия платформы. Транзакции отбираются
+// по компании (индекс idx_balance_transactions_owner_order) и по началу периода: оплата
+// не бывает раньше создания заказа. Конец периода к транзакциям не применяется, иначе
+// пропадут начисления по заказам, созданным в периоде и оплаченным после него
+const analyticsMetricsSQL = `COUNT(*) AS orders,
+	COUNT(*) FILTER (WHERE o.payment_status IN ('paid', 'refunded')) AS paid,
+	COUNT(*) FILTER (WHERE o.status = 'cancelled') AS cancelled,
+	COUNT(*) FILTER (WHERE o.status = 'finished') AS finished,
+	COALESCE(SUM(o.amount) FILTER (WHERE o.status = 'finished'), 0)::bigint AS revenue,
+	COALESCE(SUM(e.amount), 0)::bigint AS earnings,
+	COALESCE(SUM(EXTRACT(EPOCH FROM (o.completed_at - o.created_at))), 0) AS completion_seconds,
+	COUNT(o.completed_at) AS completion_count`
+
+const analyticsFromSQL = `FROM orders o
+	LEFT JOIN (
+		SELECT order_id, SUM(amount) AS amount FROM balance_transactions
+		WHERE user_type = 'company' AND user_id = @company AND created_at >= @from
+			AND type IN ('payment', 'fee') AND status = 'completed' AND deleted_at IS NULL
+		GROUP BY order_id
+	) e ON e.order_id = o.id`
+
+const analyticsWhereSQL = `WHERE o.company_id = @company AND o.currency = @currency AND o.deleted_at IS NULL
+	AND o.created_at >= @from AND o.created_at < @to`
+
+func (r *analyticsRepository) GetOrderSeries(filter AnalyticsRange) ([]AnalyticsBucket, error) {
+	var buckets []AnalyticsBucket
+	err := r.db.Raw(`SELECT date_trunc(@interval, o.created_at AT TIME ZONE @tz) AS bucket, `+
+		analyticsMetricsSQL+" "+analyticsFromSQL+" "+analyticsWhereSQL+`
+		GROUP BY bucket ORDER BY bucket`, analyticsVars(filter)).Scan(&buckets).Error
+	return buckets, err
+}
+
+func (r *analyticsRepository) GetCardBreakdown(filter AnalyticsRange) ([]CardAnalyticsRow, error) {
+	var rows []CardAnalyticsRow
+	err := r.db.Raw(`SELECT o.card_id, COALESCE(c.title, '') AS title, `+
+		analyticsMetricsSQL+" "+analyticsFromSQL+`
+		LEFT JOIN cards c ON c.id = o.card_id `+analyticsWhereSQL+`
+		GROUP BY o.card_id, c.title ORDER BY revenue DESC, orders DESC, o.card_id`, analyticsVars(filter)).Scan(&rows).Error
+	return rows, err
+}
+
+func analyticsVars(filter AnalyticsRange) map[string]interface{} {
+	return map[string]interface{}{
+		"company":  filter.CompanyID,
+		"currency": filter.Currency,
+		"from":     filter.From,
+		"to":       filter.To,
+		"interval": filter.Interval,
+		"tz":       filter.TimeZone,
+	}
+}
+
+func NewAnalyticsRepository(db *gorm.DB) AnalyticsRepository {
+	return &analyticsRepository{db: db}
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"core/internal/database"
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type AuthTokenRepository interface {
+	Create(token *database.AuthToken) error
+	// RevokeUnused отмечает использованными все действующие токены пользователя с целью purpose,
+	// чтобы работала только последняя отправленная ссылка
+	RevokeUnused(userID uint, userType, purpose string) error
+	// UpdateAccount меняет поля клиента или компании
+	UpdateAccount(userID uint, userType string, updates map[string]interface{}) error
+
+	// Методы для работы с транзакциями
+	BeginTransaction() *gorm.DB
+	// ConsumeInTx отмечает токен использованным и возвращает его. Токен, который истек
+	// или уже был использован, не принимается
+	ConsumeInTx(tx *gorm.DB, tokenHash, purpose string, at time.Time) (*database.AuthToken, error)
+	UpdateAccountInTx(tx *gorm.DB, userID uint, userType string, updates map[string]interface{}) error
+}
+
+type authTokenRepository struct {
+	db *gorm.DB
+}
+
+func (r *authTokenRepository) Create(token *database.AuthToken) error {
+	return r.db.Create(token).Error
+}
+
+func (r *authTokenRepository) RevokeUnused(userID uint, userType, purpose string) error {
+	return r.db.Model(&database.AuthToken{}).
+		Where("user_id = ? AND user_type = ? AND purpose = ? AND used_at IS NULL", userID, userType, purpose).
+		Update("used_at", time.Now()).Error
+}
+
+func (r *authTokenRepository) UpdateAccount(userID uint, userType string, updates map[string]interface{}) error {
+	return r.UpdateAccountInTx(r.db, userID, userType, updates)
+}
+
+func (r *authTokenRepository) BeginTransaction() *gorm.DB {
+	return r.db.Begin()
+}
+
+func (r *authTokenRepository) ConsumeInTx(tx *gorm.DB, tokenHash, purpose string, at time.Time) (*database.AuthToken, error) {
+	var token database.AuthToken
+	result := tx.Model(&token).Clauses(clause.Returning{}).
+		Where("token_hash = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?", tokenHash, purpose, at).
+		Update("used_at", at)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New("invalid or expired token")
+	}
+	return &token, nil
+}
+
+func (r *authTokenRepository) UpdateAccountInTx(tx *gorm.DB, userID uint, userType string, updates map[string]interface{}) error {
+	var model interface{} = &database.ClientDB{}
+	switch userType {
+	case "company":
+		model = &database.CompanyDB{}
+	case "user":
+		model = &database.UserDB{}
+	}
+	result := tx.Model(model).Where("id = ?", userID).Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("account not found")
+	}
+	return nil
+}
+
+func NewAuthTokenRepository(db *gorm.DB) AuthTokenRepository {
+	return &authTokenRepository{db: db}
+}
@@ -2,21 +2,36 @@ package repository
 
 import (
 	"core/internal/database"
+	"core/internal/money"
+	"core/internal/pagination"
+	"errors"
 	"gorm.io/gorm"
 )
 
 type BalanceRepository interface {
-	GetClientBalance(clientID uint) (float64, error)
-	GetCompanyBalance(companyID uint) (float64, error)
-	UpdateClientBalance(clientID uint, amount float64) error
-	UpdateCompanyBalance(companyID uint, amount float64) error
+	// GetClientBalance и GetCompanyBalance возвращают баланс в валюте счета
+	GetClientBalance(clientID uint) (money.Money, error)
+	GetCompanyBalance(companyID uint) (money.Money, error)
+	UpdateClientBalance(clientID uint, amount money.Minor) error
+	UpdateCompanyBalance(companyID uint, amount money.Minor) error
 	CreateTransaction(transaction *database.BalanceTransaction) error
-	GetTransactionsByUser(userID uint, userType string, limit, offset int) ([]database.BalanceTransaction, error)
+	GetTransactionsByUser(userID uint, userType string, page pagination.Request) ([]database.BalanceTransaction, pagination.Page, error)
 	GetTransactionCountByUser(userID uint, userType string) (int, error)
+	GetTransactionByID(id uint) (*database.BalanceTransaction, error)
+	// GetPayoutPeriodTransactions возвращает транзакции компании между предыдущим выводом
+	// средств и выводом withdrawal
+	GetPayoutPeriodTransactions(withdrawal *database.BalanceTransaction) ([]database.BalanceTransaction, error)
+	// ChangeClientCurrency меняет валюту счета клиента с нулевым балансом без оплаченных незавершенных заказов
+	ChangeClientCurrency(clientID uint, currency string) error
+	// ChangeCompanyCurrency меняет валюту счета компании с нулевым балансом без незавершенных заказов
+	// и пересчитывает цены ее карточек по курсу rate
+	ChangeCompanyCurrency(companyID uint, currency string, rate float64) error
 	
 	// Методы для работы с транзакциями
-	UpdateClientBalanceInTx(tx *gorm.DB, clientID uint, amount float64) error
-	UpdateCompanyBalanceInTx(tx *gorm.DB, companyID uint, amount float64) error
+	UpdateClientBalanceInTx(tx *gorm.DB, clientID uint, amount money.Minor) error
+	// DebitClientInTx списывает amount, только если на балансе клиента достаточно средств
+	DebitClientInTx(tx *gorm.DB, clientID uint, amount money.Minor) error
+	UpdateCompanyBalanceInTx(tx *gorm.DB, companyID uint, amount money.Minor) error
 	CreateTransactionInTx(tx *gorm.DB, transaction *database.BalanceTransaction) error
 }
 
@@ -24,30 +39,30 @@ type balanceRepository struct {
 	db *gorm.DB
 }
 
-func (r *balanceRepository) GetClientBalance(clientID uint) (float64, error) {
+func (r *balanceRepository) GetClientBalance(clientID uint) (money.Money, error) {
 	var client database.ClientDB
-	err := r.db.Select("balance").First(&client, clientID).Error
+	err := r.db.Select("balance", "currency").First(&client, clientID).Error
 	if err != nil {
-		return 0, err
+		return money.Money{}, err
 	}
-	return client.Balance, nil
+	return money.New(client.Balance, client.Currency), nil
 }
 
-func (r *balanceRepository) GetCompanyBalance(companyID uint) (float64, error) {
+func (r *balanceRepository) GetCompanyBalance(companyID uint) (money.Money, error) {
 	var company database.CompanyDB
-	err := r.db.Select("balance").First(&company, companyID).Error
+	err := r.db.Select("balance", "currency").First(&company, companyID).Error
 	if err != nil {
-		return 0, err
+		return money.Money{}, err
 	}
-	return company.Balance, nil
+	return money.New(company.Balance, company.Currency), nil
 }
 
-func (r *balanceRepository) UpdateClientBalance(clientID uint, amount float64) error {
+func (r *balanceRepository) UpdateClientBalance(clientID uint, amount money.Minor) error {
 	return r.db.Model(&database.ClientDB{}).Where("id = ?", clientID).
 		Update("balance", gorm.Expr("balance + ?", amount)).Error
 }
 
-func (r *balanceRepository) UpdateCompanyBalance(companyID uint, amount float64) error {
+func (r *balanceRepository) UpdateCompanyBalance(companyID uint, amount money.Minor) error {
 	return r.db.Model(&database.CompanyDB{}).Where("id = ?", companyID).
 		Update("balance", gorm.Expr("balance + ?", amount)).Error
 }
@@ -56,11 +71,15 @@ func (r *balanceRepository) CreateTransaction(transaction *database.BalanceTrans
 	return r.db.Create(transaction).Error
 }
 
-func (r *balanceRepository) GetTransactionsByUser(userID uint, userType string, limit, offset int) ([]database.BalanceTransaction, error) {
+func (r *balanceRepository) GetTransactionsByUser(userID uint, userType string, page pagination.Request) ([]database.BalanceTransaction, pagination.Page, error) {
 	var transactions []database.BalanceTransaction
-	err := r.db.Where("user_id = ? AND user_type = ?", userID, userType).
-		Limit(limit).Offset(offset).Order("created_at DESC").Find(&transactions).Error
-	return transactions, err
+	query := r.db.Where("user_id = ? AND user_type = ?", userID, userType)
+	err := pagination.Apply(query, page).Find(&transactions).Error
+	if err != nil {
+		return nil, pagination.Page{}, err
+	}
+	transactions, next := pagination.Trim(transactions, page, transactionCursor)
+	return transactions, next, nil
 }
 
 func (r *balanceRepository) GetTransactionCountByUser(userID uint, userType string) (int, error) {
@@ -70,13 +89,81 @@ func (r *balanceRepository) GetTransactionCountByUser(userID uint, userType stri
 	return int(count), err
 }
 
+func (r *balanceRepository) GetTransactionByID(id uint) (*database.BalanceTransaction, error) {
+	var transaction database.BalanceTransaction
+	err := r.db.First(&transaction, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &transaction, nil
+}
+
+func (r *balanceRepository) GetPayoutPeriodTransactions(withdrawal *database.BalanceTransaction) ([]database.BalanceTransaction, error) {
+	var transactions []database.BalanceTransaction
+	previous := r.db.Model(&database.BalanceTransaction{}).Select("COALESCE(MAX(id), 0)").
+		Where("user_id = ? AND user_type = ? AND type = 'withdrawal' AND id < ?", withdrawal.UserID, withdrawal.UserType, withdrawal.ID)
+	err := r.db.Where("user_id = ? AND user_type = ? AND type <> 'withdrawal'", withdrawal.UserID, withdrawal.UserType).
+		Where("id < ? AND id > (?)", withdrawal.ID, previous).
+		Order("id").Find(&transactions).Error
+	return transactions, err
+}
+
+func (r *balanceRepository) ChangeClientCurrency(clientID uint, currency string) error {
+	openOrders := r.db.Model(&database.Order{}).Select("1").
+		Where("client_id = ? AND payment_status = 'paid' AND status NOT IN ?", clientID, []string{"finished", "cancelled"})
+	result := r.db.Model(&database.ClientDB{}).
+		Where("id = ? AND balance = 0 AND NOT EXISTS (?)", clientID, openOrders).
+		Update("currency", currency)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("currency can be changed only with zero balance and no paid orders in progress")
+	}
+	return nil
+}
+
+func (r *balanceRepository) ChangeCompanyCurrency(companyID uint, currency string, rate float64) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		openOrders := tx.Model(&database.Order{}).Select("1").
+			Where("company_id = ? AND status NOT IN ?", companyID, []string{"finished", "cancelled"})
+		result := tx.Model(&database.CompanyDB{}).
+			Where("id = ? AND balance = 0 AND NOT EXISTS (?)", companyID, openOrders).
+			Update("currency", currency)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("currency can be changed only with zero balance and no orders in progress")
+		}
+
+		return tx.Model(&database.Card{}).Unscoped().Where("company_id = ?", companyID).
+			Updates(map[string]interface{}{
+				"price":    gorm.Expr("ROUND(price * ?)::bigint", rate),
+				"currency": currency,
+			}).Error
+	})
+}
+
 // Методы для работы с транзакциями
-func (r *balanceRepository) UpdateClientBalanceInTx(tx *gorm.DB, clientID uint, amount float64) error {
+func (r *balanceRepository) UpdateClientBalanceInTx(tx *gorm.DB, clientID uint, amount money.Minor) error {
 	return tx.Model(&database.ClientDB{}).Where("id = ?", clientID).
 		Update("balance", gorm.Expr("balance + ?", amount)).Error
 }
 
-func (r *balanceRepository) UpdateCompanyBalanceInTx(tx *gorm.DB, companyID uint, amount float64) error {
+func (r *balanceRepository) DebitClientInTx(tx *gorm.DB, clientID uint, amount money.Minor) error {
+	result := tx.Model(&database.ClientDB{}).Where("id = ? AND balance >= ?", clientID, amount).
+		Update("balance", gorm.Expr("balance - ?", amount))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("insufficient balance")
+	}
+	return nil
+}
+
+func (r *balanceRepository) UpdateCompanyBalanceInTx(tx *gorm.DB, companyID uint, amount money.Minor) error {
 	return tx.Model(&database.CompanyDB{}).Where("id = ?", companyID).
 		Update("balance", gorm.Expr("balance + ?", amount)).Error
 }
@@ -85,6 +172,10 @@ func (r *balanceRepository) CreateTransactionInTx(tx *gorm.DB, transaction *data
 	return tx.Create(transaction).Error
 }
 
+func transactionCursor(transaction database.BalanceTransaction) pagination.Cursor {
+	return pagination.Cursor{CreatedAt: transaction.CreatedAt, ID: transaction.ID}
+}
+
 func NewBalanceRepository(db *gorm.DB) BalanceRepository {
 	return &balanceRepository{db: db}
 }
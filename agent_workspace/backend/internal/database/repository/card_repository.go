@@ -2,25 +2,103 @@ package repository
 
 import (
 	"core/internal/database"
+	"core/internal/money"
+	"core/internal/pagination"
 	"errors"
 	"fmt"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CardSearchFilter параметры объединенного поиска карточек
+type CardSearchFilter struct {
+	Query        string
+	Category     string   // Слаг или название, сервис раскрывает его в CategoryIDs
+	CategoryIDs  []uint   // Категория вместе с подкатегориями
+	MinPrice     *float64 // В основных единицах валюты карточки
+	MaxPrice     *float64
+	Location     string
+	MinRating    *float64
+	VerifiedOnly bool
+	Latitude     *float64 // Точка клиента для поиска рядом
+	Longitude    *float64
+	RadiusKm     float64 // Радиус для карточек без собственной зоны обслуживания
+	Sort         string  // relevance, price_asc, price_desc, rating, newest, distance
+	Limit        int
+	Offset       int
+}
+
+// CardFacetCount количество карточек для одного значения фасета
+type CardFacetCount struct {
+	Value string
+	Count int64
+}
+
+// CardSearchFacets счетчики для фильтров поиска. Каждый фасет считается без
+// собственного фильтра, чтобы было видно, сколько карточек даст другое значение
+type CardSearchFacets struct {
+	Categories  []CardFacetCount
+	PriceRanges []CardFacetCount
+	Ratings     []CardFacetCount
+	Verified    int64
+}
+
+// Диапазоны цен для фасета
+var cardPriceRanges = []struct {
+	Label string
+	Min   float64
+	Max   float64 // 0 - без верхней границы
+}{
+	{"0-1000", 0, 1000},
+	{"1000-3000", 1000, 3000},
+	{"3000-10000", 3000, 10000},
+	{"10000+", 10000, 0},
+}
+
+// Пороги рейтинга для фасета
+var cardRatingThresholds = []float64{3, 4, 4.5}
+
+const (
+	facetCategory = "category"
+	facetPrice    = "price"
+	facetRating   = "rating"
+	facetVerified = "verified"
+
+	cardTextQuery = "(websearch_to_tsquery('russian', @query) || websearch_to_tsquery('english', @query))"
+
+	// Координаты и зона карточки с откатом на данные компании
+	cardLatitude   = "COALESCE(cards.latitude, company_dbs.latitude)"
+	cardLongitude  = "COALESCE(cards.longitude, company_dbs.longitude)"
+	cardAreaRadius = "COALESCE(cards.service_radius_km, company_dbs.service_radius_km)"
+	cardArea       = "COALESCE(cards.service_area, company_dbs.service_area)"
+
+	// Расстояние по формуле гаверсинусов на чистом SQL, если PostGIS не установлен
+	haversineDistance = "6371 * 2 * ASIN(LEAST(1, SQRT(" +
+		"POWER(SIN(RADIANS(" + cardLatitude + " - @lat) / 2), 2) + " +
+		"COS(RADIANS(@lat)) * COS(RADIANS(" + cardLatitude + ")) * " +
+		"POWER(SIN(RADIANS(" + cardLongitude + " - @lon) / 2), 2))))"
+	postgisDistance = "ST_DistanceSphere(ST_MakePoint(" + cardLongitude + ", " + cardLatitude + "), ST_MakePoint(@lon, @lat)) / 1000"
 )
 
 type CardRepository interface {
 	Create(card *database.Card) error
 	GetByID(id uint) (*database.Card, error)
-	GetByCompanyID(companyID uint, limit, offset int) ([]database.Card, error)
-	GetAll(limit, offset int) ([]database.Card, error)
-	GetByCategory(category string, limit, offset int) ([]database.Card, error)
+	GetByIDs(ids []uint) ([]database.Card, error)
+	GetByCompanyID(companyID uint, page pagination.Request) ([]database.Card, pagination.Page, error)
+	GetAll(page pagination.Request) ([]database.Card, pagination.Page, error)
+	GetByCategoryIDs(categoryIDs []uint, page pagination.Request) ([]database.Card, pagination.Page, error)
 	Update(card *database.Card) error
 	Delete(id uint) error
-	SearchByTitle(query string, limit, offset int) ([]database.Card, error)
+	SearchByTitle(query string, page pagination.Request) ([]database.Card, pagination.Page, error)
 	GetByPriceRange(minPrice, maxPrice float64, limit, offset int) ([]database.Card, error)
+	Search(filter CardSearchFilter) ([]database.Card, int64, error)
+	SearchFacets(filter CardSearchFilter) (*CardSearchFacets, error)
+	MatchesSearch(cardID uint, filter CardSearchFilter) (bool, error)
 }
 
 type cardRepository struct {
-	db *gorm.DB
+	db      *gorm.DB
+	postgis bool
 }
 
 func (r *cardRepository) Create(card *database.Card) error {
@@ -39,25 +117,48 @@ func (r *cardRepository) GetByID(id uint) (*database.Card, error) {
 	return &card, nil
 }
 
-func (r *cardRepository) GetByCompanyID(companyID uint, limit, offset int) ([]database.Card, error) {
+// GetByIDs возвращает активные карточки из списка в порядке убывания даты создания
+func (r *cardRepository) GetByIDs(ids []uint) ([]database.Card, error) {
 	var cards []database.Card
-	err := r.db.Preload("Company").Where("company_id = ? AND is_active = true", companyID).
-		Limit(limit).Offset(offset).Order("created_at DESC").Find(&cards).Error
+	if len(ids) == 0 {
+		return cards, nil
+	}
+	err := r.db.Preload("Company").Where("id IN ? AND is_active = true", ids).
+		Order("created_at DESC").Find(&cards).Error
 	return cards, err
 }
 
-func (r *cardRepository) GetAll(limit, offset int) ([]database.Card, error) {
+func (r *cardRepository) GetByCompanyID(companyID uint, page pagination.Request) ([]database.Card, pagination.Page, error) {
 	var cards []database.Card
-	err := r.db.Preload("Company").Where("is_active = true").
-		Limit(limit).Offset(offset).Order("created_at DESC").Find(&cards).Error
-	return cards, err
+	query := r.db.Preload("Company").Where("company_id = ? AND is_active = true", companyID)
+	err := pagination.Apply(query, page).Find(&cards).Error
+	if err != nil {
+		return nil, pagination.Page{}, err
+	}
+	cards, next := pagination.Trim(cards, page, cardCursor)
+	return cards, next, nil
 }
 
-func (r *cardRepository) GetByCategory(category string, limit, offset int) ([]database.Card, error) {
+func (r *cardRepository) GetAll(page pagination.Request) ([]database.Card, pagination.Page, error) {
 	var cards []database.Card
-	err := r.db.Preload("Company").Where("category = ? AND is_active = true", category).
-		Limit(limit).Offset(offset).Order("created_at DESC").Find(&cards).Error
-	return cards, err
+	query := r.db.Preload("Company").Where("is_active = true")
+	err := pagination.Apply(query, page).Find(&cards).Error
+	if err != nil {
+		return nil, pagination.Page{}, err
+	}
+	cards, next := pagination.Trim(cards, page, cardCursor)
+	return cards, next, nil
+}
+
+func (r *cardRepository) GetByCategoryIDs(categoryIDs []uint, page pagination.Request) ([]database.Card, pagination.Page, error) {
+	var cards []database.Card
+	query := r.db.Preload("Company").Where("category_id IN ? AND is_active = true", categoryIDs)
+	err := pagination.Apply(query, page).Find(&cards).Error
+	if err != nil {
+		return nil, pagination.Page{}, err
+	}
+	cards, next := pagination.Trim(cards, page, cardCursor)
+	return cards, next, nil
 }
 
 func (r *cardRepository) Update(card *database.Card) error {
@@ -68,22 +169,207 @@ func (r *cardRepository) Delete(id uint) error {
 	return r.db.Model(&database.Card{}).Where("id = ?", id).Update("is_active", false).Error
 }
 
-func (r *cardRepository) SearchByTitle(query string, limit, offset int) ([]database.Card, error) {
+func (r *cardRepository) SearchByTitle(query string, page pagination.Request) ([]database.Card, pagination.Page, error) {
 	var cards []database.Card
 	searchQuery := "%" + query + "%"
-	err := r.db.Preload("Company").
-		Where("(title ILIKE ? OR description ILIKE ?) AND is_active = true", searchQuery, searchQuery).
-		Limit(limit).Offset(offset).Order("created_at DESC").Find(&cards).Error
-	return cards, err
+	titleQuery := r.db.Preload("Company").
+		Where("(title ILIKE ? OR description ILIKE ?) AND is_active = true", searchQuery, searchQuery)
+	err := pagination.Apply(titleQuery, page).Find(&cards).Error
+	if err != nil {
+		return nil, pagination.Page{}, err
+	}
+	cards, next := pagination.Trim(cards, page, cardCursor)
+	return cards, next, nil
 }
 
 func (r *cardRepository) GetByPriceRange(minPrice, maxPrice float64, limit, offset int) ([]database.Card, error) {
 	var cards []database.Card
-	err := r.db.Preload("Company").Where("price BETWEEN ? AND ? AND is_active = true", minPrice, maxPrice).
+	err := r.db.Preload("Company").Where("price BETWEEN ? AND ? AND is_active = true", money.FromMajor(minPrice), money.FromMajor(maxPrice)).
 		Limit(limit).Offset(offset).Order("price ASC").Find(&cards).Error
 	return cards, err
 }
 
+func (r *cardRepository) Search(filter CardSearchFilter) ([]database.Card, int64, error) {
+	var total int64
+	if err := r.searchQuery(filter, "").Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var cards []database.Card
+	query := r.searchQuery(filter, "").Preload("Company")
+	if filter.Latitude != nil && filter.Longitude != nil {
+		query = query.Select("cards.*, "+r.distanceSQL()+" AS distance_km", geoVars(filter))
+	} else {
+		query = query.Select("cards.*")
+	}
+	for _, order := range r.searchOrder(filter) {
+		query = query.Order(order)
+	}
+	err := query.Limit(filter.Limit).Offset(filter.Offset).Find(&cards).Error
+	return cards, total, err
+}
+
+// MatchesSearch проверяет, попадает ли карточка в выдачу поиска с указанными фильтрами
+func (r *cardRepository) MatchesSearch(cardID uint, filter CardSearchFilter) (bool, error) {
+	var count int64
+	err := r.searchQuery(filter, "").Where("cards.id = ?", cardID).Count(&count).Error
+	return count > 0, err
+}
+
+func (r *cardRepository) SearchFacets(filter CardSearchFilter) (*CardSearchFacets, error) {
+	facets := &CardSearchFacets{}
+
+	err := r.searchQuery(filter, facetCategory).
+		Joins("JOIN categories ON categories.id = cards.category_id AND categories.deleted_at IS NULL").
+		Select("categories.slug AS value, COUNT(*) AS count").
+		Group("categories.slug").Order("count DESC, value ASC").
+		Scan(&facets.Categories).Error
+	if err != nil {
+		return nil, err
+	}
+
+	priceCase := "CASE"
+	var priceVars []interface{}
+	for _, priceRange := range cardPriceRanges {
+		if priceRange.Max > 0 {
+			priceCase += " WHEN cards.price < ? THEN ?"
+			priceVars = append(priceVars, money.FromMajor(priceRange.Max), priceRange.Label)
+		} else {
+			priceCase += " ELSE ?"
+			priceVars = append(priceVars, priceRange.Label)
+		}
+	}
+	priceCase += " END"
+	var priceCounts []CardFacetCount
+	err = r.searchQuery(filter, facetPrice).
+		Select(priceCase+" AS value, COUNT(*) AS count", priceVars...).
+		Group("value").Scan(&priceCounts).Error
+	if err != nil {
+		return nil, err
+	}
+	// Отдаем все диапазоны в фиксированном порядке, включая пустые
+	for _, priceRange := range cardPriceRanges {
+		facet := CardFacetCount{Value: priceRange.Label}
+		for _, count := range priceCounts {
+			if count.Value == priceRange.Label {
+				facet.Count = count.Count
+			}
+		}
+		facets.PriceRanges = append(facets.PriceRanges, facet)
+	}
+
+	for _, threshold := range cardRatingThresholds {
+		var count int64
+		err = r.searchQuery(filter, facetRating).Where("company_dbs.stars >= ?", threshold).Count(&count).Error
+		if err != nil {
+			return nil, err
+		}
+		facets.Ratings = append(facets.Ratings, CardFacetCount{Value: fmt.Sprintf("%g+", threshold), Count: count})
+	}
+
+	err = r.searchQuery(filter, facetVerified).Where("company_dbs.is_verified = true").Count(&facets.Verified).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return facets, nil
+}
+
+// searchQuery собирает запрос с фильтрами поиска. skip отключает один из фильтров
+// при подсчете соответствующего фасета
+func (r *cardRepository) searchQuery(filter CardSearchFilter, skip string) *gorm.DB {
+	query := r.db.Model(&database.Card{}).
+		Joins("JOIN company_dbs ON company_dbs.id = cards.company_id AND company_dbs.deleted_at IS NULL").
+		Where("cards.is_active = true")
+
+	if filter.Query != "" {
+		// Опечатки в названии ловим через триграммы: похожие по названию карточки находятся
+		// вместе с полнотекстовыми совпадениями, а сортировка по релевантности ставит точные выше
+		query = query.Where("(cards.search_vector @@ "+cardTextQuery+" OR @query <% cards.title)",
+			map[string]interface{}{"query": filter.Query})
+	}
+	if len(filter.CategoryIDs) > 0 && skip != facetCategory {
+		query = query.Where("cards.category_id IN ?", filter.CategoryIDs)
+	}
+	if skip != facetPrice {
+		if filter.MinPrice != nil {
+			query = query.Where("cards.price >= ?", money.FromMajor(*filter.MinPrice))
+		}
+		if filter.MaxPrice != nil {
+			query = query.Where("cards.price <= ?", money.FromMajor(*filter.MaxPrice))
+		}
+	}
+	if filter.Location != "" {
+		query = query.Where("cards.location ILIKE ?", "%"+filter.Location+"%")
+	}
+	if filter.MinRating != nil && skip != facetRating {
+		query = query.Where("company_dbs.stars >= ?", *filter.MinRating)
+	}
+	if filter.VerifiedOnly && skip != facetVerified {
+		query = query.Where("company_dbs.is_verified = true")
+	}
+	if filter.Latitude != nil && filter.Longitude != nil {
+		// Точка клиента должна попасть в многоугольник или радиус обслуживания.
+		// Карточки без зоны ищем в радиусе из запроса
+		distance := r.distanceSQL()
+		query = query.Where("CASE"+
+			" WHEN "+cardArea+" IS NOT NULL THEN CAST("+cardArea+" AS polygon) @> point(@lon, @lat)"+
+			" WHEN "+cardAreaRadius+" IS NOT NULL THEN "+distance+" <= "+cardAreaRadius+
+			" ELSE "+distance+" <= @radius END", geoVars(filter))
+	}
+	return query
+}
+
+func (r *cardRepository) distanceSQL() string {
+	if r.postgis {
+		return postgisDistance
+	}
+	return haversineDistance
+}
+
+func geoVars(filter CardSearchFilter) map[string]interface{} {
+	return map[string]interface{}{
+		"lat":    *filter.Latitude,
+		"lon":    *filter.Longitude,
+		"radius": filter.RadiusKm,
+	}
+}
+
+func (r *cardRepository) searchOrder(filter CardSearchFilter) []interface{} {
+	switch filter.Sort {
+	case "price_asc":
+		return []interface{}{"cards.price ASC", "cards.id DESC"}
+	case "price_desc":
+		return []interface{}{"cards.price DESC", "cards.id DESC"}
+	case "rating":
+		return []interface{}{"company_dbs.ranking_score DESC", "company_dbs.review_count DESC", "cards.id DESC"}
+	case "newest":
+		return []interface{}{"cards.created_at DESC", "cards.id DESC"}
+	case "distance":
+		if filter.Latitude != nil && filter.Longitude != nil {
+			return []interface{}{clause.OrderBy{Expression: clause.NamedExpr{
+				SQL:  r.distanceSQL() + " ASC NULLS LAST, cards.id DESC",
+				Vars: []interface{}{geoVars(filter)},
+			}}}
+		}
+	}
+
+	if filter.Query == "" {
+		return []interface{}{"cards.created_at DESC", "cards.id DESC"}
+	}
+	// По релевантности: полнотекстовый ранг плюс похожесть названия на запрос
+	return []interface{}{
+		clause.OrderBy{Expression: clause.NamedExpr{
+			SQL:  "ts_rank_cd(cards.search_vector, " + cardTextQuery + ") + word_similarity(@query, cards.title) DESC, cards.id DESC",
+			Vars: []interface{}{map[string]interface{}{"query": filter.Query}},
+		}},
+	}
+}
+
+func cardCursor(card database.Card) pagination.Cursor {
+	return pagination.Cursor{CreatedAt: card.CreatedAt, ID: card.ID}
+}
+
 func NewCardRepository(db *gorm.DB) CardRepository {
-	return &cardRepository{db: db}
+	return &cardRepository{db: db, postgis: database.HasPostGIS(db)}
 }
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"core/internal/database"
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CartRepository interface {
+	// GetItems возвращает корзину клиента, сгруппированную по компаниям в порядке добавления
+	GetItems(clientID uint) ([]database.CartItem, error)
+	GetItem(clientID, cardID uint) (*database.CartItem, error)
+	// SaveItem добавляет карточку в корзину или меняет ее количество
+	SaveItem(clientID, cardID uint, quantity int) error
+	RemoveItem(clientID, cardID uint) error
+	// Clear удаляет из корзины карточки cardIDs, без них очищает корзину полностью
+	Clear(clientID uint, cardIDs []uint) error
+	// TakeItemsInTx удаляет из корзины оформляемые позиции. Если позиции уже удалены
+	// параллельным оформлением или корзина изменилась, возвращает ошибку
+	TakeItemsInTx(tx *gorm.DB, clientID uint, items []database.CartItem) error
+}
+
+type cartRepository struct {
+	db *gorm.DB
+}
+
+func (r *cartRepository) GetItems(clientID uint) ([]database.CartItem, error) {
+	var items []database.CartItem
+	err := r.db.Preload("Card").Preload("Card.Company").
+		Joins("JOIN cards ON cards.id = cart_items.card_id").
+		Where("cart_items.client_id = ?", clientID).
+		Order("cards.company_id, cart_items.id").Find(&items).Error
+	return items, err
+}
+
+func (r *cartRepository) GetItem(clientID, cardID uint) (*database.CartItem, error) {
+	var item database.CartItem
+	err := r.db.Where("client_id = ? AND card_id = ?", clientID, cardID).First(&item).Error
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+func (r *cartRepository) SaveItem(clientID, cardID uint, quantity int) error {
+	item := &database.CartItem{ClientID: clientID, CardID: cardID, Quantity: quantity}
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "client_id"}, {Name: "card_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"quantity": quantity, "updated_at": gorm.Expr("NOW()")}),
+	}).Create(item).Error
+}
+
+// Строки корзины удаляются физически, иначе уникальный индекс не даст добавить карточку снова
+func (r *cartRepository) RemoveItem(clientID, cardID uint) error {
+	return r.db.Unscoped().Where("client_id = ? AND card_id = ?", clientID, cardID).
+		Delete(&database.CartItem{}).Error
+}
+
+func (r *cartRepository) Clear(clientID uint, cardIDs []uint) error {
+	query := r.db.Unscoped().Where("client_id = ?", clientID)
+	if len(cardIDs) > 0 {
+		query = query.Where("card_id IN ?", cardIDs)
+	}
+	return query.Delete(&database.CartItem{}).Error
+}
+
+func (r *cartRepository) TakeItemsInTx(tx *gorm.DB, clientID uint, items []database.CartItem) error {
+	cardIDs := make([]uint, 0, len(items))
+	quantities := make(map[uint]int, len(items))
+	for _, item := range items {
+		cardIDs = append(cardIDs, item.CardID)
+		quantities[item.CardID] = item.Quantity
+	}
+
+	var deleted []database.CartItem
+	err := tx.Unscoped().Clauses(clause.Returning{}).
+		Where("client_id = ? AND card_id IN ?", clientID, cardIDs).
+		Delete(&deleted).Error
+	if err != nil {
+		return err
+	}
+	if len(deleted) != len(items) {
+		return errors.New("cart has already been checked out")
+	}
+	for _, item := range deleted {
+		if quantities[item.CardID] != item.Quantity {
+			return errors.New("cart has changed, please review it and try again")
+		}
+	}
+	return nil
+}
+
+func NewCartRepository(db *gorm.DB) CartRepository {
+	return &cartRepository{db: db}
+}
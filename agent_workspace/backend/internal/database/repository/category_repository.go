@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"core/internal/database"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+)
+
+// CategoryCardCount количество активных карточек, привязанных напрямую к категории
+type CategoryCardCount struct {
+	CategoryID uint
+	Count      int64
+}
+
+type CategoryRepository interface {
+	Create(category *database.Category) error
+	GetByID(id uint) (*database.Category, error)
+	GetBySlug(slug string) (*database.Category, error)
+	GetAll() ([]database.Category, error)
+	Update(category *database.Category) error
+	Delete(id uint) error
+	FindByName(name string) (*database.Category, error)
+	CountCards() ([]CategoryCardCount, error)
+}
+
+type categoryRepository struct {
+	db *gorm.DB
+}
+
+func (r *categoryRepository) Create(category *database.Category) error {
+	return r.db.Create(category).Error
+}
+
+func (r *categoryRepository) GetByID(id uint) (*database.Category, error) {
+	var category database.Category
+	err := r.db.First(&category, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("category with ID %d not found", id)
+		}
+		return nil, err
+	}
+	return &category, nil
+}
+
+func (r *categoryRepository) GetBySlug(slug string) (*database.Category, error) {
+	var category database.Category
+	err := r.db.Where("slug = ?", slug).First(&category).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("category %q not found", slug)
+		}
+		return nil, err
+	}
+	return &category, nil
+}
+
+func (r *categoryRepository) GetAll() ([]database.Category, error) {
+	var categories []database.Category
+	err := r.db.Order("sort_order ASC, id ASC").Find(&categories).Error
+	return categories, err
+}
+
+func (r *categoryRepository) Update(category *database.Category) error {
+	return r.db.Save(category).Error
+}
+
+// Delete удаляет категорию, если у нее нет подкатегорий и карточек
+func (r *categoryRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var children int64
+		if err := tx.Model(&database.Category{}).Where("parent_id = ?", id).Count(&children).Error; err != nil {
+			return err
+		}
+		if children > 0 {
+			return errors.New("category has subcategories")
+		}
+
+		var cards int64
+		if err := tx.Model(&database.Card{}).Where("category_id = ?", id).Count(&cards).Error; err != nil {
+			return err
+		}
+		if cards > 0 {
+			return errors.New("category has cards")
+		}
+
+		// Удаляем полностью, чтобы слаг можно было использовать снова
+		return tx.Unscoped().Delete(&database.Category{}, id).Error
+	})
+}
+
+func (r *categoryRepository) FindByName(name string) (*database.Category, error) {
+	category, err := database.FindCategoryByName(r.db, name)
+	if err != nil {
+		return nil, err
+	}
+	if category == nil {
+		return nil, fmt.Errorf("category %q not found", name)
+	}
+	return category, nil
+}
+
+func (r *categoryRepository) CountCards() ([]CategoryCardCount, error) {
+	var counts []CategoryCardCount
+	err := r.db.Model(&database.Card{}).
+		Select("category_id, COUNT(*) AS count").
+		Where("category_id IS NOT NULL AND is_active = true").
+		Group("category_id").Scan(&counts).Error
+	return counts, err
+}
+
+func NewCategoryRepository(db *gorm.DB) CategoryRepository {
+	return &categoryRepository{db: db}
+}
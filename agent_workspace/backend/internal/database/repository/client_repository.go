@@ -56,9 +56,11 @@ func (repository *clientRepository) CheckPassword(email string, password string)
 		if err := security.CheckPassword(password, dbUser.PasswordHash); err == nil {
 			return dbUser, nil
 		}
-		return database.ClientDB{}, errors.New("bad password")
+		return database.ClientDB{}, errors.New("invalid email or password")
 	}
-	return database.ClientDB{}, errors.New("user not found")
+	// Ответ и время проверки не должны зависеть от того, есть ли такой пользователь
+	security.SimulatePasswordCheck(password)
+	return database.ClientDB{}, errors.New("invalid email or password")
 }
 
 func (repository *clientRepository) Update(client *database.ClientDB) error {
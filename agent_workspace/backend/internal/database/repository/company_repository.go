@@ -3,10 +3,13 @@ package repository
 import (
 	"core/internal/api"
 	"core/internal/database"
+	"core/internal/money"
+	"core/internal/pagination"
 	"core/internal/security"
 	"errors"
 	"fmt"
 	"gorm.io/gorm"
+	"time"
 )
 
 type CompanyRepository interface {
@@ -19,6 +22,14 @@ type CompanyRepository interface {
 	DeleteCard(id int) bool
 	Update(company *database.CompanyDB) error
 	GetCompanyStats(companyID uint) (*api.CompanyStats, error)
+	CountCompletedOrders(companyID uint) (int64, error)
+	UpdateTier(companyID uint, tier string) error
+	// UpdatePayoutDetails сохраняет реквизиты для вывода средств
+	UpdatePayoutDetails(companyID uint, updates map[string]interface{}) error
+	// GetVerificationQueue возвращает непроверенные компании, загрузившие документы
+	GetVerificationQueue(page pagination.Request) ([]database.CompanyDB, pagination.Page, error)
+	// SetVerified отмечает документы компании проверенными или снимает отметку
+	SetVerified(companyID uint, verified bool, adminID uint) error
 }
 
 type companyRepository struct {
@@ -26,87 +37,129 @@ type companyRepository struct {
 }
 
 func (r *companyRepository) GetCompanyStats(companyID uint) (*api.CompanyStats, error) {
-	var (
-		totalOrders      int64
-		activeOrders     int64
-		completedOrders  int64
-		totalRevenue     float64
-		averageRating    float64
-		reviewCount      int64
-		totalServices    int64
-		balanceAvailable float64
-	)
-
-	// Подсчет общего количества заказов
-	if err := r.db.Model(&database.Order{}).
-		Where("company_id = ?", companyID).
-		Count(&totalOrders).Error; err != nil {
-		return nil, err
-	}
-
-	// Подсчет активных заказов
-	if err := r.db.Model(&database.Order{}).
-		Where("company_id = ? AND status IN ?", companyID, []string{"created", "paid", "in_progress"}).
-		Count(&activeOrders).Error; err != nil {
-		return nil, err
-	}
-
-	// Подсчет завершенных заказов
-	if err := r.db.Model(&database.Order{}).
-		Where("company_id = ? AND status = ?", companyID, "finished").
-		Count(&completedOrders).Error; err != nil {
-		return nil, err
-	}
-
-	// Подсчет общей выручки по завершенным заказам
-	if err := r.db.Model(&database.Order{}).
-		Where("company_id = ? AND status = ?", companyID, "finished").
-		Select("COALESCE(SUM(amount), 0)").Scan(&totalRevenue).Error; err != nil {
+	// Рейтинг, баланс и валюта хранятся в записи компании. Суммы считаются в валюте счета
+	company, err := r.GetByID(companyID)
+	if err != nil {
 		return nil, err
 	}
 
-	// Средний рейтинг
-	if err := r.db.Model(&database.Review{}).
-		Where("company_id = ?", companyID).
-		Select("COALESCE(AVG(rating), 0)").Scan(&averageRating).Error; err != nil {
+	// Все показатели по заказам считаются одним запросом
+	var orders struct {
+		TotalOrders     int64
+		ActiveOrders    int64
+		CompletedOrders int64
+		TotalRevenue    money.Minor
+	}
+	err = r.db.Model(&database.Order{}).
+		Select(`COUNT(*) AS total_orders,
+			COUNT(*) FILTER (WHERE status IN ('created', 'paid', 'in_progress')) AS active_orders,
+			COUNT(*) FILTER (WHERE status = 'finished') AS completed_orders,
+			COALESCE(SUM(amount) FILTER (WHERE status = 'finished'), 0)::bigint AS total_revenue`).
+		Where("company_id = ? AND currency = ?", companyID, company.Currency).Scan(&orders).Error
+	if err != nil {
 		return nil, err
 	}
 
-	// Количество отзывов
-	if err := r.db.Model(&database.Review{}).
-		Where("company_id = ?", companyID).
-		Count(&reviewCount).Error; err != nil {
+	// Фактически зачисленные компании оплаты за заказы за вычетом комиссий платформы
+	var totalEarnings money.Minor
+	err = r.db.Model(&database.BalanceTransaction{}).
+		Where("user_id = ? AND user_type = ? AND type IN ? AND status = ? AND currency = ? AND order_id IS NOT NULL",
+			companyID, "company", []string{"payment", "fee"}, "completed", company.Currency).
+		Select("COALESCE(SUM(amount), 0)::bigint").Scan(&totalEarnings).Error
+	if err != nil {
 		return nil, err
 	}
 
-	// Количество услуг (cards)
+	var totalServices int64
 	if err := r.db.Model(&database.Card{}).
 		Where("company_id = ?", companyID).
 		Count(&totalServices).Error; err != nil {
 		return nil, err
 	}
 
-	// Баланс компании
-	if err := r.db.Model(&database.CompanyDB{}).
-		Where("id = ?", companyID).
-		Select("COALESCE(balance, 0)").Scan(&balanceAvailable).Error; err != nil {
-		return nil, err
+	averageRating := company.Stars
+	if company.ReviewCount == 0 {
+		averageRating = 0 // У новых компаний stars заполнено значением по умолчанию
 	}
 
 	return &api.CompanyStats{
 		TotalServices:    int(totalServices),
-		TotalOrders:      int(totalOrders),
-		ActiveOrders:     int(activeOrders),
-		CompletedOrders:  int(completedOrders),
-		TotalRevenue:     totalRevenue,
-		TotalEarnings:    totalRevenue, // Если нужно считать TotalEarnings = TotalRevenue
+		TotalOrders:      int(orders.TotalOrders),
+		ActiveOrders:     int(orders.ActiveOrders),
+		CompletedOrders:  int(orders.CompletedOrders),
+		TotalRevenue:     orders.TotalRevenue,
+		TotalEarnings:    totalEarnings,
 		AverageRating:    averageRating,
-		TotalReviews:     int(reviewCount),
-		ReviewCount:      int(reviewCount),
-		BalanceAvailable: balanceAvailable,
+		TotalReviews:     company.ReviewCount,
+		ReviewCount:      company.ReviewCount,
+		BalanceAvailable: company.Balance,
+		Currency:         company.Currency,
 	}, nil
 }
 
+func (r *companyRepository) CountCompletedOrders(companyID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&database.Order{}).
+		Where("company_id = ? AND status = ?", companyID, "finished").
+		Count(&count).Error
+	return count, err
+}
+
+func (r *companyRepository) UpdateTier(companyID uint, tier string) error {
+	result := r.db.Model(&database.CompanyDB{}).Where("id = ?", companyID).Update("tier", tier)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("company not found")
+	}
+	return nil
+}
+
+func (r *companyRepository) UpdatePayoutDetails(companyID uint, updates map[string]interface{}) error {
+	result := r.db.Model(&database.CompanyDB{}).Where("id = ?", companyID).Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("company not found")
+	}
+	return nil
+}
+
+func (r *companyRepository) GetVerificationQueue(page pagination.Request) ([]database.CompanyDB, pagination.Page, error) {
+	var companies []database.CompanyDB
+	query := r.db.Where("is_verified = false AND cardinality(documents) > 0")
+	err := pagination.Apply(query, page).Find(&companies).Error
+	if err != nil {
+		return nil, pagination.Page{}, err
+	}
+	companies, next := pagination.Trim(companies, page, companyCursor)
+	return companies, next, nil
+}
+
+func (r *companyRepository) SetVerified(companyID uint, verified bool, adminID uint) error {
+	updates := map[string]interface{}{"is_verified": false, "verified_at": nil, "verified_by_id": nil}
+	if verified {
+		updates = map[string]interface{}{"is_verified": true, "verified_at": time.Now(), "verified_by_id": adminID}
+	}
+	result := r.db.Model(&database.CompanyDB{}).Where("id = ? AND is_verified = ?", companyID, !verified).Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		if verified {
+			return errors.New("company not found or already verified")
+		}
+		return errors.New("company not found or not verified")
+	}
+	return nil
+}
+
+func companyCursor(company database.CompanyDB) pagination.Cursor {
+	return pagination.Cursor{CreatedAt: company.CreatedAt, ID: company.ID}
+}
+
 func (repository *companyRepository) PreloadDB(name string, company *database.CompanyDB, limit int, page int) {
 	query := repository.db
 
@@ -176,9 +229,11 @@ func (repository *companyRepository) CheckPassword(email string, password string
 		if err := security.CheckPassword(password, dbUser.PasswordHash); err == nil {
 			return dbUser, nil
 		}
-		return database.CompanyDB{}, errors.New("bad password")
+		return database.CompanyDB{}, errors.New("invalid email or password")
 	}
-	return database.CompanyDB{}, errors.New("user not found")
+	// Ответ и время проверки не должны зависеть от того, есть ли такой пользователь
+	security.SimulatePasswordCheck(password)
+	return database.CompanyDB{}, errors.New("invalid email or password")
 }
 
 func (repository *companyRepository) Update(company *database.CompanyDB) error {
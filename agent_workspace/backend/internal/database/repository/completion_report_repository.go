@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"core/internal/database"
+	"errors"
+	"gorm.io/gorm"
+)
+
+type CompletionReportRepository interface {
+	GetByOrderID(orderID uint) (*database.CompletionReport, error)
+	CompleteOrder(order *database.Order, report *database.CompletionReport, linkToken string) error
+}
+
+type completionReportRepository struct {
+	db *gorm.DB
+}
+
+func (r *completionReportRepository) GetByOrderID(orderID uint) (*database.CompletionReport, error) {
+	var report database.CompletionReport
+	err := r.db.Where("order_id = ?", orderID).First(&report).Error
+	if err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// CompleteOrder сохраняет отчет и переводит заказ в статус completed одной транзакцией.
+// Если заказ завершается по ссылке, ссылка помечается использованной там же
+func (r *completionReportRepository) CompleteOrder(order *database.Order, report *database.CompletionReport, linkToken string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(report).Error; err != nil {
+			return err
+		}
+
+		result := tx.Model(&database.Order{}).
+			Where("id = ? AND status = ?", order.ID, "in_progress").
+			Updates(map[string]interface{}{
+				"status":          "completed",
+				"completed_at":    order.CompletedAt,
+				"completed_by_id": order.CompletedByID,
+				"check_out_at":    order.CheckOutAt,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("order cannot be completed in current status")
+		}
+
+		// Ссылку могли отозвать или использовать, пока работник заполнял форму
+		if linkToken != "" {
+			result := tx.Model(&database.WorkerLink{}).
+				Where("token = ? AND is_used = false AND revoked_at IS NULL", linkToken).
+				Update("is_used", true)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return errors.New("worker link is no longer valid")
+			}
+		}
+		return nil
+	})
+}
+
+func NewCompletionReportRepository(db *gorm.DB) CompletionReportRepository {
+	return &completionReportRepository{db: db}
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"core/internal/database"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type DocumentRepository interface {
+	GetByID(id uint) (*database.FinancialDocument, error)
+	GetByOrder(docType string, orderID uint) (*database.FinancialDocument, error)
+	GetByTransaction(docType string, transactionID uint) (*database.FinancialDocument, error)
+	// Create присваивает документу следующий номер с префиксом prefix и сохраняет его.
+	// save получает документ с номером до записи в базу и сохраняет файл; при ошибке
+	// документ не создается и номер не расходуется
+	Create(document *database.FinancialDocument, prefix string, save func(document *database.FinancialDocument) error) error
+}
+
+type documentRepository struct {
+	db *gorm.DB
+}
+
+func (r *documentRepository) GetByID(id uint) (*database.FinancialDocument, error) {
+	var document database.FinancialDocument
+	err := r.db.First(&document, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &document, nil
+}
+
+func (r *documentRepository) GetByOrder(docType string, orderID uint) (*database.FinancialDocument, error) {
+	var document database.FinancialDocument
+	err := r.db.Where("type = ? AND order_id = ?", docType, orderID).First(&document).Error
+	if err != nil {
+		return nil, err
+	}
+	return &document, nil
+}
+
+func (r *documentRepository) GetByTransaction(docType string, transactionID uint) (*database.FinancialDocument, error) {
+	var document database.FinancialDocument
+	err := r.db.Where("type = ? AND transaction_id = ?", docType, transactionID).First(&document).Error
+	if err != nil {
+		return nil, err
+	}
+	return &document, nil
+}
+
+func (r *documentRepository) Create(document *database.FinancialDocument, prefix string, save func(document *database.FinancialDocument) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// Счетчик блокируется до конца транзакции, поэтому номера не повторяются и не пропускаются
+		sequence := &database.DocumentSequence{Type: document.Type, Year: document.IssuedAt.Year(), Value: 1}
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "type"}, {Name: "year"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{"value": gorm.Expr("document_sequences.value + 1")}),
+		}, clause.Returning{Columns: []clause.Column{{Name: "value"}}}).Create(sequence).Error
+		if err != nil {
+			return err
+		}
+
+		document.Number = fmt.Sprintf("%s-%d-%06d", prefix, sequence.Year, sequence.Value)
+		if err := save(document); err != nil {
+			return err
+		}
+		return tx.Create(document).Error
+	})
+}
+
+func NewDocumentRepository(db *gorm.DB) DocumentRepository {
+	return &documentRepository{db: db}
+}
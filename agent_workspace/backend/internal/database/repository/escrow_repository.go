@@ -20,8 +20,16 @@ type EscrowRepository interface {
 type WorkerLinkRepository interface {
 	Create(link *database.WorkerLink) error
 	GetByToken(token string) (*database.WorkerLink, error)
+	FindByToken(token string) (*database.WorkerLink, error)
+	GetByOrderID(orderID uint) ([]database.WorkerLink, error)
 	MarkAsUsed(token string) error
-	GenerateWorkerLink(orderID uint) (*database.WorkerLink, error)
+	Reissue(orderID uint, ttl time.Duration, buildURL func(token string) string) (*database.WorkerLink, error)
+	RevokeByOrderID(orderID uint) (int64, error)
+	LogAccess(access *database.WorkerLinkAccess) error
+	GetAccessLog(orderID uint) ([]database.WorkerLinkAccess, error)
+
+	// Метод для работы с транзакциями
+	GenerateWorkerLinkInTx(tx *gorm.DB, orderID uint, ttl time.Duration) (*database.WorkerLink, error)
 }
 
 type escrowRepository struct {
@@ -52,18 +60,79 @@ func (r *workerLinkRepository) Create(link *database.WorkerLink) error {
 
 func (r *workerLinkRepository) GetByToken(token string) (*database.WorkerLink, error) {
 	var link database.WorkerLink
-	err := r.db.Where("token = ? AND is_used = false AND expires_at > ?", token, time.Now()).First(&link).Error
+	err := r.db.Where("token = ? AND is_used = false AND revoked_at IS NULL AND expires_at > ?", token, time.Now()).
+		First(&link).Error
+	if err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// FindByToken ищет ссылку независимо от ее состояния, чтобы показать причину недоступности
+func (r *workerLinkRepository) FindByToken(token string) (*database.WorkerLink, error) {
+	var link database.WorkerLink
+	err := r.db.Where("token = ?", token).First(&link).Error
 	if err != nil {
 		return nil, err
 	}
 	return &link, nil
 }
 
+func (r *workerLinkRepository) GetByOrderID(orderID uint) ([]database.WorkerLink, error) {
+	var links []database.WorkerLink
+	err := r.db.Where("order_id = ?", orderID).Order("created_at DESC").Find(&links).Error
+	return links, err
+}
+
 func (r *workerLinkRepository) MarkAsUsed(token string) error {
 	return r.db.Model(&database.WorkerLink{}).Where("token = ?", token).Update("is_used", true).Error
 }
 
-func (r *workerLinkRepository) GenerateWorkerLink(orderID uint) (*database.WorkerLink, error) {
+// Reissue отзывает действующие ссылки заказа и выпускает новую одной транзакцией
+func (r *workerLinkRepository) Reissue(orderID uint, ttl time.Duration, buildURL func(token string) string) (*database.WorkerLink, error) {
+	var link *database.WorkerLink
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		link, err = r.GenerateWorkerLinkInTx(tx, orderID, ttl)
+		if err != nil {
+			return err
+		}
+		return tx.Model(&database.Order{}).Where("id = ?", orderID).
+			Update("worker_complete_url", buildURL(link.Token)).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return link, nil
+}
+
+// RevokeByOrderID отзывает все действующие ссылки заказа и убирает ссылку из заказа
+func (r *workerLinkRepository) RevokeByOrderID(orderID uint) (int64, error) {
+	var revoked int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := revokeActiveLinks(tx, orderID)
+		if result.Error != nil {
+			return result.Error
+		}
+		revoked = result.RowsAffected
+		return tx.Model(&database.Order{}).Where("id = ?", orderID).Update("worker_complete_url", "").Error
+	})
+	return revoked, err
+}
+
+func (r *workerLinkRepository) LogAccess(access *database.WorkerLinkAccess) error {
+	return r.db.Create(access).Error
+}
+
+func (r *workerLinkRepository) GetAccessLog(orderID uint) ([]database.WorkerLinkAccess, error) {
+	var accesses []database.WorkerLinkAccess
+	err := r.db.Where("order_id = ?", orderID).Order("created_at DESC").Find(&accesses).Error
+	return accesses, err
+}
+
+// GenerateWorkerLinkInTx создает новую ссылку в переданной транзакции.
+// Ранее выданные ссылки заказа отзываются, чтобы действовала только одна
+func (r *workerLinkRepository) GenerateWorkerLinkInTx(tx *gorm.DB, orderID uint, ttl time.Duration) (*database.WorkerLink, error) {
 	// Генерируем случайный токен
 	bytes := make([]byte, 32)
 	if _, err := rand.Read(bytes); err != nil {
@@ -71,14 +140,18 @@ func (r *workerLinkRepository) GenerateWorkerLink(orderID uint) (*database.Worke
 	}
 	token := hex.EncodeToString(bytes)
 
+	if err := revokeActiveLinks(tx, orderID).Error; err != nil {
+		return nil, err
+	}
+
 	link := &database.WorkerLink{
 		OrderID:   orderID,
 		Token:     token,
 		IsUsed:    false,
-		ExpiresAt: time.Now().Add(7 * 24 * time.Hour), // Действует 7 дней
+		ExpiresAt: time.Now().Add(ttl),
 	}
 
-	err := r.db.Create(link).Error
+	err := tx.Create(link).Error
 	if err != nil {
 		return nil, err
 	}
@@ -86,6 +159,12 @@ func (r *workerLinkRepository) GenerateWorkerLink(orderID uint) (*database.Worke
 	return link, nil
 }
 
+func revokeActiveLinks(tx *gorm.DB, orderID uint) *gorm.DB {
+	return tx.Model(&database.WorkerLink{}).
+		Where("order_id = ? AND is_used = false AND revoked_at IS NULL", orderID).
+		Update("revoked_at", time.Now())
+}
+
 // Метод для работы с транзакциями
 func (r *escrowRepository) CreateTransactionInTx(tx *gorm.DB, transaction *database.EscrowTransaction) error {
 	return tx.Create(transaction).Error
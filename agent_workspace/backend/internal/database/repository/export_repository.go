@@ -0,0 +1,194 @@
+package repository
+
+import (
+	"core/internal/database"
+	"core/internal/money"
+	"gorm.io/gorm"
+	"time"
+)
+
+// ExportFilter условия выгрузки: данные владельца, созданные в [From, To), с нужным статусом.
+// Пустые границы и статус не ограничивают выгрузку
+type ExportFilter struct {
+	UserID   uint
+	UserType string
+	From     *time.Time
+	To       *time.Time
+	Status   string
+}
+
+// OrderExportRow строка выгрузки заказов
+type OrderExportRow struct {
+	ID            uint
+	CreatedAt     time.Time
+	Status        string
+	PaymentStatus string
+	CardID        uint
+	CardTitle     string
+	ClientID      uint
+	CompanyID     uint
+	Amount        money.Minor
+	Currency      string
+	FeeAmount     money.Minor
+	ScheduledAt   *time.Time
+	CompletedAt   *time.Time
+	Description   string
+}
+
+// ExportRepository читает данные для выгрузок построчно, не загружая их в память целиком
+type ExportRepository interface {
+	CountBalanceTransactions(filter ExportFilter) (int64, error)
+	StreamBalanceTransactions(filter ExportFilter, fn func(transaction *database.BalanceTransaction) error) error
+	CountEscrowTransactions(filter ExportFilter) (int64, error)
+	StreamEscrowTransactions(filter ExportFilter, fn func(transaction *database.EscrowTransaction) error) error
+	CountOrders(filter ExportFilter) (int64, error)
+	StreamOrders(filter ExportFilter, fn func(order *OrderExportRow) error) error
+}
+
+type exportRepository struct {
+	db *gorm.DB
+}
+
+func (r *exportRepository) CountBalanceTransactions(filter ExportFilter) (int64, error) {
+	var count int64
+	err := r.balanceQuery(filter).Count(&count).Error
+	return count, err
+}
+
+func (r *exportRepository) StreamBalanceTransactions(filter ExportFilter, fn func(transaction *database.BalanceTransaction) error) error {
+	return streamRows(r.balanceQuery(filter).Order("balance_transactions.id"), fn)
+}
+
+func (r *exportRepository) CountEscrowTransactions(filter ExportFilter) (int64, error) {
+	var count int64
+	err := r.escrowQuery(filter).Count(&count).Error
+	return count, err
+}
+
+func (r *exportRepository) StreamEscrowTransactions(filter ExportFilter, fn func(transaction *database.EscrowTransaction) error) error {
+	return streamRows(r.escrowQuery(filter).Order("escrow_transactions.id"), fn)
+}
+
+func (r *exportRepository) CountOrders(filter ExportFilter) (int64, error) {
+	var count int64
+	err := r.orderQuery(filter).Count(&count).Error
+	return count, err
+}
+
+func (r *exportRepository) StreamOrders(filter ExportFilter, fn func(order *OrderExportRow) error) error {
+	query := r.orderQuery(filter).
+		Select("orders.id, orders.created_at, orders.status, orders.payment_status, orders.card_id, " +
+			"COALESCE(cards.title, '') AS card_title, orders.client_id, orders.company_id, orders.amount, " +
+			"orders.currency, orders.fee_amount, orders.scheduled_at, orders.completed_at, orders.description").
+		Joins("LEFT JOIN cards ON cards.id = orders.card_id").
+		Order("orders.id")
+	return streamRows(query, fn)
+}
+
+func (r *exportRepository) balanceQuery(filter ExportFilter) *gorm.DB {
+	query := r.db.Model(&database.BalanceTransaction{}).
+		Where("balance_transactions.user_id = ? AND balance_transactions.user_type = ?", filter.UserID, filter.UserType)
+	return applyExportFilter(query, "balance_transactions", filter)
+}
+
+// escrowQuery выбирает движения эскроу по заказам клиента или компании
+func (r *exportRepository) escrowQuery(filter ExportFilter) *gorm.DB {
+	orders := r.db.Model(&database.Order{}).Select("id").Where(exportOwnerColumn(filter)+" = ?", filter.UserID)
+	query := r.db.Model(&database.EscrowTransaction{}).Where("escrow_transactions.order_id IN (?)", orders)
+	return applyExportFilter(query, "escrow_transactions", filter)
+}
+
+func (r *exportRepository) orderQuery(filter ExportFilter) *gorm.DB {
+	query := r.db.Model(&database.Order{}).Where("orders."+exportOwnerColumn(filter)+" = ?", filter.UserID)
+	return applyExportFilter(query, "orders", filter)
+}
+
+func exportOwnerColumn(filter ExportFilter) string {
+	if filter.UserType == "company" {
+		return "company_id"
+	}
+	return "client_id"
+}
+
+func applyExportFilter(query *gorm.DB, table string, filter ExportFilter) *gorm.DB {
+	if filter.From != nil {
+		query = query.Where(table+".created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where(table+".created_at < ?", *filter.To)
+	}
+	if filter.Status != "" {
+		query = query.Where(table+".status = ?", filter.Status)
+	}
+	return query
+}
+
+// streamRows читает результат запроса по одной строке
+func streamRows[T any](query *gorm.DB, fn func(row *T) error) error {
+	rows, err := query.Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row T
+		if err := query.ScanRows(rows, &row); err != nil {
+			return err
+		}
+		if err := fn(&row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func NewExportRepository(db *gorm.DB) ExportRepository {
+	return &exportRepository{db: db}
+}
+
+type ExportJobRepository interface {
+	Create(job *database.ExportJob) error
+	GetByID(id uint) (*database.ExportJob, error)
+	GetByOwner(userID uint, userType string, limit int) ([]database.ExportJob, error)
+	Update(job *database.ExportJob) error
+	// FailUnfinished помечает задачи, прерванные остановкой сервера, как неудачные
+	FailUnfinished(reason string) error
+}
+
+type exportJobRepository struct {
+	db *gorm.DB
+}
+
+func (r *exportJobRepository) Create(job *database.ExportJob) error {
+	return r.db.Create(job).Error
+}
+
+func (r *exportJobRepository) GetByID(id uint) (*database.ExportJob, error) {
+	var job database.ExportJob
+	err := r.db.First(&job, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *exportJobRepository) GetByOwner(userID uint, userType string, limit int) ([]database.ExportJob, error) {
+	var jobs []database.ExportJob
+	err := r.db.Where("user_id = ? AND user_type = ?", userID, userType).
+		Order("id DESC").Limit(limit).Find(&jobs).Error
+	return jobs, err
+}
+
+func (r *exportJobRepository) Update(job *database.ExportJob) error {
+	return r.db.Save(job).Error
+}
+
+func (r *exportJobRepository) FailUnfinished(reason string) error {
+	return r.db.Model(&database.ExportJob{}).Where("status IN ?", []string{"pending", "running"}).
+		Updates(map[string]interface{}{"status": "failed", "error": reason}).Error
+}
+
+func NewExportJobRepository(db *gorm.DB) ExportJobRepository {
+	return &exportJobRepository{db: db}
+}
@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"core/internal/database"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+const (
+	FavoriteTargetCard    = "card"
+	FavoriteTargetCompany = "company"
+)
+
+type FavoriteRepository interface {
+	Add(favorite *database.Favorite) error
+	Remove(clientID uint, targetType string, targetID uint) (int64, error)
+	GetByClient(clientID uint, targetType string) ([]database.Favorite, error)
+	CountByCards(cardIDs []uint) (map[uint]int64, error)
+	GetClientIDsByTarget(targetType string, targetID uint) ([]uint, error)
+}
+
+type favoriteRepository struct {
+	db *gorm.DB
+}
+
+// Add добавляет в избранное. Повторное добавление ничего не меняет
+func (r *favoriteRepository) Add(favorite *database.Favorite) error {
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(favorite).Error
+}
+
+func (r *favoriteRepository) Remove(clientID uint, targetType string, targetID uint) (int64, error) {
+	result := r.db.Unscoped().
+		Where("client_id = ? AND target_type = ? AND target_id = ?", clientID, targetType, targetID).
+		Delete(&database.Favorite{})
+	return result.RowsAffected, result.Error
+}
+
+func (r *favoriteRepository) GetByClient(clientID uint, targetType string) ([]database.Favorite, error) {
+	var favorites []database.Favorite
+	err := r.db.Where("client_id = ? AND target_type = ?", clientID, targetType).
+		Order("created_at DESC").Find(&favorites).Error
+	return favorites, err
+}
+
+// CountByCards считает, сколько клиентов добавили каждую карточку в избранное
+func (r *favoriteRepository) CountByCards(cardIDs []uint) (map[uint]int64, error) {
+	counts := make(map[uint]int64, len(cardIDs))
+	if len(cardIDs) == 0 {
+		return counts, nil
+	}
+
+	var rows []struct {
+		TargetID uint
+		Count    int64
+	}
+	err := r.db.Model(&database.Favorite{}).
+		Select("target_id, COUNT(*) AS count").
+		Where("target_type = ? AND target_id IN ?", FavoriteTargetCard, cardIDs).
+		Group("target_id").Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		counts[row.TargetID] = row.Count
+	}
+	return counts, nil
+}
+
+func (r *favoriteRepository) GetClientIDsByTarget(targetType string, targetID uint) ([]uint, error) {
+	var clientIDs []uint
+	err := r.db.Model(&database.Favorite{}).
+		Where("target_type = ? AND target_id = ?", targetType, targetID).
+		Pluck("client_id", &clientIDs).Error
+	return clientIDs, err
+}
+
+func NewFavoriteRepository(db *gorm.DB) FavoriteRepository {
+	return &favoriteRepository{db: db}
+}
+
+type SavedSearchRepository interface {
+	Create(search *database.SavedSearch) error
+	GetByID(id uint) (*database.SavedSearch, error)
+	GetByClient(clientID uint) ([]database.SavedSearch, error)
+	CountByClient(clientID uint) (int64, error)
+	Update(search *database.SavedSearch) error
+	Delete(id uint) error
+	GetNotifiableForPrice(price float64) ([]database.SavedSearch, error)
+	MarkNotified(ids []uint, notifiedAt time.Time) error
+}
+
+type savedSearchRepository struct {
+	db *gorm.DB
+}
+
+func (r *savedSearchRepository) Create(search *database.SavedSearch) error {
+	return r.db.Create(search).Error
+}
+
+func (r *savedSearchRepository) GetByID(id uint) (*database.SavedSearch, error) {
+	var search database.SavedSearch
+	err := r.db.First(&search, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("saved search with ID %d not found", id)
+		}
+		return nil, err
+	}
+	return &search, nil
+}
+
+func (r *savedSearchRepository) GetByClient(clientID uint) ([]database.SavedSearch, error) {
+	var searches []database.SavedSearch
+	err := r.db.Where("client_id = ?", clientID).Order("created_at DESC").Find(&searches).Error
+	return searches, err
+}
+
+func (r *savedSearchRepository) CountByClient(clientID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&database.SavedSearch{}).Where("client_id = ?", clientID).Count(&count).Error
+	return count, err
+}
+
+func (r *savedSearchRepository) Update(search *database.SavedSearch) error {
+	return r.db.Save(search).Error
+}
+
+func (r *savedSearchRepository) Delete(id uint) error {
+	return r.db.Delete(&database.SavedSearch{}, id).Error
+}
+
+// GetNotifiableForPrice возвращает поиски с уведомлениями, ценовой диапазон которых
+// допускает указанную цену. Остальные фильтры проверяются запросом к карточкам
+func (r *savedSearchRepository) GetNotifiableForPrice(price float64) ([]database.SavedSearch, error) {
+	var searches []database.SavedSearch
+	err := r.db.Where("notify_new_cards = true").
+		Where("(min_price IS NULL OR min_price <= ?) AND (max_price IS NULL OR max_price >= ?)", price, price).
+		Find(&searches).Error
+	return searches, err
+}
+
+func (r *savedSearchRepository) MarkNotified(ids []uint, notifiedAt time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.Model(&database.SavedSearch{}).Where("id IN ?", ids).
+		Update("last_notified_at", notifiedAt).Error
+}
+
+func NewSavedSearchRepository(db *gorm.DB) SavedSearchRepository {
+	return &savedSearchRepository{db: db}
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"core/internal/database"
+	"core/internal/money"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+// PlatformRevenueAccount счет, на который зачисляются комиссии платформы
+const PlatformRevenueAccount = "revenue"
+
+type FeeRuleRepository interface {
+	Create(rule *database.FeeRule) error
+	GetByID(id uint) (*database.FeeRule, error)
+	GetAll() ([]database.FeeRule, error)
+	// GetActiveAt возвращает включенные правила, срок действия которых включает момент at
+	GetActiveAt(at time.Time) ([]database.FeeRule, error)
+	Update(rule *database.FeeRule) error
+	Delete(id uint) error
+}
+
+type feeRuleRepository struct {
+	db *gorm.DB
+}
+
+func (r *feeRuleRepository) Create(rule *database.FeeRule) error {
+	return r.db.Create(rule).Error
+}
+
+func (r *feeRuleRepository) GetByID(id uint) (*database.FeeRule, error) {
+	var rule database.FeeRule
+	err := r.db.First(&rule, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (r *feeRuleRepository) GetAll() ([]database.FeeRule, error) {
+	var rules []database.FeeRule
+	err := r.db.Order("priority DESC, id").Find(&rules).Error
+	return rules, err
+}
+
+func (r *feeRuleRepository) GetActiveAt(at time.Time) ([]database.FeeRule, error) {
+	var rules []database.FeeRule
+	err := r.db.Where("is_active = true").
+		Where("(starts_at IS NULL OR starts_at <= ?) AND (ends_at IS NULL OR ends_at > ?)", at, at).
+		Find(&rules).Error
+	return rules, err
+}
+
+func (r *feeRuleRepository) Update(rule *database.FeeRule) error {
+	return r.db.Save(rule).Error
+}
+
+func (r *feeRuleRepository) Delete(id uint) error {
+	return r.db.Delete(&database.FeeRule{}, id).Error
+}
+
+func NewFeeRuleRepository(db *gorm.DB) FeeRuleRepository {
+	return &feeRuleRepository{db: db}
+}
+
+type PlatformAccountRepository interface {
+	// GetByCode возвращает счета платформы с кодом code во всех валютах
+	GetByCode(code string) ([]database.PlatformAccount, error)
+	// CreditInTx зачисляет сумму на счет платформы в валюте currency, создавая счет при первом зачислении
+	CreditInTx(tx *gorm.DB, code, currency string, amount money.Minor) error
+}
+
+type platformAccountRepository struct {
+	db *gorm.DB
+}
+
+func (r *platformAccountRepository) GetByCode(code string) ([]database.PlatformAccount, error) {
+	var accounts []database.PlatformAccount
+	err := r.db.Where("code = ?", code).Order("currency").Find(&accounts).Error
+	return accounts, err
+}
+
+func (r *platformAccountRepository) CreditInTx(tx *gorm.DB, code, currency string, amount money.Minor) error {
+	account := &database.PlatformAccount{Code: code, Currency: currency, Balance: amount}
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "code"}, {Name: "currency"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"balance": gorm.Expr("platform_accounts.balance + ?", amount)}),
+	}).Create(account).Error
+}
+
+func NewPlatformAccountRepository(db *gorm.DB) PlatformAccountRepository {
+	return &platformAccountRepository{db: db}
+}
@@ -2,13 +2,14 @@ package repository
 
 import (
 	"core/internal/database"
+	"core/internal/pagination"
 	"gorm.io/gorm"
 )
 
 type NotificationRepository interface {
 	Create(notification *database.Notification) error
 	GetByID(id uint) (*database.Notification, error)
-	GetByUser(userID uint, userType string, limit, offset int) ([]database.Notification, error)
+	GetByUser(userID uint, userType string, page pagination.Request) ([]database.Notification, pagination.Page, error)
 	CountByUser(userID uint, userType string) (int, error)
 	CountUnreadByUser(userID uint, userType string) (int, error)
 	MarkAsRead(id uint) error
@@ -33,11 +34,15 @@ func (r *notificationRepository) GetByID(id uint) (*database.Notification, error
 	return &notification, nil
 }
 
-func (r *notificationRepository) GetByUser(userID uint, userType string, limit, offset int) ([]database.Notification, error) {
+func (r *notificationRepository) GetByUser(userID uint, userType string, page pagination.Request) ([]database.Notification, pagination.Page, error) {
 	var notifications []database.Notification
-	err := r.db.Where("user_id = ? AND user_type = ?", userID, userType).
-		Limit(limit).Offset(offset).Order("created_at DESC").Find(&notifications).Error
-	return notifications, err
+	query := r.db.Where("user_id = ? AND user_type = ?", userID, userType)
+	err := pagination.Apply(query, page).Find(&notifications).Error
+	if err != nil {
+		return nil, pagination.Page{}, err
+	}
+	notifications, next := pagination.Trim(notifications, page, notificationCursor)
+	return notifications, next, nil
 }
 
 func (r *notificationRepository) CountByUser(userID uint, userType string) (int, error) {
@@ -68,6 +73,10 @@ func (r *notificationRepository) DeleteOld(days int) error {
 	return r.db.Where("created_at < NOW() - INTERVAL ? DAY", days).Delete(&database.Notification{}).Error
 }
 
+func notificationCursor(notification database.Notification) pagination.Cursor {
+	return pagination.Cursor{CreatedAt: notification.CreatedAt, ID: notification.ID}
+}
+
 func NewNotificationRepository(db *gorm.DB) NotificationRepository {
 	return &notificationRepository{db: db}
 }
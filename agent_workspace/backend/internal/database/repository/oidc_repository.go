@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"core/internal/database"
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type OIDCRepository interface {
+	CreateState(state *database.OIDCState) error
+	// ConsumeState отмечает state использованным и возвращает его. State, который истек
+	// или уже был использован, не принимается
+	ConsumeState(stateHash, purpose string, at time.Time) (*database.OIDCState, error)
+
+	GetIdentity(provider, subject string) (*database.ExternalIdentity, error)
+	ListIdentities(userID uint) ([]database.ExternalIdentity, error)
+	CreateIdentity(identity *database.ExternalIdentity) error
+	// CreateUserWithIdentity сохраняет нового пользователя с клиентским профилем и его
+	// учетную запись у провайдера
+	CreateUserWithIdentity(user *database.UserDB, client *database.ClientDB, identity *database.ExternalIdentity) error
+	MarkIdentityUsed(id uint, at time.Time) error
+	// DeleteIdentity отвязывает провайдера. Последний способ входа пользователя без пароля
+	// отвязать нельзя
+	DeleteIdentity(userID uint, provider string) error
+}
+
+type oidcRepository struct {
+	db *gorm.DB
+}
+
+func (r *oidcRepository) CreateState(state *database.OIDCState) error {
+	return r.db.Create(state).Error
+}
+
+func (r *oidcRepository) ConsumeState(stateHash, purpose string, at time.Time) (*database.OIDCState, error) {
+	var state database.OIDCState
+	result := r.db.Model(&state).Clauses(clause.Returning{}).
+		Where("state_hash = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?", stateHash, purpose, at).
+		Update("used_at", at)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New("invalid or expired state")
+	}
+	return &state, nil
+}
+
+func (r *oidcRepository) GetIdentity(provider, subject string) (*database.ExternalIdentity, error) {
+	var identity database.ExternalIdentity
+	err := r.db.Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error
+	if err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+func (r *oidcRepository) ListIdentities(userID uint) ([]database.ExternalIdentity, error) {
+	var identities []database.ExternalIdentity
+	err := r.db.Where("user_id = ?", userID).Order("id ASC").Find(&identities).Error
+	return identities, err
+}
+
+func (r *oidcRepository) CreateIdentity(identity *database.ExternalIdentity) error {
+	return r.db.Create(identity).Error
+}
+
+func (r *oidcRepository) CreateUserWithIdentity(user *database.UserDB, client *database.ClientDB, identity *database.ExternalIdentity) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(client).Error; err != nil {
+			return err
+		}
+		user.ClientID = &client.ID
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		identity.UserID = user.ID
+		return tx.Create(identity).Error
+	})
+}
+
+func (r *oidcRepository) MarkIdentityUsed(id uint, at time.Time) error {
+	return r.db.Model(&database.ExternalIdentity{}).Where("id = ?", id).Update("last_used_at", at).Error
+}
+
+func (r *oidcRepository) DeleteIdentity(userID uint, provider string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// Блокировка пользователя: параллельные отвязки не должны оставить его без способа входа
+		var user database.UserDB
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", userID).First(&user).Error
+		if err != nil {
+			return err
+		}
+
+		var identities []database.ExternalIdentity
+		if err := tx.Where("user_id = ?", userID).Find(&identities).Error; err != nil {
+			return err
+		}
+		var target *database.ExternalIdentity
+		for i := range identities {
+			if identities[i].Provider == provider {
+				target = &identities[i]
+			}
+		}
+		if target == nil {
+			return errors.New("provider is not linked")
+		}
+		if user.PasswordHash == "" && len(identities) < 2 {
+			return errors.New("set a password before unlinking the last sign-in method")
+		}
+		// Без мягкого удаления: иначе учетную запись провайдера нельзя привязать повторно
+		return tx.Unscoped().Delete(target).Error
+	})
+}
+
+func NewOIDCRepository(db *gorm.DB) OIDCRepository {
+	return &oidcRepository{db: db}
+}
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"core/internal/database"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"os"
+	"testing"
+	"time"
+)
+
+// newTestDB открывает тестовую базу PostgreSQL из TEST_DATABASE_URL. Каждый тест работает
+// в своей транзакции, которая откатывается в конце
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("failed to connect to test database: %v", err)
+	}
+	if err := db.AutoMigrate(&database.UserDB{}, &database.ExternalIdentity{}, &database.OIDCState{}); err != nil {
+		t.Fatalf("failed to migrate test database: %v", err)
+	}
+	tx := db.Begin()
+	t.Cleanup(func() { tx.Rollback() })
+	return tx
+}
+
+func TestOIDCRepositoryConsumeState(t *testing.T) {
+	repo := NewOIDCRepository(newTestDB(t))
+	now := time.Now()
+
+	states := []database.OIDCState{
+		{StateHash: "active", Provider: "test", Purpose: "login", Nonce: "nonce", ExpiresAt: now.Add(time.Minute)},
+		{StateHash: "expired", Provider: "test", Purpose: "login", Nonce: "nonce", ExpiresAt: now.Add(-time.Minute)},
+	}
+	for i := range states {
+		if err := repo.CreateState(&states[i]); err != nil {
+			t.Fatalf("CreateState: %v", err)
+		}
+	}
+
+	if _, err := repo.ConsumeState("active", "link", now); err == nil {
+		t.Error("state was accepted for another purpose")
+	}
+	state, err := repo.ConsumeState("active", "login", now)
+	if err != nil {
+		t.Fatalf("ConsumeState: %v", err)
+	}
+	if state.Nonce != "nonce" || state.Provider != "test" {
+		t.Errorf("state = %+v, want saved nonce and provider", state)
+	}
+	if _, err := repo.ConsumeState("active", "login", now); err == nil {
+		t.Error("state was accepted twice")
+	}
+	if _, err := repo.ConsumeState("expired", "login", now); err == nil {
+		t.Error("expired state was accepted")
+	}
+	if _, err := repo.ConsumeState("unknown", "login", now); err == nil {
+		t.Error("unknown state was accepted")
+	}
+}
+
+func TestOIDCRepositoryDeleteIdentity(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewOIDCRepository(db)
+
+	user := &database.UserDB{Email: "oidc-delete@example.com"}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("Create user: %v", err)
+	}
+	for _, provider := range []string{"google", "yandex"} {
+		err := repo.CreateIdentity(&database.ExternalIdentity{UserID: user.ID, Provider: provider, Subject: provider + "-sub"})
+		if err != nil {
+			t.Fatalf("CreateIdentity: %v", err)
+		}
+	}
+
+	if err := repo.DeleteIdentity(user.ID, "corp"); err == nil {
+		t.Error("unlinked provider was deleted")
+	}
+	if err := repo.DeleteIdentity(user.ID, "google"); err != nil {
+		t.Fatalf("DeleteIdentity with another sign-in method: %v", err)
+	}
+	if err := repo.DeleteIdentity(user.ID, "yandex"); err == nil {
+		t.Fatal("the last sign-in method of a user without a password was deleted")
+	}
+
+	if err := NewUserRepository(db).Update(user.ID, map[string]interface{}{"password_hash": "hash"}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if err := repo.DeleteIdentity(user.ID, "yandex"); err != nil {
+		t.Fatalf("DeleteIdentity with a password: %v", err)
+	}
+	if identities, _ := repo.ListIdentities(user.ID); len(identities) != 0 {
+		t.Errorf("identities = %+v, want none", identities)
+	}
+	// Отвязанную учетную запись можно привязать снова
+	err := repo.CreateIdentity(&database.ExternalIdentity{UserID: user.ID, Provider: "google", Subject: "google-sub"})
+	if err != nil {
+		t.Errorf("CreateIdentity after unlink: %v", err)
+	}
+}
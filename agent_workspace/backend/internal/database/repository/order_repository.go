@@ -2,31 +2,44 @@ package repository
 
 import (
 	"core/internal/database"
+	"core/internal/pagination"
 	"errors"
 	"fmt"
 	"gorm.io/gorm"
+	"time"
 )
 
 type OrderRepository interface {
 	Create(order *database.Order) error
 	GetByID(id uint) (*database.Order, error)
 	GetByIDWithRelations(id uint) (*database.Order, error)
-	GetByClientID(clientID uint, limit, offset int) ([]database.Order, error)
-	GetByCompanyID(companyID uint, limit, offset int) ([]database.Order, error)
-	GetOrdersByClientWithStatus(clientID uint, status string, limit, offset int) ([]database.Order, error)
+	GetByClientID(clientID uint, page pagination.Request) ([]database.Order, pagination.Page, error)
+	GetByCompanyID(companyID uint, page pagination.Request) ([]database.Order, pagination.Page, error)
+	GetOrdersByClientWithStatus(clientID uint, status string, page pagination.Request) ([]database.Order, pagination.Page, error)
 	CountOrdersByClientWithStatus(clientID uint, status string) (int, error)
-	GetOrdersByCompanyWithStatus(companyID uint, status string, limit, offset int) ([]database.Order, error)
+	GetOrdersByCompanyWithStatus(companyID uint, status string, page pagination.Request) ([]database.Order, pagination.Page, error)
 	CountOrdersByCompanyWithStatus(companyID uint, status string) (int, error)
 	UpdateStatus(id uint, status string) error
 	GetByWorkerToken(token string) (*database.Order, error)
 	Update(order *database.Order) error
-	GetAllActive(limit, offset int) ([]database.Order, error)
+	// AssignWorker назначает работника на заказ компании, пока заказ не завершен и не отменен
+	AssignWorker(orderID, companyID, workerID uint) error
+	// CheckIn отмечает прибытие работника и переводит оплаченный заказ в работу. Отметка
+	// ставится один раз
+	CheckIn(orderID, workerID uint, at time.Time) error
+	// CheckOut отмечает уход работника с заказа в работе после прибытия. Отметка ставится один раз
+	CheckOut(orderID, workerID uint, at time.Time) error
+	GetAllActive(page pagination.Request) ([]database.Order, pagination.Page, error)
+	GetByWorkerID(workerID uint, statuses []string) ([]database.Order, error)
 	UpdateStatusesInTx(tx *gorm.DB, id uint, status, paymentStatus string) error
 
 	// Методы для работы с транзакциями
 	BeginTransaction() *gorm.DB
+	CreateInTx(tx *gorm.DB, order *database.Order) error
 	UpdateStatusInTx(tx *gorm.DB, id uint, status string) error
 	UpdatePaymentStatusInTx(tx *gorm.DB, id uint, paymentStatus string) error
+	UpdateFeeInTx(tx *gorm.DB, order *database.Order) error
+	CreateCheckoutInTx(tx *gorm.DB, checkout *database.Checkout) error
 }
 
 type orderRepository struct {
@@ -58,18 +71,26 @@ func (r *orderRepository) UpdateStatusesInTx(tx *gorm.DB, id uint, status, payme
 		}).Error
 }
 
-func (r *orderRepository) GetByClientID(clientID uint, limit, offset int) ([]database.Order, error) {
+func (r *orderRepository) GetByClientID(clientID uint, page pagination.Request) ([]database.Order, pagination.Page, error) {
 	var orders []database.Order
-	err := r.db.Preload("Company").Preload("Card").Where("client_id = ?", clientID).
-		Limit(limit).Offset(offset).Order("created_at DESC").Find(&orders).Error
-	return orders, err
+	query := r.db.Preload("Company").Preload("Card").Where("client_id = ?", clientID)
+	err := pagination.Apply(query, page).Find(&orders).Error
+	if err != nil {
+		return nil, pagination.Page{}, err
+	}
+	orders, next := pagination.Trim(orders, page, orderCursor)
+	return orders, next, nil
 }
 
-func (r *orderRepository) GetByCompanyID(companyID uint, limit, offset int) ([]database.Order, error) {
+func (r *orderRepository) GetByCompanyID(companyID uint, page pagination.Request) ([]database.Order, pagination.Page, error) {
 	var orders []database.Order
-	err := r.db.Preload("Client").Preload("Card").Where("company_id = ?", companyID).
-		Limit(limit).Offset(offset).Order("created_at DESC").Find(&orders).Error
-	return orders, err
+	query := r.db.Preload("Client").Preload("Card").Preload("Worker").Where("company_id = ?", companyID)
+	err := pagination.Apply(query, page).Find(&orders).Error
+	if err != nil {
+		return nil, pagination.Page{}, err
+	}
+	orders, next := pagination.Trim(orders, page, orderCursor)
+	return orders, next, nil
 }
 
 func (r *orderRepository) UpdateStatus(id uint, status string) error {
@@ -78,7 +99,7 @@ func (r *orderRepository) UpdateStatus(id uint, status string) error {
 
 func (r *orderRepository) GetByWorkerToken(token string) (*database.Order, error) {
 	var workerLink database.WorkerLink
-	err := r.db.Where("token = ? AND is_used = false AND expires_at > NOW()", token).First(&workerLink).Error
+	err := r.db.Where("token = ? AND is_used = false AND revoked_at IS NULL AND expires_at > NOW()", token).First(&workerLink).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, fmt.Errorf("worker link not found or expired")
@@ -99,17 +120,74 @@ func (r *orderRepository) Update(order *database.Order) error {
 	return r.db.Save(order).Error
 }
 
-func (r *orderRepository) GetAllActive(limit, offset int) ([]database.Order, error) {
+// Отметки работника меняют только свои поля: полное сохранение заказа затерло бы
+// параллельные изменения оплаты и статуса
+func (r *orderRepository) AssignWorker(orderID, companyID, workerID uint) error {
+	result := r.db.Model(&database.Order{}).
+		Where("id = ? AND company_id = ? AND status IN ?", orderID, companyID, []string{"created", "accepted", "paid", "in_progress"}).
+		Update("worker_id", workerID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("order cannot be assigned in current status")
+	}
+	return nil
+}
+
+func (r *orderRepository) CheckIn(orderID, workerID uint, at time.Time) error {
+	result := r.db.Model(&database.Order{}).
+		Where("id = ? AND worker_id = ? AND status IN ? AND check_in_at IS NULL", orderID, workerID, []string{"paid", "in_progress"}).
+		Updates(map[string]interface{}{
+			"check_in_at": at,
+			"status":      "in_progress",
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("order cannot be checked in in current status")
+	}
+	return nil
+}
+
+func (r *orderRepository) CheckOut(orderID, workerID uint, at time.Time) error {
+	result := r.db.Model(&database.Order{}).
+		Where("id = ? AND worker_id = ? AND status = ? AND check_in_at IS NOT NULL AND check_out_at IS NULL", orderID, workerID, "in_progress").
+		Update("check_out_at", at)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("order cannot be checked out in current status")
+	}
+	return nil
+}
+
+func (r *orderRepository) GetAllActive(page pagination.Request) ([]database.Order, pagination.Page, error) {
 	var orders []database.Order
-	err := r.db.Preload("Client").Preload("Company").Preload("Card").
-		Where("status IN ?", []string{"created", "paid", "in_progress"}).
-		Limit(limit).Offset(offset).Order("created_at DESC").Find(&orders).Error
+	query := r.db.Preload("Client").Preload("Company").Preload("Card").
+		Where("status IN ?", []string{"created", "paid", "in_progress"})
+	err := pagination.Apply(query, page).Find(&orders).Error
+	if err != nil {
+		return nil, pagination.Page{}, err
+	}
+	orders, next := pagination.Trim(orders, page, orderCursor)
+	return orders, next, nil
+}
+
+func (r *orderRepository) GetByWorkerID(workerID uint, statuses []string) ([]database.Order, error) {
+	var orders []database.Order
+	err := r.db.Preload("Client").Preload("Card").
+		Where("worker_id = ? AND status IN ?", workerID, statuses).
+		Order("scheduled_at ASC NULLS LAST, created_at ASC").Find(&orders).Error
 	return orders, err
 }
 
 func (r *orderRepository) GetByIDWithRelations(id uint) (*database.Order, error) {
 	var order database.Order
-	err := r.db.Preload("Client").Preload("Company").Preload("Card").First(&order, id).Error
+	err := r.db.Preload("Client").Preload("Company").Preload("Card").Preload("Worker").Preload("CompletionReport").Preload("LineItems").
+		First(&order, id).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, fmt.Errorf("order with ID %d not found", id)
@@ -119,16 +197,20 @@ func (r *orderRepository) GetByIDWithRelations(id uint) (*database.Order, error)
 	return &order, nil
 }
 
-func (r *orderRepository) GetOrdersByClientWithStatus(clientID uint, status string, limit, offset int) ([]database.Order, error) {
+func (r *orderRepository) GetOrdersByClientWithStatus(clientID uint, status string, page pagination.Request) ([]database.Order, pagination.Page, error) {
 	var orders []database.Order
-	query := r.db.Preload("Company").Preload("Card").Where("client_id = ?", clientID)
+	query := r.db.Preload("Company").Preload("Card").Preload("CompletionReport").Preload("LineItems").Where("client_id = ?", clientID)
 
 	if status != "" {
 		query = query.Where("status = ?", status)
 	}
 
-	err := query.Limit(limit).Offset(offset).Order("created_at DESC").Find(&orders).Error
-	return orders, err
+	err := pagination.Apply(query, page).Find(&orders).Error
+	if err != nil {
+		return nil, pagination.Page{}, err
+	}
+	orders, next := pagination.Trim(orders, page, orderCursor)
+	return orders, next, nil
 }
 
 func (r *orderRepository) CountOrdersByClientWithStatus(clientID uint, status string) (int, error) {
@@ -143,16 +225,21 @@ func (r *orderRepository) CountOrdersByClientWithStatus(clientID uint, status st
 	return int(count), err
 }
 
-func (r *orderRepository) GetOrdersByCompanyWithStatus(companyID uint, status string, limit, offset int) ([]database.Order, error) {
+func (r *orderRepository) GetOrdersByCompanyWithStatus(companyID uint, status string, page pagination.Request) ([]database.Order, pagination.Page, error) {
 	var orders []database.Order
-	query := r.db.Preload("Client").Preload("Card").Where("company_id = ?", companyID)
+	query := r.db.Preload("Client").Preload("Card").Preload("Worker").Preload("CompletionReport").Preload("LineItems").
+		Where("company_id = ?", companyID)
 
 	if status != "" {
 		query = query.Where("status = ?", status)
 	}
 
-	err := query.Limit(limit).Offset(offset).Order("created_at DESC").Find(&orders).Error
-	return orders, err
+	err := pagination.Apply(query, page).Find(&orders).Error
+	if err != nil {
+		return nil, pagination.Page{}, err
+	}
+	orders, next := pagination.Trim(orders, page, orderCursor)
+	return orders, next, nil
 }
 
 func (r *orderRepository) CountOrdersByCompanyWithStatus(companyID uint, status string) (int, error) {
@@ -172,6 +259,14 @@ func (r *orderRepository) BeginTransaction() *gorm.DB {
 	return r.db.Begin()
 }
 
+func (r *orderRepository) CreateInTx(tx *gorm.DB, order *database.Order) error {
+	return tx.Create(order).Error
+}
+
+func (r *orderRepository) CreateCheckoutInTx(tx *gorm.DB, checkout *database.Checkout) error {
+	return tx.Create(checkout).Error
+}
+
 func (r *orderRepository) UpdateStatusInTx(tx *gorm.DB, id uint, status string) error {
 	return tx.Model(&database.Order{}).Where("id = ?", id).Update("status", status).Error
 }
@@ -180,6 +275,21 @@ func (r *orderRepository) UpdatePaymentStatusInTx(tx *gorm.DB, id uint, paymentS
 	return tx.Model(&database.Order{}).Where("id = ?", id).Update("payment_status", paymentStatus).Error
 }
 
+// UpdateFeeInTx сохраняет зафиксированную в заказе комиссию платформы
+func (r *orderRepository) UpdateFeeInTx(tx *gorm.DB, order *database.Order) error {
+	return tx.Model(&database.Order{}).Where("id = ?", order.ID).Updates(map[string]interface{}{
+		"fee_rule_id":   order.FeeRuleID,
+		"fee_percent":   order.FeePercent,
+		"fee_fixed":     order.FeeFixed,
+		"fee_amount":    order.FeeAmount,
+		"fee_quoted_at": order.FeeQuotedAt,
+	}).Error
+}
+
+func orderCursor(order database.Order) pagination.Cursor {
+	return pagination.Cursor{CreatedAt: order.CreatedAt, ID: order.ID}
+}
+
 func NewOrderRepository(db *gorm.DB) OrderRepository {
 	return &orderRepository{db: db}
 }
@@ -0,0 +1,241 @@
+package repository
+
+import (
+	"core/internal/database"
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+// PlatformPromotionsAccount счет платформы, с которого оплачиваются скидки за счет
+// платформы и реферальные бонусы. Баланс счета уходит в минус на сумму расходов
+const PlatformPromotionsAccount = "promotions"
+
+type PromoCodeRepository interface {
+	Create(promo *database.PromoCode) error
+	GetByID(id uint) (*database.PromoCode, error)
+	GetByCode(code string) (*database.PromoCode, error)
+	// GetAll возвращает промокоды компании companyID или все промокоды, если companyID не указан
+	GetAll(companyID *uint) ([]database.PromoCode, error)
+	Update(promo *database.PromoCode) error
+	Delete(id uint) error
+	// CountRedemptions возвращает число применений промокода клиентом
+	CountRedemptions(promoID, clientID uint) (int64, error)
+	// RedeemInTx учитывает применение промокода к заказу. Лимиты проверяются под блокировкой
+	// строки промокода, поэтому параллельные заказы не превысят их
+	RedeemInTx(tx *gorm.DB, promo *database.PromoCode, redemption *database.PromoRedemption) error
+	// ReleaseInTx отменяет применение промокода к заказу orderID, если оно было
+	ReleaseInTx(tx *gorm.DB, orderID uint) error
+	CreateLineItemsInTx(tx *gorm.DB, items []database.OrderLineItem) error
+	GetLineItems(orderID uint) ([]database.OrderLineItem, error)
+}
+
+type promoCodeRepository struct {
+	db *gorm.DB
+}
+
+func (r *promoCodeRepository) Create(promo *database.PromoCode) error {
+	return r.db.Create(promo).Error
+}
+
+func (r *promoCodeRepository) GetByID(id uint) (*database.PromoCode, error) {
+	var promo database.PromoCode
+	err := r.db.First(&promo, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &promo, nil
+}
+
+func (r *promoCodeRepository) GetByCode(code string) (*database.PromoCode, error) {
+	var promo database.PromoCode
+	err := r.db.Where("code = ?", code).First(&promo).Error
+	if err != nil {
+		return nil, err
+	}
+	return &promo, nil
+}
+
+func (r *promoCodeRepository) GetAll(companyID *uint) ([]database.PromoCode, error) {
+	var promos []database.PromoCode
+	query := r.db.Order("id DESC")
+	if companyID != nil {
+		query = query.Where("company_id = ?", *companyID)
+	}
+	err := query.Find(&promos).Error
+	return promos, err
+}
+
+func (r *promoCodeRepository) Update(promo *database.PromoCode) error {
+	return r.db.Save(promo).Error
+}
+
+func (r *promoCodeRepository) Delete(id uint) error {
+	return r.db.Delete(&database.PromoCode{}, id).Error
+}
+
+func (r *promoCodeRepository) CountRedemptions(promoID, clientID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&database.PromoRedemption{}).
+		Where("promo_code_id = ? AND client_id = ?", promoID, clientID).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *promoCodeRepository) RedeemInTx(tx *gorm.DB, promo *database.PromoCode, redemption *database.PromoRedemption) error {
+	var locked database.PromoCode
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&locked, promo.ID).Error; err != nil {
+		return err
+	}
+	if !locked.IsActive {
+		return errors.New("promo code is not active")
+	}
+	if locked.UsageLimit > 0 && locked.UsedCount >= locked.UsageLimit {
+		return errors.New("promo code usage limit reached")
+	}
+
+	if locked.PerUserLimit > 0 {
+		var used int64
+		err := tx.Model(&database.PromoRedemption{}).
+			Where("promo_code_id = ? AND client_id = ?", promo.ID, redemption.ClientID).
+			Count(&used).Error
+		if err != nil {
+			return err
+		}
+		if used >= int64(locked.PerUserLimit) {
+			return errors.New("promo code has already been used")
+		}
+	}
+
+	if err := tx.Create(redemption).Error; err != nil {
+		return err
+	}
+	return tx.Model(&database.PromoCode{}).Where("id = ?", promo.ID).
+		Update("used_count", gorm.Expr("used_count + 1")).Error
+}
+
+func (r *promoCodeRepository) ReleaseInTx(tx *gorm.DB, orderID uint) error {
+	var redemption database.PromoRedemption
+	err := tx.Where("order_id = ?", orderID).First(&redemption).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// Удаляем физически, чтобы повторное применение не упиралось в уникальный индекс заказа
+	if err := tx.Unscoped().Delete(&redemption).Error; err != nil {
+		return err
+	}
+	return tx.Model(&database.PromoCode{}).Where("id = ? AND used_count > 0", redemption.PromoCodeID).
+		Update("used_count", gorm.Expr("used_count - 1")).Error
+}
+
+func (r *promoCodeRepository) CreateLineItemsInTx(tx *gorm.DB, items []database.OrderLineItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+	return tx.Create(&items).Error
+}
+
+func (r *promoCodeRepository) GetLineItems(orderID uint) ([]database.OrderLineItem, error) {
+	var items []database.OrderLineItem
+	err := r.db.Where("order_id = ?", orderID).Order("id").Find(&items).Error
+	return items, err
+}
+
+func NewPromoCodeRepository(db *gorm.DB) PromoCodeRepository {
+	return &promoCodeRepository{db: db}
+}
+
+type ReferralRepository interface {
+	GetCodeByClient(clientID uint) (*database.ReferralCode, error)
+	GetCodeByCode(code string) (*database.ReferralCode, error)
+	CreateCode(code *database.ReferralCode) error
+	Create(referral *database.Referral) error
+	GetByReferred(referredID uint) (*database.Referral, error)
+	// CountByReferrer возвращает число приглашенных клиентов и число начисленных за них бонусов
+	CountByReferrer(referrerID uint) (invited int64, credited int64, err error)
+	// MarkCreditedInTx помечает приглашение начисленным. Возвращает ошибку, если бонус
+	// уже был начислен параллельным запросом
+	MarkCreditedInTx(tx *gorm.DB, referralID, orderID uint, creditedAt time.Time) error
+	BeginTransaction() *gorm.DB
+}
+
+type referralRepository struct {
+	db *gorm.DB
+}
+
+func (r *referralRepository) GetCodeByClient(clientID uint) (*database.ReferralCode, error) {
+	var code database.ReferralCode
+	err := r.db.Where("client_id = ?", clientID).First(&code).Error
+	if err != nil {
+		return nil, err
+	}
+	return &code, nil
+}
+
+func (r *referralRepository) GetCodeByCode(code string) (*database.ReferralCode, error) {
+	var referralCode database.ReferralCode
+	err := r.db.Where("code = ?", code).First(&referralCode).Error
+	if err != nil {
+		return nil, err
+	}
+	return &referralCode, nil
+}
+
+func (r *referralRepository) CreateCode(code *database.ReferralCode) error {
+	return r.db.Create(code).Error
+}
+
+func (r *referralRepository) Create(referral *database.Referral) error {
+	return r.db.Create(referral).Error
+}
+
+func (r *referralRepository) GetByReferred(referredID uint) (*database.Referral, error) {
+	var referral database.Referral
+	err := r.db.Where("referred_id = ?", referredID).First(&referral).Error
+	if err != nil {
+		return nil, err
+	}
+	return &referral, nil
+}
+
+func (r *referralRepository) CountByReferrer(referrerID uint) (int64, int64, error) {
+	var counts struct {
+		Invited  int64
+		Credited int64
+	}
+	err := r.db.Model(&database.Referral{}).
+		Select("COUNT(*) AS invited, COUNT(*) FILTER (WHERE status = 'credited') AS credited").
+		Where("referrer_id = ?", referrerID).
+		Scan(&counts).Error
+	return counts.Invited, counts.Credited, err
+}
+
+func (r *referralRepository) MarkCreditedInTx(tx *gorm.DB, referralID, orderID uint, creditedAt time.Time) error {
+	result := tx.Model(&database.Referral{}).
+		Where("id = ? AND status = ?", referralID, "pending").
+		Updates(map[string]interface{}{
+			"status":      "credited",
+			"order_id":    orderID,
+			"credited_at": creditedAt,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("referral reward has already been credited")
+	}
+	return nil
+}
+
+func (r *referralRepository) BeginTransaction() *gorm.DB {
+	return r.db.Begin()
+}
+
+func NewReferralRepository(db *gorm.DB) ReferralRepository {
+	return &referralRepository{db: db}
+}
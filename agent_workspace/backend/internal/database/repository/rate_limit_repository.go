@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"core/internal/database"
+	"core/internal/ratelimit"
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+// RateLimitRepository хранилище ограничений в Postgres, общее для всех реплик сервиса.
+// Строка корзины блокируется на время пересчета, поэтому одновременные запросы
+// не забирают один и тот же токен
+type RateLimitRepository interface {
+	ratelimit.Store
+}
+
+type rateLimitRepository struct {
+	db *gorm.DB
+}
+
+func (r *rateLimitRepository) Take(key string, limit ratelimit.Limit, now time.Time) (time.Duration, error) {
+	var wait time.Duration
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// Новая корзина создается полной
+		err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&database.RateLimitBucket{Key: key, Tokens: float64(limit.Requests), RefilledAt: now}).Error
+		if err != nil {
+			return err
+		}
+
+		var bucket database.RateLimitBucket
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("key = ?", key).First(&bucket).Error
+		if err != nil {
+			return err
+		}
+
+		var tokens float64
+		tokens, wait = limit.Take(bucket.Tokens, now.Sub(bucket.RefilledAt))
+		return tx.Model(&database.RateLimitBucket{}).Where("key = ?", key).
+			Updates(map[string]interface{}{"tokens": tokens, "refilled_at": now}).Error
+	})
+	return wait, err
+}
+
+func (r *rateLimitRepository) LockedUntil(key string, now time.Time) (time.Time, error) {
+	var failure database.RateLimitFailure
+	err := r.db.Where("key = ?", key).First(&failure).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	if failure.LockedUntil == nil {
+		return time.Time{}, nil
+	}
+	return *failure.LockedUntil, nil
+}
+
+func (r *rateLimitRepository) RecordFailure(key string, policy ratelimit.LockoutPolicy, now time.Time) (time.Time, error) {
+	var lockedUntil time.Time
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&database.RateLimitFailure{Key: key, LastFailureAt: now}).Error
+		if err != nil {
+			return err
+		}
+
+		var failure database.RateLimitFailure
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("key = ?", key).First(&failure).Error
+		if err != nil {
+			return err
+		}
+
+		var failures int
+		failures, lockedUntil = policy.Next(failure.Failures, failure.LastFailureAt, now)
+		updates := map[string]interface{}{"failures": failures, "last_failure_at": now, "locked_until": nil}
+		if !lockedUntil.IsZero() {
+			updates["locked_until"] = lockedUntil
+		}
+		return tx.Model(&database.RateLimitFailure{}).Where("key = ?", key).Updates(updates).Error
+	})
+	return lockedUntil, err
+}
+
+func (r *rateLimitRepository) ResetFailures(key string) error {
+	return r.db.Where("key = ?", key).Delete(&database.RateLimitFailure{}).Error
+}
+
+func (r *rateLimitRepository) Prune(before time.Time) error {
+	err := r.db.Where("refilled_at < ?", before).Delete(&database.RateLimitBucket{}).Error
+	if err != nil {
+		return err
+	}
+	return r.db.Where("last_failure_at < ? AND (locked_until IS NULL OR locked_until < ?)", before, before).
+		Delete(&database.RateLimitFailure{}).Error
+}
+
+func NewRateLimitRepository(db *gorm.DB) RateLimitRepository {
+	return &rateLimitRepository{db: db}
+}
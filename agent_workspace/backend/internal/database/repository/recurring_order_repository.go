@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"core/internal/database"
+	"errors"
+	"gorm.io/gorm"
+	"time"
+)
+
+type RecurringOrderRepository interface {
+	Create(recurring *database.RecurringOrder) error
+	GetByID(id uint) (*database.RecurringOrder, error)
+	GetByClient(clientID uint) ([]database.RecurringOrder, error)
+	// GetByCompany возвращает расписания компании в статусах statuses
+	GetByCompany(companyID uint, statuses []string) ([]database.RecurringOrder, error)
+	// GetDue возвращает активные расписания, ближайшее занятие которых не позже until
+	GetDue(until time.Time, limit int) ([]database.RecurringOrder, error)
+	Update(recurring *database.RecurringOrder) error
+	// ClaimNext переносит ближайшее занятие с at на next. Возвращает false, если занятие
+	// уже обработано параллельно. Без next расписание завершается
+	ClaimNext(id uint, at time.Time, next *time.Time) (bool, error)
+	SetLastError(id uint, message string) error
+	LinkOrder(orderID, recurringID uint) error
+
+	GetOccurrence(recurringID uint, scheduledAt time.Time) (*database.RecurringOccurrence, error)
+	// GetOccurrences возвращает занятия расписаний recurringIDs в интервале [from, to)
+	GetOccurrences(recurringIDs []uint, from, to time.Time) ([]database.RecurringOccurrence, error)
+	CreateOccurrence(occurrence *database.RecurringOccurrence) error
+	UpdateOccurrence(occurrence *database.RecurringOccurrence) error
+	// GetFailedPayments возвращает занятия после after, заказы которых не удалось оплатить автоматически
+	GetFailedPayments(after time.Time) ([]database.RecurringOccurrence, error)
+	// ClaimFailedPayment забирает занятие для повторной оплаты. Возвращает false, если его
+	// уже забрал другой обработчик. Занятие, забранное раньше staleBefore, считается
+	// брошенным упавшим обработчиком и забирается снова
+	ClaimFailedPayment(id uint, staleBefore time.Time) (bool, error)
+}
+
+type recurringOrderRepository struct {
+	db *gorm.DB
+}
+
+func (r *recurringOrderRepository) Create(recurring *database.RecurringOrder) error {
+	return r.db.Create(recurring).Error
+}
+
+func (r *recurringOrderRepository) GetByID(id uint) (*database.RecurringOrder, error) {
+	var recurring database.RecurringOrder
+	err := r.db.Preload("Client").Preload("Company").Preload("Card").First(&recurring, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("recurring order not found")
+		}
+		return nil, err
+	}
+	return &recurring, nil
+}
+
+func (r *recurringOrderRepository) GetByClient(clientID uint) ([]database.RecurringOrder, error) {
+	var recurring []database.RecurringOrder
+	err := r.db.Preload("Company").Preload("Card").
+		Where("client_id = ?", clientID).
+		Order("id DESC").Find(&recurring).Error
+	return recurring, err
+}
+
+func (r *recurringOrderRepository) GetByCompany(companyID uint, statuses []string) ([]database.RecurringOrder, error) {
+	var recurring []database.RecurringOrder
+	err := r.db.Preload("Client").Preload("Card").
+		Where("company_id = ? AND status IN ?", companyID, statuses).
+		Order("next_at ASC NULLS LAST, id").Find(&recurring).Error
+	return recurring, err
+}
+
+func (r *recurringOrderRepository) GetDue(until time.Time, limit int) ([]database.RecurringOrder, error) {
+	var recurring []database.RecurringOrder
+	err := r.db.Where("status = ? AND next_at IS NOT NULL AND next_at <= ?", "active", until).
+		Order("next_at").Limit(limit).Find(&recurring).Error
+	return recurring, err
+}
+
+func (r *recurringOrderRepository) Update(recurring *database.RecurringOrder) error {
+	return r.db.Omit("Client", "Company", "Card").Save(recurring).Error
+}
+
+func (r *recurringOrderRepository) ClaimNext(id uint, at time.Time, next *time.Time) (bool, error) {
+	updates := map[string]interface{}{"next_at": next}
+	if next == nil {
+		updates["status"] = "finished"
+	}
+	result := r.db.Model(&database.RecurringOrder{}).
+		Where("id = ? AND status = ? AND next_at = ?", id, "active", at).
+		Updates(updates)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *recurringOrderRepository) SetLastError(id uint, message string) error {
+	return r.db.Model(&database.RecurringOrder{}).Where("id = ?", id).Update("last_error", message).Error
+}
+
+func (r *recurringOrderRepository) LinkOrder(orderID, recurringID uint) error {
+	return r.db.Model(&database.Order{}).Where("id = ?", orderID).Update("recurring_order_id", recurringID).Error
+}
+
+func (r *recurringOrderRepository) GetOccurrence(recurringID uint, scheduledAt time.Time) (*database.RecurringOccurrence, error) {
+	var occurrence database.RecurringOccurrence
+	err := r.db.Where("recurring_order_id = ? AND scheduled_at = ?", recurringID, scheduledAt).First(&occurrence).Error
+	if err != nil {
+		return nil, err
+	}
+	return &occurrence, nil
+}
+
+func (r *recurringOrderRepository) GetOccurrences(recurringIDs []uint, from, to time.Time) ([]database.RecurringOccurrence, error) {
+	var occurrences []database.RecurringOccurrence
+	if len(recurringIDs) == 0 {
+		return occurrences, nil
+	}
+	err := r.db.Where("recurring_order_id IN ? AND scheduled_at >= ? AND scheduled_at < ?", recurringIDs, from, to).
+		Order("scheduled_at").Find(&occurrences).Error
+	return occurrences, err
+}
+
+func (r *recurringOrderRepository) CreateOccurrence(occurrence *database.RecurringOccurrence) error {
+	return r.db.Create(occurrence).Error
+}
+
+func (r *recurringOrderRepository) UpdateOccurrence(occurrence *database.RecurringOccurrence) error {
+	return r.db.Save(occurrence).Error
+}
+
+func (r *recurringOrderRepository) GetFailedPayments(after time.Time) ([]database.RecurringOccurrence, error) {
+	var occurrences []database.RecurringOccurrence
+	err := r.db.Where("status IN ? AND order_id IS NOT NULL AND scheduled_at > ?", []string{"payment_failed", "payment_retrying"}, after).
+		Order("scheduled_at").Find(&occurrences).Error
+	return occurrences, err
+}
+
+func (r *recurringOrderRepository) ClaimFailedPayment(id uint, staleBefore time.Time) (bool, error) {
+	result := r.db.Model(&database.RecurringOccurrence{}).
+		Where("id = ? AND (status = ? OR (status = ? AND updated_at < ?))", id, "payment_failed", "payment_retrying", staleBefore).
+		Update("status", "payment_retrying")
+	return result.RowsAffected > 0, result.Error
+}
+
+func NewRecurringOrderRepository(db *gorm.DB) RecurringOrderRepository {
+	return &recurringOrderRepository{db: db}
+}
@@ -2,15 +2,32 @@ package repository
 
 import (
 	"core/internal/database"
+	"core/internal/pagination"
+	"core/internal/rating"
+	"database/sql"
+	"errors"
 	"gorm.io/gorm"
+	"time"
 )
 
 type ReviewRepository interface {
 	Create(review *database.Review) error
-	GetByCompanyID(companyID uint, limit, offset int) ([]database.Review, error)
+	GetByID(id uint) (*database.Review, error)
+	// UpdateContent сохраняет оценки и текст, измененные автором, и пересчитывает рейтинг
+	// компании. Скрытый отзыв или отзыв другого клиента не меняется
+	UpdateContent(review *database.Review) error
+	// SetReply сохраняет ответ компании. Ответ можно дать только один раз
+	SetReply(review *database.Review) error
+	// SetHidden скрывает или восстанавливает отзыв. При скрытии ожидающие жалобы
+	// закрываются как удовлетворенные
+	SetHidden(review *database.Review, hidden bool, reason string, adminID uint) error
+	GetByCompanyID(companyID uint, page pagination.Request) ([]database.Review, pagination.Page, error)
 	GetByOrderID(orderID uint) (*database.Review, error)
 	UpdateCompanyRating(companyID uint) error
 	GetCompanyAverageRating(companyID uint) (float64, int, error)
+	GetRecentByCompanyID(companyID uint, limit int) ([]database.Review, error)
+	GetRatingDistribution(companyID uint) (map[int]int64, error)
+	RecalculateAllRatings() error
 }
 
 type reviewRepository struct {
@@ -38,11 +55,100 @@ func (r *reviewRepository) Create(review *database.Review) error {
 	return tx.Commit().Error
 }
 
-func (r *reviewRepository) GetByCompanyID(companyID uint, limit, offset int) ([]database.Review, error) {
+func (r *reviewRepository) GetByCompanyID(companyID uint, page pagination.Request) ([]database.Review, pagination.Page, error) {
 	var reviews []database.Review
-	err := r.db.Preload("Client").Where("company_id = ?", companyID).
-		Limit(limit).Offset(offset).Order("created_at DESC").Find(&reviews).Error
-	return reviews, err
+	query := r.db.Preload("Client").Where("company_id = ? AND is_hidden = false", companyID)
+	err := pagination.Apply(query, page).Find(&reviews).Error
+	if err != nil {
+		return nil, pagination.Page{}, err
+	}
+	reviews, next := pagination.Trim(reviews, page, reviewCursor)
+	return reviews, next, nil
+}
+
+func (r *reviewRepository) GetByID(id uint) (*database.Review, error) {
+	var review database.Review
+	err := r.db.Preload("Client").First(&review, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &review, nil
+}
+
+func (r *reviewRepository) UpdateContent(review *database.Review) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// Только поля автора: параллельное скрытие администратором и ответ компании не затираются
+		result := tx.Model(&database.Review{}).
+			Where("id = ? AND client_id = ? AND is_hidden = false", review.ID, review.ClientID).
+			Updates(map[string]interface{}{
+				"rating":               review.Rating,
+				"comment":              review.Comment,
+				"quality_rating":       review.QualityRating,
+				"punctuality_rating":   review.PunctualityRating,
+				"communication_rating": review.CommunicationRating,
+				"value_rating":         review.ValueRating,
+				"edited_at":            review.EditedAt,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("review cannot be edited")
+		}
+		return r.updateCompanyRatingInTx(tx, review.CompanyID)
+	})
+}
+
+func (r *reviewRepository) SetReply(review *database.Review) error {
+	result := r.db.Model(&database.Review{}).
+		Where("id = ? AND company_id = ? AND COALESCE(reply, '') = ''", review.ID, review.CompanyID).
+		Updates(map[string]interface{}{
+			"reply":      review.Reply,
+			"replied_at": review.RepliedAt,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("review already has a reply")
+	}
+	return nil
+}
+
+func (r *reviewRepository) SetHidden(review *database.Review, hidden bool, reason string, adminID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		review.IsHidden = hidden
+		review.HiddenReason = reason
+		review.HiddenAt = nil
+		if hidden {
+			review.HiddenAt = &now
+		}
+		err := tx.Model(&database.Review{}).Where("id = ?", review.ID).
+			Updates(map[string]interface{}{
+				"is_hidden":     review.IsHidden,
+				"hidden_at":     review.HiddenAt,
+				"hidden_reason": review.HiddenReason,
+			}).Error
+		if err != nil {
+			return err
+		}
+
+		if hidden {
+			err = tx.Model(&database.ReviewReport{}).
+				Where("review_id = ? AND status = ?", review.ID, ReviewReportPending).
+				Updates(map[string]interface{}{
+					"status":         ReviewReportHidden,
+					"resolved_by_id": adminID,
+					"resolved_at":    now,
+				}).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		return r.updateCompanyRatingInTx(tx, review.CompanyID)
+	})
 }
 
 func (r *reviewRepository) GetByOrderID(orderID uint) (*database.Review, error) {
@@ -58,23 +164,94 @@ func (r *reviewRepository) UpdateCompanyRating(companyID uint) error {
 	return r.updateCompanyRatingInTx(r.db, companyID)
 }
 
+// companyRatingStats агрегаты по видимым отзывам компании
+type companyRatingStats struct {
+	CompanyID           uint
+	AvgRating           float64
+	Count               int64
+	QualityRating       float64
+	PunctualityRating   float64
+	CommunicationRating float64
+	ValueRating         float64
+}
+
+const companyRatingStatsSQL = "company_id, COALESCE(AVG(rating), 0) as avg_rating, COUNT(*) as count, " +
+	"COALESCE(AVG(quality_rating), 0) as quality_rating, COALESCE(AVG(punctuality_rating), 0) as punctuality_rating, " +
+	"COALESCE(AVG(communication_rating), 0) as communication_rating, COALESCE(AVG(value_rating), 0) as value_rating"
+
 func (r *reviewRepository) updateCompanyRatingInTx(tx *gorm.DB, companyID uint) error {
-	var result struct {
-		AvgRating float64
-		Count     int64
+	var result companyRatingStats
+	err := tx.Model(&database.Review{}).Select(companyRatingStatsSQL).
+		Where("company_id = ? AND is_hidden = false", companyID).Group("company_id").Scan(&result).Error
+	if err != nil {
+		return err
 	}
 
-	err := tx.Model(&database.Review{}).Select("AVG(rating) as avg_rating, COUNT(*) as count").
-		Where("company_id = ?", companyID).Scan(&result).Error
+	priorMean, err := r.platformMeanRating(tx)
 	if err != nil {
 		return err
 	}
-
 	return tx.Model(&database.CompanyDB{}).Where("id = ?", companyID).
-		Updates(map[string]interface{}{
-			"stars":        result.AvgRating,
-			"review_count": result.Count,
-		}).Error
+		Updates(companyRatingColumns(result, priorMean)).Error
+}
+
+// RecalculateAllRatings пересчитывает рейтинги всех компаний. Средняя оценка платформы
+// меняется с каждым отзывом, поэтому байесовские оценки остальных компаний со временем
+// устаревают; пересчет выполняется при запуске
+func (r *reviewRepository) RecalculateAllRatings() error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var stats []companyRatingStats
+		err := tx.Model(&database.Review{}).Select(companyRatingStatsSQL).
+			Where("is_hidden = false").Group("company_id").Scan(&stats).Error
+		if err != nil {
+			return err
+		}
+
+		priorMean, err := r.platformMeanRating(tx)
+		if err != nil {
+			return err
+		}
+
+		// Компании без видимых отзывов получают среднюю оценку платформы
+		err = tx.Model(&database.CompanyDB{}).Where("1 = 1").
+			Update("ranking_score", priorMean).Error
+		if err != nil {
+			return err
+		}
+		for _, stat := range stats {
+			err = tx.Model(&database.CompanyDB{}).Where("id = ?", stat.CompanyID).
+				Updates(companyRatingColumns(stat, priorMean)).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *reviewRepository) platformMeanRating(tx *gorm.DB) (float64, error) {
+	var mean sql.NullFloat64
+	err := tx.Model(&database.Review{}).Select("AVG(rating)").
+		Where("is_hidden = false").Scan(&mean).Error
+	if err != nil {
+		return 0, err
+	}
+	if !mean.Valid {
+		return rating.DefaultPriorMean, nil
+	}
+	return mean.Float64, nil
+}
+
+func companyRatingColumns(stats companyRatingStats, priorMean float64) map[string]interface{} {
+	return map[string]interface{}{
+		"stars":                stats.AvgRating,
+		"review_count":         stats.Count,
+		"quality_rating":       stats.QualityRating,
+		"punctuality_rating":   stats.PunctualityRating,
+		"communication_rating": stats.CommunicationRating,
+		"value_rating":         stats.ValueRating,
+		"ranking_score":        rating.BayesianScore(stats.AvgRating, stats.Count, priorMean),
+	}
 }
 
 func (r *reviewRepository) GetCompanyAverageRating(companyID uint) (float64, int, error) {
@@ -83,12 +260,108 @@ func (r *reviewRepository) GetCompanyAverageRating(companyID uint) (float64, int
 		Count     int64
 	}
 
-	err := r.db.Model(&database.Review{}).Select("AVG(rating) as avg_rating, COUNT(*) as count").
-		Where("company_id = ?", companyID).Scan(&result).Error
+	err := r.db.Model(&database.Review{}).Select("COALESCE(AVG(rating), 0) as avg_rating, COUNT(*) as count").
+		Where("company_id = ? AND is_hidden = false", companyID).Scan(&result).Error
 	
 	return result.AvgRating, int(result.Count), err
 }
 
+func (r *reviewRepository) GetRecentByCompanyID(companyID uint, limit int) ([]database.Review, error) {
+	var reviews []database.Review
+	err := r.db.Preload("Client").Where("company_id = ? AND is_hidden = false", companyID).
+		Order("created_at DESC, id DESC").Limit(limit).Find(&reviews).Error
+	return reviews, err
+}
+
+// GetRatingDistribution возвращает число отзывов компании для каждой оценки
+func (r *reviewRepository) GetRatingDistribution(companyID uint) (map[int]int64, error) {
+	var rows []struct {
+		Rating int
+		Count  int64
+	}
+	err := r.db.Model(&database.Review{}).Select("rating, COUNT(*) as count").
+		Where("company_id = ? AND is_hidden = false", companyID).Group("rating").Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	distribution := make(map[int]int64, len(rows))
+	for _, row := range rows {
+		distribution[row.Rating] = row.Count
+	}
+	return distribution, nil
+}
+
+func reviewCursor(review database.Review) pagination.Cursor {
+	return pagination.Cursor{CreatedAt: review.CreatedAt, ID: review.ID}
+}
+
 func NewReviewRepository(db *gorm.DB) ReviewRepository {
 	return &reviewRepository{db: db}
 }
+
+// Статусы жалоб на отзывы
+const (
+	ReviewReportPending   = "pending"
+	ReviewReportHidden    = "hidden"
+	ReviewReportDismissed = "dismissed"
+)
+
+type ReviewReportRepository interface {
+	Create(report *database.ReviewReport) error
+	GetByID(id uint) (*database.ReviewReport, error)
+	Update(report *database.ReviewReport) error
+	// ExistsPending проверяет, есть ли у пользователя нерассмотренная жалоба на отзыв
+	ExistsPending(reviewID, reporterID uint, reporterType string) (bool, error)
+	// GetQueue возвращает жалобы с указанным статусом вместе с отзывами
+	GetQueue(status string, page pagination.Request) ([]database.ReviewReport, pagination.Page, error)
+}
+
+type reviewReportRepository struct {
+	db *gorm.DB
+}
+
+func (r *reviewReportRepository) Create(report *database.ReviewReport) error {
+	return r.db.Create(report).Error
+}
+
+func (r *reviewReportRepository) GetByID(id uint) (*database.ReviewReport, error) {
+	var report database.ReviewReport
+	err := r.db.Preload("Review.Client").First(&report, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+func (r *reviewReportRepository) Update(report *database.ReviewReport) error {
+	return r.db.Omit("Review").Save(report).Error
+}
+
+func (r *reviewReportRepository) ExistsPending(reviewID, reporterID uint, reporterType string) (bool, error) {
+	var count int64
+	err := r.db.Model(&database.ReviewReport{}).
+		Where("review_id = ? AND reporter_id = ? AND reporter_type = ? AND status = ?",
+			reviewID, reporterID, reporterType, ReviewReportPending).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *reviewReportRepository) GetQueue(status string, page pagination.Request) ([]database.ReviewReport, pagination.Page, error) {
+	var reports []database.ReviewReport
+	query := r.db.Preload("Review.Client").Where("status = ?", status)
+	err := pagination.Apply(query, page).Find(&reports).Error
+	if err != nil {
+		return nil, pagination.Page{}, err
+	}
+	reports, next := pagination.Trim(reports, page, reviewReportCursor)
+	return reports, next, nil
+}
+
+func reviewReportCursor(report database.ReviewReport) pagination.Cursor {
+	return pagination.Cursor{CreatedAt: report.CreatedAt, ID: report.ID}
+}
+
+func NewReviewReportRepository(db *gorm.DB) ReviewReportRepository {
+	return &reviewReportRepository{db: db}
+}
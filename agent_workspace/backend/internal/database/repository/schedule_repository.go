@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"core/internal/database"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type ScheduleRepository interface {
+	GetWorkingHours(companyID uint) ([]database.WorkingHours, error)
+	ReplaceWorkingHours(companyID uint, hours []database.WorkingHours) error
+	GetBlackoutDates(companyID uint, from, to time.Time) ([]database.BlackoutDate, error)
+	GetBlackoutDateByID(id uint) (*database.BlackoutDate, error)
+	CreateBlackoutDate(blackout *database.BlackoutDate) error
+	DeleteBlackoutDate(id uint) error
+	GetBookedOrders(companyID uint, from, to time.Time) ([]database.Order, error)
+	CountBookedBetween(companyID uint, start, end time.Time) (int, error)
+
+	// Методы для работы с транзакциями
+	LockCompanyInTx(tx *gorm.DB, companyID uint) error
+	CountBookedBetweenInTx(tx *gorm.DB, companyID uint, start, end time.Time) (int, error)
+}
+
+type scheduleRepository struct {
+	db *gorm.DB
+}
+
+func (r *scheduleRepository) GetWorkingHours(companyID uint) ([]database.WorkingHours, error) {
+	var hours []database.WorkingHours
+	err := r.db.Where("company_id = ?", companyID).
+		Order("weekday ASC, start_minute ASC").Find(&hours).Error
+	return hours, err
+}
+
+func (r *scheduleRepository) ReplaceWorkingHours(companyID uint, hours []database.WorkingHours) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("company_id = ?", companyID).Delete(&database.WorkingHours{}).Error; err != nil {
+			return err
+		}
+		if len(hours) == 0 {
+			return nil
+		}
+		return tx.Create(&hours).Error
+	})
+}
+
+func (r *scheduleRepository) GetBlackoutDates(companyID uint, from, to time.Time) ([]database.BlackoutDate, error) {
+	var blackouts []database.BlackoutDate
+	err := r.db.Where("company_id = ? AND date >= ? AND date < ?", companyID, from, to).
+		Order("date ASC").Find(&blackouts).Error
+	return blackouts, err
+}
+
+func (r *scheduleRepository) GetBlackoutDateByID(id uint) (*database.BlackoutDate, error) {
+	var blackout database.BlackoutDate
+	err := r.db.First(&blackout, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("blackout date with ID %d not found", id)
+		}
+		return nil, err
+	}
+	return &blackout, nil
+}
+
+func (r *scheduleRepository) CreateBlackoutDate(blackout *database.BlackoutDate) error {
+	return r.db.Create(blackout).Error
+}
+
+func (r *scheduleRepository) DeleteBlackoutDate(id uint) error {
+	return r.db.Delete(&database.BlackoutDate{}, id).Error
+}
+
+func (r *scheduleRepository) GetBookedOrders(companyID uint, from, to time.Time) ([]database.Order, error) {
+	var orders []database.Order
+	err := r.db.Preload("Client").Preload("Card").
+		Where("company_id = ? AND scheduled_at IS NOT NULL AND status <> ?", companyID, "cancelled").
+		Where("scheduled_at < ? AND scheduled_end > ?", to, from).
+		Order("scheduled_at ASC").Find(&orders).Error
+	return orders, err
+}
+
+func (r *scheduleRepository) CountBookedBetween(companyID uint, start, end time.Time) (int, error) {
+	return r.CountBookedBetweenInTx(r.db, companyID, start, end)
+}
+
+// Методы для работы с транзакциями
+
+// LockCompanyInTx блокирует строку компании до конца транзакции, чтобы
+// параллельные бронирования одного слота выполнялись последовательно
+func (r *scheduleRepository) LockCompanyInTx(tx *gorm.DB, companyID uint) error {
+	var company database.CompanyDB
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id").First(&company, companyID).Error
+}
+
+func (r *scheduleRepository) CountBookedBetweenInTx(tx *gorm.DB, companyID uint, start, end time.Time) (int, error) {
+	var count int64
+	err := tx.Model(&database.Order{}).
+		Where("company_id = ? AND status <> ?", companyID, "cancelled").
+		Where("scheduled_at < ? AND scheduled_end > ?", end, start).
+		Count(&count).Error
+	return int(count), err
+}
+
+func NewScheduleRepository(db *gorm.DB) ScheduleRepository {
+	return &scheduleRepository{db: db}
+}
@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"core/internal/database"
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type TwoFactorRepository interface {
+	GetByUser(userID uint, userType string) (*database.TwoFactor, error)
+	Save(twoFactor *database.TwoFactor) error
+	CountUnusedRecoveryCodes(userID uint, userType string) (int64, error)
+	CreateTrustedDevice(device *database.TrustedDevice) error
+	// TouchTrustedDevice отмечает вход с доверенного устройства. Возвращает ошибку, если
+	// устройство не найдено или срок доверия истек
+	TouchTrustedDevice(userID uint, userType, tokenHash string, at time.Time) error
+	ListTrustedDevices(userID uint, userType string, at time.Time) ([]database.TrustedDevice, error)
+	DeleteTrustedDevice(userID uint, userType string, deviceID uint) error
+
+	// Методы для работы с транзакциями
+	BeginTransaction() *gorm.DB
+	GetByUserForUpdateInTx(tx *gorm.DB, userID uint, userType string) (*database.TwoFactor, error)
+	EnableInTx(tx *gorm.DB, twoFactorID uint, at time.Time) error
+	// AcceptStepInTx запоминает шаг принятого кода. Код того же или более раннего шага
+	// уже использован и не принимается
+	AcceptStepInTx(tx *gorm.DB, twoFactorID uint, step int64) error
+	ReplaceRecoveryCodesInTx(tx *gorm.DB, userID uint, userType string, codeHashes []string) error
+	ConsumeRecoveryCodeInTx(tx *gorm.DB, userID uint, userType, codeHash string, at time.Time) error
+	// DeleteAllInTx удаляет настройку, резервные коды и доверенные устройства пользователя
+	DeleteAllInTx(tx *gorm.DB, userID uint, userType string) error
+}
+
+type twoFactorRepository struct {
+	db *gorm.DB
+}
+
+func (r *twoFactorRepository) GetByUser(userID uint, userType string) (*database.TwoFactor, error) {
+	var twoFactor database.TwoFactor
+	err := r.db.Where("user_id = ? AND user_type = ?", userID, userType).First(&twoFactor).Error
+	if err != nil {
+		return nil, err
+	}
+	return &twoFactor, nil
+}
+
+func (r *twoFactorRepository) Save(twoFactor *database.TwoFactor) error {
+	return r.db.Save(twoFactor).Error
+}
+
+func (r *twoFactorRepository) CountUnusedRecoveryCodes(userID uint, userType string) (int64, error) {
+	var count int64
+	err := r.db.Model(&database.RecoveryCode{}).
+		Where("user_id = ? AND user_type = ? AND used_at IS NULL", userID, userType).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *twoFactorRepository) CreateTrustedDevice(device *database.TrustedDevice) error {
+	return r.db.Create(device).Error
+}
+
+func (r *twoFactorRepository) TouchTrustedDevice(userID uint, userType, tokenHash string, at time.Time) error {
+	result := r.db.Model(&database.TrustedDevice{}).
+		Where("user_id = ? AND user_type = ? AND token_hash = ? AND expires_at > ?", userID, userType, tokenHash, at).
+		Update("last_used_at", at)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("trusted device not found")
+	}
+	return nil
+}
+
+func (r *twoFactorRepository) ListTrustedDevices(userID uint, userType string, at time.Time) ([]database.TrustedDevice, error) {
+	var devices []database.TrustedDevice
+	err := r.db.Where("user_id = ? AND user_type = ? AND expires_at > ?", userID, userType, at).
+		Order("last_used_at DESC").
+		Find(&devices).Error
+	return devices, err
+}
+
+func (r *twoFactorRepository) DeleteTrustedDevice(userID uint, userType string, deviceID uint) error {
+	result := r.db.Where("id = ? AND user_id = ? AND user_type = ?", deviceID, userID, userType).
+		Delete(&database.TrustedDevice{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("trusted device not found")
+	}
+	return nil
+}
+
+func (r *twoFactorRepository) BeginTransaction() *gorm.DB {
+	return r.db.Begin()
+}
+
+func (r *twoFactorRepository) GetByUserForUpdateInTx(tx *gorm.DB, userID uint, userType string) (*database.TwoFactor, error) {
+	var twoFactor database.TwoFactor
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ? AND user_type = ?", userID, userType).
+		First(&twoFactor).Error
+	if err != nil {
+		return nil, err
+	}
+	return &twoFactor, nil
+}
+
+func (r *twoFactorRepository) EnableInTx(tx *gorm.DB, twoFactorID uint, at time.Time) error {
+	result := tx.Model(&database.TwoFactor{}).
+		Where("id = ? AND enabled_at IS NULL", twoFactorID).
+		Update("enabled_at", at)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("two-factor authentication is already enabled")
+	}
+	return nil
+}
+
+func (r *twoFactorRepository) AcceptStepInTx(tx *gorm.DB, twoFactorID uint, step int64) error {
+	result := tx.Model(&database.TwoFactor{}).
+		Where("id = ? AND last_used_step < ?", twoFactorID, step).
+		Update("last_used_step", step)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("code has already been used")
+	}
+	return nil
+}
+
+func (r *twoFactorRepository) ReplaceRecoveryCodesInTx(tx *gorm.DB, userID uint, userType string, codeHashes []string) error {
+	err := tx.Unscoped().Where("user_id = ? AND user_type = ?", userID, userType).
+		Delete(&database.RecoveryCode{}).Error
+	if err != nil {
+		return err
+	}
+
+	codes := make([]database.RecoveryCode, 0, len(codeHashes))
+	for _, hash := range codeHashes {
+		codes = append(codes, database.RecoveryCode{UserID: userID, UserType: userType, CodeHash: hash})
+	}
+	return tx.Create(&codes).Error
+}
+
+func (r *twoFactorRepository) ConsumeRecoveryCodeInTx(tx *gorm.DB, userID uint, userType, codeHash string, at time.Time) error {
+	result := tx.Model(&database.RecoveryCode{}).
+		Where("user_id = ? AND user_type = ? AND code_hash = ? AND used_at IS NULL", userID, userType, codeHash).
+		Update("used_at", at)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("invalid recovery code")
+	}
+	return nil
+}
+
+func (r *twoFactorRepository) DeleteAllInTx(tx *gorm.DB, userID uint, userType string) error {
+	// Удаляем без мягкого удаления: иначе уникальный индекс не даст подключить 2FA заново
+	for _, model := range []interface{}{&database.TwoFactor{}, &database.RecoveryCode{}, &database.TrustedDevice{}} {
+		err := tx.Unscoped().Where("user_id = ? AND user_type = ?", userID, userType).Delete(model).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func NewTwoFactorRepository(db *gorm.DB) TwoFactorRepository {
+	return &twoFactorRepository{db: db}
+}
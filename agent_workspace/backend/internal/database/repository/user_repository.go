@@ -0,0 +1,215 @@
+package repository
+
+import (
+	"core/internal/database"
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"strings"
+)
+
+type UserRepository interface {
+	GetByID(id uint) (*database.UserDB, error)
+	// GetByEmail ищет пользователя по email без учета регистра
+	GetByEmail(email string) (*database.UserDB, error)
+	ExistsByEmail(email string) bool
+	Update(userID uint, updates map[string]interface{}) error
+	// CreateWithClient сохраняет клиента и пользователя с этим клиентским профилем
+	CreateWithClient(user *database.UserDB, client *database.ClientDB) error
+	// CreateWithCompany сохраняет компанию и пользователя, который становится ее владельцем
+	CreateWithCompany(user *database.UserDB, company *database.CompanyDB) error
+	// AddClient создает клиентский профиль пользователю, у которого его еще нет
+	AddClient(userID uint, client *database.ClientDB) error
+	// AddCompany создает компанию, владельцем которой становится пользователь
+	AddCompany(userID uint, company *database.CompanyDB) error
+
+	ListMemberships(userID uint) ([]database.CompanyMembership, error)
+	GetMembership(userID, companyID uint) (*database.CompanyMembership, error)
+	ListCompanyMembers(companyID uint) ([]database.CompanyMembership, error)
+	GetByIDs(ids []uint) ([]database.UserDB, error)
+	CreateMembership(membership *database.CompanyMembership) error
+	// UpdateMembershipRole меняет роль сотрудника. Последнего владельца понизить нельзя
+	UpdateMembershipRole(companyID, userID uint, role string) error
+	// DeleteMembership исключает сотрудника из компании. Последнего владельца исключить нельзя
+	DeleteMembership(companyID, userID uint) error
+}
+
+type userRepository struct {
+	db *gorm.DB
+}
+
+func (r *userRepository) GetByID(id uint) (*database.UserDB, error) {
+	var user database.UserDB
+	if err := r.db.Where("id = ?", id).First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *userRepository) GetByEmail(email string) (*database.UserDB, error) {
+	var user database.UserDB
+	err := r.db.Where("LOWER(email) = ?", strings.ToLower(strings.TrimSpace(email))).First(&user).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *userRepository) ExistsByEmail(email string) bool {
+	_, err := r.GetByEmail(email)
+	return err == nil
+}
+
+func (r *userRepository) Update(userID uint, updates map[string]interface{}) error {
+	return r.db.Model(&database.UserDB{}).Where("id = ?", userID).Updates(updates).Error
+}
+
+func (r *userRepository) CreateWithClient(user *database.UserDB, client *database.ClientDB) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(client).Error; err != nil {
+			return err
+		}
+		user.ClientID = &client.ID
+		return tx.Create(user).Error
+	})
+}
+
+func (r *userRepository) CreateWithCompany(user *database.UserDB, company *database.CompanyDB) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(company).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		return tx.Create(&database.CompanyMembership{UserID: user.ID, CompanyID: company.ID, Role: database.RoleOwner}).Error
+	})
+}
+
+func (r *userRepository) AddClient(userID uint, client *database.ClientDB) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(client).Error; err != nil {
+			return err
+		}
+		result := tx.Model(&database.UserDB{}).
+			Where("id = ? AND client_id IS NULL", userID).
+			Update("client_id", client.ID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("user already has a client account")
+		}
+		return nil
+	})
+}
+
+func (r *userRepository) AddCompany(userID uint, company *database.CompanyDB) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(company).Error; err != nil {
+			return err
+		}
+		return tx.Create(&database.CompanyMembership{UserID: userID, CompanyID: company.ID, Role: database.RoleOwner}).Error
+	})
+}
+
+func (r *userRepository) ListMemberships(userID uint) ([]database.CompanyMembership, error) {
+	var memberships []database.CompanyMembership
+	err := r.db.Where("user_id = ?", userID).Order("id ASC").Find(&memberships).Error
+	return memberships, err
+}
+
+func (r *userRepository) GetMembership(userID, companyID uint) (*database.CompanyMembership, error) {
+	var membership database.CompanyMembership
+	err := r.db.Where("user_id = ? AND company_id = ?", userID, companyID).First(&membership).Error
+	if err != nil {
+		return nil, err
+	}
+	return &membership, nil
+}
+
+func (r *userRepository) ListCompanyMembers(companyID uint) ([]database.CompanyMembership, error) {
+	var memberships []database.CompanyMembership
+	err := r.db.Where("company_id = ?", companyID).Order("id ASC").Find(&memberships).Error
+	return memberships, err
+}
+
+func (r *userRepository) GetByIDs(ids []uint) ([]database.UserDB, error) {
+	var users []database.UserDB
+	if len(ids) == 0 {
+		return users, nil
+	}
+	err := r.db.Where("id IN ?", ids).Find(&users).Error
+	return users, err
+}
+
+func (r *userRepository) CreateMembership(membership *database.CompanyMembership) error {
+	return r.db.Create(membership).Error
+}
+
+func (r *userRepository) UpdateMembershipRole(companyID, userID uint, role string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		membership, err := lockMembershipInTx(tx, companyID, userID)
+		if err != nil {
+			return err
+		}
+		if membership.Role == database.RoleOwner && role != database.RoleOwner {
+			if err := ensureAnotherOwnerInTx(tx, companyID); err != nil {
+				return err
+			}
+		}
+		return tx.Model(membership).Update("role", role).Error
+	})
+}
+
+func (r *userRepository) DeleteMembership(companyID, userID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		membership, err := lockMembershipInTx(tx, companyID, userID)
+		if err != nil {
+			return err
+		}
+		if membership.Role == database.RoleOwner {
+			if err := ensureAnotherOwnerInTx(tx, companyID); err != nil {
+				return err
+			}
+		}
+		// Без мягкого удаления: иначе сотрудника нельзя добавить повторно из-за уникального индекса
+		return tx.Unscoped().Delete(membership).Error
+	})
+}
+
+// lockMembershipInTx блокирует всех сотрудников компании, чтобы параллельные изменения
+// ролей не оставили компанию без владельца, и возвращает членство userID
+func lockMembershipInTx(tx *gorm.DB, companyID, userID uint) (*database.CompanyMembership, error) {
+	var memberships []database.CompanyMembership
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("company_id = ?", companyID).
+		Find(&memberships).Error
+	if err != nil {
+		return nil, err
+	}
+	for i := range memberships {
+		if memberships[i].UserID == userID {
+			return &memberships[i], nil
+		}
+	}
+	return nil, errors.New("member not found")
+}
+
+func ensureAnotherOwnerInTx(tx *gorm.DB, companyID uint) error {
+	var owners int64
+	err := tx.Model(&database.CompanyMembership{}).
+		Where("company_id = ? AND role = ?", companyID, database.RoleOwner).
+		Count(&owners).Error
+	if err != nil {
+		return err
+	}
+	if owners < 2 {
+		return errors.New("company must keep at least one owner")
+	}
+	return nil
+}
+
+func NewUserRepository(db *gorm.DB) UserRepository {
+	return &userRepository{db: db}
+}
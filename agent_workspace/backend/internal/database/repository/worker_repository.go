@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"core/internal/database"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+)
+
+type WorkerRepository interface {
+	Create(worker *database.Worker) error
+	GetByID(id uint) (*database.Worker, error)
+	GetByEmail(email string) (*database.Worker, error)
+	GetByCompanyID(companyID uint) ([]database.Worker, error)
+	ExistsByEmail(email string) (bool, error)
+	Update(worker *database.Worker) error
+}
+
+type workerRepository struct {
+	db *gorm.DB
+}
+
+func (r *workerRepository) Create(worker *database.Worker) error {
+	return r.db.Create(worker).Error
+}
+
+func (r *workerRepository) GetByID(id uint) (*database.Worker, error) {
+	var worker database.Worker
+	err := r.db.First(&worker, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("worker with ID %d not found", id)
+		}
+		return nil, err
+	}
+	return &worker, nil
+}
+
+func (r *workerRepository) GetByEmail(email string) (*database.Worker, error) {
+	var worker database.Worker
+	err := r.db.Where("email = ?", email).First(&worker).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("worker not found")
+		}
+		return nil, err
+	}
+	return &worker, nil
+}
+
+func (r *workerRepository) GetByCompanyID(companyID uint) ([]database.Worker, error) {
+	var workers []database.Worker
+	err := r.db.Where("company_id = ?", companyID).Order("full_name ASC").Find(&workers).Error
+	return workers, err
+}
+
+func (r *workerRepository) ExistsByEmail(email string) (bool, error) {
+	var count int64
+	err := r.db.Model(&database.Worker{}).Where("email = ?", email).Count(&count).Error
+	return count > 0, err
+}
+
+func (r *workerRepository) Update(worker *database.Worker) error {
+	return r.db.Save(worker).Error
+}
+
+func NewWorkerRepository(db *gorm.DB) WorkerRepository {
+	return &workerRepository{db: db}
+}
@@ -0,0 +1,38 @@
+package database
+
+import "gorm.io/gorm"
+
+// cardSearchMigrations добавляют к карточкам поисковый вектор и индексы.
+// Вектор собирается сразу по русской и английской конфигурациям, чтобы находились
+// словоформы на обоих языках, а триграммный индекс по названию ловит опечатки
+var cardSearchMigrations = []string{
+	`CREATE EXTENSION IF NOT EXISTS pg_trgm`,
+	`ALTER TABLE cards ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+		setweight(to_tsvector('russian', coalesce(title, '')), 'A') ||
+		setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+		setweight(to_tsvector('russian', coalesce(description, '')), 'B') ||
+		setweight(to_tsvector('english', coalesce(description, '')), 'B') ||
+		setweight(to_tsvector('simple', coalesce(category, '') || ' ' || coalesce(location, '')), 'C')
+	) STORED`,
+	`CREATE INDEX IF NOT EXISTS idx_cards_search_vector ON cards USING GIN (search_vector)`,
+	`CREATE INDEX IF NOT EXISTS idx_cards_title_trgm ON cards USING GIN (title gin_trgm_ops)`,
+}
+
+// SetupCardSearch подготавливает таблицу карточек к полнотекстовому поиску.
+// Вызывается после AutoMigrate, повторный запуск безопасен
+func SetupCardSearch(db *gorm.DB) error {
+	for _, statement := range cardSearchMigrations {
+		if err := db.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HasPostGIS проверяет, установлено ли расширение PostGIS. Без него расстояния
+// считаются по формуле гаверсинусов на чистом SQL
+func HasPostGIS(db *gorm.DB) bool {
+	var installed bool
+	err := db.Raw("SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'postgis')").Scan(&installed).Error
+	return err == nil && installed
+}
@@ -0,0 +1,122 @@
+package database
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"strings"
+	"time"
+)
+
+// Роли пользователя: клиентский профиль и роли в компании, см. CompanyMembership
+const (
+	RoleClient     = "client"
+	RoleOwner      = "owner"
+	RoleManager    = "manager"
+	RoleAccountant = "accountant"
+)
+
+// MigrateUsers создает пользователей для клиентов и компаний, зарегистрированных до
+// появления UserDB. Клиент становится пользователем с клиентским профилем, компания -
+// пользователем с ролью owner. Если пользователь с таким email уже есть, профиль или
+// компания добавляются ему, пароль остается прежним. Пароль, подтверждение email,
+// неиспользованные ссылки из писем и настройка 2FA переходят к пользователю.
+// Вызывается после AutoMigrate, повторный запуск обрабатывает только новые записи
+func MigrateUsers(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var clients []ClientDB
+		err := tx.Where("id NOT IN (?)", tx.Model(&UserDB{}).Where("client_id IS NOT NULL").Select("client_id")).
+			Find(&clients).Error
+		if err != nil {
+			return err
+		}
+		for _, client := range clients {
+			user, err := findOrCreateUser(tx, client.Email, client.FullName, client.PasswordHash, client.EmailVerifiedAt)
+			if err != nil {
+				return err
+			}
+			// У пользователя уже есть клиентский профиль: второй не привязывается
+			if user.ClientID != nil {
+				continue
+			}
+			if err := tx.Model(user).Update("client_id", client.ID).Error; err != nil {
+				return err
+			}
+			if err := moveAccountSecurity(tx, client.ID, "client", user.ID); err != nil {
+				return err
+			}
+		}
+
+		var companies []CompanyDB
+		err = tx.Where("id NOT IN (?)", tx.Model(&CompanyMembership{}).Select("company_id")).Find(&companies).Error
+		if err != nil {
+			return err
+		}
+		for _, company := range companies {
+			name := company.FullName
+			if name == "" {
+				name = company.CompanyName
+			}
+			user, err := findOrCreateUser(tx, company.Email, name, company.PasswordHash, company.EmailVerifiedAt)
+			if err != nil {
+				return err
+			}
+			err = tx.Create(&CompanyMembership{UserID: user.ID, CompanyID: company.ID, Role: RoleOwner}).Error
+			if err != nil {
+				return err
+			}
+			if err := moveAccountSecurity(tx, company.ID, "company", user.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func findOrCreateUser(tx *gorm.DB, email, fullName, passwordHash string, emailVerifiedAt *time.Time) (*UserDB, error) {
+	var user UserDB
+	err := tx.Where("LOWER(email) = ?", strings.ToLower(strings.TrimSpace(email))).First(&user).Error
+	if err == nil {
+		return &user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	user = UserDB{
+		Email:           strings.TrimSpace(email),
+		FullName:        fullName,
+		PasswordHash:    passwordHash,
+		EmailVerifiedAt: emailVerifiedAt,
+	}
+	if err := tx.Create(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// moveAccountSecurity передает пользователю 2FA, резервные коды, доверенные устройства
+// и неиспользованные ссылки из писем учетной записи. Если у пользователя 2FA уже
+// настроена, настройка второй учетной записи не переносится
+func moveAccountSecurity(tx *gorm.DB, accountID uint, accountType string, userID uint) error {
+	owned := func(model interface{}) *gorm.DB {
+		return tx.Model(model).Where("user_id = ? AND user_type = ?", accountID, accountType)
+	}
+	moved := map[string]interface{}{"user_id": userID, "user_type": "user"}
+
+	err := owned(&AuthToken{}).Where("used_at IS NULL").Updates(moved).Error
+	if err != nil {
+		return err
+	}
+
+	var existing int64
+	err = tx.Model(&TwoFactor{}).Where("user_id = ? AND user_type = ?", userID, "user").Count(&existing).Error
+	if err != nil || existing > 0 {
+		return err
+	}
+	for _, model := range []interface{}{&TwoFactor{}, &RecoveryCode{}, &TrustedDevice{}} {
+		if err := owned(model).Updates(moved).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package database
+
+import (
+	"fmt"
+	"gorm.io/gorm"
+)
+
+// MigrateEmailVerification добавляет колонку email_verified_at и считает email уже
+// зарегистрированных пользователей подтвержденным, чтобы они не потеряли доступ.
+// Вызывается до AutoMigrate, повторный запуск ничего не меняет: колонка уже есть
+func MigrateEmailVerification(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, table := range []string{"client_dbs", "company_dbs"} {
+			var tables, columns int64
+			err := tx.Raw(`SELECT COUNT(*) FROM information_schema.tables
+				WHERE table_schema = current_schema() AND table_name = ?`, table).Scan(&tables).Error
+			if err != nil {
+				return err
+			}
+			// Новая база: таблицу с колонкой создаст AutoMigrate
+			if tables == 0 {
+				continue
+			}
+
+			err = tx.Raw(`SELECT COUNT(*) FROM information_schema.columns
+				WHERE table_schema = current_schema() AND table_name = ? AND column_name = 'email_verified_at'`,
+				table).Scan(&columns).Error
+			if err != nil {
+				return err
+			}
+			if columns > 0 {
+				continue
+			}
+
+			statements := []string{
+				fmt.Sprintf("ALTER TABLE %s ADD COLUMN email_verified_at timestamptz", table),
+				fmt.Sprintf("UPDATE %s SET email_verified_at = created_at", table),
+			}
+			for _, statement := range statements {
+				if err := tx.Exec(statement).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,164 @@
+// Package export записывает табличные данные в CSV, XLSX и NDJSON построчно, не держа
+// всю выгрузку в памяти
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Поддерживаемые форматы выгрузки
+const (
+	FormatCSV    = "csv"
+	FormatXLSX   = "xlsx"
+	FormatNDJSON = "ndjson"
+)
+
+var contentTypes = map[string]string{
+	FormatCSV:    "text/csv; charset=utf-8",
+	FormatXLSX:   "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	FormatNDJSON: "application/x-ndjson",
+}
+
+// Writer построчная запись выгрузки. Значения ячеек: string, float64, int64, uint, *uint,
+// time.Time, *time.Time и nil. Close дописывает окончание файла, но не закрывает
+// нижележащий io.Writer
+type Writer interface {
+	WriteRow(values []interface{}) error
+	Close() error
+}
+
+// IsSupported сообщает, поддерживается ли формат
+func IsSupported(format string) bool {
+	_, ok := contentTypes[format]
+	return ok
+}
+
+// ContentType возвращает MIME-тип файла в формате format
+func ContentType(format string) string {
+	return contentTypes[format]
+}
+
+// NewWriter создает запись в формате format с колонками columns
+func NewWriter(format string, w io.Writer, columns []string) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w, columns)
+	case FormatXLSX:
+		return newXLSXWriter(w, columns)
+	case FormatNDJSON:
+		return &ndjsonWriter{w: w, columns: columns}, nil
+	}
+	return nil, errors.New("unsupported export format")
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func newCSVWriter(w io.Writer, columns []string) (Writer, error) {
+	// BOM нужен, чтобы Excel открыл кириллицу в UTF-8 без мастера импорта
+	if _, err := io.WriteString(w, "\ufeff"); err != nil {
+		return nil, err
+	}
+	writer := &csvWriter{w: csv.NewWriter(w)}
+	if err := writer.w.Write(columns); err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+func (c *csvWriter) WriteRow(values []interface{}) error {
+	record := make([]string, len(values))
+	for i, value := range values {
+		record[i] = formatValue(value)
+	}
+	return c.w.Write(record)
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type ndjsonWriter struct {
+	w       io.Writer
+	columns []string
+	buf     bytes.Buffer
+}
+
+// WriteRow записывает строку объектом JSON с ключами в порядке колонок
+func (n *ndjsonWriter) WriteRow(values []interface{}) error {
+	n.buf.Reset()
+	n.buf.WriteByte('{')
+	for i, column := range n.columns {
+		if i > 0 {
+			n.buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(column)
+		n.buf.Write(key)
+		n.buf.WriteByte(':')
+
+		var value interface{}
+		if i < len(values) {
+			value = values[i]
+		}
+		switch v := value.(type) {
+		case time.Time, *time.Time:
+			value = nullableString(v)
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		n.buf.Write(encoded)
+	}
+	n.buf.WriteString("}\n")
+	_, err := n.w.Write(n.buf.Bytes())
+	return err
+}
+
+func (n *ndjsonWriter) Close() error {
+	return nil
+}
+
+// formatValue переводит значение ячейки в строку. Суммы записываются с двумя знаками
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', 2, 64)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint:
+		return strconv.FormatUint(uint64(v), 10)
+	case *uint:
+		if v == nil {
+			return ""
+		}
+		return strconv.FormatUint(uint64(*v), 10)
+	case time.Time:
+		return v.Format(time.RFC3339)
+	case *time.Time:
+		if v == nil {
+			return ""
+		}
+		return v.Format(time.RFC3339)
+	}
+	return ""
+}
+
+func nullableString(value interface{}) interface{} {
+	if formatted := formatValue(value); formatted != "" {
+		return formatted
+	}
+	return nil
+}
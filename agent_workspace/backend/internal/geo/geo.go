@@ -0,0 +1,78 @@
+package geo
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const earthRadiusKm = 6371.0
+
+// Point координаты в градусах
+type Point struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// Validate проверяет, что координаты лежат в допустимых пределах
+func (p Point) Validate() error {
+	if p.Latitude < -90 || p.Latitude > 90 || p.Longitude < -180 || p.Longitude > 180 {
+		return errors.New("invalid coordinates")
+	}
+	return nil
+}
+
+// DistanceKm расстояние между точками по формуле гаверсинусов
+func DistanceKm(a, b Point) float64 {
+	lat1, lat2 := a.Latitude*math.Pi/180, b.Latitude*math.Pi/180
+	dLat := lat2 - lat1
+	dLon := (b.Longitude - a.Longitude) * math.Pi / 180
+	h := math.Pow(math.Sin(dLat/2), 2) + math.Cos(lat1)*math.Cos(lat2)*math.Pow(math.Sin(dLon/2), 2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// FormatPolygon переводит вершины в текстовый формат типа polygon Postgres: ((x,y),...),
+// где x - долгота, y - широта
+func FormatPolygon(points []Point) (string, error) {
+	if len(points) < 3 {
+		return "", errors.New("service area polygon must have at least 3 points")
+	}
+	vertices := make([]string, 0, len(points))
+	for _, point := range points {
+		if err := point.Validate(); err != nil {
+			return "", err
+		}
+		vertices = append(vertices, fmt.Sprintf("(%s,%s)",
+			strconv.FormatFloat(point.Longitude, 'f', -1, 64),
+			strconv.FormatFloat(point.Latitude, 'f', -1, 64)))
+	}
+	return "(" + strings.Join(vertices, ",") + ")", nil
+}
+
+// ParsePolygon разбирает строку, созданную FormatPolygon
+func ParsePolygon(value string) ([]Point, error) {
+	value = strings.TrimSpace(value)
+	if !strings.HasPrefix(value, "((") || !strings.HasSuffix(value, "))") {
+		return nil, errors.New("invalid polygon format")
+	}
+
+	var points []Point
+	for _, vertex := range strings.Split(value[2:len(value)-2], "),(") {
+		coordinates := strings.Split(vertex, ",")
+		if len(coordinates) != 2 {
+			return nil, errors.New("invalid polygon format")
+		}
+		longitude, err := strconv.ParseFloat(strings.TrimSpace(coordinates[0]), 64)
+		if err != nil {
+			return nil, errors.New("invalid polygon format")
+		}
+		latitude, err := strconv.ParseFloat(strings.TrimSpace(coordinates[1]), 64)
+		if err != nil {
+			return nil, errors.New("invalid polygon format")
+		}
+		points = append(points, Point{Latitude: latitude, Longitude: longitude})
+	}
+	return points, nil
+}
@@ -0,0 +1,54 @@
+package geo
+
+import (
+	"errors"
+	"strings"
+)
+
+var ErrAddressNotFound = errors.New("address not found")
+
+// Geocoder определяет координаты по адресу
+type Geocoder interface {
+	Geocode(address string) (*Point, error)
+}
+
+type stubGeocoder struct {
+	places map[string]Point
+}
+
+// NewStubGeocoder создает геокодер без внешних сервисов. Он знает только центры
+// крупных городов и подходит для разработки и офлайн окружений
+func NewStubGeocoder() Geocoder {
+	return &stubGeocoder{places: map[string]Point{
+		"томск":            {Latitude: 56.4847, Longitude: 84.9482},
+		"tomsk":            {Latitude: 56.4847, Longitude: 84.9482},
+		"северск":          {Latitude: 56.6031, Longitude: 84.8809},
+		"новосибирск":      {Latitude: 55.0302, Longitude: 82.9204},
+		"novosibirsk":      {Latitude: 55.0302, Longitude: 82.9204},
+		"кемерово":         {Latitude: 55.3547, Longitude: 86.0873},
+		"барнаул":          {Latitude: 53.3481, Longitude: 83.7798},
+		"красноярск":       {Latitude: 56.0153, Longitude: 92.8932},
+		"омск":             {Latitude: 54.9893, Longitude: 73.3682},
+		"екатеринбург":     {Latitude: 56.8389, Longitude: 60.6057},
+		"москва":           {Latitude: 55.7558, Longitude: 37.6173},
+		"moscow":           {Latitude: 55.7558, Longitude: 37.6173},
+		"санкт-петербург":  {Latitude: 59.9311, Longitude: 30.3609},
+		"saint petersburg": {Latitude: 59.9311, Longitude: 30.3609},
+	}}
+}
+
+// Geocode ищет в адресе самое длинное известное название
+func (g *stubGeocoder) Geocode(address string) (*Point, error) {
+	normalized := strings.ToLower(address)
+	var found string
+	for name := range g.places {
+		if strings.Contains(normalized, name) && len(name) > len(found) {
+			found = name
+		}
+	}
+	if found == "" {
+		return nil, ErrAddressNotFound
+	}
+	point := g.places[found]
+	return &point, nil
+}
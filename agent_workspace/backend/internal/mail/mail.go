@@ -0,0 +1,96 @@
+package mail
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"mime"
+	"net/smtp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Message письмо с текстом без разметки
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender отправляет письма. Реализация выбирается переменной MAIL_DRIVER
+type Sender interface {
+	Send(message Message) error
+}
+
+type logSender struct{}
+
+// NewLogSender создает отправителя, который пишет письма в журнал сервера вместо отправки.
+// Подходит для разработки: ссылки из писем видны в логе
+func NewLogSender() Sender {
+	return &logSender{}
+}
+
+func (s *logSender) Send(message Message) error {
+	log.Printf("mail to %s: %s\n%s", message.To, message.Subject, message.Body)
+	return nil
+}
+
+type smtpSender struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+// NewSMTPSender создает отправителя через SMTP сервер. Без username письма отправляются
+// без авторизации, например через локальный релей
+func NewSMTPSender(host string, port int, username, password, from string) Sender {
+	sender := &smtpSender{addr: host + ":" + strconv.Itoa(port), from: from}
+	if username != "" {
+		sender.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return sender
+}
+
+func (s *smtpSender) Send(message Message) error {
+	return smtp.SendMail(s.addr, s.auth, s.from, []string{message.To}, buildMessage(s.from, message))
+}
+
+// Outbox сохраняет письма в памяти. Используется в тестах и локальных сценариях,
+// где нужно прочитать ссылку из отправленного письма
+type Outbox struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+func NewOutbox() *Outbox {
+	return &Outbox{}
+}
+
+func (o *Outbox) Send(message Message) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.messages = append(o.messages, message)
+	return nil
+}
+
+// Messages возвращает копию отправленных писем в порядке отправки
+func (o *Outbox) Messages() []Message {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]Message(nil), o.messages...)
+}
+
+// buildMessage собирает письмо в формате RFC 5322. Тема кодируется, так как письма на русском
+func buildMessage(from string, message Message) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", message.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", message.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	buf.WriteString(message.Body)
+	return buf.Bytes()
+}
@@ -0,0 +1,98 @@
+package money
+
+import (
+	"strconv"
+	"strings"
+)
+
+// DefaultLocale локаль описаний транзакций и документов
+const DefaultLocale = "ru"
+
+// localeFormat правила записи сумм: разделители разрядов и дробной части, положение знака валюты
+type localeFormat struct {
+	group        string
+	decimal      string
+	symbolBefore bool
+}
+
+var locales = map[string]localeFormat{
+	"ru": {group: " ", decimal: ",", symbolBefore: false},
+	"kk": {group: " ", decimal: ",", symbolBefore: false},
+	"be": {group: " ", decimal: ",", symbolBefore: false},
+	"de": {group: ".", decimal: ",", symbolBefore: false},
+	"en": {group: ",", decimal: ".", symbolBefore: true},
+	"zh": {group: ",", decimal: ".", symbolBefore: true},
+}
+
+// ParseLocale выбирает поддерживаемую локаль из заголовка Accept-Language или кода
+// языка. Неизвестные локали заменяются на DefaultLocale
+func ParseLocale(value string) string {
+	for _, part := range strings.Split(value, ",") {
+		tag := strings.TrimSpace(strings.SplitN(part, ";", 2)[0])
+		language := strings.ToLower(strings.SplitN(strings.ReplaceAll(tag, "_", "-"), "-", 2)[0])
+		if _, ok := locales[language]; ok {
+			return language
+		}
+	}
+	return DefaultLocale
+}
+
+// Format записывает сумму по правилам локали со знаком валюты: "1 234,50 ₽", "$1,234.50"
+func Format(amount Money, locale string) string {
+	format, ok := locales[locale]
+	if !ok {
+		format = locales[DefaultLocale]
+	}
+	symbol := amount.Currency
+	if currency, ok := currencies[amount.Currency]; ok {
+		symbol = currency.Symbol
+	}
+
+	number := formatNumber(amount.Amount, format)
+	if format.symbolBefore {
+		if strings.HasPrefix(number, "-") {
+			return "-" + symbol + number[1:]
+		}
+		return symbol + number
+	}
+	return number + " " + symbol
+}
+
+// FormatCode записывает сумму по правилам локали с кодом валюты: "1 234,50 RUB".
+// Подходит для описаний транзакций и документов, шрифты которых не содержат знаков валют
+func FormatCode(amount Money, locale string) string {
+	return FormatNumber(amount.Amount, locale) + " " + amount.Currency
+}
+
+// FormatNumber записывает сумму по правилам локали без валюты: "1 234,50"
+func FormatNumber(amount Minor, locale string) string {
+	format, ok := locales[locale]
+	if !ok {
+		format = locales[DefaultLocale]
+	}
+	return formatNumber(amount, format)
+}
+
+func formatNumber(amount Minor, format localeFormat) string {
+	text := amount.String()
+	sign := ""
+	if strings.HasPrefix(text, "-") {
+		sign = "-"
+		text = text[1:]
+	}
+	whole, fraction, _ := strings.Cut(text, ".")
+
+	var grouped strings.Builder
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			grouped.WriteString(format.group)
+		}
+		grouped.WriteRune(digit)
+	}
+	return sign + grouped.String() + format.decimal + fraction
+}
+
+// FormatRate записывает курс без лишних нулей
+func FormatRate(rate float64) string {
+	return strconv.FormatFloat(rate, 'f', -1, 64)
+}
@@ -0,0 +1,115 @@
+// Package money хранит суммы в целых минимальных единицах валюты, переводит их между
+// валютами по курсам из подключаемых источников и форматирует по правилам локали
+package money
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Scale число минимальных единиц в основной. Все поддерживаемые валюты делятся на 100
+const Scale = 100
+
+// DefaultCurrency валюта счетов и карточек, созданных до появления валют
+const DefaultCurrency = "RUB"
+
+// Minor сумма в минимальных единицах валюты: копейках, центах. В JSON записывается
+// в основных единицах, как до перехода на целые суммы: 1234.5
+type Minor int64
+
+// FromMajor переводит сумму в основных единицах в минимальные с округлением
+func FromMajor(value float64) Minor {
+	return Minor(math.Round(value * Scale))
+}
+
+// Major возвращает сумму в основных единицах
+func (m Minor) Major() float64 {
+	return float64(m) / Scale
+}
+
+// Percent возвращает percent процентов суммы с округлением до минимальной единицы
+func (m Minor) Percent(percent float64) Minor {
+	return Minor(math.Round(float64(m) * percent / 100))
+}
+
+// String возвращает сумму в основных единицах с двумя знаками: 1234.50
+func (m Minor) String() string {
+	sign := ""
+	value := int64(m)
+	if value < 0 {
+		sign = "-"
+		value = -value
+	}
+	fraction := strconv.FormatInt(value%Scale, 10)
+	if len(fraction) < 2 {
+		fraction = "0" + fraction
+	}
+	return sign + strconv.FormatInt(value/Scale, 10) + "." + fraction
+}
+
+func (m Minor) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+func (m *Minor) UnmarshalJSON(data []byte) error {
+	text := strings.Trim(string(data), `"`)
+	if text == "null" || text == "" {
+		*m = 0
+		return nil
+	}
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return errors.New("invalid amount")
+	}
+	*m = FromMajor(value)
+	return nil
+}
+
+// Money сумма с валютой
+type Money struct {
+	Amount   Minor
+	Currency string
+}
+
+func New(amount Minor, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// Currency валюта из справочника ISO 4217
+type Currency struct {
+	Code   string
+	Symbol string
+}
+
+var currencies = map[string]Currency{
+	"RUB": {Code: "RUB", Symbol: "₽"},
+	"USD": {Code: "USD", Symbol: "$"},
+	"EUR": {Code: "EUR", Symbol: "€"},
+	"KZT": {Code: "KZT", Symbol: "₸"},
+	"BYN": {Code: "BYN", Symbol: "Br"},
+	"CNY": {Code: "CNY", Symbol: "¥"},
+}
+
+// NormalizeCurrency приводит код валюты к верхнему регистру и проверяет, что валюта
+// поддерживается. Пустой код означает валюту по умолчанию
+func NormalizeCurrency(code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" {
+		return DefaultCurrency, nil
+	}
+	if _, ok := currencies[code]; !ok {
+		return "", errors.New("unsupported currency " + code)
+	}
+	return code, nil
+}
+
+// Currencies возвращает коды поддерживаемых валют
+func Currencies() []string {
+	codes := make([]string, 0, len(currencies))
+	for code := range currencies {
+		codes = append(codes, code)
+	}
+	return codes
+}
@@ -0,0 +1,82 @@
+package money
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// RateProvider источник курсов валют. Rate возвращает, сколько единиц валюты to стоит
+// одна единица валюты from. Внешние источники подключаются реализацией интерфейса
+type RateProvider interface {
+	Rate(from, to string) (float64, error)
+}
+
+// Convert переводит сумму по курсу rate с округлением до минимальной единицы
+func Convert(amount Minor, rate float64) Minor {
+	return Minor(math.Round(float64(amount) * rate))
+}
+
+// StaticProvider курсы из настроек без обращения к внешним сервисам. Курс каждой
+// валюты задается в базовой валюте, кросс-курсы вычисляются через нее
+type StaticProvider struct {
+	base  string
+	rates map[string]float64
+}
+
+// NewStaticProvider создает источник с курсами rates: стоимостью единицы валюты в base
+func NewStaticProvider(base string, rates map[string]float64) (*StaticProvider, error) {
+	base, err := NormalizeCurrency(base)
+	if err != nil {
+		return nil, err
+	}
+	normalized := map[string]float64{base: 1}
+	for code, rate := range rates {
+		code, err := NormalizeCurrency(code)
+		if err != nil {
+			return nil, err
+		}
+		if rate <= 0 {
+			return nil, errors.New("exchange rate must be positive for " + code)
+		}
+		normalized[code] = rate
+	}
+	return &StaticProvider{base: base, rates: normalized}, nil
+}
+
+func (p *StaticProvider) Rate(from, to string) (float64, error) {
+	if from == to {
+		return 1, nil
+	}
+	fromRate, ok := p.rates[from]
+	if !ok {
+		return 0, errors.New("no exchange rate for " + from)
+	}
+	toRate, ok := p.rates[to]
+	if !ok {
+		return 0, errors.New("no exchange rate for " + to)
+	}
+	return fromRate / toRate, nil
+}
+
+// ParseRates разбирает курсы вида "USD=92.5,EUR=100.1"
+func ParseRates(spec string) (map[string]float64, error) {
+	rates := map[string]float64{}
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		code, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, errors.New("invalid exchange rate " + pair + ", expected CODE=rate")
+		}
+		rate, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return nil, errors.New("invalid exchange rate " + pair)
+		}
+		rates[strings.TrimSpace(code)] = rate
+	}
+	return rates, nil
+}
@@ -0,0 +1,386 @@
+// Package oidc вход через внешних провайдеров OpenID Connect (authorization code flow с PKCE).
+// Адреса провайдера берутся из discovery-документа /.well-known/openid-configuration,
+// поэтому подходит любой провайдер, который его публикует: Google, Яндекс, Keycloak,
+// Azure AD, Okta и корпоративные IdP
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrInvalidIDToken ID token не прошел проверку подписи, издателя, получателя, срока или nonce
+var ErrInvalidIDToken = errors.New("the identity provider returned an invalid id token")
+
+// Config настройки провайдера. Name - идентификатор в API и в переменных окружения,
+// Title - название для кнопки входа. AllowedDomains ограничивает вход адресами этих
+// доменов, пустой список - без ограничений
+type Config struct {
+	Name           string
+	Title          string
+	Issuer         string
+	ClientID       string
+	ClientSecret   string
+	Scopes         []string
+	AllowedDomains []string
+}
+
+// Identity пользователь, подтвержденный провайдером. Subject постоянный идентификатор
+// пользователя у провайдера, email может меняться
+type Identity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// discovery нужные поля документа /.well-known/openid-configuration
+type discovery struct {
+	Issuer                string   `json:"issuer"`
+	AuthorizationEndpoint string   `json:"authorization_endpoint"`
+	TokenEndpoint         string   `json:"token_endpoint"`
+	UserinfoEndpoint      string   `json:"userinfo_endpoint"`
+	JWKSURI               string   `json:"jwks_uri"`
+	TokenAuthMethods      []string `json:"token_endpoint_auth_methods_supported"`
+}
+
+// keysRefreshInterval не чаще этого интервала ключи перезапрашиваются из-за неизвестного kid
+const keysRefreshInterval = time.Minute
+
+type Provider struct {
+	config      Config
+	redirectURL string
+	client      *http.Client
+
+	mu            sync.Mutex
+	discovery     *discovery
+	keys          map[string]interface{}
+	keysFetchedAt time.Time
+}
+
+// NewProvider создает провайдера. Discovery-документ загружается при первом входе,
+// поэтому недоступный провайдер не мешает запуску сервиса
+func NewProvider(config Config, redirectURL string) *Provider {
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{"openid", "email", "profile"}
+	}
+	if config.Title == "" {
+		config.Title = config.Name
+	}
+	return &Provider{
+		config:      config,
+		redirectURL: redirectURL,
+		client:      &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (p *Provider) Name() string {
+	return p.config.Name
+}
+
+func (p *Provider) Title() string {
+	return p.config.Title
+}
+
+// AllowsEmail проверяет, что email из разрешенного домена провайдера
+func (p *Provider) AllowsEmail(email string) bool {
+	if len(p.config.AllowedDomains) == 0 {
+		return true
+	}
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	domain := strings.ToLower(email[at+1:])
+	for _, allowed := range p.config.AllowedDomains {
+		if domain == strings.ToLower(strings.TrimSpace(allowed)) {
+			return true
+		}
+	}
+	return false
+}
+
+// AuthCodeURL адрес страницы входа провайдера. state возвращается в redirect без изменений,
+// nonce попадает в ID token, codeVerifier передается в Exchange (PKCE, S256)
+func (p *Provider) AuthCodeURL(state, nonce, codeVerifier string) (string, error) {
+	doc, err := p.loadDiscovery()
+	if err != nil {
+		return "", err
+	}
+	endpoint, err := url.Parse(doc.AuthorizationEndpoint)
+	if err != nil {
+		return "", err
+	}
+	query := endpoint.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", p.config.ClientID)
+	query.Set("redirect_uri", p.redirectURL)
+	query.Set("scope", strings.Join(p.config.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", CodeChallenge(codeVerifier))
+	query.Set("code_challenge_method", "S256")
+	endpoint.RawQuery = query.Encode()
+	return endpoint.String(), nil
+}
+
+// Exchange меняет код авторизации на токены и возвращает пользователя из проверенного
+// ID token. Если email в ID token нет, он запрашивается у userinfo
+func (p *Provider) Exchange(code, codeVerifier, nonce string) (*Identity, error) {
+	doc, err := p.loadDiscovery()
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.redirectURL},
+		"code_verifier": {codeVerifier},
+		"client_id":     {p.config.ClientID},
+	}
+	useBasic := p.config.ClientSecret != "" && supportsBasicAuth(doc.TokenAuthMethods)
+	if p.config.ClientSecret != "" && !useBasic {
+		form.Set("client_secret", p.config.ClientSecret)
+	}
+	request, err := http.NewRequest(http.MethodPost, doc.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+	if useBasic {
+		// RFC 6749, 2.3.1: идентификатор и секрет кодируются как form-urlencoded
+		request.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+	}
+
+	var tokens struct {
+		AccessToken string `json:"access_token"`
+		IDToken     string `json:"id_token"`
+	}
+	if err := p.doJSON(request, &tokens); err != nil {
+		return nil, fmt.Errorf("token exchange failed: %w", err)
+	}
+	if tokens.IDToken == "" {
+		return nil, ErrInvalidIDToken
+	}
+
+	identity, err := p.verifyIDToken(doc, tokens.IDToken, nonce)
+	if err != nil {
+		return nil, err
+	}
+	if identity.Email == "" && doc.UserinfoEndpoint != "" && tokens.AccessToken != "" {
+		if err := p.fillFromUserinfo(doc, tokens.AccessToken, identity); err != nil {
+			return nil, err
+		}
+	}
+	return identity, nil
+}
+
+type idTokenClaims struct {
+	jwt.RegisteredClaims
+	Nonce         string      `json:"nonce"`
+	Email         string      `json:"email"`
+	EmailVerified interface{} `json:"email_verified"`
+	Name          string      `json:"name"`
+}
+
+func (p *Provider) verifyIDToken(doc *discovery, raw, nonce string) (*Identity, error) {
+	claims := &idTokenClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims, p.keyFunc,
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384"}),
+		jwt.WithIssuer(doc.Issuer),
+		jwt.WithAudience(p.config.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+	if claims.Subject == "" || claims.Nonce != nonce {
+		return nil, ErrInvalidIDToken
+	}
+	return &Identity{
+		Subject:       claims.Subject,
+		Email:         strings.TrimSpace(claims.Email),
+		EmailVerified: isTrue(claims.EmailVerified),
+		Name:          claims.Name,
+	}, nil
+}
+
+func (p *Provider) fillFromUserinfo(doc *discovery, accessToken string, identity *Identity) error {
+	request, err := http.NewRequest(http.MethodGet, doc.UserinfoEndpoint, nil)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Authorization", "Bearer "+accessToken)
+	request.Header.Set("Accept", "application/json")
+
+	var info struct {
+		Subject       string      `json:"sub"`
+		Email         string      `json:"email"`
+		EmailVerified interface{} `json:"email_verified"`
+		Name          string      `json:"name"`
+	}
+	if err := p.doJSON(request, &info); err != nil {
+		return fmt.Errorf("userinfo request failed: %w", err)
+	}
+	// OIDC Core 5.3.2: ответ userinfo принимается, только если sub совпадает с ID token
+	if info.Subject != identity.Subject {
+		return ErrInvalidIDToken
+	}
+	identity.Email = strings.TrimSpace(info.Email)
+	identity.EmailVerified = isTrue(info.EmailVerified)
+	if identity.Name == "" {
+		identity.Name = info.Name
+	}
+	return nil
+}
+
+func (p *Provider) loadDiscovery() (*discovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	issuer := strings.TrimSuffix(p.config.Issuer, "/")
+	request, err := http.NewRequest(http.MethodGet, issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+	doc := &discovery{}
+	if err := p.doJSON(request, doc); err != nil {
+		return nil, fmt.Errorf("oidc discovery for %s failed: %w", p.config.Name, err)
+	}
+	if strings.TrimSuffix(doc.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("oidc discovery for %s returned issuer %q", p.config.Name, doc.Issuer)
+	}
+	if doc.AuthorizationEndpoint == "" || doc.TokenEndpoint == "" || doc.JWKSURI == "" {
+		return nil, fmt.Errorf("oidc discovery for %s is incomplete", p.config.Name)
+	}
+	p.discovery = doc
+	return doc, nil
+}
+
+// keyFunc возвращает открытый ключ по kid из заголовка. Неизвестный kid означает, что
+// провайдер сменил ключи, и набор ключей загружается заново
+func (p *Provider) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if key := p.findKey(kid); key != nil {
+		return key, nil
+	}
+	if time.Since(p.keysFetchedAt) < keysRefreshInterval {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	keys, err := p.fetchKeys()
+	if err != nil {
+		return nil, err
+	}
+	p.keys, p.keysFetchedAt = keys, time.Now()
+	if key := p.findKey(kid); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// findKey ищет ключ по kid. Без kid подходит единственный ключ набора
+func (p *Provider) findKey(kid string) interface{} {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key
+		}
+	}
+	return p.keys[kid]
+}
+
+func (p *Provider) fetchKeys() (map[string]interface{}, error) {
+	request, err := http.NewRequest(http.MethodGet, p.discovery.JWKSURI, nil)
+	if err != nil {
+		return nil, err
+	}
+	var set jwkSet
+	if err := p.doJSON(request, &set); err != nil {
+		return nil, fmt.Errorf("jwks request failed: %w", err)
+	}
+	return set.publicKeys(), nil
+}
+
+func (p *Provider) doJSON(request *http.Request, target interface{}) error {
+	response, err := p.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(response.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %d: %s", request.URL.Path, response.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return json.Unmarshal(body, target)
+}
+
+// NewCodeVerifier создает случайный code_verifier для PKCE (RFC 7636)
+func NewCodeVerifier() (string, error) {
+	return randomString(32)
+}
+
+// NewNonce создает случайный nonce для ID token
+func NewNonce() (string, error) {
+	return randomString(16)
+}
+
+// CodeChallenge code_challenge метода S256 для codeVerifier
+func CodeChallenge(codeVerifier string) string {
+	sum := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func randomString(size int) (string, error) {
+	bytes := make([]byte, size)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(bytes), nil
+}
+
+func supportsBasicAuth(methods []string) bool {
+	// По спецификации discovery без списка методов используется client_secret_basic
+	if len(methods) == 0 {
+		return true
+	}
+	for _, method := range methods {
+		if method == "client_secret_basic" {
+			return true
+		}
+	}
+	return false
+}
+
+// isTrue разбирает email_verified: некоторые провайдеры передают его строкой "true"
+func isTrue(value interface{}) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case string:
+		return strings.EqualFold(v, "true")
+	}
+	return false
+}
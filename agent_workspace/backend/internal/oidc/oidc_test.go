@@ -0,0 +1,232 @@
+package oidc
+
+import (
+	"core/internal/oidc/oidctest"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"github.com/golang-jwt/jwt/v5"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testClientID = "test-client"
+
+func newTestProvider(server *oidctest.Server) *Provider {
+	return NewProvider(Config{
+		Name:         "test",
+		Issuer:       server.Issuer(),
+		ClientID:     testClientID,
+		ClientSecret: "secret",
+	}, "http://localhost/oidc/callback")
+}
+
+func TestAuthCodeURL(t *testing.T) {
+	server := oidctest.NewServer(testClientID)
+	defer server.Close()
+
+	authURL, err := newTestProvider(server).AuthCodeURL("state-1", "nonce-1", "verifier-1")
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("parse %q: %v", authURL, err)
+	}
+	query := parsed.Query()
+	if got := parsed.Scheme + "://" + parsed.Host + parsed.Path; got != server.URL+"/authorize" {
+		t.Errorf("endpoint = %q, want %q", got, server.URL+"/authorize")
+	}
+	for name, want := range map[string]string{
+		"client_id":             testClientID,
+		"state":                 "state-1",
+		"nonce":                 "nonce-1",
+		"code_challenge":        CodeChallenge("verifier-1"),
+		"code_challenge_method": "S256",
+	} {
+		if got := query.Get(name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestDiscoveryIssuerMismatch(t *testing.T) {
+	server := oidctest.NewServer(testClientID)
+	defer server.Close()
+	server.SetDiscoveryIssuer("https://attacker.example")
+
+	_, err := newTestProvider(server).AuthCodeURL("state", "nonce", "verifier")
+	if err == nil || !strings.Contains(err.Error(), "returned issuer") {
+		t.Fatalf("AuthCodeURL error = %v, want issuer mismatch", err)
+	}
+}
+
+func TestExchange(t *testing.T) {
+	server := oidctest.NewServer(testClientID)
+	defer server.Close()
+
+	claims := server.Claims("user-1", "nonce-1")
+	claims["email"] = "user@example.com"
+	claims["email_verified"] = "true"
+	claims["name"] = "Test User"
+	server.Respond(server.Sign(claims), nil)
+
+	identity, err := newTestProvider(server).Exchange("code", "verifier", "nonce-1")
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	want := Identity{Subject: "user-1", Email: "user@example.com", EmailVerified: true, Name: "Test User"}
+	if *identity != want {
+		t.Errorf("identity = %+v, want %+v", *identity, want)
+	}
+}
+
+func TestExchangeRejectsInvalidIDToken(t *testing.T) {
+	server := oidctest.NewServer(testClientID)
+	defer server.Close()
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		token func() string
+	}{
+		{"bad signature", func() string {
+			return oidctest.SignWith(otherKey, server.KeyID(), server.Claims("user-1", "nonce"))
+		}},
+		{"wrong audience", func() string {
+			claims := server.Claims("user-1", "nonce")
+			claims["aud"] = "another-client"
+			return server.Sign(claims)
+		}},
+		{"wrong issuer", func() string {
+			claims := server.Claims("user-1", "nonce")
+			claims["iss"] = "https://attacker.example"
+			return server.Sign(claims)
+		}},
+		{"expired", func() string {
+			claims := server.Claims("user-1", "nonce")
+			claims["exp"] = time.Now().Add(-time.Hour).Unix()
+			return server.Sign(claims)
+		}},
+		{"without expiration", func() string {
+			claims := server.Claims("user-1", "nonce")
+			delete(claims, "exp")
+			return server.Sign(claims)
+		}},
+		{"nonce mismatch", func() string {
+			return server.Sign(server.Claims("user-1", "another-nonce"))
+		}},
+		{"without subject", func() string {
+			return server.Sign(server.Claims("", "nonce"))
+		}},
+		{"unsigned", func() string {
+			token := jwt.NewWithClaims(jwt.SigningMethodNone, server.Claims("user-1", "nonce"))
+			signed, err := token.SignedString(jwt.UnsafeAllowNoneSignatureType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return signed
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server.Respond(tt.token(), nil)
+			_, err := newTestProvider(server).Exchange("code", "verifier", "nonce")
+			if !errors.Is(err, ErrInvalidIDToken) {
+				t.Fatalf("Exchange error = %v, want ErrInvalidIDToken", err)
+			}
+		})
+	}
+}
+
+func TestExchangeRefetchesKeysForUnknownKid(t *testing.T) {
+	server := oidctest.NewServer(testClientID)
+	defer server.Close()
+	provider := newTestProvider(server)
+	claims := server.Claims("user-1", "nonce")
+	claims["email"] = "user@example.com"
+
+	server.Respond(server.Sign(claims), nil)
+	if _, err := provider.Exchange("code", "verifier", "nonce"); err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if got := server.JWKSRequests(); got != 1 {
+		t.Fatalf("jwks requests = %d, want 1", got)
+	}
+
+	// Провайдер сменил ключ сразу после загрузки: повторный запрос ключей ограничен
+	server.RotateKey()
+	server.Respond(server.Sign(claims), nil)
+	if _, err := provider.Exchange("code", "verifier", "nonce"); !errors.Is(err, ErrInvalidIDToken) {
+		t.Fatalf("Exchange error = %v, want ErrInvalidIDToken", err)
+	}
+	if got := server.JWKSRequests(); got != 1 {
+		t.Fatalf("jwks requests = %d, want 1 within refresh interval", got)
+	}
+
+	provider.mu.Lock()
+	provider.keysFetchedAt = time.Now().Add(-keysRefreshInterval)
+	provider.mu.Unlock()
+	if _, err := provider.Exchange("code", "verifier", "nonce"); err != nil {
+		t.Fatalf("Exchange after key rotation: %v", err)
+	}
+	if got := server.JWKSRequests(); got != 2 {
+		t.Fatalf("jwks requests = %d, want 2", got)
+	}
+}
+
+func TestExchangeUserinfo(t *testing.T) {
+	server := oidctest.NewServer(testClientID)
+	defer server.Close()
+	token := server.Sign(server.Claims("user-1", "nonce"))
+
+	t.Run("fills email", func(t *testing.T) {
+		server.Respond(token, map[string]interface{}{
+			"sub":            "user-1",
+			"email":          "user@example.com",
+			"email_verified": true,
+			"name":           "Test User",
+		})
+		identity, err := newTestProvider(server).Exchange("code", "verifier", "nonce")
+		if err != nil {
+			t.Fatalf("Exchange: %v", err)
+		}
+		if identity.Email != "user@example.com" || !identity.EmailVerified || identity.Name != "Test User" {
+			t.Errorf("identity = %+v, want email and name from userinfo", *identity)
+		}
+	})
+
+	t.Run("subject mismatch", func(t *testing.T) {
+		server.Respond(token, map[string]interface{}{
+			"sub":            "user-2",
+			"email":          "victim@example.com",
+			"email_verified": true,
+		})
+		_, err := newTestProvider(server).Exchange("code", "verifier", "nonce")
+		if !errors.Is(err, ErrInvalidIDToken) {
+			t.Fatalf("Exchange error = %v, want ErrInvalidIDToken", err)
+		}
+	})
+}
+
+func TestAllowsEmail(t *testing.T) {
+	provider := NewProvider(Config{Name: "corp", AllowedDomains: []string{"Example.com"}}, "")
+	tests := map[string]bool{
+		"user@example.com":      true,
+		"user@EXAMPLE.COM":      true,
+		"user@example.com.evil": false,
+		"user@sub.example.com":  false,
+		"example.com":           false,
+	}
+	for email, want := range tests {
+		if got := provider.AllowsEmail(email); got != want {
+			t.Errorf("AllowsEmail(%q) = %v, want %v", email, got, want)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+// Package oidctest провайдер OpenID Connect для тестов. Отдает discovery, JWKS, токены
+// и userinfo, а ID token и ответ userinfo задает тест. Код авторизации и PKCE не
+// проверяются: для ручной проверки всего входа есть cmd/mockoidc
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+)
+
+type Server struct {
+	*httptest.Server
+	ClientID string
+
+	mu              sync.Mutex
+	key             *rsa.PrivateKey
+	keyID           string
+	keyVersion      int
+	discoveryIssuer string
+	idToken         string
+	userinfo        map[string]interface{}
+	jwksRequests    int
+}
+
+// NewServer запускает провайдера с одним ключом подписи RS256. Сервер нужно закрыть
+// через Close
+func NewServer(clientID string) *Server {
+	s := &Server{ClientID: clientID}
+	s.RotateKey()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", s.discovery)
+	mux.HandleFunc("/token", s.token)
+	mux.HandleFunc("/userinfo", s.userinfoHandler)
+	mux.HandleFunc("/jwks", s.jwks)
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// Issuer адрес провайдера для oidc.Config
+func (s *Server) Issuer() string {
+	return s.URL
+}
+
+// SetDiscoveryIssuer подменяет issuer в discovery-документе
+func (s *Server) SetDiscoveryIssuer(issuer string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.discoveryIssuer = issuer
+}
+
+// RotateKey заменяет ключ подписи на новый с другим kid. JWKS отдает только новый ключ
+func (s *Server) RotateKey() {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keyVersion++
+	s.key, s.keyID = key, fmt.Sprintf("key-%d", s.keyVersion)
+}
+
+func (s *Server) KeyID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.keyID
+}
+
+// Claims корректные claims ID token: издатель, получатель и срок действия на час
+func (s *Server) Claims(subject, nonce string) jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss":   s.URL,
+		"aud":   s.ClientID,
+		"sub":   subject,
+		"nonce": nonce,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}
+}
+
+// Sign подписывает claims текущим ключом провайдера
+func (s *Server) Sign(claims jwt.MapClaims) string {
+	s.mu.Lock()
+	key, keyID := s.key, s.keyID
+	s.mu.Unlock()
+	return SignWith(key, keyID, claims)
+}
+
+// SignWith подписывает claims произвольным ключом RS256 с указанным kid
+func SignWith(key *rsa.PrivateKey, keyID string, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = keyID
+	signed, err := token.SignedString(key)
+	if err != nil {
+		panic(err)
+	}
+	return signed
+}
+
+// Respond задает ID token, который вернет обмен кода, и ответ userinfo. Без userinfo
+// запрос к нему завершается ошибкой
+func (s *Server) Respond(idToken string, userinfo map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.idToken, s.userinfo = idToken, userinfo
+}
+
+// JWKSRequests сколько раз клиент запрашивал ключи
+func (s *Server) JWKSRequests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.jwksRequests
+}
+
+func (s *Server) discovery(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	issuer := s.discoveryIssuer
+	s.mu.Unlock()
+	if issuer == "" {
+		issuer = s.URL
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                issuer,
+		"authorization_endpoint":                s.URL + "/authorize",
+		"token_endpoint":                        s.URL + "/token",
+		"userinfo_endpoint":                     s.URL + "/userinfo",
+		"jwks_uri":                              s.URL + "/jwks",
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic"},
+	})
+}
+
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.FormValue("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+	s.mu.Lock()
+	idToken := s.idToken
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": "test-access-token",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idToken,
+	})
+}
+
+func (s *Server) userinfoHandler(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	userinfo := s.userinfo
+	s.mu.Unlock()
+	if userinfo == nil || r.Header.Get("Authorization") != "Bearer test-access-token" {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_token"})
+		return
+	}
+	writeJSON(w, http.StatusOK, userinfo)
+}
+
+func (s *Server) jwks(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.jwksRequests++
+	key, keyID := s.key, s.keyID
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kid": keyID,
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}},
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
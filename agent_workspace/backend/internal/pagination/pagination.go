@@ -0,0 +1,145 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"errors"
+	"gorm.io/gorm"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultLimit размер страницы, если клиент его не указал
+	DefaultLimit = 10
+	// MaxLimit максимальный размер страницы. Большие значения урезаются до него
+	MaxLimit = 100
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor позиция последней записи страницы. Списки сортируются по (created_at, id)
+// по убыванию, поэтому пары достаточно, чтобы продолжить выдачу без пропусков и дублей
+type Cursor struct {
+	CreatedAt time.Time
+	ID        uint
+}
+
+// Encode возвращает непрозрачную строку курсора для клиента
+func (c Cursor) Encode() string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + strconv.FormatUint(uint64(c.ID), 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeCursor разбирает строку, полученную из Encode
+func DecodeCursor(value string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	createdAt, id, found := strings.Cut(string(raw), "|")
+	if !found {
+		return nil, ErrInvalidCursor
+	}
+	cursor := &Cursor{}
+	cursor.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	parsedID, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	cursor.ID = uint(parsedID)
+	return cursor, nil
+}
+
+// Request параметры запрашиваемой страницы. В режиме Keyset страница начинается
+// после Cursor (первая страница - без курсора), иначе используется смещение,
+// оставленное для совместимости с v1
+type Request struct {
+	Limit  int
+	Offset int
+	Cursor *Cursor
+	Keyset bool
+}
+
+// Page описание полученной страницы для ответа клиенту
+type Page struct {
+	Limit      int    `json:"limit"`
+	HasMore    bool   `json:"has_more"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// Offset создает запрос страницы по смещению
+func Offset(limit, offset int) Request {
+	if offset < 0 {
+		offset = 0
+	}
+	return Request{Limit: normalizeLimit(limit), Offset: offset}
+}
+
+// FromPage создает запрос страницы по ее номеру, начиная с 1
+func FromPage(page, limit int) Request {
+	if page < 1 {
+		page = 1
+	}
+	limit = normalizeLimit(limit)
+	return Request{Limit: limit, Offset: (page - 1) * limit}
+}
+
+// Keyset создает запрос страницы по курсору. Пустой курсор означает первую страницу
+func Keyset(cursor string, limit int) (Request, error) {
+	request := Request{Limit: normalizeLimit(limit), Keyset: true}
+	if cursor == "" {
+		return request, nil
+	}
+	decoded, err := DecodeCursor(cursor)
+	if err != nil {
+		return Request{}, err
+	}
+	request.Cursor = decoded
+	return request, nil
+}
+
+// Parse выбирает режим по полям тела запроса: курсор, если он передан, иначе смещение
+func Parse(cursor *string, limit, offset int) (Request, error) {
+	if cursor != nil {
+		return Keyset(*cursor, limit)
+	}
+	return Offset(limit, offset), nil
+}
+
+// Apply добавляет к запросу сортировку по (created_at, id) и границы страницы.
+// Выбирается на одну запись больше, чтобы Trim мог определить наличие следующей страницы
+func Apply(query *gorm.DB, request Request) *gorm.DB {
+	query = query.Order("created_at DESC, id DESC").Limit(request.Limit + 1)
+	if !request.Keyset {
+		return query.Offset(request.Offset)
+	}
+	if request.Cursor != nil {
+		query = query.Where("(created_at, id) < (?, ?)", request.Cursor.CreatedAt, request.Cursor.ID)
+	}
+	return query
+}
+
+// Trim отбрасывает лишнюю запись, выбранную Apply, и описывает страницу
+func Trim[T any](items []T, request Request, key func(T) Cursor) ([]T, Page) {
+	page := Page{Limit: request.Limit}
+	if len(items) > request.Limit {
+		items = items[:request.Limit]
+		page.HasMore = true
+		page.NextCursor = key(items[len(items)-1]).Encode()
+	}
+	return items, page
+}
+
+func normalizeLimit(limit int) int {
+	if limit <= 0 {
+		return DefaultLimit
+	}
+	if limit > MaxLimit {
+		return MaxLimit
+	}
+	return limit
+}
@@ -0,0 +1,178 @@
+package pdf
+
+import (
+	"fmt"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Метрики запрашиваются в тысячных долях кегля: в этих единицах PDF задает ширины глифов
+var thousandths = fixed.I(1000)
+
+// Шрифты Go (WGL4: латиница, кириллица, греческий алфавит). В документ встраиваются
+// только использованные глифы
+var (
+	regularFont = mustParseFont(goregular.TTF)
+	boldFont    = mustParseFont(gobold.TTF)
+)
+
+// trueTypeFont шрифт TrueType с кешем глифов. Кеш общий для всех документов
+type trueTypeFont struct {
+	data      []byte
+	name      string
+	bounds    [4]int
+	ascent    int
+	descent   int
+	capHeight int
+
+	mu     sync.Mutex
+	buffer sfnt.Buffer
+	font   *sfnt.Font
+	glyphs map[rune]glyph
+}
+
+// glyph номер глифа в шрифте и его ширина в тысячных долях кегля. char - символ,
+// который выводит глиф: для символов, которых нет в шрифте, это '?'
+type glyph struct {
+	index uint16
+	width int
+	char  rune
+}
+
+func mustParseFont(data []byte) *trueTypeFont {
+	parsed, err := sfnt.Parse(data)
+	if err != nil {
+		panic(fmt.Sprintf("pdf: failed to parse font: %v", err))
+	}
+	f := &trueTypeFont{data: data, font: parsed, glyphs: map[rune]glyph{}}
+	if f.name, err = parsed.Name(&f.buffer, sfnt.NameIDPostScript); err != nil {
+		panic(fmt.Sprintf("pdf: failed to read font name: %v", err))
+	}
+	bounds, err := parsed.Bounds(&f.buffer, thousandths, font.HintingNone)
+	if err != nil {
+		panic(fmt.Sprintf("pdf: failed to read font bounds: %v", err))
+	}
+	metrics, err := parsed.Metrics(&f.buffer, thousandths, font.HintingNone)
+	if err != nil {
+		panic(fmt.Sprintf("pdf: failed to read font metrics: %v", err))
+	}
+	// В sfnt ось Y направлена вниз, в PDF - вверх
+	f.bounds = [4]int{bounds.Min.X.Round(), -bounds.Max.Y.Round(), bounds.Max.X.Round(), -bounds.Min.Y.Round()}
+	f.ascent, f.descent, f.capHeight = metrics.Ascent.Round(), -metrics.Descent.Round(), metrics.CapHeight.Round()
+	return f
+}
+
+// glyph возвращает глиф символа. Символы, которых нет в шрифте, заменяются на '?'
+func (f *trueTypeFont) glyph(r rune) glyph {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.lookup(r)
+}
+
+func (f *trueTypeFont) lookup(r rune) glyph {
+	if g, ok := f.glyphs[r]; ok {
+		return g
+	}
+
+	index, err := f.font.GlyphIndex(&f.buffer, r)
+	if (err != nil || index == 0) && r != '?' {
+		g := f.lookup('?')
+		f.glyphs[r] = g
+		return g
+	}
+	advance, err := f.font.GlyphAdvance(&f.buffer, index, thousandths, font.HintingNone)
+	if err != nil {
+		advance = 0
+	}
+	g := glyph{index: uint16(index), width: advance.Round(), char: r}
+	f.glyphs[r] = g
+	return g
+}
+
+// fontUsage глифы шрифта, использованные в документе, с символами для карты ToUnicode
+type fontUsage struct {
+	font  *trueTypeFont
+	runes map[uint16]rune
+}
+
+// show кодирует строку номерами глифов (Identity-H) и запоминает использованные глифы
+func (u *fontUsage) show(text string) string {
+	var b strings.Builder
+	b.WriteByte('<')
+	for _, r := range text {
+		if r == '\t' {
+			r = ' '
+		}
+		g := u.font.glyph(r)
+		u.runes[g.index] = g.char
+		fmt.Fprintf(&b, "%04X", g.index)
+	}
+	b.WriteByte('>')
+	return b.String()
+}
+
+func (u *fontUsage) indexes() []uint16 {
+	indexes := make([]uint16, 0, len(u.runes))
+	for index := range u.runes {
+		indexes = append(indexes, index)
+	}
+	sort.Slice(indexes, func(i, j int) bool { return indexes[i] < indexes[j] })
+	return indexes
+}
+
+// objects возвращает объекты шрифта Type0 с номерами от first: сам шрифт, CIDFontType2,
+// описание шрифта, файл шрифта и карту ToUnicode
+func (u *fontUsage) objects(first int) [][]byte {
+	f := u.font
+	indexes := u.indexes()
+
+	widths := make([]string, 0, len(indexes))
+	for _, index := range indexes {
+		widths = append(widths, fmt.Sprintf("%d [%d]", index, f.glyph(u.runes[index]).width))
+	}
+	// Встраиваются только использованные глифы. Шрифты пакета разбираются при запуске,
+	// поэтому ошибка здесь не ожидается, но и тогда документ останется верным с целым шрифтом
+	name, file := f.name, f.data
+	if subset, err := subsetFont(f.data, indexes); err == nil {
+		name, file = subsetTag(indexes)+"+"+f.name, subset
+	}
+	return [][]byte{
+		[]byte(fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont /%s /Encoding /Identity-H "+
+			"/DescendantFonts [%d 0 R] /ToUnicode %d 0 R >>", name, first+1, first+4)),
+		[]byte(fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType2 /BaseFont /%s "+
+			"/CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> "+
+			"/FontDescriptor %d 0 R /CIDToGIDMap /Identity /DW 0 /W [%s] >>", name, first+2, strings.Join(widths, " "))),
+		[]byte(fmt.Sprintf("<< /Type /FontDescriptor /FontName /%s /Flags 32 /FontBBox [%d %d %d %d] "+
+			"/ItalicAngle 0 /Ascent %d /Descent %d /CapHeight %d /StemV 80 /FontFile2 %d 0 R >>",
+			name, f.bounds[0], f.bounds[1], f.bounds[2], f.bounds[3], f.ascent, f.descent, f.capHeight, first+3)),
+		streamObject(file, true, fmt.Sprintf("/Length1 %d", len(file))),
+		streamObject([]byte(u.toUnicode()), false, ""),
+	}
+}
+
+// toUnicode карта из номеров глифов в символы, чтобы текст можно было копировать и искать
+func (u *fontUsage) toUnicode() string {
+	var b strings.Builder
+	b.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n" +
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n" +
+		"/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n" +
+		"1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+	indexes := u.indexes()
+	// В одном блоке bfchar допускается не больше 100 записей
+	for start := 0; start < len(indexes); start += 100 {
+		end := min(start+100, len(indexes))
+		fmt.Fprintf(&b, "%d beginbfchar\n", end-start)
+		for _, index := range indexes[start:end] {
+			fmt.Fprintf(&b, "<%04X> <%s>\n", index, utf16Hex(string(u.runes[index])))
+		}
+		b.WriteString("endbfchar\n")
+	}
+	b.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend")
+	return b.String()
+}
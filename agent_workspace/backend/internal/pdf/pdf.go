@@ -0,0 +1,184 @@
+// Package pdf формирует простые PDF-документы из текста и линий.
+//
+// Текст выводится шрифтами Go, встроенными в документ как CIDFontType2 с кодировкой
+// Identity-H, поэтому кириллица отображается одинаково в любом просмотрщике. Карта
+// ToUnicode позволяет копировать и искать текст
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// Размер страницы A4 в пунктах
+const (
+	PageWidth  = 595.28
+	PageHeight = 841.89
+)
+
+// Document документ из страниц A4
+type Document struct {
+	title     string
+	createdAt time.Time
+	pages     []*Page
+	regular   *fontUsage
+	bold      *fontUsage
+}
+
+// Page страница документа. Координаты отсчитываются от левого нижнего угла
+type Page struct {
+	document *Document
+	content  bytes.Buffer
+}
+
+func New(title string, createdAt time.Time) *Document {
+	return &Document{
+		title:     title,
+		createdAt: createdAt,
+		regular:   &fontUsage{font: regularFont, runes: map[uint16]rune{}},
+		bold:      &fontUsage{font: boldFont, runes: map[uint16]rune{}},
+	}
+}
+
+func (d *Document) AddPage() *Page {
+	page := &Page{document: d}
+	d.pages = append(d.pages, page)
+	return page
+}
+
+// Text выводит строку, левый край которой находится в точке x
+func (p *Page) Text(x, y, size float64, bold bool, text string) {
+	font, usage := "F1", p.document.regular
+	if bold {
+		font, usage = "F2", p.document.bold
+	}
+	fmt.Fprintf(&p.content, "BT /%s %.2f Tf %.2f %.2f Td %s Tj ET\n", font, size, x, y, usage.show(text))
+}
+
+// TextRight выводит строку, правый край которой находится в точке x
+func (p *Page) TextRight(x, y, size float64, bold bool, text string) {
+	p.Text(x-TextWidth(text, size, bold), y, size, bold, text)
+}
+
+// Line рисует отрезок толщиной width
+func (p *Page) Line(x1, y1, x2, y2, width float64) {
+	fmt.Fprintf(&p.content, "%.2f w %.2f %.2f m %.2f %.2f l S\n", width, x1, y1, x2, y2)
+}
+
+// TextWidth ширина строки по метрикам шрифта, которым она будет выведена
+func TextWidth(text string, size float64, bold bool) float64 {
+	font := regularFont
+	if bold {
+		font = boldFont
+	}
+	units := 0
+	for _, r := range text {
+		if r == '\t' {
+			r = ' '
+		}
+		units += font.glyph(r).width
+	}
+	return float64(units) * size / 1000
+}
+
+// WriteTo записывает документ в формате PDF 1.4
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	if len(d.pages) == 0 {
+		d.AddPage()
+	}
+
+	// Номера объектов: 1 - каталог, 2 - дерево страниц, 3 - сведения о документе,
+	// далее по пять объектов на каждый использованный шрифт, затем страницы и их
+	// содержимое парами
+	objects := make([][]byte, 3, 13+2*len(d.pages))
+	objects[0] = []byte("<< /Type /Catalog /Pages 2 0 R >>")
+	objects[2] = []byte(fmt.Sprintf("<< /Title %s /CreationDate (D:%s) >>", textString(d.title), d.createdAt.UTC().Format("20060102150405Z")))
+
+	var fonts []string
+	for i, usage := range []*fontUsage{d.regular, d.bold} {
+		if len(usage.runes) == 0 {
+			continue
+		}
+		fonts = append(fonts, fmt.Sprintf("/F%d %d 0 R", i+1, len(objects)+1))
+		objects = append(objects, usage.objects(len(objects)+1)...)
+	}
+
+	kids := make([]string, 0, len(d.pages))
+	for _, page := range d.pages {
+		kids = append(kids, fmt.Sprintf("%d 0 R", len(objects)+1))
+		objects = append(objects,
+			[]byte(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] "+
+				"/Resources << /Font << %s >> >> /Contents %d 0 R >>", PageWidth, PageHeight, strings.Join(fonts, " "), len(objects)+2)),
+			streamObject(page.content.Bytes(), true, ""),
+		)
+	}
+	objects[1] = []byte(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+
+	var out bytes.Buffer
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n", i+1)
+		out.Write(object)
+		out.WriteString("\nendobj\n")
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R /Info 3 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	n, err := w.Write(out.Bytes())
+	return int64(n), err
+}
+
+// Bytes возвращает документ в формате PDF
+func (d *Document) Bytes() []byte {
+	var buf bytes.Buffer
+	d.WriteTo(&buf)
+	return buf.Bytes()
+}
+
+// streamObject поток с данными. extra - дополнительные записи словаря потока
+func streamObject(data []byte, compress bool, extra string) []byte {
+	filter := ""
+	if compress {
+		var buf bytes.Buffer
+		zw := zlib.NewWriter(&buf)
+		zw.Write(data)
+		zw.Close()
+		data = buf.Bytes()
+		filter = " /Filter /FlateDecode"
+	}
+	if extra != "" {
+		extra = " " + extra
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "<< /Length %d%s%s >>\nstream\n", len(data), filter, extra)
+	out.Write(data)
+	out.WriteString("\nendstream")
+	return out.Bytes()
+}
+
+// textString кодирует строку метаданных в UTF-16BE
+func textString(text string) string {
+	return "<FEFF" + utf16Hex(text) + ">"
+}
+
+// utf16Hex текст в UTF-16BE шестнадцатеричными цифрами
+func utf16Hex(text string) string {
+	var b strings.Builder
+	for _, unit := range utf16.Encode([]rune(text)) {
+		fmt.Fprintf(&b, "%04X", unit)
+	}
+	return b.String()
+}
@@ -0,0 +1,142 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"golang.org/x/image/font/sfnt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDocumentStructure(t *testing.T) {
+	doc := New("Акт № 1", time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+	page := doc.AddPage()
+	page.Text(40, 800, 12, true, "Исполнитель")
+	page.TextRight(555, 780, 10, false, "Итого: 1 200,00 RUB — «оплачено»")
+	page.Line(40, 770, 555, 770, 0.8)
+	doc.AddPage().Text(40, 800, 10, false, "Ёлка")
+	data := doc.Bytes()
+
+	// Каждая запись xref указывает на начало своего объекта
+	xref := bytes.LastIndex(data, []byte("\nxref\n"))
+	if xref < 0 {
+		t.Fatal("xref table not found")
+	}
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(data[xref:], -1)
+	if len(entries) == 0 {
+		t.Fatal("xref table is empty")
+	}
+	for i, entry := range entries {
+		offset, _ := strconv.Atoi(string(entry[1]))
+		if want := fmt.Sprintf("%d 0 obj\n", i+1); !bytes.HasPrefix(data[offset:], []byte(want)) {
+			t.Errorf("xref entry %d points to %q", i+1, data[offset:offset+10])
+		}
+	}
+	if !bytes.Contains(data, []byte(fmt.Sprintf("/Size %d", len(entries)+1))) {
+		t.Errorf("trailer size does not match %d objects", len(entries))
+	}
+
+	for _, name := range []string{regularFont.name, boldFont.name} {
+		pattern := regexp.MustCompile(`/Subtype /Type0 /BaseFont /[A-Z]{6}\+` + name + ` /Encoding /Identity-H`)
+		if !pattern.Match(data) {
+			t.Errorf("font %s is not embedded", name)
+		}
+	}
+	for _, want := range []string{"/Subtype /CIDFontType2", "/FontFile2", "/Count 2"} {
+		if !bytes.Contains(data, []byte(want)) {
+			t.Errorf("document does not contain %q", want)
+		}
+	}
+	if len(data) > 64<<10 {
+		t.Errorf("document size = %d, fonts are not subset", len(data))
+	}
+}
+
+func TestToUnicode(t *testing.T) {
+	doc := New("", time.Now())
+	doc.AddPage().Text(0, 0, 10, false, "Ёж №1")
+
+	cmap := doc.regular.toUnicode()
+	for _, r := range "Ёж №1" {
+		entry := fmt.Sprintf("<%04X> <%s>", regularFont.glyph(r).index, utf16Hex(string(r)))
+		if !strings.Contains(cmap, entry) {
+			t.Errorf("ToUnicode does not map %q: want %s", r, entry)
+		}
+	}
+	if len(doc.bold.runes) != 0 {
+		t.Error("bold font is used without bold text")
+	}
+}
+
+func TestTextWidth(t *testing.T) {
+	if got := TextWidth("", 10, false); got != 0 {
+		t.Errorf("empty width = %v", got)
+	}
+	// Ширина пропорциональна кеглю и складывается из ширин глифов
+	word := TextWidth("Щит", 10, false)
+	if sum := TextWidth("Щ", 10, false) + TextWidth("ит", 10, false); word != sum {
+		t.Errorf("width of word = %v, sum of parts = %v", word, sum)
+	}
+	if double := TextWidth("Щит", 20, false); double != 2*word {
+		t.Errorf("width at 20pt = %v, want %v", double, 2*word)
+	}
+	if TextWidth("Ш", 10, false) <= TextWidth("г", 10, false) {
+		t.Error("wide and narrow Cyrillic letters have the same width")
+	}
+	if TextWidth("Итого", 10, true) <= TextWidth("Итого", 10, false) {
+		t.Error("bold text is not wider than regular")
+	}
+	// Символ, которого нет в шрифте, выводится как '?'
+	if got, want := TextWidth("\U0001F600", 10, false), TextWidth("?", 10, false); got != want {
+		t.Errorf("width of missing glyph = %v, want %v", got, want)
+	}
+}
+
+func TestSubsetFont(t *testing.T) {
+	used := []uint16{regularFont.glyph('A').index, regularFont.glyph('Й').index}
+	data, err := subsetFont(regularFont.data, used)
+	if err != nil {
+		t.Fatalf("subsetFont: %v", err)
+	}
+	if len(data) >= len(regularFont.data)/4 {
+		t.Errorf("subset size = %d, full font = %d", len(data), len(regularFont.data))
+	}
+	if fontChecksum(data) != 0xb1b0afba {
+		t.Error("font checksum adjustment is wrong")
+	}
+
+	parsed, err := sfnt.Parse(data)
+	if err != nil {
+		t.Fatalf("subset is not a valid font: %v", err)
+	}
+	if parsed.NumGlyphs() != regularFont.font.NumGlyphs() {
+		t.Fatalf("glyph count = %d, want %d", parsed.NumGlyphs(), regularFont.font.NumGlyphs())
+	}
+	var buffer sfnt.Buffer
+	for _, r := range "AЙ" {
+		index := sfnt.GlyphIndex(regularFont.glyph(r).index)
+		segments, err := parsed.LoadGlyph(&buffer, index, thousandths, nil)
+		if err != nil || len(segments) == 0 {
+			t.Errorf("glyph %q is missing in subset: %v", r, err)
+		}
+	}
+	segments, err := parsed.LoadGlyph(&buffer, sfnt.GlyphIndex(regularFont.glyph('Z').index), thousandths, nil)
+	if err != nil || len(segments) != 0 {
+		t.Errorf("unused glyph has %d segments, err %v", len(segments), err)
+	}
+}
+
+func TestGlyphComponents(t *testing.T) {
+	glyph := []byte{
+		0xff, 0xff, 0, 0, 0, 0, 0, 0, 0, 0, // составной глиф и его габариты
+		0x00, 0x21, 0x00, 0x05, 0, 0, 0, 0, // MORE_COMPONENTS | ARG_1_AND_2_ARE_WORDS, глиф 5
+		0x00, 0x08, 0x00, 0x07, 0, 0, 0x40, 0x00, // WE_HAVE_A_SCALE, глиф 7
+	}
+	components := glyphComponents(glyph)
+	if len(components) != 2 || components[0] != 5 || components[1] != 7 {
+		t.Errorf("components = %v, want [5 7]", components)
+	}
+}
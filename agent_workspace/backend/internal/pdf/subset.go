@@ -0,0 +1,231 @@
+package pdf
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"sort"
+)
+
+// Флаги составного глифа (спецификация TrueType, таблица glyf)
+const (
+	argsAreWords   = 0x0001
+	hasScale       = 0x0008
+	moreComponents = 0x0020
+	hasXYScale     = 0x0040
+	hasTwoByTwo    = 0x0080
+)
+
+var errMalformedFont = errors.New("pdf: malformed TrueType font")
+
+type fontTable struct {
+	tag  string
+	data []byte
+}
+
+// subsetFont оставляет в шрифте контуры только использованных глифов и тех, из которых
+// они составлены. Номера глифов не меняются, остальные глифы становятся пустыми. Прочие
+// таблицы копируются без изменений: контуры занимают почти весь файл шрифта
+func subsetFont(data []byte, used []uint16) ([]byte, error) {
+	tables, err := readFontTables(data)
+	if err != nil {
+		return nil, err
+	}
+	head, loca, glyf := tables["head"], tables["loca"], tables["glyf"]
+	if len(head) < 54 || loca == nil || glyf == nil {
+		return nil, errMalformedFont
+	}
+	offsets, err := glyphOffsets(loca, binary.BigEndian.Uint16(head[50:]) == 1, len(glyf))
+	if err != nil {
+		return nil, err
+	}
+
+	keep := map[uint16]bool{0: true}
+	queue := append([]uint16{0}, used...)
+	for len(queue) > 0 {
+		index := queue[0]
+		queue = queue[1:]
+		keep[index] = true
+		if int(index)+1 >= len(offsets) {
+			continue
+		}
+		for _, component := range glyphComponents(glyf[offsets[index]:offsets[index+1]]) {
+			if !keep[component] {
+				queue = append(queue, component)
+			}
+		}
+	}
+
+	// Контуры выравниваются по 4 байта, смещения записываются в длинном формате loca
+	newGlyf := make([]byte, 0, len(glyf)/4)
+	newLoca := make([]byte, 0, 4*len(offsets))
+	for index := 0; index < len(offsets)-1; index++ {
+		newLoca = binary.BigEndian.AppendUint32(newLoca, uint32(len(newGlyf)))
+		if keep[uint16(index)] {
+			newGlyf = append(newGlyf, glyf[offsets[index]:offsets[index+1]]...)
+			for len(newGlyf)%4 != 0 {
+				newGlyf = append(newGlyf, 0)
+			}
+		}
+	}
+	newLoca = binary.BigEndian.AppendUint32(newLoca, uint32(len(newGlyf)))
+
+	newHead := append([]byte(nil), head...)
+	binary.BigEndian.PutUint32(newHead[8:], 0)
+	binary.BigEndian.PutUint16(newHead[50:], 1)
+
+	var result []fontTable
+	for tag, table := range tables {
+		switch tag {
+		case "glyf":
+			table = newGlyf
+		case "loca":
+			table = newLoca
+		case "head":
+			table = newHead
+		}
+		result = append(result, fontTable{tag: tag, data: table})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].tag < result[j].tag })
+	return writeFontTables(binary.BigEndian.Uint32(data), result), nil
+}
+
+func readFontTables(data []byte) (map[string][]byte, error) {
+	if len(data) < 12 {
+		return nil, errMalformedFont
+	}
+	count := int(binary.BigEndian.Uint16(data[4:]))
+	if len(data) < 12+16*count {
+		return nil, errMalformedFont
+	}
+	tables := make(map[string][]byte, count)
+	for i := 0; i < count; i++ {
+		record := data[12+16*i:]
+		offset, length := binary.BigEndian.Uint32(record[8:]), binary.BigEndian.Uint32(record[12:])
+		if uint64(offset)+uint64(length) > uint64(len(data)) {
+			return nil, errMalformedFont
+		}
+		tables[string(record[:4])] = data[offset : offset+length]
+	}
+	return tables, nil
+}
+
+// glyphOffsets разбирает таблицу loca: контур глифа i занимает glyf[offsets[i]:offsets[i+1]]
+func glyphOffsets(loca []byte, long bool, glyfLength int) ([]int, error) {
+	size := 2
+	if long {
+		size = 4
+	}
+	offsets := make([]int, 0, len(loca)/size)
+	for i := 0; i+size <= len(loca); i += size {
+		var offset int
+		if long {
+			offset = int(binary.BigEndian.Uint32(loca[i:]))
+		} else {
+			offset = 2 * int(binary.BigEndian.Uint16(loca[i:]))
+		}
+		if offset > glyfLength || (len(offsets) > 0 && offset < offsets[len(offsets)-1]) {
+			return nil, errMalformedFont
+		}
+		offsets = append(offsets, offset)
+	}
+	if len(offsets) < 2 {
+		return nil, errMalformedFont
+	}
+	return offsets, nil
+}
+
+// glyphComponents номера глифов, из которых составлен составной глиф
+func glyphComponents(glyph []byte) []uint16 {
+	if len(glyph) < 10 || int16(binary.BigEndian.Uint16(glyph)) >= 0 {
+		return nil
+	}
+	var components []uint16
+	for position := 10; position+4 <= len(glyph); {
+		flags := binary.BigEndian.Uint16(glyph[position:])
+		components = append(components, binary.BigEndian.Uint16(glyph[position+2:]))
+		position += 4
+		if flags&argsAreWords != 0 {
+			position += 4
+		} else {
+			position += 2
+		}
+		switch {
+		case flags&hasScale != 0:
+			position += 2
+		case flags&hasXYScale != 0:
+			position += 4
+		case flags&hasTwoByTwo != 0:
+			position += 8
+		}
+		if flags&moreComponents == 0 {
+			break
+		}
+	}
+	return components
+}
+
+// writeFontTables собирает файл шрифта и пересчитывает контрольные суммы
+func writeFontTables(version uint32, tables []fontTable) []byte {
+	count := len(tables)
+	entrySelector := 0
+	for 1<<(entrySelector+1) <= count {
+		entrySelector++
+	}
+	searchRange := 16 << entrySelector
+
+	out := make([]byte, 0, 12+16*count)
+	out = binary.BigEndian.AppendUint32(out, version)
+	out = binary.BigEndian.AppendUint16(out, uint16(count))
+	out = binary.BigEndian.AppendUint16(out, uint16(searchRange))
+	out = binary.BigEndian.AppendUint16(out, uint16(entrySelector))
+	out = binary.BigEndian.AppendUint16(out, uint16(16*count-searchRange))
+
+	offset := 12 + 16*count
+	headOffset := -1
+	for _, table := range tables {
+		if table.tag == "head" {
+			headOffset = offset
+		}
+		out = append(out, table.tag...)
+		out = binary.BigEndian.AppendUint32(out, fontChecksum(table.data))
+		out = binary.BigEndian.AppendUint32(out, uint32(offset))
+		out = binary.BigEndian.AppendUint32(out, uint32(len(table.data)))
+		offset += (len(table.data) + 3) &^ 3
+	}
+	for _, table := range tables {
+		out = append(out, table.data...)
+		for len(out)%4 != 0 {
+			out = append(out, 0)
+		}
+	}
+	if headOffset >= 0 {
+		binary.BigEndian.PutUint32(out[headOffset+8:], 0xb1b0afba-fontChecksum(out))
+	}
+	return out
+}
+
+func fontChecksum(data []byte) uint32 {
+	var sum uint32
+	for i := 0; i < len(data); i += 4 {
+		var word [4]byte
+		copy(word[:], data[i:])
+		sum += binary.BigEndian.Uint32(word[:])
+	}
+	return sum
+}
+
+// subsetTag префикс имени подмножества шрифта из шести заглавных букв (ISO 32000, 9.6.4).
+// Префикс зависит от набора глифов, поэтому одинаковые документы совпадают побайтно
+func subsetTag(used []uint16) string {
+	hash := sha256.New()
+	for _, index := range used {
+		hash.Write([]byte{byte(index >> 8), byte(index)})
+	}
+	sum := hash.Sum(nil)
+	tag := make([]byte, 6)
+	for i := range tag {
+		tag[i] = 'A' + sum[i]%26
+	}
+	return string(tag)
+}
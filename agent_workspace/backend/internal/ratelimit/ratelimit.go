@@ -0,0 +1,160 @@
+// Package ratelimit ограничивает частоту запросов (token bucket) и блокирует учетные записи
+// после серии неудачных попыток входа. Состояние хранится в Store: в памяти процесса для
+// одного экземпляра или в Postgres, когда сервис запущен в нескольких репликах
+package ratelimit
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"time"
+)
+
+// Limit емкость корзины и период, за который она полностью наполняется.
+// Limit{Requests: 10, Per: time.Minute} - до 10 запросов подряд, затем один раз в 6 секунд
+type Limit struct {
+	Requests int
+	Per      time.Duration
+}
+
+// Take пересчитывает токены корзины за прошедшее время и забирает один. Возвращает новое
+// число токенов и время ожидания: если оно больше нуля, токена нет и запрос отклоняется
+func (l Limit) Take(tokens float64, elapsed time.Duration) (float64, time.Duration) {
+	rate := float64(l.Requests) / l.Per.Seconds()
+	if elapsed > 0 {
+		tokens = math.Min(float64(l.Requests), tokens+elapsed.Seconds()*rate)
+	}
+	if tokens >= 1 {
+		return tokens - 1, 0
+	}
+	wait := time.Duration(math.Ceil((1 - tokens) / rate * float64(time.Second)))
+	return tokens, wait
+}
+
+// LockoutPolicy прогрессивная блокировка: после Threshold неудач подряд ключ блокируется
+// на Base, каждая следующая неудача удваивает срок, но не больше Max. Неудачи старше Window
+// забываются
+type LockoutPolicy struct {
+	Threshold int
+	Base      time.Duration
+	Max       time.Duration
+	Window    time.Duration
+}
+
+// Next учитывает новую неудачу. Возвращает число неудач подряд и время окончания
+// блокировки (нулевое, если блокировки нет)
+func (p LockoutPolicy) Next(failures int, lastFailure, now time.Time) (int, time.Time) {
+	if now.Sub(lastFailure) > p.Window {
+		failures = 0
+	}
+	failures++
+	if failures < p.Threshold {
+		return failures, time.Time{}
+	}
+
+	duration := p.Base
+	for i := p.Threshold; i < failures && duration < p.Max; i++ {
+		duration *= 2
+	}
+	if duration > p.Max {
+		duration = p.Max
+	}
+	return failures, now.Add(duration)
+}
+
+// Store хранилище корзин и счетчиков неудач
+type Store interface {
+	// Take забирает токен из корзины key. Возвращает время ожидания, 0 - запрос разрешен
+	Take(key string, limit Limit, now time.Time) (time.Duration, error)
+	// LockedUntil время окончания блокировки key, нулевое - блокировки нет
+	LockedUntil(key string, now time.Time) (time.Time, error)
+	// RecordFailure учитывает неудачную попытку и возвращает время окончания блокировки
+	RecordFailure(key string, policy LockoutPolicy, now time.Time) (time.Time, error)
+	ResetFailures(key string) error
+	// Prune удаляет корзины и счетчики, которые не менялись с before
+	Prune(before time.Time) error
+}
+
+// LimitError запрос отклонен ограничением или блокировкой. RetryAfter передается
+// клиенту в заголовке Retry-After
+type LimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("too many attempts, retry after %d seconds", e.RetrySeconds())
+}
+
+// RetrySeconds время ожидания в целых секундах, не меньше одной
+func (e *LimitError) RetrySeconds() int {
+	return int(math.Max(1, math.Ceil(e.RetryAfter.Seconds())))
+}
+
+// pruneAge возраст, после которого состояние ключа удаляется: корзина к этому времени
+// полностью наполнена, а неудачи забыты
+const pruneAge = 24 * time.Hour
+
+// Limiter проверяет ограничения и блокировки. Ошибки хранилища не мешают работе сервиса:
+// запрос пропускается, ошибка записывается в журнал
+type Limiter struct {
+	store   Store
+	lockout LockoutPolicy
+}
+
+// Allow забирает токен из корзины key. Возвращает *LimitError, если токенов нет
+func (l *Limiter) Allow(key string, limit Limit) error {
+	wait, err := l.store.Take(key, limit, time.Now())
+	if err != nil {
+		log.Printf("failed to check rate limit %s: %v", key, err)
+		return nil
+	}
+	if wait > 0 {
+		return &LimitError{RetryAfter: wait}
+	}
+	return nil
+}
+
+// CheckLock возвращает *LimitError, если key заблокирован после неудачных попыток
+func (l *Limiter) CheckLock(key string) error {
+	now := time.Now()
+	lockedUntil, err := l.store.LockedUntil(key, now)
+	if err != nil {
+		log.Printf("failed to check lockout %s: %v", key, err)
+		return nil
+	}
+	if lockedUntil.After(now) {
+		return &LimitError{RetryAfter: lockedUntil.Sub(now)}
+	}
+	return nil
+}
+
+// Fail учитывает неудачную попытку для key
+func (l *Limiter) Fail(key string) {
+	if _, err := l.store.RecordFailure(key, l.lockout, time.Now()); err != nil {
+		log.Printf("failed to record failure %s: %v", key, err)
+	}
+}
+
+// Succeed сбрасывает счетчик неудач key после успешной попытки
+func (l *Limiter) Succeed(key string) {
+	if err := l.store.ResetFailures(key); err != nil {
+		log.Printf("failed to reset failures %s: %v", key, err)
+	}
+}
+
+// Start периодически удаляет устаревшие корзины и счетчики
+func (l *Limiter) Start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := l.store.Prune(time.Now().Add(-pruneAge)); err != nil {
+				log.Printf("failed to prune rate limits: %v", err)
+			}
+		}
+	}()
+}
+
+func NewLimiter(store Store, lockout LockoutPolicy) *Limiter {
+	return &Limiter{store: store, lockout: lockout}
+}
@@ -0,0 +1,19 @@
+package rating
+
+// PriorWeight число "виртуальных" отзывов со средней оценкой платформы, которые
+// добавляются к отзывам компании. Чем меньше у компании отзывов, тем ближе ее
+// рейтинг к среднему по платформе
+const PriorWeight = 10
+
+// DefaultPriorMean средняя оценка, пока на платформе нет ни одного отзыва
+const DefaultPriorMean = 3.5
+
+// BayesianScore байесовская оценка рейтинга компании со средней оценкой average
+// по count отзывам при средней оценке платформы priorMean
+func BayesianScore(average float64, count int64, priorMean float64) float64 {
+	if count <= 0 {
+		return priorMean
+	}
+	total := average * float64(count)
+	return (PriorWeight*priorMean + total) / (PriorWeight + float64(count))
+}
@@ -0,0 +1,249 @@
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Поддерживаемые частоты RRULE (RFC 5545)
+const (
+	Daily   = "DAILY"
+	Weekly  = "WEEKLY"
+	Monthly = "MONTHLY"
+
+	// maxPeriods ограничивает перебор периодов, чтобы правило без подходящих дат
+	// (например, BYMONTHDAY=31 с INTERVAL=12 для февраля) не зациклилось
+	maxPeriods = 5000
+)
+
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday, "TH": time.Thursday,
+	"FR": time.Friday, "SA": time.Saturday, "SU": time.Sunday,
+}
+
+// Presets правила для стандартных частот повторения
+var Presets = map[string]string{
+	"weekly":   "FREQ=WEEKLY;INTERVAL=1",
+	"biweekly": "FREQ=WEEKLY;INTERVAL=2",
+	"monthly":  "FREQ=MONTHLY;INTERVAL=1",
+}
+
+// Rule подмножество RRULE: FREQ (DAILY, WEEKLY, MONTHLY), INTERVAL, BYDAY (без номеров
+// недель), BYMONTHDAY (отрицательные значения отсчитываются от конца месяца), COUNT и UNTIL.
+// Время занятий берется из первого занятия
+type Rule struct {
+	Freq       string
+	Interval   int
+	ByDay      []time.Weekday
+	ByMonthDay []int
+	Count      int
+	Until      *time.Time
+}
+
+// Parse разбирает строку вида "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH", допускается префикс "RRULE:"
+func Parse(value string) (*Rule, error) {
+	value = strings.TrimPrefix(strings.TrimSpace(value), "RRULE:")
+	if value == "" {
+		return nil, errors.New("rule is empty")
+	}
+
+	rule := &Rule{Interval: 1}
+	for _, part := range strings.Split(value, ";") {
+		key, val, ok := strings.Cut(part, "=")
+		if !ok || val == "" {
+			return nil, fmt.Errorf("invalid rule part %q", part)
+		}
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			rule.Freq = strings.ToUpper(val)
+			if rule.Freq != Daily && rule.Freq != Weekly && rule.Freq != Monthly {
+				return nil, errors.New("FREQ must be DAILY, WEEKLY or MONTHLY")
+			}
+		case "INTERVAL":
+			interval, err := strconv.Atoi(val)
+			if err != nil || interval < 1 || interval > 365 {
+				return nil, errors.New("INTERVAL must be between 1 and 365")
+			}
+			rule.Interval = interval
+		case "BYDAY":
+			for _, day := range strings.Split(strings.ToUpper(val), ",") {
+				weekday, ok := weekdays[day]
+				if !ok {
+					return nil, fmt.Errorf("invalid BYDAY value %q", day)
+				}
+				if !slices.Contains(rule.ByDay, weekday) {
+					rule.ByDay = append(rule.ByDay, weekday)
+				}
+			}
+		case "BYMONTHDAY":
+			for _, day := range strings.Split(val, ",") {
+				monthDay, err := strconv.Atoi(day)
+				if err != nil || monthDay == 0 || monthDay < -31 || monthDay > 31 {
+					return nil, fmt.Errorf("invalid BYMONTHDAY value %q", day)
+				}
+				if !slices.Contains(rule.ByMonthDay, monthDay) {
+					rule.ByMonthDay = append(rule.ByMonthDay, monthDay)
+				}
+			}
+		case "COUNT":
+			count, err := strconv.Atoi(val)
+			if err != nil || count < 1 {
+				return nil, errors.New("COUNT must be positive")
+			}
+			rule.Count = count
+		case "UNTIL":
+			until, err := parseUntil(val)
+			if err != nil {
+				return nil, errors.New("UNTIL must be a date (20060102) or UTC time (20060102T150405Z)")
+			}
+			rule.Until = &until
+		default:
+			return nil, fmt.Errorf("unsupported rule part %s", key)
+		}
+	}
+
+	if rule.Freq == "" {
+		return nil, errors.New("FREQ is required")
+	}
+	if rule.Count > 0 && rule.Until != nil {
+		return nil, errors.New("COUNT and UNTIL cannot be used together")
+	}
+	if len(rule.ByDay) > 0 && rule.Freq != Weekly {
+		return nil, errors.New("BYDAY is supported only with FREQ=WEEKLY")
+	}
+	if len(rule.ByMonthDay) > 0 && rule.Freq != Monthly {
+		return nil, errors.New("BYMONTHDAY is supported only with FREQ=MONTHLY")
+	}
+	return rule, nil
+}
+
+// String возвращает правило в каноническом виде
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + r.Freq, "INTERVAL=" + strconv.Itoa(r.Interval)}
+	if len(r.ByDay) > 0 {
+		days := make([]string, 0, len(r.ByDay))
+		for _, weekday := range r.ByDay {
+			for name, day := range weekdays {
+				if day == weekday {
+					days = append(days, name)
+				}
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, 0, len(r.ByMonthDay))
+		for _, day := range r.ByMonthDay {
+			days = append(days, strconv.Itoa(day))
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Occurrences возвращает до limit занятий, начиная с момента from включительно.
+// Первое занятие start задает время и часовой пояс, COUNT отсчитывается от него
+func (r *Rule) Occurrences(start, from time.Time, limit int) []time.Time {
+	result := []time.Time{}
+	index := 0
+	for period := 0; period < maxPeriods && len(result) < limit; period++ {
+		for _, occurrence := range r.period(start, period) {
+			if occurrence.Before(start) {
+				continue
+			}
+			if r.Until != nil && occurrence.After(*r.Until) {
+				return result
+			}
+			index++
+			if r.Count > 0 && index > r.Count {
+				return result
+			}
+			if !occurrence.Before(from) {
+				result = append(result, occurrence)
+				if len(result) == limit {
+					return result
+				}
+			}
+		}
+	}
+	return result
+}
+
+// Next возвращает первое занятие строго после after
+func (r *Rule) Next(start, after time.Time) (time.Time, bool) {
+	occurrences := r.Occurrences(start, after.Add(time.Nanosecond), 1)
+	if len(occurrences) == 0 {
+		return time.Time{}, false
+	}
+	return occurrences[0], true
+}
+
+// period возвращает отсортированные занятия периода с номером n
+func (r *Rule) period(start time.Time, n int) []time.Time {
+	hour, minute, second := start.Clock()
+	at := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, hour, minute, second, 0, start.Location())
+	}
+
+	var result []time.Time
+	switch r.Freq {
+	case Daily:
+		day := start.AddDate(0, 0, n*r.Interval)
+		result = append(result, at(day.Year(), day.Month(), day.Day()))
+	case Weekly:
+		// Недели начинаются с понедельника, как WKST=MO по умолчанию
+		offset := (int(start.Weekday()) + 6) % 7
+		monday := start.AddDate(0, 0, -offset+n*r.Interval*7)
+		days := r.ByDay
+		if len(days) == 0 {
+			days = []time.Weekday{start.Weekday()}
+		}
+		for _, weekday := range days {
+			day := monday.AddDate(0, 0, (int(weekday)+6)%7)
+			result = append(result, at(day.Year(), day.Month(), day.Day()))
+		}
+	case Monthly:
+		first := time.Date(start.Year(), start.Month()+time.Month(n*r.Interval), 1, 0, 0, 0, 0, start.Location())
+		lastDay := first.AddDate(0, 1, -1).Day()
+		days := r.ByMonthDay
+		if len(days) == 0 {
+			days = []int{start.Day()}
+		}
+		for _, day := range days {
+			if day < 0 {
+				day = lastDay + day + 1
+			}
+			// Несуществующие даты, например 31 число в апреле, пропускаются
+			if day < 1 || day > lastDay {
+				continue
+			}
+			result = append(result, at(first.Year(), first.Month(), day))
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Before(result[j]) })
+	return result
+}
+
+func parseUntil(value string) (time.Time, error) {
+	if until, err := time.Parse("20060102T150405Z", value); err == nil {
+		return until, nil
+	}
+	until, err := time.Parse("20060102", value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	// Дата без времени включает весь день
+	return until.Add(24*time.Hour - time.Second), nil
+}
@@ -0,0 +1,57 @@
+package security
+
+import (
+	"core/internal"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// NewCSRFToken создает пару nonce/token для защиты HTML-форм (double submit cookie).
+// nonce кладется в cookie, token - в скрытое поле формы
+func NewCSRFToken(scope string) (nonce string, token string, err error) {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", "", err
+	}
+	nonce = hex.EncodeToString(bytes)
+	return nonce, sign("csrf|" + scope + "|" + nonce), nil
+}
+
+// CheckCSRFToken проверяет, что token из формы соответствует nonce из cookie
+func CheckCSRFToken(nonce, scope, token string) bool {
+	if nonce == "" || token == "" {
+		return false
+	}
+	return hmac.Equal([]byte(sign("csrf|"+scope+"|"+nonce)), []byte(token))
+}
+
+// SignPath добавляет к пути срок действия и подпись, чтобы файл можно было
+// отдать по прямой ссылке без токена доступа
+func SignPath(path string, expires time.Time) string {
+	expiresStr := strconv.FormatInt(expires.Unix(), 10)
+	query := url.Values{}
+	query.Set("expires", expiresStr)
+	query.Set("signature", sign("path|"+path+"|"+expiresStr))
+	return fmt.Sprintf("%s?%s", path, query.Encode())
+}
+
+// VerifyPathSignature проверяет подпись и срок действия ссылки, созданной SignPath
+func VerifyPathSignature(path, expires, signature string) bool {
+	expiresUnix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > expiresUnix {
+		return false
+	}
+	return hmac.Equal([]byte(sign("path|"+path+"|"+expires)), []byte(signature))
+}
+
+func sign(value string) string {
+	mac := hmac.New(sha256.New, []byte(internal.KeyJWT))
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
 	"log"
+	"sync"
 	"time"
 )
 
@@ -32,7 +33,115 @@ func CreateToken(isCompany bool, accessID uint, lifetimeSec int) string {
 	return s
 }
 
+// CreateSessionToken создает токен пользователя в одной из его ролей. accessID и isCompany
+// совпадают с токеном CreateToken, поэтому эндпоинты клиентов и компаний работают без
+// изменений. userID - пользователь, role - активная роль, companyID - компания для ролей в ней
+func CreateSessionToken(userID uint, role string, accountID uint, lifetimeSec int) string {
+	isCompany := role != "client"
+	claims := jwt.MapClaims{
+		"isCompany": isCompany,
+		"accessID":  accountID,
+		"userID":    userID,
+		"role":      role,
+		"lifetime":  lifetimeSec, // in seconds
+		"startTime": time.Now().Unix(),
+	}
+	if isCompany {
+		claims["companyID"] = accountID
+	}
+	s, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(internal.KeyJWT))
+	if err != nil {
+		log.Println(err)
+		return ""
+	}
+	return s
+}
+
+// CreateWorkerToken создает токен работника компании. Такие токены не принимаются
+// обычными эндпоинтами клиентов и компаний, см. CheckToken
+func CreateWorkerToken(workerID, companyID uint, lifetimeSec int) string {
+	key := []byte(internal.KeyJWT)
+	t := jwt.NewWithClaims(jwt.SigningMethodHS256,
+		jwt.MapClaims{
+			"isCompany": false,
+			"isWorker":  true,
+			"accessID":  workerID,
+			"companyID": companyID,
+			"lifetime":  lifetimeSec, // in seconds
+			"startTime": time.Now().Unix(),
+		})
+	s, err := t.SignedString(key)
+	if err != nil {
+		log.Println(err)
+		return ""
+	}
+	return s
+}
+
+// CreateAdminToken создает токен администратора платформы
+func CreateAdminToken(adminID uint, lifetimeSec int) string {
+	key := []byte(internal.KeyJWT)
+	t := jwt.NewWithClaims(jwt.SigningMethodHS256,
+		jwt.MapClaims{
+			"isCompany": false,
+			"isAdmin":   true,
+			"accessID":  adminID,
+			"lifetime":  lifetimeSec, // in seconds
+			"startTime": time.Now().Unix(),
+		})
+	s, err := t.SignedString(key)
+	if err != nil {
+		log.Println(err)
+		return ""
+	}
+	return s
+}
+
+// CheckToken проверяет токен клиента или компании
 func CheckToken(tokenS string) (bool, jwt.MapClaims) {
+	ok, claims := parseToken(tokenS)
+	if claims != nil && isWorkerClaims(claims) {
+		log.Println("Worker token used on a client/company endpoint")
+		return false, nil
+	}
+	if claims != nil && isAdminClaims(claims) {
+		log.Println("Admin token used on a client/company endpoint")
+		return false, nil
+	}
+	return ok, claims
+}
+
+// CheckAdminToken проверяет токен администратора
+func CheckAdminToken(tokenS string) (bool, jwt.MapClaims) {
+	ok, claims := parseToken(tokenS)
+	if claims != nil && !isAdminClaims(claims) {
+		log.Println("Non-admin token used on an admin endpoint")
+		return false, nil
+	}
+	return ok, claims
+}
+
+// CheckWorkerToken проверяет токен работника компании
+func CheckWorkerToken(tokenS string) (bool, jwt.MapClaims) {
+	ok, claims := parseToken(tokenS)
+	if claims != nil && !isWorkerClaims(claims) {
+		log.Println("Client/company token used on a worker endpoint")
+		return false, nil
+	}
+	return ok, claims
+}
+
+func isWorkerClaims(claims jwt.MapClaims) bool {
+	isWorker, _ := claims["isWorker"].(bool)
+	return isWorker
+}
+
+func isAdminClaims(claims jwt.MapClaims) bool {
+	isAdmin, _ := claims["isAdmin"].(bool)
+	return isAdmin
+}
+
+func parseToken(tokenS string) (bool, jwt.MapClaims) {
 	secretKey := internal.KeyJWT
 	parsedToken, err := jwt.Parse(tokenS, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
@@ -90,3 +199,18 @@ func HashPassword(password string) (string, error) {
 func CheckPassword(password, hashedPassword string) error {
 	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
 }
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     []byte
+)
+
+// SimulatePasswordCheck тратит на проверку столько же времени, сколько CheckPassword.
+// Вызывается, когда пользователь не найден, чтобы по времени ответа нельзя было
+// узнать, зарегистрирован ли email
+func SimulatePasswordCheck(password string) {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+	})
+	_ = bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+}
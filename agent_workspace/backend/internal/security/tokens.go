@@ -0,0 +1,24 @@
+package security
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// NewOpaqueToken создает случайный токен для ссылок из писем. В базе хранится только
+// хеш, поэтому копия таблицы не позволяет воспользоваться ссылками
+func NewOpaqueToken() (token string, hash string, err error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", "", err
+	}
+	token = hex.EncodeToString(bytes)
+	return token, HashOpaqueToken(token), nil
+}
+
+// HashOpaqueToken возвращает хеш токена для поиска в базе
+func HashOpaqueToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
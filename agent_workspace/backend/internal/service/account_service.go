@@ -0,0 +1,520 @@
+package service
+
+import (
+	"core/internal"
+	"core/internal/database"
+	"core/internal/database/repository"
+	"core/internal/mail"
+	"core/internal/ratelimit"
+	"core/internal/security"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Цели одноразовых токенов из писем
+const (
+	TokenVerifyEmail   = "verify_email"
+	TokenResetPassword = "reset_password"
+	TokenChangeEmail   = "change_email"
+)
+
+// ErrEmailNotVerified возвращается при входе, пока пользователь не подтвердил email
+var ErrEmailNotVerified = errors.New("email is not verified")
+
+// ErrInvalidCredentials единая ошибка входа: по ней нельзя понять, зарегистрирован ли email
+var ErrInvalidCredentials = errors.New("invalid email or password")
+
+const (
+	minPasswordLength = 8
+	maxPasswordLength = 72 // Больше bcrypt не учитывает
+)
+
+type AccountService interface {
+	// SendVerification отправляет ссылку подтверждения email. Предыдущие ссылки перестают работать
+	SendVerification(userID uint, userType string) error
+	// ResendVerification повторно отправляет ссылку по email. Ничего не сообщает о том,
+	// есть ли такой пользователь
+	ResendVerification(email string) error
+	VerifyEmail(token string) error
+	// ForgotPassword отправляет ссылку сброса пароля. Ничего не сообщает о том, есть ли такой пользователь
+	ForgotPassword(email string) error
+	ResetPassword(token, password string) error
+	ChangePassword(userID uint, userType, currentPassword, newPassword string) error
+	// ChangeEmail отправляет подтверждение на новый адрес. Email меняется после перехода по ссылке
+	ChangeEmail(userID uint, userType, currentPassword, newEmail string) error
+	ConfirmEmailChange(token string) error
+	// NotifySecurity сообщает пользователю о важном изменении учетной записи письмом и уведомлением
+	NotifySecurity(userID uint, userType, title, message string)
+}
+
+type accountService struct {
+	userRepo            repository.UserRepository
+	clientRepo          repository.ClientRepository
+	companyRepo         repository.CompanyRepository
+	tokenRepo           repository.AuthTokenRepository
+	notificationService NotificationService
+	mailer              mail.Sender
+}
+
+// account общие поля пользователя, клиента и компании, нужные для операций с учетной записью.
+// Клиенты и компании со своими паролями остались от учетных записей до появления пользователей
+type account struct {
+	ID              uint
+	Type            string
+	Email           string
+	Name            string
+	PasswordHash    string
+	EmailVerifiedAt *time.Time
+}
+
+func (s *accountService) SendVerification(userID uint, userType string) error {
+	acc, err := s.getAccount(userID, userType)
+	if err != nil {
+		return err
+	}
+	if acc.EmailVerifiedAt != nil {
+		return errors.New("email is already verified")
+	}
+
+	token, err := s.issueToken(acc, TokenVerifyEmail, acc.Email, internal.EmailTokenTTL)
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(mail.Message{
+		To:      acc.Email,
+		Subject: "Подтверждение email",
+		Body: fmt.Sprintf("Здравствуйте, %s!\n\nЧтобы подтвердить email, перейдите по ссылке:\n%s\n\n"+
+			"Ссылка действует до %s. Если вы не регистрировались, просто проигнорируйте это письмо.",
+			acc.Name, accountLink("verify-email", token), formatMailTime(time.Now().Add(internal.EmailTokenTTL))),
+	})
+}
+
+func (s *accountService) ResendVerification(email string) error {
+	acc, err := s.findAccount(email)
+	if err != nil || acc.EmailVerifiedAt != nil {
+		return nil
+	}
+	return s.SendVerification(acc.ID, acc.Type)
+}
+
+func (s *accountService) VerifyEmail(token string) error {
+	tx := s.tokenRepo.BeginTransaction()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	authToken, err := s.tokenRepo.ConsumeInTx(tx, security.HashOpaqueToken(token), TokenVerifyEmail, time.Now())
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// Ссылка подтверждает только тот адрес, на который была отправлена
+	acc, err := s.getAccount(authToken.UserID, authToken.UserType)
+	if err != nil || acc.Email != authToken.Email {
+		tx.Rollback()
+		return errors.New("invalid or expired token")
+	}
+
+	if err := s.tokenRepo.UpdateAccountInTx(tx, acc.ID, acc.Type, map[string]interface{}{"email_verified_at": time.Now()}); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+func (s *accountService) ForgotPassword(email string) error {
+	acc, err := s.findAccount(email)
+	if err != nil {
+		return nil
+	}
+
+	token, err := s.issueToken(acc, TokenResetPassword, acc.Email, internal.PasswordResetTTL)
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(mail.Message{
+		To:      acc.Email,
+		Subject: "Восстановление пароля",
+		Body: fmt.Sprintf("Здравствуйте, %s!\n\nЧтобы задать новый пароль, перейдите по ссылке:\n%s\n\n"+
+			"Ссылка одноразовая и действует до %s. Если вы не запрашивали восстановление, "+
+			"проигнорируйте письмо: пароль останется прежним.",
+			acc.Name, accountLink("reset-password", token), formatMailTime(time.Now().Add(internal.PasswordResetTTL))),
+	})
+}
+
+func (s *accountService) ResetPassword(token, password string) error {
+	if err := validatePassword(password); err != nil {
+		return err
+	}
+	passwordHash, err := security.HashPassword(password)
+	if err != nil {
+		return errors.New("failed to hash password")
+	}
+
+	tx := s.tokenRepo.BeginTransaction()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	authToken, err := s.tokenRepo.ConsumeInTx(tx, security.HashOpaqueToken(token), TokenResetPassword, time.Now())
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// Ссылка пришла на почту пользователя, поэтому сброс пароля заодно подтверждает email
+	updates := map[string]interface{}{"password_hash": passwordHash}
+	acc, err := s.getAccount(authToken.UserID, authToken.UserType)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if acc.EmailVerifiedAt == nil && acc.Email == authToken.Email {
+		updates["email_verified_at"] = time.Now()
+	}
+
+	if err := s.tokenRepo.UpdateAccountInTx(tx, acc.ID, acc.Type, updates); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	s.notifySecurity(acc, acc.Email, "Пароль изменен",
+		"Пароль от вашей учетной записи был сброшен по ссылке из письма. Если это были не вы, "+
+			"сразу восстановите пароль и обратитесь в поддержку.")
+	return nil
+}
+
+func (s *accountService) ChangePassword(userID uint, userType, currentPassword, newPassword string) error {
+	acc, err := s.getAccount(userID, userType)
+	if err != nil {
+		return err
+	}
+	if security.CheckPassword(currentPassword, acc.PasswordHash) != nil {
+		return errors.New("current password is incorrect")
+	}
+	if err := validatePassword(newPassword); err != nil {
+		return err
+	}
+	if currentPassword == newPassword {
+		return errors.New("new password must differ from the current one")
+	}
+
+	passwordHash, err := security.HashPassword(newPassword)
+	if err != nil {
+		return errors.New("failed to hash password")
+	}
+
+	if err := s.tokenRepo.UpdateAccount(acc.ID, acc.Type, map[string]interface{}{"password_hash": passwordHash}); err != nil {
+		return err
+	}
+
+	// Ранее отправленные ссылки сброса больше не нужны
+	if err := s.tokenRepo.RevokeUnused(acc.ID, acc.Type, TokenResetPassword); err != nil {
+		log.Printf("failed to revoke password reset tokens of %s %d: %v", acc.Type, acc.ID, err)
+	}
+	s.notifySecurity(acc, acc.Email, "Пароль изменен",
+		"Пароль от вашей учетной записи был изменен. Если это были не вы, сразу восстановите пароль "+
+			"и обратитесь в поддержку.")
+	return nil
+}
+
+func (s *accountService) ChangeEmail(userID uint, userType, currentPassword, newEmail string) error {
+	newEmail = strings.TrimSpace(newEmail)
+	if !strings.Contains(newEmail, "@") {
+		return errors.New("invalid email")
+	}
+
+	acc, err := s.getAccount(userID, userType)
+	if err != nil {
+		return err
+	}
+	if security.CheckPassword(currentPassword, acc.PasswordHash) != nil {
+		return errors.New("current password is incorrect")
+	}
+	if newEmail == acc.Email {
+		return errors.New("new email is the same as the current one")
+	}
+	if s.userRepo.ExistsByEmail(newEmail) {
+		return errors.New("email already exists")
+	}
+
+	token, err := s.issueToken(acc, TokenChangeEmail, newEmail, internal.EmailTokenTTL)
+	if err != nil {
+		return err
+	}
+	err = s.mailer.Send(mail.Message{
+		To:      newEmail,
+		Subject: "Подтверждение нового email",
+		Body: fmt.Sprintf("Здравствуйте, %s!\n\nЧтобы использовать этот адрес для входа, перейдите по ссылке:\n%s\n\n"+
+			"Ссылка действует до %s.",
+			acc.Name, accountLink("confirm-email", token), formatMailTime(time.Now().Add(internal.EmailTokenTTL))),
+	})
+	if err != nil {
+		return err
+	}
+
+	s.notifySecurity(acc, acc.Email, "Запрошена смена email",
+		fmt.Sprintf("Для вашей учетной записи запрошена смена email на %s. Адрес изменится после "+
+			"подтверждения. Если это были не вы, смените пароль.", newEmail))
+	return nil
+}
+
+func (s *accountService) ConfirmEmailChange(token string) error {
+	tx := s.tokenRepo.BeginTransaction()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	authToken, err := s.tokenRepo.ConsumeInTx(tx, security.HashOpaqueToken(token), TokenChangeEmail, time.Now())
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	acc, err := s.getAccount(authToken.UserID, authToken.UserType)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	// Адрес мог занять другой пользователь, пока письмо шло
+	if s.userRepo.ExistsByEmail(authToken.Email) {
+		tx.Rollback()
+		return errors.New("email already exists")
+	}
+
+	err = s.tokenRepo.UpdateAccountInTx(tx, acc.ID, acc.Type, map[string]interface{}{
+		"email":             authToken.Email,
+		"email_verified_at": time.Now(),
+	})
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	// Уведомление уходит на прежний адрес, чтобы владелец узнал о смене
+	s.notifySecurity(acc, acc.Email, "Email изменен",
+		fmt.Sprintf("Email вашей учетной записи изменен на %s. Если это были не вы, обратитесь в поддержку.", authToken.Email))
+	return nil
+}
+
+func (s *accountService) NotifySecurity(userID uint, userType, title, message string) {
+	acc, err := s.getAccount(userID, userType)
+	if err != nil {
+		log.Printf("failed to load %s %d for security notice: %v", userType, userID, err)
+		return
+	}
+	s.notifySecurity(acc, acc.Email, title, message)
+}
+
+// issueToken создает токен и отзывает предыдущие токены с той же целью
+func (s *accountService) issueToken(acc *account, purpose, email string, ttl time.Duration) (string, error) {
+	if err := s.tokenRepo.RevokeUnused(acc.ID, acc.Type, purpose); err != nil {
+		return "", err
+	}
+
+	token, hash, err := security.NewOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	err = s.tokenRepo.Create(&database.AuthToken{
+		UserID:    acc.ID,
+		UserType:  acc.Type,
+		Purpose:   purpose,
+		TokenHash: hash,
+		Email:     email,
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// notifySecurity сообщает об изменении учетной записи письмом на email и уведомлением в кабинете
+func (s *accountService) notifySecurity(acc *account, email, title, message string) {
+	sendSecurityNotice(s.mailer, s.notificationService, acc, email, title, message)
+}
+
+func (s *accountService) getAccount(userID uint, userType string) (*account, error) {
+	return loadAccount(s.userRepo, s.clientRepo, s.companyRepo, userID, userType)
+}
+
+func (s *accountService) findAccount(email string) (*account, error) {
+	email = strings.TrimSpace(email)
+	if email == "" {
+		return nil, errors.New("email is required")
+	}
+	if user, err := s.userRepo.GetByEmail(email); err == nil {
+		return userAccount(user), nil
+	}
+	if exists, _, client := s.clientRepo.ExistsByEmail(email); exists {
+		return clientAccount(&client), nil
+	}
+	if exists, _, company := s.companyRepo.ExistsByEmail(email); exists {
+		return companyAccount(&company), nil
+	}
+	return nil, errors.New("account not found")
+}
+
+// guardLogin проверяет пароль через check с учетом блокировки email после серии неудачных
+// попыток. Блокировка не зависит от того, зарегистрирован ли email. Ошибка проверки
+// пароля возвращается как ErrInvalidCredentials
+func guardLogin(limiter *ratelimit.Limiter, email string, check func() error) error {
+	key := "login:" + strings.ToLower(strings.TrimSpace(email))
+	if err := limiter.CheckLock(key); err != nil {
+		return err
+	}
+	if err := check(); err != nil {
+		limiter.Fail(key)
+		return ErrInvalidCredentials
+	}
+	limiter.Succeed(key)
+	return nil
+}
+
+// loadAccount загружает пользователя, клиента или компанию в виде общей учетной записи
+func loadAccount(userRepo repository.UserRepository, clientRepo repository.ClientRepository, companyRepo repository.CompanyRepository, userID uint, userType string) (*account, error) {
+	if userType == "user" {
+		user, err := userRepo.GetByID(userID)
+		if err != nil {
+			return nil, err
+		}
+		return userAccount(user), nil
+	}
+	if userType == "company" {
+		company, err := companyRepo.GetByID(userID)
+		if err != nil {
+			return nil, err
+		}
+		return companyAccount(company), nil
+	}
+
+	client, err := clientRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	return clientAccount(client), nil
+}
+
+// sendSecurityNotice отправляет письмо и уведомление типа security. Ошибки только
+// записываются в журнал: само изменение уже сохранено
+func sendSecurityNotice(mailer mail.Sender, notificationService NotificationService, acc *account, email, title, message string) {
+	if err := mailer.Send(mail.Message{To: email, Subject: title, Body: message}); err != nil {
+		log.Printf("failed to send security email to %s %d: %v", acc.Type, acc.ID, err)
+	}
+	// Ленты уведомлений есть у клиентов и компаний, пользователю достаточно письма
+	if acc.Type == "user" {
+		return
+	}
+	if err := notificationService.CreateNotification(acc.ID, acc.Type, title, message, "security", nil); err != nil {
+		log.Printf("failed to create security notification for %s %d: %v", acc.Type, acc.ID, err)
+	}
+}
+
+func userAccount(user *database.UserDB) *account {
+	return &account{
+		ID:              user.ID,
+		Type:            "user",
+		Email:           user.Email,
+		Name:            user.FullName,
+		PasswordHash:    user.PasswordHash,
+		EmailVerifiedAt: user.EmailVerifiedAt,
+	}
+}
+
+func clientAccount(client *database.ClientDB) *account {
+	return &account{
+		ID:              client.ID,
+		Type:            "client",
+		Email:           client.Email,
+		Name:            client.FullName,
+		PasswordHash:    client.PasswordHash,
+		EmailVerifiedAt: client.EmailVerifiedAt,
+	}
+}
+
+func companyAccount(company *database.CompanyDB) *account {
+	return &account{
+		ID:              company.ID,
+		Type:            "company",
+		Email:           company.Email,
+		Name:            company.CompanyName,
+		PasswordHash:    company.PasswordHash,
+		EmailVerifiedAt: company.EmailVerifiedAt,
+	}
+}
+
+func validatePassword(password string) error {
+	if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+		return fmt.Errorf("password must be between %d and %d characters", minPasswordLength, maxPasswordLength)
+	}
+	return nil
+}
+
+// signupVerifiedAt время подтверждения email нового пользователя. Если подтверждение
+// не требуется, email считается подтвержденным сразу
+func signupVerifiedAt() *time.Time {
+	if internal.EmailVerificationRequired {
+		return nil
+	}
+	now := time.Now()
+	return &now
+}
+
+// requestVerification отправляет новому пользователю письмо с подтверждением email.
+// Ошибка отправки не мешает регистрации: письмо можно запросить повторно
+func requestVerification(accountService AccountService, user *database.UserDB) {
+	if user.EmailVerifiedAt != nil {
+		return
+	}
+	if err := accountService.SendVerification(user.ID, "user"); err != nil {
+		log.Printf("failed to send verification email to user %d: %v", user.ID, err)
+	}
+}
+
+// accountLink ссылка на страницу сайта, которая передает токен в API
+func accountLink(page, token string) string {
+	return strings.TrimRight(internal.AccountLinkBaseURL, "/") + "/" + page + "?token=" + url.QueryEscape(token)
+}
+
+func formatMailTime(t time.Time) string {
+	return t.In(internal.TimeZone).Format("02.01.2006 15:04")
+}
+
+func NewAccountService(
+	userRepo repository.UserRepository,
+	clientRepo repository.ClientRepository,
+	companyRepo repository.CompanyRepository,
+	tokenRepo repository.AuthTokenRepository,
+	notificationService NotificationService,
+	mailer mail.Sender,
+) AccountService {
+	return &accountService{
+		userRepo:            userRepo,
+		clientRepo:          clientRepo,
+		companyRepo:         companyRepo,
+		tokenRepo:           tokenRepo,
+		notificationService: notificationService,
+		mailer:              mailer,
+	}
+}
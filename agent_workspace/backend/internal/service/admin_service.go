@@ -0,0 +1,70 @@
+package service
+
+import (
+	"core/internal/api"
+	"core/internal/database"
+	"core/internal/database/repository"
+	"core/internal/ratelimit"
+	"core/internal/security"
+	"errors"
+)
+
+type AdminService interface {
+	Login(request *api.LoginRequest) (*database.AdminDB, error)
+	EnsureAdmin(email, password string) error
+}
+
+type adminService struct {
+	adminRepo repository.AdminRepository
+	limiter   *ratelimit.Limiter
+}
+
+func (s *adminService) Login(request *api.LoginRequest) (*database.AdminDB, error) {
+	var admin *database.AdminDB
+	err := guardLogin(s.limiter, request.Email, func() (err error) {
+		admin, err = s.adminRepo.GetByEmail(request.Email)
+		if err != nil {
+			// Незарегистрированный email проверяется так же долго, как неверный пароль
+			security.SimulatePasswordCheck(request.Password)
+			return err
+		}
+		return security.CheckPassword(request.Password, admin.PasswordHash)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !admin.IsActive {
+		return nil, errors.New("admin account is deactivated")
+	}
+
+	return admin, nil
+}
+
+// EnsureAdmin создает первого администратора из настроек окружения, если его еще нет
+func (s *adminService) EnsureAdmin(email, password string) error {
+	if email == "" || password == "" {
+		return nil
+	}
+
+	exists, err := s.adminRepo.ExistsByEmail(email)
+	if err != nil || exists {
+		return err
+	}
+
+	hashedPassword, err := security.HashPassword(password)
+	if err != nil {
+		return errors.New("failed to hash password")
+	}
+
+	return s.adminRepo.Create(&database.AdminDB{
+		FullName:     "Administrator",
+		Email:        email,
+		PasswordHash: hashedPassword,
+		IsActive:     true,
+	})
+}
+
+func NewAdminService(adminRepo repository.AdminRepository, limiter *ratelimit.Limiter) AdminService {
+	return &adminService{adminRepo: adminRepo, limiter: limiter}
+}
@@ -0,0 +1,200 @@
+package service
+
+import (
+	"core/internal"
+	"core/internal/api"
+	"core/internal/database/repository"
+	"errors"
+	"time"
+)
+
+const (
+	analyticsDateLayout  = "2006-01-02"
+	defaultAnalyticsDays = 30
+	maxAnalyticsDays     = 731
+	maxAnalyticsPoints   = 366
+)
+
+var analyticsIntervals = map[string]bool{
+	"day":   true,
+	"week":  true,
+	"month": true,
+}
+
+type AnalyticsService interface {
+	// GetCompanyAnalytics строит отчет по заказам, созданным с from по to включительно.
+	// Пустые даты означают последние 30 дней
+	GetCompanyAnalytics(companyID uint, from, to, interval string) (*api.CompanyAnalytics, error)
+}
+
+type analyticsService struct {
+	analyticsRepo repository.AnalyticsRepository
+	companyRepo   repository.CompanyRepository
+}
+
+func (s *analyticsService) GetCompanyAnalytics(companyID uint, from, to, interval string) (*api.CompanyAnalytics, error) {
+	if interval == "" {
+		interval = "day"
+	}
+	if !analyticsIntervals[interval] {
+		return nil, errors.New("interval must be day, week or month")
+	}
+
+	fromDate, toDate, err := parseAnalyticsRange(from, to)
+	if err != nil {
+		return nil, err
+	}
+	periods := analyticsPeriods(fromDate, toDate, interval)
+	if len(periods) > maxAnalyticsPoints {
+		return nil, errors.New("too many points in series, use a larger interval")
+	}
+
+	company, err := s.companyRepo.GetByID(companyID)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := repository.AnalyticsRange{
+		CompanyID: companyID,
+		Currency:  company.Currency,
+		From:      fromDate,
+		To:        toDate.AddDate(0, 0, 1),
+		Interval:  interval,
+		TimeZone:  internal.TimeZone.String(),
+	}
+	buckets, err := s.analyticsRepo.GetOrderSeries(filter)
+	if err != nil {
+		return nil, err
+	}
+	cards, err := s.analyticsRepo.GetCardBreakdown(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	// Интервалы без заказов тоже попадают в ряд, с нулевыми значениями
+	byPeriod := make(map[string]repository.AnalyticsMetrics, len(buckets))
+	var totals repository.AnalyticsMetrics
+	for _, bucket := range buckets {
+		byPeriod[bucket.Bucket.Format(analyticsDateLayout)] = bucket.AnalyticsMetrics
+		totals = addAnalyticsMetrics(totals, bucket.AnalyticsMetrics)
+	}
+
+	response := &api.CompanyAnalytics{
+		From:     fromDate.Format(analyticsDateLayout),
+		To:       toDate.Format(analyticsDateLayout),
+		Interval: interval,
+		Currency: company.Currency,
+		Totals:   convertAnalyticsMetrics(totals),
+		Series:   []api.AnalyticsPoint{},
+		Cards:    []api.CardAnalytics{},
+	}
+	for _, period := range periods {
+		key := period.Format(analyticsDateLayout)
+		response.Series = append(response.Series, api.AnalyticsPoint{
+			Period:           key,
+			AnalyticsMetrics: convertAnalyticsMetrics(byPeriod[key]),
+		})
+	}
+	for _, card := range cards {
+		response.Cards = append(response.Cards, api.CardAnalytics{
+			CardID:           card.CardID,
+			Title:            card.Title,
+			AnalyticsMetrics: convertAnalyticsMetrics(card.AnalyticsMetrics),
+		})
+	}
+	return response, nil
+}
+
+// parseAnalyticsRange разбирает даты отчета в часовом поясе платформы
+func parseAnalyticsRange(from, to string) (time.Time, time.Time, error) {
+	now := time.Now().In(internal.TimeZone)
+	toDate := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, internal.TimeZone)
+	if to != "" {
+		parsed, err := time.ParseInLocation(analyticsDateLayout, to, internal.TimeZone)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("invalid to date, expected YYYY-MM-DD")
+		}
+		toDate = parsed
+	}
+
+	fromDate := toDate.AddDate(0, 0, -(defaultAnalyticsDays - 1))
+	if from != "" {
+		parsed, err := time.ParseInLocation(analyticsDateLayout, from, internal.TimeZone)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("invalid from date, expected YYYY-MM-DD")
+		}
+		fromDate = parsed
+	}
+
+	if fromDate.After(toDate) {
+		return time.Time{}, time.Time{}, errors.New("from must not be after to")
+	}
+	if toDate.Sub(fromDate) > maxAnalyticsDays*24*time.Hour {
+		return time.Time{}, time.Time{}, errors.New("date range must not exceed two years")
+	}
+	return fromDate, toDate, nil
+}
+
+// analyticsPeriods возвращает начала интервалов, пересекающихся с периодом отчета.
+// Границы совпадают с date_trunc Postgres: неделя начинается с понедельника
+func analyticsPeriods(fromDate, toDate time.Time, interval string) []time.Time {
+	start := fromDate
+	switch interval {
+	case "week":
+		offset := (int(start.Weekday()) + 6) % 7
+		start = start.AddDate(0, 0, -offset)
+	case "month":
+		start = time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, start.Location())
+	}
+
+	periods := []time.Time{}
+	for period := start; !period.After(toDate); {
+		periods = append(periods, period)
+		switch interval {
+		case "week":
+			period = period.AddDate(0, 0, 7)
+		case "month":
+			period = period.AddDate(0, 1, 0)
+		default:
+			period = period.AddDate(0, 0, 1)
+		}
+	}
+	return periods
+}
+
+func addAnalyticsMetrics(a, b repository.AnalyticsMetrics) repository.AnalyticsMetrics {
+	return repository.AnalyticsMetrics{
+		Orders:            a.Orders + b.Orders,
+		Paid:              a.Paid + b.Paid,
+		Cancelled:         a.Cancelled + b.Cancelled,
+		Finished:          a.Finished + b.Finished,
+		Revenue:           a.Revenue + b.Revenue,
+		Earnings:          a.Earnings + b.Earnings,
+		CompletionSeconds: a.CompletionSeconds + b.CompletionSeconds,
+		CompletionCount:   a.CompletionCount + b.CompletionCount,
+	}
+}
+
+func convertAnalyticsMetrics(metrics repository.AnalyticsMetrics) api.AnalyticsMetrics {
+	result := api.AnalyticsMetrics{
+		Orders:    metrics.Orders,
+		Paid:      metrics.Paid,
+		Cancelled: metrics.Cancelled,
+		Finished:  metrics.Finished,
+		Revenue:   metrics.Revenue,
+		Earnings:  metrics.Earnings,
+	}
+	if metrics.Orders > 0 {
+		result.ConversionRate = float64(metrics.Paid) / float64(metrics.Orders)
+		result.CancellationRate = float64(metrics.Cancelled) / float64(metrics.Orders)
+	}
+	if metrics.CompletionCount > 0 {
+		hours := metrics.CompletionSeconds / float64(metrics.CompletionCount) / 3600
+		result.AvgCompletionHours = &hours
+	}
+	return result
+}
+
+func NewAnalyticsService(analyticsRepo repository.AnalyticsRepository, companyRepo repository.CompanyRepository) AnalyticsService {
+	return &analyticsService{analyticsRepo: analyticsRepo, companyRepo: companyRepo}
+}
@@ -4,42 +4,54 @@ import (
 	"core/internal/api"
 	"core/internal/database"
 	"core/internal/database/repository"
+	"core/internal/money"
+	"core/internal/pagination"
 	"errors"
-	"fmt"
+	"log"
 	"time"
 )
 
 type BalanceService interface {
-	GetClientBalance(clientID uint) (float64, error)
-	GetCompanyBalance(companyID uint) (float64, error)
-	DepositClientBalance(clientID uint, amount float64) error
-	DepositCompanyBalance(companyID uint, amount float64) error
-	WithdrawCompanyBalance(companyID uint, amount float64) error
-	GetClientTransactions(clientID uint, page, limit int) ([]database.BalanceTransaction, error)
-	GetCompanyTransactions(companyID uint, page, limit int) ([]database.BalanceTransaction, error)
-	GetTransactionHistory(userID uint, userType string, limit, offset int) ([]api.BalanceHistoryItem, int, error)
+	GetClientBalance(clientID uint) (money.Money, error)
+	GetCompanyBalance(companyID uint) (money.Money, error)
+	DepositClientBalance(clientID uint, amount money.Minor) error
+	DepositCompanyBalance(companyID uint, amount money.Minor) error
+	WithdrawCompanyBalance(companyID uint, amount money.Minor) error
+	GetClientTransactions(clientID uint, page pagination.Request) ([]database.BalanceTransaction, pagination.Page, error)
+	GetCompanyTransactions(companyID uint, page pagination.Request) ([]database.BalanceTransaction, pagination.Page, error)
+	GetTransactionHistory(userID uint, userType string, page pagination.Request) ([]api.BalanceHistoryItem, int, pagination.Page, error)
+	// SetCurrency меняет валюту счета. Баланс должен быть нулевым, а оплаченные заказы завершены.
+	// Цены карточек компании пересчитываются по текущему курсу
+	SetCurrency(userID uint, userType, currency string) error
 }
 
 type balanceService struct {
-	balanceRepo repository.BalanceRepository
+	balanceRepo     repository.BalanceRepository
+	documentService DocumentService
+	rates           money.RateProvider
 }
 
-func (s *balanceService) GetClientBalance(clientID uint) (float64, error) {
+func (s *balanceService) GetClientBalance(clientID uint) (money.Money, error) {
 	return s.balanceRepo.GetClientBalance(clientID)
 }
 
-func (s *balanceService) GetCompanyBalance(companyID uint) (float64, error) {
+func (s *balanceService) GetCompanyBalance(companyID uint) (money.Money, error) {
 	return s.balanceRepo.GetCompanyBalance(companyID)
 }
 
-func (s *balanceService) DepositClientBalance(clientID uint, amount float64) error {
+func (s *balanceService) DepositClientBalance(clientID uint, amount money.Minor) error {
 	if amount <= 0 {
 		return errors.New("amount must be greater than 0")
 	}
 
+	balance, err := s.balanceRepo.GetClientBalance(clientID)
+	if err != nil {
+		return err
+	}
+
 	// В реальной системе здесь была бы интеграция с платежным шлюзом
 	// Пока просто добавляем деньги на счет
-	err := s.balanceRepo.UpdateClientBalance(clientID, amount)
+	err = s.balanceRepo.UpdateClientBalance(clientID, amount)
 	if err != nil {
 		return err
 	}
@@ -49,15 +61,20 @@ func (s *balanceService) DepositClientBalance(clientID uint, amount float64) err
 		UserID:      clientID,
 		UserType:    "client",
 		Amount:      amount,
+		Currency:    balance.Currency,
 		Type:        "deposit",
 		Status:      "completed",
-		Description: fmt.Sprintf("Пополнение баланса на %.2f руб.", amount),
+		Description: "Пополнение баланса на " + money.FormatCode(money.New(amount, balance.Currency), money.DefaultLocale),
 	}
 
-	return s.balanceRepo.CreateTransaction(transaction)
+	if err := s.balanceRepo.CreateTransaction(transaction); err != nil {
+		return err
+	}
+	s.issueDocument(transaction.ID)
+	return nil
 }
 
-func (s *balanceService) WithdrawCompanyBalance(companyID uint, amount float64) error {
+func (s *balanceService) WithdrawCompanyBalance(companyID uint, amount money.Minor) error {
 	if amount <= 0 {
 		return errors.New("amount must be greater than 0")
 	}
@@ -68,7 +85,7 @@ func (s *balanceService) WithdrawCompanyBalance(companyID uint, amount float64)
 		return err
 	}
 
-	if balance < amount {
+	if balance.Amount < amount {
 		return errors.New("insufficient balance")
 	}
 
@@ -83,31 +100,39 @@ func (s *balanceService) WithdrawCompanyBalance(companyID uint, amount float64)
 		UserID:      companyID,
 		UserType:    "company",
 		Amount:      -amount,
+		Currency:    balance.Currency,
 		Type:        "withdrawal",
 		Status:      "completed",
-		Description: fmt.Sprintf("Вывод средств %.2f руб.", amount),
+		Description: "Вывод средств " + money.FormatCode(money.New(amount, balance.Currency), money.DefaultLocale),
 	}
 
-	return s.balanceRepo.CreateTransaction(transaction)
+	if err := s.balanceRepo.CreateTransaction(transaction); err != nil {
+		return err
+	}
+	s.issueDocument(transaction.ID)
+	return nil
 }
 
-func (s *balanceService) GetClientTransactions(clientID uint, page, limit int) ([]database.BalanceTransaction, error) {
-	offset := (page - 1) * limit
-	return s.balanceRepo.GetTransactionsByUser(clientID, "client", limit, offset)
+func (s *balanceService) GetClientTransactions(clientID uint, page pagination.Request) ([]database.BalanceTransaction, pagination.Page, error) {
+	return s.balanceRepo.GetTransactionsByUser(clientID, "client", page)
 }
 
-func (s *balanceService) GetCompanyTransactions(companyID uint, page, limit int) ([]database.BalanceTransaction, error) {
-	offset := (page - 1) * limit
-	return s.balanceRepo.GetTransactionsByUser(companyID, "company", limit, offset)
+func (s *balanceService) GetCompanyTransactions(companyID uint, page pagination.Request) ([]database.BalanceTransaction, pagination.Page, error) {
+	return s.balanceRepo.GetTransactionsByUser(companyID, "company", page)
 }
 
-func (s *balanceService) DepositCompanyBalance(companyID uint, amount float64) error {
+func (s *balanceService) DepositCompanyBalance(companyID uint, amount money.Minor) error {
 	if amount <= 0 {
 		return errors.New("amount must be greater than 0")
 	}
 
+	balance, err := s.balanceRepo.GetCompanyBalance(companyID)
+	if err != nil {
+		return err
+	}
+
 	// Добавляем деньги на счет компании
-	err := s.balanceRepo.UpdateCompanyBalance(companyID, amount)
+	err = s.balanceRepo.UpdateCompanyBalance(companyID, amount)
 	if err != nil {
 		return err
 	}
@@ -117,23 +142,28 @@ func (s *balanceService) DepositCompanyBalance(companyID uint, amount float64) e
 		UserID:      companyID,
 		UserType:    "company",
 		Amount:      amount,
+		Currency:    balance.Currency,
 		Type:        "deposit",
 		Status:      "completed",
-		Description: fmt.Sprintf("Пополнение баланса на %.2f руб.", amount),
+		Description: "Пополнение баланса на " + money.FormatCode(money.New(amount, balance.Currency), money.DefaultLocale),
 	}
 
-	return s.balanceRepo.CreateTransaction(transaction)
+	if err := s.balanceRepo.CreateTransaction(transaction); err != nil {
+		return err
+	}
+	s.issueDocument(transaction.ID)
+	return nil
 }
 
-func (s *balanceService) GetTransactionHistory(userID uint, userType string, limit, offset int) ([]api.BalanceHistoryItem, int, error) {
-	transactions, err := s.balanceRepo.GetTransactionsByUser(userID, userType, limit, offset)
+func (s *balanceService) GetTransactionHistory(userID uint, userType string, page pagination.Request) ([]api.BalanceHistoryItem, int, pagination.Page, error) {
+	transactions, next, err := s.balanceRepo.GetTransactionsByUser(userID, userType, page)
 	if err != nil {
-		return nil, 0, err
+		return nil, 0, pagination.Page{}, err
 	}
 
 	total, err := s.balanceRepo.GetTransactionCountByUser(userID, userType)
 	if err != nil {
-		return nil, 0, err
+		return nil, 0, pagination.Page{}, err
 	}
 
 	var historyItems []api.BalanceHistoryItem
@@ -141,16 +171,47 @@ func (s *balanceService) GetTransactionHistory(userID uint, userType string, lim
 		historyItems = append(historyItems, api.BalanceHistoryItem{
 			ID:          transaction.ID,
 			Amount:      transaction.Amount,
+			Currency:    transaction.Currency,
 			Type:        transaction.Type,
 			Status:      transaction.Status,
 			Description: transaction.Description,
 			CreatedAt:   transaction.CreatedAt.Format(time.RFC3339),
+			OrderID:     transaction.OrderID,
 		})
 	}
 
-	return historyItems, total, nil
+	return historyItems, total, next, nil
+}
+
+func (s *balanceService) SetCurrency(userID uint, userType, currency string) error {
+	currency, err := money.NormalizeCurrency(currency)
+	if err != nil {
+		return err
+	}
+
+	if userType != "company" {
+		return s.balanceRepo.ChangeClientCurrency(userID, currency)
+	}
+
+	balance, err := s.balanceRepo.GetCompanyBalance(userID)
+	if err != nil {
+		return err
+	}
+	rate, err := s.rates.Rate(balance.Currency, currency)
+	if err != nil {
+		return err
+	}
+	return s.balanceRepo.ChangeCompanyCurrency(userID, currency, rate)
+}
+
+// issueDocument выпускает квитанцию или выписку сразу после операции. Ошибка не отменяет
+// операцию: документ будет сформирован при первом запросе
+func (s *balanceService) issueDocument(transactionID uint) {
+	if _, err := s.documentService.IssueTransactionDocument(transactionID); err != nil {
+		log.Printf("failed to issue document for transaction %d: %v", transactionID, err)
+	}
 }
 
-func NewBalanceService(balanceRepo repository.BalanceRepository) BalanceService {
-	return &balanceService{balanceRepo: balanceRepo}
+func NewBalanceService(balanceRepo repository.BalanceRepository, documentService DocumentService, rates money.RateProvider) BalanceService {
+	return &balanceService{balanceRepo: balanceRepo, documentService: documentService, rates: rates}
 }
@@ -1,28 +1,55 @@
 package service
 
 import (
+	"core/internal/api"
 	"core/internal/database"
 	"core/internal/database/repository"
+	"core/internal/geo"
+	"core/internal/money"
+	"core/internal/pagination"
 	"errors"
+	"log"
+	"time"
 )
 
+const (
+	defaultSearchRadiusKm = 25
+	maxSearchRadiusKm     = 500
+)
+
+// Допустимые варианты сортировки результатов поиска
+var cardSearchSorts = map[string]bool{
+	"relevance":  true,
+	"price_asc":  true,
+	"price_desc": true,
+	"rating":     true,
+	"newest":     true,
+	"distance":   true,
+}
+
 type CardService interface {
-	CreateCard(companyID uint, title, description, category, location string, price float64) (*database.Card, error)
+	CreateCard(companyID uint, title, description, category string, categoryID *uint, location string, price money.Minor) (*database.Card, error)
 	GetCardByID(id uint) (*database.Card, error)
-	GetCardsByCompany(companyID uint, page, limit int) ([]database.Card, error)
-	GetAllCards(page, limit int) ([]database.Card, error)
-	GetCardsByCategory(category string, page, limit int) ([]database.Card, error)
-	UpdateCard(cardID, companyID uint, title, description, category, location string, price float64) (*database.Card, error)
+	GetCardsByCompany(companyID uint, page pagination.Request) ([]api.ExtendedCardResponse, pagination.Page, error)
+	GetAllCards(page pagination.Request) ([]database.Card, pagination.Page, error)
+	GetCardsByCategory(category string, page pagination.Request) ([]database.Card, pagination.Page, error)
+	UpdateCard(cardID, companyID uint, title, description, category string, categoryID *uint, location string, price money.Minor) (*database.Card, error)
 	DeleteCard(cardID, companyID uint) error
-	SearchCards(query string, page, limit int) ([]database.Card, error)
+	SearchCards(query string, page pagination.Request) ([]database.Card, pagination.Page, error)
 	GetCardsByPriceRange(minPrice, maxPrice float64, page, limit int) ([]database.Card, error)
+	FindCards(filter repository.CardSearchFilter, page, limit int) (*api.CardSearchResponse, error)
 }
 
 type cardService struct {
-	cardRepo repository.CardRepository
+	cardRepo        repository.CardRepository
+	companyRepo     repository.CompanyRepository
+	categoryRepo    repository.CategoryRepository
+	favoriteRepo    repository.FavoriteRepository
+	geocoder        geo.Geocoder
+	favoriteService FavoriteService
 }
 
-func (s *cardService) CreateCard(companyID uint, title, description, category, location string, price float64) (*database.Card, error) {
+func (s *cardService) CreateCard(companyID uint, title, description, category string, categoryID *uint, location string, price money.Minor) (*database.Card, error) {
 	if title == "" {
 		return nil, errors.New("title cannot be empty")
 	}
@@ -33,44 +60,83 @@ func (s *cardService) CreateCard(companyID uint, title, description, category, l
 		return nil, errors.New("price must be greater than 0")
 	}
 
+	resolved, err := s.resolveCategory(category, categoryID)
+	if err != nil {
+		return nil, err
+	}
+	if resolved == nil {
+		return nil, errors.New("category is required")
+	}
+
+	// Цена указывается в валюте счета компании
+	company, err := s.companyRepo.GetByID(companyID)
+	if err != nil {
+		return nil, err
+	}
+
 	card := &database.Card{
 		Title:       title,
 		Description: description,
-		Category:    category,
+		Category:    resolved.NameRu,
+		CategoryID:  &resolved.ID,
 		Location:    location,
 		Price:       price,
+		Currency:    company.Currency,
 		CompanyID:   companyID,
 		IsActive:    true,
 	}
+	s.geocodeLocation(card)
+
+	err = s.cardRepo.Create(card)
+	if err != nil {
+		return nil, err
+	}
 
-	err := s.cardRepo.Create(card)
+	created, err := s.cardRepo.GetByID(card.ID)
 	if err != nil {
 		return nil, err
 	}
 
-	return s.cardRepo.GetByID(card.ID)
+	// Карточка уже сохранена, поэтому ошибка рассылки не отменяет создание
+	if err := s.favoriteService.NotifyNewCard(created); err != nil {
+		log.Printf("failed to notify clients about card %d: %v", created.ID, err)
+	}
+	return created, nil
 }
 
 func (s *cardService) GetCardByID(id uint) (*database.Card, error) {
 	return s.cardRepo.GetByID(id)
 }
 
-func (s *cardService) GetCardsByCompany(companyID uint, page, limit int) ([]database.Card, error) {
-	offset := (page - 1) * limit
-	return s.cardRepo.GetByCompanyID(companyID, limit, offset)
+// GetCardsByCompany возвращает карточки компании вместе с числом добавлений в избранное
+func (s *cardService) GetCardsByCompany(companyID uint, page pagination.Request) ([]api.ExtendedCardResponse, pagination.Page, error) {
+	cards, next, err := s.cardRepo.GetByCompanyID(companyID, page)
+	if err != nil {
+		return nil, pagination.Page{}, err
+	}
+
+	responses, err := s.convertCardsWithFavorites(cards)
+	if err != nil {
+		return nil, pagination.Page{}, err
+	}
+	return responses, next, nil
 }
 
-func (s *cardService) GetAllCards(page, limit int) ([]database.Card, error) {
-	offset := (page - 1) * limit
-	return s.cardRepo.GetAll(limit, offset)
+func (s *cardService) GetAllCards(page pagination.Request) ([]database.Card, pagination.Page, error) {
+	return s.cardRepo.GetAll(page)
 }
 
-func (s *cardService) GetCardsByCategory(category string, page, limit int) ([]database.Card, error) {
-	offset := (page - 1) * limit
-	return s.cardRepo.GetByCategory(category, limit, offset)
+// GetCardsByCategory возвращает карточки категории вместе с ее подкатегориями.
+// Категория задается слагом или названием
+func (s *cardService) GetCardsByCategory(category string, page pagination.Request) ([]database.Card, pagination.Page, error) {
+	ids, err := s.categorySubtree(category)
+	if err != nil {
+		return nil, pagination.Page{}, err
+	}
+	return s.cardRepo.GetByCategoryIDs(ids, page)
 }
 
-func (s *cardService) UpdateCard(cardID, companyID uint, title, description, category, location string, price float64) (*database.Card, error) {
+func (s *cardService) UpdateCard(cardID, companyID uint, title, description, category string, categoryID *uint, location string, price money.Minor) (*database.Card, error) {
 	card, err := s.cardRepo.GetByID(cardID)
 	if err != nil {
 		return nil, err
@@ -86,11 +152,17 @@ func (s *cardService) UpdateCard(cardID, companyID uint, title, description, cat
 	if description != "" {
 		card.Description = description
 	}
-	if category != "" {
-		card.Category = category
+	resolved, err := s.resolveCategory(category, categoryID)
+	if err != nil {
+		return nil, err
+	}
+	if resolved != nil {
+		card.Category = resolved.NameRu
+		card.CategoryID = &resolved.ID
 	}
-	if location != "" {
+	if location != "" && location != card.Location {
 		card.Location = location
+		s.geocodeLocation(card)
 	}
 	if price > 0 {
 		card.Price = price
@@ -117,9 +189,8 @@ func (s *cardService) DeleteCard(cardID, companyID uint) error {
 	return s.cardRepo.Delete(cardID)
 }
 
-func (s *cardService) SearchCards(query string, page, limit int) ([]database.Card, error) {
-	offset := (page - 1) * limit
-	return s.cardRepo.SearchByTitle(query, limit, offset)
+func (s *cardService) SearchCards(query string, page pagination.Request) ([]database.Card, pagination.Page, error) {
+	return s.cardRepo.SearchByTitle(query, page)
 }
 
 func (s *cardService) GetCardsByPriceRange(minPrice, maxPrice float64, page, limit int) ([]database.Card, error) {
@@ -127,6 +198,195 @@ func (s *cardService) GetCardsByPriceRange(minPrice, maxPrice float64, page, lim
 	return s.cardRepo.GetByPriceRange(minPrice, maxPrice, limit, offset)
 }
 
-func NewCardService(cardRepo repository.CardRepository) CardService {
-	return &cardService{cardRepo: cardRepo}
+func (s *cardService) FindCards(filter repository.CardSearchFilter, page, limit int) (*api.CardSearchResponse, error) {
+	if err := validateCardSearchFilter(&filter); err != nil {
+		return nil, err
+	}
+
+	if filter.Category != "" {
+		ids, err := s.categorySubtree(filter.Category)
+		if err != nil {
+			return nil, err
+		}
+		filter.CategoryIDs = ids
+	}
+
+	filter.Limit = limit
+	filter.Offset = (page - 1) * limit
+
+	cards, total, err := s.cardRepo.Search(filter)
+	if err != nil {
+		return nil, err
+	}
+	facets, err := s.cardRepo.SearchFacets(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &api.CardSearchResponse{
+		Total: total,
+		Page:  page,
+		Limit: limit,
+		Facets: api.CardSearchFacets{
+			Categories:  convertFacetCounts(facets.Categories),
+			PriceRanges: convertFacetCounts(facets.PriceRanges),
+			Ratings:     convertFacetCounts(facets.Ratings),
+			Verified:    facets.Verified,
+		},
+	}
+	response.Cards, err = s.convertCardsWithFavorites(cards)
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// convertCardsWithFavorites преобразует карточки в ответ и добавляет число добавлений в избранное
+func (s *cardService) convertCardsWithFavorites(cards []database.Card) ([]api.ExtendedCardResponse, error) {
+	cardIDs := make([]uint, 0, len(cards))
+	for _, card := range cards {
+		cardIDs = append(cardIDs, card.ID)
+	}
+	counts, err := s.favoriteRepo.CountByCards(cardIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := []api.ExtendedCardResponse{}
+	for _, card := range cards {
+		response := convertCardToExtendedResponse(card)
+		response.FavoriteCount = counts[card.ID]
+		responses = append(responses, response)
+	}
+	return responses, nil
+}
+
+// validateCardSearchFilter проверяет параметры поиска и подставляет значения по умолчанию
+func validateCardSearchFilter(filter *repository.CardSearchFilter) error {
+	if filter.Sort == "" {
+		filter.Sort = "relevance"
+	}
+	if !cardSearchSorts[filter.Sort] {
+		return errors.New("unknown sort: " + filter.Sort)
+	}
+	if filter.MinPrice != nil && filter.MaxPrice != nil && *filter.MinPrice > *filter.MaxPrice {
+		return errors.New("min_price must not exceed max_price")
+	}
+	if (filter.Latitude == nil) != (filter.Longitude == nil) {
+		return errors.New("both lat and lon must be provided")
+	}
+	if filter.Latitude != nil {
+		point := geo.Point{Latitude: *filter.Latitude, Longitude: *filter.Longitude}
+		if err := point.Validate(); err != nil {
+			return err
+		}
+		if filter.RadiusKm == 0 {
+			filter.RadiusKm = defaultSearchRadiusKm
+		}
+		if filter.RadiusKm < 0 || filter.RadiusKm > maxSearchRadiusKm {
+			return errors.New("radius_km must be between 0 and 500")
+		}
+	} else if filter.Sort == "distance" {
+		return errors.New("sort by distance requires lat and lon")
+	}
+	return nil
+}
+
+func convertCardToExtendedResponse(card database.Card) api.ExtendedCardResponse {
+	response := api.ExtendedCardResponse{
+		ID:          card.ID,
+		Title:       card.Title,
+		Description: card.Description,
+		Category:    card.Category,
+		CategoryID:  card.CategoryID,
+		Location:    card.Location,
+		Price:       card.Price,
+		Currency:    card.Currency,
+		IsActive:    card.IsActive,
+		CompanyID:   card.CompanyID,
+		Latitude:    card.Latitude,
+		Longitude:   card.Longitude,
+		DistanceKm:  card.DistanceKm,
+		CreatedAt:   card.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:   card.UpdatedAt.Format(time.RFC3339),
+	}
+	response.Company.ID = card.Company.ID
+	response.Company.CompanyName = card.Company.CompanyName
+	response.Company.Stars = card.Company.Stars
+	response.Company.ReviewCount = card.Company.ReviewCount
+	response.Company.Photo = card.Company.Photo
+	response.Company.IsVerified = card.Company.IsVerified
+	if response.Latitude == nil {
+		response.Latitude = card.Company.Latitude
+		response.Longitude = card.Company.Longitude
+	}
+	return response
+}
+
+// resolveCategory находит категорию по ID или, для старых клиентов, по названию.
+// Возвращает nil, если категория не указана
+func (s *cardService) resolveCategory(name string, categoryID *uint) (*database.Category, error) {
+	var category *database.Category
+	var err error
+	switch {
+	case categoryID != nil:
+		category, err = s.categoryRepo.GetByID(*categoryID)
+	case name != "":
+		category, err = s.categoryRepo.FindByName(name)
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.New("unknown category")
+	}
+	if !category.IsActive {
+		return nil, errors.New("category is not active")
+	}
+	return category, nil
+}
+
+// categorySubtree возвращает ID категории и всех ее подкатегорий
+func (s *cardService) categorySubtree(name string) ([]uint, error) {
+	category, err := s.categoryRepo.FindByName(name)
+	if err != nil {
+		return nil, errors.New("unknown category")
+	}
+	categories, err := s.categoryRepo.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	return categorySubtreeIDs(categories, category.ID), nil
+}
+
+// geocodeLocation определяет координаты карточки по адресу. Неизвестный адрес
+// не мешает сохранению: тогда в поиске используются координаты компании
+func (s *cardService) geocodeLocation(card *database.Card) {
+	if card.Location == "" {
+		return
+	}
+	point, err := s.geocoder.Geocode(card.Location)
+	if err != nil {
+		return
+	}
+	card.Latitude = &point.Latitude
+	card.Longitude = &point.Longitude
+}
+
+func convertFacetCounts(counts []repository.CardFacetCount) []api.FacetCount {
+	facets := []api.FacetCount{}
+	for _, count := range counts {
+		facets = append(facets, api.FacetCount{Value: count.Value, Count: count.Count})
+	}
+	return facets
+}
+
+func NewCardService(cardRepo repository.CardRepository, companyRepo repository.CompanyRepository, categoryRepo repository.CategoryRepository, favoriteRepo repository.FavoriteRepository, geocoder geo.Geocoder, favoriteService FavoriteService) CardService {
+	return &cardService{
+		cardRepo:        cardRepo,
+		companyRepo:     companyRepo,
+		categoryRepo:    categoryRepo,
+		favoriteRepo:    favoriteRepo,
+		geocoder:        geocoder,
+		favoriteService: favoriteService,
+	}
 }
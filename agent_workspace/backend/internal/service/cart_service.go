@@ -0,0 +1,241 @@
+package service
+
+import (
+	"core/internal/api"
+	"core/internal/database"
+	"core/internal/database/repository"
+	"core/internal/money"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"time"
+)
+
+const (
+	maxCartItems    = 50
+	maxCartQuantity = 99
+)
+
+type CartService interface {
+	GetCart(clientID uint) (*api.CartInfo, error)
+	// AddItem добавляет quantity единиц карточки к уже лежащим в корзине
+	AddItem(clientID, cardID uint, quantity int) (*api.CartInfo, error)
+	// UpdateItem задает количество карточки, 0 удаляет ее из корзины
+	UpdateItem(clientID, cardID uint, quantity int) (*api.CartInfo, error)
+	RemoveItem(clientID, cardID uint) (*api.CartInfo, error)
+	Clear(clientID uint) error
+	// Checkout оформляет корзину: по заказу на компанию, все оплачиваются одним списанием.
+	// Промокоды при оформлении корзины не применяются
+	Checkout(clientID uint, request api.CartCheckoutRequest) (*api.CheckoutInfo, error)
+}
+
+type cartService struct {
+	cartRepo     repository.CartRepository
+	cardRepo     repository.CardRepository
+	scheduleRepo repository.ScheduleRepository
+	balanceRepo  repository.BalanceRepository
+	orderService OrderService
+	rates        money.RateProvider
+}
+
+func (s *cartService) GetCart(clientID uint) (*api.CartInfo, error) {
+	items, err := s.cartRepo.GetItems(clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	balance, err := s.balanceRepo.GetClientBalance(clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	cart := &api.CartInfo{Companies: []api.CartCompanyInfo{}, Currency: balance.Currency}
+	for _, item := range items {
+		if len(cart.Companies) == 0 || cart.Companies[len(cart.Companies)-1].CompanyID != item.Card.CompanyID {
+			hours, err := s.scheduleRepo.GetWorkingHours(item.Card.CompanyID)
+			if err != nil {
+				return nil, err
+			}
+			cart.Companies = append(cart.Companies, api.CartCompanyInfo{
+				CompanyID:    item.Card.CompanyID,
+				CompanyName:  item.Card.Company.CompanyName,
+				Currency:     item.Card.Currency,
+				RequiresSlot: len(hours) > 0,
+			})
+		}
+
+		company := &cart.Companies[len(cart.Companies)-1]
+		available := cartItemAvailable(item)
+		amount := item.Card.Price * money.Minor(item.Quantity)
+		company.Items = append(company.Items, api.CartItemInfo{
+			CardID:    item.CardID,
+			Title:     item.Card.Title,
+			Price:     item.Card.Price,
+			Quantity:  item.Quantity,
+			Amount:    amount,
+			Available: available,
+		})
+		cart.ItemCount += item.Quantity
+		if !available {
+			continue
+		}
+		company.Subtotal += amount
+	}
+
+	// Итог пересчитывается в валюту счета так же, как при оплате: отдельно по каждой компании
+	for _, company := range cart.Companies {
+		if company.Currency == balance.Currency {
+			cart.Total += company.Subtotal
+			continue
+		}
+		rate, err := s.rates.Rate(company.Currency, balance.Currency)
+		if err != nil {
+			return nil, err
+		}
+		cart.Total += money.Convert(company.Subtotal, rate)
+	}
+	return cart, nil
+}
+
+func (s *cartService) AddItem(clientID, cardID uint, quantity int) (*api.CartInfo, error) {
+	if quantity == 0 {
+		quantity = 1
+	}
+	if existing, err := s.cartRepo.GetItem(clientID, cardID); err == nil {
+		quantity += existing.Quantity
+	}
+	if err := s.saveItem(clientID, cardID, quantity); err != nil {
+		return nil, err
+	}
+	return s.GetCart(clientID)
+}
+
+func (s *cartService) UpdateItem(clientID, cardID uint, quantity int) (*api.CartInfo, error) {
+	if quantity == 0 {
+		return s.RemoveItem(clientID, cardID)
+	}
+	if _, err := s.cartRepo.GetItem(clientID, cardID); err != nil {
+		return nil, errors.New("card is not in the cart")
+	}
+	if err := s.saveItem(clientID, cardID, quantity); err != nil {
+		return nil, err
+	}
+	return s.GetCart(clientID)
+}
+
+func (s *cartService) RemoveItem(clientID, cardID uint) (*api.CartInfo, error) {
+	if err := s.cartRepo.RemoveItem(clientID, cardID); err != nil {
+		return nil, err
+	}
+	return s.GetCart(clientID)
+}
+
+func (s *cartService) Clear(clientID uint) error {
+	return s.cartRepo.Clear(clientID, nil)
+}
+
+func (s *cartService) Checkout(clientID uint, request api.CartCheckoutRequest) (*api.CheckoutInfo, error) {
+	items, err := s.cartRepo.GetItems(clientID)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, errors.New("cart is empty")
+	}
+
+	slots := make(map[uint]*time.Time, len(request.Slots))
+	for _, slot := range request.Slots {
+		scheduledAt, err := parseOptionalRFC3339(&slot.ScheduledAt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid scheduled_at for company %d", slot.CompanyID)
+		}
+		slots[slot.CompanyID] = scheduledAt
+	}
+
+	var cartOrders []CartOrder
+	for _, item := range items {
+		if !cartItemAvailable(item) {
+			return nil, fmt.Errorf("card %d is no longer available", item.CardID)
+		}
+		if len(cartOrders) == 0 || cartOrders[len(cartOrders)-1].CompanyID != item.Card.CompanyID {
+			cartOrders = append(cartOrders, CartOrder{
+				CompanyID:   item.Card.CompanyID,
+				ScheduledAt: slots[item.Card.CompanyID],
+				Description: request.Description,
+			})
+		}
+		card := item.Card
+		cartOrder := &cartOrders[len(cartOrders)-1]
+		cartOrder.Items = append(cartOrder.Items, CartOrderItem{Card: &card, Quantity: item.Quantity})
+	}
+
+	checkout, err := s.orderService.CheckoutCart(clientID, cartOrders, func(tx *gorm.DB) error {
+		return s.cartRepo.TakeItemsInTx(tx, clientID, items)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	info := &api.CheckoutInfo{
+		ID:        checkout.ID,
+		Amount:    checkout.Amount,
+		Currency:  checkout.Currency,
+		Orders:    []api.OrderInfo{},
+		CreatedAt: checkout.CreatedAt.Format(time.RFC3339),
+	}
+	for _, order := range checkout.Orders {
+		orderInfo, err := s.orderService.GetOrderInfo(order.ID, clientID, "client")
+		if err != nil {
+			return nil, err
+		}
+		info.Orders = append(info.Orders, *orderInfo)
+	}
+	return info, nil
+}
+
+// saveItem проверяет карточку и количество перед сохранением в корзину
+func (s *cartService) saveItem(clientID, cardID uint, quantity int) error {
+	if quantity < 1 || quantity > maxCartQuantity {
+		return fmt.Errorf("quantity must be between 1 and %d", maxCartQuantity)
+	}
+
+	card, err := s.cardRepo.GetByID(cardID)
+	if err != nil || !card.IsActive {
+		return errors.New("card not found")
+	}
+
+	if _, err := s.cartRepo.GetItem(clientID, cardID); err != nil {
+		items, err := s.cartRepo.GetItems(clientID)
+		if err != nil {
+			return err
+		}
+		if len(items) >= maxCartItems {
+			return fmt.Errorf("cart cannot contain more than %d cards", maxCartItems)
+		}
+	}
+
+	return s.cartRepo.SaveItem(clientID, cardID, quantity)
+}
+
+// cartItemAvailable сообщает, можно ли еще заказать карточку из корзины
+func cartItemAvailable(item database.CartItem) bool {
+	return item.Card.ID != 0 && item.Card.IsActive
+}
+
+func NewCartService(
+	cartRepo repository.CartRepository,
+	cardRepo repository.CardRepository,
+	scheduleRepo repository.ScheduleRepository,
+	balanceRepo repository.BalanceRepository,
+	orderService OrderService,
+	rates money.RateProvider,
+) CartService {
+	return &cartService{
+		cartRepo:     cartRepo,
+		cardRepo:     cardRepo,
+		scheduleRepo: scheduleRepo,
+		balanceRepo:  balanceRepo,
+		orderService: orderService,
+		rates:        rates,
+	}
+}
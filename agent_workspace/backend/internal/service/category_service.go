@@ -0,0 +1,212 @@
+package service
+
+import (
+	"core/internal/api"
+	"core/internal/database"
+	"core/internal/database/repository"
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var categorySlugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+type CategoryService interface {
+	GetTree(locale string, includeInactive bool) ([]api.CategoryNode, error)
+	CreateCategory(info api.CategoryInfo) (*database.Category, error)
+	UpdateCategory(categoryID uint, info api.CategoryInfo) (*database.Category, error)
+	DeleteCategory(categoryID uint) error
+}
+
+type categoryService struct {
+	categoryRepo repository.CategoryRepository
+}
+
+// GetTree возвращает дерево категорий. Количество карточек у каждой категории
+// включает карточки всех ее подкатегорий
+func (s *categoryService) GetTree(locale string, includeInactive bool) ([]api.CategoryNode, error) {
+	categories, err := s.categoryRepo.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	counts, err := s.categoryRepo.CountCards()
+	if err != nil {
+		return nil, err
+	}
+	direct := make(map[uint]int64, len(counts))
+	for _, count := range counts {
+		direct[count.CategoryID] = count.Count
+	}
+
+	children := make(map[uint][]database.Category)
+	var roots []database.Category
+	for _, category := range categories {
+		if !category.IsActive && !includeInactive {
+			continue
+		}
+		if category.ParentID == nil {
+			roots = append(roots, category)
+		} else {
+			children[*category.ParentID] = append(children[*category.ParentID], category)
+		}
+	}
+
+	var build func(items []database.Category) []api.CategoryNode
+	build = func(items []database.Category) []api.CategoryNode {
+		nodes := make([]api.CategoryNode, 0, len(items))
+		for _, item := range items {
+			node := convertCategoryToNode(item, locale)
+			node.Children = build(children[item.ID])
+			node.CardCount = direct[item.ID]
+			for _, child := range node.Children {
+				node.CardCount += child.CardCount
+			}
+			nodes = append(nodes, node)
+		}
+		return nodes
+	}
+	return build(roots), nil
+}
+
+func (s *categoryService) CreateCategory(info api.CategoryInfo) (*database.Category, error) {
+	category := &database.Category{IsActive: true}
+	if err := s.applyCategoryInfo(category, info); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.categoryRepo.GetBySlug(category.Slug); err == nil {
+		return nil, errors.New("category with this slug already exists")
+	}
+
+	if err := s.categoryRepo.Create(category); err != nil {
+		return nil, err
+	}
+	return category, nil
+}
+
+func (s *categoryService) UpdateCategory(categoryID uint, info api.CategoryInfo) (*database.Category, error) {
+	category, err := s.categoryRepo.GetByID(categoryID)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.NameRu == "" {
+		info.NameRu = category.NameRu
+	}
+	if info.Slug == "" {
+		info.Slug = category.Slug
+	}
+	if err := s.applyCategoryInfo(category, info); err != nil {
+		return nil, err
+	}
+
+	if existing, err := s.categoryRepo.GetBySlug(category.Slug); err == nil && existing.ID != category.ID {
+		return nil, errors.New("category with this slug already exists")
+	}
+
+	if err := s.categoryRepo.Update(category); err != nil {
+		return nil, err
+	}
+	return category, nil
+}
+
+func (s *categoryService) DeleteCategory(categoryID uint) error {
+	if _, err := s.categoryRepo.GetByID(categoryID); err != nil {
+		return err
+	}
+	return s.categoryRepo.Delete(categoryID)
+}
+
+// applyCategoryInfo проверяет данные категории и переносит их в модель
+func (s *categoryService) applyCategoryInfo(category *database.Category, info api.CategoryInfo) error {
+	info.NameRu = strings.TrimSpace(info.NameRu)
+	if info.NameRu == "" {
+		return errors.New("name_ru cannot be empty")
+	}
+
+	slug := strings.TrimSpace(info.Slug)
+	if slug == "" {
+		slug = database.Slugify(info.NameRu)
+	}
+	if !categorySlugPattern.MatchString(slug) {
+		return errors.New("slug may contain only lowercase latin letters, digits and dashes")
+	}
+
+	if info.ParentID != nil {
+		if category.ID != 0 && *info.ParentID == category.ID {
+			return errors.New("category cannot be its own parent")
+		}
+		if _, err := s.categoryRepo.GetByID(*info.ParentID); err != nil {
+			return err
+		}
+		if category.ID != 0 {
+			categories, err := s.categoryRepo.GetAll()
+			if err != nil {
+				return err
+			}
+			for _, id := range categorySubtreeIDs(categories, category.ID) {
+				if id == *info.ParentID {
+					return errors.New("category cannot be moved into its own subcategory")
+				}
+			}
+		}
+	}
+
+	category.ParentID = info.ParentID
+	category.Slug = slug
+	category.NameRu = info.NameRu
+	category.NameEn = strings.TrimSpace(info.NameEn)
+	category.Icon = info.Icon
+	category.SortOrder = info.SortOrder
+	category.Aliases = info.Aliases
+	if info.IsActive != nil {
+		category.IsActive = *info.IsActive
+	}
+	return nil
+}
+
+// categorySubtreeIDs возвращает ID категории и всех ее потомков
+func categorySubtreeIDs(categories []database.Category, rootID uint) []uint {
+	children := make(map[uint][]uint)
+	for _, category := range categories {
+		if category.ParentID != nil {
+			children[*category.ParentID] = append(children[*category.ParentID], category.ID)
+		}
+	}
+
+	ids := []uint{rootID}
+	for i := 0; i < len(ids); i++ {
+		ids = append(ids, children[ids[i]]...)
+	}
+	sort.Slice(ids, func(a, b int) bool { return ids[a] < ids[b] })
+	return ids
+}
+
+func convertCategoryToNode(category database.Category, locale string) api.CategoryNode {
+	name := category.NameRu
+	if locale == "en" && category.NameEn != "" {
+		name = category.NameEn
+	}
+	aliases := []string(category.Aliases)
+	if aliases == nil {
+		aliases = []string{}
+	}
+	return api.CategoryNode{
+		ID:        category.ID,
+		ParentID:  category.ParentID,
+		Slug:      category.Slug,
+		Name:      name,
+		NameRu:    category.NameRu,
+		NameEn:    category.NameEn,
+		Icon:      category.Icon,
+		SortOrder: category.SortOrder,
+		Aliases:   aliases,
+		IsActive:  category.IsActive,
+	}
+}
+
+func NewCategoryService(categoryRepo repository.CategoryRepository) CategoryService {
+	return &categoryService{categoryRepo: categoryRepo}
+}
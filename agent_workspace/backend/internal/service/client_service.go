@@ -7,71 +7,60 @@ import (
 	"core/internal/database/repository"
 	"core/internal/security"
 	"errors"
+	"log"
 )
 
 type ClientService interface {
-	Signup(request *api.ClientRegister) (database.ClientDB, error)
-	SignupSimple(request *api.ClientRegisterRequest) (database.ClientDB, error)
-	Login(request *api.GeneralAuth) (dbUser database.ClientDB, jwtToken string, err error)
-	LoginSimple(request *api.LoginRequest) (database.ClientDB, error)
+	// Signup и SignupSimple создают пользователя с клиентским профилем. Вход - IdentityService.Login
+	Signup(request *api.ClientRegister) (database.ClientDB, *database.UserDB, error)
+	SignupSimple(request *api.ClientRegisterRequest) (database.ClientDB, *database.UserDB, error)
 	GetClient(id uint) (database.ClientDB, error)
 	AccessByToken(request *api.TokenAccess) (*api.ResponseSuccessAccess, database.ClientDB, error)
 	UpdateProfile(userID uint, profile api.ClientProfileInfo) (database.ClientDB, error)
 }
 
 type clientService struct {
-	repository repository.ClientRepository
+	repository     repository.ClientRepository
+	userRepository repository.UserRepository
+	accountService AccountService
 }
 
-func (service *clientService) Signup(request *api.ClientRegister) (database.ClientDB, error) {
-	exists, existsCompany, _ := service.repository.ExistsByEmail(request.Client.RegisterInfoPost.Email)
-	if exists || existsCompany {
-		return database.ClientDB{}, errors.New("email already exists")
+func (service *clientService) Signup(request *api.ClientRegister) (database.ClientDB, *database.UserDB, error) {
+	info := request.Client.RegisterInfoPost
+	if service.emailTaken(info.Email) {
+		return database.ClientDB{}, nil, errors.New("email already exists")
 	}
 
 	client := &database.ClientDB{
-		FullName:     request.Client.RegisterInfoPost.FullName,
-		Email:        request.Client.RegisterInfoPost.Email,
-		Phone:        request.Client.RegisterInfoPost.Phone,
-		PasswordHash: request.Client.RegisterInfoPost.PasswordHash,
-		Photo:        request.Client.RegisterInfoPost.Photo,
-		Type:         request.Client.RegisterInfoPost.Type,
+		FullName: info.FullName,
+		Email:    info.Email,
+		Phone:    info.Phone,
+		Photo:    info.Photo,
+		Type:     info.Type,
 	}
-	service.repository.Save(client)
-	return *client, nil
+	return service.createUser(client, info.PasswordHash)
 }
 
-func (service *clientService) SignupSimple(request *api.ClientRegisterRequest) (database.ClientDB, error) {
-	exists, existsCompany, _ := service.repository.ExistsByEmail(request.Email)
-	if exists || existsCompany {
-		return database.ClientDB{}, errors.New("email already exists")
+func (service *clientService) SignupSimple(request *api.ClientRegisterRequest) (database.ClientDB, *database.UserDB, error) {
+	if service.emailTaken(request.Email) {
+		return database.ClientDB{}, nil, errors.New("email already exists")
 	}
 
 	// Хешируем пароль
 	hashedPassword, err := security.HashPassword(request.Password)
 	if err != nil {
-		return database.ClientDB{}, errors.New("failed to hash password")
+		return database.ClientDB{}, nil, errors.New("failed to hash password")
 	}
 
 	client := &database.ClientDB{
-		FullName:     request.FullName,
-		Email:        request.Email,
-		Phone:        request.Phone,
-		PasswordHash: hashedPassword,
-		Photo:        request.Photo,
-		Type:         "client",
-		Balance:      0,
-	}
-	service.repository.Save(client)
-	return *client, nil
-}
-
-func (service *clientService) LoginSimple(request *api.LoginRequest) (database.ClientDB, error) {
-	dbUser, err := service.repository.CheckPassword(request.Email, request.Password)
-	if err != nil {
-		return database.ClientDB{}, err
+		FullName: request.FullName,
+		Email:    request.Email,
+		Phone:    request.Phone,
+		Photo:    request.Photo,
+		Type:     "client",
+		Balance:  0,
 	}
-	return dbUser, nil
+	return service.createUser(client, hashedPassword)
 }
 
 func (service *clientService) GetClient(id uint) (database.ClientDB, error) {
@@ -82,18 +71,6 @@ func (service *clientService) GetClient(id uint) (database.ClientDB, error) {
 	return *client, nil
 }
 
-func (service *clientService) Login(request *api.GeneralAuth) (dbUser database.ClientDB, jwtToken string, err error) {
-	dbUser, err = service.repository.CheckPassword(request.GeneralLogin.GeneralLoginAttributes.Email, request.GeneralLogin.GeneralLoginAttributes.PasswordHash)
-	if err != nil {
-		return dbUser, "", err
-	}
-	jwtToken = security.CreateToken(dbUser.Type == "company", dbUser.ID, internal.LifeTimeJWT)
-	if jwtToken == "" {
-		return dbUser, "", errors.New("the created jwt was faulty")
-	}
-	return dbUser, jwtToken, nil
-}
-
 func (service *clientService) AccessByToken(request *api.TokenAccess) (*api.ResponseSuccessAccess, database.ClientDB, error) {
 	result, tokenStructure := security.CheckToken(request.User.Login.Token)
 	client, err := service.GetClient(uint(tokenStructure["accessID"].(float64)))
@@ -131,17 +108,13 @@ func (service *clientService) UpdateProfile(userID uint, profile api.ClientProfi
 		return database.ClientDB{}, errors.New("user not found")
 	}
 
-	// Проверяем, не занят ли email другим пользователем
-	if profile.Email != client.Email {
-		exists, existsCompany, _ := service.repository.ExistsByEmail(profile.Email)
-		if exists || existsCompany {
-			return database.ClientDB{}, errors.New("email already exists")
-		}
+	// Email меняется только с подтверждением нового адреса, см. AccountService.ChangeEmail
+	if profile.Email != "" && profile.Email != client.Email {
+		return database.ClientDB{}, errors.New("email can be changed only via /v1/account/security/change-email")
 	}
 
 	// Обновляем поля
 	client.FullName = profile.FullName
-	client.Email = profile.Email
 	client.Phone = profile.Phone
 	client.Photo = profile.Photo
 
@@ -154,8 +127,36 @@ func (service *clientService) UpdateProfile(userID uint, profile api.ClientProfi
 	return *client, nil
 }
 
-func NewClientService(repository repository.ClientRepository) ClientService {
+// createUser сохраняет клиента вместе с пользователем, который входит с email и паролем
+func (service *clientService) createUser(client *database.ClientDB, passwordHash string) (database.ClientDB, *database.UserDB, error) {
+	user := &database.UserDB{
+		Email:           client.Email,
+		FullName:        client.FullName,
+		PasswordHash:    passwordHash,
+		EmailVerifiedAt: signupVerifiedAt(),
+	}
+	client.EmailVerifiedAt = user.EmailVerifiedAt
+	if err := service.userRepository.CreateWithClient(user, client); err != nil {
+		log.Printf("failed to create client %s: %v", client.Email, err)
+		return database.ClientDB{}, nil, errors.New("failed to create account")
+	}
+	requestVerification(service.accountService, user)
+	return *client, user, nil
+}
+
+// emailTaken проверяет email пользователей, клиентов и компаний
+func (service *clientService) emailTaken(email string) bool {
+	if service.userRepository.ExistsByEmail(email) {
+		return true
+	}
+	exists, existsCompany, _ := service.repository.ExistsByEmail(email)
+	return exists || existsCompany
+}
+
+func NewClientService(repository repository.ClientRepository, userRepository repository.UserRepository, accountService AccountService) ClientService {
 	return &clientService{
-		repository: repository,
+		repository:     repository,
+		userRepository: userRepository,
+		accountService: accountService,
 	}
 }
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"core/internal/api"
+	"core/internal/database/repository"
+	"core/internal/pagination"
+	"errors"
+	"time"
+)
+
+// Сколько карточек и последних отзывов показывается на странице компании
+const (
+	profileCardLimit   = 50
+	profileReviewLimit = 5
+)
+
+type CompanyProfileService interface {
+	// GetPublicProfile собирает публичную страницу компании без приватных данных
+	GetPublicProfile(companyID uint) (*api.CompanyPublicProfile, error)
+}
+
+type companyProfileService struct {
+	companyRepo  repository.CompanyRepository
+	cardRepo     repository.CardRepository
+	reviewRepo   repository.ReviewRepository
+	favoriteRepo repository.FavoriteRepository
+}
+
+func (s *companyProfileService) GetPublicProfile(companyID uint) (*api.CompanyPublicProfile, error) {
+	company, err := s.companyRepo.GetByID(companyID)
+	if err != nil {
+		return nil, errors.New("company not found")
+	}
+
+	profile := &api.CompanyPublicProfile{
+		ID:           company.ID,
+		CompanyName:  company.CompanyName,
+		Description:  company.Description,
+		Website:      company.Website,
+		Photo:        company.Photo,
+		Address:      company.Address,
+		TypeService:  company.TypeService,
+		IsVerified:   company.IsVerified,
+		Stars:        company.Stars,
+		ReviewCount:  company.ReviewCount,
+		MemberSince:  company.CreatedAt.Format(time.RFC3339),
+		RankingScore: company.RankingScore,
+		SubRatings:   convertCompanySubRatings(*company),
+	}
+
+	distribution, err := s.reviewRepo.GetRatingDistribution(companyID)
+	if err != nil {
+		return nil, err
+	}
+	profile.RatingDistribution = convertRatingDistribution(distribution)
+
+	if profile.CompletedOrders, err = s.companyRepo.CountCompletedOrders(companyID); err != nil {
+		return nil, err
+	}
+
+	cards, _, err := s.cardRepo.GetByCompanyID(companyID, pagination.Offset(profileCardLimit, 0))
+	if err != nil {
+		return nil, err
+	}
+	cardIDs := make([]uint, 0, len(cards))
+	for _, card := range cards {
+		cardIDs = append(cardIDs, card.ID)
+	}
+	counts, err := s.favoriteRepo.CountByCards(cardIDs)
+	if err != nil {
+		return nil, err
+	}
+	profile.Cards = []api.ExtendedCardResponse{}
+	for _, card := range cards {
+		response := convertCardToExtendedResponse(card)
+		response.FavoriteCount = counts[card.ID]
+		profile.Cards = append(profile.Cards, response)
+	}
+
+	reviews, err := s.reviewRepo.GetRecentByCompanyID(companyID, profileReviewLimit)
+	if err != nil {
+		return nil, err
+	}
+	profile.RecentReviews = convertReviewsToPublic(reviews)
+
+	return profile, nil
+}
+
+func NewCompanyProfileService(companyRepo repository.CompanyRepository, cardRepo repository.CardRepository, reviewRepo repository.ReviewRepository, favoriteRepo repository.FavoriteRepository) CompanyProfileService {
+	return &companyProfileService{
+		companyRepo:  companyRepo,
+		cardRepo:     cardRepo,
+		reviewRepo:   reviewRepo,
+		favoriteRepo: favoriteRepo,
+	}
+}
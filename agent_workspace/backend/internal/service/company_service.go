@@ -5,17 +5,22 @@ import (
 	"core/internal/api"
 	"core/internal/database"
 	"core/internal/database/repository"
+	"core/internal/pagination"
+	"core/internal/rating"
 	"core/internal/security"
 	"errors"
+	"fmt"
+	"log"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type CompanyService interface {
-	Signup(request *api.UserCompanyRegister) (database.CompanyDB, error)
-	SignupSimple(request *api.CompanyRegisterRequest) (database.CompanyDB, error)
+	// Signup и SignupSimple создают компанию и пользователя-владельца. Вход - IdentityService.Login
+	Signup(request *api.UserCompanyRegister) (database.CompanyDB, *database.UserDB, error)
+	SignupSimple(request *api.CompanyRegisterRequest) (database.CompanyDB, *database.UserDB, error)
 	GetCompany(id uint) (database.CompanyDB, error)
-	Login(request *api.GeneralAuth) (dbUser database.CompanyDB, jwtToken string, err error)
-	LoginSimple(request *api.LoginRequest) (database.CompanyDB, error)
 	AccessByToken(request *api.TokenAccess) (*api.ResponseSuccessAccess, database.CompanyDB, error)
 	CreateCard(request *api.TokenCreateCard) (error, database.Card)
 	ListCard(request *api.TokenListCard, limit string, page string) (error, []database.Card)
@@ -23,47 +28,55 @@ type CompanyService interface {
 	UpdateProfile(companyID uint, profile api.ClientProfileInfo) error
 	GetCompanyStats(companyID uint) (*api.CompanyStats, error)
 	UpdateCard(companyID uint, cardID uint, cardData api.CardInfo) error
+	GetPayoutDetails(companyID uint) (*api.PayoutDetails, error)
+	// UpdatePayoutDetails меняет реквизиты для вывода средств. Код 2FA проверяет контроллер
+	UpdatePayoutDetails(companyID uint, details api.PayoutDetails) error
+
+	// GetVerificationQueue список компаний, ожидающих проверки документов
+	GetVerificationQueue(page pagination.Request) ([]api.CompanyVerificationInfo, pagination.Page, error)
+	// SetVerified отмечает компанию проверенной: отметка показывается в поиске и на странице компании
+	SetVerified(adminID, companyID uint, verified bool) error
 }
 
 type companyService struct {
-	repository repository.CompanyRepository
+	repository     repository.CompanyRepository
+	userRepository repository.UserRepository
+	accountService AccountService
 }
 
-func (service *companyService) Signup(request *api.UserCompanyRegister) (database.CompanyDB, error) {
-	exists, existsCompany, _ := service.repository.ExistsByEmail(request.CompanyRegister.CompanyInfoPost.Email)
-	if exists || existsCompany {
-		return database.CompanyDB{}, errors.New("email already exists")
+func (service *companyService) Signup(request *api.UserCompanyRegister) (database.CompanyDB, *database.UserDB, error) {
+	info := request.CompanyRegister.CompanyInfoPost
+	if service.emailTaken(info.Email) {
+		return database.CompanyDB{}, nil, errors.New("email already exists")
 	}
 
 	company := &database.CompanyDB{
-		CompanyName:   request.CompanyRegister.CompanyInfoPost.CompanyName,
-		FullName:      request.CompanyRegister.CompanyInfoPost.FullName,
-		PositionAgent: request.CompanyRegister.CompanyInfoPost.PositionAgent,
-		IDCompany:     request.CompanyRegister.CompanyInfoPost.IDCompany,
-		Email:         request.CompanyRegister.CompanyInfoPost.Email,
-		Phone:         request.CompanyRegister.CompanyInfoPost.Phone,
-		Address:       request.CompanyRegister.CompanyInfoPost.Address,
-		TypeService:   request.CompanyRegister.CompanyInfoPost.TypeService,
-		PasswordHash:  request.CompanyRegister.CompanyInfoPost.PasswordHash,
-		Photo:         request.CompanyRegister.CompanyInfoPost.Photo,
-		Documents:     request.CompanyRegister.CompanyInfoPost.Documents,
+		CompanyName:   info.CompanyName,
+		FullName:      info.FullName,
+		PositionAgent: info.PositionAgent,
+		IDCompany:     info.IDCompany,
+		Email:         info.Email,
+		Phone:         info.Phone,
+		Address:       info.Address,
+		TypeService:   info.TypeService,
+		Photo:         info.Photo,
+		Documents:     info.Documents,
 		Stars:         5,
+		RankingScore:  rating.DefaultPriorMean, // Уточняется после первого отзыва
 		Type:          "company",
 	}
-	service.repository.Save(company)
-	return *company, nil
+	return service.createOwner(company, info.PasswordHash)
 }
 
-func (service *companyService) SignupSimple(request *api.CompanyRegisterRequest) (database.CompanyDB, error) {
-	exists, existsCompany, _ := service.repository.ExistsByEmail(request.Email)
-	if exists || existsCompany {
-		return database.CompanyDB{}, errors.New("email already exists")
+func (service *companyService) SignupSimple(request *api.CompanyRegisterRequest) (database.CompanyDB, *database.UserDB, error) {
+	if service.emailTaken(request.Email) {
+		return database.CompanyDB{}, nil, errors.New("email already exists")
 	}
 
 	// Хешируем пароль
 	hashedPassword, err := security.HashPassword(request.Password)
 	if err != nil {
-		return database.CompanyDB{}, errors.New("failed to hash password")
+		return database.CompanyDB{}, nil, errors.New("failed to hash password")
 	}
 
 	company := &database.CompanyDB{
@@ -71,25 +84,16 @@ func (service *companyService) SignupSimple(request *api.CompanyRegisterRequest)
 		FullName:     request.CompanyName, // Используем company_name как full_name по умолчанию
 		Email:        request.Email,
 		Phone:        request.Phone,
-		PasswordHash: hashedPassword,
 		Photo:        request.Photo,
 		Website:      request.Website,
 		Description:  request.Description,
 		Stars:        5,
 		ReviewCount:  0,
+		RankingScore: rating.DefaultPriorMean, // Уточняется после первого отзыва
 		Type:         "company",
 		Balance:      0,
 	}
-	service.repository.Save(company)
-	return *company, nil
-}
-
-func (service *companyService) LoginSimple(request *api.LoginRequest) (database.CompanyDB, error) {
-	dbUser, err := service.repository.CheckPassword(request.Email, request.Password)
-	if err != nil {
-		return database.CompanyDB{}, err
-	}
-	return dbUser, nil
+	return service.createOwner(company, hashedPassword)
 }
 
 func (service *companyService) GetCompany(id uint) (database.CompanyDB, error) {
@@ -100,18 +104,6 @@ func (service *companyService) GetCompany(id uint) (database.CompanyDB, error) {
 	return *company, nil
 }
 
-func (service *companyService) Login(request *api.GeneralAuth) (dbUser database.CompanyDB, jwtToken string, err error) {
-	dbUser, err = service.repository.CheckPassword(request.GeneralLogin.GeneralLoginAttributes.Email, request.GeneralLogin.GeneralLoginAttributes.PasswordHash)
-	if err != nil {
-		return dbUser, "", err
-	}
-	jwtToken = security.CreateToken(dbUser.Type == "company", dbUser.ID, internal.LifeTimeJWT)
-	if jwtToken == "" {
-		return dbUser, "", errors.New("the created jwt was faulty")
-	}
-	return dbUser, jwtToken, nil
-}
-
 func (service *companyService) AccessByToken(request *api.TokenAccess) (*api.ResponseSuccessAccess, database.CompanyDB, error) {
 	result, tokenStructure := security.CheckToken(request.User.Login.Token)
 	company, err := service.GetCompany(uint(tokenStructure["accessID"].(float64)))
@@ -155,6 +147,7 @@ func (service *companyService) CreateCard(request *api.TokenCreateCard) (error,
 			Category:    request.Card.Category,
 			Location:    request.Card.Location,
 			Price:       request.Card.Price,
+			Currency:    company.Currency,
 			IsActive:    true,
 			CompanyID:   company.ID,
 		}
@@ -215,13 +208,15 @@ func (service *companyService) UpdateProfile(companyID uint, profile api.ClientP
 		return err
 	}
 
+	// Email меняется только с подтверждением нового адреса, см. AccountService.ChangeEmail
+	if profile.Email != "" && profile.Email != company.Email {
+		return errors.New("email can be changed only via /v1/account/security/change-email")
+	}
+
 	// Обновляем поля профиля
 	if profile.FullName != "" {
 		company.FullName = profile.FullName
 	}
-	if profile.Email != "" {
-		company.Email = profile.Email
-	}
 	if profile.Phone != "" {
 		company.Phone = profile.Phone
 	}
@@ -247,8 +242,146 @@ func (service *companyService) UpdateCard(companyID uint, cardID uint, cardData
 	return errors.New("method not implemented yet - need card repository update methods")
 }
 
-func NewCompanyService(repository repository.CompanyRepository) CompanyService {
+func (service *companyService) GetPayoutDetails(companyID uint) (*api.PayoutDetails, error) {
+	company, err := service.repository.GetByID(companyID)
+	if err != nil {
+		return nil, err
+	}
+	return &api.PayoutDetails{
+		Recipient: company.PayoutRecipient,
+		INN:       company.PayoutINN,
+		BankName:  company.PayoutBankName,
+		BIK:       company.PayoutBIK,
+		Account:   company.PayoutAccount,
+		UpdatedAt: company.PayoutUpdatedAt,
+	}, nil
+}
+
+func (service *companyService) UpdatePayoutDetails(companyID uint, details api.PayoutDetails) error {
+	details.Recipient = strings.TrimSpace(details.Recipient)
+	details.BankName = strings.TrimSpace(details.BankName)
+	if details.Recipient == "" || details.BankName == "" {
+		return errors.New("recipient and bank_name are required")
+	}
+	if !isDigits(details.INN) || (len(details.INN) != 10 && len(details.INN) != 12) {
+		return errors.New("inn must contain 10 or 12 digits")
+	}
+	if !isDigits(details.BIK) || len(details.BIK) != 9 {
+		return errors.New("bik must contain 9 digits")
+	}
+	if !isDigits(details.Account) || len(details.Account) != 20 {
+		return errors.New("account must contain 20 digits")
+	}
+
+	err := service.repository.UpdatePayoutDetails(companyID, map[string]interface{}{
+		"payout_recipient":  details.Recipient,
+		"payout_inn":        details.INN,
+		"payout_bank_name":  details.BankName,
+		"payout_bik":        details.BIK,
+		"payout_account":    details.Account,
+		"payout_updated_at": time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Подмена реквизитов - частая цель взлома, поэтому владелец получает письмо
+	service.accountService.NotifySecurity(companyID, "company", "Реквизиты для выплат изменены",
+		fmt.Sprintf("Реквизиты для вывода средств изменены: счет %s, БИК %s. Если это были не вы, "+
+			"сразу смените пароль и обратитесь в поддержку.", maskAccount(details.Account), details.BIK))
+	return nil
+}
+
+// GetVerificationQueue компании с загруженными документами, которые еще не проверены,
+// вместе с документами для администратора
+func (service *companyService) GetVerificationQueue(page pagination.Request) ([]api.CompanyVerificationInfo, pagination.Page, error) {
+	companies, next, err := service.repository.GetVerificationQueue(page)
+	if err != nil {
+		return nil, pagination.Page{}, err
+	}
+
+	result := []api.CompanyVerificationInfo{}
+	for _, company := range companies {
+		result = append(result, api.CompanyVerificationInfo{
+			ID:          company.ID,
+			CompanyName: company.CompanyName,
+			IDCompany:   company.IDCompany,
+			Email:       company.Email,
+			Phone:       company.Phone,
+			Address:     company.Address,
+			TypeService: company.TypeService,
+			Documents:   company.Documents,
+			CreatedAt:   company.CreatedAt.Format(time.RFC3339),
+		})
+	}
+	return result, next, nil
+}
+
+// SetVerified ставит или снимает отметку о проверке. Проверенной можно отметить только
+// компанию, загрузившую документы
+func (service *companyService) SetVerified(adminID, companyID uint, verified bool) error {
+	if verified {
+		company, err := service.repository.GetByID(companyID)
+		if err != nil {
+			return errors.New("company not found")
+		}
+		if len(company.Documents) == 0 {
+			return errors.New("company has not uploaded any documents")
+		}
+	}
+	return service.repository.SetVerified(companyID, verified, adminID)
+}
+
+// createOwner сохраняет компанию вместе с пользователем-владельцем, который входит с email и паролем
+func (service *companyService) createOwner(company *database.CompanyDB, passwordHash string) (database.CompanyDB, *database.UserDB, error) {
+	user := &database.UserDB{
+		Email:           company.Email,
+		FullName:        company.FullName,
+		PasswordHash:    passwordHash,
+		EmailVerifiedAt: signupVerifiedAt(),
+	}
+	company.EmailVerifiedAt = user.EmailVerifiedAt
+	if err := service.userRepository.CreateWithCompany(user, company); err != nil {
+		log.Printf("failed to create company %s: %v", company.Email, err)
+		return database.CompanyDB{}, nil, errors.New("failed to create account")
+	}
+	requestVerification(service.accountService, user)
+	return *company, user, nil
+}
+
+// emailTaken проверяет email пользователей, клиентов и компаний
+func (service *companyService) emailTaken(email string) bool {
+	if service.userRepository.ExistsByEmail(email) {
+		return true
+	}
+	exists, existsClient, _ := service.repository.ExistsByEmail(email)
+	return exists || existsClient
+}
+
+func isDigits(value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// maskAccount оставляет последние 4 цифры счета
+func maskAccount(account string) string {
+	if len(account) <= 4 {
+		return account
+	}
+	return strings.Repeat("*", len(account)-4) + account[len(account)-4:]
+}
+
+func NewCompanyService(repository repository.CompanyRepository, userRepository repository.UserRepository, accountService AccountService) CompanyService {
 	return &companyService{
-		repository: repository,
+		repository:     repository,
+		userRepository: userRepository,
+		accountService: accountService,
 	}
 }
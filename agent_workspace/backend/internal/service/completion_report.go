@@ -0,0 +1,128 @@
+package service
+
+import (
+	"bytes"
+	"core/internal/api"
+	"core/internal/database"
+	"core/internal/security"
+	"core/internal/storage"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	MaxCompletionPhotos   = 10
+	MaxCompletionNotesLen = 5000
+	completionPhotoURLTTL = 24 * time.Hour
+)
+
+var completionPhotoExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+}
+
+// CompletionPhoto фотография, приложенная к отчету о выполнении
+type CompletionPhoto struct {
+	Content io.Reader
+}
+
+// CompletionReportInput данные отчета о выполнении заказа
+type CompletionReportInput struct {
+	Notes            string
+	TimeSpentMinutes int
+	Latitude         *float64
+	Longitude        *float64
+	Photos           []CompletionPhoto
+	IP               string
+	UserAgent        string
+}
+
+func (input *CompletionReportInput) validate() error {
+	if len(input.Notes) > MaxCompletionNotesLen {
+		return fmt.Errorf("notes must be at most %d characters", MaxCompletionNotesLen)
+	}
+	if input.TimeSpentMinutes < 0 || input.TimeSpentMinutes > 24*60*7 {
+		return errors.New("time spent is out of range")
+	}
+	if (input.Latitude == nil) != (input.Longitude == nil) {
+		return errors.New("both latitude and longitude must be provided")
+	}
+	if input.Latitude != nil && (*input.Latitude < -90 || *input.Latitude > 90 || *input.Longitude < -180 || *input.Longitude > 180) {
+		return errors.New("invalid coordinates")
+	}
+	if len(input.Photos) > MaxCompletionPhotos {
+		return fmt.Errorf("at most %d photos are allowed", MaxCompletionPhotos)
+	}
+	return nil
+}
+
+// saveCompletionPhotos сохраняет фотографии в хранилище и возвращает их имена.
+// При ошибке уже сохраненные файлы удаляются
+func saveCompletionPhotos(fileStorage storage.Storage, orderID uint, photos []CompletionPhoto) ([]string, error) {
+	var names []string
+	for _, photo := range photos {
+		name, err := saveCompletionPhoto(fileStorage, orderID, photo)
+		if err != nil {
+			deleteFiles(fileStorage, names)
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+func saveCompletionPhoto(fileStorage storage.Storage, orderID uint, photo CompletionPhoto) (string, error) {
+	head := make([]byte, 512)
+	n, err := io.ReadFull(photo.Content, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return "", errors.New("failed to read photo")
+	}
+	head = head[:n]
+
+	extension, ok := completionPhotoExtensions[http.DetectContentType(head)]
+	if !ok {
+		return "", errors.New("only JPEG, PNG and WebP photos are allowed")
+	}
+
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	name := fmt.Sprintf("completion/%d/%s%s", orderID, hex.EncodeToString(random), extension)
+
+	if err := fileStorage.Save(name, io.MultiReader(bytes.NewReader(head), photo.Content)); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+func deleteFiles(fileStorage storage.Storage, names []string) {
+	for _, name := range names {
+		fileStorage.Delete(name)
+	}
+}
+
+// convertReportToInfo подписывает ссылки на фотографии, чтобы их можно было показать клиенту
+func convertReportToInfo(report *database.CompletionReport) *api.CompletionReportInfo {
+	expires := time.Now().Add(completionPhotoURLTTL)
+	photos := []string{}
+	for _, name := range report.Photos {
+		photos = append(photos, security.SignPath("/files/"+name, expires))
+	}
+
+	return &api.CompletionReportInfo{
+		Notes:            report.Notes,
+		TimeSpentMinutes: report.TimeSpentMinutes,
+		Latitude:         report.Latitude,
+		Longitude:        report.Longitude,
+		Photos:           photos,
+		WorkerID:         report.WorkerID,
+		SubmittedAt:      report.CreatedAt.Format(time.RFC3339),
+	}
+}
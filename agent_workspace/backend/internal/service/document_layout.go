@@ -0,0 +1,135 @@
+package service
+
+import (
+	"core/internal"
+	"core/internal/money"
+	"core/internal/pdf"
+	"strings"
+	"time"
+)
+
+const (
+	documentDateLayout = "02.01.2006"
+	documentMargin     = 50.0
+	documentAmountCol  = 110.0 // Ширина колонки сумм
+	documentLabelCol   = 120.0 // Ширина колонки подписей в реквизитах
+	documentBodySize   = 10.0
+)
+
+// documentLayout содержимое документа: реквизиты сторон, строки с суммами и итоги.
+// Все суммы документа в валюте Currency
+type documentLayout struct {
+	Title    string
+	IssuedAt time.Time
+	Currency string
+	Parties  []documentParty
+	Lines    []documentLine
+	Totals   []documentLine
+	Note     string
+}
+
+type documentParty struct {
+	Label string
+	Value string
+}
+
+type documentLine struct {
+	Description string
+	Amount      money.Minor
+}
+
+// renderDocument верстает документ на страницах A4, перенося строки таблицы на новые страницы
+func renderDocument(layout documentLayout) []byte {
+	doc := pdf.New(layout.Title, layout.IssuedAt)
+	right := pdf.PageWidth - documentMargin
+	top := pdf.PageHeight - 60.0
+
+	page := doc.AddPage()
+	y := top
+	page.Text(documentMargin, y, 18, true, layout.Title)
+	y -= 20
+	page.Text(documentMargin, y, documentBodySize, false, "от "+layout.IssuedAt.In(internal.TimeZone).Format(documentDateLayout))
+	y -= 30
+
+	valueX := documentMargin + documentLabelCol
+	for _, party := range layout.Parties {
+		page.Text(documentMargin, y, documentBodySize, true, party.Label)
+		for _, line := range wrapDocumentText(party.Value, right-valueX, documentBodySize) {
+			page.Text(valueX, y, documentBodySize, false, line)
+			y -= 14
+		}
+		y -= 4
+	}
+	y -= 10
+
+	tableHeader := func() {
+		page.Text(documentMargin, y, documentBodySize, true, "Наименование")
+		page.TextRight(right, y, documentBodySize, true, "Сумма, "+layout.Currency)
+		y -= 6
+		page.Line(documentMargin, y, right, y, 0.8)
+		y -= 14
+	}
+	tableHeader()
+
+	descriptionWidth := right - documentAmountCol - documentMargin
+	for _, line := range layout.Lines {
+		wrapped := wrapDocumentText(line.Description, descriptionWidth, documentBodySize)
+		if y-float64(len(wrapped))*14 < 90 {
+			page = doc.AddPage()
+			y = top
+			tableHeader()
+		}
+		page.TextRight(right, y, documentBodySize, false, formatDocumentAmount(line.Amount))
+		for _, text := range wrapped {
+			page.Text(documentMargin, y, documentBodySize, false, text)
+			y -= 14
+		}
+		y -= 2
+	}
+
+	if y-float64(len(layout.Totals))*16-60 < 50 {
+		page = doc.AddPage()
+		y = top
+	}
+	page.Line(documentMargin, y+8, right, y+8, 0.8)
+	y -= 8
+	for _, total := range layout.Totals {
+		page.TextRight(right-documentAmountCol, y, documentBodySize+1, true, total.Description)
+		page.TextRight(right, y, documentBodySize+1, true, formatDocumentAmount(total.Amount))
+		y -= 16
+	}
+
+	if layout.Note != "" {
+		y -= 20
+		for _, line := range wrapDocumentText(layout.Note, right-documentMargin, 8) {
+			page.Text(documentMargin, y, 8, false, line)
+			y -= 11
+		}
+	}
+	return doc.Bytes()
+}
+
+// wrapDocumentText разбивает текст на строки, помещающиеся в ширину width
+func wrapDocumentText(text string, width, size float64) []string {
+	lines := []string{}
+	current := ""
+	for _, word := range strings.Fields(text) {
+		candidate := word
+		if current != "" {
+			candidate = current + " " + word
+		}
+		if current != "" && pdf.TextWidth(candidate, size, false) > width {
+			lines = append(lines, current)
+			candidate = word
+		}
+		current = candidate
+	}
+	if current != "" || len(lines) == 0 {
+		lines = append(lines, current)
+	}
+	return lines
+}
+
+func formatDocumentAmount(amount money.Minor) string {
+	return money.FormatNumber(amount, money.DefaultLocale)
+}
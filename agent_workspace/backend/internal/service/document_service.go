@@ -0,0 +1,336 @@
+package service
+
+import (
+	"bytes"
+	"core/internal"
+	"core/internal/api"
+	"core/internal/database"
+	"core/internal/database/repository"
+	"core/internal/money"
+	"core/internal/security"
+	"core/internal/storage"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Типы бухгалтерских документов
+const (
+	DocumentInvoice         = "invoice"
+	DocumentReceipt         = "receipt"
+	DocumentPayoutStatement = "payout_statement"
+
+	documentURLTTL = time.Hour
+	documentNote   = "Документ сформирован автоматически и действителен без подписи."
+)
+
+var documentPrefixes = map[string]string{
+	DocumentInvoice:         "INV",
+	DocumentReceipt:         "RCP",
+	DocumentPayoutStatement: "PAY",
+}
+
+type DocumentService interface {
+	// IssueOrderInvoice выпускает счет по оплаченному заказу. Повторный вызов возвращает
+	// уже выпущенный счет
+	IssueOrderInvoice(orderID uint) (*database.FinancialDocument, error)
+	// IssueTransactionDocument выпускает квитанцию о пополнении баланса или выписку
+	// по выводу средств компании
+	IssueTransactionDocument(transactionID uint) (*database.FinancialDocument, error)
+	GetOrderInvoice(orderID, userID uint, userType string) (*api.DocumentInfo, error)
+	GetTransactionDocument(transactionID, userID uint, userType string) (*api.DocumentInfo, error)
+}
+
+type documentService struct {
+	documentRepo repository.DocumentRepository
+	orderRepo    repository.OrderRepository
+	balanceRepo  repository.BalanceRepository
+	clientRepo   repository.ClientRepository
+	companyRepo  repository.CompanyRepository
+	fileStorage  storage.Storage
+}
+
+func (s *documentService) IssueOrderInvoice(orderID uint) (*database.FinancialDocument, error) {
+	if document, err := s.documentRepo.GetByOrder(DocumentInvoice, orderID); err == nil {
+		return document, nil
+	}
+
+	order, err := s.orderRepo.GetByIDWithRelations(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.PaymentStatus != "paid" && order.PaymentStatus != "refunded" {
+		return nil, errors.New("invoice is available only for paid orders")
+	}
+
+	document := &database.FinancialDocument{
+		Type:      DocumentInvoice,
+		OrderID:   &order.ID,
+		ClientID:  &order.ClientID,
+		CompanyID: &order.CompanyID,
+		Amount:    order.Amount,
+		Currency:  order.Currency,
+	}
+	parties := []documentParty{
+		{Label: "Исполнитель", Value: companyDocumentName(&order.Company)},
+		{Label: "Адрес", Value: order.Company.Address},
+		{Label: "Заказчик", Value: order.Client.FullName},
+		{Label: "Заказ", Value: fmt.Sprintf("№ %d от %s", order.ID, order.CreatedAt.In(internal.TimeZone).Format(documentDateLayout))},
+	}
+	// Оплата с баланса в другой валюте списана по курсу, зафиксированному при оплате
+	if order.PaymentCurrency != "" && order.PaymentCurrency != order.Currency {
+		parties = append(parties, documentParty{
+			Label: "Списано с баланса",
+			Value: money.FormatCode(money.New(order.PaymentAmount, order.PaymentCurrency), money.DefaultLocale) +
+				" по курсу " + money.FormatRate(order.ExchangeRate),
+		})
+	}
+	lines := invoiceLines(order)
+	return s.issue(document, func(number string) documentLayout {
+		return documentLayout{
+			Title:    "Счет № " + number,
+			Currency: order.Currency,
+			Parties:  parties,
+			Lines:    lines,
+			Totals:   []documentLine{{Description: "Итого оплачено:", Amount: order.Amount}},
+			Note:     "Оплата списана с баланса заказчика на платформе. " + documentNote,
+		}
+	})
+}
+
+func (s *documentService) IssueTransactionDocument(transactionID uint) (*database.FinancialDocument, error) {
+	transaction, err := s.balanceRepo.GetTransactionByID(transactionID)
+	if err != nil {
+		return nil, errors.New("transaction not found")
+	}
+	if transaction.Status != "completed" {
+		return nil, errors.New("document is available only for completed transactions")
+	}
+
+	var docType string
+	switch {
+	case transaction.Type == "deposit":
+		docType = DocumentReceipt
+	case transaction.Type == "withdrawal" && transaction.UserType == "company":
+		docType = DocumentPayoutStatement
+	default:
+		return nil, errors.New("no document is issued for this transaction")
+	}
+	if document, err := s.documentRepo.GetByTransaction(docType, transaction.ID); err == nil {
+		return document, nil
+	}
+
+	document := &database.FinancialDocument{
+		Type:          docType,
+		TransactionID: &transaction.ID,
+		Amount:        transaction.Amount,
+		Currency:      transaction.Currency,
+	}
+	if document.Amount < 0 {
+		document.Amount = -document.Amount
+	}
+	owner, err := s.ownerName(transaction.UserID, transaction.UserType)
+	if err != nil {
+		return nil, err
+	}
+	if transaction.UserType == "company" {
+		document.CompanyID = &transaction.UserID
+	} else {
+		document.ClientID = &transaction.UserID
+	}
+
+	if docType == DocumentReceipt {
+		return s.issue(document, func(number string) documentLayout {
+			return documentLayout{
+				Title:    "Квитанция № " + number,
+				Currency: transaction.Currency,
+				Parties: []documentParty{
+					{Label: "Плательщик", Value: owner},
+					{Label: "Дата платежа", Value: transaction.CreatedAt.In(internal.TimeZone).Format(documentDateLayout)},
+				},
+				Lines:  []documentLine{{Description: transaction.Description, Amount: transaction.Amount}},
+				Totals: []documentLine{{Description: "Зачислено на баланс:", Amount: transaction.Amount}},
+				Note:   documentNote,
+			}
+		})
+	}
+
+	// Выписка включает начисления и списания с предыдущего вывода средств
+	period, err := s.balanceRepo.GetPayoutPeriodTransactions(transaction)
+	if err != nil {
+		return nil, err
+	}
+	lines := []documentLine{}
+	var accrued money.Minor
+	for _, item := range period {
+		if item.Currency != transaction.Currency {
+			continue // Операции до смены валюты счета
+		}
+		lines = append(lines, documentLine{
+			Description: item.CreatedAt.In(internal.TimeZone).Format(documentDateLayout) + " " + item.Description,
+			Amount:      item.Amount,
+		})
+		accrued += item.Amount
+	}
+	periodStart := "начала работы"
+	if len(period) > 0 {
+		periodStart = period[0].CreatedAt.In(internal.TimeZone).Format(documentDateLayout)
+	}
+
+	return s.issue(document, func(number string) documentLayout {
+		return documentLayout{
+			Title:    "Выписка по выплате № " + number,
+			Currency: transaction.Currency,
+			Parties: []documentParty{
+				{Label: "Получатель", Value: owner},
+				{Label: "Период", Value: periodStart + " - " + transaction.CreatedAt.In(internal.TimeZone).Format(documentDateLayout)},
+			},
+			Lines: lines,
+			Totals: []documentLine{
+				{Description: "Начислено за период:", Amount: accrued},
+				{Description: "Выплачено:", Amount: -transaction.Amount},
+			},
+			Note: documentNote,
+		}
+	})
+}
+
+func (s *documentService) GetOrderInvoice(orderID, userID uint, userType string) (*api.DocumentInfo, error) {
+	order, err := s.orderRepo.GetByID(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if (userType == "client" && order.ClientID != userID) || (userType == "company" && order.CompanyID != userID) {
+		return nil, errors.New("access denied")
+	}
+
+	document, err := s.IssueOrderInvoice(orderID)
+	if err != nil {
+		return nil, err
+	}
+	return convertDocumentToInfo(document), nil
+}
+
+func (s *documentService) GetTransactionDocument(transactionID, userID uint, userType string) (*api.DocumentInfo, error) {
+	transaction, err := s.balanceRepo.GetTransactionByID(transactionID)
+	if err != nil || transaction.UserID != userID || transaction.UserType != userType {
+		return nil, errors.New("transaction not found")
+	}
+
+	document, err := s.IssueTransactionDocument(transactionID)
+	if err != nil {
+		return nil, err
+	}
+	return convertDocumentToInfo(document), nil
+}
+
+// issue присваивает документу номер, формирует PDF и сохраняет его в хранилище.
+// Если документ одновременно выпускается в другом запросе, возвращается тот документ
+func (s *documentService) issue(document *database.FinancialDocument, layout func(number string) documentLayout) (*database.FinancialDocument, error) {
+	document.IssuedAt = time.Now().In(internal.TimeZone)
+	err := s.documentRepo.Create(document, documentPrefixes[document.Type], func(document *database.FinancialDocument) error {
+		content := layout(document.Number)
+		content.IssuedAt = document.IssuedAt
+		document.FileName = fmt.Sprintf("documents/%s/%d/%s.pdf", document.Type, document.IssuedAt.Year(), document.Number)
+		return s.fileStorage.Save(document.FileName, bytes.NewReader(renderDocument(content)))
+	})
+	if err != nil {
+		if document.FileName != "" {
+			s.fileStorage.Delete(document.FileName)
+		}
+		if existing := s.findExisting(document); existing != nil {
+			return existing, nil
+		}
+		return nil, fmt.Errorf("failed to issue document: %w", err)
+	}
+	return document, nil
+}
+
+func (s *documentService) findExisting(document *database.FinancialDocument) *database.FinancialDocument {
+	var existing *database.FinancialDocument
+	if document.OrderID != nil {
+		existing, _ = s.documentRepo.GetByOrder(document.Type, *document.OrderID)
+	} else if document.TransactionID != nil {
+		existing, _ = s.documentRepo.GetByTransaction(document.Type, *document.TransactionID)
+	}
+	return existing
+}
+
+func (s *documentService) ownerName(userID uint, userType string) (string, error) {
+	if userType == "company" {
+		company, err := s.companyRepo.GetByID(userID)
+		if err != nil {
+			return "", err
+		}
+		return companyDocumentName(company), nil
+	}
+	client, err := s.clientRepo.GetByID(userID)
+	if err != nil {
+		return "", err
+	}
+	return client.FullName, nil
+}
+
+func companyDocumentName(company *database.CompanyDB) string {
+	if company.IDCompany != "" {
+		return fmt.Sprintf("%s, ИНН %s", company.CompanyName, company.IDCompany)
+	}
+	return company.CompanyName
+}
+
+// invoiceLines возвращает услуги заказа и скидку по промокоду отдельной строкой. В заказе
+// из корзины услуг несколько, у заказов без строк услуга берется из карточки
+func invoiceLines(order *database.Order) []documentLine {
+	var lines []documentLine
+	for _, item := range order.LineItems {
+		if item.Type != "service" {
+			continue
+		}
+		description := fmt.Sprintf("Заказ № %d. %s", order.ID, item.Description)
+		if item.Quantity > 1 {
+			description += fmt.Sprintf(" × %d", item.Quantity)
+		}
+		lines = append(lines, documentLine{Description: description, Amount: item.Amount})
+	}
+	if len(lines) == 0 {
+		lines = append(lines, documentLine{Description: invoiceLineDescription(order), Amount: orderSubtotal(order)})
+	}
+	if order.DiscountAmount > 0 {
+		lines = append(lines, documentLine{Description: "Скидка по промокоду", Amount: -order.DiscountAmount})
+	}
+	return lines
+}
+
+func invoiceLineDescription(order *database.Order) string {
+	description := order.Card.Title
+	if description == "" {
+		description = order.Description
+	}
+	return fmt.Sprintf("Заказ № %d. %s", order.ID, description)
+}
+
+// convertDocumentToInfo подписывает ссылку на файл документа
+func convertDocumentToInfo(document *database.FinancialDocument) *api.DocumentInfo {
+	return &api.DocumentInfo{
+		ID:            document.ID,
+		Number:        document.Number,
+		Type:          document.Type,
+		OrderID:       document.OrderID,
+		TransactionID: document.TransactionID,
+		Amount:        document.Amount,
+		Currency:      document.Currency,
+		IssuedAt:      document.IssuedAt.Format(time.RFC3339),
+		URL:           security.SignPath("/files/"+document.FileName, time.Now().Add(documentURLTTL)),
+	}
+}
+
+func NewDocumentService(documentRepo repository.DocumentRepository, orderRepo repository.OrderRepository, balanceRepo repository.BalanceRepository, clientRepo repository.ClientRepository, companyRepo repository.CompanyRepository, fileStorage storage.Storage) DocumentService {
+	return &documentService{
+		documentRepo: documentRepo,
+		orderRepo:    orderRepo,
+		balanceRepo:  balanceRepo,
+		clientRepo:   clientRepo,
+		companyRepo:  companyRepo,
+		fileStorage:  fileStorage,
+	}
+}
@@ -0,0 +1,313 @@
+package service
+
+import (
+	"core/internal"
+	"core/internal/api"
+	"core/internal/database"
+	"core/internal/database/repository"
+	"core/internal/export"
+	"core/internal/security"
+	"core/internal/storage"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+)
+
+// Виды выгрузок
+const (
+	ExportBalance = "balance"
+	ExportEscrow  = "escrow"
+	ExportOrders  = "orders"
+
+	exportDateLayout     = "2006-01-02"
+	exportJobListLimit   = 20
+	exportURLTTL         = time.Hour
+	maxConcurrentExports = 2
+)
+
+// Допустимые значения фильтра по статусу для каждого вида выгрузки
+var exportStatuses = map[string]map[string]bool{
+	ExportBalance: {"pending": true, "completed": true, "failed": true},
+	ExportEscrow:  {"pending": true, "completed": true, "failed": true},
+	ExportOrders: {
+		"created": true, "pending": true, "accepted": true, "paid": true,
+		"in_progress": true, "completed": true, "finished": true, "cancelled": true,
+	},
+}
+
+// ExportRequest параметры выгрузки. Даты в формате YYYY-MM-DD, to включительно
+type ExportRequest struct {
+	Kind   string
+	Format string
+	From   string
+	To     string
+	Status string
+}
+
+// ExportTarget возвращает поток ответа для выгрузки, которая отдается сразу
+type ExportTarget func(fileName, contentType string) io.Writer
+
+type ExportService interface {
+	// Export отдает выгрузку сразу в target, если в ней не больше EXPORT_INLINE_ROWS строк.
+	// Иначе ставит фоновую задачу и возвращает ее
+	Export(userID uint, userType string, request ExportRequest, target ExportTarget) (*api.ExportJobInfo, error)
+	GetJob(jobID, userID uint, userType string) (*api.ExportJobInfo, error)
+	GetJobs(userID uint, userType string) ([]api.ExportJobInfo, error)
+	// FailInterruptedJobs помечает задачи, не завершенные до перезапуска сервера
+	FailInterruptedJobs() error
+}
+
+type exportService struct {
+	exportRepo  repository.ExportRepository
+	jobRepo     repository.ExportJobRepository
+	fileStorage storage.Storage
+	slots       chan struct{}
+}
+
+func (s *exportService) Export(userID uint, userType string, request ExportRequest, target ExportTarget) (*api.ExportJobInfo, error) {
+	if request.Format == "" {
+		request.Format = export.FormatCSV
+	}
+	if !export.IsSupported(request.Format) {
+		return nil, errors.New("format must be csv, xlsx or ndjson")
+	}
+	statuses, ok := exportStatuses[request.Kind]
+	if !ok {
+		return nil, errors.New("kind must be balance, escrow or orders")
+	}
+	if request.Status != "" && !statuses[request.Status] {
+		return nil, errors.New("invalid status filter")
+	}
+
+	filter := repository.ExportFilter{UserID: userID, UserType: userType, Status: request.Status}
+	var err error
+	if filter.From, filter.To, err = parseExportRange(request.From, request.To); err != nil {
+		return nil, err
+	}
+
+	count, err := s.count(request.Kind, filter)
+	if err != nil {
+		return nil, err
+	}
+	if count <= internal.ExportInlineRows {
+		fileName := fmt.Sprintf("%s-%s.%s", request.Kind, time.Now().In(internal.TimeZone).Format(exportDateLayout), request.Format)
+		_, err := s.write(request.Kind, request.Format, filter, target(fileName, export.ContentType(request.Format)))
+		return nil, err
+	}
+
+	job := &database.ExportJob{
+		UserID:       userID,
+		UserType:     userType,
+		Kind:         request.Kind,
+		Format:       request.Format,
+		DateFrom:     filter.From,
+		DateTo:       filter.To,
+		StatusFilter: request.Status,
+		Status:       "pending",
+	}
+	if err := s.jobRepo.Create(job); err != nil {
+		return nil, err
+	}
+	go s.run(*job, filter)
+
+	return convertExportJobToInfo(job), nil
+}
+
+func (s *exportService) GetJob(jobID, userID uint, userType string) (*api.ExportJobInfo, error) {
+	job, err := s.jobRepo.GetByID(jobID)
+	if err != nil || job.UserID != userID || job.UserType != userType {
+		return nil, errors.New("export job not found")
+	}
+	return convertExportJobToInfo(job), nil
+}
+
+func (s *exportService) GetJobs(userID uint, userType string) ([]api.ExportJobInfo, error) {
+	jobs, err := s.jobRepo.GetByOwner(userID, userType, exportJobListLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []api.ExportJobInfo{}
+	for i := range jobs {
+		result = append(result, *convertExportJobToInfo(&jobs[i]))
+	}
+	return result, nil
+}
+
+func (s *exportService) FailInterruptedJobs() error {
+	return s.jobRepo.FailUnfinished("interrupted by server restart")
+}
+
+// run выполняет фоновую выгрузку, передавая строки в хранилище через канал без буфера в памяти
+func (s *exportService) run(job database.ExportJob, filter repository.ExportFilter) {
+	s.slots <- struct{}{}
+	defer func() { <-s.slots }()
+
+	job.Status = "running"
+	if err := s.jobRepo.Update(&job); err != nil {
+		log.Printf("failed to start export job %d: %v", job.ID, err)
+		return
+	}
+
+	job.FileName = fmt.Sprintf("exports/%s/%d/%d.%s", job.UserType, job.UserID, job.ID, job.Format)
+	reader, writer := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		rows, err := s.write(job.Kind, job.Format, filter, writer)
+		job.Rows = rows
+		writer.CloseWithError(err)
+		done <- err
+	}()
+	saveErr := s.fileStorage.Save(job.FileName, reader)
+	reader.CloseWithError(saveErr) // Разблокирует запись, если хранилище перестало читать
+	writeErr := <-done
+
+	now := time.Now()
+	job.CompletedAt = &now
+	job.Status = "completed"
+	if err := errors.Join(writeErr, saveErr); err != nil {
+		log.Printf("export job %d failed: %v", job.ID, err)
+		s.fileStorage.Delete(job.FileName)
+		job.FileName = ""
+		job.Status = "failed"
+		job.Error = "export failed"
+	}
+	if err := s.jobRepo.Update(&job); err != nil {
+		log.Printf("failed to save export job %d: %v", job.ID, err)
+	}
+}
+
+func (s *exportService) count(kind string, filter repository.ExportFilter) (int64, error) {
+	switch kind {
+	case ExportBalance:
+		return s.exportRepo.CountBalanceTransactions(filter)
+	case ExportEscrow:
+		return s.exportRepo.CountEscrowTransactions(filter)
+	default:
+		return s.exportRepo.CountOrders(filter)
+	}
+}
+
+// write записывает выгрузку в w построчно и возвращает число записанных строк
+func (s *exportService) write(kind, format string, filter repository.ExportFilter, w io.Writer) (int64, error) {
+	var rows int64
+	var writer export.Writer
+	open := func(columns []string) error {
+		var err error
+		writer, err = export.NewWriter(format, w, columns)
+		return err
+	}
+	emit := func(values ...interface{}) error {
+		rows++
+		return writer.WriteRow(values)
+	}
+
+	var err error
+	switch kind {
+	case ExportBalance:
+		if err = open([]string{"id", "created_at", "type", "status", "amount", "currency", "order_id", "description"}); err != nil {
+			return 0, err
+		}
+		err = s.exportRepo.StreamBalanceTransactions(filter, func(t *database.BalanceTransaction) error {
+			return emit(t.ID, t.CreatedAt.In(internal.TimeZone), t.Type, t.Status, t.Amount.Major(), t.Currency, t.OrderID, t.Description)
+		})
+	case ExportEscrow:
+		if err = open([]string{"id", "created_at", "order_id", "type", "status", "amount", "currency", "from_user", "to_user"}); err != nil {
+			return 0, err
+		}
+		err = s.exportRepo.StreamEscrowTransactions(filter, func(t *database.EscrowTransaction) error {
+			return emit(t.ID, t.CreatedAt.In(internal.TimeZone), t.OrderID, t.Type, t.Status, t.Amount.Major(), t.Currency, t.FromUser, t.ToUser)
+		})
+	default:
+		// Комиссия платформы видна только компании, как и в информации о заказе
+		isCompany := filter.UserType == "company"
+		columns := []string{"id", "created_at", "status", "payment_status", "card_id", "card_title", "client_id", "company_id", "amount", "currency"}
+		if isCompany {
+			columns = append(columns, "fee_amount")
+		}
+		if err = open(append(columns, "scheduled_at", "completed_at", "description")); err != nil {
+			return 0, err
+		}
+		err = s.exportRepo.StreamOrders(filter, func(o *repository.OrderExportRow) error {
+			values := []interface{}{o.ID, o.CreatedAt.In(internal.TimeZone), o.Status, o.PaymentStatus, o.CardID, o.CardTitle, o.ClientID, o.CompanyID, o.Amount.Major(), o.Currency}
+			if isCompany {
+				values = append(values, o.FeeAmount.Major())
+			}
+			return emit(append(values, inTimeZone(o.ScheduledAt), inTimeZone(o.CompletedAt), o.Description)...)
+		})
+	}
+	if err != nil {
+		return rows, err
+	}
+	return rows, writer.Close()
+}
+
+// parseExportRange разбирает даты выгрузки в часовом поясе платформы. Возвращает
+// полуинтервал [from, to + 1 день)
+func parseExportRange(from, to string) (*time.Time, *time.Time, error) {
+	var fromDate, toDate *time.Time
+	if from != "" {
+		parsed, err := time.ParseInLocation(exportDateLayout, from, internal.TimeZone)
+		if err != nil {
+			return nil, nil, errors.New("invalid from date, expected YYYY-MM-DD")
+		}
+		fromDate = &parsed
+	}
+	if to != "" {
+		parsed, err := time.ParseInLocation(exportDateLayout, to, internal.TimeZone)
+		if err != nil {
+			return nil, nil, errors.New("invalid to date, expected YYYY-MM-DD")
+		}
+		next := parsed.AddDate(0, 0, 1)
+		toDate = &next
+	}
+	if fromDate != nil && toDate != nil && !fromDate.Before(*toDate) {
+		return nil, nil, errors.New("from must not be after to")
+	}
+	return fromDate, toDate, nil
+}
+
+func inTimeZone(value *time.Time) *time.Time {
+	if value == nil {
+		return nil
+	}
+	local := value.In(internal.TimeZone)
+	return &local
+}
+
+func convertExportJobToInfo(job *database.ExportJob) *api.ExportJobInfo {
+	info := &api.ExportJobInfo{
+		ID:           job.ID,
+		Kind:         job.Kind,
+		Format:       job.Format,
+		StatusFilter: job.StatusFilter,
+		Status:       job.Status,
+		Rows:         job.Rows,
+		Error:        job.Error,
+		CreatedAt:    job.CreatedAt.Format(time.RFC3339),
+		CompletedAt:  formatOptionalTime(job.CompletedAt),
+	}
+	if job.DateFrom != nil {
+		from := job.DateFrom.In(internal.TimeZone).Format(exportDateLayout)
+		info.From = &from
+	}
+	if job.DateTo != nil {
+		to := job.DateTo.In(internal.TimeZone).AddDate(0, 0, -1).Format(exportDateLayout)
+		info.To = &to
+	}
+	if job.Status == "completed" && job.FileName != "" {
+		info.URL = security.SignPath("/files/"+job.FileName, time.Now().Add(exportURLTTL))
+	}
+	return info
+}
+
+func NewExportService(exportRepo repository.ExportRepository, jobRepo repository.ExportJobRepository, fileStorage storage.Storage) ExportService {
+	return &exportService{
+		exportRepo:  exportRepo,
+		jobRepo:     jobRepo,
+		fileStorage: fileStorage,
+		slots:       make(chan struct{}, maxConcurrentExports),
+	}
+}
@@ -0,0 +1,353 @@
+package service
+
+import (
+	"core/internal/api"
+	"core/internal/database"
+	"core/internal/database/repository"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// maxSavedSearches ограничение на число сохраненных поисков одного клиента
+const maxSavedSearches = 20
+
+type FavoriteService interface {
+	AddFavorite(clientID uint, targetType string, targetID uint) error
+	RemoveFavorite(clientID uint, targetType string, targetID uint) error
+	GetFavorites(clientID uint) (*api.FavoritesResponse, error)
+	CreateSavedSearch(clientID uint, info api.SavedSearchInfo) (*api.SavedSearchInfo, error)
+	UpdateSavedSearch(clientID, searchID uint, info api.SavedSearchInfo) (*api.SavedSearchInfo, error)
+	DeleteSavedSearch(clientID, searchID uint) error
+	GetSavedSearches(clientID uint) ([]api.SavedSearchInfo, error)
+	// NotifyNewCard уведомляет клиентов, которым интересна новая карточка
+	NotifyNewCard(card *database.Card) error
+}
+
+type favoriteService struct {
+	favoriteRepo        repository.FavoriteRepository
+	savedSearchRepo     repository.SavedSearchRepository
+	cardRepo            repository.CardRepository
+	companyRepo         repository.CompanyRepository
+	categoryRepo        repository.CategoryRepository
+	notificationService NotificationService
+}
+
+func (s *favoriteService) AddFavorite(clientID uint, targetType string, targetID uint) error {
+	switch targetType {
+	case repository.FavoriteTargetCard:
+		card, err := s.cardRepo.GetByID(targetID)
+		if err != nil {
+			return err
+		}
+		if !card.IsActive {
+			return errors.New("card is not active")
+		}
+	case repository.FavoriteTargetCompany:
+		if _, err := s.companyRepo.GetByID(targetID); err != nil {
+			return err
+		}
+	default:
+		return errors.New("target_type must be card or company")
+	}
+
+	return s.favoriteRepo.Add(&database.Favorite{
+		ClientID:   clientID,
+		TargetType: targetType,
+		TargetID:   targetID,
+	})
+}
+
+func (s *favoriteService) RemoveFavorite(clientID uint, targetType string, targetID uint) error {
+	removed, err := s.favoriteRepo.Remove(clientID, targetType, targetID)
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		return errors.New("favorite not found")
+	}
+	return nil
+}
+
+func (s *favoriteService) GetFavorites(clientID uint) (*api.FavoritesResponse, error) {
+	response := &api.FavoritesResponse{
+		Cards:     []api.ExtendedCardResponse{},
+		Companies: []api.FavoriteCompanyInfo{},
+	}
+
+	favoriteCards, err := s.favoriteRepo.GetByClient(clientID, repository.FavoriteTargetCard)
+	if err != nil {
+		return nil, err
+	}
+	cardIDs := make([]uint, 0, len(favoriteCards))
+	for _, favorite := range favoriteCards {
+		cardIDs = append(cardIDs, favorite.TargetID)
+	}
+	cards, err := s.cardRepo.GetByIDs(cardIDs)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := s.favoriteRepo.CountByCards(cardIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, card := range cards {
+		cardResponse := convertCardToExtendedResponse(card)
+		cardResponse.FavoriteCount = counts[card.ID]
+		response.Cards = append(response.Cards, cardResponse)
+	}
+
+	favoriteCompanies, err := s.favoriteRepo.GetByClient(clientID, repository.FavoriteTargetCompany)
+	if err != nil {
+		return nil, err
+	}
+	for _, favorite := range favoriteCompanies {
+		company, err := s.companyRepo.GetByID(favorite.TargetID)
+		if err != nil {
+			// Удаленные компании просто не показываем
+			continue
+		}
+		response.Companies = append(response.Companies, api.FavoriteCompanyInfo{
+			ID:          company.ID,
+			CompanyName: company.CompanyName,
+			Description: company.Description,
+			Photo:       company.Photo,
+			Stars:       company.Stars,
+			ReviewCount: company.ReviewCount,
+			IsVerified:  company.IsVerified,
+		})
+	}
+
+	return response, nil
+}
+
+func (s *favoriteService) CreateSavedSearch(clientID uint, info api.SavedSearchInfo) (*api.SavedSearchInfo, error) {
+	count, err := s.savedSearchRepo.CountByClient(clientID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= maxSavedSearches {
+		return nil, fmt.Errorf("no more than %d saved searches allowed", maxSavedSearches)
+	}
+
+	search := &database.SavedSearch{ClientID: clientID, NotifyNewCards: true}
+	if err := s.applySavedSearchInfo(search, info); err != nil {
+		return nil, err
+	}
+
+	if err := s.savedSearchRepo.Create(search); err != nil {
+		return nil, err
+	}
+	return convertSavedSearchToInfo(*search), nil
+}
+
+func (s *favoriteService) UpdateSavedSearch(clientID, searchID uint, info api.SavedSearchInfo) (*api.SavedSearchInfo, error) {
+	search, err := s.getOwnSavedSearch(clientID, searchID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.applySavedSearchInfo(search, info); err != nil {
+		return nil, err
+	}
+
+	if err := s.savedSearchRepo.Update(search); err != nil {
+		return nil, err
+	}
+	return convertSavedSearchToInfo(*search), nil
+}
+
+func (s *favoriteService) DeleteSavedSearch(clientID, searchID uint) error {
+	if _, err := s.getOwnSavedSearch(clientID, searchID); err != nil {
+		return err
+	}
+	return s.savedSearchRepo.Delete(searchID)
+}
+
+func (s *favoriteService) GetSavedSearches(clientID uint) ([]api.SavedSearchInfo, error) {
+	searches, err := s.savedSearchRepo.GetByClient(clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := []api.SavedSearchInfo{}
+	for _, search := range searches {
+		infos = append(infos, *convertSavedSearchToInfo(search))
+	}
+	return infos, nil
+}
+
+// NotifyNewCard отправляет уведомления клиентам, добавившим компанию в избранное,
+// и владельцам сохраненных поисков, в выдачу которых попадает карточка.
+// Каждый клиент получает не больше одного уведомления
+func (s *favoriteService) NotifyNewCard(card *database.Card) error {
+	messages := make(map[uint]string)
+
+	clientIDs, err := s.favoriteRepo.GetClientIDsByTarget(repository.FavoriteTargetCompany, card.CompanyID)
+	if err != nil {
+		return err
+	}
+	for _, clientID := range clientIDs {
+		messages[clientID] = fmt.Sprintf("Компания %s добавила новую услугу «%s»", card.Company.CompanyName, card.Title)
+	}
+
+	searches, err := s.savedSearchRepo.GetNotifiableForPrice(card.Price.Major())
+	if err != nil {
+		return err
+	}
+	var notifiedSearches []uint
+	for _, search := range searches {
+		if _, ok := messages[search.ClientID]; ok {
+			continue
+		}
+		filter, err := s.savedSearchFilter(search)
+		if err != nil {
+			// Категория поиска могла быть удалена, такой поиск пропускаем
+			continue
+		}
+		matches, err := s.cardRepo.MatchesSearch(card.ID, filter)
+		if err != nil {
+			return err
+		}
+		if matches {
+			messages[search.ClientID] = fmt.Sprintf("По сохраненному поиску «%s» появилась новая услуга «%s»", search.Name, card.Title)
+			notifiedSearches = append(notifiedSearches, search.ID)
+		}
+	}
+
+	for clientID, message := range messages {
+		err := s.notificationService.CreateNotification(clientID, "client", "Новая услуга", message, "new_card", &card.ID)
+		if err != nil {
+			return err
+		}
+	}
+	return s.savedSearchRepo.MarkNotified(notifiedSearches, time.Now())
+}
+
+func (s *favoriteService) getOwnSavedSearch(clientID, searchID uint) (*database.SavedSearch, error) {
+	search, err := s.savedSearchRepo.GetByID(searchID)
+	if err != nil {
+		return nil, err
+	}
+	if search.ClientID != clientID {
+		return nil, errors.New("access denied")
+	}
+	return search, nil
+}
+
+// applySavedSearchInfo проверяет фильтры так же, как /search/cards, и переносит их в модель
+func (s *favoriteService) applySavedSearchInfo(search *database.SavedSearch, info api.SavedSearchInfo) error {
+	filter := repository.CardSearchFilter{
+		Query:        strings.TrimSpace(info.Query),
+		Category:     info.Category,
+		MinPrice:     info.MinPrice,
+		MaxPrice:     info.MaxPrice,
+		Location:     info.Location,
+		MinRating:    info.MinRating,
+		VerifiedOnly: info.VerifiedOnly,
+		Latitude:     info.Latitude,
+		Longitude:    info.Longitude,
+		RadiusKm:     info.RadiusKm,
+		Sort:         info.Sort,
+	}
+	if err := validateCardSearchFilter(&filter); err != nil {
+		return err
+	}
+	if filter.Category != "" {
+		category, err := s.categoryRepo.FindByName(filter.Category)
+		if err != nil {
+			return errors.New("unknown category")
+		}
+		filter.Category = category.Slug
+	}
+
+	name := strings.TrimSpace(info.Name)
+	if name == "" {
+		name = filter.Query
+	}
+	if name == "" {
+		name = "Поиск"
+	}
+
+	search.Name = name
+	search.Query = filter.Query
+	search.Category = filter.Category
+	search.MinPrice = filter.MinPrice
+	search.MaxPrice = filter.MaxPrice
+	search.Location = filter.Location
+	search.MinRating = filter.MinRating
+	search.VerifiedOnly = filter.VerifiedOnly
+	search.Latitude = filter.Latitude
+	search.Longitude = filter.Longitude
+	search.RadiusKm = filter.RadiusKm
+	search.Sort = filter.Sort
+	if info.NotifyNewCards != nil {
+		search.NotifyNewCards = *info.NotifyNewCards
+	}
+	return nil
+}
+
+// savedSearchFilter собирает фильтр поиска карточек из сохраненного поиска
+func (s *favoriteService) savedSearchFilter(search database.SavedSearch) (repository.CardSearchFilter, error) {
+	filter := repository.CardSearchFilter{
+		Query:        search.Query,
+		MinPrice:     search.MinPrice,
+		MaxPrice:     search.MaxPrice,
+		Location:     search.Location,
+		MinRating:    search.MinRating,
+		VerifiedOnly: search.VerifiedOnly,
+		Latitude:     search.Latitude,
+		Longitude:    search.Longitude,
+		RadiusKm:     search.RadiusKm,
+	}
+	if search.Category != "" {
+		category, err := s.categoryRepo.FindByName(search.Category)
+		if err != nil {
+			return filter, err
+		}
+		categories, err := s.categoryRepo.GetAll()
+		if err != nil {
+			return filter, err
+		}
+		filter.CategoryIDs = categorySubtreeIDs(categories, category.ID)
+	}
+	return filter, nil
+}
+
+func convertSavedSearchToInfo(search database.SavedSearch) *api.SavedSearchInfo {
+	notify := search.NotifyNewCards
+	info := &api.SavedSearchInfo{
+		ID:             search.ID,
+		Name:           search.Name,
+		Query:          search.Query,
+		Category:       search.Category,
+		MinPrice:       search.MinPrice,
+		MaxPrice:       search.MaxPrice,
+		Location:       search.Location,
+		MinRating:      search.MinRating,
+		VerifiedOnly:   search.VerifiedOnly,
+		Latitude:       search.Latitude,
+		Longitude:      search.Longitude,
+		RadiusKm:       search.RadiusKm,
+		Sort:           search.Sort,
+		NotifyNewCards: &notify,
+		CreatedAt:      search.CreatedAt.Format(time.RFC3339),
+	}
+	if search.LastNotifiedAt != nil {
+		lastNotified := search.LastNotifiedAt.Format(time.RFC3339)
+		info.LastNotifiedAt = &lastNotified
+	}
+	return info
+}
+
+func NewFavoriteService(favoriteRepo repository.FavoriteRepository, savedSearchRepo repository.SavedSearchRepository, cardRepo repository.CardRepository, companyRepo repository.CompanyRepository, categoryRepo repository.CategoryRepository, notificationService NotificationService) FavoriteService {
+	return &favoriteService{
+		favoriteRepo:        favoriteRepo,
+		savedSearchRepo:     savedSearchRepo,
+		cardRepo:            cardRepo,
+		companyRepo:         companyRepo,
+		categoryRepo:        categoryRepo,
+		notificationService: notificationService,
+	}
+}
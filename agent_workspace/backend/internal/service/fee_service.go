@@ -0,0 +1,314 @@
+package service
+
+import (
+	"core/internal"
+	"core/internal/api"
+	"core/internal/database"
+	"core/internal/database/repository"
+	"core/internal/money"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var companyTierPattern = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+// FeeQuote рассчитанная комиссия платформы в валюте заказа
+type FeeQuote struct {
+	RuleID  *uint
+	Percent float64
+	Fixed   money.Minor
+	Amount  money.Minor
+}
+
+type FeeService interface {
+	// Quote рассчитывает комиссию платформы за услугу категории categoryID на момент at
+	// Фиксированная часть правила в другой валюте пересчитывается в валюту amount
+	Quote(companyID uint, categoryID *uint, amount money.Money, at time.Time) (*FeeQuote, error)
+	GetRules() ([]api.FeeRuleInfo, error)
+	CreateRule(info api.FeeRuleInfo) (*api.FeeRuleInfo, error)
+	UpdateRule(ruleID uint, info api.FeeRuleInfo) (*api.FeeRuleInfo, error)
+	DeleteRule(ruleID uint) error
+	SetCompanyTier(companyID uint, tier string) error
+	// GetPlatformRevenue возвращает суммы комиссий, зачисленных платформе, по валютам
+	GetPlatformRevenue() (map[string]money.Minor, error)
+}
+
+type feeService struct {
+	feeRuleRepo  repository.FeeRuleRepository
+	platformRepo repository.PlatformAccountRepository
+	companyRepo  repository.CompanyRepository
+	categoryRepo repository.CategoryRepository
+	rates        money.RateProvider
+}
+
+func (s *feeService) Quote(companyID uint, categoryID *uint, amount money.Money, at time.Time) (*FeeQuote, error) {
+	company, err := s.companyRepo.GetByID(companyID)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := s.feeRuleRepo.GetActiveAt(at)
+	if err != nil {
+		return nil, err
+	}
+
+	// Глубина категории карточки и ее предков: правило более глубокой категории конкретнее
+	depths := map[uint]int{}
+	if categoryID != nil && len(rules) > 0 {
+		categories, err := s.categoryRepo.GetAll()
+		if err != nil {
+			return nil, err
+		}
+		depths = categoryAncestorDepths(categories, *categoryID)
+	}
+
+	var best *database.FeeRule
+	bestRank := [3]int{}
+	for i := range rules {
+		rule := &rules[i]
+		categoryRank := 0
+		if rule.CategoryID != nil {
+			depth, ok := depths[*rule.CategoryID]
+			if !ok {
+				continue
+			}
+			categoryRank = depth + 1
+		}
+		tierRank := 0
+		if rule.CompanyTier != "" {
+			if rule.CompanyTier != company.Tier {
+				continue
+			}
+			tierRank = 1
+		}
+
+		rank := [3]int{rule.Priority, categoryRank, tierRank}
+		if best == nil || compareRanks(rank, bestRank) > 0 || (rank == bestRank && rule.ID > best.ID) {
+			best = rule
+			bestRank = rank
+		}
+	}
+
+	quote := &FeeQuote{Percent: internal.PlatformFeePercent}
+	fixed := money.New(money.FromMajor(internal.PlatformFeeFixed), internal.BaseCurrency)
+	if best != nil {
+		quote.RuleID = &best.ID
+		quote.Percent = best.Percent
+		fixed = money.New(best.Fixed, best.Currency)
+	}
+	if quote.Fixed, err = s.convert(fixed, amount.Currency); err != nil {
+		return nil, err
+	}
+	quote.Amount = calculateFee(amount.Amount, quote.Percent, quote.Fixed)
+	return quote, nil
+}
+
+func (s *feeService) GetRules() ([]api.FeeRuleInfo, error) {
+	rules, err := s.feeRuleRepo.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	result := []api.FeeRuleInfo{}
+	for _, rule := range rules {
+		result = append(result, convertFeeRuleToInfo(rule))
+	}
+	return result, nil
+}
+
+func (s *feeService) CreateRule(info api.FeeRuleInfo) (*api.FeeRuleInfo, error) {
+	rule := &database.FeeRule{IsActive: true}
+	if err := s.applyFeeRuleInfo(rule, info); err != nil {
+		return nil, err
+	}
+	if err := s.feeRuleRepo.Create(rule); err != nil {
+		return nil, err
+	}
+
+	result := convertFeeRuleToInfo(*rule)
+	return &result, nil
+}
+
+func (s *feeService) UpdateRule(ruleID uint, info api.FeeRuleInfo) (*api.FeeRuleInfo, error) {
+	rule, err := s.feeRuleRepo.GetByID(ruleID)
+	if err != nil {
+		return nil, errors.New("fee rule not found")
+	}
+	if err := s.applyFeeRuleInfo(rule, info); err != nil {
+		return nil, err
+	}
+	if err := s.feeRuleRepo.Update(rule); err != nil {
+		return nil, err
+	}
+
+	result := convertFeeRuleToInfo(*rule)
+	return &result, nil
+}
+
+func (s *feeService) DeleteRule(ruleID uint) error {
+	if _, err := s.feeRuleRepo.GetByID(ruleID); err != nil {
+		return errors.New("fee rule not found")
+	}
+	return s.feeRuleRepo.Delete(ruleID)
+}
+
+func (s *feeService) SetCompanyTier(companyID uint, tier string) error {
+	tier = strings.TrimSpace(tier)
+	if !companyTierPattern.MatchString(tier) {
+		return errors.New("tier must contain only lowercase letters, digits, '-' and '_'")
+	}
+	return s.companyRepo.UpdateTier(companyID, tier)
+}
+
+func (s *feeService) GetPlatformRevenue() (map[string]money.Minor, error) {
+	accounts, err := s.platformRepo.GetByCode(repository.PlatformRevenueAccount)
+	if err != nil {
+		return nil, err
+	}
+
+	revenue := map[string]money.Minor{}
+	for _, account := range accounts {
+		revenue[account.Currency] = account.Balance
+	}
+	return revenue, nil
+}
+
+// convert пересчитывает фиксированную часть комиссии в валюту заказа
+func (s *feeService) convert(amount money.Money, currency string) (money.Minor, error) {
+	if amount.Amount == 0 || amount.Currency == currency {
+		return amount.Amount, nil
+	}
+	rate, err := s.rates.Rate(amount.Currency, currency)
+	if err != nil {
+		return 0, err
+	}
+	return money.Convert(amount.Amount, rate), nil
+}
+
+// applyFeeRuleInfo проверяет параметры правила и переносит их в модель
+func (s *feeService) applyFeeRuleInfo(rule *database.FeeRule, info api.FeeRuleInfo) error {
+	if strings.TrimSpace(info.Name) == "" {
+		return errors.New("name is required")
+	}
+	if info.Percent < 0 || info.Percent > 100 {
+		return errors.New("percent must be between 0 and 100")
+	}
+	if info.Fixed < 0 {
+		return errors.New("fixed fee must not be negative")
+	}
+	if info.CompanyTier != "" && !companyTierPattern.MatchString(info.CompanyTier) {
+		return errors.New("invalid company tier")
+	}
+	if info.Currency == "" {
+		info.Currency = internal.BaseCurrency
+	}
+	currency, err := money.NormalizeCurrency(info.Currency)
+	if err != nil {
+		return err
+	}
+	if info.CategoryID != nil {
+		if _, err := s.categoryRepo.GetByID(*info.CategoryID); err != nil {
+			return errors.New("category not found")
+		}
+	}
+
+	startsAt, err := parseOptionalRFC3339(info.StartsAt)
+	if err != nil {
+		return errors.New("invalid starts_at, expected RFC3339")
+	}
+	endsAt, err := parseOptionalRFC3339(info.EndsAt)
+	if err != nil {
+		return errors.New("invalid ends_at, expected RFC3339")
+	}
+	if startsAt != nil && endsAt != nil && !endsAt.After(*startsAt) {
+		return errors.New("ends_at must be after starts_at")
+	}
+
+	rule.Name = strings.TrimSpace(info.Name)
+	rule.CategoryID = info.CategoryID
+	rule.CompanyTier = info.CompanyTier
+	rule.Percent = info.Percent
+	rule.Fixed = info.Fixed
+	rule.Currency = currency
+	rule.Priority = info.Priority
+	rule.StartsAt = startsAt
+	rule.EndsAt = endsAt
+	if info.IsActive != nil {
+		rule.IsActive = *info.IsActive
+	}
+	return nil
+}
+
+// calculateFee считает комиссию с округлением до минимальной единицы валюты.
+// Комиссия не превышает сумму заказа
+func calculateFee(amount money.Minor, percent float64, fixed money.Minor) money.Minor {
+	fee := amount.Percent(percent) + fixed
+	return max(0, min(fee, amount))
+}
+
+// categoryAncestorDepths возвращает категорию и ее предков с глубиной от корня
+func categoryAncestorDepths(categories []database.Category, categoryID uint) map[uint]int {
+	parents := make(map[uint]*uint, len(categories))
+	for _, category := range categories {
+		parents[category.ID] = category.ParentID
+	}
+
+	chain := []uint{}
+	for id := &categoryID; id != nil && len(chain) <= len(categories); id = parents[*id] {
+		chain = append(chain, *id)
+	}
+
+	depths := make(map[uint]int, len(chain))
+	for i, id := range chain {
+		depths[id] = len(chain) - 1 - i
+	}
+	return depths
+}
+
+func compareRanks(a, b [3]int) int {
+	for i := range a {
+		if a[i] != b[i] {
+			return a[i] - b[i]
+		}
+	}
+	return 0
+}
+
+func parseOptionalRFC3339(value *string) (*time.Time, error) {
+	if value == nil || *value == "" {
+		return nil, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, *value)
+	if err != nil {
+		return nil, err
+	}
+	return &parsed, nil
+}
+
+func convertFeeRuleToInfo(rule database.FeeRule) api.FeeRuleInfo {
+	isActive := rule.IsActive
+	return api.FeeRuleInfo{
+		ID:          rule.ID,
+		Name:        rule.Name,
+		CategoryID:  rule.CategoryID,
+		CompanyTier: rule.CompanyTier,
+		Percent:     rule.Percent,
+		Fixed:       rule.Fixed,
+		Currency:    rule.Currency,
+		Priority:    rule.Priority,
+		StartsAt:    formatOptionalTime(rule.StartsAt),
+		EndsAt:      formatOptionalTime(rule.EndsAt),
+		IsActive:    &isActive,
+	}
+}
+
+func NewFeeService(feeRuleRepo repository.FeeRuleRepository, platformRepo repository.PlatformAccountRepository, companyRepo repository.CompanyRepository, categoryRepo repository.CategoryRepository, rates money.RateProvider) FeeService {
+	return &feeService{
+		feeRuleRepo:  feeRuleRepo,
+		platformRepo: platformRepo,
+		companyRepo:  companyRepo,
+		categoryRepo: categoryRepo,
+		rates:        rates,
+	}
+}
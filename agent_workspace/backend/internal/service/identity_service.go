@@ -0,0 +1,317 @@
+package service
+
+import (
+	"core/internal"
+	"core/internal/api"
+	"core/internal/database"
+	"core/internal/database/repository"
+	"core/internal/ratelimit"
+	"core/internal/rating"
+	"core/internal/security"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// ErrAccountNotAvailable у пользователя нет запрошенной роли
+var ErrAccountNotAvailable = errors.New("the account is not available for this user")
+
+type IdentityService interface {
+	// Login проверяет email и пароль пользователя с учетом блокировки после серии неудачных попыток
+	Login(email, password string) (*database.UserDB, error)
+	// StartSession выдает токен в роли accountType ("client" или "company"). Без типа
+	// выбирается клиентский профиль, а если его нет - первая компания. companyID выбирает
+	// компанию, когда их несколько
+	StartSession(userID uint, accountType string, companyID uint) (*api.ResponseUser, error)
+	ListAccounts(userID uint) ([]api.UserAccount, error)
+	// CreateClientProfile делает пользователя клиентом и выдает токен в этой роли
+	CreateClientProfile(userID uint, phone string) (*api.ResponseUser, error)
+	// CreateCompany регистрирует компанию, владельцем которой становится пользователь
+	CreateCompany(userID uint, info api.CompanyCreateInfo) (*api.ResponseUser, error)
+
+	// MemberRole текущая роль пользователя в компании. Ошибка - пользователь в ней не состоит
+	MemberRole(userID, companyID uint) (string, error)
+	ListMembers(companyID uint) ([]api.CompanyMember, error)
+	// AddMember добавляет в компанию зарегистрированного пользователя
+	AddMember(companyID uint, email, role string) (*api.CompanyMember, error)
+	UpdateMemberRole(companyID, userID uint, role string) error
+	RemoveMember(companyID, userID uint) error
+}
+
+type identityService struct {
+	userRepo       repository.UserRepository
+	clientRepo     repository.ClientRepository
+	companyRepo    repository.CompanyRepository
+	accountService AccountService
+	limiter        *ratelimit.Limiter
+}
+
+func (s *identityService) Login(email, password string) (*database.UserDB, error) {
+	var user *database.UserDB
+	err := guardLogin(s.limiter, email, func() (err error) {
+		user, err = s.userRepo.GetByEmail(email)
+		if err != nil {
+			// Незарегистрированный email проверяется так же долго, как неверный пароль
+			security.SimulatePasswordCheck(password)
+			return err
+		}
+		return security.CheckPassword(password, user.PasswordHash)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if internal.EmailVerificationRequired && user.EmailVerifiedAt == nil {
+		return nil, ErrEmailNotVerified
+	}
+	return user, nil
+}
+
+func (s *identityService) StartSession(userID uint, accountType string, companyID uint) (*api.ResponseUser, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var role string
+	var accountID uint
+	switch {
+	case accountType == "client" || (accountType == "" && user.ClientID != nil):
+		if user.ClientID == nil {
+			return nil, ErrAccountNotAvailable
+		}
+		role, accountID = database.RoleClient, *user.ClientID
+	case accountType == "company" || accountType == "":
+		memberships, err := s.userRepo.ListMemberships(userID)
+		if err != nil {
+			return nil, err
+		}
+		for _, membership := range memberships {
+			if companyID == 0 || membership.CompanyID == companyID {
+				role, accountID = membership.Role, membership.CompanyID
+				break
+			}
+		}
+		if accountID == 0 {
+			return nil, ErrAccountNotAvailable
+		}
+	default:
+		return nil, errors.New("account_type must be client or company")
+	}
+
+	token := security.CreateSessionToken(user.ID, role, accountID, internal.LifeTimeJWT)
+	if token == "" {
+		return nil, errors.New("the created jwt was faulty")
+	}
+	response := &api.ResponseUser{
+		ID:     accountID,
+		Token:  token,
+		Type:   "client",
+		UserID: user.ID,
+		Role:   role,
+	}
+	if role != database.RoleClient {
+		response.Type = "company"
+		response.CompanyID = accountID
+	}
+	return response, nil
+}
+
+func (s *identityService) ListAccounts(userID uint) ([]api.UserAccount, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	accounts := []api.UserAccount{}
+	if user.ClientID != nil {
+		client, err := s.clientRepo.GetByID(*user.ClientID)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, api.UserAccount{Type: "client", ID: client.ID, Role: database.RoleClient, Name: client.FullName})
+	}
+
+	memberships, err := s.userRepo.ListMemberships(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, membership := range memberships {
+		company, err := s.companyRepo.GetByID(membership.CompanyID)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, api.UserAccount{Type: "company", ID: company.ID, Role: membership.Role, Name: company.CompanyName})
+	}
+	return accounts, nil
+}
+
+func (s *identityService) CreateClientProfile(userID uint, phone string) (*api.ResponseUser, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.ClientID != nil {
+		return nil, errors.New("user already has a client account")
+	}
+	if exists, _, _ := s.clientRepo.ExistsByEmail(user.Email); exists {
+		return nil, errors.New("email is already used by another client")
+	}
+
+	client := &database.ClientDB{
+		FullName:        user.FullName,
+		Email:           user.Email,
+		Phone:           strings.TrimSpace(phone),
+		Type:            "client",
+		EmailVerifiedAt: user.EmailVerifiedAt,
+	}
+	if err := s.userRepo.AddClient(user.ID, client); err != nil {
+		return nil, err
+	}
+	return s.StartSession(user.ID, "client", 0)
+}
+
+func (s *identityService) CreateCompany(userID uint, info api.CompanyCreateInfo) (*api.ResponseUser, error) {
+	info.CompanyName = strings.TrimSpace(info.CompanyName)
+	info.Email = strings.TrimSpace(info.Email)
+	if info.CompanyName == "" || info.Phone == "" || !strings.Contains(info.Email, "@") {
+		return nil, errors.New("company_name, email and phone are required")
+	}
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if exists, _, _ := s.companyRepo.ExistsByEmail(info.Email); exists {
+		return nil, errors.New("email already exists")
+	}
+
+	company := &database.CompanyDB{
+		CompanyName:  info.CompanyName,
+		FullName:     user.FullName,
+		Email:        info.Email,
+		Phone:        info.Phone,
+		Photo:        info.Photo,
+		Website:      info.Website,
+		Description:  info.Description,
+		Stars:        5,
+		RankingScore: rating.DefaultPriorMean, // Уточняется после первого отзыва
+		Type:         "company",
+	}
+	if err := s.userRepo.AddCompany(user.ID, company); err != nil {
+		return nil, err
+	}
+	return s.StartSession(user.ID, "company", company.ID)
+}
+
+func (s *identityService) MemberRole(userID, companyID uint) (string, error) {
+	membership, err := s.userRepo.GetMembership(userID, companyID)
+	if err != nil {
+		return "", err
+	}
+	return membership.Role, nil
+}
+
+func (s *identityService) ListMembers(companyID uint) ([]api.CompanyMember, error) {
+	memberships, err := s.userRepo.ListCompanyMembers(companyID)
+	if err != nil {
+		return nil, err
+	}
+	userIDs := make([]uint, 0, len(memberships))
+	for _, membership := range memberships {
+		userIDs = append(userIDs, membership.UserID)
+	}
+	users, err := s.userRepo.GetByIDs(userIDs)
+	if err != nil {
+		return nil, err
+	}
+	usersByID := make(map[uint]database.UserDB, len(users))
+	for _, user := range users {
+		usersByID[user.ID] = user
+	}
+
+	members := make([]api.CompanyMember, 0, len(memberships))
+	for _, membership := range memberships {
+		user := usersByID[membership.UserID]
+		members = append(members, companyMember(&membership, &user))
+	}
+	return members, nil
+}
+
+func (s *identityService) AddMember(companyID uint, email, role string) (*api.CompanyMember, error) {
+	if !isCompanyRole(role) {
+		return nil, errors.New("role must be owner, manager or accountant")
+	}
+	user, err := s.userRepo.GetByEmail(email)
+	if err != nil {
+		return nil, errors.New("user not found, ask them to sign up first")
+	}
+	if _, err := s.userRepo.GetMembership(user.ID, companyID); err == nil {
+		return nil, errors.New("user is already a member of the company")
+	}
+	company, err := s.companyRepo.GetByID(companyID)
+	if err != nil {
+		return nil, err
+	}
+
+	membership := &database.CompanyMembership{UserID: user.ID, CompanyID: companyID, Role: role}
+	if err := s.userRepo.CreateMembership(membership); err != nil {
+		return nil, err
+	}
+
+	s.accountService.NotifySecurity(user.ID, "user", "Вас добавили в компанию",
+		fmt.Sprintf("Вас добавили в компанию «%s» с ролью %s. Выбрать компанию можно после входа в "+
+			"списке учетных записей.", company.CompanyName, role))
+	member := companyMember(membership, user)
+	return &member, nil
+}
+
+func (s *identityService) UpdateMemberRole(companyID, userID uint, role string) error {
+	if !isCompanyRole(role) {
+		return errors.New("role must be owner, manager or accountant")
+	}
+	return s.userRepo.UpdateMembershipRole(companyID, userID, role)
+}
+
+func (s *identityService) RemoveMember(companyID, userID uint) error {
+	if err := s.userRepo.DeleteMembership(companyID, userID); err != nil {
+		return err
+	}
+	company, err := s.companyRepo.GetByID(companyID)
+	if err != nil {
+		log.Printf("failed to load company %d for member removal notice: %v", companyID, err)
+		return nil
+	}
+	s.accountService.NotifySecurity(userID, "user", "Доступ к компании закрыт",
+		fmt.Sprintf("Вас исключили из компании «%s». Действовать от ее имени больше нельзя.", company.CompanyName))
+	return nil
+}
+
+func isCompanyRole(role string) bool {
+	return role == database.RoleOwner || role == database.RoleManager || role == database.RoleAccountant
+}
+
+func companyMember(membership *database.CompanyMembership, user *database.UserDB) api.CompanyMember {
+	return api.CompanyMember{
+		UserID:   membership.UserID,
+		Email:    user.Email,
+		FullName: user.FullName,
+		Role:     membership.Role,
+		AddedAt:  membership.CreatedAt,
+	}
+}
+
+func NewIdentityService(
+	userRepo repository.UserRepository,
+	clientRepo repository.ClientRepository,
+	companyRepo repository.CompanyRepository,
+	accountService AccountService,
+	limiter *ratelimit.Limiter,
+) IdentityService {
+	return &identityService{
+		userRepo:       userRepo,
+		clientRepo:     clientRepo,
+		companyRepo:    companyRepo,
+		accountService: accountService,
+		limiter:        limiter,
+	}
+}
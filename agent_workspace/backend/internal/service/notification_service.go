@@ -4,6 +4,8 @@ import (
 	"core/internal/api"
 	"core/internal/database"
 	"core/internal/database/repository"
+	"core/internal/money"
+	"core/internal/pagination"
 	"errors"
 	"fmt"
 	"time"
@@ -11,12 +13,12 @@ import (
 
 type NotificationService interface {
 	CreateNotification(userID uint, userType, title, message, notificationType string, relatedID *uint) error
-	GetUserNotifications(userID uint, userType string, limit, offset int) ([]api.NotificationInfo, int, error)
+	GetUserNotifications(userID uint, userType string, page pagination.Request) ([]api.NotificationInfo, int, pagination.Page, error)
 	MarkAsRead(notificationID, userID uint) error
 	GetUnreadCount(userID uint, userType string) (int, error)
 	// Методы для создания специфических уведомлений
 	NotifyOrderStatusChange(orderID uint, status string) error
-	NotifyPaymentReceived(companyID uint, amount float64, orderID uint) error
+	NotifyPaymentReceived(companyID uint, amount money.Money, orderID uint) error
 	NotifyNewOrder(companyID uint, orderID uint) error
 }
 
@@ -39,15 +41,15 @@ func (s *notificationService) CreateNotification(userID uint, userType, title, m
 	return s.notificationRepo.Create(notification)
 }
 
-func (s *notificationService) GetUserNotifications(userID uint, userType string, limit, offset int) ([]api.NotificationInfo, int, error) {
-	notifications, err := s.notificationRepo.GetByUser(userID, userType, limit, offset)
+func (s *notificationService) GetUserNotifications(userID uint, userType string, page pagination.Request) ([]api.NotificationInfo, int, pagination.Page, error) {
+	notifications, next, err := s.notificationRepo.GetByUser(userID, userType, page)
 	if err != nil {
-		return nil, 0, err
+		return nil, 0, pagination.Page{}, err
 	}
 
 	total, err := s.notificationRepo.CountByUser(userID, userType)
 	if err != nil {
-		return nil, 0, err
+		return nil, 0, pagination.Page{}, err
 	}
 
 	var notificationInfos []api.NotificationInfo
@@ -63,7 +65,7 @@ func (s *notificationService) GetUserNotifications(userID uint, userType string,
 		})
 	}
 
-	return notificationInfos, total, nil
+	return notificationInfos, total, next, nil
 }
 
 func (s *notificationService) MarkAsRead(notificationID, userID uint) error {
@@ -127,9 +129,9 @@ func (s *notificationService) NotifyOrderStatusChange(orderID uint, status strin
 	return nil
 }
 
-func (s *notificationService) NotifyPaymentReceived(companyID uint, amount float64, orderID uint) error {
+func (s *notificationService) NotifyPaymentReceived(companyID uint, amount money.Money, orderID uint) error {
 	title := "Получена оплата"
-	message := fmt.Sprintf("Получена оплата %.2f руб. за заказ #%d", amount, orderID)
+	message := fmt.Sprintf("Получена оплата %s за заказ #%d", money.Format(amount, money.DefaultLocale), orderID)
 	return s.CreateNotification(companyID, "company", title, message, "payment", &orderID)
 }
 
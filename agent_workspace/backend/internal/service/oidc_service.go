@@ -0,0 +1,335 @@
+package service
+
+import (
+	"core/internal"
+	"core/internal/api"
+	"core/internal/database"
+	"core/internal/database/repository"
+	"core/internal/oidc"
+	"core/internal/security"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"log"
+	"strings"
+	"time"
+)
+
+const (
+	oidcPurposeLogin = "login"
+	oidcPurposeLink  = "link"
+)
+
+var (
+	ErrUnknownProvider = errors.New("unknown identity provider")
+	// ErrInvalidOIDCState state не найден, истек или уже использован: вход нужно начать заново
+	ErrInvalidOIDCState = errors.New("the sign-in attempt has expired, please start again")
+	// ErrProviderEmailNotVerified провайдер не подтвердил email, по нему нельзя найти или создать пользователя
+	ErrProviderEmailNotVerified = errors.New("the identity provider did not confirm the email")
+	ErrProviderDomainNotAllowed = errors.New("this email domain is not allowed for the identity provider")
+	ErrIdentityLinked           = errors.New("this account is already linked to another user")
+)
+
+type OIDCService interface {
+	Providers() []api.OIDCProvider
+	// StartLogin создает state и возвращает адрес страницы входа провайдера
+	StartLogin(provider string) (*api.OIDCAuthorization, error)
+	// CompleteLogin меняет код на данные пользователя у провайдера и находит пользователя:
+	// по привязанной учетной записи, затем по подтвержденному email. Если пользователя
+	// нет, он создается с клиентским профилем
+	CompleteLogin(state, code string) (*database.UserDB, error)
+
+	ListIdentities(userID uint) ([]api.LinkedIdentity, error)
+	StartLink(userID uint, provider string) (*api.OIDCAuthorization, error)
+	// CompleteLink привязывает учетную запись провайдера к пользователю, начавшему привязку
+	CompleteLink(userID uint, state, code string) (*api.LinkedIdentity, error)
+	Unlink(userID uint, provider string) error
+}
+
+type oidcService struct {
+	providers      []*oidc.Provider
+	oidcRepo       repository.OIDCRepository
+	userRepo       repository.UserRepository
+	clientRepo     repository.ClientRepository
+	accountService AccountService
+}
+
+func (s *oidcService) Providers() []api.OIDCProvider {
+	providers := make([]api.OIDCProvider, 0, len(s.providers))
+	for _, provider := range s.providers {
+		providers = append(providers, api.OIDCProvider{Name: provider.Name(), Title: provider.Title()})
+	}
+	return providers
+}
+
+func (s *oidcService) StartLogin(provider string) (*api.OIDCAuthorization, error) {
+	return s.start(provider, oidcPurposeLogin, 0)
+}
+
+func (s *oidcService) CompleteLogin(state, code string) (*database.UserDB, error) {
+	provider, identity, err := s.complete(state, code, oidcPurposeLogin, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	linked, err := s.oidcRepo.GetIdentity(provider.Name(), identity.Subject)
+	if err == nil {
+		if err := s.oidcRepo.MarkIdentityUsed(linked.ID, time.Now()); err != nil {
+			log.Printf("failed to update last use of identity %d: %v", linked.ID, err)
+		}
+		return s.userRepo.GetByID(linked.UserID)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if !identity.EmailVerified || identity.Email == "" {
+		return nil, ErrProviderEmailNotVerified
+	}
+	user, err := s.userRepo.GetByEmail(identity.Email)
+	if err == nil {
+		return s.linkByEmail(provider, identity, user)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	return s.createUser(provider, identity)
+}
+
+func (s *oidcService) ListIdentities(userID uint) ([]api.LinkedIdentity, error) {
+	identities, err := s.oidcRepo.ListIdentities(userID)
+	if err != nil {
+		return nil, err
+	}
+	linked := make([]api.LinkedIdentity, 0, len(identities))
+	for _, identity := range identities {
+		linked = append(linked, s.linkedIdentity(&identity))
+	}
+	return linked, nil
+}
+
+func (s *oidcService) StartLink(userID uint, provider string) (*api.OIDCAuthorization, error) {
+	return s.start(provider, oidcPurposeLink, userID)
+}
+
+func (s *oidcService) CompleteLink(userID uint, state, code string) (*api.LinkedIdentity, error) {
+	provider, identity, err := s.complete(state, code, oidcPurposeLink, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if linked, err := s.oidcRepo.GetIdentity(provider.Name(), identity.Subject); err == nil {
+		if linked.UserID != userID {
+			return nil, ErrIdentityLinked
+		}
+		result := s.linkedIdentity(linked)
+		return &result, nil
+	}
+
+	external := &database.ExternalIdentity{
+		UserID:   userID,
+		Provider: provider.Name(),
+		Subject:  identity.Subject,
+		Email:    identity.Email,
+	}
+	if err := s.oidcRepo.CreateIdentity(external); err != nil {
+		log.Printf("failed to link %s identity to user %d: %v", provider.Name(), userID, err)
+		return nil, fmt.Errorf("%s is already linked, unlink it first", provider.Title())
+	}
+	s.notifyLinked(userID, provider, identity.Email)
+	result := s.linkedIdentity(external)
+	return &result, nil
+}
+
+func (s *oidcService) Unlink(userID uint, provider string) error {
+	provider = strings.ToLower(provider)
+	if err := s.oidcRepo.DeleteIdentity(userID, provider); err != nil {
+		return err
+	}
+	title := provider
+	if p := s.provider(provider); p != nil {
+		title = p.Title()
+	}
+	s.accountService.NotifySecurity(userID, "user", "Вход через "+title+" отключен",
+		"Учетная запись "+title+" отвязана, входить через нее больше нельзя. Если это были не вы, "+
+			"смените пароль.")
+	return nil
+}
+
+// start сохраняет state, nonce и code_verifier и возвращает адрес входа провайдера
+func (s *oidcService) start(name, purpose string, userID uint) (*api.OIDCAuthorization, error) {
+	provider := s.provider(name)
+	if provider == nil {
+		return nil, ErrUnknownProvider
+	}
+
+	state, stateHash, err := security.NewOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := oidc.NewNonce()
+	if err != nil {
+		return nil, err
+	}
+	codeVerifier, err := oidc.NewCodeVerifier()
+	if err != nil {
+		return nil, err
+	}
+	authURL, err := provider.AuthCodeURL(state, nonce, codeVerifier)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.oidcRepo.CreateState(&database.OIDCState{
+		StateHash:    stateHash,
+		Provider:     provider.Name(),
+		Purpose:      purpose,
+		UserID:       userID,
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+		ExpiresAt:    time.Now().Add(internal.OIDCStateTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &api.OIDCAuthorization{
+		AuthorizationURL: authURL,
+		State:            state,
+		ExpiresIn:        int(internal.OIDCStateTTL.Seconds()),
+	}, nil
+}
+
+// complete принимает state один раз и меняет код на пользователя провайдера. Привязку
+// может завершить только пользователь, который ее начал
+func (s *oidcService) complete(state, code, purpose string, userID uint) (*oidc.Provider, *oidc.Identity, error) {
+	saved, err := s.oidcRepo.ConsumeState(security.HashOpaqueToken(state), purpose, time.Now())
+	if err != nil {
+		return nil, nil, ErrInvalidOIDCState
+	}
+	if saved.UserID != userID {
+		return nil, nil, ErrInvalidOIDCState
+	}
+	provider := s.provider(saved.Provider)
+	if provider == nil {
+		return nil, nil, ErrUnknownProvider
+	}
+
+	identity, err := provider.Exchange(code, saved.CodeVerifier, saved.Nonce)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !provider.AllowsEmail(identity.Email) {
+		return nil, nil, ErrProviderDomainNotAllowed
+	}
+	return provider, identity, nil
+}
+
+// linkByEmail привязывает провайдера к пользователю с тем же email. Если пользователь
+// свой email не подтвердил, адрес мог зарегистрировать кто-то другой: подтверждение
+// провайдера засчитывается, а пароль сбрасывается
+func (s *oidcService) linkByEmail(provider *oidc.Provider, identity *oidc.Identity, user *database.UserDB) (*database.UserDB, error) {
+	if user.EmailVerifiedAt == nil {
+		now := time.Now()
+		if err := s.userRepo.Update(user.ID, map[string]interface{}{"email_verified_at": now, "password_hash": ""}); err != nil {
+			return nil, err
+		}
+		user.EmailVerifiedAt, user.PasswordHash = &now, ""
+	}
+
+	now := time.Now()
+	err := s.oidcRepo.CreateIdentity(&database.ExternalIdentity{
+		UserID:     user.ID,
+		Provider:   provider.Name(),
+		Subject:    identity.Subject,
+		Email:      identity.Email,
+		LastUsedAt: &now,
+	})
+	if err != nil {
+		// У пользователя уже привязана другая учетная запись этого провайдера
+		log.Printf("failed to link %s identity to user %d by email: %v", provider.Name(), user.ID, err)
+		return nil, ErrIdentityLinked
+	}
+	s.notifyLinked(user.ID, provider, identity.Email)
+	return user, nil
+}
+
+// createUser регистрирует пользователя с клиентским профилем без пароля. Пароль можно
+// задать позже через сброс пароля
+func (s *oidcService) createUser(provider *oidc.Provider, identity *oidc.Identity) (*database.UserDB, error) {
+	if exists, _, _ := s.clientRepo.ExistsByEmail(identity.Email); exists {
+		return nil, errors.New("email is already used by another client")
+	}
+
+	name := strings.TrimSpace(identity.Name)
+	if name == "" {
+		name, _, _ = strings.Cut(identity.Email, "@")
+	}
+	now := time.Now()
+	user := &database.UserDB{
+		Email:           identity.Email,
+		FullName:        name,
+		EmailVerifiedAt: &now,
+	}
+	client := &database.ClientDB{
+		FullName:        name,
+		Email:           identity.Email,
+		Type:            "client",
+		EmailVerifiedAt: &now,
+	}
+	external := &database.ExternalIdentity{
+		Provider:   provider.Name(),
+		Subject:    identity.Subject,
+		Email:      identity.Email,
+		LastUsedAt: &now,
+	}
+	if err := s.oidcRepo.CreateUserWithIdentity(user, client, external); err != nil {
+		log.Printf("failed to create user %s from %s: %v", identity.Email, provider.Name(), err)
+		return nil, errors.New("failed to create account")
+	}
+	return user, nil
+}
+
+func (s *oidcService) notifyLinked(userID uint, provider *oidc.Provider, email string) {
+	s.accountService.NotifySecurity(userID, "user", "Привязан вход через "+provider.Title(),
+		fmt.Sprintf("К вашей учетной записи привязан вход через %s (%s). Если это были не вы, "+
+			"отвяжите его в настройках безопасности и смените пароль.", provider.Title(), email))
+}
+
+func (s *oidcService) linkedIdentity(identity *database.ExternalIdentity) api.LinkedIdentity {
+	title := identity.Provider
+	if provider := s.provider(identity.Provider); provider != nil {
+		title = provider.Title()
+	}
+	return api.LinkedIdentity{
+		Provider:   identity.Provider,
+		Title:      title,
+		Email:      identity.Email,
+		LinkedAt:   identity.CreatedAt,
+		LastUsedAt: identity.LastUsedAt,
+	}
+}
+
+func (s *oidcService) provider(name string) *oidc.Provider {
+	for _, provider := range s.providers {
+		if provider.Name() == strings.ToLower(name) {
+			return provider
+		}
+	}
+	return nil
+}
+
+func NewOIDCService(
+	providers []*oidc.Provider,
+	oidcRepo repository.OIDCRepository,
+	userRepo repository.UserRepository,
+	clientRepo repository.ClientRepository,
+	accountService AccountService,
+) OIDCService {
+	return &oidcService{
+		providers:      providers,
+		oidcRepo:       oidcRepo,
+		userRepo:       userRepo,
+		clientRepo:     clientRepo,
+		accountService: accountService,
+	}
+}
@@ -0,0 +1,316 @@
+package service
+
+import (
+	"core/internal"
+	"core/internal/database"
+	"core/internal/database/repository"
+	"core/internal/oidc"
+	"core/internal/oidc/oidctest"
+	"core/internal/security"
+	"errors"
+	"gorm.io/gorm"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeOIDCRepository хранит state и привязки в памяти с теми же условиями, что и
+// oidcRepository: state принимается один раз и до истечения срока
+type fakeOIDCRepository struct {
+	states     map[string]*database.OIDCState
+	identities []database.ExternalIdentity
+	users      *fakeUserRepository
+}
+
+func (r *fakeOIDCRepository) CreateState(state *database.OIDCState) error {
+	r.states[state.StateHash] = state
+	return nil
+}
+
+func (r *fakeOIDCRepository) ConsumeState(stateHash, purpose string, at time.Time) (*database.OIDCState, error) {
+	state, ok := r.states[stateHash]
+	if !ok || state.Purpose != purpose || state.UsedAt != nil || !state.ExpiresAt.After(at) {
+		return nil, errors.New("invalid or expired state")
+	}
+	state.UsedAt = &at
+	saved := *state
+	return &saved, nil
+}
+
+func (r *fakeOIDCRepository) GetIdentity(provider, subject string) (*database.ExternalIdentity, error) {
+	for _, identity := range r.identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			return &identity, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeOIDCRepository) ListIdentities(userID uint) ([]database.ExternalIdentity, error) {
+	var identities []database.ExternalIdentity
+	for _, identity := range r.identities {
+		if identity.UserID == userID {
+			identities = append(identities, identity)
+		}
+	}
+	return identities, nil
+}
+
+func (r *fakeOIDCRepository) CreateIdentity(identity *database.ExternalIdentity) error {
+	for _, existing := range r.identities {
+		if existing.Provider == identity.Provider &&
+			(existing.Subject == identity.Subject || existing.UserID == identity.UserID) {
+			return errors.New("duplicate key value violates unique constraint")
+		}
+	}
+	identity.ID = uint(len(r.identities) + 1)
+	r.identities = append(r.identities, *identity)
+	return nil
+}
+
+func (r *fakeOIDCRepository) CreateUserWithIdentity(user *database.UserDB, client *database.ClientDB, identity *database.ExternalIdentity) error {
+	r.users.add(user)
+	identity.UserID = user.ID
+	return r.CreateIdentity(identity)
+}
+
+func (r *fakeOIDCRepository) MarkIdentityUsed(id uint, at time.Time) error {
+	return nil
+}
+
+func (r *fakeOIDCRepository) DeleteIdentity(userID uint, provider string) error {
+	return errors.New("not implemented")
+}
+
+type fakeUserRepository struct {
+	repository.UserRepository
+	users map[uint]*database.UserDB
+}
+
+func (r *fakeUserRepository) add(user *database.UserDB) {
+	user.ID = uint(len(r.users) + 1)
+	r.users[user.ID] = user
+}
+
+func (r *fakeUserRepository) GetByID(id uint) (*database.UserDB, error) {
+	if user, ok := r.users[id]; ok {
+		return user, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeUserRepository) GetByEmail(email string) (*database.UserDB, error) {
+	for _, user := range r.users {
+		if strings.EqualFold(user.Email, email) {
+			return user, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeUserRepository) Update(userID uint, updates map[string]interface{}) error {
+	user, ok := r.users[userID]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	if hash, ok := updates["password_hash"].(string); ok {
+		user.PasswordHash = hash
+	}
+	if verifiedAt, ok := updates["email_verified_at"].(time.Time); ok {
+		user.EmailVerifiedAt = &verifiedAt
+	}
+	return nil
+}
+
+type fakeClientRepository struct {
+	repository.ClientRepository
+}
+
+func (r *fakeClientRepository) ExistsByEmail(email string) (bool, bool, database.ClientDB) {
+	return false, false, database.ClientDB{}
+}
+
+type fakeAccountService struct {
+	AccountService
+	notified []uint
+}
+
+func (s *fakeAccountService) NotifySecurity(userID uint, userType, title, message string) {
+	s.notified = append(s.notified, userID)
+}
+
+type oidcServiceFixture struct {
+	server   *oidctest.Server
+	repo     *fakeOIDCRepository
+	users    *fakeUserRepository
+	accounts *fakeAccountService
+	service  OIDCService
+}
+
+func newOIDCServiceFixture(t *testing.T) *oidcServiceFixture {
+	t.Helper()
+	internal.OIDCStateTTL = 10 * time.Minute
+
+	server := oidctest.NewServer("test-client")
+	t.Cleanup(server.Close)
+	provider := oidc.NewProvider(oidc.Config{
+		Name:     "test",
+		Issuer:   server.Issuer(),
+		ClientID: "test-client",
+	}, "http://localhost/oidc/callback")
+
+	users := &fakeUserRepository{users: map[uint]*database.UserDB{}}
+	repo := &fakeOIDCRepository{states: map[string]*database.OIDCState{}, users: users}
+	accounts := &fakeAccountService{}
+	return &oidcServiceFixture{
+		server:   server,
+		repo:     repo,
+		users:    users,
+		accounts: accounts,
+		service:  NewOIDCService([]*oidc.Provider{provider}, repo, users, &fakeClientRepository{}, accounts),
+	}
+}
+
+// respond настраивает ответ провайдера на вход, начатый с state
+func (f *oidcServiceFixture) respond(t *testing.T, state, subject, email string, emailVerified bool) {
+	t.Helper()
+	saved, ok := f.repo.states[security.HashOpaqueToken(state)]
+	if !ok {
+		t.Fatal("state was not saved")
+	}
+	claims := f.server.Claims(subject, saved.Nonce)
+	claims["email"] = email
+	claims["email_verified"] = emailVerified
+	f.server.Respond(f.server.Sign(claims), nil)
+}
+
+func TestOIDCCompleteLoginCreatesUser(t *testing.T) {
+	f := newOIDCServiceFixture(t)
+
+	authorization, err := f.service.StartLogin("test")
+	if err != nil {
+		t.Fatalf("StartLogin: %v", err)
+	}
+	f.respond(t, authorization.State, "sub-1", "new@example.com", true)
+
+	user, err := f.service.CompleteLogin(authorization.State, "code")
+	if err != nil {
+		t.Fatalf("CompleteLogin: %v", err)
+	}
+	if user.Email != "new@example.com" || user.EmailVerifiedAt == nil {
+		t.Errorf("user = %+v, want verified new@example.com", user)
+	}
+	if len(f.repo.identities) != 1 || f.repo.identities[0].UserID != user.ID {
+		t.Errorf("identities = %+v, want one identity of user %d", f.repo.identities, user.ID)
+	}
+}
+
+func TestOIDCCompleteLoginRejectsReplayedState(t *testing.T) {
+	f := newOIDCServiceFixture(t)
+
+	authorization, err := f.service.StartLogin("test")
+	if err != nil {
+		t.Fatalf("StartLogin: %v", err)
+	}
+	f.respond(t, authorization.State, "sub-1", "user@example.com", true)
+	if _, err := f.service.CompleteLogin(authorization.State, "code"); err != nil {
+		t.Fatalf("CompleteLogin: %v", err)
+	}
+
+	if _, err := f.service.CompleteLogin(authorization.State, "code"); !errors.Is(err, ErrInvalidOIDCState) {
+		t.Fatalf("replayed CompleteLogin error = %v, want ErrInvalidOIDCState", err)
+	}
+}
+
+func TestOIDCCompleteLoginRejectsExpiredState(t *testing.T) {
+	f := newOIDCServiceFixture(t)
+
+	authorization, err := f.service.StartLogin("test")
+	if err != nil {
+		t.Fatalf("StartLogin: %v", err)
+	}
+	f.respond(t, authorization.State, "sub-1", "user@example.com", true)
+	for _, saved := range f.repo.states {
+		saved.ExpiresAt = time.Now().Add(-time.Second)
+	}
+
+	if _, err := f.service.CompleteLogin(authorization.State, "code"); !errors.Is(err, ErrInvalidOIDCState) {
+		t.Fatalf("CompleteLogin error = %v, want ErrInvalidOIDCState", err)
+	}
+}
+
+func TestOIDCCompleteLinkRequiresSameUser(t *testing.T) {
+	f := newOIDCServiceFixture(t)
+	owner := &database.UserDB{Email: "owner@example.com", PasswordHash: "hash"}
+	f.users.add(owner)
+
+	authorization, err := f.service.StartLink(owner.ID, "test")
+	if err != nil {
+		t.Fatalf("StartLink: %v", err)
+	}
+	f.respond(t, authorization.State, "sub-1", "owner@example.com", true)
+
+	if _, err := f.service.CompleteLogin(authorization.State, "code"); !errors.Is(err, ErrInvalidOIDCState) {
+		t.Errorf("CompleteLogin with link state error = %v, want ErrInvalidOIDCState", err)
+	}
+	authorization, err = f.service.StartLink(owner.ID, "test")
+	if err != nil {
+		t.Fatalf("StartLink: %v", err)
+	}
+	if _, err := f.service.CompleteLink(owner.ID+1, authorization.State, "code"); !errors.Is(err, ErrInvalidOIDCState) {
+		t.Errorf("CompleteLink by another user error = %v, want ErrInvalidOIDCState", err)
+	}
+	if len(f.repo.identities) != 0 {
+		t.Errorf("identities = %+v, want none", f.repo.identities)
+	}
+}
+
+func TestOIDCCompleteLoginRefusesUnverifiedEmail(t *testing.T) {
+	f := newOIDCServiceFixture(t)
+	now := time.Now()
+	existing := &database.UserDB{Email: "user@example.com", PasswordHash: "hash", EmailVerifiedAt: &now}
+	f.users.add(existing)
+
+	authorization, err := f.service.StartLogin("test")
+	if err != nil {
+		t.Fatalf("StartLogin: %v", err)
+	}
+	f.respond(t, authorization.State, "sub-1", "user@example.com", false)
+
+	if _, err := f.service.CompleteLogin(authorization.State, "code"); !errors.Is(err, ErrProviderEmailNotVerified) {
+		t.Fatalf("CompleteLogin error = %v, want ErrProviderEmailNotVerified", err)
+	}
+	if len(f.repo.identities) != 0 {
+		t.Errorf("identities = %+v, want none", f.repo.identities)
+	}
+	if existing.PasswordHash != "hash" {
+		t.Error("password of the existing user was changed")
+	}
+}
+
+func TestOIDCCompleteLoginLinksUnverifiedLocalUser(t *testing.T) {
+	f := newOIDCServiceFixture(t)
+	existing := &database.UserDB{Email: "user@example.com", PasswordHash: "hash"}
+	f.users.add(existing)
+
+	authorization, err := f.service.StartLogin("test")
+	if err != nil {
+		t.Fatalf("StartLogin: %v", err)
+	}
+	f.respond(t, authorization.State, "sub-1", "user@example.com", true)
+
+	user, err := f.service.CompleteLogin(authorization.State, "code")
+	if err != nil {
+		t.Fatalf("CompleteLogin: %v", err)
+	}
+	if user.ID != existing.ID {
+		t.Fatalf("user = %d, want existing user %d", user.ID, existing.ID)
+	}
+	// Пароль мог задать тот, кто зарегистрировал чужой адрес
+	if existing.PasswordHash != "" || existing.EmailVerifiedAt == nil {
+		t.Errorf("user = %+v, want verified email and cleared password", existing)
+	}
+	if len(f.accounts.notified) != 1 || f.accounts.notified[0] != existing.ID {
+		t.Errorf("notified = %v, want user %d", f.accounts.notified, existing.ID)
+	}
+}
@@ -1,39 +1,76 @@
 package service
 
 import (
+	"core/internal"
 	"core/internal/api"
 	"core/internal/database"
 	"core/internal/database/repository"
+	"core/internal/money"
+	"core/internal/pagination"
+	"core/internal/storage"
 	"errors"
 	"fmt"
+	"gorm.io/gorm"
+	"log"
+	"sort"
+	"strings"
 	"time"
 )
 
 type OrderService interface {
-	CreateOrder(clientID, companyID, cardID uint, description string) (*database.Order, error)
+	// CreateOrder создает заказ по цене карточки. Необязательный promoCode уменьшает сумму к оплате
+	CreateOrder(clientID, companyID, cardID uint, description string, scheduledAt *time.Time, promoCode string) (*database.Order, error)
 	GetOrderByID(id uint) (*database.Order, error)
-	GetOrdersByClient(clientID uint, page, limit int) ([]database.Order, error)
-	GetOrdersByCompany(companyID uint, page, limit int) ([]database.Order, error)
+	GetOrdersByClient(clientID uint, page pagination.Request) ([]database.Order, pagination.Page, error)
+	GetOrdersByCompany(companyID uint, page pagination.Request) ([]database.Order, pagination.Page, error)
 	PayForOrder(orderID, clientID uint) error
+	// CheckoutCart создает по заказу на каждую компанию корзины и оплачивает их одним списанием
+	// с баланса клиента. takeCart удаляет оформляемые позиции из корзины в той же транзакции.
+	// При любой ошибке не создается ни один заказ
+	CheckoutCart(clientID uint, cartOrders []CartOrder, takeCart func(tx *gorm.DB) error) (*database.Checkout, error)
 	AcceptOrder(orderID, companyID uint) error
 	StartOrder(orderID, companyID uint) error
-	CompleteOrderByWorker(token string) error
+	OpenWorkerLink(token, action, ip, userAgent string) (*database.Order, error)
+	CompleteOrderByWorker(token string, input *CompletionReportInput) error
 	FinishOrder(orderID, clientID uint) error
 	CancelOrder(orderID uint, userID uint, userType string) error
-	GetAllOrders(page, limit int) ([]database.Order, error)
-	GetOrdersWithFilter(userID uint, userType, status string, limit, offset int) ([]api.OrderInfo, int, error)
+	// GetAllOrders активные заказы для публичного списка, только публичные поля
+	GetAllOrders(page pagination.Request) ([]api.PublicOrder, pagination.Page, error)
+	GetOrdersWithFilter(userID uint, userType, status string, page pagination.Request) ([]api.OrderInfo, int, pagination.Page, error)
 	GetOrderInfo(orderID, userID uint, userType string) (*api.OrderInfo, error)
+	GetCompletionReport(orderID, userID uint, userType string) (*api.CompletionReportInfo, error)
+}
+
+// CartOrder услуги одной компании из корзины, которые оформляются одним заказом
+type CartOrder struct {
+	CompanyID   uint
+	Items       []CartOrderItem
+	ScheduledAt *time.Time
+	Description string
+}
+
+type CartOrderItem struct {
+	Card     *database.Card
+	Quantity int
 }
 
 type orderService struct {
-	orderRepo      repository.OrderRepository
-	cardRepo       repository.CardRepository
-	balanceRepo    repository.BalanceRepository
-	escrowRepo     repository.EscrowRepository
-	workerLinkRepo repository.WorkerLinkRepository
+	orderRepo       repository.OrderRepository
+	cardRepo        repository.CardRepository
+	balanceRepo     repository.BalanceRepository
+	escrowRepo      repository.EscrowRepository
+	workerLinkRepo  repository.WorkerLinkRepository
+	scheduleRepo    repository.ScheduleRepository
+	reportRepo      repository.CompletionReportRepository
+	fileStorage     storage.Storage
+	feeService      FeeService
+	platformRepo    repository.PlatformAccountRepository
+	documentService DocumentService
+	rates           money.RateProvider
+	promoService    PromoService
 }
 
-func (s *orderService) CreateOrder(clientID, companyID, cardID uint, description string) (*database.Order, error) {
+func (s *orderService) CreateOrder(clientID, companyID, cardID uint, description string, scheduledAt *time.Time, promoCode string) (*database.Order, error) {
 	// Получаем карточку услуги
 	card, err := s.cardRepo.GetByID(cardID)
 	if err != nil {
@@ -49,15 +86,55 @@ func (s *orderService) CreateOrder(clientID, companyID, cardID uint, description
 		ClientID:      clientID,
 		CompanyID:     companyID,
 		CardID:        cardID,
+		Subtotal:      card.Price,
 		Amount:        card.Price,
+		Currency:      card.Currency,
 		Status:        "created",
 		PaymentStatus: "pending",
 		Description:   description,
 	}
 
-	err = s.orderRepo.Create(order)
+	now := time.Now()
+	var discount *PromoDiscount
+	if strings.TrimSpace(promoCode) != "" {
+		discount, err = s.promoService.Quote(clientID, promoCode, card, now)
+		if err != nil {
+			return nil, err
+		}
+		order.DiscountAmount = discount.Amount
+		order.Amount = card.Price - discount.Amount
+		order.PromoCodeID = &discount.Promo.ID
+		if discount.FundedBy == "platform" {
+			order.PlatformDiscount = discount.Amount
+		}
+	}
+
+	if err := s.applyFeeQuote(order, card.CategoryID, now); err != nil {
+		return nil, err
+	}
+
+	capacity, err := s.resolveOrderSlot(order, scheduledAt)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create order: %w", err)
+		return nil, err
+	}
+
+	tx := s.orderRepo.BeginTransaction()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	// Строки заказа и применение промокода сохраняются вместе с заказом
+	items := []database.OrderLineItem{{Description: card.Title, Amount: order.Subtotal, CardID: &card.ID, Quantity: 1}}
+	if err := s.insertOrderInTx(tx, order, capacity, items, discount); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
 	}
 
 	return order, nil
@@ -67,14 +144,12 @@ func (s *orderService) GetOrderByID(id uint) (*database.Order, error) {
 	return s.orderRepo.GetByID(id)
 }
 
-func (s *orderService) GetOrdersByClient(clientID uint, page, limit int) ([]database.Order, error) {
-	offset := (page - 1) * limit
-	return s.orderRepo.GetByClientID(clientID, limit, offset)
+func (s *orderService) GetOrdersByClient(clientID uint, page pagination.Request) ([]database.Order, pagination.Page, error) {
+	return s.orderRepo.GetByClientID(clientID, page)
 }
 
-func (s *orderService) GetOrdersByCompany(companyID uint, page, limit int) ([]database.Order, error) {
-	offset := (page - 1) * limit
-	return s.orderRepo.GetByCompanyID(companyID, limit, offset)
+func (s *orderService) GetOrdersByCompany(companyID uint, page pagination.Request) ([]database.Order, pagination.Page, error) {
+	return s.orderRepo.GetByCompanyID(companyID, page)
 }
 
 func (s *orderService) AcceptOrder(orderID, companyID uint) error {
@@ -113,7 +188,18 @@ func (s *orderService) PayForOrder(orderID, clientID uint) error {
 		return err
 	}
 
-	if balance < order.Amount {
+	// Если счет клиента в другой валюте, сумма списания считается по текущему курсу.
+	// Курс фиксируется в заказе: по нему же будет сделан возврат
+	rate := 1.0
+	if balance.Currency != order.Currency {
+		if rate, err = s.rates.Rate(order.Currency, balance.Currency); err != nil {
+			return err
+		}
+	}
+	payment := money.New(money.Convert(order.Amount, rate), balance.Currency)
+	lockedAt := time.Now()
+
+	if balance.Amount < payment.Amount {
 		return errors.New("insufficient balance")
 	}
 
@@ -125,67 +211,205 @@ func (s *orderService) PayForOrder(orderID, clientID uint) error {
 		}
 	}()
 
-	// Списываем деньги
-	if err := s.balanceRepo.UpdateClientBalanceInTx(tx, clientID, -order.Amount); err != nil {
-		tx.Rollback()
-		return err
-	}
-
-	// Эскроу транзакция
-	escrowTx := &database.EscrowTransaction{
-		OrderID:  order.ID,
-		Amount:   order.Amount,
-		Type:     "hold",
-		Status:   "completed",
-		FromUser: "client",
-		ToUser:   "escrow",
-	}
-	if err := s.escrowRepo.CreateTransactionInTx(tx, escrowTx); err != nil {
+	// Списываем деньги. Проверка баланса выше не защищает от параллельных списаний,
+	// поэтому нехватка средств проверяется еще раз в самом списании
+	if err := s.balanceRepo.DebitClientInTx(tx, clientID, payment.Amount); err != nil {
 		tx.Rollback()
 		return err
 	}
 
 	// Баланс транзакция
+	description := fmt.Sprintf("Оплата заказа #%d", order.ID)
+	if payment.Currency != order.Currency {
+		description += fmt.Sprintf(" на %s по курсу %s",
+			money.FormatCode(money.New(order.Amount, order.Currency), money.DefaultLocale), money.FormatRate(rate))
+	}
 	balanceTx := &database.BalanceTransaction{
 		UserID:      clientID,
 		UserType:    "client",
-		Amount:      -order.Amount,
+		Amount:      -payment.Amount,
+		Currency:    payment.Currency,
 		Type:        "payment",
 		Status:      "completed",
 		OrderID:     &order.ID,
-		Description: fmt.Sprintf("Оплата заказа #%d", order.ID),
+		Description: description,
 	}
 	if err := s.balanceRepo.CreateTransactionInTx(tx, balanceTx); err != nil {
 		tx.Rollback()
 		return err
 	}
 
-	// Обновляем статус и payment_status в одной операции
-	if err := tx.Model(&database.Order{}).Where("id = ?", orderID).
-		Updates(map[string]interface{}{"status": "paid", "payment_status": "paid"}).Error; err != nil {
+	if err := s.holdPaymentInTx(tx, order, payment, rate, lockedAt); err != nil {
 		tx.Rollback()
 		return err
 	}
 
-	// Генерация workerURL прямо в транзакции
-	workerLink, err := s.workerLinkRepo.GenerateWorkerLink(orderID)
+	// Фиксируем транзакцию
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	// Счет выпускается сразу после оплаты. При ошибке он будет сформирован при первом запросе
+	if _, err := s.documentService.IssueOrderInvoice(orderID); err != nil {
+		log.Printf("failed to issue invoice for order %d: %v", orderID, err)
+	}
+	return nil
+}
+
+func (s *orderService) CheckoutCart(clientID uint, cartOrders []CartOrder, takeCart func(tx *gorm.DB) error) (*database.Checkout, error) {
+	if len(cartOrders) == 0 {
+		return nil, errors.New("cart is empty")
+	}
+
+	balance, err := s.balanceRepo.GetClientBalance(clientID)
 	if err != nil {
+		return nil, err
+	}
+
+	// Слоты компаний блокируются по возрастанию ID, чтобы параллельные оформления
+	// не ждали друг друга по кругу
+	sort.Slice(cartOrders, func(i, j int) bool { return cartOrders[i].CompanyID < cartOrders[j].CompanyID })
+
+	now := time.Now()
+	orders := make([]database.Order, len(cartOrders))
+	items := make([][]database.OrderLineItem, len(cartOrders))
+	capacities := make([]int, len(cartOrders))
+	payments := make([]money.Money, len(cartOrders))
+	rates := make([]float64, len(cartOrders))
+	var total money.Minor
+	for i, cartOrder := range cartOrders {
+		if len(cartOrder.Items) == 0 {
+			return nil, fmt.Errorf("no items for company %d", cartOrder.CompanyID)
+		}
+
+		first := cartOrder.Items[0].Card
+		order := &orders[i]
+		*order = database.Order{
+			ClientID:      clientID,
+			CompanyID:     cartOrder.CompanyID,
+			CardID:        first.ID,
+			Currency:      first.Currency,
+			Status:        "created",
+			PaymentStatus: "pending",
+			Description:   cartOrder.Description,
+		}
+		for _, item := range cartOrder.Items {
+			if item.Card.CompanyID != cartOrder.CompanyID {
+				return nil, errors.New("card does not belong to this company")
+			}
+			if item.Card.Currency != order.Currency {
+				return nil, errors.New("cards of one company must have the same currency")
+			}
+			amount := item.Card.Price * money.Minor(item.Quantity)
+			order.Subtotal += amount
+			items[i] = append(items[i], database.OrderLineItem{
+				Description: item.Card.Title,
+				Amount:      amount,
+				CardID:      &item.Card.ID,
+				Quantity:    item.Quantity,
+			})
+		}
+		order.Amount = order.Subtotal
+
+		// Комиссия считается со всего заказа по категории первой карточки,
+		// фиксированная часть берется один раз на заказ
+		if err := s.applyFeeQuote(order, first.CategoryID, now); err != nil {
+			return nil, err
+		}
+
+		if capacities[i], err = s.resolveOrderSlot(order, cartOrder.ScheduledAt); err != nil {
+			return nil, fmt.Errorf("company %d: %w", cartOrder.CompanyID, err)
+		}
+
+		// Курс фиксируется отдельно для каждого заказа: по нему же будет сделан возврат при отмене
+		rates[i] = 1.0
+		if balance.Currency != order.Currency {
+			if rates[i], err = s.rates.Rate(order.Currency, balance.Currency); err != nil {
+				return nil, err
+			}
+		}
+		payments[i] = money.New(money.Convert(order.Amount, rates[i]), balance.Currency)
+		total += payments[i].Amount
+	}
+
+	if balance.Amount < total {
+		return nil, errors.New("insufficient balance")
+	}
+
+	tx := s.orderRepo.BeginTransaction()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	// Позиции удаляются из корзины первыми: повторное оформление той же корзины ждет
+	// блокировки строк и не находит их
+	if err := takeCart(tx); err != nil {
 		tx.Rollback()
-		return err
+		return nil, err
 	}
 
-	if err := tx.Model(&database.Order{}).Where("id = ?", orderID).
-		Update("worker_complete_url", fmt.Sprintf("https://auth.tomsk-center.ru/worker/complete/%s", workerLink.Token)).Error; err != nil {
+	checkout := &database.Checkout{
+		ClientID:   clientID,
+		Amount:     total,
+		Currency:   balance.Currency,
+		OrderCount: len(orders),
+	}
+	if err := s.orderRepo.CreateCheckoutInTx(tx, checkout); err != nil {
 		tx.Rollback()
-		return err
+		return nil, err
+	}
+
+	// Одно списание на все заказы. Условие на остаток защищает от параллельной оплаты
+	if err := s.balanceRepo.DebitClientInTx(tx, clientID, total); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	numbers := make([]string, 0, len(orders))
+	for i := range orders {
+		order := &orders[i]
+		order.CheckoutID = &checkout.ID
+		if err := s.insertOrderInTx(tx, order, capacities[i], items[i], nil); err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("company %d: %w", order.CompanyID, err)
+		}
+		if err := s.holdPaymentInTx(tx, order, payments[i], rates[i], now); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		numbers = append(numbers, fmt.Sprintf("#%d", order.ID))
+	}
+
+	balanceTx := &database.BalanceTransaction{
+		UserID:      clientID,
+		UserType:    "client",
+		Amount:      -total,
+		Currency:    balance.Currency,
+		Type:        "payment",
+		Status:      "completed",
+		CheckoutID:  &checkout.ID,
+		Description: "Оплата заказов " + strings.Join(numbers, ", "),
+	}
+	if err := s.balanceRepo.CreateTransactionInTx(tx, balanceTx); err != nil {
+		tx.Rollback()
+		return nil, err
 	}
 
-	// Фиксируем транзакцию
 	if err := tx.Commit().Error; err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	for _, order := range orders {
+		if _, err := s.documentService.IssueOrderInvoice(order.ID); err != nil {
+			log.Printf("failed to issue invoice for order %d: %v", order.ID, err)
+		}
+	}
+
+	checkout.Orders = orders
+	return checkout, nil
 }
 
 func (s *orderService) StartOrder(orderID, companyID uint) error {
@@ -205,7 +429,16 @@ func (s *orderService) StartOrder(orderID, companyID uint) error {
 	return s.orderRepo.UpdateStatus(orderID, "in_progress")
 }
 
-func (s *orderService) CompleteOrderByWorker(token string) error {
+// OpenWorkerLink возвращает заказ по ссылке работника и записывает обращение в журнал
+func (s *orderService) OpenWorkerLink(token, action, ip, userAgent string) (*database.Order, error) {
+	link, err := resolveWorkerLink(s.workerLinkRepo, token, action, ip, userAgent)
+	if err != nil {
+		return nil, err
+	}
+	return s.orderRepo.GetByIDWithRelations(link.OrderID)
+}
+
+func (s *orderService) CompleteOrderByWorker(token string, input *CompletionReportInput) error {
 	order, err := s.orderRepo.GetByWorkerToken(token)
 	if err != nil {
 		return err
@@ -215,17 +448,42 @@ func (s *orderService) CompleteOrderByWorker(token string) error {
 		return errors.New("order cannot be completed in current status")
 	}
 
-	// Помечаем ссылку как использованную
-	err = s.workerLinkRepo.MarkAsUsed(token)
+	if err := input.validate(); err != nil {
+		return err
+	}
+
+	// Сначала сохраняем фотографии, чтобы отчет ссылался только на существующие файлы
+	photos, err := saveCompletionPhotos(s.fileStorage, order.ID, input.Photos)
 	if err != nil {
 		return err
 	}
 
-	// Обновляем статус заказа
+	// Если заказ назначен работнику, выполнение по ссылке засчитывается ему
 	now := time.Now()
-	order.Status = "completed"
 	order.CompletedAt = &now
-	return s.orderRepo.Update(order)
+	order.CompletedByID = order.WorkerID
+	if order.CheckOutAt == nil {
+		order.CheckOutAt = &now
+	}
+
+	report := &database.CompletionReport{
+		OrderID:          order.ID,
+		WorkerID:         order.WorkerID,
+		Notes:            input.Notes,
+		TimeSpentMinutes: input.TimeSpentMinutes,
+		Latitude:         input.Latitude,
+		Longitude:        input.Longitude,
+		Photos:           photos,
+		SubmittedIP:      input.IP,
+		UserAgent:        input.UserAgent,
+	}
+
+	// Отчет, статус заказа и ссылка обновляются одной транзакцией
+	if err := s.reportRepo.CompleteOrder(order, report, token); err != nil {
+		deleteFiles(s.fileStorage, photos)
+		return err
+	}
+	return nil
 }
 
 func (s *orderService) FinishOrder(orderID, clientID uint) error {
@@ -256,53 +514,47 @@ func (s *orderService) FinishOrder(orderID, clientID uint) error {
 		}
 	}()
 
-	// Создаем эскроу транзакцию ПЕРЕД переводом денег
-	escrowTx := &database.EscrowTransaction{
-		OrderID:  order.ID,
-		Amount:   order.Amount,
-		Type:     "release",
-		Status:   "completed",
-		FromUser: "escrow",
-		ToUser:   "company",
-	}
-	err = s.escrowRepo.CreateTransactionInTx(tx, escrowTx)
-	if err != nil {
+	// Статус меняется первым и условно, как при оплате: параллельное подтверждение ждет
+	// блокировки строки, не находит выполненный заказ, и эскроу выплачивается один раз
+	result := tx.Model(&database.Order{}).
+		Where("id = ? AND status = ? AND payment_status = ?", order.ID, "completed", "paid").
+		Update("status", "finished")
+	if result.Error != nil {
 		tx.Rollback()
-		return err
+		return result.Error
 	}
-
-	// Переводим деньги из эскроу компании
-	err = s.balanceRepo.UpdateCompanyBalanceInTx(tx, order.CompanyID, order.Amount)
-	if err != nil {
+	if result.RowsAffected != 1 {
 		tx.Rollback()
-		return err
+		return errors.New("order is already finished or cannot be finished in current status")
 	}
 
-	// Создаем транзакцию баланса для компании
-	balanceTx := &database.BalanceTransaction{
-		UserID:      order.CompanyID,
-		UserType:    "company",
-		Amount:      order.Amount,
-		Type:        "payment",
-		Status:      "completed",
-		OrderID:     &order.ID,
-		Description: fmt.Sprintf("Оплата за заказ #%d", order.ID),
+	// Заказы, созданные до появления комиссий, получают комиссию по текущим правилам
+	if order.FeeQuotedAt == nil {
+		if err := s.applyFeeQuote(order, order.Card.CategoryID, time.Now()); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := s.orderRepo.UpdateFeeInTx(tx, order); err != nil {
+			tx.Rollback()
+			return err
+		}
 	}
-	err = s.balanceRepo.CreateTransactionInTx(tx, balanceTx)
-	if err != nil {
+
+	if err := s.releaseEscrowInTx(tx, order); err != nil {
 		tx.Rollback()
 		return err
 	}
 
-	// Обновляем статус заказа
-	err = s.orderRepo.UpdateStatusInTx(tx, orderID, "finished")
-	if err != nil {
-		tx.Rollback()
+	// Фиксируем транзакцию
+	if err := tx.Commit().Error; err != nil {
 		return err
 	}
 
-	// Фиксируем транзакцию
-	return tx.Commit().Error
+	// Бонусы за приглашение начисляются после первого завершенного заказа приглашенного
+	if err := s.promoService.CreditReferral(order.ClientID, orderID); err != nil {
+		log.Printf("failed to credit referral reward for order %d: %v", orderID, err)
+	}
+	return nil
 }
 
 func (s *orderService) CancelOrder(orderID uint, userID uint, userType string) error {
@@ -333,12 +585,29 @@ func (s *orderService) CancelOrder(orderID uint, userID uint, userType string) e
 		}
 	}()
 
-	// Если заказ был оплачен, возвращаем деньги клиенту
-	if order.Status == "paid" && order.PaymentStatus == "paid" {
+	// Статус меняется первым и условно: параллельные отмена и оплата ждут блокировки строки,
+	// поэтому деньги возвращаются один раз и только если заказ был оплачен к моменту отмены
+	result := tx.Model(&database.Order{}).
+		Where("id = ? AND payment_status = ? AND status IN ?", order.ID, order.PaymentStatus, []string{"created", "paid", "in_progress"}).
+		Update("status", "cancelled")
+	if result.Error != nil {
+		tx.Rollback()
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		return errors.New("order cannot be cancelled in current status")
+	}
+
+	// Если заказ был оплачен, возвращаем деньги клиенту, в том числе после начала работ
+	if order.PaymentStatus == "paid" {
+		payment := orderPayment(order)
+
 		// Создаем эскроу транзакцию возврата ПЕРЕД возвратом денег
 		escrowTx := &database.EscrowTransaction{
 			OrderID:  order.ID,
 			Amount:   order.Amount,
+			Currency: order.Currency,
 			Type:     "refund",
 			Status:   "completed",
 			FromUser: "escrow",
@@ -350,8 +619,8 @@ func (s *orderService) CancelOrder(orderID uint, userID uint, userType string) e
 			return err
 		}
 
-		// Возвращаем деньги клиенту
-		err = s.balanceRepo.UpdateClientBalanceInTx(tx, order.ClientID, order.Amount)
+		// Возвращаем клиенту списанную сумму в валюте оплаты, без пересчета по новому курсу
+		err = s.balanceRepo.UpdateClientBalanceInTx(tx, order.ClientID, payment.Amount)
 		if err != nil {
 			tx.Rollback()
 			return err
@@ -361,7 +630,8 @@ func (s *orderService) CancelOrder(orderID uint, userID uint, userType string) e
 		balanceTx := &database.BalanceTransaction{
 			UserID:      order.ClientID,
 			UserType:    "client",
-			Amount:      order.Amount,
+			Amount:      payment.Amount,
+			Currency:    payment.Currency,
 			Type:        "refund",
 			Status:      "completed",
 			OrderID:     &order.ID,
@@ -373,6 +643,14 @@ func (s *orderService) CancelOrder(orderID uint, userID uint, userType string) e
 			return err
 		}
 
+		// Доплату платформы по скидке возвращаем на ее счет
+		if order.PlatformDiscount > 0 {
+			if err := s.transferPlatformDiscountInTx(tx, order, "subsidy_return"); err != nil {
+				tx.Rollback()
+				return err
+			}
+		}
+
 		// Обновляем payment_status на refunded
 		err = s.orderRepo.UpdatePaymentStatusInTx(tx, orderID, "refunded")
 		if err != nil {
@@ -381,9 +659,8 @@ func (s *orderService) CancelOrder(orderID uint, userID uint, userType string) e
 		}
 	}
 
-	// Обновляем статус заказа на cancelled
-	err = s.orderRepo.UpdateStatusInTx(tx, orderID, "cancelled")
-	if err != nil {
+	// Отмененный заказ не расходует лимит промокода
+	if err := s.promoService.ReleaseInTx(tx, orderID); err != nil {
 		tx.Rollback()
 		return err
 	}
@@ -392,35 +669,62 @@ func (s *orderService) CancelOrder(orderID uint, userID uint, userType string) e
 	return tx.Commit().Error
 }
 
-func (s *orderService) GetAllOrders(page, limit int) ([]database.Order, error) {
-	offset := (page - 1) * limit
-	return s.orderRepo.GetAllActive(limit, offset)
+// orderPayment возвращает сумму, списанную с клиента при оплате. У заказов, оплаченных
+// до появления валют, она совпадает с суммой заказа
+func orderPayment(order *database.Order) money.Money {
+	if order.PaymentCurrency == "" {
+		return money.New(order.Amount, order.Currency)
+	}
+	return money.New(order.PaymentAmount, order.PaymentCurrency)
+}
+
+func (s *orderService) GetAllOrders(page pagination.Request) ([]api.PublicOrder, pagination.Page, error) {
+	orders, next, err := s.orderRepo.GetAllActive(page)
+	if err != nil {
+		return nil, pagination.Page{}, err
+	}
+
+	result := []api.PublicOrder{}
+	for _, order := range orders {
+		result = append(result, api.PublicOrder{
+			ID:          order.ID,
+			CompanyID:   order.CompanyID,
+			CompanyName: order.Company.CompanyName,
+			CardID:      order.CardID,
+			ServiceName: order.Card.Title,
+			Status:      order.Status,
+			CreatedAt:   order.CreatedAt.Format(time.RFC3339),
+			ScheduledAt: formatOptionalTime(order.ScheduledAt),
+		})
+	}
+	return result, next, nil
 }
 
-func (s *orderService) GetOrdersWithFilter(userID uint, userType, status string, limit, offset int) ([]api.OrderInfo, int, error) {
+func (s *orderService) GetOrdersWithFilter(userID uint, userType, status string, page pagination.Request) ([]api.OrderInfo, int, pagination.Page, error) {
 	var orders []database.Order
+	var next pagination.Page
 	var total int
 	var err error
 
 	// Получаем заказы в зависимости от типа пользователя и фильтра
 	if userType == "client" {
-		orders, err = s.orderRepo.GetOrdersByClientWithStatus(userID, status, limit, offset)
+		orders, next, err = s.orderRepo.GetOrdersByClientWithStatus(userID, status, page)
 		if err != nil {
-			return nil, 0, err
+			return nil, 0, pagination.Page{}, err
 		}
 		total, err = s.orderRepo.CountOrdersByClientWithStatus(userID, status)
 	} else if userType == "company" {
-		orders, err = s.orderRepo.GetOrdersByCompanyWithStatus(userID, status, limit, offset)
+		orders, next, err = s.orderRepo.GetOrdersByCompanyWithStatus(userID, status, page)
 		if err != nil {
-			return nil, 0, err
+			return nil, 0, pagination.Page{}, err
 		}
 		total, err = s.orderRepo.CountOrdersByCompanyWithStatus(userID, status)
 	} else {
-		return nil, 0, errors.New("invalid user type")
+		return nil, 0, pagination.Page{}, errors.New("invalid user type")
 	}
 
 	if err != nil {
-		return nil, 0, err
+		return nil, 0, pagination.Page{}, err
 	}
 
 	// Преобразуем в API формат
@@ -430,7 +734,7 @@ func (s *orderService) GetOrdersWithFilter(userID uint, userType, status string,
 		orderInfos = append(orderInfos, orderInfo)
 	}
 
-	return orderInfos, total, nil
+	return orderInfos, total, next, nil
 }
 
 func (s *orderService) GetOrderInfo(orderID, userID uint, userType string) (*api.OrderInfo, error) {
@@ -451,6 +755,305 @@ func (s *orderService) GetOrderInfo(orderID, userID uint, userType string) (*api
 	return &orderInfo, nil
 }
 
+func (s *orderService) GetCompletionReport(orderID, userID uint, userType string) (*api.CompletionReportInfo, error) {
+	order, err := s.orderRepo.GetByID(orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Проверяем права доступа
+	if userType == "client" && order.ClientID != userID {
+		return nil, errors.New("access denied")
+	}
+	if userType == "company" && order.CompanyID != userID {
+		return nil, errors.New("access denied")
+	}
+
+	report, err := s.reportRepo.GetByOrderID(orderID)
+	if err != nil {
+		return nil, errors.New("completion report not found")
+	}
+	return convertReportToInfo(report), nil
+}
+
+// applyFeeQuote фиксирует в заказе комиссию платформы на момент at. Комиссия считается
+// с суммы, которую получит компания: скидка за счет платформы ее не уменьшает
+func (s *orderService) applyFeeQuote(order *database.Order, categoryID *uint, at time.Time) error {
+	quote, err := s.feeService.Quote(order.CompanyID, categoryID, money.New(orderGross(order), order.Currency), at)
+	if err != nil {
+		return fmt.Errorf("failed to calculate platform fee: %w", err)
+	}
+	order.FeeRuleID = quote.RuleID
+	order.FeePercent = quote.Percent
+	order.FeeFixed = quote.Fixed
+	order.FeeAmount = quote.Amount
+	order.FeeQuotedAt = &at
+	return nil
+}
+
+// resolveOrderSlot проверяет время заказа и сохраняет в нем границы слота. Возвращает
+// вместимость слота. Компании с настроенным расписанием принимают заказы только на конкретный слот
+func (s *orderService) resolveOrderSlot(order *database.Order, scheduledAt *time.Time) (int, error) {
+	if scheduledAt == nil {
+		hours, err := s.scheduleRepo.GetWorkingHours(order.CompanyID)
+		if err != nil {
+			return 0, err
+		}
+		if len(hours) > 0 {
+			return 0, errors.New("scheduled_at is required for this company")
+		}
+		return 0, nil
+	}
+
+	scheduledEnd, capacity, err := resolveBookingSlot(s.scheduleRepo, order.CompanyID, *scheduledAt)
+	if err != nil {
+		return 0, err
+	}
+	order.ScheduledAt = scheduledAt
+	order.ScheduledEnd = &scheduledEnd
+	return capacity, nil
+}
+
+// insertOrderInTx сохраняет заказ со строками услуг и скидкой. Занятость слота проверяется
+// под блокировкой компании, чтобы два клиента не заняли последнее место одновременно
+func (s *orderService) insertOrderInTx(tx *gorm.DB, order *database.Order, capacity int, items []database.OrderLineItem, discount *PromoDiscount) error {
+	if order.ScheduledAt != nil {
+		if err := s.scheduleRepo.LockCompanyInTx(tx, order.CompanyID); err != nil {
+			return err
+		}
+
+		booked, err := s.scheduleRepo.CountBookedBetweenInTx(tx, order.CompanyID, *order.ScheduledAt, *order.ScheduledEnd)
+		if err != nil {
+			return err
+		}
+		if booked >= capacity {
+			return errors.New("selected time slot is already booked")
+		}
+	}
+
+	if err := s.orderRepo.CreateInTx(tx, order); err != nil {
+		return fmt.Errorf("failed to create order: %w", err)
+	}
+
+	return s.promoService.CreateLineItemsInTx(tx, order, items, discount)
+}
+
+// holdPaymentInTx удерживает сумму заказа в эскроу, фиксирует курс оплаты и выдает ссылку
+// работнику. Списание с баланса клиента проводит вызывающий
+func (s *orderService) holdPaymentInTx(tx *gorm.DB, order *database.Order, payment money.Money, rate float64, lockedAt time.Time) error {
+	// Обновляем статус и payment_status в одной операции вместе с зафиксированным курсом.
+	// Условие не дает оплатить заказ дважды: параллельная оплата ждет блокировки строки
+	// и не находит неоплаченный заказ
+	result := tx.Model(&database.Order{}).
+		Where("id = ? AND payment_status <> ? AND status IN ?", order.ID, "paid", []string{"created", "pending", "accepted"}).
+		Updates(map[string]interface{}{
+			"status":           "paid",
+			"payment_status":   "paid",
+			"payment_currency": payment.Currency,
+			"payment_amount":   payment.Amount,
+			"exchange_rate":    rate,
+			"rate_locked_at":   lockedAt,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("order is already paid or cannot be paid in current status")
+	}
+
+	escrowTx := &database.EscrowTransaction{
+		OrderID:  order.ID,
+		Amount:   order.Amount,
+		Currency: order.Currency,
+		Type:     "hold",
+		Status:   "completed",
+		FromUser: "client",
+		ToUser:   "escrow",
+	}
+	if err := s.escrowRepo.CreateTransactionInTx(tx, escrowTx); err != nil {
+		return err
+	}
+
+	// Скидку за счет платформы платформа доплачивает в эскроу, чтобы компания получила полную сумму
+	if order.PlatformDiscount > 0 {
+		if err := s.transferPlatformDiscountInTx(tx, order, "subsidy"); err != nil {
+			return err
+		}
+	}
+
+	workerLink, err := s.workerLinkRepo.GenerateWorkerLinkInTx(tx, order.ID, internal.WorkerLinkTTL)
+	if err != nil {
+		return err
+	}
+
+	order.WorkerCompleteURL = workerCompleteURL(workerLink.Token)
+	if err := tx.Model(&database.Order{}).Where("id = ?", order.ID).
+		Update("worker_complete_url", order.WorkerCompleteURL).Error; err != nil {
+		return err
+	}
+
+	order.Status = "paid"
+	order.PaymentStatus = "paid"
+	order.PaymentCurrency = payment.Currency
+	order.PaymentAmount = payment.Amount
+	order.ExchangeRate = rate
+	order.RateLockedAt = &lockedAt
+	return nil
+}
+
+// releaseEscrowInTx переводит удержанную сумму: выплату компании и комиссию на счет
+// платформы. Каждая часть проводится отдельными эскроу и балансовыми транзакциями
+func (s *orderService) releaseEscrowInTx(tx *gorm.DB, order *database.Order) error {
+	gross := orderGross(order)
+	payout := gross - order.FeeAmount
+
+	// Создаем эскроу транзакции ПЕРЕД переводом денег
+	escrowTx := &database.EscrowTransaction{
+		OrderID:  order.ID,
+		Amount:   payout,
+		Currency: order.Currency,
+		Type:     "release",
+		Status:   "completed",
+		FromUser: "escrow",
+		ToUser:   "company",
+	}
+	if err := s.escrowRepo.CreateTransactionInTx(tx, escrowTx); err != nil {
+		return err
+	}
+
+	// Переводим выплату из эскроу компании
+	if err := s.balanceRepo.UpdateCompanyBalanceInTx(tx, order.CompanyID, payout); err != nil {
+		return err
+	}
+
+	// В истории компании оплата и комиссия видны отдельными строками
+	balanceTx := &database.BalanceTransaction{
+		UserID:      order.CompanyID,
+		UserType:    "company",
+		Amount:      gross,
+		Currency:    order.Currency,
+		Type:        "payment",
+		Status:      "completed",
+		OrderID:     &order.ID,
+		Description: fmt.Sprintf("Оплата за заказ #%d", order.ID),
+	}
+	if err := s.balanceRepo.CreateTransactionInTx(tx, balanceTx); err != nil {
+		return err
+	}
+
+	if order.FeeAmount <= 0 {
+		return nil
+	}
+
+	feeEscrowTx := &database.EscrowTransaction{
+		OrderID:  order.ID,
+		Amount:   order.FeeAmount,
+		Currency: order.Currency,
+		Type:     "fee",
+		Status:   "completed",
+		FromUser: "escrow",
+		ToUser:   "platform",
+	}
+	if err := s.escrowRepo.CreateTransactionInTx(tx, feeEscrowTx); err != nil {
+		return err
+	}
+
+	feeTx := &database.BalanceTransaction{
+		UserID:      order.CompanyID,
+		UserType:    "company",
+		Amount:      -order.FeeAmount,
+		Currency:    order.Currency,
+		Type:        "fee",
+		Status:      "completed",
+		OrderID:     &order.ID,
+		Description: fmt.Sprintf("Комиссия платформы за заказ #%d (%s)", order.ID, describeFee(order.FeePercent, money.New(order.FeeFixed, order.Currency))),
+	}
+	if err := s.balanceRepo.CreateTransactionInTx(tx, feeTx); err != nil {
+		return err
+	}
+
+	if err := s.platformRepo.CreditInTx(tx, repository.PlatformRevenueAccount, order.Currency, order.FeeAmount); err != nil {
+		return err
+	}
+	platformTx := &database.BalanceTransaction{
+		UserType:    "platform",
+		Amount:      order.FeeAmount,
+		Currency:    order.Currency,
+		Type:        "fee",
+		Status:      "completed",
+		OrderID:     &order.ID,
+		Description: fmt.Sprintf("Комиссия за заказ #%d", order.ID),
+	}
+	return s.balanceRepo.CreateTransactionInTx(tx, platformTx)
+}
+
+// transferPlatformDiscountInTx переводит скидку за счет платформы между счетом промо-расходов
+// платформы и эскроу заказа: subsidy при оплате, subsidy_return при возврате
+func (s *orderService) transferPlatformDiscountInTx(tx *gorm.DB, order *database.Order, txType string) error {
+	amount := order.PlatformDiscount
+	fromUser, toUser := "platform", "escrow"
+	description := fmt.Sprintf("Скидка по промокоду для заказа #%d", order.ID)
+	if txType == "subsidy_return" {
+		amount = -amount
+		fromUser, toUser = "escrow", "platform"
+		description = fmt.Sprintf("Возврат скидки по отмененному заказу #%d", order.ID)
+	}
+
+	escrowTx := &database.EscrowTransaction{
+		OrderID:  order.ID,
+		Amount:   order.PlatformDiscount,
+		Currency: order.Currency,
+		Type:     txType,
+		Status:   "completed",
+		FromUser: fromUser,
+		ToUser:   toUser,
+	}
+	if err := s.escrowRepo.CreateTransactionInTx(tx, escrowTx); err != nil {
+		return err
+	}
+
+	if err := s.platformRepo.CreditInTx(tx, repository.PlatformPromotionsAccount, order.Currency, -amount); err != nil {
+		return err
+	}
+	platformTx := &database.BalanceTransaction{
+		UserType:    "platform",
+		Amount:      -amount,
+		Currency:    order.Currency,
+		Type:        "discount",
+		Status:      "completed",
+		OrderID:     &order.ID,
+		Description: description,
+	}
+	return s.balanceRepo.CreateTransactionInTx(tx, platformTx)
+}
+
+// orderGross возвращает сумму, которую получает эскроу по заказу: оплату клиента
+// и доплату платформы по скидке
+func orderGross(order *database.Order) money.Minor {
+	return order.Amount + order.PlatformDiscount
+}
+
+// orderSubtotal возвращает сумму заказа до скидки. У заказов, созданных до появления
+// скидок, она совпадает с суммой заказа
+func orderSubtotal(order *database.Order) money.Minor {
+	if order.Subtotal == 0 {
+		return order.Amount
+	}
+	return order.Subtotal
+}
+
+func describeFee(percent float64, fixed money.Money) string {
+	if fixed.Amount == 0 {
+		return fmt.Sprintf("%g%%", percent)
+	}
+	return fmt.Sprintf("%g%% + %s", percent, money.FormatCode(fixed, money.DefaultLocale))
+}
+
+// workerCompleteURL собирает ссылку завершения заказа из настроенного базового адреса
+func workerCompleteURL(token string) string {
+	return strings.TrimRight(internal.WorkerLinkBaseURL, "/") + "/" + token
+}
+
 func (s *orderService) convertOrderToOrderInfo(order database.Order, userType string) api.OrderInfo {
 	orderInfo := api.OrderInfo{
 		ID:            order.ID,
@@ -459,10 +1062,40 @@ func (s *orderService) convertOrderToOrderInfo(order database.Order, userType st
 		ServiceName:   order.Card.Title,
 		Description:   order.Description,
 		Amount:        order.Amount,
+		Currency:      order.Currency,
 		Status:        order.Status,
 		PaymentStatus: order.PaymentStatus,
 		CreatedAt:     order.CreatedAt.Format(time.RFC3339),
 		WorkerURL:     order.WorkerCompleteURL,
+		WorkerID:      order.WorkerID,
+		RecurringID:   order.RecurringOrderID,
+		CheckoutID:    order.CheckoutID,
+	}
+
+	orderInfo.Subtotal = orderSubtotal(&order)
+	orderInfo.Discount = order.DiscountAmount
+	for _, item := range order.LineItems {
+		orderInfo.LineItems = append(orderInfo.LineItems, api.OrderLineItem{
+			Type:        item.Type,
+			Description: item.Description,
+			Amount:      item.Amount,
+			FundedBy:    item.FundedBy,
+			CardID:      item.CardID,
+			Quantity:    item.Quantity,
+		})
+	}
+
+	if order.RateLockedAt != nil {
+		orderInfo.Payment = &api.PaymentInfo{
+			Currency:     order.PaymentCurrency,
+			Amount:       order.PaymentAmount,
+			ExchangeRate: order.ExchangeRate,
+			LockedAt:     order.RateLockedAt.Format(time.RFC3339),
+		}
+	}
+
+	if order.Worker != nil {
+		orderInfo.WorkerName = order.Worker.FullName
 	}
 
 	if order.CompletedAt != nil {
@@ -470,6 +1103,27 @@ func (s *orderService) convertOrderToOrderInfo(order database.Order, userType st
 		orderInfo.CompletedAt = &completedAt
 	}
 
+	if order.ScheduledAt != nil && order.ScheduledEnd != nil {
+		scheduledAt := order.ScheduledAt.Format(time.RFC3339)
+		scheduledEnd := order.ScheduledEnd.Format(time.RFC3339)
+		orderInfo.ScheduledAt = &scheduledAt
+		orderInfo.ScheduledEnd = &scheduledEnd
+	}
+
+	if order.CompletionReport != nil {
+		orderInfo.CompletionReport = convertReportToInfo(order.CompletionReport)
+	}
+
+	if userType == "company" && order.FeeQuotedAt != nil {
+		orderInfo.Fee = &api.FeeBreakdown{
+			RuleID:  order.FeeRuleID,
+			Percent: order.FeePercent,
+			Fixed:   order.FeeFixed,
+			Fee:     order.FeeAmount,
+			Payout:  orderGross(&order) - order.FeeAmount,
+		}
+	}
+
 	// Определяем доступные действия
 	orderInfo.CanCancel = order.Status == "created" || order.Status == "paid"
 	orderInfo.CanPay = userType == "client" && order.Status == "created" && order.PaymentStatus == "pending"
@@ -484,12 +1138,28 @@ func NewOrderService(
 	balanceRepo repository.BalanceRepository,
 	escrowRepo repository.EscrowRepository,
 	workerLinkRepo repository.WorkerLinkRepository,
+	scheduleRepo repository.ScheduleRepository,
+	reportRepo repository.CompletionReportRepository,
+	fileStorage storage.Storage,
+	feeService FeeService,
+	platformRepo repository.PlatformAccountRepository,
+	documentService DocumentService,
+	rates money.RateProvider,
+	promoService PromoService,
 ) OrderService {
 	return &orderService{
-		orderRepo:      orderRepo,
-		cardRepo:       cardRepo,
-		balanceRepo:    balanceRepo,
-		escrowRepo:     escrowRepo,
-		workerLinkRepo: workerLinkRepo,
+		orderRepo:       orderRepo,
+		cardRepo:        cardRepo,
+		balanceRepo:     balanceRepo,
+		escrowRepo:      escrowRepo,
+		workerLinkRepo:  workerLinkRepo,
+		scheduleRepo:    scheduleRepo,
+		reportRepo:      reportRepo,
+		fileStorage:     fileStorage,
+		feeService:      feeService,
+		platformRepo:    platformRepo,
+		documentService: documentService,
+		rates:           rates,
+		promoService:    promoService,
 	}
 }
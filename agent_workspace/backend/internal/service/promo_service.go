@@ -0,0 +1,605 @@
+package service
+
+import (
+	"core/internal"
+	"core/internal/api"
+	"core/internal/database"
+	"core/internal/database/repository"
+	"core/internal/money"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var promoCodePattern = regexp.MustCompile(`^[A-Z0-9_-]{3,32}$`)
+
+// referralAlphabet символы реферальных кодов без похожих друг на друга 0/O и 1/I
+const referralAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// PromoDiscount скидка по промокоду, рассчитанная для услуги в валюте карточки
+type PromoDiscount struct {
+	Promo    *database.PromoCode
+	Amount   money.Minor
+	FundedBy string
+}
+
+type PromoService interface {
+	// Quote проверяет, что клиент может применить промокод к карточке card на момент at,
+	// и рассчитывает скидку
+	Quote(clientID uint, code string, card *database.Card, at time.Time) (*PromoDiscount, error)
+	// CreateLineItemsInTx записывает строки услуг созданного заказа, строку скидки
+	// и учитывает применение промокода
+	CreateLineItemsInTx(tx *gorm.DB, order *database.Order, items []database.OrderLineItem, discount *PromoDiscount) error
+	// ReleaseInTx возвращает применение промокода при отмене заказа
+	ReleaseInTx(tx *gorm.DB, orderID uint) error
+	GetLineItems(orderID uint) ([]database.OrderLineItem, error)
+	CheckPromoCode(clientID uint, code string, cardID uint) (*api.PromoQuote, error)
+
+	// Управление промокодами. Для компании companyID промокод всегда привязан к ней
+	// и оплачивается ею, администратор передает nil
+	GetPromoCodes(companyID *uint) ([]api.PromoCodeInfo, error)
+	CreatePromoCode(info api.PromoCodeInfo, companyID *uint) (*api.PromoCodeInfo, error)
+	UpdatePromoCode(promoID uint, info api.PromoCodeInfo, companyID *uint) (*api.PromoCodeInfo, error)
+	DeletePromoCode(promoID uint) error
+
+	GetReferralInfo(clientID uint) (*api.ReferralInfo, error)
+	ApplyReferralCode(clientID uint, code string) error
+	// CreditReferral начисляет бонусы обоим участникам, если клиент clientID пришел по
+	// приглашению и бонус за него еще не начислен
+	CreditReferral(clientID, orderID uint) error
+}
+
+type promoService struct {
+	promoRepo    repository.PromoCodeRepository
+	referralRepo repository.ReferralRepository
+	cardRepo     repository.CardRepository
+	categoryRepo repository.CategoryRepository
+	companyRepo  repository.CompanyRepository
+	orderRepo    repository.OrderRepository
+	balanceRepo  repository.BalanceRepository
+	platformRepo repository.PlatformAccountRepository
+	rates        money.RateProvider
+}
+
+func (s *promoService) Quote(clientID uint, code string, card *database.Card, at time.Time) (*PromoDiscount, error) {
+	promo, err := s.promoRepo.GetByCode(normalizePromoCode(code))
+	if err != nil {
+		return nil, errors.New("promo code not found")
+	}
+
+	if !promo.IsActive {
+		return nil, errors.New("promo code is not active")
+	}
+	if promo.StartsAt != nil && at.Before(*promo.StartsAt) {
+		return nil, errors.New("promo code is not active yet")
+	}
+	if promo.EndsAt != nil && !at.Before(*promo.EndsAt) {
+		return nil, errors.New("promo code has expired")
+	}
+	if promo.UsageLimit > 0 && promo.UsedCount >= promo.UsageLimit {
+		return nil, errors.New("promo code usage limit reached")
+	}
+	if promo.PerUserLimit > 0 {
+		used, err := s.promoRepo.CountRedemptions(promo.ID, clientID)
+		if err != nil {
+			return nil, err
+		}
+		if used >= int64(promo.PerUserLimit) {
+			return nil, errors.New("promo code has already been used")
+		}
+	}
+
+	if promo.CompanyID != nil && *promo.CompanyID != card.CompanyID {
+		return nil, errors.New("promo code is not valid for this company")
+	}
+	if promo.CategoryID != nil {
+		// Промокод категории действует и на ее подкатегории
+		inCategory := false
+		if card.CategoryID != nil {
+			categories, err := s.categoryRepo.GetAll()
+			if err != nil {
+				return nil, err
+			}
+			_, inCategory = categoryAncestorDepths(categories, *card.CategoryID)[*promo.CategoryID]
+		}
+		if !inCategory {
+			return nil, errors.New("promo code is not valid for this category")
+		}
+	}
+
+	minOrder, err := s.convert(money.New(promo.MinOrder, promo.Currency), card.Currency)
+	if err != nil {
+		return nil, err
+	}
+	if card.Price < minOrder {
+		return nil, errors.New("order amount is below the promo code minimum")
+	}
+
+	var amount money.Minor
+	switch promo.Type {
+	case "percent":
+		amount = card.Price.Percent(promo.Percent)
+		if promo.MaxDiscount > 0 {
+			maxDiscount, err := s.convert(money.New(promo.MaxDiscount, promo.Currency), card.Currency)
+			if err != nil {
+				return nil, err
+			}
+			amount = min(amount, maxDiscount)
+		}
+	case "fixed":
+		if amount, err = s.convert(money.New(promo.Amount, promo.Currency), card.Currency); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("invalid promo code type")
+	}
+
+	// Скидка не превышает цену услуги
+	amount = min(amount, card.Price)
+	if amount <= 0 {
+		return nil, errors.New("promo code gives no discount for this service")
+	}
+
+	return &PromoDiscount{Promo: promo, Amount: amount, FundedBy: promo.FundedBy}, nil
+}
+
+func (s *promoService) CreateLineItemsInTx(tx *gorm.DB, order *database.Order, items []database.OrderLineItem, discount *PromoDiscount) error {
+	for i := range items {
+		items[i].OrderID = order.ID
+		items[i].Type = "service"
+		items[i].Currency = order.Currency
+	}
+
+	if discount != nil {
+		redemption := &database.PromoRedemption{
+			PromoCodeID: discount.Promo.ID,
+			ClientID:    order.ClientID,
+			OrderID:     order.ID,
+			Amount:      discount.Amount,
+			Currency:    order.Currency,
+		}
+		if err := s.promoRepo.RedeemInTx(tx, discount.Promo, redemption); err != nil {
+			return err
+		}
+
+		items = append(items, database.OrderLineItem{
+			OrderID:     order.ID,
+			Type:        "discount",
+			Description: fmt.Sprintf("Скидка по промокоду %s", discount.Promo.Code),
+			Amount:      -discount.Amount,
+			Currency:    order.Currency,
+			FundedBy:    discount.FundedBy,
+			PromoCodeID: &discount.Promo.ID,
+		})
+	}
+
+	return s.promoRepo.CreateLineItemsInTx(tx, items)
+}
+
+func (s *promoService) ReleaseInTx(tx *gorm.DB, orderID uint) error {
+	return s.promoRepo.ReleaseInTx(tx, orderID)
+}
+
+func (s *promoService) GetLineItems(orderID uint) ([]database.OrderLineItem, error) {
+	return s.promoRepo.GetLineItems(orderID)
+}
+
+func (s *promoService) CheckPromoCode(clientID uint, code string, cardID uint) (*api.PromoQuote, error) {
+	card, err := s.cardRepo.GetByID(cardID)
+	if err != nil {
+		return nil, errors.New("card not found")
+	}
+
+	discount, err := s.Quote(clientID, code, card, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.PromoQuote{
+		Code:     discount.Promo.Code,
+		Subtotal: card.Price,
+		Discount: discount.Amount,
+		Total:    card.Price - discount.Amount,
+		Currency: card.Currency,
+	}, nil
+}
+
+func (s *promoService) GetPromoCodes(companyID *uint) ([]api.PromoCodeInfo, error) {
+	promos, err := s.promoRepo.GetAll(companyID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []api.PromoCodeInfo{}
+	for _, promo := range promos {
+		result = append(result, convertPromoCodeToInfo(promo))
+	}
+	return result, nil
+}
+
+func (s *promoService) CreatePromoCode(info api.PromoCodeInfo, companyID *uint) (*api.PromoCodeInfo, error) {
+	code := normalizePromoCode(info.Code)
+	if !promoCodePattern.MatchString(code) {
+		return nil, errors.New("code must be 3-32 characters: letters, digits, '-' and '_'")
+	}
+	if _, err := s.promoRepo.GetByCode(code); err == nil {
+		return nil, errors.New("promo code already exists")
+	}
+
+	promo := &database.PromoCode{Code: code, IsActive: true}
+	if err := s.applyPromoCodeInfo(promo, info, companyID); err != nil {
+		return nil, err
+	}
+	if err := s.promoRepo.Create(promo); err != nil {
+		return nil, err
+	}
+
+	result := convertPromoCodeToInfo(*promo)
+	return &result, nil
+}
+
+func (s *promoService) UpdatePromoCode(promoID uint, info api.PromoCodeInfo, companyID *uint) (*api.PromoCodeInfo, error) {
+	promo, err := s.promoRepo.GetByID(promoID)
+	if err != nil {
+		return nil, errors.New("promo code not found")
+	}
+	if companyID != nil && (promo.CompanyID == nil || *promo.CompanyID != *companyID) {
+		return nil, errors.New("promo code not found")
+	}
+
+	// Код не меняется: он уже мог быть разослан клиентам
+	if err := s.applyPromoCodeInfo(promo, info, companyID); err != nil {
+		return nil, err
+	}
+	if err := s.promoRepo.Update(promo); err != nil {
+		return nil, err
+	}
+
+	result := convertPromoCodeToInfo(*promo)
+	return &result, nil
+}
+
+func (s *promoService) DeletePromoCode(promoID uint) error {
+	if _, err := s.promoRepo.GetByID(promoID); err != nil {
+		return errors.New("promo code not found")
+	}
+	return s.promoRepo.Delete(promoID)
+}
+
+func (s *promoService) GetReferralInfo(clientID uint) (*api.ReferralInfo, error) {
+	code, err := s.referralRepo.GetCodeByClient(clientID)
+	if err != nil {
+		if code, err = s.createReferralCode(clientID); err != nil {
+			return nil, err
+		}
+	}
+
+	invited, credited, err := s.referralRepo.CountByReferrer(clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	balance, err := s.balanceRepo.GetClientBalance(clientID)
+	if err != nil {
+		return nil, err
+	}
+	reward, err := s.referralReward(balance.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &api.ReferralInfo{
+		Code:     code.Code,
+		Invited:  int(invited),
+		Credited: int(credited),
+		Reward:   reward,
+		Currency: balance.Currency,
+	}
+	if referral, err := s.referralRepo.GetByReferred(clientID); err == nil {
+		if referrerCode, err := s.referralRepo.GetCodeByClient(referral.ReferrerID); err == nil {
+			info.ReferredBy = &referrerCode.Code
+		}
+	}
+	return info, nil
+}
+
+func (s *promoService) ApplyReferralCode(clientID uint, code string) error {
+	referrerCode, err := s.referralRepo.GetCodeByCode(normalizePromoCode(code))
+	if err != nil {
+		return errors.New("referral code not found")
+	}
+	if referrerCode.ClientID == clientID {
+		return errors.New("you cannot use your own referral code")
+	}
+	if _, err := s.referralRepo.GetByReferred(clientID); err == nil {
+		return errors.New("referral code has already been applied")
+	}
+
+	finished, err := s.orderRepo.CountOrdersByClientWithStatus(clientID, "finished")
+	if err != nil {
+		return err
+	}
+	if finished > 0 {
+		return errors.New("referral code can be applied only before the first finished order")
+	}
+
+	return s.referralRepo.Create(&database.Referral{
+		ReferrerID: referrerCode.ClientID,
+		ReferredID: clientID,
+		Status:     "pending",
+	})
+}
+
+func (s *promoService) CreditReferral(clientID, orderID uint) error {
+	referral, err := s.referralRepo.GetByReferred(clientID)
+	if err != nil || referral.Status != "pending" {
+		return nil
+	}
+
+	tx := s.referralRepo.BeginTransaction()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	if err := s.referralRepo.MarkCreditedInTx(tx, referral.ID, orderID, time.Now()); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	parties := []struct {
+		clientID    uint
+		description string
+	}{
+		{referral.ReferrerID, fmt.Sprintf("Бонус за приглашенного клиента #%d", referral.ReferredID)},
+		{referral.ReferredID, "Бонус за регистрацию по приглашению"},
+	}
+	for _, party := range parties {
+		if err := s.creditReferralRewardInTx(tx, party.clientID, orderID, party.description); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit().Error
+}
+
+// creditReferralRewardInTx зачисляет бонус клиенту в валюте его счета за счет платформы
+func (s *promoService) creditReferralRewardInTx(tx *gorm.DB, clientID, orderID uint, description string) error {
+	balance, err := s.balanceRepo.GetClientBalance(clientID)
+	if err != nil {
+		return err
+	}
+	reward, err := s.referralReward(balance.Currency)
+	if err != nil {
+		return err
+	}
+	if reward <= 0 {
+		return nil
+	}
+
+	if err := s.balanceRepo.UpdateClientBalanceInTx(tx, clientID, reward); err != nil {
+		return err
+	}
+	clientTx := &database.BalanceTransaction{
+		UserID:      clientID,
+		UserType:    "client",
+		Amount:      reward,
+		Currency:    balance.Currency,
+		Type:        "referral",
+		Status:      "completed",
+		OrderID:     &orderID,
+		Description: description,
+	}
+	if err := s.balanceRepo.CreateTransactionInTx(tx, clientTx); err != nil {
+		return err
+	}
+
+	if err := s.platformRepo.CreditInTx(tx, repository.PlatformPromotionsAccount, balance.Currency, -reward); err != nil {
+		return err
+	}
+	platformTx := &database.BalanceTransaction{
+		UserType:    "platform",
+		Amount:      -reward,
+		Currency:    balance.Currency,
+		Type:        "referral",
+		Status:      "completed",
+		OrderID:     &orderID,
+		Description: fmt.Sprintf("Реферальный бонус клиенту #%d", clientID),
+	}
+	return s.balanceRepo.CreateTransactionInTx(tx, platformTx)
+}
+
+// referralReward возвращает реферальный бонус в валюте currency
+func (s *promoService) referralReward(currency string) (money.Minor, error) {
+	return s.convert(money.New(money.FromMajor(internal.ReferralReward), internal.BaseCurrency), currency)
+}
+
+// createReferralCode выдает клиенту случайный код. При совпадении с существующим кодом
+// делается несколько попыток
+func (s *promoService) createReferralCode(clientID uint) (*database.ReferralCode, error) {
+	var err error
+	for range 5 {
+		random := make([]byte, 8)
+		if _, err = rand.Read(random); err != nil {
+			return nil, err
+		}
+		for i, b := range random {
+			random[i] = referralAlphabet[int(b)%len(referralAlphabet)]
+		}
+
+		code := &database.ReferralCode{ClientID: clientID, Code: string(random)}
+		if err = s.referralRepo.CreateCode(code); err == nil {
+			return code, nil
+		}
+		// Код мог быть создан параллельным запросом того же клиента
+		if existing, getErr := s.referralRepo.GetCodeByClient(clientID); getErr == nil {
+			return existing, nil
+		}
+	}
+	return nil, err
+}
+
+// convert пересчитывает сумму промокода или бонуса в валюту заказа или счета
+func (s *promoService) convert(amount money.Money, currency string) (money.Minor, error) {
+	if amount.Amount == 0 || amount.Currency == currency {
+		return amount.Amount, nil
+	}
+	rate, err := s.rates.Rate(amount.Currency, currency)
+	if err != nil {
+		return 0, err
+	}
+	return money.Convert(amount.Amount, rate), nil
+}
+
+// applyPromoCodeInfo проверяет параметры промокода и переносит их в модель
+func (s *promoService) applyPromoCodeInfo(promo *database.PromoCode, info api.PromoCodeInfo, companyID *uint) error {
+	if companyID != nil {
+		info.CompanyID = companyID
+		info.FundedBy = "company"
+	}
+	if info.FundedBy == "" {
+		info.FundedBy = "platform"
+	}
+	if info.FundedBy != "platform" && info.FundedBy != "company" {
+		return errors.New("funded_by must be 'platform' or 'company'")
+	}
+	if info.FundedBy == "company" && info.CompanyID == nil {
+		return errors.New("company_id is required for company-funded promo codes")
+	}
+	if info.CompanyID != nil && companyID == nil {
+		if _, err := s.companyRepo.GetByID(*info.CompanyID); err != nil {
+			return errors.New("company not found")
+		}
+	}
+	if info.CategoryID != nil {
+		if _, err := s.categoryRepo.GetByID(*info.CategoryID); err != nil {
+			return errors.New("category not found")
+		}
+	}
+
+	switch info.Type {
+	case "percent":
+		if info.Percent <= 0 || info.Percent > 100 {
+			return errors.New("percent must be between 0 and 100")
+		}
+	case "fixed":
+		if info.Amount <= 0 {
+			return errors.New("amount must be positive")
+		}
+	default:
+		return errors.New("type must be 'percent' or 'fixed'")
+	}
+	if info.MaxDiscount < 0 || info.MinOrder < 0 {
+		return errors.New("max_discount and min_order must not be negative")
+	}
+	if info.UsageLimit < 0 || info.PerUserLimit < 0 {
+		return errors.New("usage limits must not be negative")
+	}
+
+	// Промокод компании задается в валюте ее счета
+	if companyID != nil {
+		balance, err := s.balanceRepo.GetCompanyBalance(*companyID)
+		if err != nil {
+			return err
+		}
+		info.Currency = balance.Currency
+	}
+	if info.Currency == "" {
+		info.Currency = internal.BaseCurrency
+	}
+	currency, err := money.NormalizeCurrency(info.Currency)
+	if err != nil {
+		return err
+	}
+
+	startsAt, err := parseOptionalRFC3339(info.StartsAt)
+	if err != nil {
+		return errors.New("invalid starts_at, expected RFC3339")
+	}
+	endsAt, err := parseOptionalRFC3339(info.EndsAt)
+	if err != nil {
+		return errors.New("invalid ends_at, expected RFC3339")
+	}
+	if startsAt != nil && endsAt != nil && !endsAt.After(*startsAt) {
+		return errors.New("ends_at must be after starts_at")
+	}
+
+	promo.Description = strings.TrimSpace(info.Description)
+	promo.Type = info.Type
+	promo.Percent = info.Percent
+	promo.Amount = info.Amount
+	promo.MaxDiscount = info.MaxDiscount
+	promo.Currency = currency
+	promo.MinOrder = info.MinOrder
+	promo.UsageLimit = info.UsageLimit
+	promo.PerUserLimit = info.PerUserLimit
+	promo.CategoryID = info.CategoryID
+	promo.CompanyID = info.CompanyID
+	promo.FundedBy = info.FundedBy
+	promo.StartsAt = startsAt
+	promo.EndsAt = endsAt
+	if info.IsActive != nil {
+		promo.IsActive = *info.IsActive
+	}
+	return nil
+}
+
+// normalizePromoCode приводит код к виду, в котором он хранится
+func normalizePromoCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func convertPromoCodeToInfo(promo database.PromoCode) api.PromoCodeInfo {
+	isActive := promo.IsActive
+	return api.PromoCodeInfo{
+		ID:           promo.ID,
+		Code:         promo.Code,
+		Description:  promo.Description,
+		Type:         promo.Type,
+		Percent:      promo.Percent,
+		Amount:       promo.Amount,
+		MaxDiscount:  promo.MaxDiscount,
+		Currency:     promo.Currency,
+		MinOrder:     promo.MinOrder,
+		UsageLimit:   promo.UsageLimit,
+		PerUserLimit: promo.PerUserLimit,
+		UsedCount:    promo.UsedCount,
+		CategoryID:   promo.CategoryID,
+		CompanyID:    promo.CompanyID,
+		FundedBy:     promo.FundedBy,
+		StartsAt:     formatOptionalTime(promo.StartsAt),
+		EndsAt:       formatOptionalTime(promo.EndsAt),
+		IsActive:     &isActive,
+	}
+}
+
+func NewPromoService(
+	promoRepo repository.PromoCodeRepository,
+	referralRepo repository.ReferralRepository,
+	cardRepo repository.CardRepository,
+	categoryRepo repository.CategoryRepository,
+	companyRepo repository.CompanyRepository,
+	orderRepo repository.OrderRepository,
+	balanceRepo repository.BalanceRepository,
+	platformRepo repository.PlatformAccountRepository,
+	rates money.RateProvider,
+) PromoService {
+	return &promoService{
+		promoRepo:    promoRepo,
+		referralRepo: referralRepo,
+		cardRepo:     cardRepo,
+		categoryRepo: categoryRepo,
+		companyRepo:  companyRepo,
+		orderRepo:    orderRepo,
+		balanceRepo:  balanceRepo,
+		platformRepo: platformRepo,
+		rates:        rates,
+	}
+}
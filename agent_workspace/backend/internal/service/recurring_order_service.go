@@ -0,0 +1,572 @@
+package service
+
+import (
+	"core/internal"
+	"core/internal/api"
+	"core/internal/database"
+	"core/internal/database/repository"
+	"core/internal/recurrence"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	recurringBatchSize       = 100
+	recurringUpcomingDays    = 14
+	recurringMaxUpcomingDays = 90
+	recurringListOccurrences = 5
+	// paymentRetryClaimTTL через сколько занятие, забранное для повторной оплаты, снова
+	// доступно другим обработчикам, если забравший сервер остановился
+	paymentRetryClaimTTL = 10 * time.Minute
+)
+
+type RecurringOrderService interface {
+	CreateRecurringOrder(clientID uint, request api.RecurringOrderRequest) (*api.RecurringOrderInfo, error)
+	// GetRecurringOrders возвращает расписания клиента или активные и приостановленные
+	// расписания компании с ближайшими занятиями
+	GetRecurringOrders(userID uint, userType string) ([]api.RecurringOrderInfo, error)
+	// GetUpcoming возвращает занятия всех расписаний пользователя на days дней вперед
+	GetUpcoming(userID uint, userType string, days int) ([]api.RecurringOccurrenceInfo, error)
+	Pause(recurringID, clientID uint) error
+	Resume(recurringID, clientID uint) error
+	Cancel(recurringID, clientID uint) error
+	SetAutoPay(recurringID, clientID uint, autoPay bool) error
+	// Skip пропускает занятие. Если заказ на него уже создан, заказ отменяется с возвратом оплаты
+	Skip(recurringID, clientID uint, scheduledAt string) error
+	// ProcessDue создает заказы для занятий, до которых осталось меньше RECURRING_LEAD_HOURS,
+	// и повторяет неудавшиеся автоплатежи
+	ProcessDue(now time.Time) error
+	// Start запускает периодическую обработку расписаний в фоне
+	Start(interval time.Duration)
+}
+
+type recurringOrderService struct {
+	recurringRepo       repository.RecurringOrderRepository
+	cardRepo            repository.CardRepository
+	scheduleRepo        repository.ScheduleRepository
+	orderRepo           repository.OrderRepository
+	orderService        OrderService
+	notificationService NotificationService
+}
+
+func (s *recurringOrderService) CreateRecurringOrder(clientID uint, request api.RecurringOrderRequest) (*api.RecurringOrderInfo, error) {
+	card, err := s.cardRepo.GetByID(request.CardID)
+	if err != nil {
+		return nil, errors.New("card not found")
+	}
+	if card.CompanyID != request.CompanyID {
+		return nil, errors.New("card does not belong to this company")
+	}
+
+	startsAt, err := time.Parse(time.RFC3339, request.StartsAt)
+	if err != nil {
+		return nil, errors.New("invalid starts_at, expected RFC3339")
+	}
+	if !startsAt.After(time.Now()) {
+		return nil, errors.New("starts_at must be in the future")
+	}
+
+	frequency := strings.ToLower(strings.TrimSpace(request.Frequency))
+	value := request.Rule
+	if frequency != "custom" {
+		preset, ok := recurrence.Presets[frequency]
+		if !ok {
+			return nil, errors.New("frequency must be weekly, biweekly, monthly or custom")
+		}
+		value = preset
+	}
+	rule, err := recurrence.Parse(value)
+	if err != nil {
+		return nil, err
+	}
+
+	recurring := &database.RecurringOrder{
+		ClientID:    clientID,
+		CompanyID:   request.CompanyID,
+		CardID:      request.CardID,
+		Description: strings.TrimSpace(request.Description),
+		Frequency:   frequency,
+		Rule:        rule.String(),
+		StartsAt:    startsAt,
+		AutoPay:     request.AutoPay,
+		Status:      "active",
+	}
+	first := s.occurrences(recurring, rule, startsAt, 1)
+	if len(first) == 0 {
+		return nil, errors.New("rule has no occurrences")
+	}
+	recurring.NextAt = &first[0]
+
+	// Компании с расписанием принимают заказы только на свободные слоты: проверяем первое занятие
+	hours, err := s.scheduleRepo.GetWorkingHours(request.CompanyID)
+	if err != nil {
+		return nil, err
+	}
+	if len(hours) > 0 {
+		if _, _, err := resolveBookingSlot(s.scheduleRepo, request.CompanyID, first[0]); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.recurringRepo.Create(recurring); err != nil {
+		return nil, err
+	}
+	recurring.Card = *card
+
+	info := convertRecurringToInfo(recurring, s.upcoming([]database.RecurringOrder{*recurring}, time.Now(), recurringUpcomingDays)[recurring.ID])
+	return &info, nil
+}
+
+func (s *recurringOrderService) GetRecurringOrders(userID uint, userType string) ([]api.RecurringOrderInfo, error) {
+	recurring, err := s.listByUser(userID, userType)
+	if err != nil {
+		return nil, err
+	}
+
+	upcoming := s.upcoming(recurring, time.Now(), recurringUpcomingDays)
+	result := []api.RecurringOrderInfo{}
+	for i := range recurring {
+		occurrences := upcoming[recurring[i].ID]
+		if len(occurrences) > recurringListOccurrences {
+			occurrences = occurrences[:recurringListOccurrences]
+		}
+		result = append(result, convertRecurringToInfo(&recurring[i], occurrences))
+	}
+	return result, nil
+}
+
+func (s *recurringOrderService) GetUpcoming(userID uint, userType string, days int) ([]api.RecurringOccurrenceInfo, error) {
+	if days <= 0 {
+		days = recurringUpcomingDays
+	}
+	if days > recurringMaxUpcomingDays {
+		return nil, fmt.Errorf("days must not exceed %d", recurringMaxUpcomingDays)
+	}
+
+	recurring, err := s.listByUser(userID, userType)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []api.RecurringOccurrenceInfo{}
+	for _, occurrences := range s.upcoming(recurring, time.Now(), days) {
+		result = append(result, occurrences...)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, _ := time.Parse(time.RFC3339, result[i].ScheduledAt)
+		b, _ := time.Parse(time.RFC3339, result[j].ScheduledAt)
+		return a.Before(b)
+	})
+	return result, nil
+}
+
+func (s *recurringOrderService) Pause(recurringID, clientID uint) error {
+	recurring, err := s.getOwned(recurringID, clientID)
+	if err != nil {
+		return err
+	}
+	if recurring.Status != "active" {
+		return errors.New("only active recurring orders can be paused")
+	}
+
+	recurring.Status = "paused"
+	return s.recurringRepo.Update(recurring)
+}
+
+func (s *recurringOrderService) Resume(recurringID, clientID uint) error {
+	recurring, err := s.getOwned(recurringID, clientID)
+	if err != nil {
+		return err
+	}
+	if recurring.Status != "paused" {
+		return errors.New("only paused recurring orders can be resumed")
+	}
+	rule, err := recurrence.Parse(recurring.Rule)
+	if err != nil {
+		return err
+	}
+
+	// Занятия, выпавшие на паузу, не создаются задним числом
+	recurring.Status = "active"
+	recurring.NextAt = nil
+	if next := s.occurrences(recurring, rule, time.Now(), 1); len(next) > 0 {
+		recurring.NextAt = &next[0]
+	} else {
+		recurring.Status = "finished"
+	}
+	return s.recurringRepo.Update(recurring)
+}
+
+func (s *recurringOrderService) Cancel(recurringID, clientID uint) error {
+	recurring, err := s.getOwned(recurringID, clientID)
+	if err != nil {
+		return err
+	}
+	if recurring.Status != "active" && recurring.Status != "paused" {
+		return errors.New("recurring order is already finished")
+	}
+
+	recurring.Status = "cancelled"
+	recurring.NextAt = nil
+	return s.recurringRepo.Update(recurring)
+}
+
+func (s *recurringOrderService) SetAutoPay(recurringID, clientID uint, autoPay bool) error {
+	recurring, err := s.getOwned(recurringID, clientID)
+	if err != nil {
+		return err
+	}
+
+	recurring.AutoPay = autoPay
+	return s.recurringRepo.Update(recurring)
+}
+
+func (s *recurringOrderService) Skip(recurringID, clientID uint, scheduledAt string) error {
+	recurring, err := s.getOwned(recurringID, clientID)
+	if err != nil {
+		return err
+	}
+	if recurring.Status != "active" && recurring.Status != "paused" {
+		return errors.New("recurring order is already finished")
+	}
+
+	at, err := time.Parse(time.RFC3339, scheduledAt)
+	if err != nil {
+		return errors.New("invalid scheduled_at, expected RFC3339")
+	}
+	if !at.After(time.Now()) {
+		return errors.New("only future occurrences can be skipped")
+	}
+	rule, err := recurrence.Parse(recurring.Rule)
+	if err != nil {
+		return err
+	}
+	if next := s.occurrences(recurring, rule, at, 1); len(next) == 0 || !next[0].Equal(at) {
+		return errors.New("scheduled_at does not match any occurrence")
+	}
+
+	occurrence, err := s.recurringRepo.GetOccurrence(recurring.ID, at)
+	if err != nil {
+		// Заказ еще не создан: обработчик увидит пропуск и перейдет к следующему занятию
+		return s.recurringRepo.CreateOccurrence(&database.RecurringOccurrence{
+			RecurringOrderID: recurring.ID,
+			ScheduledAt:      at,
+			Status:           "skipped",
+		})
+	}
+
+	switch {
+	case occurrence.Status == "skipped":
+		return errors.New("occurrence is already skipped")
+	case occurrence.OrderID == nil && occurrence.Status == "created":
+		return errors.New("order for this occurrence is being created, try again later")
+	case occurrence.OrderID != nil:
+		if err := s.orderService.CancelOrder(*occurrence.OrderID, clientID, "client"); err != nil {
+			return err
+		}
+	}
+
+	occurrence.Status = "skipped"
+	return s.recurringRepo.UpdateOccurrence(occurrence)
+}
+
+func (s *recurringOrderService) ProcessDue(now time.Time) error {
+	due, err := s.recurringRepo.GetDue(now.Add(internal.RecurringLeadTime), recurringBatchSize)
+	if err != nil {
+		return err
+	}
+	for i := range due {
+		s.processNext(&due[i], now)
+	}
+
+	return s.retryPayments(now)
+}
+
+func (s *recurringOrderService) Start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if err := s.ProcessDue(time.Now()); err != nil {
+				log.Printf("failed to process recurring orders: %v", err)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// processNext создает заказ на ближайшее занятие расписания и переносит расписание на следующее
+func (s *recurringOrderService) processNext(recurring *database.RecurringOrder, now time.Time) {
+	at := *recurring.NextAt
+	rule, err := recurrence.Parse(recurring.Rule)
+	if err != nil {
+		log.Printf("failed to parse rule of recurring order %d: %v", recurring.ID, err)
+		return
+	}
+
+	var next *time.Time
+	if upcoming := s.occurrences(recurring, rule, at.Add(time.Second), 1); len(upcoming) > 0 {
+		next = &upcoming[0]
+	}
+	// Занятие забирает только один обработчик, даже если запущено несколько серверов
+	claimed, err := s.recurringRepo.ClaimNext(recurring.ID, at, next)
+	if err != nil || !claimed {
+		return
+	}
+
+	occurrence := &database.RecurringOccurrence{RecurringOrderID: recurring.ID, ScheduledAt: at, Status: "created"}
+	// Занятия, пропущенные из-за остановки сервера, не создаются задним числом
+	if !at.After(now) {
+		occurrence.Status = "missed"
+	}
+	if err := s.recurringRepo.CreateOccurrence(occurrence); err != nil {
+		// Клиент пропустил это занятие
+		return
+	}
+	if occurrence.Status == "missed" {
+		return
+	}
+
+	order, err := s.createOrder(recurring, at)
+	if err != nil {
+		occurrence.Status = "failed"
+		occurrence.Error = err.Error()
+		s.saveOccurrence(recurring, occurrence)
+		s.notifyClient(recurring, "Заказ не создан",
+			fmt.Sprintf("Не удалось создать повторяющийся заказ на %s: %v", formatOccurrence(at), err), nil)
+		return
+	}
+	occurrence.OrderID = &order.ID
+	if err := s.recurringRepo.LinkOrder(order.ID, recurring.ID); err != nil {
+		log.Printf("failed to link order %d to recurring order %d: %v", order.ID, recurring.ID, err)
+	}
+	if err := s.notificationService.NotifyNewOrder(recurring.CompanyID, order.ID); err != nil {
+		log.Printf("failed to notify company about order %d: %v", order.ID, err)
+	}
+
+	if recurring.AutoPay {
+		if err := s.orderService.PayForOrder(order.ID, recurring.ClientID); err != nil {
+			occurrence.Status = "payment_failed"
+			occurrence.Error = err.Error()
+			s.notifyClient(recurring, "Автоплатеж не прошел",
+				fmt.Sprintf("Не удалось оплатить заказ #%d на %s: %v. Пополните баланс, оплата будет повторена автоматически, или оплатите заказ вручную",
+					order.ID, formatOccurrence(at), err), &order.ID)
+		} else {
+			occurrence.Status = "paid"
+		}
+	}
+	s.saveOccurrence(recurring, occurrence)
+}
+
+// retryPayments повторяет автоплатежи, не прошедшие из-за нехватки средств, пока занятие не наступило
+func (s *recurringOrderService) retryPayments(now time.Time) error {
+	failed, err := s.recurringRepo.GetFailedPayments(now)
+	if err != nil {
+		return err
+	}
+
+	for i := range failed {
+		occurrence := &failed[i]
+		// Занятие оплачивает только один обработчик, даже если запущено несколько серверов
+		claimed, err := s.recurringRepo.ClaimFailedPayment(occurrence.ID, now.Add(-paymentRetryClaimTTL))
+		if err != nil || !claimed {
+			continue
+		}
+		order, err := s.orderRepo.GetByID(*occurrence.OrderID)
+		if err != nil {
+			s.releasePayment(occurrence)
+			continue
+		}
+
+		switch {
+		case order.PaymentStatus == "paid":
+			// Клиент оплатил заказ вручную
+			occurrence.Status = "paid"
+		case order.Status == "cancelled":
+			occurrence.Status = "cancelled"
+		default:
+			if err := s.orderService.PayForOrder(order.ID, order.ClientID); err != nil {
+				s.releasePayment(occurrence)
+				continue
+			}
+			occurrence.Status = "paid"
+		}
+		occurrence.Error = ""
+		if err := s.recurringRepo.UpdateOccurrence(occurrence); err != nil {
+			log.Printf("failed to update recurring occurrence %d: %v", occurrence.ID, err)
+		}
+	}
+	return nil
+}
+
+// releasePayment возвращает занятие в очередь повторной оплаты
+func (s *recurringOrderService) releasePayment(occurrence *database.RecurringOccurrence) {
+	occurrence.Status = "payment_failed"
+	if err := s.recurringRepo.UpdateOccurrence(occurrence); err != nil {
+		log.Printf("failed to update recurring occurrence %d: %v", occurrence.ID, err)
+	}
+}
+
+// createOrder создает заказ на занятие. Компаниям с расписанием заказ передается на слот занятия
+func (s *recurringOrderService) createOrder(recurring *database.RecurringOrder, at time.Time) (*database.Order, error) {
+	hours, err := s.scheduleRepo.GetWorkingHours(recurring.CompanyID)
+	if err != nil {
+		return nil, err
+	}
+	var scheduledAt *time.Time
+	if len(hours) > 0 {
+		scheduledAt = &at
+	}
+
+	description := "Повторяющийся заказ на " + formatOccurrence(at)
+	if recurring.Description != "" {
+		description = recurring.Description + ". " + description
+	}
+	return s.orderService.CreateOrder(recurring.ClientID, recurring.CompanyID, recurring.CardID, description, scheduledAt, "")
+}
+
+func (s *recurringOrderService) saveOccurrence(recurring *database.RecurringOrder, occurrence *database.RecurringOccurrence) {
+	if err := s.recurringRepo.UpdateOccurrence(occurrence); err != nil {
+		log.Printf("failed to update recurring occurrence %d: %v", occurrence.ID, err)
+	}
+	if err := s.recurringRepo.SetLastError(recurring.ID, occurrence.Error); err != nil {
+		log.Printf("failed to update recurring order %d: %v", recurring.ID, err)
+	}
+}
+
+func (s *recurringOrderService) notifyClient(recurring *database.RecurringOrder, title, message string, orderID *uint) {
+	relatedID := orderID
+	if relatedID == nil {
+		relatedID = &recurring.ID
+	}
+	if err := s.notificationService.CreateNotification(recurring.ClientID, "client", title, message, "recurring_order", relatedID); err != nil {
+		log.Printf("failed to notify client about recurring order %d: %v", recurring.ID, err)
+	}
+}
+
+// occurrences возвращает до limit занятий расписания начиная с from. Дни недели
+// и месяца считаются в часовом поясе платформы
+func (s *recurringOrderService) occurrences(recurring *database.RecurringOrder, rule *recurrence.Rule, from time.Time, limit int) []time.Time {
+	return rule.Occurrences(recurring.StartsAt.In(internal.TimeZone), from, limit)
+}
+
+// upcoming возвращает занятия расписаний на days дней вперед: созданные и пропущенные
+// занятия из базы и будущие занятия по правилу
+func (s *recurringOrderService) upcoming(recurring []database.RecurringOrder, from time.Time, days int) map[uint][]api.RecurringOccurrenceInfo {
+	to := from.AddDate(0, 0, days)
+	ids := make([]uint, 0, len(recurring))
+	for _, item := range recurring {
+		ids = append(ids, item.ID)
+	}
+
+	stored := map[uint]map[int64]database.RecurringOccurrence{}
+	occurrences, err := s.recurringRepo.GetOccurrences(ids, from, to)
+	if err != nil {
+		log.Printf("failed to load recurring occurrences: %v", err)
+	}
+	for _, occurrence := range occurrences {
+		if stored[occurrence.RecurringOrderID] == nil {
+			stored[occurrence.RecurringOrderID] = map[int64]database.RecurringOccurrence{}
+		}
+		stored[occurrence.RecurringOrderID][occurrence.ScheduledAt.Unix()] = occurrence
+	}
+
+	result := map[uint][]api.RecurringOccurrenceInfo{}
+	for i := range recurring {
+		item := &recurring[i]
+		rule, err := recurrence.Parse(item.Rule)
+		if err != nil {
+			continue
+		}
+		for _, at := range s.occurrences(item, rule, from, days*24) {
+			if !at.Before(to) {
+				break
+			}
+			info := api.RecurringOccurrenceInfo{
+				RecurringID: item.ID,
+				ScheduledAt: at.Format(time.RFC3339),
+				ClientName:  item.Client.FullName,
+				ServiceName: item.Card.Title,
+			}
+			if occurrence, ok := stored[item.ID][at.Unix()]; ok {
+				info.Status = occurrence.Status
+				info.OrderID = occurrence.OrderID
+			} else if item.Status == "active" && item.NextAt != nil && !at.Before(*item.NextAt) {
+				info.Status = "scheduled"
+			} else {
+				continue
+			}
+			result[item.ID] = append(result[item.ID], info)
+		}
+	}
+	return result
+}
+
+func (s *recurringOrderService) listByUser(userID uint, userType string) ([]database.RecurringOrder, error) {
+	switch userType {
+	case "client":
+		return s.recurringRepo.GetByClient(userID)
+	case "company":
+		return s.recurringRepo.GetByCompany(userID, []string{"active", "paused"})
+	}
+	return nil, errors.New("invalid user type")
+}
+
+func (s *recurringOrderService) getOwned(recurringID, clientID uint) (*database.RecurringOrder, error) {
+	recurring, err := s.recurringRepo.GetByID(recurringID)
+	if err != nil || recurring.ClientID != clientID {
+		return nil, errors.New("recurring order not found")
+	}
+	return recurring, nil
+}
+
+func formatOccurrence(at time.Time) string {
+	return at.In(internal.TimeZone).Format("02.01.2006 15:04")
+}
+
+func convertRecurringToInfo(recurring *database.RecurringOrder, upcoming []api.RecurringOccurrenceInfo) api.RecurringOrderInfo {
+	if upcoming == nil {
+		upcoming = []api.RecurringOccurrenceInfo{}
+	}
+	return api.RecurringOrderInfo{
+		ID:          recurring.ID,
+		CompanyID:   recurring.CompanyID,
+		CompanyName: recurring.Company.CompanyName,
+		ClientName:  recurring.Client.FullName,
+		CardID:      recurring.CardID,
+		ServiceName: recurring.Card.Title,
+		Description: recurring.Description,
+		Amount:      recurring.Card.Price,
+		Currency:    recurring.Card.Currency,
+		Frequency:   recurring.Frequency,
+		Rule:        recurring.Rule,
+		StartsAt:    recurring.StartsAt.Format(time.RFC3339),
+		NextAt:      formatOptionalTime(recurring.NextAt),
+		AutoPay:     recurring.AutoPay,
+		Status:      recurring.Status,
+		LastError:   recurring.LastError,
+		Upcoming:    upcoming,
+	}
+}
+
+func NewRecurringOrderService(
+	recurringRepo repository.RecurringOrderRepository,
+	cardRepo repository.CardRepository,
+	scheduleRepo repository.ScheduleRepository,
+	orderRepo repository.OrderRepository,
+	orderService OrderService,
+	notificationService NotificationService,
+) RecurringOrderService {
+	return &recurringOrderService{
+		recurringRepo:       recurringRepo,
+		cardRepo:            cardRepo,
+		scheduleRepo:        scheduleRepo,
+		orderRepo:           orderRepo,
+		orderService:        orderService,
+		notificationService: notificationService,
+	}
+}
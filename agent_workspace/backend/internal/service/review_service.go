@@ -1,27 +1,57 @@
 package service
 
 import (
+	"core/internal"
+	"core/internal/api"
 	"core/internal/database"
 	"core/internal/database/repository"
+	"core/internal/pagination"
 	"errors"
+	"log"
+	"strings"
+	"time"
+)
+
+// Ограничения на длину текстов в отзывах
+const (
+	maxReviewReplyLength  = 2000
+	maxReviewReasonLength = 1000
 )
 
 type ReviewService interface {
-	CreateReview(clientID, companyID, orderID uint, rating int, comment string) (*database.Review, error)
-	GetReviewsByCompany(companyID uint, page, limit int) ([]database.Review, error)
-	GetReviewByOrder(orderID uint) (*database.Review, error)
-	GetCompanyRating(companyID uint) (float64, int, error)
+	CreateReview(clientID, companyID, orderID uint, rating int, comment string, subRatings api.ReviewSubRatings) (*database.Review, error)
+	GetReviewsByCompany(companyID uint, page pagination.Request) ([]api.PublicReview, pagination.Page, error)
+	GetReviewByOrder(orderID uint) (*api.PublicReview, error)
+	// GetCompanyRating возвращает рейтинг компании с распределением оценок и оценками по критериям
+	GetCompanyRating(companyID uint) (*api.CompanyRatingSummary, error)
+	// UpdateReview изменяет отзыв клиента в течение internal.ReviewEditWindow после создания
+	UpdateReview(clientID, reviewID uint, rating int, comment string, subRatings api.ReviewSubRatings) (*api.PublicReview, error)
+	// ReplyToReview сохраняет публичный ответ компании. На отзыв можно ответить один раз
+	ReplyToReview(companyID, reviewID uint, reply string) (*api.PublicReview, error)
+	ReportReview(reporterID uint, reporterType string, reviewID uint, reason string) error
+
+	// Модерация отзывов администраторами
+	GetModerationQueue(status string, page pagination.Request) ([]api.ReviewReportInfo, pagination.Page, error)
+	HideReview(adminID, reviewID uint, reason string) error
+	RestoreReview(adminID, reviewID uint) error
+	DismissReport(adminID, reportID uint) error
 }
 
 type reviewService struct {
-	reviewRepo repository.ReviewRepository
-	orderRepo  repository.OrderRepository
+	reviewRepo          repository.ReviewRepository
+	reviewReportRepo    repository.ReviewReportRepository
+	orderRepo           repository.OrderRepository
+	companyRepo         repository.CompanyRepository
+	notificationService NotificationService
 }
 
-func (s *reviewService) CreateReview(clientID, companyID, orderID uint, rating int, comment string) (*database.Review, error) {
+func (s *reviewService) CreateReview(clientID, companyID, orderID uint, rating int, comment string, subRatings api.ReviewSubRatings) (*database.Review, error) {
 	if rating < 1 || rating > 5 {
 		return nil, errors.New("rating must be between 1 and 5")
 	}
+	if err := validateSubRatings(subRatings); err != nil {
+		return nil, err
+	}
 
 	// Проверяем, что заказ существует и принадлежит клиенту
 	order, err := s.orderRepo.GetByID(orderID)
@@ -54,6 +84,7 @@ func (s *reviewService) CreateReview(clientID, companyID, orderID uint, rating i
 		Rating:    rating,
 		Comment:   comment,
 	}
+	applySubRatings(review, subRatings)
 
 	err = s.reviewRepo.Create(review)
 	if err != nil {
@@ -63,22 +94,308 @@ func (s *reviewService) CreateReview(clientID, companyID, orderID uint, rating i
 	return review, nil
 }
 
-func (s *reviewService) GetReviewsByCompany(companyID uint, page, limit int) ([]database.Review, error) {
-	offset := (page - 1) * limit
-	return s.reviewRepo.GetByCompanyID(companyID, limit, offset)
+func (s *reviewService) GetReviewsByCompany(companyID uint, page pagination.Request) ([]api.PublicReview, pagination.Page, error) {
+	reviews, next, err := s.reviewRepo.GetByCompanyID(companyID, page)
+	if err != nil {
+		return nil, pagination.Page{}, err
+	}
+	return convertReviewsToPublic(reviews), next, nil
+}
+
+func (s *reviewService) GetReviewByOrder(orderID uint) (*api.PublicReview, error) {
+	review, err := s.reviewRepo.GetByOrderID(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if review.IsHidden {
+		return nil, errors.New("review not found")
+	}
+	public := convertReviewToPublic(*review)
+	return &public, nil
+}
+
+func (s *reviewService) GetCompanyRating(companyID uint) (*api.CompanyRatingSummary, error) {
+	company, err := s.companyRepo.GetByID(companyID)
+	if err != nil {
+		return nil, errors.New("company not found")
+	}
+	distribution, err := s.reviewRepo.GetRatingDistribution(companyID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.CompanyRatingSummary{
+		CompanyID:    company.ID,
+		Rating:       company.Stars,
+		ReviewCount:  company.ReviewCount,
+		RankingScore: company.RankingScore,
+		Histogram:    convertRatingDistribution(distribution),
+		SubRatings:   convertCompanySubRatings(*company),
+	}, nil
+}
+
+func (s *reviewService) UpdateReview(clientID, reviewID uint, rating int, comment string, subRatings api.ReviewSubRatings) (*api.PublicReview, error) {
+	if rating < 1 || rating > 5 {
+		return nil, errors.New("rating must be between 1 and 5")
+	}
+	if err := validateSubRatings(subRatings); err != nil {
+		return nil, err
+	}
+
+	review, err := s.reviewRepo.GetByID(reviewID)
+	if err != nil {
+		return nil, errors.New("review not found")
+	}
+	if review.ClientID != clientID {
+		return nil, errors.New("unauthorized: review does not belong to this client")
+	}
+	if review.IsHidden {
+		return nil, errors.New("hidden review cannot be edited")
+	}
+	if time.Since(review.CreatedAt) > internal.ReviewEditWindow {
+		return nil, errors.New("review edit window has expired")
+	}
+
+	now := time.Now()
+	review.Rating = rating
+	review.Comment = comment
+	applySubRatings(review, subRatings)
+	review.EditedAt = &now
+	if err := s.reviewRepo.UpdateContent(review); err != nil {
+		return nil, err
+	}
+
+	public := convertReviewToPublic(*review)
+	return &public, nil
 }
 
-func (s *reviewService) GetReviewByOrder(orderID uint) (*database.Review, error) {
-	return s.reviewRepo.GetByOrderID(orderID)
+func (s *reviewService) ReplyToReview(companyID, reviewID uint, reply string) (*api.PublicReview, error) {
+	reply = strings.TrimSpace(reply)
+	if reply == "" {
+		return nil, errors.New("reply cannot be empty")
+	}
+	if len([]rune(reply)) > maxReviewReplyLength {
+		return nil, errors.New("reply is too long")
+	}
+
+	review, err := s.reviewRepo.GetByID(reviewID)
+	if err != nil {
+		return nil, errors.New("review not found")
+	}
+	if review.CompanyID != companyID {
+		return nil, errors.New("unauthorized: review does not belong to this company")
+	}
+	if review.Reply != "" {
+		return nil, errors.New("review already has a reply")
+	}
+
+	now := time.Now()
+	review.Reply = reply
+	review.RepliedAt = &now
+	if err := s.reviewRepo.SetReply(review); err != nil {
+		return nil, err
+	}
+
+	// Ответ уже сохранен, поэтому ошибка уведомления только логируется
+	message := "Компания ответила на ваш отзыв о заказе"
+	err = s.notificationService.CreateNotification(review.ClientID, "client", "Ответ на отзыв", message, "review_reply", &review.ID)
+	if err != nil {
+		log.Printf("failed to notify client about reply to review %d: %v", review.ID, err)
+	}
+
+	public := convertReviewToPublic(*review)
+	return &public, nil
 }
 
-func (s *reviewService) GetCompanyRating(companyID uint) (float64, int, error) {
-	return s.reviewRepo.GetCompanyAverageRating(companyID)
+func (s *reviewService) ReportReview(reporterID uint, reporterType string, reviewID uint, reason string) error {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return errors.New("reason is required")
+	}
+	if len([]rune(reason)) > maxReviewReasonLength {
+		return errors.New("reason is too long")
+	}
+
+	review, err := s.reviewRepo.GetByID(reviewID)
+	if err != nil || review.IsHidden {
+		return errors.New("review not found")
+	}
+
+	exists, err := s.reviewReportRepo.ExistsPending(reviewID, reporterID, reporterType)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return errors.New("review already reported")
+	}
+
+	return s.reviewReportRepo.Create(&database.ReviewReport{
+		ReviewID:     reviewID,
+		ReporterID:   reporterID,
+		ReporterType: reporterType,
+		Reason:       reason,
+		Status:       repository.ReviewReportPending,
+	})
+}
+
+func (s *reviewService) GetModerationQueue(status string, page pagination.Request) ([]api.ReviewReportInfo, pagination.Page, error) {
+	if status == "" {
+		status = repository.ReviewReportPending
+	}
+	switch status {
+	case repository.ReviewReportPending, repository.ReviewReportHidden, repository.ReviewReportDismissed:
+	default:
+		return nil, pagination.Page{}, errors.New("unknown report status: " + status)
+	}
+
+	reports, next, err := s.reviewReportRepo.GetQueue(status, page)
+	if err != nil {
+		return nil, pagination.Page{}, err
+	}
+
+	result := []api.ReviewReportInfo{}
+	for _, report := range reports {
+		result = append(result, api.ReviewReportInfo{
+			ID:           report.ID,
+			ReporterID:   report.ReporterID,
+			ReporterType: report.ReporterType,
+			Reason:       report.Reason,
+			Status:       report.Status,
+			ResolvedAt:   formatOptionalTime(report.ResolvedAt),
+			CreatedAt:    report.CreatedAt.Format(time.RFC3339),
+			Review: api.ModerationReview{
+				PublicReview: convertReviewToPublic(report.Review),
+				ClientID:     report.Review.ClientID,
+				IsHidden:     report.Review.IsHidden,
+				HiddenReason: report.Review.HiddenReason,
+			},
+		})
+	}
+	return result, next, nil
+}
+
+func (s *reviewService) HideReview(adminID, reviewID uint, reason string) error {
+	review, err := s.reviewRepo.GetByID(reviewID)
+	if err != nil {
+		return errors.New("review not found")
+	}
+	if review.IsHidden {
+		return errors.New("review is already hidden")
+	}
+	return s.reviewRepo.SetHidden(review, true, strings.TrimSpace(reason), adminID)
+}
+
+func (s *reviewService) RestoreReview(adminID, reviewID uint) error {
+	review, err := s.reviewRepo.GetByID(reviewID)
+	if err != nil {
+		return errors.New("review not found")
+	}
+	if !review.IsHidden {
+		return errors.New("review is not hidden")
+	}
+	return s.reviewRepo.SetHidden(review, false, "", adminID)
+}
+
+func (s *reviewService) DismissReport(adminID, reportID uint) error {
+	report, err := s.reviewReportRepo.GetByID(reportID)
+	if err != nil {
+		return errors.New("report not found")
+	}
+	if report.Status != repository.ReviewReportPending {
+		return errors.New("report is already resolved")
+	}
+
+	now := time.Now()
+	report.Status = repository.ReviewReportDismissed
+	report.ResolvedByID = &adminID
+	report.ResolvedAt = &now
+	return s.reviewReportRepo.Update(report)
+}
+
+func convertReviewsToPublic(reviews []database.Review) []api.PublicReview {
+	result := []api.PublicReview{}
+	for _, review := range reviews {
+		result = append(result, convertReviewToPublic(review))
+	}
+	return result
+}
+
+// convertReviewToPublic оставляет в отзыве только публичные данные: контакты
+// клиента не показываются, а фамилия сокращается до инициала
+func convertReviewToPublic(review database.Review) api.PublicReview {
+	return api.PublicReview{
+		ID:         review.ID,
+		OrderID:    review.OrderID,
+		CompanyID:  review.CompanyID,
+		Rating:     review.Rating,
+		Comment:    review.Comment,
+		ClientName: publicClientName(review.Client.FullName),
+		CreatedAt:  review.CreatedAt.Format(time.RFC3339),
+		EditedAt:   formatOptionalTime(review.EditedAt),
+		Reply:      review.Reply,
+		RepliedAt:  formatOptionalTime(review.RepliedAt),
+		SubRatings: api.ReviewSubRatings{
+			Quality:       review.QualityRating,
+			Punctuality:   review.PunctualityRating,
+			Communication: review.CommunicationRating,
+			Value:         review.ValueRating,
+		},
+	}
+}
+
+func validateSubRatings(subRatings api.ReviewSubRatings) error {
+	for _, value := range []*int{subRatings.Quality, subRatings.Punctuality, subRatings.Communication, subRatings.Value} {
+		if value != nil && (*value < 1 || *value > 5) {
+			return errors.New("sub-ratings must be between 1 and 5")
+		}
+	}
+	return nil
+}
+
+func applySubRatings(review *database.Review, subRatings api.ReviewSubRatings) {
+	review.QualityRating = subRatings.Quality
+	review.PunctualityRating = subRatings.Punctuality
+	review.CommunicationRating = subRatings.Communication
+	review.ValueRating = subRatings.Value
+}
+
+func convertRatingDistribution(distribution map[int]int64) api.RatingDistribution {
+	return api.RatingDistribution{
+		One:   distribution[1],
+		Two:   distribution[2],
+		Three: distribution[3],
+		Four:  distribution[4],
+		Five:  distribution[5],
+	}
+}
+
+func convertCompanySubRatings(company database.CompanyDB) api.CompanySubRatings {
+	return api.CompanySubRatings{
+		Quality:       company.QualityRating,
+		Punctuality:   company.PunctualityRating,
+		Communication: company.CommunicationRating,
+		Value:         company.ValueRating,
+	}
+}
+
+func publicClientName(fullName string) string {
+	parts := strings.Fields(fullName)
+	if len(parts) == 0 {
+		return ""
+	}
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	initial := []rune(parts[1])[0]
+	return parts[0] + " " + string(initial) + "."
 }
 
-func NewReviewService(reviewRepo repository.ReviewRepository, orderRepo repository.OrderRepository) ReviewService {
+func NewReviewService(reviewRepo repository.ReviewRepository, reviewReportRepo repository.ReviewReportRepository, orderRepo repository.OrderRepository, companyRepo repository.CompanyRepository, notificationService NotificationService) ReviewService {
 	return &reviewService{
-		reviewRepo: reviewRepo,
-		orderRepo:  orderRepo,
+		reviewRepo:          reviewRepo,
+		reviewReportRepo:    reviewReportRepo,
+		orderRepo:           orderRepo,
+		companyRepo:         companyRepo,
+		notificationService: notificationService,
 	}
 }
@@ -0,0 +1,345 @@
+package service
+
+import (
+	"bytes"
+	"core/internal"
+	"core/internal/api"
+	"core/internal/database"
+	"core/internal/database/repository"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	defaultSlotMinutes = 60
+	maxSlotsLookahead  = 60 // Максимальное количество дней, на которое можно запросить слоты
+	calendarPastDays   = 90 // Сколько дней истории попадает в экспорт календаря
+)
+
+type ScheduleService interface {
+	GetSchedule(companyID uint) (*api.ScheduleResponse, error)
+	SetWorkingHours(companyID uint, hours []api.WorkingHoursInfo) error
+	AddBlackoutDate(companyID uint, date, reason string) (*database.BlackoutDate, error)
+	RemoveBlackoutDate(companyID, blackoutID uint) error
+	GetAvailableSlots(companyID uint, from time.Time, days int) ([]api.SlotInfo, error)
+	ExportCalendar(companyID uint) ([]byte, error)
+}
+
+type scheduleService struct {
+	scheduleRepo repository.ScheduleRepository
+}
+
+func (s *scheduleService) GetSchedule(companyID uint) (*api.ScheduleResponse, error) {
+	hours, err := s.scheduleRepo.GetWorkingHours(companyID)
+	if err != nil {
+		return nil, err
+	}
+
+	today := startOfDay(time.Now())
+	blackouts, err := s.scheduleRepo.GetBlackoutDates(companyID, today, today.AddDate(1, 0, 0))
+	if err != nil {
+		return nil, err
+	}
+
+	response := &api.ScheduleResponse{
+		Hours:     []api.WorkingHoursInfo{},
+		Blackouts: []api.BlackoutDateInfo{},
+	}
+	for _, h := range hours {
+		response.Hours = append(response.Hours, api.WorkingHoursInfo{
+			Weekday:     h.Weekday,
+			Start:       formatClock(h.StartMinute),
+			End:         formatClock(h.EndMinute),
+			SlotMinutes: h.SlotMinutes,
+			Capacity:    h.Capacity,
+		})
+	}
+	for _, b := range blackouts {
+		response.Blackouts = append(response.Blackouts, api.BlackoutDateInfo{
+			ID:     b.ID,
+			Date:   b.Date.Format("2006-01-02"),
+			Reason: b.Reason,
+		})
+	}
+	return response, nil
+}
+
+func (s *scheduleService) SetWorkingHours(companyID uint, hours []api.WorkingHoursInfo) error {
+	var records []database.WorkingHours
+	for _, h := range hours {
+		if h.Weekday < 0 || h.Weekday > 6 {
+			return errors.New("weekday must be between 0 and 6")
+		}
+		start, err := parseClock(h.Start)
+		if err != nil {
+			return err
+		}
+		end, err := parseClock(h.End)
+		if err != nil {
+			return err
+		}
+		if end <= start {
+			return errors.New("working hours end must be after start")
+		}
+
+		slotMinutes := h.SlotMinutes
+		if slotMinutes == 0 {
+			slotMinutes = defaultSlotMinutes
+		}
+		if slotMinutes < 15 || slotMinutes > end-start {
+			return errors.New("slot length must be at least 15 minutes and fit into working hours")
+		}
+
+		capacity := h.Capacity
+		if capacity == 0 {
+			capacity = 1
+		}
+		if capacity < 0 {
+			return errors.New("capacity must be greater than 0")
+		}
+
+		for _, r := range records {
+			if r.Weekday == h.Weekday && start < r.EndMinute && end > r.StartMinute {
+				return errors.New("working hours intervals overlap")
+			}
+		}
+
+		records = append(records, database.WorkingHours{
+			CompanyID:   companyID,
+			Weekday:     h.Weekday,
+			StartMinute: start,
+			EndMinute:   end,
+			SlotMinutes: slotMinutes,
+			Capacity:    capacity,
+		})
+	}
+
+	return s.scheduleRepo.ReplaceWorkingHours(companyID, records)
+}
+
+func (s *scheduleService) AddBlackoutDate(companyID uint, date, reason string) (*database.BlackoutDate, error) {
+	day, err := time.ParseInLocation("2006-01-02", date, internal.TimeZone)
+	if err != nil {
+		return nil, errors.New("date must be in YYYY-MM-DD format")
+	}
+
+	blackout := &database.BlackoutDate{
+		CompanyID: companyID,
+		Date:      day,
+		Reason:    reason,
+	}
+	if err := s.scheduleRepo.CreateBlackoutDate(blackout); err != nil {
+		return nil, err
+	}
+	return blackout, nil
+}
+
+func (s *scheduleService) RemoveBlackoutDate(companyID, blackoutID uint) error {
+	blackout, err := s.scheduleRepo.GetBlackoutDateByID(blackoutID)
+	if err != nil {
+		return err
+	}
+
+	if blackout.CompanyID != companyID {
+		return errors.New("unauthorized: blackout date does not belong to this company")
+	}
+
+	return s.scheduleRepo.DeleteBlackoutDate(blackoutID)
+}
+
+func (s *scheduleService) GetAvailableSlots(companyID uint, from time.Time, days int) ([]api.SlotInfo, error) {
+	if days < 1 || days > maxSlotsLookahead {
+		return nil, fmt.Errorf("days must be between 1 and %d", maxSlotsLookahead)
+	}
+
+	hours, err := s.scheduleRepo.GetWorkingHours(companyID)
+	if err != nil {
+		return nil, err
+	}
+
+	from = startOfDay(from)
+	to := from.AddDate(0, 0, days)
+
+	blackouts, err := s.scheduleRepo.GetBlackoutDates(companyID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	booked, err := s.scheduleRepo.GetBookedOrders(companyID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	slots := []api.SlotInfo{}
+	for day := from; day.Before(to); day = day.AddDate(0, 0, 1) {
+		if isBlackoutDay(blackouts, day) {
+			continue
+		}
+		for _, h := range hours {
+			if h.Weekday != int(day.Weekday()) {
+				continue
+			}
+			for minute := h.StartMinute; minute+h.SlotMinutes <= h.EndMinute; minute += h.SlotMinutes {
+				start := day.Add(time.Duration(minute) * time.Minute)
+				end := start.Add(time.Duration(h.SlotMinutes) * time.Minute)
+				if !start.After(now) {
+					continue
+				}
+
+				available := h.Capacity - countOverlapping(booked, start, end)
+				if available <= 0 {
+					continue
+				}
+				slots = append(slots, api.SlotInfo{
+					Start:     start.Format(time.RFC3339),
+					End:       end.Format(time.RFC3339),
+					Available: available,
+				})
+			}
+		}
+	}
+
+	return slots, nil
+}
+
+func (s *scheduleService) ExportCalendar(companyID uint) ([]byte, error) {
+	from := startOfDay(time.Now()).AddDate(0, 0, -calendarPastDays)
+	orders, err := s.scheduleRepo.GetBookedOrders(companyID, from, from.AddDate(2, 0, 0))
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	writeICSLine(&buf, "BEGIN:VCALENDAR")
+	writeICSLine(&buf, "VERSION:2.0")
+	writeICSLine(&buf, "PRODID:-//tomsk-center.ru//outsource//RU")
+	writeICSLine(&buf, "CALSCALE:GREGORIAN")
+	writeICSLine(&buf, "METHOD:PUBLISH")
+
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+	for _, order := range orders {
+		description := fmt.Sprintf("Клиент: %s\nТелефон: %s\nСтатус: %s\n%s",
+			order.Client.FullName, order.Client.Phone, order.Status, order.Description)
+
+		writeICSLine(&buf, "BEGIN:VEVENT")
+		writeICSLine(&buf, fmt.Sprintf("UID:order-%d@tomsk-center.ru", order.ID))
+		writeICSLine(&buf, "DTSTAMP:"+stamp)
+		writeICSLine(&buf, "DTSTART:"+order.ScheduledAt.UTC().Format("20060102T150405Z"))
+		writeICSLine(&buf, "DTEND:"+order.ScheduledEnd.UTC().Format("20060102T150405Z"))
+		writeICSLine(&buf, "SUMMARY:"+escapeICSText(fmt.Sprintf("Заказ #%d: %s", order.ID, order.Card.Title)))
+		writeICSLine(&buf, "DESCRIPTION:"+escapeICSText(strings.TrimSpace(description)))
+		writeICSLine(&buf, "STATUS:CONFIRMED")
+		writeICSLine(&buf, "END:VEVENT")
+	}
+
+	writeICSLine(&buf, "END:VCALENDAR")
+	return buf.Bytes(), nil
+}
+
+// resolveBookingSlot проверяет, что start совпадает с началом рабочего слота компании,
+// и возвращает конец слота и его вместимость
+func resolveBookingSlot(scheduleRepo repository.ScheduleRepository, companyID uint, start time.Time) (time.Time, int, error) {
+	hours, err := scheduleRepo.GetWorkingHours(companyID)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	if len(hours) == 0 {
+		return time.Time{}, 0, errors.New("company does not accept scheduled orders")
+	}
+
+	if !start.After(time.Now()) {
+		return time.Time{}, 0, errors.New("scheduled time must be in the future")
+	}
+
+	local := start.In(internal.TimeZone)
+	day := startOfDay(local)
+	blackouts, err := scheduleRepo.GetBlackoutDates(companyID, day, day.AddDate(0, 0, 1))
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	if isBlackoutDay(blackouts, day) {
+		return time.Time{}, 0, errors.New("company does not work on this date")
+	}
+
+	minute := local.Hour()*60 + local.Minute()
+	if local.Second() != 0 || local.Nanosecond() != 0 {
+		return time.Time{}, 0, errors.New("scheduled time does not match any slot")
+	}
+	for _, h := range hours {
+		if h.Weekday != int(local.Weekday()) {
+			continue
+		}
+		if minute >= h.StartMinute && minute+h.SlotMinutes <= h.EndMinute && (minute-h.StartMinute)%h.SlotMinutes == 0 {
+			return start.Add(time.Duration(h.SlotMinutes) * time.Minute), h.Capacity, nil
+		}
+	}
+
+	return time.Time{}, 0, errors.New("scheduled time does not match any slot")
+}
+
+func startOfDay(t time.Time) time.Time {
+	local := t.In(internal.TimeZone)
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, internal.TimeZone)
+}
+
+func isBlackoutDay(blackouts []database.BlackoutDate, day time.Time) bool {
+	for _, b := range blackouts {
+		if b.Date.Format("2006-01-02") == day.Format("2006-01-02") {
+			return true
+		}
+	}
+	return false
+}
+
+func countOverlapping(orders []database.Order, start, end time.Time) int {
+	count := 0
+	for _, order := range orders {
+		if order.ScheduledAt.Before(end) && order.ScheduledEnd.After(start) {
+			count++
+		}
+	}
+	return count
+}
+
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func formatClock(minute int) string {
+	return fmt.Sprintf("%02d:%02d", minute/60, minute%60)
+}
+
+// escapeICSText экранирует текст по RFC 5545
+func escapeICSText(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+	return replacer.Replace(value)
+}
+
+// writeICSLine пишет строку календаря, перенося её по 75 байт согласно RFC 5545
+func writeICSLine(buf *bytes.Buffer, line string) {
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		// Не разрываем многобайтовые символы UTF-8
+		for cut > 0 && line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		buf.WriteString(line[:cut])
+		buf.WriteString("\r\n ")
+		line = line[cut:]
+		limit = 74 // Пробел в начале строки продолжения тоже учитывается
+	}
+	buf.WriteString(line)
+	buf.WriteString("\r\n")
+}
+
+func NewScheduleService(scheduleRepo repository.ScheduleRepository) ScheduleService {
+	return &scheduleService{scheduleRepo: scheduleRepo}
+}
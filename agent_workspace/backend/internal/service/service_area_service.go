@@ -0,0 +1,165 @@
+package service
+
+import (
+	"core/internal/api"
+	"core/internal/database/repository"
+	"core/internal/geo"
+	"errors"
+)
+
+const maxServiceRadiusKm = 500
+
+type ServiceAreaService interface {
+	SetCompanyArea(companyID uint, area api.ServiceAreaInfo) (*api.ServiceAreaInfo, error)
+	SetCardArea(companyID, cardID uint, area api.ServiceAreaInfo) (*api.ServiceAreaInfo, error)
+}
+
+type serviceAreaService struct {
+	companyRepo repository.CompanyRepository
+	cardRepo    repository.CardRepository
+	geocoder    geo.Geocoder
+}
+
+// resolvedArea зона обслуживания в том виде, в котором она хранится в базе
+type resolvedArea struct {
+	Latitude  *float64
+	Longitude *float64
+	RadiusKm  *float64
+	Polygon   *string
+}
+
+func (s *serviceAreaService) SetCompanyArea(companyID uint, area api.ServiceAreaInfo) (*api.ServiceAreaInfo, error) {
+	company, err := s.companyRepo.GetByID(companyID)
+	if err != nil {
+		return nil, err
+	}
+
+	resolved, err := s.resolveArea(area)
+	if err != nil {
+		return nil, err
+	}
+
+	company.Latitude = resolved.Latitude
+	company.Longitude = resolved.Longitude
+	company.ServiceRadiusKm = resolved.RadiusKm
+	company.ServiceArea = resolved.Polygon
+	if err := s.companyRepo.Update(company); err != nil {
+		return nil, err
+	}
+	return convertAreaToInfo(area.Address, resolved), nil
+}
+
+func (s *serviceAreaService) SetCardArea(companyID, cardID uint, area api.ServiceAreaInfo) (*api.ServiceAreaInfo, error) {
+	card, err := s.cardRepo.GetByID(cardID)
+	if err != nil {
+		return nil, err
+	}
+
+	if card.CompanyID != companyID {
+		return nil, errors.New("unauthorized: card does not belong to this company")
+	}
+
+	resolved, err := s.resolveArea(area)
+	if err != nil {
+		return nil, err
+	}
+
+	card.Latitude = resolved.Latitude
+	card.Longitude = resolved.Longitude
+	card.ServiceRadiusKm = resolved.RadiusKm
+	card.ServiceArea = resolved.Polygon
+	if err := s.cardRepo.Update(card); err != nil {
+		return nil, err
+	}
+	return convertAreaToInfo(area.Address, resolved), nil
+}
+
+// resolveArea проверяет зону и при необходимости геокодирует адрес.
+// Пустая зона сбрасывает настройки, и тогда действуют данные компании
+func (s *serviceAreaService) resolveArea(area api.ServiceAreaInfo) (*resolvedArea, error) {
+	resolved := &resolvedArea{}
+
+	switch {
+	case area.Latitude != nil || area.Longitude != nil:
+		if area.Latitude == nil || area.Longitude == nil {
+			return nil, errors.New("both latitude and longitude must be provided")
+		}
+		point := geo.Point{Latitude: *area.Latitude, Longitude: *area.Longitude}
+		if err := point.Validate(); err != nil {
+			return nil, err
+		}
+		resolved.Latitude, resolved.Longitude = &point.Latitude, &point.Longitude
+	case area.Address != "":
+		point, err := s.geocoder.Geocode(area.Address)
+		if err != nil {
+			return nil, errors.New("failed to geocode address: " + err.Error())
+		}
+		resolved.Latitude, resolved.Longitude = &point.Latitude, &point.Longitude
+	}
+
+	if area.RadiusKm != nil {
+		if *area.RadiusKm <= 0 || *area.RadiusKm > maxServiceRadiusKm {
+			return nil, errors.New("radius_km must be between 0 and 500")
+		}
+		resolved.RadiusKm = area.RadiusKm
+	}
+
+	if len(area.Polygon) > 0 {
+		points := make([]geo.Point, 0, len(area.Polygon))
+		for _, vertex := range area.Polygon {
+			points = append(points, geo.Point{Latitude: vertex[0], Longitude: vertex[1]})
+		}
+		polygon, err := geo.FormatPolygon(points)
+		if err != nil {
+			return nil, err
+		}
+		resolved.Polygon = &polygon
+
+		// Без явной точки считаем расстояние до центра многоугольника
+		if resolved.Latitude == nil {
+			center := polygonCenter(points)
+			resolved.Latitude, resolved.Longitude = &center.Latitude, &center.Longitude
+		}
+	}
+
+	if resolved.RadiusKm != nil && resolved.Latitude == nil {
+		return nil, errors.New("radius_km requires coordinates or address")
+	}
+	return resolved, nil
+}
+
+func polygonCenter(points []geo.Point) geo.Point {
+	var center geo.Point
+	for _, point := range points {
+		center.Latitude += point.Latitude
+		center.Longitude += point.Longitude
+	}
+	center.Latitude /= float64(len(points))
+	center.Longitude /= float64(len(points))
+	return center
+}
+
+func convertAreaToInfo(address string, area *resolvedArea) *api.ServiceAreaInfo {
+	info := &api.ServiceAreaInfo{
+		Address:   address,
+		Latitude:  area.Latitude,
+		Longitude: area.Longitude,
+		RadiusKm:  area.RadiusKm,
+		Polygon:   [][2]float64{},
+	}
+	if area.Polygon != nil {
+		points, _ := geo.ParsePolygon(*area.Polygon)
+		for _, point := range points {
+			info.Polygon = append(info.Polygon, [2]float64{point.Latitude, point.Longitude})
+		}
+	}
+	return info
+}
+
+func NewServiceAreaService(companyRepo repository.CompanyRepository, cardRepo repository.CardRepository, geocoder geo.Geocoder) ServiceAreaService {
+	return &serviceAreaService{
+		companyRepo: companyRepo,
+		cardRepo:    cardRepo,
+		geocoder:    geocoder,
+	}
+}
@@ -0,0 +1,538 @@
+package service
+
+import (
+	"core/internal"
+	"core/internal/api"
+	"core/internal/database"
+	"core/internal/database/repository"
+	"core/internal/ratelimit"
+	"core/internal/security"
+	"core/internal/totp"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"log"
+	"strings"
+	"time"
+)
+
+// TokenTwoFactorLogin цель токена, который выдается после пароля и обменивается на токен доступа вместе с кодом
+const TokenTwoFactorLogin = "two_factor_login"
+
+var (
+	// ErrTwoFactorRequired возвращается, когда у пользователя включена 2FA, а код не передан
+	ErrTwoFactorRequired = errors.New("two-factor code is required")
+	// ErrInvalidTwoFactorCode неверный, устаревший или уже использованный код
+	ErrInvalidTwoFactorCode = errors.New("invalid two-factor code")
+)
+
+const (
+	recoveryCodeCount  = 10
+	recoveryCodeLength = 10 // Без дефиса, который добавляется для удобства чтения
+	maxDeviceNameLen   = 100
+)
+
+// TwoFactorLogin результат второго шага входа
+type TwoFactorLogin struct {
+	UserID      uint
+	UserType    string
+	DeviceToken string // Заполняется, если устройство отмечено доверенным
+}
+
+type TwoFactorService interface {
+	GetStatus(userID uint, userType string) (*api.TwoFactorStatus, error)
+	// BeginSetup выдает новый секрет. 2FA начинает действовать после ConfirmSetup
+	BeginSetup(userID uint, userType, password string) (*api.TwoFactorSetup, error)
+	// ConfirmSetup включает 2FA по первому коду из приложения и возвращает резервные коды.
+	// Коды показываются один раз, в базе хранятся только их хеши
+	ConfirmSetup(userID uint, userType, code string) ([]string, error)
+	Disable(userID uint, userType, password, code string) error
+	RegenerateRecoveryCodes(userID uint, userType, code string) ([]string, error)
+	ListTrustedDevices(userID uint, userType string) ([]database.TrustedDevice, error)
+	RevokeTrustedDevice(userID uint, userType string, deviceID uint) error
+
+	// BeginLogin вызывается после проверки пароля. Возвращает challenge для второго шага
+	// или пустую строку, если код не нужен: 2FA не включена или устройство доверенное
+	BeginLogin(userID uint, userType, deviceToken string) (string, error)
+	CompleteLogin(challenge, code string, trustDevice bool, deviceName string) (*TwoFactorLogin, error)
+	// VerifyStepUp повторно проверяет код перед выводом средств и сменой реквизитов.
+	// Доверенное устройство от этой проверки не освобождает
+	VerifyStepUp(userID uint, userType, code string) error
+}
+
+type twoFactorService struct {
+	twoFactorRepo  repository.TwoFactorRepository
+	tokenRepo      repository.AuthTokenRepository
+	userRepo       repository.UserRepository
+	clientRepo     repository.ClientRepository
+	companyRepo    repository.CompanyRepository
+	accountService AccountService
+	limiter        *ratelimit.Limiter
+}
+
+func (s *twoFactorService) GetStatus(userID uint, userType string) (*api.TwoFactorStatus, error) {
+	twoFactor, err := s.twoFactorRepo.GetByUser(userID, userType)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &api.TwoFactorStatus{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	status := &api.TwoFactorStatus{
+		Enabled:   twoFactor.EnabledAt != nil,
+		Pending:   twoFactor.EnabledAt == nil,
+		EnabledAt: twoFactor.EnabledAt,
+	}
+	if status.Enabled {
+		status.RecoveryCodesLeft, err = s.twoFactorRepo.CountUnusedRecoveryCodes(userID, userType)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return status, nil
+}
+
+func (s *twoFactorService) BeginSetup(userID uint, userType, password string) (*api.TwoFactorSetup, error) {
+	acc, err := loadAccount(s.userRepo, s.clientRepo, s.companyRepo, userID, userType)
+	if err != nil {
+		return nil, err
+	}
+	if security.CheckPassword(password, acc.PasswordHash) != nil {
+		return nil, errors.New("password is incorrect")
+	}
+
+	twoFactor, err := s.twoFactorRepo.GetByUser(userID, userType)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if twoFactor == nil {
+		twoFactor = &database.TwoFactor{UserID: userID, UserType: userType}
+	}
+	if twoFactor.EnabledAt != nil {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+
+	// Повторный вызов до подтверждения выдает новый секрет вместо прежнего
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	twoFactor.SecretEncrypted, err = security.EncryptSecret(secret)
+	if err != nil {
+		return nil, err
+	}
+	twoFactor.LastUsedStep = 0
+	if err := s.twoFactorRepo.Save(twoFactor); err != nil {
+		return nil, err
+	}
+
+	return &api.TwoFactorSetup{
+		Secret: secret,
+		URI:    totp.URI(internal.TwoFactorIssuer, acc.Email, secret),
+	}, nil
+}
+
+func (s *twoFactorService) ConfirmSetup(userID uint, userType, code string) ([]string, error) {
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	tx := s.twoFactorRepo.BeginTransaction()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	twoFactor, err := s.twoFactorRepo.GetByUserForUpdateInTx(tx, userID, userType)
+	if err != nil {
+		tx.Rollback()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("two-factor setup is not started")
+		}
+		return nil, err
+	}
+	if twoFactor.EnabledAt != nil {
+		tx.Rollback()
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+
+	// Подключение подтверждается только кодом из приложения: резервных кодов еще нет
+	if _, err := s.verifyCodeInTx(tx, twoFactor, code, false); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := s.twoFactorRepo.EnableInTx(tx, twoFactor.ID, time.Now()); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := s.twoFactorRepo.ReplaceRecoveryCodesInTx(tx, userID, userType, hashes); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	s.accountService.NotifySecurity(userID, userType, "Двухфакторная аутентификация включена",
+		"Для входа в учетную запись теперь нужен код из приложения-аутентификатора. Если это были не вы, "+
+			"сразу смените пароль и обратитесь в поддержку.")
+	return codes, nil
+}
+
+func (s *twoFactorService) Disable(userID uint, userType, password, code string) error {
+	acc, err := loadAccount(s.userRepo, s.clientRepo, s.companyRepo, userID, userType)
+	if err != nil {
+		return err
+	}
+	if security.CheckPassword(password, acc.PasswordHash) != nil {
+		return errors.New("password is incorrect")
+	}
+
+	tx := s.twoFactorRepo.BeginTransaction()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	twoFactor, err := s.twoFactorRepo.GetByUserForUpdateInTx(tx, userID, userType)
+	if err != nil {
+		tx.Rollback()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("two-factor authentication is not enabled")
+		}
+		return err
+	}
+	// Неподтвержденное подключение можно отменить без кода
+	if twoFactor.EnabledAt != nil {
+		if _, err := s.verifyCodeInTx(tx, twoFactor, code, true); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if err := s.twoFactorRepo.DeleteAllInTx(tx, userID, userType); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	if twoFactor.EnabledAt != nil {
+		s.accountService.NotifySecurity(userID, userType, "Двухфакторная аутентификация отключена",
+			"Для входа в учетную запись больше не нужен код из приложения. Если это были не вы, "+
+				"сразу смените пароль и обратитесь в поддержку.")
+	}
+	return nil
+}
+
+func (s *twoFactorService) RegenerateRecoveryCodes(userID uint, userType, code string) ([]string, error) {
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	tx := s.twoFactorRepo.BeginTransaction()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	twoFactor, err := s.enabledForUpdateInTx(tx, userID, userType)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	// Новые коды выпускаются только по коду из приложения
+	if _, err := s.verifyCodeInTx(tx, twoFactor, code, false); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := s.twoFactorRepo.ReplaceRecoveryCodesInTx(tx, userID, userType, hashes); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	s.accountService.NotifySecurity(userID, userType, "Резервные коды обновлены",
+		"Для вашей учетной записи созданы новые резервные коды, прежние больше не действуют.")
+	return codes, nil
+}
+
+func (s *twoFactorService) ListTrustedDevices(userID uint, userType string) ([]database.TrustedDevice, error) {
+	return s.twoFactorRepo.ListTrustedDevices(userID, userType, time.Now())
+}
+
+func (s *twoFactorService) RevokeTrustedDevice(userID uint, userType string, deviceID uint) error {
+	return s.twoFactorRepo.DeleteTrustedDevice(userID, userType, deviceID)
+}
+
+func (s *twoFactorService) BeginLogin(userID uint, userType, deviceToken string) (string, error) {
+	twoFactor, err := s.twoFactorRepo.GetByUser(userID, userType)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if twoFactor.EnabledAt == nil {
+		return "", nil
+	}
+
+	if deviceToken != "" {
+		err := s.twoFactorRepo.TouchTrustedDevice(userID, userType, security.HashOpaqueToken(deviceToken), time.Now())
+		if err == nil {
+			return "", nil
+		}
+	}
+
+	challenge, hash, err := security.NewOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	err = s.tokenRepo.Create(&database.AuthToken{
+		UserID:    userID,
+		UserType:  userType,
+		Purpose:   TokenTwoFactorLogin,
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(internal.TwoFactorChallengeTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+	return challenge, nil
+}
+
+func (s *twoFactorService) CompleteLogin(challenge, code string, trustDevice bool, deviceName string) (*TwoFactorLogin, error) {
+	tx := s.twoFactorRepo.BeginTransaction()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	// Challenge одноразовый даже при неверном коде: для новой попытки нужно снова ввести пароль
+	authToken, err := s.tokenRepo.ConsumeInTx(tx, security.HashOpaqueToken(challenge), TokenTwoFactorLogin, time.Now())
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	tx = s.twoFactorRepo.BeginTransaction()
+	twoFactor, err := s.enabledForUpdateInTx(tx, authToken.UserID, authToken.UserType)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	usedRecoveryCode, err := s.verifyCodeInTx(tx, twoFactor, code, true)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	login := &TwoFactorLogin{UserID: authToken.UserID, UserType: authToken.UserType}
+	if trustDevice {
+		login.DeviceToken, err = s.trustDevice(authToken.UserID, authToken.UserType, deviceName)
+		if err != nil {
+			// Вход уже подтвержден, без доверенного устройства код просто спросят в следующий раз
+			log.Printf("failed to trust device for %s %d: %v", authToken.UserType, authToken.UserID, err)
+		}
+	}
+
+	if usedRecoveryCode {
+		left, _ := s.twoFactorRepo.CountUnusedRecoveryCodes(authToken.UserID, authToken.UserType)
+		s.accountService.NotifySecurity(authToken.UserID, authToken.UserType, "Вход по резервному коду",
+			fmt.Sprintf("Выполнен вход в учетную запись по резервному коду. Осталось кодов: %d. "+
+				"Если это были не вы, сразу смените пароль и обратитесь в поддержку.", left))
+	}
+	return login, nil
+}
+
+func (s *twoFactorService) VerifyStepUp(userID uint, userType, code string) error {
+	twoFactor, err := s.twoFactorRepo.GetByUser(userID, userType)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if twoFactor.EnabledAt == nil {
+		return nil
+	}
+	if strings.TrimSpace(code) == "" {
+		return ErrTwoFactorRequired
+	}
+
+	tx := s.twoFactorRepo.BeginTransaction()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	twoFactor, err = s.enabledForUpdateInTx(tx, userID, userType)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if _, err := s.verifyCodeInTx(tx, twoFactor, code, true); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+// enabledForUpdateInTx блокирует включенную настройку 2FA, чтобы два запроса не приняли один код
+func (s *twoFactorService) enabledForUpdateInTx(tx *gorm.DB, userID uint, userType string) (*database.TwoFactor, error) {
+	twoFactor, err := s.twoFactorRepo.GetByUserForUpdateInTx(tx, userID, userType)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("two-factor authentication is not enabled")
+		}
+		return nil, err
+	}
+	if twoFactor.EnabledAt == nil {
+		return nil, errors.New("two-factor authentication is not enabled")
+	}
+	return twoFactor, nil
+}
+
+// verifyCodeInTx принимает код из приложения или, если allowRecovery, резервный код.
+// Возвращает true, если был использован резервный код. После серии неверных кодов
+// проверка блокируется, чтобы код нельзя было подобрать
+func (s *twoFactorService) verifyCodeInTx(tx *gorm.DB, twoFactor *database.TwoFactor, code string, allowRecovery bool) (bool, error) {
+	key := fmt.Sprintf("two_factor:%s:%d", twoFactor.UserType, twoFactor.UserID)
+	if err := s.limiter.CheckLock(key); err != nil {
+		return false, err
+	}
+
+	var err error
+	normalized := normalizeRecoveryCode(code)
+	usedRecoveryCode := allowRecovery && len(normalized) == recoveryCodeLength
+	if usedRecoveryCode {
+		hash := security.HashOpaqueToken(normalized)
+		if s.twoFactorRepo.ConsumeRecoveryCodeInTx(tx, twoFactor.UserID, twoFactor.UserType, hash, time.Now()) != nil {
+			err = ErrInvalidTwoFactorCode
+		}
+	} else {
+		err = s.acceptTOTPInTx(tx, twoFactor, code)
+	}
+
+	if errors.Is(err, ErrInvalidTwoFactorCode) {
+		s.limiter.Fail(key)
+		return false, err
+	}
+	if err != nil {
+		return false, err
+	}
+	s.limiter.Succeed(key)
+	return usedRecoveryCode, nil
+}
+
+func (s *twoFactorService) acceptTOTPInTx(tx *gorm.DB, twoFactor *database.TwoFactor, code string) error {
+	secret, err := security.DecryptSecret(twoFactor.SecretEncrypted)
+	if err != nil {
+		return err
+	}
+	step, ok := totp.Validate(secret, code, time.Now())
+	if !ok {
+		return ErrInvalidTwoFactorCode
+	}
+	if err := s.twoFactorRepo.AcceptStepInTx(tx, twoFactor.ID, step); err != nil {
+		return ErrInvalidTwoFactorCode
+	}
+	return nil
+}
+
+func (s *twoFactorService) trustDevice(userID uint, userType, name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = "Устройство"
+	}
+	if len([]rune(name)) > maxDeviceNameLen {
+		name = string([]rune(name)[:maxDeviceNameLen])
+	}
+
+	token, hash, err := security.NewOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	err = s.twoFactorRepo.CreateTrustedDevice(&database.TrustedDevice{
+		UserID:     userID,
+		UserType:   userType,
+		TokenHash:  hash,
+		Name:       name,
+		ExpiresAt:  now.Add(internal.TrustedDeviceTTL),
+		LastUsedAt: now,
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// generateRecoveryCodes создает резервные коды вида xxxxx-xxxxx и их хеши
+func generateRecoveryCodes() ([]string, []string, error) {
+	// Без 0, o, l и i: с оставшимися символами их не спутать. 32 символа делят 256 нацело,
+	// поэтому остаток от деления байта распределен равномерно
+	const alphabet = "abcdefghjkmnpqrstuvwxyz123456789"
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		bytes := make([]byte, recoveryCodeLength)
+		if _, err := rand.Read(bytes); err != nil {
+			return nil, nil, err
+		}
+		for j := range bytes {
+			bytes[j] = alphabet[int(bytes[j])%len(alphabet)]
+		}
+		code := string(bytes)
+		codes = append(codes, code[:recoveryCodeLength/2]+"-"+code[recoveryCodeLength/2:])
+		hashes = append(hashes, security.HashOpaqueToken(code))
+	}
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+func NewTwoFactorService(
+	twoFactorRepo repository.TwoFactorRepository,
+	tokenRepo repository.AuthTokenRepository,
+	userRepo repository.UserRepository,
+	clientRepo repository.ClientRepository,
+	companyRepo repository.CompanyRepository,
+	accountService AccountService,
+	limiter *ratelimit.Limiter,
+) TwoFactorService {
+	return &twoFactorService{
+		twoFactorRepo:  twoFactorRepo,
+		tokenRepo:      tokenRepo,
+		userRepo:       userRepo,
+		clientRepo:     clientRepo,
+		companyRepo:    companyRepo,
+		accountService: accountService,
+		limiter:        limiter,
+	}
+}
@@ -0,0 +1,205 @@
+package service
+
+import (
+	"core/internal"
+	"core/internal/api"
+	"core/internal/database"
+	"core/internal/database/repository"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Ошибки недоступной ссылки работника. По ним страница объясняет причину
+var (
+	ErrWorkerLinkNotFound = errors.New("worker link not found")
+	ErrWorkerLinkExpired  = errors.New("worker link has expired")
+	ErrWorkerLinkRevoked  = errors.New("worker link has been revoked")
+	ErrWorkerLinkUsed     = errors.New("worker link has already been used")
+)
+
+// Действия и результаты в журнале обращений к ссылке
+const (
+	WorkerLinkActionView   = "view"
+	WorkerLinkActionSubmit = "submit"
+
+	workerLinkResultOK       = "ok"
+	workerLinkResultNotFound = "not_found"
+	workerLinkResultExpired  = "expired"
+	workerLinkResultRevoked  = "revoked"
+	workerLinkResultUsed     = "used"
+
+	maxUserAgentLen = 512
+)
+
+type WorkerLinkService interface {
+	Reissue(companyID, orderID uint, ttlHours int) (*api.WorkerLinkInfo, error)
+	Revoke(companyID, orderID uint) error
+	GetOrderLinks(companyID, orderID uint) (*api.WorkerLinksResponse, error)
+}
+
+type workerLinkService struct {
+	workerLinkRepo repository.WorkerLinkRepository
+	orderRepo      repository.OrderRepository
+}
+
+func (s *workerLinkService) Reissue(companyID, orderID uint, ttlHours int) (*api.WorkerLinkInfo, error) {
+	order, err := s.getCompanyOrder(companyID, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if order.Status != "paid" && order.Status != "in_progress" {
+		return nil, errors.New("worker link can be reissued only for paid orders in progress")
+	}
+
+	ttl := internal.WorkerLinkTTL
+	if ttlHours < 0 {
+		return nil, errors.New("ttl_hours must be positive")
+	}
+	if ttlHours > 0 {
+		ttl = time.Duration(ttlHours) * time.Hour
+	}
+	if ttl > internal.WorkerLinkMaxTTL {
+		return nil, fmt.Errorf("ttl_hours must be at most %d", int(internal.WorkerLinkMaxTTL.Hours()))
+	}
+
+	link, err := s.workerLinkRepo.Reissue(order.ID, ttl, workerCompleteURL)
+	if err != nil {
+		return nil, err
+	}
+
+	info := convertWorkerLinkToInfo(*link)
+	return &info, nil
+}
+
+func (s *workerLinkService) Revoke(companyID, orderID uint) error {
+	if _, err := s.getCompanyOrder(companyID, orderID); err != nil {
+		return err
+	}
+
+	revoked, err := s.workerLinkRepo.RevokeByOrderID(orderID)
+	if err != nil {
+		return err
+	}
+	if revoked == 0 {
+		return errors.New("order has no active worker link")
+	}
+	return nil
+}
+
+func (s *workerLinkService) GetOrderLinks(companyID, orderID uint) (*api.WorkerLinksResponse, error) {
+	if _, err := s.getCompanyOrder(companyID, orderID); err != nil {
+		return nil, err
+	}
+
+	links, err := s.workerLinkRepo.GetByOrderID(orderID)
+	if err != nil {
+		return nil, err
+	}
+	accesses, err := s.workerLinkRepo.GetAccessLog(orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &api.WorkerLinksResponse{
+		Links:    []api.WorkerLinkInfo{},
+		Accesses: []api.WorkerLinkAccessInfo{},
+	}
+	for _, link := range links {
+		response.Links = append(response.Links, convertWorkerLinkToInfo(link))
+	}
+	for _, access := range accesses {
+		response.Accesses = append(response.Accesses, api.WorkerLinkAccessInfo{
+			WorkerLinkID: access.WorkerLinkID,
+			Action:       access.Action,
+			Result:       access.Result,
+			IP:           access.IP,
+			UserAgent:    access.UserAgent,
+			CreatedAt:    access.CreatedAt.Format(time.RFC3339),
+		})
+	}
+	return response, nil
+}
+
+func (s *workerLinkService) getCompanyOrder(companyID, orderID uint) (*database.Order, error) {
+	order, err := s.orderRepo.GetByID(orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if order.CompanyID != companyID {
+		return nil, errors.New("unauthorized: order does not belong to this company")
+	}
+	return order, nil
+}
+
+// resolveWorkerLink проверяет состояние ссылки и записывает обращение в журнал
+func resolveWorkerLink(linkRepo repository.WorkerLinkRepository, token, action, ip, userAgent string) (*database.WorkerLink, error) {
+	if len(userAgent) > maxUserAgentLen {
+		userAgent = userAgent[:maxUserAgentLen]
+	}
+	access := &database.WorkerLinkAccess{
+		Action:    action,
+		IP:        ip,
+		UserAgent: userAgent,
+	}
+
+	link, err := linkRepo.FindByToken(token)
+	if err != nil {
+		link = nil
+		access.Result = workerLinkResultNotFound
+		err = ErrWorkerLinkNotFound
+	} else {
+		access.WorkerLinkID = &link.ID
+		access.OrderID = &link.OrderID
+		access.Result, err = workerLinkState(*link)
+	}
+
+	// Журнал не должен мешать работнику завершить заказ
+	if logErr := linkRepo.LogAccess(access); logErr != nil {
+		log.Printf("failed to log worker link access: %v", logErr)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return link, nil
+}
+
+func workerLinkState(link database.WorkerLink) (string, error) {
+	switch {
+	case link.IsUsed:
+		return workerLinkResultUsed, ErrWorkerLinkUsed
+	case link.RevokedAt != nil:
+		return workerLinkResultRevoked, ErrWorkerLinkRevoked
+	case time.Now().After(link.ExpiresAt):
+		return workerLinkResultExpired, ErrWorkerLinkExpired
+	}
+	return workerLinkResultOK, nil
+}
+
+func convertWorkerLinkToInfo(link database.WorkerLink) api.WorkerLinkInfo {
+	info := api.WorkerLinkInfo{
+		ID:        link.ID,
+		Status:    "active",
+		CreatedAt: link.CreatedAt.Format(time.RFC3339),
+		ExpiresAt: link.ExpiresAt.Format(time.RFC3339),
+		RevokedAt: formatOptionalTime(link.RevokedAt),
+	}
+
+	if state, err := workerLinkState(link); err != nil {
+		info.Status = state
+	} else {
+		info.URL = workerCompleteURL(link.Token)
+	}
+	return info
+}
+
+func NewWorkerLinkService(workerLinkRepo repository.WorkerLinkRepository, orderRepo repository.OrderRepository) WorkerLinkService {
+	return &workerLinkService{
+		workerLinkRepo: workerLinkRepo,
+		orderRepo:      orderRepo,
+	}
+}
@@ -0,0 +1,344 @@
+package service
+
+import (
+	"core/internal/api"
+	"core/internal/database"
+	"core/internal/database/repository"
+	"core/internal/ratelimit"
+	"core/internal/security"
+	"errors"
+	"time"
+)
+
+// Права работника компании
+const (
+	WorkerPermissionViewTasks      = "view_tasks"
+	WorkerPermissionCheckIn        = "check_in"
+	WorkerPermissionCompleteOrders = "complete_orders"
+)
+
+var defaultWorkerPermissions = []string{
+	WorkerPermissionViewTasks,
+	WorkerPermissionCheckIn,
+	WorkerPermissionCompleteOrders,
+}
+
+type WorkerService interface {
+	CreateWorker(companyID uint, request *api.TokenCreateWorker) (*database.Worker, error)
+	GetCompanyWorkers(companyID uint) ([]api.WorkerInfo, error)
+	DeactivateWorker(companyID, workerID uint) error
+	Login(request *api.LoginRequest) (*database.Worker, error)
+	AssignOrder(companyID, orderID, workerID uint) error
+	GetTasks(workerID uint) ([]api.WorkerTaskInfo, error)
+	CheckIn(workerID, orderID uint) error
+	CheckOut(workerID, orderID uint) error
+	CompleteOrder(workerID, orderID uint, input *CompletionReportInput) error
+}
+
+type workerService struct {
+	workerRepo repository.WorkerRepository
+	orderRepo  repository.OrderRepository
+	reportRepo repository.CompletionReportRepository
+	limiter    *ratelimit.Limiter
+}
+
+func (s *workerService) CreateWorker(companyID uint, request *api.TokenCreateWorker) (*database.Worker, error) {
+	if request.Worker.FullName == "" || request.Worker.Email == "" {
+		return nil, errors.New("full_name and email are required")
+	}
+	if len(request.Worker.Password) < 6 {
+		return nil, errors.New("password must be at least 6 characters")
+	}
+
+	exists, err := s.workerRepo.ExistsByEmail(request.Worker.Email)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, errors.New("email already exists")
+	}
+
+	permissions := defaultWorkerPermissions
+	if len(request.Worker.Permissions) > 0 {
+		for _, permission := range request.Worker.Permissions {
+			if !containsString(defaultWorkerPermissions, permission) {
+				return nil, errors.New("unknown worker permission: " + permission)
+			}
+		}
+		permissions = request.Worker.Permissions
+	}
+
+	hashedPassword, err := security.HashPassword(request.Worker.Password)
+	if err != nil {
+		return nil, errors.New("failed to hash password")
+	}
+
+	worker := &database.Worker{
+		CompanyID:    companyID,
+		FullName:     request.Worker.FullName,
+		Email:        request.Worker.Email,
+		Phone:        request.Worker.Phone,
+		PasswordHash: hashedPassword,
+		Permissions:  permissions,
+		IsActive:     true,
+	}
+	if err := s.workerRepo.Create(worker); err != nil {
+		return nil, err
+	}
+	return worker, nil
+}
+
+func (s *workerService) GetCompanyWorkers(companyID uint) ([]api.WorkerInfo, error) {
+	workers, err := s.workerRepo.GetByCompanyID(companyID)
+	if err != nil {
+		return nil, err
+	}
+
+	workerInfos := []api.WorkerInfo{}
+	for _, worker := range workers {
+		workerInfos = append(workerInfos, convertWorkerToWorkerInfo(worker))
+	}
+	return workerInfos, nil
+}
+
+func (s *workerService) DeactivateWorker(companyID, workerID uint) error {
+	worker, err := s.workerRepo.GetByID(workerID)
+	if err != nil {
+		return err
+	}
+
+	if worker.CompanyID != companyID {
+		return errors.New("unauthorized: worker does not belong to this company")
+	}
+
+	worker.IsActive = false
+	return s.workerRepo.Update(worker)
+}
+
+func (s *workerService) Login(request *api.LoginRequest) (*database.Worker, error) {
+	var worker *database.Worker
+	err := guardLogin(s.limiter, request.Email, func() (err error) {
+		worker, err = s.workerRepo.GetByEmail(request.Email)
+		if err != nil {
+			// Незарегистрированный email проверяется так же долго, как неверный пароль
+			security.SimulatePasswordCheck(request.Password)
+			return err
+		}
+		return security.CheckPassword(request.Password, worker.PasswordHash)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !worker.IsActive {
+		return nil, errors.New("worker account is deactivated")
+	}
+
+	return worker, nil
+}
+
+func (s *workerService) AssignOrder(companyID, orderID, workerID uint) error {
+	order, err := s.orderRepo.GetByID(orderID)
+	if err != nil {
+		return err
+	}
+
+	if order.CompanyID != companyID {
+		return errors.New("unauthorized: order does not belong to this company")
+	}
+
+	if order.Status != "created" && order.Status != "accepted" && order.Status != "paid" && order.Status != "in_progress" {
+		return errors.New("order cannot be assigned in current status")
+	}
+
+	worker, err := s.workerRepo.GetByID(workerID)
+	if err != nil {
+		return err
+	}
+
+	if worker.CompanyID != companyID {
+		return errors.New("unauthorized: worker does not belong to this company")
+	}
+
+	if !worker.IsActive {
+		return errors.New("worker account is deactivated")
+	}
+
+	return s.orderRepo.AssignWorker(order.ID, companyID, worker.ID)
+}
+
+func (s *workerService) GetTasks(workerID uint) ([]api.WorkerTaskInfo, error) {
+	if _, err := s.getActiveWorker(workerID, WorkerPermissionViewTasks); err != nil {
+		return nil, err
+	}
+
+	orders, err := s.orderRepo.GetByWorkerID(workerID, []string{"accepted", "paid", "in_progress", "completed"})
+	if err != nil {
+		return nil, err
+	}
+
+	tasks := []api.WorkerTaskInfo{}
+	for _, order := range orders {
+		tasks = append(tasks, api.WorkerTaskInfo{
+			OrderID:      order.ID,
+			ServiceName:  order.Card.Title,
+			Description:  order.Description,
+			ClientName:   order.Client.FullName,
+			ClientPhone:  order.Client.Phone,
+			Location:     order.Card.Location,
+			Status:       order.Status,
+			ScheduledAt:  formatOptionalTime(order.ScheduledAt),
+			ScheduledEnd: formatOptionalTime(order.ScheduledEnd),
+			CheckInAt:    formatOptionalTime(order.CheckInAt),
+			CheckOutAt:   formatOptionalTime(order.CheckOutAt),
+		})
+	}
+	return tasks, nil
+}
+
+func (s *workerService) CheckIn(workerID, orderID uint) error {
+	order, err := s.getAssignedOrder(workerID, orderID, WorkerPermissionCheckIn)
+	if err != nil {
+		return err
+	}
+
+	if order.Status != "paid" && order.Status != "in_progress" {
+		return errors.New("order cannot be checked in in current status")
+	}
+
+	if order.CheckInAt != nil {
+		return errors.New("already checked in")
+	}
+
+	// Прибытие работника на место означает начало работ
+	return s.orderRepo.CheckIn(order.ID, workerID, time.Now())
+}
+
+func (s *workerService) CheckOut(workerID, orderID uint) error {
+	order, err := s.getAssignedOrder(workerID, orderID, WorkerPermissionCheckIn)
+	if err != nil {
+		return err
+	}
+
+	if order.CheckInAt == nil {
+		return errors.New("not checked in")
+	}
+
+	if order.CheckOutAt != nil {
+		return errors.New("already checked out")
+	}
+
+	return s.orderRepo.CheckOut(order.ID, workerID, time.Now())
+}
+
+func (s *workerService) CompleteOrder(workerID, orderID uint, input *CompletionReportInput) error {
+	order, err := s.getAssignedOrder(workerID, orderID, WorkerPermissionCompleteOrders)
+	if err != nil {
+		return err
+	}
+
+	if order.Status != "in_progress" {
+		return errors.New("order cannot be completed in current status")
+	}
+
+	// Фотографии принимаются только через форму по ссылке
+	input.Photos = nil
+	if err := input.validate(); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if order.CheckOutAt == nil {
+		order.CheckOutAt = &now
+	}
+	order.CompletedAt = &now
+	order.CompletedByID = &workerID
+
+	report := &database.CompletionReport{
+		OrderID:          order.ID,
+		WorkerID:         &workerID,
+		Notes:            input.Notes,
+		TimeSpentMinutes: input.TimeSpentMinutes,
+		Latitude:         input.Latitude,
+		Longitude:        input.Longitude,
+		SubmittedIP:      input.IP,
+		UserAgent:        input.UserAgent,
+	}
+	return s.reportRepo.CompleteOrder(order, report, "")
+}
+
+func (s *workerService) getActiveWorker(workerID uint, permission string) (*database.Worker, error) {
+	worker, err := s.workerRepo.GetByID(workerID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !worker.IsActive {
+		return nil, errors.New("worker account is deactivated")
+	}
+
+	if !containsString(worker.Permissions, permission) {
+		return nil, errors.New("access denied: missing permission " + permission)
+	}
+
+	return worker, nil
+}
+
+func (s *workerService) getAssignedOrder(workerID, orderID uint, permission string) (*database.Order, error) {
+	if _, err := s.getActiveWorker(workerID, permission); err != nil {
+		return nil, err
+	}
+
+	order, err := s.orderRepo.GetByID(orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if order.WorkerID == nil || *order.WorkerID != workerID {
+		return nil, errors.New("unauthorized: order is not assigned to this worker")
+	}
+
+	return order, nil
+}
+
+func convertWorkerToWorkerInfo(worker database.Worker) api.WorkerInfo {
+	return api.WorkerInfo{
+		ID:          worker.ID,
+		FullName:    worker.FullName,
+		Email:       worker.Email,
+		Phone:       worker.Phone,
+		Permissions: worker.Permissions,
+		IsActive:    worker.IsActive,
+	}
+}
+
+func formatOptionalTime(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	formatted := t.Format(time.RFC3339)
+	return &formatted
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func NewWorkerService(
+	workerRepo repository.WorkerRepository,
+	orderRepo repository.OrderRepository,
+	reportRepo repository.CompletionReportRepository,
+	limiter *ratelimit.Limiter,
+) WorkerService {
+	return &workerService{
+		workerRepo: workerRepo,
+		orderRepo:  orderRepo,
+		reportRepo: reportRepo,
+		limiter:    limiter,
+	}
+}
@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Storage хранилище загружаемых и генерируемых файлов
+type Storage interface {
+	Save(name string, content io.Reader) error
+	Open(name string) (io.ReadCloser, error)
+	Delete(name string) error
+}
+
+type localStorage struct {
+	root string
+}
+
+// NewLocalStorage создает хранилище в каталоге на диске
+func NewLocalStorage(root string) (Storage, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, err
+	}
+	return &localStorage{root: root}, nil
+}
+
+func (s *localStorage) Save(name string, content io.Reader) error {
+	path, err := s.resolve(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, content); err != nil {
+		file.Close()
+		os.Remove(path)
+		return err
+	}
+	return file.Close()
+}
+
+func (s *localStorage) Open(name string) (io.ReadCloser, error) {
+	path, err := s.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+func (s *localStorage) Delete(name string) error {
+	path, err := s.resolve(name)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// resolve не дает выйти за пределы корневого каталога хранилища
+func (s *localStorage) resolve(name string) (string, error) {
+	clean := filepath.Clean("/" + name)
+	if clean == "/" || strings.Contains(name, "..") {
+		return "", errors.New("invalid file name")
+	}
+	return filepath.Join(s.root, clean), nil
+}